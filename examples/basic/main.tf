@@ -28,11 +28,8 @@ terraform {
 
 # Configure gopass provider (optional - defaults work for most setups)
 provider "gopass" {
-  # Uncomment to use a specific gopass binary
-  # gopass_binary = "/usr/local/bin/gopass"
-
-  # Uncomment to use a specific store
-  # store = "work"
+  # Uncomment to use a specific store instead of gopass's configuration
+  # store_path = "~/.password-store"
 }
 
 # -----------------------------------------------------------------------------
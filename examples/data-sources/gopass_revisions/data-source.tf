@@ -0,0 +1,30 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Revision history of the secret, never its content
+data "gopass_revisions" "api" {
+  path = "services/api/token"
+}
+
+check "api_token_rotated" {
+  assert {
+    condition     = timecmp(data.gopass_revisions.api.revisions[0].time, timeadd(plantimestamp(), "-2160h")) > 0
+    error_message = "The API token was not rotated in the last 90 days."
+  }
+}
+
+# The version before the current one, e.g. to roll back a rotation
+ephemeral "gopass_secret" "api_previous" {
+  path     = "services/api/token"
+  revision = data.gopass_revisions.api.revisions[1].id
+}
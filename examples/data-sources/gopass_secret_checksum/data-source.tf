@@ -0,0 +1,32 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Facts about the secret, never its value
+data "gopass_secret_checksum" "db" {
+  path = "infrastructure/database/admin"
+}
+
+check "db_password" {
+  assert {
+    condition     = data.gopass_secret_checksum.db.exists
+    error_message = "The database password is missing from gopass."
+  }
+  assert {
+    condition     = data.gopass_secret_checksum.db.length >= 20
+    error_message = "The database password is too short."
+  }
+  assert {
+    condition     = contains(data.gopass_secret_checksum.db.keys, "username")
+    error_message = "The database secret has no username."
+  }
+}
@@ -0,0 +1,32 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Structure of the secret, nothing derived from its value
+data "gopass_secret_metadata" "db" {
+  path = "infrastructure/database/admin"
+}
+
+check "db_secret_structure" {
+  assert {
+    condition     = data.gopass_secret_metadata.db.exists
+    error_message = "The database secret is missing from gopass."
+  }
+  assert {
+    condition     = contains(data.gopass_secret_metadata.db.keys, "username")
+    error_message = "The database secret has no username."
+  }
+}
+
+output "db_secret_last_modified" {
+  value = data.gopass_secret_metadata.db.last_modified
+}
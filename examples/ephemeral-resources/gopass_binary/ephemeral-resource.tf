@@ -0,0 +1,19 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Stored with `gopass fscopy keystore.p12 services/api/keystore.p12`. Pass
+# ephemeral.gopass_binary.keystore.content_base64 to a provider or write-only
+# argument that takes base64 content, or decode it with base64decode().
+ephemeral "gopass_binary" "keystore" {
+  path = "services/api/keystore.p12"
+}
@@ -0,0 +1,29 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# postgres://admin:<password>@db.internal:5432/app?sslmode=verify-full, with the
+# username taken from the secret and every part escaped
+ephemeral "gopass_connection_string" "db" {
+  path     = "infrastructure/database/admin"
+  scheme   = "postgres"
+  host     = "db.internal"
+  port     = "5432"
+  database = "app"
+  params   = { sslmode = "verify-full" }
+}
+
+resource "gopass_secret" "database_url" {
+  path             = "apps/reporting/DATABASE_URL"
+  value_wo         = ephemeral.gopass_connection_string.db.value
+  value_wo_version = 1
+}
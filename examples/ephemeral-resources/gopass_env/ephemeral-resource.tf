@@ -0,0 +1,41 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Every secret directly below the path, by name
+ephemeral "gopass_env" "scaleway" {
+  path = "env/terraform/scaleway"
+}
+
+# Typically passed to another provider:
+#
+#   provider "scaleway" {
+#     access_key = ephemeral.gopass_env.scaleway.values["SCW_ACCESS_KEY"]
+#     secret_key = ephemeral.gopass_env.scaleway.values["SCW_SECRET_KEY"]
+#   }
+resource "gopass_secret" "access_key_copy" {
+  path             = "backup/scaleway/SCW_ACCESS_KEY"
+  value_wo         = ephemeral.gopass_env.scaleway.values["SCW_ACCESS_KEY"]
+  value_wo_version = 1
+}
+
+# A whole environment at once, keyed by the path below env/prod
+ephemeral "gopass_env" "prod" {
+  path      = "env/prod"
+  recursive = true
+}
+
+resource "gopass_secret" "db_password_copy" {
+  path             = "backup/prod/db/primary/password"
+  value_wo         = ephemeral.gopass_env.prod.values["db/primary/password"]
+  value_wo_version = 1
+}
@@ -0,0 +1,19 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# A service account key stored with `gopass insert -m`. Index the decoded
+# document directly, e.g. ephemeral.gopass_json.sa.data.private_key, instead
+# of calling jsondecode() on a sensitive string.
+ephemeral "gopass_json" "sa" {
+  path = "gcp/deploy-service-account"
+}
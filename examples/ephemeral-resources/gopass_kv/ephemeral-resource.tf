@@ -0,0 +1,24 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Same shape as ephemeral "vault_kv_secret_v2": the secret's keys and its
+# password in data, the version in metadata
+ephemeral "gopass_kv" "db" {
+  path = "infrastructure/database/admin"
+}
+
+resource "gopass_secret" "db_username_copy" {
+  path             = "backup/database/username"
+  value_wo         = ephemeral.gopass_kv.db.data["username"]
+  value_wo_version = 1
+}
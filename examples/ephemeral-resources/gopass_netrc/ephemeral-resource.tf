@@ -0,0 +1,31 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# One .netrc line per entry; the login falls back to the secret's username
+ephemeral "gopass_netrc" "ci" {
+  entries = [
+    { path = "infrastructure/database/admin", machine = "db-admin.example.com" },
+  ]
+}
+
+# Typically copied into a CI image or onto a provisioned host, e.g.
+#
+#   provisioner "file" {
+#     content     = ephemeral.gopass_netrc.ci.content
+#     destination = "/home/ci/.netrc"
+#   }
+resource "gopass_secret" "netrc_copy" {
+  path             = "backup/ci/netrc"
+  value_wo         = ephemeral.gopass_netrc.ci.content
+  value_wo_version = 1
+}
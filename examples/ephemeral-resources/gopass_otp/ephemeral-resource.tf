@@ -0,0 +1,20 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# The entry holds an otpauth://totp/... URL, as `gopass otp` expects. Pass
+# ephemeral.gopass_otp.registry.code to a provider or write-only argument
+# that needs the second factor.
+ephemeral "gopass_otp" "registry" {
+  path         = "websites/registry.example.com"
+  min_validity = "10s"
+}
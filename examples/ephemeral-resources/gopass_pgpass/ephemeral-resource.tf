@@ -0,0 +1,32 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# One .pgpass line per entry; host, port and database fall back to the
+# secret's keys, or to "*" if it has none
+ephemeral "gopass_pgpass" "db" {
+  entries = [
+    { path = "infrastructure/database/admin", host = "db.internal", port = "5432" },
+  ]
+}
+
+# Typically copied to the provisioned host with mode 0600, e.g.
+#
+#   provisioner "file" {
+#     content     = ephemeral.gopass_pgpass.db.content
+#     destination = "/home/app/.pgpass"
+#   }
+resource "gopass_secret" "pgpass_copy" {
+  path             = "backup/database/pgpass"
+  value_wo         = ephemeral.gopass_pgpass.db.content
+  value_wo_version = 1
+}
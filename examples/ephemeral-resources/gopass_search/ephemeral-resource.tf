@@ -0,0 +1,22 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Paths only, nothing is decrypted: every service's production password
+ephemeral "gopass_search" "prod_passwords" {
+  glob = "services/*/prod/password"
+}
+
+# The same with a regular expression, which must match the whole path
+ephemeral "gopass_search" "certificates" {
+  regex = "certs/.+\\.(crt|pem)"
+}
@@ -0,0 +1,30 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# The first line of the secret, read on every run and never stored
+ephemeral "gopass_secret" "db_password" {
+  path = "infrastructure/database/admin"
+}
+
+# Any provider or write-only argument accepts it, e.g.
+#
+#   provider "postgresql" {
+#     password = ephemeral.gopass_secret.db_password.value
+#   }
+#
+# or a copy kept at another path:
+resource "gopass_secret" "db_password_copy" {
+  path             = "backup/database/admin"
+  value_wo         = ephemeral.gopass_secret.db_password.value
+  value_wo_version = 1
+}
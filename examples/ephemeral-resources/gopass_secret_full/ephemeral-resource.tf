@@ -0,0 +1,31 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# The whole entry: its body, the password on the first line and the
+# key/value lines below it
+ephemeral "gopass_secret_full" "db" {
+  path = "infrastructure/database/admin"
+}
+
+resource "gopass_secret" "db_copy" {
+  path             = "backup/database/admin"
+  value_wo         = ephemeral.gopass_secret_full.db.body
+  value_wo_version = 1
+}
+
+# An entry with a YAML document below the password, decoded so that values
+# can be indexed directly, e.g. ephemeral.gopass_secret_full.app.yaml.api.token
+ephemeral "gopass_secret_full" "app" {
+  path       = "services/app/config"
+  parse_yaml = true
+}
@@ -0,0 +1,22 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Several secrets in one block, decrypted concurrently. Pass e.g.
+# ephemeral.gopass_secrets.app.values["services/api/token"] to a provider or
+# write-only argument.
+ephemeral "gopass_secrets" "app" {
+  paths = [
+    "services/api/token",
+    "infrastructure/database/admin",
+  ]
+}
@@ -0,0 +1,24 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# The repository keeps its SOPS file; the age key to open it stays in gopass
+ephemeral "gopass_sops_file" "app" {
+  source       = "${path.module}/secrets.enc.yaml"
+  age_identity = "infrastructure/sops/age-key"
+}
+
+resource "gopass_secret" "api_token_copy" {
+  path             = "backup/app/api-token"
+  value_wo         = ephemeral.gopass_sops_file.app.data["api_token"]
+  value_wo_version = 1
+}
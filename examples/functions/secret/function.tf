@@ -0,0 +1,26 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Function results are regular values that end up in plan and state wherever
+# they are used. Look up configuration kept next to the secrets this way, and
+# read the secrets themselves with the ephemeral resources.
+locals {
+  db_host = provider::gopass::secret("infrastructure/database/host")
+}
+
+ephemeral "gopass_connection_string" "db" {
+  path     = "infrastructure/database/admin"
+  scheme   = "postgres"
+  host     = local.db_host
+  database = "app"
+}
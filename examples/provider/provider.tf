@@ -0,0 +1,21 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+# Uses gopass's own configuration and mounts
+provider "gopass" {
+  # Read-only access to a single store below a few prefixes
+  store_path    = "~/.password-store"
+  read_only     = true
+  allowed_paths = ["infrastructure/", "env/terraform/"]
+
+  # Give up after repeated failed decryptions instead of prompting again
+  max_decrypt_failures = 3
+}
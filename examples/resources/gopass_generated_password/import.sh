@@ -0,0 +1,3 @@
+# Existing passwords are imported by their path and kept until the rules or
+# keepers change
+tofu import gopass_generated_password.db "infrastructure/database/admin"
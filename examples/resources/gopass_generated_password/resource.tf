@@ -0,0 +1,33 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Generated into gopass: state keeps only password_sha256
+resource "gopass_generated_password" "db" {
+  path        = "infrastructure/database/admin"
+  length      = 32
+  min_numeric = 2
+  min_special = 2
+
+  # Symbols the database accepts in passwords
+  override_special = "!#%+-_"
+
+  # Change to generate a new password
+  keepers = {
+    rotation = "2026-10"
+  }
+}
+
+# Read the password where it is needed, without it reaching state
+ephemeral "gopass_secret" "db" {
+  path = gopass_generated_password.db.path
+}
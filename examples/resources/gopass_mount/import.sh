@@ -0,0 +1,2 @@
+# Mounts are imported by their prefix
+tofu import gopass_mount.team "team"
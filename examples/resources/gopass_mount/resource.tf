@@ -0,0 +1,19 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+# Mount the team store, cloned or initialized beforehand, so that gopass
+# shows its secrets below team/
+resource "gopass_mount" "team" {
+  prefix = "team"
+  path   = "~/.local/share/gopass/stores/team"
+}
@@ -0,0 +1,4 @@
+# Recipients of the root store are imported by themselves, recipients of a
+# mounted store as <store>:<recipient>
+tofu import 'gopass_recipient.team["0xDEADBEEFCAFE1234"]' "0xDEADBEEFCAFE1234"
+tofu import gopass_recipient.ci "team:age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
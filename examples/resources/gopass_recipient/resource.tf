@@ -0,0 +1,34 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {
+  mounts = {
+    team = "~/.local/share/gopass/stores/team"
+  }
+}
+
+variable "team_members" {
+  description = "GPG key fingerprints of everyone who may read the root store"
+  type        = set(string)
+}
+
+# Onboarding adds a key and re-encrypts the store; offboarding (removing the
+# key from the set) removes it and re-encrypts again
+resource "gopass_recipient" "team" {
+  for_each  = var.team_members
+  recipient = each.value
+}
+
+# An age recipient of a mounted store, e.g. a CI runner
+resource "gopass_recipient" "ci" {
+  store     = "team"
+  recipient = "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+}
@@ -0,0 +1,2 @@
+# Secrets are imported by their path
+tofu import gopass_secret.api_token "env/terraform/api/TOKEN"
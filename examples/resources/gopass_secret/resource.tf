@@ -0,0 +1,43 @@
+terraform {
+  required_version = ">= 1.11.0"
+
+  required_providers {
+    gopass = {
+      source  = "registry.opentofu.org/istr/gopass"
+      version = "~> 0.1"
+    }
+  }
+}
+
+provider "gopass" {}
+
+variable "api_token" {
+  type      = string
+  sensitive = true
+  ephemeral = true
+}
+
+# Written through a write-only argument: the value never reaches state
+resource "gopass_secret" "api_token" {
+  path             = "env/terraform/api/TOKEN"
+  value_wo         = var.api_token
+  value_wo_version = 1 # increment to write a new value
+
+  # Keep the secret in gopass when the resource is destroyed
+  delete_on_remove = false
+}
+
+variable "db_password" {
+  type      = string
+  sensitive = true
+}
+
+# Managed as a whole entry: password and fields are kept in state
+resource "gopass_secret" "db" {
+  path     = "infrastructure/database/app"
+  password = var.db_password
+  fields = {
+    username = "app"
+    host     = "db.internal"
+  }
+}
@@ -0,0 +1,181 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+// Package gopasstest creates isolated, temporary gopass stores for tests.
+//
+// A store is real: entries are age-encrypted files in a directory, read and
+// written through the gopass library, so tests exercise the same code paths
+// as a user's store. It needs neither GPG nor git nor a passphrase prompt, and
+// never touches the user's own gopass configuration or keys.
+//
+//	func TestModule(t *testing.T) {
+//		store := gopasstest.New(t, map[string]string{
+//			"app/db": "s3cret\nusername: admin",
+//		})
+//		// The provider and the gopass library now use store.Dir
+//	}
+//
+// NewGPG creates a GPG store instead, whose key is protected by a passphrase
+// that a scripted Pinentry answers, to test PIN prompts being answered,
+// dismissed or left hanging without a person at the keyboard.
+//
+// New and NewGPG point the process environment (GOPASS_HOMEDIR, PASSWORD_STORE_DIR,
+// GNUPGHOME) at the store for the rest of the test, so tests using it cannot
+// run in parallel.
+package gopasstest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// Store is a temporary gopass store. It is removed when the test ends.
+type Store struct {
+	// Home is the gopass home directory (GOPASS_HOMEDIR), holding the
+	// gopass configuration and the age identity.
+	Home string
+	// Dir is the store directory (PASSWORD_STORE_DIR).
+	Dir string
+	// Recipient is the age recipient entries are encrypted to.
+	Recipient string
+
+	t testing.TB
+}
+
+// New creates a store seeded with entries, mapping secret paths to whole
+// entry bodies: the password on the first line, "key: value" fields below.
+// It fails the test if the store cannot be set up.
+func New(t testing.TB, entries map[string]string) *Store {
+	t.Helper()
+
+	s := newHome(t)
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("gopasstest: failed to generate an age identity: %v", err)
+	}
+	s.Recipient = identity.Recipient().String()
+
+	// gopass reads unencrypted identities from the passage identities file,
+	// but only looks for them when an .ssh directory exists
+	s.writeFiles(map[string]string{
+		filepath.Join(s.Home, ".passage", "identities"): identity.String() + "\n",
+		filepath.Join(s.Dir, ".age-recipients"):         s.Recipient + "\n",
+	})
+	s.seed(entries)
+	return s
+}
+
+// newHome creates the gopass home with an empty store directory and points
+// the environment at it.
+func newHome(t testing.TB) *Store {
+	t.Helper()
+
+	home := t.TempDir()
+	s := &Store{Home: home, Dir: filepath.Join(home, "password-store"), t: t}
+	for _, dir := range []string{".ssh", ".gnupg", "password-store"} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0o700); err != nil {
+			t.Fatalf("gopasstest: %v", err)
+		}
+	}
+	s.writeFiles(map[string]string{
+		filepath.Join(home, ".config", "gopass", "config"): "[core]\n\tautoimport = false\n\tnotifications = false\n[mounts]\n\tpath = " + s.Dir + "\n",
+	})
+
+	for key, value := range map[string]string{
+		"GOPASS_HOMEDIR":         home,
+		"PASSWORD_STORE_DIR":     s.Dir,
+		"GNUPGHOME":              filepath.Join(home, ".gnupg"),
+		"GOPASS_CONFIG_NOSYSTEM": "true",
+		"GOPASS_NO_NOTIFY":       "true",
+		"CHECKPOINT_DISABLE":     "true",
+	} {
+		t.Setenv(key, value)
+	}
+	return s
+}
+
+// writeFiles writes files, as path to content, creating their directories.
+func (s *Store) writeFiles(files map[string]string) {
+	s.t.Helper()
+
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+			s.t.Fatalf("gopasstest: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			s.t.Fatalf("gopasstest: %v", err)
+		}
+	}
+}
+
+// seed writes entries in a stable order.
+func (s *Store) seed(entries map[string]string) {
+	s.t.Helper()
+
+	paths := make([]string, 0, len(entries))
+	for path := range entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		s.Set(path, entries[path])
+	}
+}
+
+// open opens the store through the gopass library and fails the test if it
+// cannot. The store is closed when the test ends.
+func (s *Store) open(ctx context.Context) gopass.Store {
+	s.t.Helper()
+
+	store, err := api.New(ctx)
+	if err != nil {
+		s.t.Fatalf("gopasstest: failed to open the store: %v", err)
+	}
+	s.t.Cleanup(func() { _ = store.Close(context.Background()) })
+	return store
+}
+
+// Set writes an entry, as path to whole entry body, adding a revision if it
+// exists.
+func (s *Store) Set(path, body string) {
+	s.t.Helper()
+
+	ctx := context.Background()
+	secret := secrets.ParseAKV([]byte(body))
+	if err := s.open(ctx).Set(ctx, path, secret); err != nil {
+		s.t.Fatalf("gopasstest: failed to write %q: %v", path, err)
+	}
+}
+
+// Get returns the password and fields of an entry as the gopass library
+// decrypts them, or an error if it does not exist.
+func (s *Store) Get(path string) (gopass.Secret, error) {
+	s.t.Helper()
+
+	ctx := context.Background()
+	secret, err := s.open(ctx).Get(ctx, path, "latest")
+	if err != nil {
+		return nil, fmt.Errorf("gopasstest: failed to read %q: %w", path, err)
+	}
+	return secret, nil
+}
+
+// Exists reports whether the encrypted file of an entry exists, without
+// decrypting it.
+func (s *Store) Exists(path string) bool {
+	for _, ext := range []string{".age", ".gpg"} {
+		if _, err := os.Stat(filepath.Join(s.Dir, filepath.FromSlash(path)+ext)); err == nil {
+			return true
+		}
+	}
+	return false
+}
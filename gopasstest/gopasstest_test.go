@@ -0,0 +1,87 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package gopasstest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	store := New(t, map[string]string{
+		"app/db":  "s3cret\nusername: admin",
+		"app/api": "token",
+	})
+
+	if os.Getenv("GOPASS_HOMEDIR") != store.Home || os.Getenv("PASSWORD_STORE_DIR") != store.Dir {
+		t.Error("expected the environment to point at the store")
+	}
+	if !strings.HasPrefix(store.Recipient, "age1") {
+		t.Errorf("unexpected recipient %q", store.Recipient)
+	}
+	for _, path := range []string{"app/db", "app/api"} {
+		if !store.Exists(path) {
+			t.Errorf("expected %s to be written", path)
+		}
+	}
+
+	secret, err := store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Password() != "s3cret" {
+		t.Errorf("unexpected password %q", secret.Password())
+	}
+	if username, _ := secret.Get("username"); username != "admin" {
+		t.Errorf("unexpected username %q", username)
+	}
+}
+
+func TestNew_Encrypted(t *testing.T) {
+	store := New(t, map[string]string{"app/db": "s3cret"})
+
+	data, err := os.ReadFile(filepath.Join(store.Dir, "app", "db.age"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Error("expected the entry to be encrypted on disk")
+	}
+}
+
+func TestStore_Set(t *testing.T) {
+	store := New(t, nil)
+
+	if store.Exists("app/db") {
+		t.Fatal("expected an empty store")
+	}
+	if _, err := store.Get("app/db"); err == nil {
+		t.Error("expected reading a missing entry to fail")
+	}
+
+	store.Set("app/db", "first")
+	store.Set("app/db", "second")
+
+	secret, err := store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Password() != "second" {
+		t.Errorf("expected the latest value, got %q", secret.Password())
+	}
+}
+
+func TestNew_Isolated(t *testing.T) {
+	first := New(t, map[string]string{"app/db": "first"})
+	second := New(t, nil)
+
+	if first.Dir == second.Dir || first.Recipient == second.Recipient {
+		t.Error("expected every store to get its own directory and key")
+	}
+	if second.Exists("app/db") {
+		t.Error("expected entries not to leak between stores")
+	}
+}
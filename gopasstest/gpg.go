@@ -0,0 +1,178 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package gopasstest
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Responses a Pinentry gives when gpg-agent asks for a passphrase.
+const (
+	// Cancel dismisses the prompt, as if the user pressed Cancel.
+	Cancel = "cancel"
+	// Hang never answers, as if nobody is at the machine or the hardware
+	// token waits for a touch.
+	Hang = "hang"
+)
+
+// PIN returns the response entering pin. It must not contain "%", CR or LF.
+func PIN(pin string) string {
+	return "pin " + pin
+}
+
+// Pinentry is a scripted stand-in for the pinentry program gpg-agent runs to
+// ask for a passphrase or PIN. It answers each prompt with the next response
+// of its script, repeating the last one once the script is used up, and
+// counts the prompts.
+type Pinentry struct {
+	dir string
+	t   testing.TB
+}
+
+// pinentryScript implements the pinentry side of the Assuan protocol gpg-agent
+// speaks with it. GETPIN answers from the responses file; every other command
+// is acknowledged.
+const pinentryScript = `#!/bin/sh
+dir=$(dirname "$0")
+echo "OK Pleased to meet you"
+while read -r cmd rest; do
+	case "$cmd" in
+	GETPIN)
+		n=$(($(cat "$dir/prompts") + 1))
+		echo "$n" > "$dir/prompts"
+		response=$(sed -n "${n}p" "$dir/responses")
+		[ -n "$response" ] || response=$(tail -n 1 "$dir/responses")
+		case "$response" in
+		"pin "*) echo "D ${response#pin }"; echo "OK" ;;
+		cancel) echo "ERR 83886179 Operation cancelled <Pinentry>" ;;
+		hang) cat >/dev/null; exit 0 ;;
+		*) echo "ERR 83886179 Operation cancelled <Pinentry>" ;;
+		esac
+		;;
+	BYE) echo "OK closing connection"; exit 0 ;;
+	*) echo "OK" ;;
+	esac
+done
+`
+
+// gpgKeyUID is the user ID of the keys NewGPG generates.
+const gpgKeyUID = "gopasstest <gopasstest@example.invalid>"
+
+// NewGPG creates a store like New, but GPG-encrypted to a freshly generated
+// key protected by passphrase. gpg-agent asks the returned Pinentry for the
+// passphrase whenever a secret is decrypted, so tests can script PIN prompts
+// being answered, cancelled or left hanging. It skips the test if gpg is not
+// installed, and stops the store's gpg-agent when the test ends.
+//
+// The agent caches the passphrase after a correct answer; call Forget to make
+// it ask again.
+func NewGPG(t testing.TB, passphrase string, entries map[string]string) (*Store, *Pinentry) {
+	t.Helper()
+
+	for _, tool := range []string{"gpg", "gpgconf"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("gopasstest: %s is not installed", tool)
+		}
+	}
+
+	s := newHome(t)
+	gnupgHome := filepath.Join(s.Home, ".gnupg")
+	p := &Pinentry{dir: filepath.Join(s.Home, "pinentry"), t: t}
+	s.writeFiles(map[string]string{
+		filepath.Join(p.dir, "pinentry"):           pinentryScript,
+		filepath.Join(p.dir, "prompts"):            "0\n",
+		filepath.Join(p.dir, "responses"):          Cancel + "\n",
+		filepath.Join(gnupgHome, "gpg-agent.conf"): "pinentry-program " + filepath.Join(p.dir, "pinentry") + "\n",
+	})
+	if err := os.Chmod(filepath.Join(p.dir, "pinentry"), 0o700); err != nil { //nolint:gosec // the script must be executable
+		t.Fatalf("gopasstest: %v", err)
+	}
+	t.Cleanup(func() {
+		// Also ends gpg processes still waiting for a hanging prompt
+		_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+	})
+
+	// The key is generated with the passphrase given on the command line,
+	// without asking the pinentry
+	gpg(t, "--batch", "--pinentry-mode", "loopback", "--passphrase", passphrase,
+		"--quick-generate-key", gpgKeyUID, "default", "default", "never")
+	for _, line := range strings.Split(gpg(t, "--with-colons", "--list-keys", gpgKeyUID), "\n") {
+		if strings.HasPrefix(line, "fpr:") {
+			s.Recipient = strings.Split(line, ":")[9]
+			break
+		}
+	}
+	if s.Recipient == "" {
+		t.Fatal("gopasstest: generated gpg key has no fingerprint")
+	}
+
+	s.writeFiles(map[string]string{filepath.Join(s.Dir, ".gpg-id"): s.Recipient + "\n"})
+	// Encrypting needs only the public key, so seeding never prompts
+	s.seed(entries)
+	return s, p
+}
+
+// gpg runs gpg and returns its output, failing the test on errors.
+func gpg(t testing.TB, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("gpg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("gopasstest: gpg %s: %v: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return string(out)
+}
+
+// Script replaces the responses to the next prompts and resets the prompt
+// count.
+func (p *Pinentry) Script(responses ...string) {
+	p.t.Helper()
+
+	if len(responses) == 0 {
+		p.t.Fatal("gopasstest: a pinentry script needs at least one response")
+	}
+	for _, file := range []struct{ name, content string }{
+		{"responses", strings.Join(responses, "\n") + "\n"},
+		{"prompts", "0\n"},
+	} {
+		if err := os.WriteFile(filepath.Join(p.dir, file.name), []byte(file.content), 0o600); err != nil {
+			p.t.Fatalf("gopasstest: %v", err)
+		}
+	}
+}
+
+// Prompts returns how often gpg-agent asked for the passphrase since the
+// last Script.
+func (p *Pinentry) Prompts() int {
+	p.t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(p.dir, "prompts"))
+	if err != nil {
+		p.t.Fatalf("gopasstest: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		p.t.Fatalf("gopasstest: invalid prompt count %q", data)
+	}
+	return n
+}
+
+// Forget makes gpg-agent drop cached passphrases, so the next decryption
+// prompts again.
+func (p *Pinentry) Forget() {
+	p.t.Helper()
+
+	if out, err := exec.Command("gpgconf", "--reload", "gpg-agent").CombinedOutput(); err != nil {
+		p.t.Fatalf("gopasstest: gpgconf --reload gpg-agent: %v: %s", err, out)
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package gopasstest
+
+import "testing"
+
+func TestNewGPG(t *testing.T) {
+	store, pinentry := NewGPG(t, "passphrase", map[string]string{"app/db": "s3cret"})
+
+	if !store.Exists("app/db") {
+		t.Fatal("expected the entry to be written")
+	}
+
+	pinentry.Script(PIN("passphrase"))
+	secret, err := store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Password() != "s3cret" {
+		t.Errorf("unexpected password %q", secret.Password())
+	}
+	if n := pinentry.Prompts(); n != 1 {
+		t.Errorf("expected one prompt, got %d", n)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	providerschema "github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	resourceschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// Ephemeral values never reach state, so a test cannot look at them there.
+// The echo pattern makes them observable: the value is passed to the echo
+// provider's configuration, which OpenTofu allows for ephemeral values, and
+// the provider's echo resource copies it into its own state. The same tests
+// then check that none of the gopass provider's own artifacts carry it.
+
+// echoProvider is a provider whose echo resource stores the provider's data
+// attribute, mirroring the echo provider of terraform-plugin-testing.
+type echoProvider struct{}
+
+func (p *echoProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "echo"
+}
+
+func (p *echoProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = providerschema.Schema{
+		Attributes: map[string]providerschema.Attribute{
+			"data": providerschema.DynamicAttribute{Optional: true},
+		},
+	}
+}
+
+func (p *echoProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var data types.Dynamic
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("data"), &data)...)
+	resp.ResourceData = data
+}
+
+func (p *echoProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{func() resource.Resource { return &echoResource{} }}
+}
+
+func (p *echoProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+// echoResource stores the echo provider's data in its state.
+type echoResource struct {
+	data types.Dynamic
+}
+
+func (r *echoResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = "echo"
+}
+
+func (r *echoResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = resourceschema.Schema{
+		Attributes: map[string]resourceschema.Attribute{
+			"data": resourceschema.DynamicAttribute{Computed: true},
+		},
+	}
+}
+
+func (r *echoResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if data, ok := req.ProviderData.(types.Dynamic); ok {
+		r.data = data
+	}
+}
+
+func (r *echoResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("data"), r.data)...)
+}
+
+func (r *echoResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+}
+
+func (r *echoResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("data"), r.data)...)
+}
+
+func (r *echoResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
+
+// echo passes value through the echo provider's configuration and an apply of
+// its echo resource, and returns the data the resource stored.
+func echo(t *testing.T, value tftypes.Value) tftypes.Value {
+	t.Helper()
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(&echoProvider{})()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := &accProvider{t: t, server: server, schemas: schemas}
+	a.checkDiags("GetProviderSchema", schemas.Diagnostics)
+
+	configured, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		TerraformVersion: "1.10.0",
+		Config:           a.dynamic(schemas.Provider, map[string]tftypes.Value{"data": value}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("ConfigureProvider", configured.Diagnostics)
+
+	schema := schemas.ResourceSchemas["echo"]
+	empty := a.encode(schema, tftypes.NewValue(schema.ValueType(), nil))
+	config := a.dynamic(schema, nil)
+	plan, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         "echo",
+		PriorState:       empty,
+		ProposedNewState: config,
+		Config:           config,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("PlanResourceChange", plan.Diagnostics)
+
+	applied, err := server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       "echo",
+		PriorState:     empty,
+		PlannedState:   plan.PlannedState,
+		Config:         config,
+		PlannedPrivate: plan.PlannedPrivate,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("ApplyResourceChange", applied.Diagnostics)
+	return a.decode(schema, applied.NewState)["data"]
+}
+
+// liveClient returns the client that opened the store at dir.
+func liveClient(t *testing.T, dir string) *GopassClient {
+	t.Helper()
+
+	liveClients.mu.Lock()
+	defer liveClients.mu.Unlock()
+	for c := range liveClients.clients {
+		if c.storePath == dir {
+			return c
+		}
+	}
+	t.Fatalf("no client configured for %s", dir)
+	return nil
+}
+
+// openLeases returns the number of ephemeral results c still holds.
+func openLeases(c *GopassClient) int {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+	return len(c.lifecycle.leases)
+}
+
+// assertNotLeaked fails the test if any of the wire artifacts contains one of
+// the secrets.
+func assertNotLeaked(t *testing.T, secrets []string, artifacts map[string][]byte) {
+	t.Helper()
+
+	for name, artifact := range artifacts {
+		for _, secret := range secrets {
+			if bytes.Contains(artifact, []byte(secret)) {
+				t.Errorf("%s contains the secret %q", name, secret)
+			}
+		}
+	}
+}
+
+// openEphemeralDirect opens an ephemeral resource without closing it when the
+// test ends, so the test can close it itself.
+func (a *accProvider) openEphemeralDirect(typeName string, config map[string]tftypes.Value) *tfprotov6.OpenEphemeralResourceResponse {
+	a.t.Helper()
+
+	resp, err := a.server.OpenEphemeralResource(context.Background(), &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: typeName,
+		Config:   a.dynamic(a.schemas.EphemeralResourceSchemas[typeName], config),
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("OpenEphemeralResource", resp.Diagnostics)
+	return resp
+}
+
+// closeEphemeral closes an ephemeral resource opened by openEphemeralDirect.
+func (a *accProvider) closeEphemeral(typeName string, opened *tfprotov6.OpenEphemeralResourceResponse) {
+	a.t.Helper()
+
+	closed, err := a.server.CloseEphemeralResource(context.Background(), &tfprotov6.CloseEphemeralResourceRequest{
+		TypeName: typeName,
+		Private:  opened.Private,
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("CloseEphemeralResource", closed.Diagnostics)
+}
+
+func TestAccEchoSecretEphemeral(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret\nusername: admin"})
+	acc := newAccProvider(t, store, nil)
+	secrets := []string{"s3cret", "admin"}
+
+	// Open: the value is there for the rest of the run, nothing secret is
+	// handed back to OpenTofu for safekeeping, and no renewal is requested
+	opened := acc.openEphemeralDirect("gopass_secret", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	if !opened.RenewAt.IsZero() {
+		t.Errorf("expected no renewal, got renew_at %s", opened.RenewAt)
+	}
+	assertNotLeaked(t, secrets, map[string][]byte{"private data": opened.Private})
+	// The client registers once it opened the store
+	client := liveClient(t, store.Dir)
+	if n := openLeases(client); n != 1 {
+		t.Errorf("expected one open lease, got %d", n)
+	}
+
+	// Apply: the value reaches another provider that uses it
+	result := acc.decode(acc.schemas.EphemeralResourceSchemas["gopass_secret"], opened.Result)
+	echoed := echo(t, result["value"])
+	if !echoed.Equal(tftypes.NewValue(tftypes.String, "s3cret")) {
+		t.Errorf("expected the value to be echoed during apply, got %v", echoed)
+	}
+
+	// State and plan: written through the write-only argument, the value
+	// appears in none of the artifacts OpenTofu persists
+	schema := acc.schemas.ResourceSchemas["gopass_secret"]
+	state := acc.apply(nil, map[string]tftypes.Value{
+		"path":             tftypes.NewValue(tftypes.String, "app/copy"),
+		"value_wo":         result["value"],
+		"value_wo_version": tftypes.NewValue(tftypes.Number, 1),
+	})
+	assertNotLeaked(t, secrets, map[string][]byte{
+		"planned state":   acc.encode(schema, state.planned).MsgPack,
+		"planned private": state.plannedPrivate,
+		"state":           acc.encode(schema, state.value).MsgPack,
+		"private state":   state.private,
+	})
+	if secret, err := store.Get("app/copy"); err != nil || secret.Password() != "s3cret" {
+		t.Errorf("expected the value to be written to the store, got %v (%v)", secret, err)
+	}
+
+	// Close: the provider lets go of the value
+	acc.closeEphemeral("gopass_secret", opened)
+	if n := openLeases(client); n != 0 {
+		t.Errorf("expected the lease to be released, got %d open", n)
+	}
+}
+
+func TestAccEchoEnvEphemeral(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{
+		"app/env/DB_PASSWORD": "s3cret",
+		"app/env/API_KEY":     "k3y",
+	})
+	acc := newAccProvider(t, store, nil)
+
+	opened := acc.openEphemeralDirect("gopass_env", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/env"),
+	})
+	if !opened.RenewAt.IsZero() {
+		t.Errorf("expected no renewal, got renew_at %s", opened.RenewAt)
+	}
+	assertNotLeaked(t, []string{"s3cret", "k3y"}, map[string][]byte{"private data": opened.Private})
+	client := liveClient(t, store.Dir)
+
+	result := acc.decode(acc.schemas.EphemeralResourceSchemas["gopass_env"], opened.Result)
+	var echoed map[string]tftypes.Value
+	if err := echo(t, result["values"]).As(&echoed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stringAttr(t, echoed, "DB_PASSWORD") != "s3cret" || stringAttr(t, echoed, "API_KEY") != "k3y" {
+		t.Errorf("expected the values to be echoed during apply, got %v", echoed)
+	}
+
+	acc.closeEphemeral("gopass_env", opened)
+	if n := openLeases(client); n != 0 {
+		t.Errorf("expected the lease to be released, got %d open", n)
+	}
+}
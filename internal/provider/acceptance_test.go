@@ -0,0 +1,864 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// The acceptance tests drive the provider over the plugin protocol, the way
+// OpenTofu does, against a temporary age-encrypted store. They need neither a
+// tofu binary nor GPG, so they run with every go test.
+
+// accProvider is a provider server configured against a temporary store.
+type accProvider struct {
+	t       *testing.T
+	server  tfprotov6.ProviderServer
+	schemas *tfprotov6.GetProviderSchemaResponse
+}
+
+// newAccProvider starts a provider server and configures it with config,
+// store_path pointing at store unless config sets it.
+func newAccProvider(t *testing.T, store *gopasstest.Store, config map[string]tftypes.Value) *accProvider {
+	t.Helper()
+	ctx := context.Background()
+
+	server, err := providerserver.NewProtocol6WithError(New("acc")())()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := &accProvider{t: t, server: server, schemas: schemas}
+	a.checkDiags("GetProviderSchema", schemas.Diagnostics)
+	t.Cleanup(func() { Shutdown(context.Background()) })
+
+	values := map[string]tftypes.Value{"store_path": tftypes.NewValue(tftypes.String, store.Dir)}
+	for name, value := range config {
+		values[name] = value
+	}
+	resp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		TerraformVersion: "1.10.0",
+		Config:           a.dynamic(schemas.Provider, values),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("ConfigureProvider", resp.Diagnostics)
+	return a
+}
+
+// checkDiags fails the test on error diagnostics.
+func (a *accProvider) checkDiags(call string, diags []*tfprotov6.Diagnostic) {
+	a.t.Helper()
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			a.t.Fatalf("%s: %s: %s", call, d.Summary, d.Detail)
+		}
+	}
+}
+
+// object builds a value of the schema's type from values, with every
+// attribute not given set to null.
+func (a *accProvider) object(schema *tfprotov6.Schema, values map[string]tftypes.Value) tftypes.Value {
+	a.t.Helper()
+
+	objectType := schema.ValueType().(tftypes.Object)
+	attrs := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, typ := range objectType.AttributeTypes {
+		attrs[name] = tftypes.NewValue(typ, nil)
+	}
+	for name, value := range values {
+		if _, ok := attrs[name]; !ok {
+			a.t.Fatalf("unknown attribute %q", name)
+		}
+		attrs[name] = value
+	}
+	return tftypes.NewValue(objectType, attrs)
+}
+
+// dynamic encodes values as the schema's type for the wire.
+func (a *accProvider) dynamic(schema *tfprotov6.Schema, values map[string]tftypes.Value) *tfprotov6.DynamicValue {
+	a.t.Helper()
+	return a.encode(schema, a.object(schema, values))
+}
+
+func (a *accProvider) encode(schema *tfprotov6.Schema, value tftypes.Value) *tfprotov6.DynamicValue {
+	a.t.Helper()
+
+	dv, err := tfprotov6.NewDynamicValue(schema.ValueType(), value)
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	return &dv
+}
+
+// decode returns the attributes of a wire value, or nil for a null object.
+func (a *accProvider) decode(schema *tfprotov6.Schema, dv *tfprotov6.DynamicValue) map[string]tftypes.Value {
+	a.t.Helper()
+
+	value, err := dv.Unmarshal(schema.ValueType())
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	if value.IsNull() {
+		return nil
+	}
+	var attrs map[string]tftypes.Value
+	if err := value.As(&attrs); err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	return attrs
+}
+
+// resourceState is the state of a managed resource between calls.
+type resourceState struct {
+	value   tftypes.Value
+	private []byte
+	// planned and plannedPrivate are what the plan leading to the state
+	// proposed; OpenTofu persists both in saved plan files
+	planned        tftypes.Value
+	plannedPrivate []byte
+}
+
+// apply plans and applies a change of the gopass_secret resource from prior
+// to config, a nil config destroying it, and returns the new state.
+func (a *accProvider) apply(prior *resourceState, config map[string]tftypes.Value) *resourceState {
+	a.t.Helper()
+	return a.applyResource("gopass_secret", prior, config)
+}
+
+// applyResource plans and applies a change of a resource from prior to
+// config, a nil config destroying it, and returns the new state.
+func (a *accProvider) applyResource(typeName string, prior *resourceState, config map[string]tftypes.Value) *resourceState {
+	a.t.Helper()
+	ctx := context.Background()
+	schema := a.schemas.ResourceSchemas[typeName]
+	objectType := schema.ValueType()
+
+	priorValue := tftypes.NewValue(objectType, nil)
+	var priorPrivate []byte
+	if prior != nil {
+		priorValue, priorPrivate = prior.value, prior.private
+	}
+	configValue := tftypes.NewValue(objectType, nil)
+	proposed := configValue
+	if config != nil {
+		configValue = a.object(schema, config)
+		validated, err := a.server.ValidateResourceConfig(ctx, &tfprotov6.ValidateResourceConfigRequest{
+			TypeName:           typeName,
+			Config:             a.encode(schema, configValue),
+			ClientCapabilities: &tfprotov6.ValidateResourceConfigClientCapabilities{WriteOnlyAttributesAllowed: true},
+		})
+		if err != nil {
+			a.t.Fatalf("unexpected error: %v", err)
+		}
+		a.checkDiags("ValidateResourceConfig", validated.Diagnostics)
+
+		// Like OpenTofu, propose the configuration without write-only values,
+		// keeping computed attributes from the prior state
+		proposedAttrs := make(map[string]tftypes.Value, len(config))
+		for name, value := range config {
+			if name != "value_wo" {
+				proposedAttrs[name] = value
+			}
+		}
+		if prior != nil {
+			var priorAttrs map[string]tftypes.Value
+			if err := priorValue.As(&priorAttrs); err != nil {
+				a.t.Fatalf("unexpected error: %v", err)
+			}
+			for name, value := range priorAttrs {
+				if _, ok := proposedAttrs[name]; !ok && name != "value_wo" && name != "policy" {
+					proposedAttrs[name] = value
+				}
+			}
+		}
+		proposed = a.object(schema, proposedAttrs)
+	}
+
+	plan, err := a.server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       a.encode(schema, priorValue),
+		ProposedNewState: a.encode(schema, proposed),
+		Config:           a.encode(schema, configValue),
+		PriorPrivate:     priorPrivate,
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("PlanResourceChange", plan.Diagnostics)
+
+	applied, err := a.server.ApplyResourceChange(ctx, &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       typeName,
+		PriorState:     a.encode(schema, priorValue),
+		PlannedState:   plan.PlannedState,
+		Config:         a.encode(schema, configValue),
+		PlannedPrivate: plan.PlannedPrivate,
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("ApplyResourceChange", applied.Diagnostics)
+
+	planned, err := plan.PlannedState.Unmarshal(objectType)
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	state, err := applied.NewState.Unmarshal(objectType)
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	return &resourceState{value: state, private: applied.Private, planned: planned, plannedPrivate: plan.PlannedPrivate}
+}
+
+// refresh reads the gopass_secret resource and returns its refreshed state,
+// nil if the resource is gone.
+func (a *accProvider) refresh(current *resourceState) *resourceState {
+	a.t.Helper()
+	return a.refreshResource("gopass_secret", current)
+}
+
+// refreshResource reads a resource and returns its refreshed state, nil if
+// the resource is gone.
+func (a *accProvider) refreshResource(typeName string, current *resourceState) *resourceState {
+	a.t.Helper()
+	schema := a.schemas.ResourceSchemas[typeName]
+
+	resp, err := a.server.ReadResource(context.Background(), &tfprotov6.ReadResourceRequest{
+		TypeName:     typeName,
+		CurrentState: a.encode(schema, current.value),
+		Private:      current.private,
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("ReadResource", resp.Diagnostics)
+
+	state, err := resp.NewState.Unmarshal(schema.ValueType())
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	if state.IsNull() {
+		return nil
+	}
+	return &resourceState{value: state, private: resp.Private}
+}
+
+// readDataSource reads a data source and returns its attributes.
+func (a *accProvider) readDataSource(typeName string, config map[string]tftypes.Value) map[string]tftypes.Value {
+	a.t.Helper()
+	schema := a.schemas.DataSourceSchemas[typeName]
+
+	resp, err := a.server.ReadDataSource(context.Background(), &tfprotov6.ReadDataSourceRequest{
+		TypeName: typeName,
+		Config:   a.dynamic(schema, config),
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("ReadDataSource", resp.Diagnostics)
+	return a.decode(schema, resp.State)
+}
+
+// openEphemeral opens an ephemeral resource, closes it when the test ends and
+// returns its result along with the diagnostics.
+func (a *accProvider) openEphemeral(typeName string, config map[string]tftypes.Value) (map[string]tftypes.Value, []*tfprotov6.Diagnostic) {
+	a.t.Helper()
+	ctx := context.Background()
+	schema := a.schemas.EphemeralResourceSchemas[typeName]
+
+	validated, err := a.server.ValidateEphemeralResourceConfig(ctx, &tfprotov6.ValidateEphemeralResourceConfigRequest{
+		TypeName: typeName,
+		Config:   a.dynamic(schema, config),
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	a.checkDiags("ValidateEphemeralResourceConfig", validated.Diagnostics)
+
+	resp, err := a.server.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: typeName,
+		Config:   a.dynamic(schema, config),
+	})
+	if err != nil {
+		a.t.Fatalf("unexpected error: %v", err)
+	}
+	for _, d := range resp.Diagnostics {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			return nil, resp.Diagnostics
+		}
+	}
+	a.t.Cleanup(func() {
+		closed, err := a.server.CloseEphemeralResource(context.Background(), &tfprotov6.CloseEphemeralResourceRequest{
+			TypeName: typeName,
+			Private:  resp.Private,
+		})
+		if err != nil {
+			a.t.Errorf("unexpected error: %v", err)
+			return
+		}
+		a.checkDiags("CloseEphemeralResource", closed.Diagnostics)
+	})
+	return a.decode(schema, resp.Result), resp.Diagnostics
+}
+
+// stringAttr returns a string attribute of a decoded object.
+func stringAttr(t *testing.T, attrs map[string]tftypes.Value, name string) string {
+	t.Helper()
+	var s string
+	if err := attrs[name].As(&s); err != nil {
+		t.Fatalf("%s: unexpected error: %v", name, err)
+	}
+	return s
+}
+
+// hasErrorSummary reports whether diags hold an error whose summary contains s.
+func hasErrorSummary(diags []*tfprotov6.Diagnostic, s string) bool {
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError && strings.Contains(d.Summary, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAccSecretEphemeral(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret\nusername: admin"})
+	acc := newAccProvider(t, store, nil)
+
+	result, _ := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	if got := stringAttr(t, result, "value"); got != "s3cret" {
+		t.Errorf("unexpected value %q", got)
+	}
+
+	_, diags := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/missing"),
+	})
+	if !hasErrorSummary(diags, "not found") {
+		t.Errorf("expected a not found error, got %v", diags)
+	}
+}
+
+func TestAccSecretEphemeral_AllowMissing(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret"})
+	acc := newAccProvider(t, store, nil)
+
+	result, _ := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path":          tftypes.NewValue(tftypes.String, "app/missing"),
+		"allow_missing": tftypes.NewValue(tftypes.Bool, true),
+		"default":       tftypes.NewValue(tftypes.String, "fall back"),
+	})
+	if got := stringAttr(t, result, "value"); got != "fall back" {
+		t.Errorf("expected the default, got %q", got)
+	}
+	if got := stringAttr(t, result, "value_urlencoded"); got != "fall%20back" {
+		t.Errorf("expected the encoded default, got %q", got)
+	}
+
+	result, _ = acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path":          tftypes.NewValue(tftypes.String, "app/missing"),
+		"allow_missing": tftypes.NewValue(tftypes.Bool, true),
+	})
+	if !result["value"].IsNull() {
+		t.Errorf("expected a null value without default, got %v", result["value"])
+	}
+
+	// Existing secrets ignore the default
+	result, _ = acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path":          tftypes.NewValue(tftypes.String, "app/db"),
+		"allow_missing": tftypes.NewValue(tftypes.Bool, true),
+		"default":       tftypes.NewValue(tftypes.String, "fall back"),
+	})
+	if got := stringAttr(t, result, "value"); got != "s3cret" {
+		t.Errorf("unexpected value %q", got)
+	}
+
+	_, diags := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path":    tftypes.NewValue(tftypes.String, "app/missing"),
+		"default": tftypes.NewValue(tftypes.String, "fall back"),
+	})
+	if !hasErrorSummary(diags, "Invalid default") {
+		t.Errorf("expected default without allow_missing to fail, got %v", diags)
+	}
+}
+
+func TestAccSecretEphemeral_AllowMissingKeepsReadErrors(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret"})
+	if err := os.WriteFile(filepath.Join(store.Dir, "app", "db.age"), []byte("not age"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc := newAccProvider(t, store, nil)
+
+	_, diags := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path":          tftypes.NewValue(tftypes.String, "app/db"),
+		"allow_missing": tftypes.NewValue(tftypes.Bool, true),
+		"default":       tftypes.NewValue(tftypes.String, "fall back"),
+	})
+	if !hasErrorSummary(diags, "Failed to decrypt secret") {
+		t.Errorf("expected the unreadable secret to fail, got %v", diags)
+	}
+}
+
+func TestAccSecretEphemeral_SecureMemory(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret\nusername: admin"})
+	acc := newAccProvider(t, store, map[string]tftypes.Value{
+		"secure_memory": tftypes.NewValue(tftypes.Bool, true),
+	})
+
+	// The result is serialized before Close wipes and releases the buffers
+	result, _ := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	if got := stringAttr(t, result, "value"); got != "s3cret" {
+		t.Errorf("unexpected value %q", got)
+	}
+	if got := stringAttr(t, result, "basic_auth_header"); got != "Basic YWRtaW46czNjcmV0" {
+		t.Errorf("unexpected basic_auth_header %q", got)
+	}
+}
+
+func TestAccSecretEphemeral_InvalidPath(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+	schema := acc.schemas.EphemeralResourceSchemas["gopass_secret"]
+
+	validated, err := acc.server.ValidateEphemeralResourceConfig(context.Background(), &tfprotov6.ValidateEphemeralResourceConfigRequest{
+		TypeName: "gopass_secret",
+		Config: acc.dynamic(schema, map[string]tftypes.Value{
+			"path": tftypes.NewValue(tftypes.String, "app/../db"),
+		}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasErrorSummary(validated.Diagnostics, "Invalid secret path") {
+		t.Fatalf("expected an invalid path error, got %v", validated.Diagnostics)
+	}
+	if attr := validated.Diagnostics[0].Attribute; attr == nil || attr.String() != tftypes.NewAttributePath().WithAttributeName("path").String() {
+		t.Errorf("expected the error on the path attribute, got %v", attr)
+	}
+}
+
+func TestAccEnvEphemeral(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{
+		"env/app/DB_HOST":     "db.example.com",
+		"env/app/DB_PASSWORD": "s3cret\ncomment: first line only",
+		"env/other/IGNORED":   "other",
+	})
+	acc := newAccProvider(t, store, nil)
+
+	result, _ := acc.openEphemeral("gopass_env", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "env/app"),
+	})
+	var values map[string]tftypes.Value
+	if err := result["values"].As(&values); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"DB_HOST": "db.example.com", "DB_PASSWORD": "s3cret"}
+	if len(values) != len(want) {
+		t.Fatalf("expected %v, got %v", want, values)
+	}
+	for key, value := range want {
+		if got := stringAttr(t, values, key); got != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got)
+		}
+	}
+}
+
+func TestAccEnvEphemeral_Filter(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{
+		"env/ci/CI_TOKEN":  "token",
+		"env/ci/CI_USER":   "user",
+		"env/ci/README":    "docs",
+		"env/ci/OLD_TOKEN": "old",
+	})
+	acc := newAccProvider(t, store, nil)
+	globs := func(globs ...string) tftypes.Value {
+		var values []tftypes.Value
+		for _, glob := range globs {
+			values = append(values, tftypes.NewValue(tftypes.String, glob))
+		}
+		return tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, values)
+	}
+
+	result, _ := acc.openEphemeral("gopass_env", map[string]tftypes.Value{
+		"path":    tftypes.NewValue(tftypes.String, "env/ci"),
+		"include": globs("*_TOKEN", "README"),
+		"exclude": globs("OLD_*", "README"),
+	})
+	var values map[string]tftypes.Value
+	if err := result["values"].As(&values); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 1 || stringAttr(t, values, "CI_TOKEN") != "token" {
+		t.Errorf("expected only CI_TOKEN, got %v", values)
+	}
+
+	_, diags := acc.openEphemeral("gopass_env", map[string]tftypes.Value{
+		"path":    tftypes.NewValue(tftypes.String, "env/ci"),
+		"exclude": globs("README", "[A-Z"),
+	})
+	if !hasErrorSummary(diags, "Invalid glob") {
+		t.Fatalf("expected an invalid glob error, got %v", diags)
+	}
+	want := tftypes.NewAttributePath().WithAttributeName("exclude").WithElementKeyInt(1)
+	if attr := diags[0].Attribute; attr == nil || !attr.Equal(want) {
+		t.Errorf("expected the error on exclude[1], got %v", attr)
+	}
+}
+
+func TestAccEnvEphemeral_SanitizeKeys(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{
+		"env/app/api-key":     "key",
+		"env/app/db/password": "s3cret",
+		"env/dup/api-key":     "key",
+		"env/dup/API_KEY":     "other key",
+	})
+	acc := newAccProvider(t, store, nil)
+
+	result, _ := acc.openEphemeral("gopass_env", map[string]tftypes.Value{
+		"path":          tftypes.NewValue(tftypes.String, "env/app"),
+		"recursive":     tftypes.NewValue(tftypes.Bool, true),
+		"sanitize_keys": tftypes.NewValue(tftypes.Bool, true),
+	})
+	var values map[string]tftypes.Value
+	if err := result["values"].As(&values); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 2 || stringAttr(t, values, "API_KEY") != "key" || stringAttr(t, values, "DB_PASSWORD") != "s3cret" {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	_, diags := acc.openEphemeral("gopass_env", map[string]tftypes.Value{
+		"path":          tftypes.NewValue(tftypes.String, "env/dup"),
+		"sanitize_keys": tftypes.NewValue(tftypes.Bool, true),
+	})
+	if !hasErrorSummary(diags, "Conflicting environment variable names") {
+		t.Errorf("expected a collision error, got %v", diags)
+	}
+}
+
+func TestAccSecretChecksumDataSource(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret\nusername: admin"})
+	// Without the secret cache, so the change below shows up within the run
+	acc := newAccProvider(t, store, map[string]tftypes.Value{
+		"cache_secrets": tftypes.NewValue(tftypes.Bool, false),
+	})
+
+	attrs := acc.readDataSource("gopass_secret_checksum", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	var exists bool
+	if err := attrs["exists"].As(&exists); err != nil || !exists {
+		t.Fatalf("expected the secret to exist, got %v (%v)", attrs["exists"], err)
+	}
+	first := stringAttr(t, attrs, "checksum")
+	if first == "" || strings.Contains(first, "s3cret") {
+		t.Errorf("unexpected checksum %q", first)
+	}
+
+	store.Set("app/db", "changed")
+	attrs = acc.readDataSource("gopass_secret_checksum", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	if stringAttr(t, attrs, "checksum") == first {
+		t.Error("expected the checksum to follow the change made outside the provider")
+	}
+
+	attrs = acc.readDataSource("gopass_secret_checksum", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/missing"),
+	})
+	if err := attrs["exists"].As(&exists); err != nil || exists {
+		t.Errorf("expected a missing secret not to exist, got %v (%v)", attrs["exists"], err)
+	}
+}
+
+func TestAccSecretCache(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret"})
+	acc := newAccProvider(t, store, nil)
+
+	read := func() string {
+		attrs, diags := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+			"path": tftypes.NewValue(tftypes.String, "app/db"),
+		})
+		acc.checkDiags("OpenEphemeralResource", diags)
+		return stringAttr(t, attrs, "value")
+	}
+
+	if got := read(); got != "s3cret" {
+		t.Fatalf("unexpected value %q", got)
+	}
+	store.Set("app/db", "changed")
+	if got := read(); got != "s3cret" {
+		t.Errorf("expected the cached value within the ttl, got %q", got)
+	}
+}
+
+func TestAccSecretFullEphemeral_ParseYAML(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/config": "s3cret\n---\napi:\n  token: abc123\n  port: 8443\n"})
+	acc := newAccProvider(t, store, nil)
+
+	attrs, diags := acc.openEphemeral("gopass_secret_full", map[string]tftypes.Value{
+		"path":       tftypes.NewValue(tftypes.String, "app/config"),
+		"parse_yaml": tftypes.NewValue(tftypes.Bool, true),
+	})
+	acc.checkDiags("OpenEphemeralResource", diags)
+
+	var doc map[string]tftypes.Value
+	if err := attrs["yaml"].As(&doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var api map[string]tftypes.Value
+	if err := doc["api"].As(&api); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stringAttr(t, api, "token"); got != "abc123" {
+		t.Errorf("unexpected token %q", got)
+	}
+	var port big.Float
+	if err := api["port"].As(&port); err != nil || port.String() != "8443" {
+		t.Errorf("unexpected port %v (%v)", api["port"], err)
+	}
+}
+
+func TestAccJSONEphemeral(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"gcp/sa": `{"client_email": "deploy@example.iam", "scopes": ["a"]}`})
+	acc := newAccProvider(t, store, nil)
+
+	attrs, diags := acc.openEphemeral("gopass_json", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "gcp/sa"),
+	})
+	acc.checkDiags("OpenEphemeralResource", diags)
+
+	var doc map[string]tftypes.Value
+	if err := attrs["data"].As(&doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stringAttr(t, doc, "client_email"); got != "deploy@example.iam" {
+		t.Errorf("unexpected client_email %q", got)
+	}
+}
+
+func TestAccSecretMetadataDataSource(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret\nusername: admin"})
+	acc := newAccProvider(t, store, nil)
+
+	attrs := acc.readDataSource("gopass_secret_metadata", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	var exists bool
+	if err := attrs["exists"].As(&exists); err != nil || !exists {
+		t.Fatalf("expected the secret to exist, got %v (%v)", attrs["exists"], err)
+	}
+	var keys []tftypes.Value
+	if err := attrs["keys"].As(&keys); err != nil || len(keys) != 1 {
+		t.Fatalf("expected one key, got %v (%v)", attrs["keys"], err)
+	}
+	if key := stringAttr(t, map[string]tftypes.Value{"key": keys[0]}, "key"); key != "username" {
+		t.Errorf("unexpected key %q", key)
+	}
+
+	attrs = acc.readDataSource("gopass_secret_metadata", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/missing"),
+	})
+	if err := attrs["exists"].As(&exists); err != nil || exists {
+		t.Errorf("expected a missing secret not to exist, got %v (%v)", attrs["exists"], err)
+	}
+}
+
+func TestAccSecretResource_Lifecycle(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+
+	config := func(value string, version int64) map[string]tftypes.Value {
+		return map[string]tftypes.Value{
+			"path":             tftypes.NewValue(tftypes.String, "app/api"),
+			"value_wo":         tftypes.NewValue(tftypes.String, value),
+			"value_wo_version": tftypes.NewValue(tftypes.Number, version),
+		}
+	}
+	assertValue := func(want string) {
+		t.Helper()
+		secret, err := store.Get("app/api")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if secret.Password() != want {
+			t.Errorf("expected %q in the store, got %q", want, secret.Password())
+		}
+	}
+
+	// Create
+	state := acc.apply(nil, config("first", 1))
+	assertValue("first")
+	var attrs map[string]tftypes.Value
+	if err := state.value.As(&attrs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !attrs["value_wo"].IsNull() {
+		t.Error("expected the write-only value to stay out of state")
+	}
+	if id := stringAttr(t, attrs, "id"); id != "app/api" {
+		t.Errorf("unexpected id %q", id)
+	}
+
+	// Update by bumping the version
+	state = acc.apply(state, config("second", 2))
+	assertValue("second")
+
+	// Refresh keeps the resource while the secret exists. A store without
+	// git keeps no revisions, so the count stays at 1
+	refreshed := acc.refresh(state)
+	if refreshed == nil {
+		t.Fatal("expected the resource to still exist")
+	}
+	if err := refreshed.value.As(&attrs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !attrs["revision_count"].Equal(tftypes.NewValue(tftypes.Number, 1)) {
+		t.Errorf("expected one revision, got %v", attrs["revision_count"])
+	}
+
+	// Import
+	imported, err := acc.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: "gopass_secret",
+		ID:       "app/api",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc.checkDiags("ImportResourceState", imported.Diagnostics)
+	if len(imported.ImportedResources) != 1 {
+		t.Fatalf("expected one imported resource, got %d", len(imported.ImportedResources))
+	}
+
+	// Destroy
+	acc.apply(refreshed, nil)
+	if store.Exists("app/api") {
+		t.Error("expected the secret to be removed from the store")
+	}
+
+	// A secret deleted outside the provider leaves state on refresh
+	if acc.refresh(refreshed) != nil {
+		t.Error("expected a deleted secret to be removed from state")
+	}
+}
+
+func TestAccSecretResource_KeepOnRemove(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+
+	state := acc.apply(nil, map[string]tftypes.Value{
+		"path":             tftypes.NewValue(tftypes.String, "app/kept"),
+		"value_wo":         tftypes.NewValue(tftypes.String, "value"),
+		"delete_on_remove": tftypes.NewValue(tftypes.Bool, false),
+	})
+	acc.apply(state, nil)
+
+	if !store.Exists("app/kept") {
+		t.Error("expected delete_on_remove = false to keep the secret")
+	}
+}
+
+func TestAccProvider_ReadOnly(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret"})
+	acc := newAccProvider(t, store, map[string]tftypes.Value{
+		"read_only": tftypes.NewValue(tftypes.Bool, true),
+	})
+
+	result, _ := acc.openEphemeral("gopass_secret", map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	if got := stringAttr(t, result, "value"); got != "s3cret" {
+		t.Errorf("unexpected value %q", got)
+	}
+
+	schema := acc.schemas.ResourceSchemas["gopass_secret"]
+	config := acc.object(schema, map[string]tftypes.Value{
+		"path":     tftypes.NewValue(tftypes.String, "app/new"),
+		"value_wo": tftypes.NewValue(tftypes.String, "value"),
+	})
+	proposed := acc.object(schema, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/new"),
+	})
+	plan, err := acc.server.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         "gopass_secret",
+		PriorState:       acc.encode(schema, tftypes.NewValue(schema.ValueType(), nil)),
+		ProposedNewState: acc.encode(schema, proposed),
+		Config:           acc.encode(schema, config),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	applied, err := acc.server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       "gopass_secret",
+		PriorState:     acc.encode(schema, tftypes.NewValue(schema.ValueType(), nil)),
+		PlannedState:   plan.PlannedState,
+		Config:         acc.encode(schema, config),
+		PlannedPrivate: plan.PlannedPrivate,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasErrorSummary(append(plan.Diagnostics, applied.Diagnostics...), "Provider is read-only") {
+		t.Error("expected a read-only provider to refuse the write")
+	}
+	if store.Exists("app/new") {
+		t.Error("expected nothing to be written")
+	}
+}
+
+func TestAccSecretFunction(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret\nusername: admin"})
+	acc := newAccProvider(t, store, nil)
+	ctx := context.Background()
+
+	call := func(path string) *tfprotov6.CallFunctionResponse {
+		t.Helper()
+		arg, err := tfprotov6.NewDynamicValue(tftypes.String, tftypes.NewValue(tftypes.String, path))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp, err := acc.server.CallFunction(ctx, &tfprotov6.CallFunctionRequest{
+			Name:      "secret",
+			Arguments: []*tfprotov6.DynamicValue{&arg},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	resp := call("app/db")
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Text)
+	}
+	value, err := resp.Result.Unmarshal(tftypes.String)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got string
+	if err := value.As(&got); err != nil || got != "s3cret" {
+		t.Errorf("unexpected value %q (%v)", got, err)
+	}
+
+	if resp := call("app/missing"); resp.Error == nil || !strings.Contains(resp.Error.Text, "Secret not found") {
+		t.Errorf("expected a not found error, got %+v", resp.Error)
+	}
+}
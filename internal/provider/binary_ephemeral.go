@@ -0,0 +1,171 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &BinaryEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &BinaryEphemeralResource{}
+)
+
+// BinaryEphemeralResource reads a binary secret and returns it base64 encoded.
+type BinaryEphemeralResource struct {
+	client *GopassClient
+}
+
+// BinaryModel describes the data model.
+type BinaryModel struct {
+	Path          types.String `tfsdk:"path"`
+	Policy        types.String `tfsdk:"policy"`
+	ContentBase64 types.String `tfsdk:"content_base64"`
+	Size          types.Int64  `tfsdk:"size"`
+}
+
+// NewBinaryEphemeralResource creates a new instance.
+func NewBinaryEphemeralResource() ephemeral.EphemeralResource {
+	return &BinaryEphemeralResource{}
+}
+
+func (r *BinaryEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_binary"
+}
+
+func (r *BinaryEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads a binary secret, such as a TLS key or a keystore, and returns it base64 encoded.",
+		MarkdownDescription: `
+Reads a binary secret, such as a TLS key or a keystore, and returns it base64
+encoded, ready for attributes that take base64 content or for
+` + "`base64decode()`" + `.
+
+Entries written with ` + "`gopass fscopy`" + ` or ` + "`gopass binary cp`" + ` are
+already stored base64 encoded and are returned as they are, without their
+headers. Any other entry is encoded as a whole, including its key-value lines.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_binary" "keystore" {
+  path = "services/api/keystore.p12"
+}
+
+provider "example" {
+  keystore_base64 = ephemeral.gopass_binary.keystore.content_base64
+}
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret in the gopass store (e.g., 'services/api/keystore.p12').",
+				MarkdownDescription: "Path to the secret in the gopass store (e.g., `services/api/keystore.p12`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"content_base64": schema.StringAttribute{
+				Description: "The binary content of the secret, standard base64 encoded without line breaks.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"size": schema.Int64Attribute{
+				Description: "Size of the decoded content in bytes.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *BinaryEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *BinaryEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data BinaryModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "ephemeral.gopass_binary", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	encoded, err := r.client.GetSecretBase64(ctx, secretPath)
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	// Hand Terraform a copy we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.ContentBase64 = types.StringValue(buffers.protect(encoded))
+	data.Size = types.Int64Value(decodedSize(encoded))
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Read binary secret from gopass", map[string]interface{}{
+		"path":  r.client.logPath(secretPath),
+		"bytes": data.Size.ValueInt64(),
+	})
+}
+
+// Close wipes the content buffer and releases the store reference taken by Open.
+func (r *BinaryEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestBinaryEphemeralResource_Open(t *testing.T) {
+	client, data := newBinaryTestClient(t, 1000)
+
+	resp := openConfiguredEphemeral(t, &BinaryEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "certs/bundle"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var result BinaryModel
+	resp.Result.Get(context.Background(), &result)
+	if result.ContentBase64.ValueString() != base64.StdEncoding.EncodeToString(data) {
+		t.Error("encoded content does not match")
+	}
+	if result.Size.ValueInt64() != 1000 {
+		t.Errorf("expected 1000 bytes, got %d", result.Size.ValueInt64())
+	}
+}
+
+func TestBinaryEphemeralResource_Open_NotFound(t *testing.T) {
+	client, _ := newBinaryTestClient(t, 10)
+
+	resp := openConfiguredEphemeral(t, &BinaryEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "certs/missing"),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error for a missing secret")
+	}
+}
+
+func TestBinaryEphemeralResource_Open_ChecksumOnly(t *testing.T) {
+	client, _ := newBinaryTestClient(t, 10)
+	client.checksumOnly = true
+
+	resp := openConfiguredEphemeral(t, &BinaryEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "certs/bundle"),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected checksum-only mode to refuse the read")
+	}
+}
+
+func TestBinaryEphemeralResource_Open_Policy(t *testing.T) {
+	resp := openConfiguredEphemeral(t, &BinaryEphemeralResource{client: newPolicyTestClient()}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/admin/root"),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected the policy to deny the read")
+	}
+	if detail := resp.Diagnostics.Errors()[0].Detail(); strings.Contains(detail, "value-of-") {
+		t.Errorf("expected no secret content in the error, got %q", detail)
+	}
+}
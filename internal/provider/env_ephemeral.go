@@ -5,16 +5,26 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
-// Ensure implementation satisfies interface.
-var _ ephemeral.EphemeralResource = &EnvEphemeralResource{}
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &EnvEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &EnvEphemeralResource{}
+)
 
 // EnvEphemeralResource reads a subtree from gopass as environment variables.
 type EnvEphemeralResource struct {
@@ -23,8 +33,13 @@ type EnvEphemeralResource struct {
 
 // EnvModel describes the data model.
 type EnvModel struct {
-	Path   types.String `tfsdk:"path"`
-	Values types.Map    `tfsdk:"values"`
+	Path      types.String `tfsdk:"path"`
+	Recursive types.Bool   `tfsdk:"recursive"`
+	Include   []string     `tfsdk:"include"`
+	Exclude   []string     `tfsdk:"exclude"`
+	Sanitize  types.Bool   `tfsdk:"sanitize_keys"`
+	Values    types.Map    `tfsdk:"values"`
+	Policy    types.String `tfsdk:"policy"`
 }
 
 // NewEnvEphemeralResource creates a new instance.
@@ -66,11 +81,31 @@ provider "scaleway" {
 }
 ` + "```" + `
 
+## Filtering
+
+` + "`include`" + ` and ` + "`exclude`" + ` select secrets by globs over their keys before
+anything is decrypted. A secret is read if it matches any ` + "`include`" + ` glob (or
+there are none) and no ` + "`exclude`" + ` glob:
+
+` + "```hcl" + `
+ephemeral "gopass_env" "tokens" {
+  path    = "env/ci"
+  include = ["*_TOKEN"]
+  exclude = ["README"]
+}
+` + "```" + `
+
+Globs use the syntax of ` + "`gopass_search`" + `: ` + "`*`" + ` does not match ` + "`/`" + `, so with
+` + "`recursive = true`" + ` use ` + "`**/*_TOKEN`" + ` to match at any depth.
+
 ## Notes
 
-- Only immediate children of the path are included (not recursive)
+- Only immediate children of the path are included, unless ` + "`recursive = true`" + `: then every
+  secret below the path is, keyed by its path relative to it (e.g. ` + "`db/primary/password`" + `)
 - Each secret's first line is used as the value (gopass password convention)
-- Secret names become map keys as-is (typically UPPER_SNAKE_CASE for env vars)
+- Secret names become map keys as-is (typically UPPER_SNAKE_CASE for env vars), unless
+  ` + "`sanitize_keys = true`" + ` turns them into valid environment variable names, e.g.
+  ` + "`db/api-key`" + ` into ` + "`DB_API_KEY`" + `
 - No subprocess spawning - direct library access for better performance
 `,
 		Attributes: map[string]schema.Attribute{
@@ -78,6 +113,46 @@ provider "scaleway" {
 				Description:         "Path prefix in the gopass store (e.g., 'env/terraform/scaleway/istr').",
 				MarkdownDescription: "Path prefix in the gopass store (e.g., `env/terraform/scaleway/istr`).",
 				Required:            true,
+				Validators:          []validator.String{secretPathValidator{prefix: true}},
+			},
+			"recursive": schema.BoolAttribute{
+				Description: "Read every secret below the path instead of only its immediate children. Keys are " +
+					"then the paths relative to path, e.g. 'db/primary/password'. Default: false.",
+				MarkdownDescription: "Read every secret below the path instead of only its immediate children. Keys are " +
+					"then the paths relative to `path`, e.g. `db/primary/password`. Default: `false`.",
+				Optional: true,
+			},
+			"include": schema.ListAttribute{
+				Description: "Globs over the keys of the secrets to read, e.g. '*_TOKEN'. " +
+					"Secrets matching none of them are not decrypted. Default: every secret.",
+				MarkdownDescription: "Globs over the keys of the secrets to read, e.g. `*_TOKEN`. " +
+					"Secrets matching none of them are not decrypted. Default: every secret.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"exclude": schema.ListAttribute{
+				Description: "Globs over the keys of secrets not to read, e.g. 'README'. " +
+					"Takes precedence over include.",
+				MarkdownDescription: "Globs over the keys of secrets not to read, e.g. `README`. " +
+					"Takes precedence over `include`.",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"sanitize_keys": schema.BoolAttribute{
+				Description: "Turn the keys into valid environment variable names: uppercased, '-', '.' and '/' " +
+					"replaced by '_', other invalid characters dropped and a leading digit prefixed with '_'. " +
+					"Keys that end up equal fail the read. Default: false.",
+				MarkdownDescription: "Turn the keys into valid environment variable names: uppercased, `-`, `.` and `/` " +
+					"replaced by `_`, other invalid characters dropped and a leading digit prefixed with `_`. " +
+					"Keys that end up equal fail the read. Default: `false`.",
+				Optional: true,
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"If any secret below the path is outside the policy, the whole read fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"If any secret below the path is outside the policy, the whole read fails.",
+				Optional: true,
 			},
 			"values": schema.MapAttribute{
 				Description:         "Map of secret names to their values.",
@@ -108,6 +183,10 @@ func (r *EnvEphemeralResource) Configure(ctx context.Context, req ephemeral.Conf
 }
 
 func (r *EnvEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
 	var data EnvModel
 
 	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
@@ -115,39 +194,148 @@ func (r *EnvEphemeralResource) Open(ctx context.Context, req ephemeral.OpenReque
 		return
 	}
 
+	filter := r.filter(&data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	basePath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "ephemeral.gopass_env", basePath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
 
+	recursive := data.Recursive.ValueBool()
 	tflog.Debug(ctx, "Reading env secrets from gopass", map[string]interface{}{
-		"path": basePath,
+		"path":      r.client.logPath(basePath),
+		"recursive": recursive,
 	})
 
 	// Use native gopass library
-	values, err := r.client.GetEnvSecrets(ctx, basePath)
+	values, err := r.client.GetFilteredEnvSecrets(ctx, basePath, recursive, filter)
+	var partial *PartialResultError
+	if errors.As(err, &partial) {
+		resp.Diagnostics.AddWarning(
+			"Some secrets timed out",
+			fmt.Sprintf("Skipped %d secret(s) under path %q that could not be read within the read timeout: %s. "+
+				"The remaining secrets are available; increase read_timeout in the provider configuration "+
+				"if these reads are expected to take longer.",
+				len(partial.TimedOut), basePath, strings.Join(partial.TimedOut, ", ")),
+		)
+		err = nil
+	}
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Failed to read secrets",
-			fmt.Sprintf("Could not read secrets under path %q: %s", basePath, err.Error()),
+			errorSummary(err, "Failed to read secrets"),
+			errorDetail(fmt.Sprintf("Could not read secrets under path %q: %s", basePath, err.Error()), err),
 		)
 		return
 	}
 
-	if len(values) == 0 {
+	if len(values) == 0 && partial == nil {
+		scope := "immediate child secrets"
+		if recursive {
+			scope = "secrets"
+		}
+		if filter != nil {
+			scope += " matching include and exclude"
+		}
 		resp.Diagnostics.AddWarning(
 			"No secrets found",
-			fmt.Sprintf("No immediate child secrets found under path %q", basePath),
+			fmt.Sprintf("No %s found under path %q", scope, basePath),
 		)
 	}
 
+	var empty []string
+	byPath := make(map[string]string, len(values))
+	for key, value := range values {
+		fullPath := joinPath(basePath, key)
+		byPath[fullPath] = value
+		if value == "" {
+			empty = append(empty, fullPath)
+		}
+	}
+	resp.Diagnostics.Append(nonUTF8Diagnostics(byPath)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	sort.Strings(empty)
+	resp.Diagnostics.Append(r.client.emptyValueDiagnostics(empty)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Sanitize.ValueBool() {
+		values, err = sanitizeEnvKeys(values)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("sanitize_keys"), "Conflicting environment variable names",
+				fmt.Sprintf("Could not sanitize the keys of the secrets under path %q: %s. Rename the secrets, "+
+					"or leave them out with exclude.", basePath, err.Error()))
+			return
+		}
+	}
+
 	// Convert to types.Map
 	// types.MapValueFrom with types.StringType and map[string]string is guaranteed to succeed
+	// Hand Terraform copies we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	for key, value := range values {
+		values[key] = buffers.protect(value)
+	}
 	mapValue, _ := types.MapValueFrom(ctx, types.StringType, values)
 	data.Values = mapValue
 
 	// Set result - NEVER written to state
 	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
 
 	tflog.Debug(ctx, "Successfully read env secrets from gopass", map[string]interface{}{
-		"path":  basePath,
+		"path":  r.client.logPath(basePath),
 		"count": len(values),
 	})
 }
+
+// filter compiles the include and exclude globs, reporting invalid ones on
+// their list elements. It returns nil without globs.
+func (r *EnvEphemeralResource) filter(data *EnvModel, diags *diag.Diagnostics) *envFilter {
+	if len(data.Include) == 0 && len(data.Exclude) == 0 {
+		return nil
+	}
+	filter := &envFilter{}
+	for _, list := range []struct {
+		name  string
+		globs []string
+		res   *[]*regexp.Regexp
+	}{
+		{name: "include", globs: data.Include, res: &filter.include},
+		{name: "exclude", globs: data.Exclude, res: &filter.exclude},
+	} {
+		for i, glob := range list.globs {
+			re, err := compileEnvGlob(glob)
+			if err != nil {
+				diags.AddAttributeError(path.Root(list.name).AtListIndex(i), "Invalid glob", err.Error())
+				continue
+			}
+			*list.res = append(*list.res, re)
+		}
+	}
+	return filter
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *EnvEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
@@ -5,7 +5,9 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/gopasspw/gopass/pkg/gopass/secrets"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
@@ -118,106 +120,126 @@ func TestSecretEphemeralResource_Configure_InvalidType(t *testing.T) {
 }
 
 func TestSecretEphemeralResource_Open(t *testing.T) {
-	r := &SecretEphemeralResource{}
 	mockStore := newMockStore()
 	client := NewGopassClient("")
 	client.store = mockStore
-	r.client = client
 
 	// Add a test secret
 	secret := secrets.New()
 	secret.SetPassword("test-password")
 	mockStore.secrets["test/secret"] = secret
 
-	ctx := context.Background()
-	schemaReq := ephemeral.SchemaRequest{}
-	schemaResp := &ephemeral.SchemaResponse{}
-	r.Schema(ctx, schemaReq, schemaResp)
+	resp := openSecretEphemeral(t, client, "test/secret")
 
-	configValue := tftypes.NewValue(tftypes.Object{
-		AttributeTypes: map[string]tftypes.Type{
-			"path":  tftypes.String,
-			"value": tftypes.String,
-		},
-	}, map[string]tftypes.Value{
-		"path":  tftypes.NewValue(tftypes.String, "test/secret"),
-		"value": tftypes.NewValue(tftypes.String, nil),
-	})
+	if resp.Diagnostics.HasError() {
+		t.Errorf("unexpected error: %v", resp.Diagnostics)
+	}
+}
 
-	// Initialize Result properly with the schema
-	resultRaw := tftypes.NewValue(tftypes.Object{
-		AttributeTypes: map[string]tftypes.Type{
-			"path":  tftypes.String,
-			"value": tftypes.String,
-		},
-	}, nil)
+func TestSecretEphemeralResource_Open_NotFound(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = newMockStore()
 
-	req := ephemeral.OpenRequest{
-		Config: tfsdk.Config{
-			Schema: schemaResp.Schema,
-			Raw:    configValue,
-		},
+	resp := openSecretEphemeral(t, client, "nonexistent")
+
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected error for non-existent secret")
 	}
-	resp := &ephemeral.OpenResponse{
-		Result: tfsdk.EphemeralResultData{
-			Schema: schemaResp.Schema,
-			Raw:    resultRaw,
-		},
+}
+
+func TestSecretEphemeralResource_Open_BasicAuthHeader(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"ci/registry": "open sesame\nuser: Aladdin",
+		"ci/token":    "t0ken",
+		"ci/colon":    "t0ken\nusername: c:i",
+	}))
+
+	tests := map[string]struct {
+		path     string
+		want     string
+		warnings int
+	}{
+		"username":    {path: "ci/registry", want: "Basic QWxhZGRpbjpvcGVuIHNlc2FtZQ=="},
+		"no username": {path: "ci/token"},
+		"colon":       {path: "ci/colon", warnings: 1},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := openSecretEphemeral(t, client, tt.path)
+			if resp.Diagnostics.HasError() || resp.Diagnostics.WarningsCount() != tt.warnings {
+				t.Fatalf("expected %d warning(s), got %v", tt.warnings, resp.Diagnostics)
+			}
+
+			var data SecretModel
+			resp.Result.Get(context.Background(), &data)
+			if tt.want == "" && !data.BasicAuthHeader.IsNull() {
+				t.Errorf("expected no header, got %q", data.BasicAuthHeader.ValueString())
+			}
+			if tt.want != "" && data.BasicAuthHeader.ValueString() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, data.BasicAuthHeader.ValueString())
+			}
+		})
 	}
+}
 
-	r.Open(ctx, req, resp)
+func TestSecretEphemeralResource_Open_URLEncoded(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"db/admin": "p@ss:w/rd\nusername: admin@corp",
+		"db/token": "t0ken+1",
+	}))
 
+	resp := openSecretEphemeral(t, client, "db/admin")
 	if resp.Diagnostics.HasError() {
-		t.Errorf("unexpected error: %v", resp.Diagnostics)
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	var data SecretModel
+	resp.Result.Get(context.Background(), &data)
+	if data.ValueURL.ValueString() != "p%40ss%3Aw%2Frd" {
+		t.Errorf("unexpected value_urlencoded %q", data.ValueURL.ValueString())
+	}
+	if data.Username.ValueString() != "admin@corp" || data.UsernameURL.ValueString() != "admin%40corp" {
+		t.Errorf("unexpected username %q, username_urlencoded %q", data.Username.ValueString(), data.UsernameURL.ValueString())
 	}
-}
 
-func TestSecretEphemeralResource_Open_NotFound(t *testing.T) {
-	r := &SecretEphemeralResource{}
-	mockStore := newMockStore()
-	client := NewGopassClient("")
-	client.store = mockStore
-	r.client = client
+	resp = openSecretEphemeral(t, client, "db/token")
+	resp.Result.Get(context.Background(), &data)
+	if data.ValueURL.ValueString() != "t0ken%2B1" || !data.Username.IsNull() || !data.UsernameURL.IsNull() {
+		t.Errorf("expected only value_urlencoded, got %q, %v, %v", data.ValueURL.ValueString(), data.Username, data.UsernameURL)
+	}
+}
 
-	ctx := context.Background()
-	schemaReq := ephemeral.SchemaRequest{}
-	schemaResp := &ephemeral.SchemaResponse{}
-	r.Schema(ctx, schemaReq, schemaResp)
+func TestSecretEphemeralResource_Open_Revision(t *testing.T) {
+	store := NewMemoryStore(map[string]string{"app/db": "first"})
+	store.Set(context.Background(), "app/db", newPasswordSecret("second"))
+	r := &SecretEphemeralResource{client: NewGopassClientWithStore(store)}
 
-	configValue := tftypes.NewValue(tftypes.Object{
-		AttributeTypes: map[string]tftypes.Type{
-			"path":  tftypes.String,
-			"value": tftypes.String,
-		},
-	}, map[string]tftypes.Value{
-		"path":  tftypes.NewValue(tftypes.String, "nonexistent"),
-		"value": tftypes.NewValue(tftypes.String, nil),
+	resp := openConfiguredEphemeral(t, r, map[string]tftypes.Value{
+		"path":     tftypes.NewValue(tftypes.String, "app/db"),
+		"revision": tftypes.NewValue(tftypes.String, "1"),
 	})
-
-	resultRaw := tftypes.NewValue(tftypes.Object{
-		AttributeTypes: map[string]tftypes.Type{
-			"path":  tftypes.String,
-			"value": tftypes.String,
-		},
-	}, nil)
-
-	req := ephemeral.OpenRequest{
-		Config: tfsdk.Config{
-			Schema: schemaResp.Schema,
-			Raw:    configValue,
-		},
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
 	}
-	resp := &ephemeral.OpenResponse{
-		Result: tfsdk.EphemeralResultData{
-			Schema: schemaResp.Schema,
-			Raw:    resultRaw,
-		},
+	var data SecretModel
+	resp.Result.Get(context.Background(), &data)
+	if data.Value.ValueString() != "first" || data.Revision.ValueString() != "1" {
+		t.Errorf("expected the first revision, got %q", data.Value.ValueString())
 	}
 
-	r.Open(ctx, req, resp)
+	resp = openConfiguredEphemeral(t, r, map[string]tftypes.Value{
+		"path":     tftypes.NewValue(tftypes.String, "app/db"),
+		"revision": tftypes.NewValue(tftypes.String, "3"),
+	})
+	if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "Secret not found" {
+		t.Errorf("expected an unknown revision not to be found, got %v", resp.Diagnostics)
+	}
 
-	if !resp.Diagnostics.HasError() {
-		t.Error("expected error for non-existent secret")
+	resp = openConfiguredEphemeral(t, r, map[string]tftypes.Value{
+		"path":     tftypes.NewValue(tftypes.String, "app/db"),
+		"revision": tftypes.NewValue(tftypes.String, ""),
+	})
+	if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "Invalid revision" {
+		t.Errorf("expected an empty revision to be refused, got %v", resp.Diagnostics)
 	}
 }
 
@@ -348,18 +370,33 @@ func TestEnvEphemeralResource_Open(t *testing.T) {
 
 	configValue := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.String,
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, map[string]tftypes.Value{
-		"path":   tftypes.NewValue(tftypes.String, "env/test"),
-		"values": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"path":          tftypes.NewValue(tftypes.String, "env/test"),
+		"recursive":     tftypes.NewValue(tftypes.Bool, nil),
+		"include":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"exclude":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"sanitize_keys": tftypes.NewValue(tftypes.Bool, nil),
+		"values":        tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"policy":        tftypes.NewValue(tftypes.String, nil),
 	})
 
 	resultRaw := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.String,
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, nil)
 
@@ -397,18 +434,33 @@ func TestEnvEphemeralResource_Open_Empty(t *testing.T) {
 
 	configValue := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.String,
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, map[string]tftypes.Value{
-		"path":   tftypes.NewValue(tftypes.String, "empty/path"),
-		"values": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"path":          tftypes.NewValue(tftypes.String, "empty/path"),
+		"recursive":     tftypes.NewValue(tftypes.Bool, nil),
+		"include":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"exclude":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"sanitize_keys": tftypes.NewValue(tftypes.Bool, nil),
+		"values":        tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"policy":        tftypes.NewValue(tftypes.String, nil),
 	})
 
 	resultRaw := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.String,
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, nil)
 
@@ -449,18 +501,33 @@ func TestEnvEphemeralResource_Open_GetEnvSecretsError(t *testing.T) {
 
 	configValue := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.String,
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, map[string]tftypes.Value{
-		"path":   tftypes.NewValue(tftypes.String, "env/test"),
-		"values": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"path":          tftypes.NewValue(tftypes.String, "env/test"),
+		"recursive":     tftypes.NewValue(tftypes.Bool, nil),
+		"include":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"exclude":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"sanitize_keys": tftypes.NewValue(tftypes.Bool, nil),
+		"values":        tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"policy":        tftypes.NewValue(tftypes.String, nil),
 	})
 
 	resultRaw := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.String,
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, nil)
 
@@ -499,18 +566,33 @@ func TestEnvEphemeralResource_Open_ConfigGetError(t *testing.T) {
 	// Use a wrong type in the raw value that doesn't match the schema
 	wrongConfigValue := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.Number, // Wrong type - schema expects String
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.Number, // Wrong type - schema expects String
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, map[string]tftypes.Value{
-		"path":   tftypes.NewValue(tftypes.Number, 123), // Wrong type
-		"values": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"path":          tftypes.NewValue(tftypes.Number, 123), // Wrong type
+		"recursive":     tftypes.NewValue(tftypes.Bool, nil),
+		"include":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"exclude":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"sanitize_keys": tftypes.NewValue(tftypes.Bool, nil),
+		"values":        tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		"policy":        tftypes.NewValue(tftypes.String, nil),
 	})
 
 	resultRaw := tftypes.NewValue(tftypes.Object{
 		AttributeTypes: map[string]tftypes.Type{
-			"path":   tftypes.String,
-			"values": tftypes.Map{ElementType: tftypes.String},
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
 		},
 	}, nil)
 
@@ -534,3 +616,205 @@ func TestEnvEphemeralResource_Open_ConfigGetError(t *testing.T) {
 		t.Error("expected error for Config.Get failure due to type mismatch")
 	}
 }
+
+func TestEnvEphemeralResource_Open_PartialTimeout(t *testing.T) {
+	r := &EnvEphemeralResource{}
+	store := &mockSlowPathStore{mockStore: newMockStore(), slow: map[string]bool{"env/test/SLOW": true}}
+	for _, name := range []string{"env/test/FAST", "env/test/SLOW"} {
+		secret := secrets.New()
+		secret.SetPassword("value")
+		store.secrets[name] = secret
+	}
+	client := NewGopassClient("")
+	client.store = store
+	client.timeouts.Read = 20 * time.Millisecond
+	r.client = client
+
+	ctx := context.Background()
+	schemaResp := &ephemeral.SchemaResponse{}
+	r.Schema(ctx, ephemeral.SchemaRequest{}, schemaResp)
+
+	objectType := tftypes.Object{
+		AttributeTypes: map[string]tftypes.Type{
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
+		},
+	}
+	req := ephemeral.OpenRequest{
+		Config: tfsdk.Config{
+			Schema: schemaResp.Schema,
+			Raw: tftypes.NewValue(objectType, map[string]tftypes.Value{
+				"path":          tftypes.NewValue(tftypes.String, "env/test"),
+				"recursive":     tftypes.NewValue(tftypes.Bool, nil),
+				"include":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+				"exclude":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+				"sanitize_keys": tftypes.NewValue(tftypes.Bool, nil),
+				"values":        tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+				"policy":        tftypes.NewValue(tftypes.String, nil),
+			}),
+		},
+	}
+	resp := &ephemeral.OpenResponse{
+		Result: tfsdk.EphemeralResultData{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(objectType, nil),
+		},
+	}
+
+	r.Open(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("expected partial results without error, got %v", resp.Diagnostics)
+	}
+	if resp.Diagnostics.WarningsCount() != 1 {
+		t.Fatalf("expected one timeout warning, got %v", resp.Diagnostics)
+	}
+	if summary := resp.Diagnostics.Warnings()[0].Summary(); summary != "Some secrets timed out" {
+		t.Errorf("unexpected warning %q", summary)
+	}
+
+	var data EnvModel
+	resp.Diagnostics.Append(resp.Result.Get(ctx, &data)...)
+	if len(data.Values.Elements()) != 1 {
+		t.Errorf("expected the fast secret in the result, got %v", data.Values)
+	}
+}
+
+func TestSecretEphemeralResource_Open_GitSyncWarning(t *testing.T) {
+	client, _ := newSyncTestClient(errors.New("Could not resolve host: git.example.com"))
+
+	resp := openSecretEphemeral(t, client, "app/db")
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("expected the secret to be read from local contents, got %v", resp.Diagnostics)
+	}
+	if resp.Diagnostics.WarningsCount() != 1 || resp.Diagnostics.Warnings()[0].Summary() != "Git sync failed" {
+		t.Errorf("expected a git sync warning, got %v", resp.Diagnostics)
+	}
+}
+
+// openSecretEphemeral opens a gopass_secret ephemeral resource for path.
+func openSecretEphemeral(t *testing.T, client *GopassClient, path string) *ephemeral.OpenResponse {
+	t.Helper()
+	return openSecretEphemeralWith(t, client, path, ephemeral.OpenClientCapabilities{})
+}
+
+// openSecretEphemeralWith opens a gopass_secret ephemeral resource for path
+// on behalf of a Terraform client with the given capabilities.
+func openSecretEphemeralWith(t *testing.T, client *GopassClient, path string, caps ephemeral.OpenClientCapabilities) *ephemeral.OpenResponse {
+	t.Helper()
+
+	r := &SecretEphemeralResource{client: client}
+	ctx := context.Background()
+	schemaResp := &ephemeral.SchemaResponse{}
+	r.Schema(ctx, ephemeral.SchemaRequest{}, schemaResp)
+
+	// Every attribute but path is left unset
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	config := map[string]tftypes.Value{}
+	for name, attrType := range objectType.AttributeTypes {
+		config[name] = tftypes.NewValue(attrType, nil)
+	}
+	config["path"] = tftypes.NewValue(tftypes.String, path)
+
+	req := ephemeral.OpenRequest{
+		ClientCapabilities: caps,
+		Config: tfsdk.Config{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(objectType, config),
+		},
+	}
+	resp := &ephemeral.OpenResponse{
+		Result: tfsdk.EphemeralResultData{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(objectType, nil),
+		},
+	}
+
+	r.Open(ctx, req, resp)
+	return resp
+}
+
+// openEnvEphemeral opens a gopass_env ephemeral resource for path.
+func openEnvEphemeral(t *testing.T, client *GopassClient, path string) *ephemeral.OpenResponse {
+	t.Helper()
+	return openEnvEphemeralWith(t, client, path, ephemeral.OpenClientCapabilities{})
+}
+
+// openEnvEphemeralWith opens a gopass_env ephemeral resource for path on
+// behalf of a Terraform client with the given capabilities.
+func openEnvEphemeralWith(t *testing.T, client *GopassClient, path string, caps ephemeral.OpenClientCapabilities) *ephemeral.OpenResponse {
+	t.Helper()
+
+	r := &EnvEphemeralResource{client: client}
+	ctx := context.Background()
+	schemaResp := &ephemeral.SchemaResponse{}
+	r.Schema(ctx, ephemeral.SchemaRequest{}, schemaResp)
+
+	objectType := tftypes.Object{
+		AttributeTypes: map[string]tftypes.Type{
+			"path":          tftypes.String,
+			"recursive":     tftypes.Bool,
+			"include":       tftypes.List{ElementType: tftypes.String},
+			"exclude":       tftypes.List{ElementType: tftypes.String},
+			"sanitize_keys": tftypes.Bool,
+			"values":        tftypes.Map{ElementType: tftypes.String},
+			"policy":        tftypes.String,
+		},
+	}
+	req := ephemeral.OpenRequest{
+		ClientCapabilities: caps,
+		Config: tfsdk.Config{
+			Schema: schemaResp.Schema,
+			Raw: tftypes.NewValue(objectType, map[string]tftypes.Value{
+				"path":          tftypes.NewValue(tftypes.String, path),
+				"recursive":     tftypes.NewValue(tftypes.Bool, nil),
+				"include":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+				"exclude":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+				"sanitize_keys": tftypes.NewValue(tftypes.Bool, nil),
+				"values":        tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+				"policy":        tftypes.NewValue(tftypes.String, nil),
+			}),
+		},
+	}
+	resp := &ephemeral.OpenResponse{
+		Result: tfsdk.EphemeralResultData{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(objectType, nil),
+		},
+	}
+
+	r.Open(ctx, req, resp)
+	return resp
+}
+
+func TestEnvEphemeralResource_Open_Recursive(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"env/prod/REGION":              "eu-west-1",
+		"env/prod/db/primary/password": "s3cret",
+	}))
+
+	resp := openConfiguredEphemeral(t, &EnvEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path":          tftypes.NewValue(tftypes.String, "env/prod"),
+		"recursive":     tftypes.NewValue(tftypes.Bool, true),
+		"include":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"exclude":       tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+		"sanitize_keys": tftypes.NewValue(tftypes.Bool, nil),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data EnvModel
+	resp.Result.Get(context.Background(), &data)
+	var values map[string]string
+	data.Values.ElementsAs(context.Background(), &values, false)
+	if len(values) != 2 || values["REGION"] != "eu-west-1" || values["db/primary/password"] != "s3cret" {
+		t.Errorf("unexpected values %v", values)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+// The configurations in examples/ are checked against the provider's schemas
+// with every go test, so a renamed or removed argument breaks the build rather
+// than the documentation. Where tofu is installed, each example that needs no
+// other provider is also validated and planned against the mock backend.
+
+const examplesDir = "../../examples"
+
+// exampleBlock is a provider, resource, data or ephemeral block of an example.
+type exampleBlock struct {
+	file, kind, typeName string
+	line                 int
+	attributes           []string
+	blocks               []string
+}
+
+func (b exampleBlock) String() string {
+	return fmt.Sprintf("%s:%d: %s %q", b.file, b.line, b.kind, b.typeName)
+}
+
+var exampleBlockHeader = regexp.MustCompile(`^(provider|resource|data|ephemeral)\s+"([^"]+)"(?:\s+"[^"]*")?\s*\{`)
+var exampleArgument = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*(=|\{)`)
+
+// stripHCLComment returns line without a trailing # or // comment, and the
+// change in brace depth on what remains. Braces and comment markers inside
+// strings are ignored.
+func stripHCLComment(line string) (string, int) {
+	depth, inString := 0, false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '#', c == '/' && strings.HasPrefix(line[i:], "//"):
+			return line[:i], depth
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		}
+	}
+	return line, depth
+}
+
+// parseExampleBlocks returns the gopass blocks of an HCL file with their
+// top-level arguments and nested blocks. It understands the subset of HCL the
+// examples use, which is all the schema check needs.
+func parseExampleBlocks(t *testing.T, file string) []exampleBlock {
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var blocks []exampleBlock
+	var current *exampleBlock
+	depth := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		code, change := stripHCLComment(line)
+		code = strings.TrimSpace(code)
+
+		switch {
+		case depth == 0:
+			if m := exampleBlockHeader.FindStringSubmatch(code); m != nil &&
+				(m[2] == "gopass" || strings.HasPrefix(m[2], "gopass_")) {
+				blocks = append(blocks, exampleBlock{file: file, kind: m[1], typeName: m[2], line: i + 1})
+				current = &blocks[len(blocks)-1]
+			}
+		case depth == 1 && current != nil:
+			if m := exampleArgument.FindStringSubmatch(code); m != nil {
+				if m[2] == "=" {
+					current.attributes = append(current.attributes, m[1])
+				} else {
+					current.blocks = append(current.blocks, m[1])
+				}
+			}
+		}
+
+		depth += change
+		if depth == 0 {
+			current = nil
+		}
+	}
+	return blocks
+}
+
+// exampleFiles returns the .tf files below examples/.
+func exampleFiles(t *testing.T) []string {
+	t.Helper()
+
+	var files []string
+	err := filepath.WalkDir(examplesDir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, ".tf") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// providerSchemas returns the schemas the provider serves.
+func providerSchemas(t *testing.T) *tfprotov6.GetProviderSchemaResponse {
+	t.Helper()
+
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	schemas, err := server.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return schemas
+}
+
+// Meta-arguments OpenTofu accepts in every block of a kind.
+var (
+	exampleMetaArguments = map[string][]string{
+		"provider":  {"alias"},
+		"resource":  {"count", "for_each", "depends_on", "provider"},
+		"data":      {"count", "for_each", "depends_on", "provider"},
+		"ephemeral": {"count", "for_each", "depends_on", "provider"},
+	}
+	exampleMetaBlocks = map[string][]string{
+		"resource":  {"lifecycle", "provisioner", "connection"},
+		"data":      {"lifecycle"},
+		"ephemeral": {"lifecycle"},
+	}
+)
+
+// checkExampleBlock returns what is wrong with block according to schema.
+func checkExampleBlock(block exampleBlock, schema *tfprotov6.Schema) []string {
+	var problems []string
+	attributes := make(map[string]*tfprotov6.SchemaAttribute)
+	for _, a := range schema.Block.Attributes {
+		attributes[a.Name] = a
+	}
+	nested := make(map[string]bool)
+	for _, b := range schema.Block.BlockTypes {
+		nested[b.TypeName] = true
+	}
+
+	for _, name := range block.attributes {
+		a, ok := attributes[name]
+		switch {
+		case slices.Contains(exampleMetaArguments[block.kind], name):
+		case !ok:
+			problems = append(problems, fmt.Sprintf("unknown argument %q", name))
+		case a.Computed && !a.Optional && !a.Required:
+			problems = append(problems, fmt.Sprintf("argument %q is read-only", name))
+		case a.Deprecated:
+			problems = append(problems, fmt.Sprintf("argument %q is deprecated", name))
+		}
+	}
+	for _, name := range block.blocks {
+		if !nested[name] && !slices.Contains(exampleMetaBlocks[block.kind], name) {
+			problems = append(problems, fmt.Sprintf("unknown block %q", name))
+		}
+	}
+	for name, a := range attributes {
+		if a.Required && !slices.Contains(block.attributes, name) {
+			problems = append(problems, fmt.Sprintf("missing required argument %q", name))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+func TestExamples_MatchSchema(t *testing.T) {
+	schemas := providerSchemas(t)
+	schemasByKind := map[string]map[string]*tfprotov6.Schema{
+		"provider":  {"gopass": schemas.Provider},
+		"resource":  schemas.ResourceSchemas,
+		"data":      schemas.DataSourceSchemas,
+		"ephemeral": schemas.EphemeralResourceSchemas,
+	}
+
+	checked := 0
+	for _, file := range exampleFiles(t) {
+		for _, block := range parseExampleBlocks(t, file) {
+			schema, ok := schemasByKind[block.kind][block.typeName]
+			if !ok {
+				t.Errorf("%s: no such type", block)
+				continue
+			}
+			for _, problem := range checkExampleBlock(block, schema) {
+				t.Errorf("%s: %s", block, problem)
+			}
+			checked++
+		}
+	}
+	if checked == 0 {
+		t.Fatal("expected examples to check")
+	}
+}
+
+func TestExamples_Coverage(t *testing.T) {
+	schemas := providerSchemas(t)
+	dirs := map[string]map[string]*tfprotov6.Schema{
+		"resources":           schemas.ResourceSchemas,
+		"data-sources":        schemas.DataSourceSchemas,
+		"ephemeral-resources": schemas.EphemeralResourceSchemas,
+	}
+	for dir, types := range dirs {
+		for typeName := range types {
+			matches, _ := filepath.Glob(filepath.Join(examplesDir, dir, typeName, "*.tf"))
+			if len(matches) == 0 {
+				t.Errorf("no example for %s in examples/%s/%s", typeName, dir, typeName)
+			}
+		}
+	}
+	for name := range schemas.Functions {
+		if matches, _ := filepath.Glob(filepath.Join(examplesDir, "functions", name, "*.tf")); len(matches) == 0 {
+			t.Errorf("no example for function %s in examples/functions/%s", name, name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(examplesDir, "provider", "provider.tf")); err != nil {
+		t.Errorf("no provider example: %v", err)
+	}
+}
+
+func TestParseExampleBlocks(t *testing.T) {
+	file := writeTemp(t, `
+provider "gopass" {
+  store_path = "~/.password-store" # a comment with { a brace
+  // mounts = {}
+}
+
+resource "other_thing" "x" {
+  name = "ignored"
+}
+
+resource "gopass_secret" "x" {
+  path = "a/b"
+  tags = {
+    nested = "not an argument of the resource"
+  }
+  lifecycle {
+    ignore_changes = [path]
+  }
+  value_wo = "{\"json\": \"}\"}"
+}
+`)
+	blocks := parseExampleBlocks(t, file)
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 gopass blocks, got %v", blocks)
+	}
+	if blocks[0].kind != "provider" || !slices.Equal(blocks[0].attributes, []string{"store_path"}) {
+		t.Errorf("unexpected provider block %+v", blocks[0])
+	}
+	if !slices.Equal(blocks[1].attributes, []string{"path", "tags", "value_wo"}) ||
+		!slices.Equal(blocks[1].blocks, []string{"lifecycle"}) || blocks[1].line != 11 {
+		t.Errorf("unexpected resource block %+v", blocks[1])
+	}
+
+	schema := providerSchemas(t).ResourceSchemas["gopass_secret"]
+	problems := checkExampleBlock(exampleBlock{kind: "resource", attributes: []string{"tags", "revision_count", "count"}}, schema)
+	want := []string{`argument "revision_count" is read-only`, `missing required argument "path"`, `unknown argument "tags"`}
+	if !slices.Equal(problems, want) {
+		t.Errorf("unexpected problems %q", problems)
+	}
+}
+
+// exampleVariables are the values examples with input variables are planned with.
+var exampleVariables = map[string]string{
+	"TF_VAR_api_token": "example-token",
+}
+
+func TestExamples_Tofu(t *testing.T) {
+	tofu, err := exec.LookPath("tofu")
+	if err != nil {
+		t.Skip("tofu is not installed")
+	}
+
+	// A development override makes tofu use this build of the provider
+	// without a registry or tofu init
+	pluginDir := t.TempDir()
+	build := exec.Command("go", "build", "-o", filepath.Join(pluginDir, "terraform-provider-gopass"), "../..")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v: %s", err, out)
+	}
+	cliConfig := writeTemp(t, fmt.Sprintf("provider_installation {\n  dev_overrides {\n    %q = %q\n  }\n  direct {}\n}\n",
+		"registry.opentofu.org/istr/gopass", pluginDir))
+	fixture, err := filepath.Abs(filepath.Join(examplesDir, "mock-fixture.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env := append(os.Environ(),
+		"TF_CLI_CONFIG_FILE="+cliConfig,
+		"TF_IN_AUTOMATION=1",
+		backendEnv+"=mock",
+		mockFixtureEnv+"="+fixture,
+	)
+	for name, value := range exampleVariables {
+		env = append(env, name+"="+value)
+	}
+
+	dirs := make(map[string]bool)
+	for _, file := range exampleFiles(t) {
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		t.Run(strings.TrimPrefix(dir, examplesDir+"/"), func(t *testing.T) {
+			if other := otherProviders(t, dir); len(other) > 0 {
+				t.Skipf("needs the %s provider(s) from a registry", strings.Join(other, ", "))
+			}
+			for _, args := range [][]string{
+				{"validate", "-no-color"},
+				{"plan", "-no-color", "-input=false", "-lock=false", "-refresh=false", "-state=" + filepath.Join(t.TempDir(), "tfstate")},
+			} {
+				cmd := exec.Command(tofu, args...)
+				cmd.Dir = dir
+				cmd.Env = env
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Errorf("tofu %s: %v\n%s", args[0], err, out)
+				}
+			}
+		})
+	}
+}
+
+var requiredProviderSource = regexp.MustCompile(`source\s*=\s*"([^"]+)"`)
+
+// otherProviders returns the provider sources besides gopass an example
+// directory requires.
+func otherProviders(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+	var other []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, m := range requiredProviderSource.FindAllStringSubmatch(string(data), -1) {
+			if !strings.HasSuffix(m[1], "/gopass") {
+				other = append(other, m[1])
+			}
+		}
+	}
+	return other
+}
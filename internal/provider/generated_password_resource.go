@@ -0,0 +1,617 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ resource.Resource                   = &GeneratedPasswordResource{}
+	_ resource.ResourceWithConfigure      = &GeneratedPasswordResource{}
+	_ resource.ResourceWithImportState    = &GeneratedPasswordResource{}
+	_ resource.ResourceWithModifyPlan     = &GeneratedPasswordResource{}
+	_ resource.ResourceWithValidateConfig = &GeneratedPasswordResource{}
+)
+
+// GeneratedPasswordResource generates a password into gopass, keeping only
+// its hash in state.
+type GeneratedPasswordResource struct {
+	client *GopassClient
+}
+
+// GeneratedPasswordModel describes the resource data model.
+type GeneratedPasswordModel struct {
+	ID              types.String `tfsdk:"id"`
+	Path            types.String `tfsdk:"path"`
+	Length          types.Int64  `tfsdk:"length"`
+	Upper           types.Bool   `tfsdk:"upper"`
+	Lower           types.Bool   `tfsdk:"lower"`
+	Numeric         types.Bool   `tfsdk:"numeric"`
+	Special         types.Bool   `tfsdk:"special"`
+	OverrideSpecial types.String `tfsdk:"override_special"`
+	MinUpper        types.Int64  `tfsdk:"min_upper"`
+	MinLower        types.Int64  `tfsdk:"min_lower"`
+	MinNumeric      types.Int64  `tfsdk:"min_numeric"`
+	MinSpecial      types.Int64  `tfsdk:"min_special"`
+	Keepers         types.Map    `tfsdk:"keepers"`
+	DeleteOnRemove  types.Bool   `tfsdk:"delete_on_remove"`
+	Policy          types.String `tfsdk:"policy"`
+	PasswordSHA256  types.String `tfsdk:"password_sha256"`
+	RevisionCount   types.Int64  `tfsdk:"revision_count"`
+}
+
+// NewGeneratedPasswordResource creates a new instance.
+func NewGeneratedPasswordResource() resource.Resource {
+	return &GeneratedPasswordResource{}
+}
+
+func (r *GeneratedPasswordResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_generated_password"
+}
+
+func (r *GeneratedPasswordResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Generates a random password and stores it in gopass, like gopass generate. " +
+			"Only the SHA-256 of the password is kept in Terraform state.",
+		MarkdownDescription: `
+Generates a random password and stores it in gopass, like ` + "`gopass generate`" + `.
+
+The password never leaves the provider except into gopass: Terraform state only keeps
+its SHA-256 in ` + "`password_sha256`" + `. Read it back with the ` + "`gopass_secret`" + ` ephemeral resource.
+
+## Example Usage
+
+` + "```hcl" + `
+resource "gopass_generated_password" "db" {
+  path        = "infrastructure/database/admin"
+  length      = 32
+  min_special = 2
+
+  # Change to generate a new password
+  keepers = {
+    rotation = "2026-10"
+  }
+}
+` + "```" + `
+
+Changing the character rules or ` + "`keepers`" + ` generates a new password and overwrites the
+entry in place. Changing ` + "`path`" + ` generates a password at the new path.
+
+## Import
+
+Existing secrets can be imported, keeping their password until the rules or keepers change:
+
+` + "```bash" + `
+tofu import gopass_generated_password.db "infrastructure/database/admin"
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "The path of the secret (same as path attribute).",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"path": schema.StringAttribute{
+				Description:         "Path in the gopass store where the generated password is written.",
+				MarkdownDescription: "Path in the gopass store where the generated password is written (e.g., `infrastructure/database/admin`).",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{secretPathValidator{}},
+			},
+			"length": schema.Int64Attribute{
+				Description:         "Number of characters of the password. Defaults to 24.",
+				MarkdownDescription: "Number of characters of the password. Defaults to `24`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             int64default.StaticInt64(defaultPasswordLength),
+			},
+			"upper": schema.BoolAttribute{
+				Description:         "Whether to use upper case letters. Defaults to true.",
+				MarkdownDescription: "Whether to use upper case letters. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"lower": schema.BoolAttribute{
+				Description:         "Whether to use lower case letters. Defaults to true.",
+				MarkdownDescription: "Whether to use lower case letters. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"numeric": schema.BoolAttribute{
+				Description:         "Whether to use digits. Defaults to true.",
+				MarkdownDescription: "Whether to use digits. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"special": schema.BoolAttribute{
+				Description:         "Whether to use symbols, like gopass generate --symbols. Defaults to true.",
+				MarkdownDescription: "Whether to use symbols, like `gopass generate --symbols`. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"override_special": schema.StringAttribute{
+				Description:         "The symbols to use instead of " + defaultSpecialChars + ", e.g. those a target system accepts.",
+				MarkdownDescription: "The symbols to use instead of `" + defaultSpecialChars + "`, e.g. those a target system accepts.",
+				Optional:            true,
+			},
+			"min_upper": schema.Int64Attribute{
+				Description: "Minimum number of upper case letters. Defaults to 0.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(0),
+			},
+			"min_lower": schema.Int64Attribute{
+				Description: "Minimum number of lower case letters. Defaults to 0.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(0),
+			},
+			"min_numeric": schema.Int64Attribute{
+				Description: "Minimum number of digits. Defaults to 0.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(0),
+			},
+			"min_special": schema.Int64Attribute{
+				Description: "Minimum number of symbols. Defaults to 0.",
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(0),
+			},
+			"keepers": schema.MapAttribute{
+				Description: "Arbitrary values that generate a new password when they change.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"delete_on_remove": schema.BoolAttribute{
+				Description:         "Whether to delete the secret from gopass when the resource is destroyed. Defaults to true.",
+				MarkdownDescription: "Whether to delete the secret from gopass when the resource is destroyed. Defaults to `true`.",
+				Optional:            true,
+				Computed:            true,
+				Default:             booldefault.StaticBool(true),
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"password_sha256": schema.StringAttribute{
+				Description: "Hex-encoded SHA-256 of the generated password. It changes with every new password " +
+					"and can trigger dependent resources, without the password in state.",
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"revision_count": schema.Int64Attribute{
+				Description: "Number of revisions in gopass for this secret. Used for drift detection. " +
+					"A warning is shown if this changes outside of Terraform.",
+				MarkdownDescription: "Number of revisions in gopass for this secret. Used for **drift detection**. " +
+					"A warning is shown if this changes outside of Terraform.",
+				Computed: true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *GeneratedPasswordResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+// rules returns the password rules of the model. Attributes left to their
+// defaults are null in the configuration and get them here.
+func (m *GeneratedPasswordModel) rules() passwordRules {
+	special := defaultSpecialChars
+	if !m.OverrideSpecial.IsNull() {
+		special = m.OverrideSpecial.ValueString()
+	}
+
+	rules := passwordRules{length: defaultPasswordLength}
+	if !m.Length.IsNull() {
+		rules.length = int(m.Length.ValueInt64())
+	}
+	for _, class := range m.classes(special) {
+		if class.enabled.IsNull() || class.enabled.ValueBool() {
+			rules.classes = append(rules.classes, passwordClass{name: class.name, chars: class.chars, min: int(class.min.ValueInt64())})
+		}
+	}
+	return rules
+}
+
+// generatedClass is a character class as configured: whether it is enabled
+// and its minimum count.
+type generatedClass struct {
+	name    string
+	chars   string
+	enabled types.Bool
+	min     types.Int64
+}
+
+// classes returns the configured character classes, using special as the
+// symbols.
+func (m *GeneratedPasswordModel) classes(special string) []generatedClass {
+	return []generatedClass{
+		{"upper", upperChars, m.Upper, m.MinUpper},
+		{"lower", lowerChars, m.Lower, m.MinLower},
+		{"numeric", numericChars, m.Numeric, m.MinNumeric},
+		{"special", special, m.Special, m.MinSpecial},
+	}
+}
+
+// rulesKnown reports whether every attribute the rules depend on is known.
+func (m *GeneratedPasswordModel) rulesKnown() bool {
+	for _, v := range []interface{ IsUnknown() bool }{
+		m.Length, m.Upper, m.Lower, m.Numeric, m.Special, m.OverrideSpecial,
+		m.MinUpper, m.MinLower, m.MinNumeric, m.MinSpecial,
+	} {
+		if v.IsUnknown() {
+			return false
+		}
+	}
+	return true
+}
+
+// rulesChanged reports whether the rules or keepers of plan differ from
+// state, which generates a new password.
+func (m *GeneratedPasswordModel) rulesChanged(state *GeneratedPasswordModel) bool {
+	return !m.Length.Equal(state.Length) ||
+		!m.Upper.Equal(state.Upper) || !m.Lower.Equal(state.Lower) ||
+		!m.Numeric.Equal(state.Numeric) || !m.Special.Equal(state.Special) ||
+		!m.OverrideSpecial.Equal(state.OverrideSpecial) ||
+		!m.MinUpper.Equal(state.MinUpper) || !m.MinLower.Equal(state.MinLower) ||
+		!m.MinNumeric.Equal(state.MinNumeric) || !m.MinSpecial.Equal(state.MinSpecial) ||
+		!m.Keepers.Equal(state.Keepers)
+}
+
+// ValidateConfig rejects rules no password can satisfy.
+func (r *GeneratedPasswordResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config GeneratedPasswordModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() || !config.rulesKnown() {
+		return
+	}
+	for _, class := range config.classes(defaultSpecialChars) {
+		if class.enabled.ValueBool() || class.enabled.IsNull() || class.min.ValueInt64() <= 0 {
+			continue
+		}
+		resp.Diagnostics.AddAttributeError(path.Root("min_"+class.name), "Invalid password rules",
+			fmt.Sprintf("min_%s requires %s characters, but %s is false.", class.name, class.name, class.name))
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := config.rules().validate(); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("length"), "Invalid password rules",
+			fmt.Sprintf("No password can be generated: %s.", err.Error()))
+	}
+}
+
+// ModifyPlan plans a new password when the rules or keepers change, and
+// forecasts the hardware token interactions of the apply.
+//
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *GeneratedPasswordResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	if !req.State.Raw.IsNull() {
+		var plan, state GeneratedPasswordModel
+		resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// An imported password has no rules in state; it is kept until the
+		// rules change after the first apply
+		if !state.Length.IsNull() && plan.rulesChanged(&state) {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("password_sha256"), types.StringUnknown())...)
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("revision_count"), types.Int64Unknown())...)
+		}
+	}
+	if req.Plan.Raw.Equal(req.State.Raw) {
+		return
+	}
+	resp.Diagnostics.Append(r.client.interactionForecast()...)
+}
+
+// generate generates a password following the rules of data, writes it to
+// the secret and records its hash and the revision count in data.
+func (r *GeneratedPasswordResource) generate(ctx context.Context, data *GeneratedPasswordModel, fallbackRevCount int64) error {
+	secretPath := data.Path.ValueString()
+
+	password, err := generatePassword(data.rules())
+	if err != nil {
+		return err
+	}
+	if err := r.client.SetSecret(ctx, secretPath, password); err != nil {
+		return err
+	}
+	data.PasswordSHA256 = types.StringValue(passwordSHA256(password))
+
+	revCount, err := r.client.GetRevisionCount(ctx, secretPath)
+	if err != nil {
+		tflog.Warn(ctx, "Could not get revision count", map[string]interface{}{
+			"path":  r.client.logPath(secretPath),
+			"error": r.client.logError(err),
+		})
+		revCount = fallbackRevCount
+	}
+	data.RevisionCount = types.Int64Value(revCount)
+	return nil
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *GeneratedPasswordResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data GeneratedPasswordModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_generated_password", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	tflog.Debug(ctx, "Generating gopass password", map[string]interface{}{
+		"path": r.client.logPath(secretPath),
+	})
+
+	// Never overwrite a password nobody planned to replace
+	exists, err := r.client.SecretExists(ctx, secretPath)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to create secret"),
+			errorDetail(fmt.Sprintf("Could not check if secret exists at %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+	if exists {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Secret already exists",
+			fmt.Sprintf("A secret exists at %q in gopass. Import it to manage its password with this resource:\n\n"+
+				"  tofu import <address> %q", secretPath, secretPath))
+		return
+	}
+
+	if err := r.generate(ctx, &data, 1); err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to create secret"),
+			errorDetail(fmt.Sprintf("Could not write generated password to gopass at %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+	data.ID = data.Path
+
+	tflog.Debug(ctx, "Generated gopass password", map[string]interface{}{
+		"path": r.client.logPath(secretPath),
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *GeneratedPasswordResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data GeneratedPasswordModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_generated_password", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	// Only check if secret exists - the password is never read back
+	exists, err := r.client.SecretExists(ctx, secretPath)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not check if secret exists at %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+	if !exists {
+		// Secret was deleted outside of Terraform; the next apply generates
+		// a new password
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	currentRevCount, err := r.client.GetRevisionCount(ctx, secretPath)
+	if err != nil {
+		tflog.Warn(ctx, "Could not get revision count for drift detection", map[string]interface{}{
+			"path":  r.client.logPath(secretPath),
+			"error": r.client.logError(err),
+		})
+	} else {
+		storedRevCount := data.RevisionCount.ValueInt64()
+		if storedRevCount > 0 && currentRevCount > storedRevCount {
+			resp.Diagnostics.AddWarning(
+				"Secret modified outside of Terraform",
+				fmt.Sprintf(
+					"The secret at %q has %d revisions, but Terraform expected %d. "+
+						"This indicates the secret was modified outside of Terraform, "+
+						"so password_sha256 may no longer match the password in gopass. "+
+						"Consider changing keepers to generate a new password.",
+					secretPath, currentRevCount, storedRevCount,
+				),
+			)
+		}
+		data.RevisionCount = types.Int64Value(currentRevCount)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *GeneratedPasswordResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data GeneratedPasswordModel
+	var state GeneratedPasswordModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_generated_password", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	// ModifyPlan left the hash unknown if a new password is due
+	if data.PasswordSHA256.IsUnknown() {
+		if err := r.generate(ctx, &data, state.RevisionCount.ValueInt64()); err != nil {
+			resp.Diagnostics.AddError(
+				errorSummary(err, "Failed to update secret"),
+				errorDetail(fmt.Sprintf("Could not write generated password to gopass at %q: %s", secretPath, err.Error()), err),
+			)
+			return
+		}
+		tflog.Info(ctx, "Generated new gopass password", map[string]interface{}{
+			"path": r.client.logPath(secretPath),
+		})
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *GeneratedPasswordResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data GeneratedPasswordModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_generated_password", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	if !data.DeleteOnRemove.ValueBool() {
+		tflog.Info(ctx, "Keeping gopass secret (delete_on_remove=false)", map[string]interface{}{
+			"path": r.client.logPath(secretPath),
+		})
+		return
+	}
+
+	exists, err := r.client.SecretExists(ctx, secretPath)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Failed to check secret existence",
+			errorDetail(fmt.Sprintf("Could not verify if secret exists at %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+	if !exists {
+		return
+	}
+	if err := r.client.RemoveSecret(ctx, secretPath); err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to remove secret"),
+			errorDetail(fmt.Sprintf("Could not remove secret from gopass at %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+	tflog.Info(ctx, "Removed gopass secret", map[string]interface{}{
+		"path": r.client.logPath(secretPath),
+	})
+}
+
+// ImportState adopts an existing password, reading it once for its hash.
+func (r *GeneratedPasswordResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	secretPath := req.ID
+	ctx = withAccessor(ctx, "gopass_generated_password", secretPath)
+	ctx = r.client.logContext(ctx)
+
+	password, err := r.client.GetSecret(ctx, secretPath)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to import secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	revCount, err := r.client.GetRevisionCount(ctx, secretPath)
+	if err != nil {
+		tflog.Warn(ctx, "Could not get revision count during import", map[string]interface{}{
+			"path":  r.client.logPath(secretPath),
+			"error": r.client.logError(err),
+		})
+		revCount = 1 // Fallback
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), secretPath)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("path"), secretPath)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("delete_on_remove"), true)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("password_sha256"), passwordSHA256(password))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("revision_count"), revCount)...)
+}
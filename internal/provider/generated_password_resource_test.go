@@ -0,0 +1,236 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestAccGeneratedPasswordResource_Lifecycle(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+
+	config := func(rotation string) map[string]tftypes.Value {
+		return map[string]tftypes.Value{
+			"path":             tftypes.NewValue(tftypes.String, "app/db"),
+			"length":           tftypes.NewValue(tftypes.Number, 32),
+			"special":          tftypes.NewValue(tftypes.Bool, false),
+			"min_numeric":      tftypes.NewValue(tftypes.Number, 4),
+			"override_special": tftypes.NewValue(tftypes.String, nil),
+			"keepers": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+				"rotation": tftypes.NewValue(tftypes.String, rotation),
+			}),
+		}
+	}
+	stored := func() string {
+		t.Helper()
+		secret, err := store.Get("app/db")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return secret.Password()
+	}
+	attributes := func(state *resourceState) map[string]tftypes.Value {
+		t.Helper()
+		var attrs map[string]tftypes.Value
+		if err := state.value.As(&attrs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return attrs
+	}
+
+	// Create
+	state := acc.applyResource("gopass_generated_password", nil, config("1"))
+	first := stored()
+	if len(first) != 32 {
+		t.Errorf("expected a 32 character password, got %d characters", len(first))
+	}
+	if strings.Trim(first, upperChars+lowerChars+numericChars) != "" {
+		t.Errorf("expected no symbols in %q", first)
+	}
+	attrs := attributes(state)
+	if got := stringAttr(t, attrs, "password_sha256"); got != passwordSHA256(first) {
+		t.Errorf("expected the hash of the stored password, got %s", got)
+	}
+	for name, value := range attrs {
+		if strings.Contains(value.String(), first) {
+			t.Errorf("expected the password to stay out of state, found it in %s", name)
+		}
+	}
+
+	// An unchanged configuration keeps the password
+	state = acc.applyResource("gopass_generated_password", state, config("1"))
+	if stored() != first {
+		t.Error("expected an unchanged configuration to keep the password")
+	}
+
+	// Changing keepers generates a new one in place
+	state = acc.applyResource("gopass_generated_password", state, config("2"))
+	second := stored()
+	if second == first {
+		t.Error("expected changed keepers to generate a new password")
+	}
+	if got := stringAttr(t, attributes(state), "password_sha256"); got != passwordSHA256(second) {
+		t.Errorf("expected the hash of the new password, got %s", got)
+	}
+
+	// Refresh keeps the resource while the secret exists
+	if acc.refreshResource("gopass_generated_password", state) == nil {
+		t.Fatal("expected the resource to still exist")
+	}
+
+	// Destroy
+	acc.applyResource("gopass_generated_password", state, nil)
+	if store.Exists("app/db") {
+		t.Error("expected the secret to be removed from the store")
+	}
+	if acc.refreshResource("gopass_generated_password", state) != nil {
+		t.Error("expected a deleted secret to be removed from state")
+	}
+}
+
+func TestAccGeneratedPasswordResource_Import(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "existing"})
+	acc := newAccProvider(t, store, nil)
+	schema := acc.schemas.ResourceSchemas["gopass_generated_password"]
+
+	imported, err := acc.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: "gopass_generated_password",
+		ID:       "app/db",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc.checkDiags("ImportResourceState", imported.Diagnostics)
+	if len(imported.ImportedResources) != 1 {
+		t.Fatalf("expected one imported resource, got %d", len(imported.ImportedResources))
+	}
+	value, err := imported.ImportedResources[0].State.Unmarshal(schema.ValueType())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state := &resourceState{value: value}
+	var attrs map[string]tftypes.Value
+	if err := value.As(&attrs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stringAttr(t, attrs, "password_sha256"); got != passwordSHA256("existing") {
+		t.Errorf("expected the hash of the imported password, got %s", got)
+	}
+
+	// The first apply after import records the rules but keeps the password
+	state = acc.applyResource("gopass_generated_password", state, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	secret, err := store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Password() != "existing" {
+		t.Errorf("expected the imported password to be kept, got %q", secret.Password())
+	}
+
+	// Later rule changes generate a new password
+	acc.applyResource("gopass_generated_password", state, map[string]tftypes.Value{
+		"path":   tftypes.NewValue(tftypes.String, "app/db"),
+		"length": tftypes.NewValue(tftypes.Number, 16),
+	})
+	secret, err = store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secret.Password()) != 16 {
+		t.Errorf("expected a new 16 character password, got %q", secret.Password())
+	}
+}
+
+func TestAccGeneratedPasswordResource_ExistingSecret(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "existing"})
+	acc := newAccProvider(t, store, nil)
+	schema := acc.schemas.ResourceSchemas["gopass_generated_password"]
+
+	config := acc.object(schema, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	prior := acc.encode(schema, tftypes.NewValue(schema.ValueType(), nil))
+	plan, err := acc.server.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         "gopass_generated_password",
+		PriorState:       prior,
+		ProposedNewState: acc.encode(schema, config),
+		Config:           acc.encode(schema, config),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc.checkDiags("PlanResourceChange", plan.Diagnostics)
+	applied, err := acc.server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "gopass_generated_password",
+		PriorState:   prior,
+		PlannedState: plan.PlannedState,
+		Config:       acc.encode(schema, config),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasErrorSummary(applied.Diagnostics, "Secret already exists") {
+		t.Errorf("expected an existing secret to be refused, got %v", applied.Diagnostics)
+	}
+	secret, err := store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Password() != "existing" {
+		t.Errorf("expected the existing password to be kept, got %q", secret.Password())
+	}
+}
+
+func TestAccGeneratedPasswordResource_InvalidRules(t *testing.T) {
+	acc := newAccProvider(t, gopasstest.New(t, nil), nil)
+	schema := acc.schemas.ResourceSchemas["gopass_generated_password"]
+
+	tests := map[string]map[string]tftypes.Value{
+		"minimums exceed length": {
+			"length":    tftypes.NewValue(tftypes.Number, 4),
+			"min_upper": tftypes.NewValue(tftypes.Number, 3),
+			"min_lower": tftypes.NewValue(tftypes.Number, 3),
+		},
+		"minimum of a disabled class": {
+			"special":     tftypes.NewValue(tftypes.Bool, false),
+			"min_special": tftypes.NewValue(tftypes.Number, 1),
+		},
+		"no classes": {
+			"upper":   tftypes.NewValue(tftypes.Bool, false),
+			"lower":   tftypes.NewValue(tftypes.Bool, false),
+			"numeric": tftypes.NewValue(tftypes.Bool, false),
+			"special": tftypes.NewValue(tftypes.Bool, false),
+		},
+		"empty symbols": {
+			"upper":            tftypes.NewValue(tftypes.Bool, false),
+			"lower":            tftypes.NewValue(tftypes.Bool, false),
+			"numeric":          tftypes.NewValue(tftypes.Bool, false),
+			"override_special": tftypes.NewValue(tftypes.String, ""),
+		},
+	}
+	for name, values := range tests {
+		t.Run(name, func(t *testing.T) {
+			values["path"] = tftypes.NewValue(tftypes.String, "app/db")
+			resp, err := acc.server.ValidateResourceConfig(context.Background(), &tfprotov6.ValidateResourceConfigRequest{
+				TypeName: "gopass_generated_password",
+				Config:   acc.encode(schema, acc.object(schema, values)),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !hasErrorSummary(resp.Diagnostics, "Invalid password rules") {
+				t.Errorf("expected invalid rules to be rejected, got %v", resp.Diagnostics)
+			}
+		})
+	}
+}
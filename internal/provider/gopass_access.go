@@ -0,0 +1,127 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Kinds of secret access recorded in the access summary.
+const (
+	accessRead   = "read"
+	accessWrite  = "write"
+	accessRemove = "remove"
+)
+
+// accessorKey is the context key for the resource on whose behalf the client
+// accesses secrets.
+type accessorKey struct{}
+
+// withAccessor records in ctx which resource the following store accesses
+// are made for. The plugin protocol does not tell providers a resource's
+// address, so resources are identified by their type and configured path.
+func withAccessor(ctx context.Context, resourceType, path string) context.Context {
+	return context.WithValue(ctx, accessorKey{}, fmt.Sprintf("%s %q", resourceType, path))
+}
+
+// accessorFrom returns the resource recorded by withAccessor, or "provider"
+// for accesses the provider makes on its own, like prefetching.
+func accessorFrom(ctx context.Context) string {
+	if accessor, ok := ctx.Value(accessorKey{}).(string); ok {
+		return accessor
+	}
+	return "provider"
+}
+
+// accessLog collects every secret path the run touched, per resource.
+// A nil accessLog records nothing.
+type accessLog struct {
+	mu       sync.Mutex
+	accesses map[string]map[string]map[string]bool // accessor -> path -> kinds
+}
+
+// record notes that the resource in ctx accessed path.
+func (l *accessLog) record(ctx context.Context, path, kind string) {
+	if l == nil {
+		return
+	}
+
+	accessor := accessorFrom(ctx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.accesses == nil {
+		l.accesses = make(map[string]map[string]map[string]bool)
+	}
+	paths, ok := l.accesses[accessor]
+	if !ok {
+		paths = make(map[string]map[string]bool)
+		l.accesses[accessor] = paths
+	}
+	kinds, ok := paths[path]
+	if !ok {
+		kinds = make(map[string]bool)
+		paths[path] = kinds
+	}
+	kinds[kind] = true
+}
+
+// summary renders the accesses as one sorted line per path, e.g.
+// "app/db (read, write)", grouped by resource.
+func (l *accessLog) summary() map[string][]string {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	summary := make(map[string][]string, len(l.accesses))
+	for accessor, paths := range l.accesses {
+		lines := make([]string, 0, len(paths))
+		for path, kinds := range paths {
+			names := make([]string, 0, len(kinds))
+			for kind := range kinds {
+				names = append(names, kind)
+			}
+			sort.Strings(names)
+			lines = append(lines, fmt.Sprintf("%s (%s)", path, strings.Join(names, ", ")))
+		}
+		sort.Strings(lines)
+		summary[accessor] = lines
+	}
+	return summary
+}
+
+// summaryFields returns the summary as log fields, one per resource, plus the
+// total number of lines under countKey. It returns nil if nothing was recorded.
+func (l *accessLog) summaryFields(countKey string) map[string]interface{} {
+	summary := l.summary()
+	if len(summary) == 0 {
+		return nil
+	}
+
+	count := 0
+	fields := make(map[string]interface{}, len(summary)+1)
+	for accessor, lines := range summary {
+		fields[accessor] = lines
+		count += len(lines)
+	}
+	fields[countKey] = count
+	return fields
+}
+
+// logSummary logs every secret path the run read or wrote, grouped by resource.
+func (l *accessLog) logSummary(ctx context.Context) {
+	if fields := l.summaryFields("paths"); fields != nil {
+		tflog.Info(ctx, "gopass access summary", fields)
+	}
+}
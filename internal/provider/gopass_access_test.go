@@ -0,0 +1,126 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestAccessLog_GroupsByResource(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.access = &accessLog{}
+	ctx := context.Background()
+
+	envCtx := withAccessor(ctx, "ephemeral.gopass_env", "app")
+	if _, err := client.GetEnvSecrets(envCtx, "app"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secretCtx := withAccessor(ctx, "gopass_secret", "app/api")
+	if err := client.SetSecret(secretCtx, "app/api", "token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GetSecret(secretCtx, "app/api"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.RemoveSecret(secretCtx, "app/api"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	summary := client.access.summary()
+	want := map[string][]string{
+		`ephemeral.gopass_env "app"`: {"app/db (read)"},
+		`gopass_secret "app/api"`:    {"app/api (read, remove, write)"},
+		"provider":                   {"app/db (read)"},
+	}
+	if len(summary) != len(want) {
+		t.Fatalf("expected %d accessors, got %v", len(want), summary)
+	}
+	for accessor, lines := range want {
+		if !slices.Equal(summary[accessor], lines) {
+			t.Errorf("%s: expected %v, got %v", accessor, lines, summary[accessor])
+		}
+	}
+}
+
+func TestAccessLog_Disabled(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.access = &accessLog{}
+	client.access = nil
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if summary := client.access.summary(); summary != nil {
+		t.Errorf("expected nothing recorded, got %v", summary)
+	}
+}
+
+func TestGopassClient_Close_LogsAccessSummary(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.access = &accessLog{}
+	if _, err := client.GetSecret(withAccessor(ctx, "ephemeral.gopass_secret", "app/db"), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client.Close(ctx)
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("failed to decode log output: %v", err)
+	}
+
+	for _, entry := range entries {
+		if entry["@message"] != "gopass access summary" {
+			continue
+		}
+		if entry["paths"] != float64(1) {
+			t.Errorf("expected one path, got %v", entry["paths"])
+		}
+		lines, _ := entry[`ephemeral.gopass_secret "app/db"`].([]interface{})
+		if len(lines) != 1 || lines[0] != "app/db (read)" {
+			t.Errorf("expected read of app/db, got %v", entry)
+		}
+		return
+	}
+	t.Errorf("expected access summary in log, got %v", entries)
+}
+
+func TestProviderConfigure_AccessSummary(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	for _, enabled := range []bool{false, true} {
+		req := provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{
+				"access_summary": tftypes.NewValue(tftypes.Bool, enabled),
+			}),
+		}
+		resp := &provider.ConfigureResponse{}
+
+		p.Configure(ctx, req, resp)
+
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("unexpected error: %v", resp.Diagnostics)
+		}
+		client := resp.EphemeralResourceData.(*GopassClient)
+		if (client.access != nil) != enabled {
+			t.Errorf("access_summary = %v: unexpected access log %v", enabled, client.access)
+		}
+	}
+}
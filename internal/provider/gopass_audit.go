@@ -0,0 +1,218 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// auditGenesis is the previous-record hash of the first record in an audit log.
+var auditGenesis = strings.Repeat("0", sha256.Size*2)
+
+// Outcomes of an audited access.
+const (
+	auditOK       = "ok"
+	auditDenied   = "denied" // refused by a path or recipient policy, or read_only
+	auditNotFound = "not_found"
+	auditError    = "error"
+)
+
+// auditRecord is one line of the audit log. Prev is the hex SHA-256 of the
+// previous line as written, so removing or editing a record breaks the chain
+// at the following one.
+type auditRecord struct {
+	Seq      int64  `json:"seq"`
+	Time     string `json:"time"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+	Path     string `json:"path"`
+	Outcome  string `json:"outcome"`
+	Prev     string `json:"prev"`
+	// Set on checkpoints only, see auditLog.checkpoint
+	Signer    string `json:"signer,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// auditLog appends a hash-chained JSON line per secret access to a file.
+// Runs append to the same file and continue its chain. A nil auditLog
+// records nothing.
+type auditLog struct {
+	mu     sync.Mutex
+	path   string
+	seq    int64
+	prev   string       // hash of the last line in the file
+	signer *auditSigner // nil unless audit_log_signing_key is set
+	signed int64        // seq of the last record covered by a checkpoint
+}
+
+// openAuditLog prepares appending to the audit log at path, creating it if
+// needed. The chain of an existing log is verified, so a tampered log is
+// noticed before more records are chained to it.
+func openAuditLog(path string) (*auditLog, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	records, head, err := VerifyAuditLog(file)
+	if err != nil {
+		return nil, fmt.Errorf("existing audit log %s is not intact: %w", path, err)
+	}
+	return &auditLog{path: path, seq: int64(records), prev: head, signed: int64(records)}, nil
+}
+
+// record appends an access by the resource in ctx. Failures to write the log
+// are logged; they don't fail the access.
+func (l *auditLog) record(ctx context.Context, c *GopassClient, path, action string, err error) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := auditRecord{
+		Seq:      l.seq + 1,
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Resource: accessorFrom(ctx),
+		Action:   action,
+		Path:     c.logPath(path),
+		Outcome:  auditOutcome(err),
+		Prev:     l.prev,
+	}
+	line, marshalErr := json.Marshal(rec)
+	if marshalErr == nil {
+		marshalErr = appendLine(l.path, line)
+	}
+	if marshalErr != nil {
+		tflog.Error(ctx, "Failed to write gopass audit log", map[string]interface{}{
+			"error": marshalErr.Error(),
+		})
+		return
+	}
+
+	l.seq = rec.Seq
+	l.prev = hashAuditLine(line)
+}
+
+// logHead signs the records of the run with a checkpoint, if a signing key
+// is configured, and logs the hash of the last record, which compliance
+// tooling can keep elsewhere to detect a log truncated after the fact.
+func (l *auditLog) logHead(ctx context.Context) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.checkpoint(ctx)
+
+	tflog.Info(ctx, "gopass audit log", map[string]interface{}{
+		"records": l.seq,
+		"head":    l.prev,
+	})
+}
+
+// auditOutcome classifies the result of an access for the audit log.
+func auditOutcome(err error) string {
+	switch {
+	case err == nil:
+		return auditOK
+	case errors.Is(err, ErrPolicyViolation), errors.Is(err, ErrReadOnly), errors.Is(err, ErrRecipientPolicy):
+		return auditDenied
+	case errors.Is(err, ErrNotFound):
+		return auditNotFound
+	default:
+		return auditError
+	}
+}
+
+// appendLine appends line and a newline to the file at path.
+func appendLine(path string, line []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// hashAuditLine returns the hex SHA-256 of an audit log line without its newline.
+func hashAuditLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditLog checks the hash chain of an audit log written by the
+// provider. It returns the number of records and the hash of the last one,
+// or an error naming the first record that does not chain to its
+// predecessor. An empty log is intact.
+func VerifyAuditLog(r io.Reader) (records int, head string, err error) {
+	records, _, head, err = verifyAuditLog(r, nil)
+	return records, head, err
+}
+
+// VerifySignedAuditLog is VerifyAuditLog also checking that every checkpoint
+// is signed with key. It additionally returns the number of records after the
+// last checkpoint, which no signature covers: they were appended by a run
+// that did not shut down cleanly, or by someone without the signing key.
+func VerifySignedAuditLog(r io.Reader, key ed25519.PublicKey) (records, unsigned int, head string, err error) {
+	return verifyAuditLog(r, key)
+}
+
+// verifyAuditLog checks the hash chain of an audit log and, unless key is
+// nil, the signatures of its checkpoints.
+func verifyAuditLog(r io.Reader, key ed25519.PublicKey) (records, unsigned int, head string, err error) {
+	head = auditGenesis
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+
+	for scanner.Scan() {
+		line := bytes.TrimRight(scanner.Bytes(), "\r")
+		var rec auditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return records, unsigned, head, fmt.Errorf("line %d is not an audit record: %w", records+1, err)
+		}
+		if rec.Prev != head {
+			return records, unsigned, head, fmt.Errorf("record %d does not chain to the record before it: "+
+				"a record was removed, inserted or modified", records+1)
+		}
+		if rec.Seq != int64(records+1) {
+			return records, unsigned, head, fmt.Errorf("record %d has sequence number %d", records+1, rec.Seq)
+		}
+		unsigned++
+		if key != nil && rec.Action == auditCheckpoint {
+			if err := verifyCheckpoint(rec, key); err != nil {
+				return records, unsigned, head, err
+			}
+			unsigned = 0
+		}
+		records++
+		head = hashAuditLine(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, unsigned, head, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, unsigned, head, nil
+}
@@ -0,0 +1,209 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newAuditTestClient returns a client writing its audit log to a temporary file.
+func newAuditTestClient(t *testing.T) (*GopassClient, string) {
+	t.Helper()
+
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := openAuditLog(logPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.audit = audit
+	return client, logPath
+}
+
+func readAuditRecords(t *testing.T, logPath string) []auditRecord {
+	t.Helper()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	var records []auditRecord
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var rec auditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestAuditLog_RecordsAccesses(t *testing.T) {
+	client, logPath := newAuditTestClient(t)
+	ctx := withAccessor(context.Background(), "gopass_secret", "app/api")
+
+	if err := client.SetSecret(ctx, "app/api", "token-value"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "app/api"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "app/missing"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+	client.readOnly = true
+	if err := client.RemoveSecret(ctx, "app/api"); err == nil {
+		t.Fatal("expected removal to be refused")
+	}
+
+	records := readAuditRecords(t, logPath)
+	want := []struct{ action, path, outcome string }{
+		{accessWrite, "app/api", auditOK},
+		{accessRead, "app/api", auditOK},
+		{accessRead, "app/missing", auditNotFound},
+		{accessRemove, "app/api", auditDenied},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d records, got %+v", len(want), records)
+	}
+	for i, w := range want {
+		rec := records[i]
+		if rec.Seq != int64(i+1) || rec.Action != w.action || rec.Path != w.path || rec.Outcome != w.outcome {
+			t.Errorf("record %d: expected %+v, got %+v", i+1, w, rec)
+		}
+		if rec.Resource != `gopass_secret "app/api"` {
+			t.Errorf("record %d: unexpected resource %q", i+1, rec.Resource)
+		}
+	}
+
+	data, _ := os.ReadFile(logPath)
+	if bytes.Contains(data, []byte("token-value")) || bytes.Contains(data, []byte("s3cret")) {
+		t.Errorf("audit log contains a secret value:\n%s", data)
+	}
+}
+
+func TestAuditLog_ChainContinuesAcrossRuns(t *testing.T) {
+	client, logPath := newAuditTestClient(t)
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reopened, err := openAuditLog(logPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.audit = reopened
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records := readAuditRecords(t, logPath)
+	if len(records) != 2 || records[0].Prev != auditGenesis || records[1].Seq != 2 {
+		t.Fatalf("unexpected records %+v", records)
+	}
+
+	file, _ := os.Open(logPath)
+	defer file.Close()
+	count, head, err := VerifyAuditLog(file)
+	if err != nil || count != 2 {
+		t.Errorf("expected an intact log of 2 records, got %d (%v)", count, err)
+	}
+	if head != reopened.prev {
+		t.Errorf("expected head %s, got %s", reopened.prev, head)
+	}
+}
+
+func TestVerifyAuditLog_DetectsTampering(t *testing.T) {
+	client, logPath := newAuditTestClient(t)
+	for range 3 {
+		if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	data, _ := os.ReadFile(logPath)
+	lines := strings.SplitAfter(strings.TrimSpace(string(data)), "\n")
+
+	tests := map[string]string{
+		"edited":   lines[0] + strings.Replace(lines[1], `"outcome":"ok"`, `"outcome":"error"`, 1) + lines[2],
+		"removed":  lines[0] + lines[2],
+		"head cut": lines[1] + lines[2],
+		"garbage":  lines[0] + "not json\n" + lines[1],
+	}
+	for name, tampered := range tests {
+		if _, _, err := VerifyAuditLog(strings.NewReader(tampered)); err == nil {
+			t.Errorf("%s: expected tampering to be detected", name)
+		}
+	}
+
+	if _, err := openAuditLog(writeTemp(t, tests["removed"])); err == nil {
+		t.Error("expected a tampered log to be refused for appending")
+	}
+}
+
+func TestVerifyAuditLog_Empty(t *testing.T) {
+	count, head, err := VerifyAuditLog(strings.NewReader(""))
+	if err != nil || count != 0 || head != auditGenesis {
+		t.Errorf("expected an intact empty log, got %d %s (%v)", count, head, err)
+	}
+}
+
+func TestAuditLog_HashedPaths(t *testing.T) {
+	client, logPath := newAuditTestClient(t)
+	client.redactor.hashPaths = true
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if records := readAuditRecords(t, logPath); records[0].Path != pathID("app/db") {
+		t.Errorf("expected hashed path, got %q", records[0].Path)
+	}
+}
+
+func TestProviderConfigure_AuditLog(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"audit_log": tftypes.NewValue(tftypes.String, logPath),
+		}),
+	}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.EphemeralResourceData.(*GopassClient); client.audit == nil || client.audit.path != logPath {
+		t.Errorf("expected audit log at %s", logPath)
+	}
+
+	resp = &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"audit_log": tftypes.NewValue(tftypes.String, filepath.Join(t.TempDir(), "missing", "audit.jsonl")),
+		}),
+	}, resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error for an unwritable audit log")
+	}
+}
+
+func writeTemp(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
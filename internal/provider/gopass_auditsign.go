@@ -0,0 +1,143 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// auditCheckpoint is the action of the signed record closing the records a
+// run appended to the audit log.
+const auditCheckpoint = "checkpoint"
+
+// auditSigner signs audit log checkpoints with the runner's Ed25519 key.
+type auditSigner struct {
+	key ed25519.PrivateKey
+	id  string // see auditKeyID
+}
+
+// auditKeyID identifies a signing key in checkpoints without exposing more
+// than a fingerprint of its public part.
+func auditKeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "ed25519:" + hex.EncodeToString(sum[:8])
+}
+
+// loadAuditSigner reads a PEM-encoded PKCS #8 Ed25519 private key, as
+// written by "openssl genpkey -algorithm ed25519".
+func loadAuditSigner(path string) (*auditSigner, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from the provider configuration
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("expected a PEM \"PRIVATE KEY\" block")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an Ed25519 key, got %T", parsed)
+	}
+	return &auditSigner{key: key, id: auditKeyID(key.Public().(ed25519.PublicKey))}, nil
+}
+
+// ParseAuditPublicKey parses the PEM-encoded public key checkpoints of an
+// audit log are verified with, as written by "openssl pkey -pubout".
+func ParseAuditPublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("expected a PEM \"PUBLIC KEY\" block")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an Ed25519 key, got %T", parsed)
+	}
+	return key, nil
+}
+
+// signedContent returns what the signature of a checkpoint covers: the
+// record without its signature. As the record contains the hash of the one
+// before it, the signature covers the whole chain up to the checkpoint.
+func signedContent(rec auditRecord) ([]byte, error) {
+	rec.Signature = ""
+	return json.Marshal(rec)
+}
+
+// checkpoint appends a record signed with the signing key, if the run
+// appended records since the log was opened or the last checkpoint. Callers
+// hold l.mu.
+func (l *auditLog) checkpoint(ctx context.Context) {
+	if l.signer == nil || l.seq == l.signed {
+		return
+	}
+
+	rec := auditRecord{
+		Seq:      l.seq + 1,
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Resource: "provider",
+		Action:   auditCheckpoint,
+		Outcome:  auditOK,
+		Prev:     l.prev,
+		Signer:   l.signer.id,
+	}
+	content, err := signedContent(rec)
+	var line []byte
+	if err == nil {
+		rec.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(l.signer.key, content))
+		line, err = json.Marshal(rec)
+	}
+	if err == nil {
+		err = appendLine(l.path, line)
+	}
+	if err != nil {
+		tflog.Error(ctx, "Failed to sign gopass audit log", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	l.seq = rec.Seq
+	l.signed = rec.Seq
+	l.prev = hashAuditLine(line)
+}
+
+// verifyCheckpoint checks the signature of a checkpoint record against key.
+func verifyCheckpoint(rec auditRecord, key ed25519.PublicKey) error {
+	if id := auditKeyID(key); rec.Signer != id {
+		return fmt.Errorf("checkpoint %d is signed by %s, not by %s", rec.Seq, rec.Signer, id)
+	}
+	signature, err := base64.StdEncoding.DecodeString(rec.Signature)
+	if err != nil {
+		return fmt.Errorf("checkpoint %d has an invalid signature: %w", rec.Seq, err)
+	}
+	content, err := signedContent(rec)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, content, signature) {
+		return fmt.Errorf("checkpoint %d has an invalid signature", rec.Seq)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newAuditKey returns a new Ed25519 public key and the path of a PEM file
+// holding the private key.
+func newAuditKey(t *testing.T) (ed25519.PublicKey, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, writeTemp(t, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+}
+
+func verifySignedLog(t *testing.T, logPath string, key ed25519.PublicKey) (records, unsigned int, err error) {
+	t.Helper()
+	file, openErr := os.Open(logPath)
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer file.Close()
+	records, unsigned, _, err = VerifySignedAuditLog(file, key)
+	return records, unsigned, err
+}
+
+func TestAuditLog_SignedCheckpoint(t *testing.T) {
+	client, logPath := newAuditTestClient(t)
+	pub, keyPath := newAuditKey(t)
+	signer, err := loadAuditSigner(keyPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.audit.signer = signer
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	client.audit.logHead(ctx)
+	// Nothing new to sign
+	client.audit.logHead(ctx)
+
+	records := readAuditRecords(t, logPath)
+	if len(records) != 3 {
+		t.Fatalf("expected 2 records and a checkpoint, got %+v", records)
+	}
+	last := records[2]
+	if last.Action != auditCheckpoint || last.Signer != auditKeyID(pub) || last.Signature == "" {
+		t.Errorf("unexpected checkpoint %+v", last)
+	}
+	if records[0].Signer != "" || records[0].Signature != "" {
+		t.Errorf("expected only checkpoints to carry signatures, got %+v", records[0])
+	}
+
+	count, unsigned, err := verifySignedLog(t, logPath, pub)
+	if err != nil || count != 3 || unsigned != 0 {
+		t.Errorf("expected a signed log of 3 records, got %d, %d unsigned (%v)", count, unsigned, err)
+	}
+
+	// The plain chain check still accepts the log
+	file, _ := os.Open(logPath)
+	defer file.Close()
+	if _, _, err := VerifyAuditLog(file); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestVerifySignedAuditLog_Problems(t *testing.T) {
+	client, logPath := newAuditTestClient(t)
+	pub, keyPath := newAuditKey(t)
+	client.audit.signer, _ = loadAuditSigner(keyPath)
+	ctx := context.Background()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.audit.logHead(ctx)
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Records after the last checkpoint are reported
+	if _, unsigned, err := verifySignedLog(t, logPath, pub); err != nil || unsigned != 1 {
+		t.Errorf("expected 1 unsigned record, got %d (%v)", unsigned, err)
+	}
+
+	// Another key did not sign the log
+	other, _ := newAuditKey(t)
+	if _, _, err := verifySignedLog(t, logPath, other); err == nil || !strings.Contains(err.Error(), "signed by") {
+		t.Errorf("expected a signer mismatch, got %v", err)
+	}
+
+	// A checkpoint re-chained without the key has an invalid signature
+	data, _ := os.ReadFile(logPath)
+	lines := strings.SplitAfter(strings.TrimSpace(string(data)), "\n")
+	forged := strings.Replace(lines[1], `"resource":"provider"`, `"resource":"someone"`, 1)
+	if forged == lines[1] {
+		t.Fatalf("unexpected checkpoint line %s", lines[1])
+	}
+	forgedPath := filepath.Join(t.TempDir(), "forged.jsonl")
+	os.WriteFile(forgedPath, []byte(lines[0]+forged), 0o600)
+	if _, _, err := verifySignedLog(t, forgedPath, pub); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("expected an invalid signature, got %v", err)
+	}
+}
+
+func TestParseAuditPublicKey(t *testing.T) {
+	pub, keyPath := newAuditKey(t)
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	parsed, err := ParseAuditPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || !parsed.Equal(pub) {
+		t.Errorf("expected the key back, got %v (%v)", parsed, err)
+	}
+
+	private, _ := os.ReadFile(keyPath)
+	if _, err := ParseAuditPublicKey(private); err == nil {
+		t.Error("expected a private key to be refused")
+	}
+	if _, err := loadAuditSigner(writeTemp(t, "not a key")); err == nil {
+		t.Error("expected an invalid key file to be refused")
+	}
+}
+
+func TestProviderConfigure_AuditLogSigningKey(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+	_, keyPath := newAuditKey(t)
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	tests := map[string]struct {
+		config  map[string]tftypes.Value
+		wantErr bool
+	}{
+		"signed": {map[string]tftypes.Value{
+			"audit_log":             tftypes.NewValue(tftypes.String, logPath),
+			"audit_log_signing_key": tftypes.NewValue(tftypes.String, keyPath),
+		}, false},
+		"without audit_log": {map[string]tftypes.Value{
+			"audit_log_signing_key": tftypes.NewValue(tftypes.String, keyPath),
+		}, true},
+		"invalid key": {map[string]tftypes.Value{
+			"audit_log":             tftypes.NewValue(tftypes.String, logPath),
+			"audit_log_signing_key": tftypes.NewValue(tftypes.String, keyPath+".missing"),
+		}, true},
+	}
+	for name, tt := range tests {
+		resp := &provider.ConfigureResponse{}
+		p.Configure(ctx, provider.ConfigureRequest{Config: newProviderConfig(t, p, tt.config)}, resp)
+		if resp.Diagnostics.HasError() != tt.wantErr {
+			t.Errorf("%s: unexpected diagnostics %v", name, resp.Diagnostics)
+			continue
+		}
+		if !tt.wantErr && resp.EphemeralResourceData.(*GopassClient).audit.signer == nil {
+			t.Errorf("%s: expected a signer", name)
+		}
+	}
+}
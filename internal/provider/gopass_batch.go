@@ -0,0 +1,54 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// GetSecrets reads the passwords of the secrets at paths and returns them
+// keyed by the paths as given. Up to envReadWorkers secrets are decrypted at
+// the same time, and a path listed more than once is read once.
+//
+// Unlike GetEnvSecrets every path must be readable: once a read fails no
+// further reads are started, and the errors of all failed reads are joined.
+func (c *GopassClient) GetSecrets(ctx context.Context, paths []string) (map[string]string, error) {
+	unique := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, secretPath := range paths {
+		if err := c.checkPlaintext(secretPath); err != nil {
+			return nil, err
+		}
+		if !seen[secretPath] {
+			seen[secretPath] = true
+			unique = append(unique, secretPath)
+		}
+	}
+
+	tflog.Debug(ctx, "Reading secrets", map[string]interface{}{
+		"count": len(unique),
+	})
+
+	reads := c.readConcurrently(ctx, unique, func(err error) bool { return err != nil })
+
+	// Report failures in the order of paths, so errors are stable
+	var failed []error
+	for _, read := range reads {
+		if read.err != nil {
+			failed = append(failed, read.err)
+		}
+	}
+	if len(failed) > 0 {
+		return nil, errors.Join(failed...)
+	}
+
+	values := make(map[string]string, len(unique))
+	for i, secretPath := range unique {
+		values[secretPath] = reads[i].value
+	}
+	return values, nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGopassClient_GetSecrets(t *testing.T) {
+	client := NewGopassClient("")
+	store := &mockSlowStore{mockStore: newMockStore()}
+	client.store = store
+	var paths []string
+	want := make(map[string]string)
+	for i := range 3 * envReadWorkers {
+		secretPath := fmt.Sprintf("app/secret%02d", i)
+		store.secrets[secretPath] = newMockSecret("value-" + secretPath)
+		paths = append(paths, secretPath)
+		want[secretPath] = "value-" + secretPath
+	}
+
+	values, err := client.GetSecrets(context.Background(), append(paths, paths[0]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(values) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+	if peak := store.maxInFlight.Load(); peak < 2 || peak > envReadWorkers {
+		t.Errorf("expected between 2 and %d concurrent reads, got %d", envReadWorkers, peak)
+	}
+}
+
+func TestGopassClient_GetSecrets_JoinsFailures(t *testing.T) {
+	// Slow reads, so all three are in flight before the first one fails
+	client := NewGopassClient("")
+	store := &mockSlowStore{mockStore: newMockStore()}
+	client.store = store
+	store.secrets["app/db"] = newMockSecret("s3cret")
+
+	values, err := client.GetSecrets(context.Background(), []string{"app/missing1", "app/db", "app/missing2"})
+	if err == nil || values != nil {
+		t.Fatalf("expected the read to fail as a whole, got %v", values)
+	}
+	if !strings.Contains(err.Error(), `"app/missing1"`) || !strings.Contains(err.Error(), `"app/missing2"`) {
+		t.Errorf("expected both missing secrets in the error, got %q", err.Error())
+	}
+}
+
+func TestGopassClient_GetSecrets_ChecksumOnly(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.checksumOnly = true
+
+	if _, err := client.GetSecrets(context.Background(), []string{"app/db"}); !errors.Is(err, ErrChecksumOnly) {
+		t.Errorf("expected a checksum-only error, got %v", err)
+	}
+}
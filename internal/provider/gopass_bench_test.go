@@ -0,0 +1,214 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// benchStore is a read-only fixture store shaped like a real team store:
+// a few top-level areas, each with services holding env-style credential sets.
+// List returns a precomputed, sorted listing, as gopass does, so benchmarks
+// measure the client rather than the fixture.
+type benchStore struct {
+	*mockStore
+	listing []string
+}
+
+func (b *benchStore) List(ctx context.Context) ([]string, error) {
+	return b.listing, nil
+}
+
+// Fixture sizes: small is a personal store, large a shared organization store.
+var benchStoreSizes = []struct {
+	name                    string
+	areas, services, values int
+}{
+	{"small", 3, 5, 4},    // 60 secrets
+	{"large", 20, 50, 10}, // 10000 secrets
+}
+
+func newBenchStore(areas, services, values int) *benchStore {
+	store := &benchStore{mockStore: newMockStore()}
+	for a := range areas {
+		for s := range services {
+			for v := range values {
+				path := fmt.Sprintf("area%02d/service%03d/KEY_%02d", a, s, v)
+				secret := secrets.New()
+				secret.SetPassword("value-of-" + path)
+				secret.Set("username", "user")
+				store.secrets[path] = secret
+				store.listing = append(store.listing, path)
+			}
+		}
+	}
+	sort.Strings(store.listing)
+	return store
+}
+
+func BenchmarkGetSecret(b *testing.B) {
+	for _, size := range benchStoreSizes {
+		b.Run(size.name, func(b *testing.B) {
+			ctx := context.Background()
+			client := NewGopassClientWithStore(newBenchStore(size.areas, size.services, size.values))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := client.GetSecret(ctx, "area01/service002/KEY_03"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkListSecrets_Prefix(b *testing.B) {
+	for _, size := range benchStoreSizes {
+		b.Run(size.name, func(b *testing.B) {
+			ctx := context.Background()
+			client := NewGopassClientWithStore(newBenchStore(size.areas, size.services, size.values))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				paths, err := client.ListSecrets(ctx, "area01/service002")
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(paths) != size.values {
+					b.Fatalf("expected %d secrets, got %d", size.values, len(paths))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkListSecrets_TopLevel(b *testing.B) {
+	for _, size := range benchStoreSizes {
+		b.Run(size.name, func(b *testing.B) {
+			ctx := context.Background()
+			// A few top-level secrets next to the area directories
+			store := newBenchStore(size.areas, size.services, size.values)
+			store.listing = append([]string{"README", "ca-cert"}, store.listing...)
+			client := NewGopassClientWithStore(store)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				paths, err := client.ListSecrets(ctx, "")
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(paths) != 2 {
+					b.Fatalf("expected 2 top-level secrets, got %d", len(paths))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkListSecrets_Uncached(b *testing.B) {
+	for _, size := range benchStoreSizes {
+		b.Run(size.name, func(b *testing.B) {
+			ctx := context.Background()
+			client := NewGopassClientWithStore(newBenchStore(size.areas, size.services, size.values))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				client.listing.reset()
+				if _, err := client.ListSecrets(ctx, "area01/service002"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetEnvSecrets(b *testing.B) {
+	for _, size := range benchStoreSizes {
+		b.Run(size.name, func(b *testing.B) {
+			ctx := context.Background()
+			client := NewGopassClientWithStore(newBenchStore(size.areas, size.services, size.values))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				values, err := client.GetEnvSecrets(ctx, "area01/service002")
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(values) != size.values {
+					b.Fatalf("expected %d values, got %d", size.values, len(values))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPrefetchLookup(b *testing.B) {
+	ctx := context.Background()
+	store := newBenchStore(3, 5, 4)
+	client := NewGopassClientWithStore(store)
+	client.prefetch = newPrefetcher([]string{"area01/"})
+
+	// Run the prefetch pass outside the measured loop
+	if _, err := client.GetSecret(ctx, "area01/service002/KEY_03"); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := client.GetSecret(ctx, "area01/service002/KEY_03"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// newLargeBodyStore holds one secret with many keys, like an exported
+// credential bundle, of which callers typically need only one or two.
+func newLargeBodyStore() *benchStore {
+	store := &benchStore{mockStore: newMockStore()}
+	secret := secrets.New()
+	secret.SetPassword("bundle")
+	for i := range 200 {
+		secret.Set(fmt.Sprintf("key_%03d", i), fmt.Sprintf("value-%03d", i))
+	}
+	store.secrets["bundle"] = secret
+	store.listing = []string{"bundle"}
+	return store
+}
+
+func BenchmarkGetSecretFull_LargeBody(b *testing.B) {
+	ctx := context.Background()
+	client := NewGopassClientWithStore(newLargeBodyStore())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, _, err := client.GetSecretFull(ctx, "bundle"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetSecretFields_LargeBody(b *testing.B) {
+	ctx := context.Background()
+	client := NewGopassClientWithStore(newLargeBodyStore())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, _, err := client.GetSecretFields(ctx, "bundle", "key_042", "key_137"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// binaryChunkSize is the size of the pieces large secrets are streamed in.
+const binaryChunkSize = 64 << 10
+
+// isBase64Encoded reports whether a secret holds base64 encoded binary content,
+// as written by "gopass fscopy" and "gopass binary cp".
+func isBase64Encoded(secret gopass.Secret) bool {
+	for _, key := range []string{"Content-Transfer-Encoding", "content-transfer-encoding"} {
+		if encoding, _ := secret.Get(key); strings.EqualFold(encoding, "base64") {
+			return true
+		}
+	}
+	return false
+}
+
+// base64Payload returns the lines holding the encoded payload of a gopass
+// binary entry: every line after the password line that is not a
+// "key: value" header. It slices the raw entry instead of going through
+// Secret.Body, which copies the payload several times on the way.
+func base64Payload(raw []byte) [][]byte {
+	var lines [][]byte
+	_, rest, _ := bytes.Cut(raw, []byte("\n"))
+	for len(rest) > 0 {
+		var line []byte
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || bytes.IndexByte(line, ':') >= 0 {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// getBinary decrypts the secret at path for binary access.
+func (c *GopassClient) getBinary(ctx context.Context, path string) (gopass.Secret, error) {
+	if err := c.checkPlaintext(path); err != nil {
+		return nil, err
+	}
+
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	secret, err := c.storeGet(ctx, store, path)
+	if err != nil {
+		return nil, c.readError(ctx, store, path, err)
+	}
+	if err := c.checkExpiry(ctx, path, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// WriteSecretTo streams the binary content of the secret at path to w and
+// returns the number of bytes written. Entries stored by gopass's binary
+// commands are decoded on the fly; other entries are written verbatim.
+//
+// Use it for large entries such as certificate bundles or keystores: the
+// decoded content is never held in memory as a whole, and encoders or hashes
+// wrapped around w work on one chunk at a time.
+func (c *GopassClient) WriteSecretTo(ctx context.Context, path string, w io.Writer) (int64, error) {
+	secret, err := c.getBinary(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+
+	var r io.Reader
+	if isBase64Encoded(secret) {
+		lines := base64Payload(secret.Bytes())
+		readers := make([]io.Reader, len(lines))
+		for i, line := range lines {
+			readers[i] = bytes.NewReader(line)
+		}
+		r = base64.NewDecoder(base64.StdEncoding, io.MultiReader(readers...))
+	} else {
+		r = bytes.NewReader(secret.Bytes())
+	}
+
+	n, err := copyChunks(ctx, w, r)
+	if err != nil {
+		return n, fmt.Errorf("failed to stream secret %q: %w", path, err)
+	}
+
+	tflog.Debug(ctx, "Streamed binary secret", map[string]interface{}{
+		"path":  c.logPath(path),
+		"bytes": n,
+	})
+	return n, nil
+}
+
+// GetSecretBase64 returns the binary content of the secret at path, base64
+// encoded. Entries stored by gopass's binary commands are already encoded and
+// are returned without decoding them; other entries are encoded into a buffer
+// of the exact final size.
+func (c *GopassClient) GetSecretBase64(ctx context.Context, path string) (string, error) {
+	secret, err := c.getBinary(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	if isBase64Encoded(secret) {
+		// gopass may wrap the encoded payload; drop the line breaks
+		lines := base64Payload(secret.Bytes())
+		size := 0
+		for _, line := range lines {
+			size += len(line)
+		}
+
+		var out strings.Builder
+		out.Grow(size)
+		for _, line := range lines {
+			out.Write(line)
+		}
+		c.redactor.addValues(out.String())
+		return out.String(), nil
+	}
+
+	body := secret.Bytes()
+	var out strings.Builder
+	out.Grow(base64.StdEncoding.EncodedLen(len(body)))
+
+	enc := base64.NewEncoder(base64.StdEncoding, &out)
+	if _, err := copyChunks(ctx, enc, bytes.NewReader(body)); err != nil {
+		return "", fmt.Errorf("failed to encode secret %q: %w", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode secret %q: %w", path, err)
+	}
+	c.redactor.addValues(out.String())
+	return out.String(), nil
+}
+
+// decodedSize returns the number of bytes the standard base64 encoding
+// encoded decodes to.
+func decodedSize(encoded string) int64 {
+	size := base64.StdEncoding.DecodedLen(len(encoded))
+	size -= len(encoded) - len(strings.TrimRight(encoded, "="))
+	return int64(max(size, 0))
+}
+
+// copyChunks copies r to w in chunks of binaryChunkSize, stopping early if
+// ctx is done.
+func copyChunks(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	buf := make([]byte, binaryChunkSize)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, contextError("streaming secret", 0, err)
+		}
+
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			m, err := w.Write(buf[:n])
+			written += int64(m)
+			if err != nil {
+				return written, err
+			}
+		}
+
+		switch readErr {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			return written, nil
+		default:
+			return written, readErr
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// newBinaryTestClient returns a client whose store holds size random bytes at "certs/bundle".
+func newBinaryTestClient(t testing.TB, size int) (*GopassClient, []byte) {
+	t.Helper()
+
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemoryStore(map[string]string{"certs/bundle": string(newBinarySecret(t, data).Bytes())})
+	return NewGopassClientWithStore(store), data
+}
+
+// newBinarySecret encodes data the way "gopass fscopy" stores files.
+func newBinarySecret(t testing.TB, data []byte) *secrets.AKV {
+	t.Helper()
+
+	secret := secrets.NewAKV()
+	if err := secret.Set("Content-Disposition", `attachment; filename="bundle.p12"`); err != nil {
+		t.Fatal(err)
+	}
+	if err := secret.Set("Content-Transfer-Encoding", "Base64"); err != nil {
+		t.Fatal(err)
+	}
+
+	enc := base64.NewEncoder(base64.StdEncoding, secret)
+	if _, err := enc.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := secret.Write([]byte("\n")); err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+// chunkRecorder records the size of every write.
+type chunkRecorder struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (r *chunkRecorder) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, len(p))
+	return r.Buffer.Write(p)
+}
+
+func TestGopassClient_WriteSecretTo(t *testing.T) {
+	client, data := newBinaryTestClient(t, 3*binaryChunkSize+17)
+
+	var out chunkRecorder
+	n, err := client.WriteSecretTo(context.Background(), "certs/bundle", &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n != int64(len(data)) || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("streamed content differs: wrote %d of %d bytes", n, len(data))
+	}
+	if len(out.writes) != 4 {
+		t.Errorf("expected 4 chunks, got %v", out.writes)
+	}
+	for _, size := range out.writes {
+		if size > binaryChunkSize {
+			t.Errorf("chunk of %d bytes exceeds %d", size, binaryChunkSize)
+		}
+	}
+}
+
+func TestGopassClient_WriteSecretTo_Hash(t *testing.T) {
+	client, data := newBinaryTestClient(t, 1<<20)
+
+	hash := sha256.New()
+	if _, err := client.WriteSecretTo(context.Background(), "certs/bundle", hash); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := sha256.Sum256(data)
+	if !bytes.Equal(hash.Sum(nil), want[:]) {
+		t.Error("hash of streamed content does not match")
+	}
+}
+
+func TestGopassClient_WriteSecretTo_NotFound(t *testing.T) {
+	client, _ := newBinaryTestClient(t, 16)
+
+	_, err := client.WriteSecretTo(context.Background(), "missing", &bytes.Buffer{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// cancellingWriter cancels its context after the first write.
+type cancellingWriter struct {
+	cancel context.CancelFunc
+	writes int
+}
+
+func (w *cancellingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.cancel()
+	return len(p), nil
+}
+
+func TestGopassClient_WriteSecretTo_Cancelled(t *testing.T) {
+	client, _ := newBinaryTestClient(t, 4*binaryChunkSize)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := &cancellingWriter{cancel: cancel}
+	n, err := client.WriteSecretTo(ctx, "certs/bundle", w)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+	if w.writes != 1 || n != binaryChunkSize {
+		t.Errorf("expected streaming to stop after one chunk, got %d writes, %d bytes", w.writes, n)
+	}
+}
+
+func TestGopassClient_GetSecretBase64(t *testing.T) {
+	client, data := newBinaryTestClient(t, 2*binaryChunkSize+1)
+
+	encoded, err := client.GetSecretBase64(context.Background(), "certs/bundle")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if encoded != base64.StdEncoding.EncodeToString(data) {
+		t.Error("encoded content does not match")
+	}
+}
+
+func TestGopassClient_WriteSecretTo_PlainEntry(t *testing.T) {
+	const body = "hunter2\nuser: admin\n"
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": body}))
+
+	var out bytes.Buffer
+	if _, err := client.WriteSecretTo(context.Background(), "app/db", &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != body {
+		t.Errorf("expected entry verbatim, got %q", out.String())
+	}
+
+	encoded, err := client.GetSecretBase64(context.Background(), "app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if encoded != base64.StdEncoding.EncodeToString([]byte(body)) {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+}
+
+func TestBase64Payload(t *testing.T) {
+	raw := []byte("\nContent-Disposition: attachment; filename=\"a.bin\"\nContent-Transfer-Encoding: Base64\n" +
+		"aGVsbG8g\r\nd29ybGQ=\n\n")
+
+	var joined []byte
+	for _, line := range base64Payload(raw) {
+		joined = append(joined, line...)
+	}
+	if string(joined) != "aGVsbG8gd29ybGQ=" {
+		t.Errorf("expected wrapped payload without headers, got %q", joined)
+	}
+}
+
+func BenchmarkWriteSecretTo_4MiB(b *testing.B) {
+	ctx := context.Background()
+	client, data := newBinaryTestClient(b, 4<<20)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := client.WriteSecretTo(ctx, "certs/bundle", io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetSecretBase64_4MiB(b *testing.B) {
+	ctx := context.Background()
+	client, data := newBinaryTestClient(b, 4<<20)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := client.GetSecretBase64(ctx, "certs/bundle"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecodedSize(t *testing.T) {
+	for _, size := range []int{0, 1, 2, 3, 4, 1000} {
+		encoded := base64.StdEncoding.EncodeToString(make([]byte, size))
+		if got := decodedSize(encoded); got != int64(size) {
+			t.Errorf("%d bytes: got %d", size, got)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// defaultMaxDecryptFailures is the number of consecutive decryption failures
+// after which the client stops attempting further reads.
+const defaultMaxDecryptFailures = 3
+
+// ErrCircuitOpen is returned for reads that were not attempted because too many
+// preceding decryptions failed in a row.
+var ErrCircuitOpen = errors.New("too many consecutive decryption failures")
+
+// circuitBreaker stops further decryption attempts after repeated failures.
+//
+// A typical trigger is a missing or locked GPG key: every read fails the same
+// way, and each attempt may pop up another pinentry dialog. Once the breaker
+// is open, remaining reads fail immediately with a summary of the failure.
+type circuitBreaker struct {
+	mu          sync.Mutex
+	threshold   int // 0 disables the breaker
+	consecutive int
+	rejected    int
+	lastErr     error
+}
+
+// allow returns an error wrapping ErrCircuitOpen if reads should not be attempted.
+func (b *circuitBreaker) allow(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.consecutive < b.threshold {
+		return nil
+	}
+
+	b.rejected++
+	return fmt.Errorf("%w: skipped reading secret %q after %d failed decryptions in a row "+
+		"(%d reads skipped so far). Fix the underlying problem and re-run; last error: %v",
+		ErrCircuitOpen, path, b.consecutive, b.rejected, b.lastErr)
+}
+
+// record updates the breaker with the outcome of a read.
+// Missing secrets and cancelled operations do not count as decryption failures.
+func (b *circuitBreaker) record(err error) {
+	if err != nil && !isDecryptionFailure(err) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.consecutive = 0
+		b.lastErr = nil
+		return
+	}
+
+	b.consecutive++
+	b.lastErr = err
+}
+
+// isDecryptionFailure reports whether a read error indicates that the store
+// could not decrypt an existing secret, as opposed to the secret not existing
+// or the caller giving up, or a replayed cassette lacking the read. Timed out
+// reads count as failures: they usually mean a prompt nobody answered, and the
+// next read would wait just as long.
+func isDecryptionFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrNotRecorded) {
+		return false
+	}
+	return !isNotFound(err)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+func TestIsDecryptionFailure(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		failure bool
+	}{
+		{name: "gpg error", err: errors.New("gpg: decryption failed: No secret key"), failure: true},
+		{name: "not found", err: fmt.Errorf("%w: x", ErrNotFound), failure: false},
+		{name: "cancelled", err: fmt.Errorf("reading secret aborted: %w", context.Canceled), failure: false},
+		{name: "timed out", err: fmt.Errorf("reading secret timed out: %w", context.DeadlineExceeded), failure: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isDecryptionFailure(tc.err); got != tc.failure {
+				t.Errorf("isDecryptionFailure(%v) = %v, want %v", tc.err, got, tc.failure)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	b := &circuitBreaker{threshold: 2}
+	decryptErr := errors.New("gpg: decryption failed")
+
+	b.record(decryptErr)
+	if err := b.allow("a"); err != nil {
+		t.Fatalf("breaker opened too early: %v", err)
+	}
+
+	b.record(decryptErr)
+	err := b.allow("b")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "gpg: decryption failed") {
+		t.Errorf("expected last error in message, got %q", err.Error())
+	}
+	if !strings.Contains(err.Error(), `"b"`) {
+		t.Errorf("expected skipped path in message, got %q", err.Error())
+	}
+}
+
+func TestCircuitBreaker_ResetOnSuccess(t *testing.T) {
+	b := &circuitBreaker{threshold: 2}
+	decryptErr := errors.New("gpg: decryption failed")
+
+	b.record(decryptErr)
+	b.record(nil)
+	b.record(decryptErr)
+
+	if err := b.allow("a"); err != nil {
+		t.Errorf("expected breaker to stay closed after a success, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_IgnoresNotFound(t *testing.T) {
+	b := &circuitBreaker{threshold: 1}
+	b.record(fmt.Errorf("%w: a", ErrNotFound))
+
+	if err := b.allow("a"); err != nil {
+		t.Errorf("expected not-found errors to be ignored, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	b := &circuitBreaker{threshold: 0}
+	for i := 0; i < 10; i++ {
+		b.record(errors.New("gpg: decryption failed"))
+	}
+
+	if err := b.allow("a"); err != nil {
+		t.Errorf("expected disabled breaker to allow reads, got %v", err)
+	}
+}
+
+func TestGopassClient_GetSecret_CircuitBreaker(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStoreWithSelectiveFailure()
+	client.store = mockStore
+
+	for i := 0; i < 5; i++ {
+		path := fmt.Sprintf("test/broken%d", i)
+		mockStore.failOnGet[path] = true
+	}
+	secret := secrets.New()
+	secret.SetPassword("fine")
+	mockStore.secrets["test/fine"] = secret
+
+	ctx := context.Background()
+
+	for i := 0; i < defaultMaxDecryptFailures; i++ {
+		_, err := client.GetSecret(ctx, fmt.Sprintf("test/broken%d", i))
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("read %d: expected a regular decryption error, got %v", i, err)
+		}
+	}
+
+	// Breaker is now open: even a healthy secret is not attempted
+	_, err := client.GetSecret(ctx, "test/fine")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+}
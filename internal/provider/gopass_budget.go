@@ -0,0 +1,48 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// defaultMaxDecryptedSecrets is the number of distinct secrets a run may
+// decrypt. It is far above what a configuration reads deliberately, but stops
+// a recursive read that accidentally covers a whole organizational store.
+const defaultMaxDecryptedSecrets = 1000
+
+// ErrDecryptLimit is returned for reads refused because the run already
+// decrypted as many secrets as max_decrypted_secrets allows.
+var ErrDecryptLimit = errors.New("too many secrets decrypted in this run")
+
+// decryptBudget caps the number of distinct secrets decrypted per run.
+// Reading the same secret again does not count twice.
+type decryptBudget struct {
+	mu    sync.Mutex
+	limit int // 0 disables the cap
+	paths map[string]bool
+}
+
+// admit returns an error wrapping ErrDecryptLimit if decrypting path would
+// exceed the cap, and counts path otherwise.
+func (b *decryptBudget) admit(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit <= 0 || b.paths[path] {
+		return nil
+	}
+	if len(b.paths) >= b.limit {
+		return fmt.Errorf("%w: refusing to decrypt %q, %d secrets were already decrypted in this run. "+
+			"Check for gopass_env or prefetch_paths entries covering more of the store than intended, "+
+			"or raise max_decrypted_secrets in the provider configuration", ErrDecryptLimit, path, len(b.paths))
+	}
+	if b.paths == nil {
+		b.paths = make(map[string]bool)
+	}
+	b.paths[path] = true
+	return nil
+}
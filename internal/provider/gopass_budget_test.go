@@ -0,0 +1,123 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func newBudgetTestClient(limit, secrets int) (*GopassClient, *mockCountingStore) {
+	entries := make(map[string]string, secrets)
+	for i := range secrets {
+		entries[fmt.Sprintf("org/SECRET_%02d", i)] = "value"
+	}
+	store := &mockCountingStore{SecretStore: NewMemoryStore(entries)}
+
+	client := NewGopassClientWithStore(store)
+	client.budget.limit = limit
+	return client, store
+}
+
+func TestDecryptBudget_Admit(t *testing.T) {
+	budget := decryptBudget{limit: 2}
+
+	for _, path := range []string{"a", "b", "a", "b"} {
+		if err := budget.admit(path); err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+	}
+	err := budget.admit("c")
+	if !errors.Is(err, ErrDecryptLimit) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "max_decrypted_secrets") {
+		t.Errorf("expected a hint at the setting, got %q", err.Error())
+	}
+
+	unlimited := decryptBudget{}
+	for i := range 5 {
+		if err := unlimited.admit(fmt.Sprint(i)); err != nil {
+			t.Fatalf("unexpected error with the cap disabled: %v", err)
+		}
+	}
+}
+
+func TestGopassClient_DecryptLimit(t *testing.T) {
+	client, store := newBudgetTestClient(2, 3)
+	ctx := context.Background()
+
+	for _, path := range []string{"org/SECRET_00", "org/SECRET_01", "org/SECRET_00"} {
+		if _, err := client.GetSecret(ctx, path); err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+	}
+
+	_, err := client.GetSecret(ctx, "org/SECRET_02")
+	if !errors.Is(err, ErrDecryptLimit) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	if store.readCount("org/SECRET_02") != 0 {
+		t.Error("expected the refused read not to reach the store")
+	}
+	if got := errorSummary(err, "fallback"); got != "Too many secrets decrypted" {
+		t.Errorf("unexpected summary %q", got)
+	}
+
+	// The refusal is not a decryption failure
+	if client.breaker.consecutive != 0 {
+		t.Errorf("expected the circuit breaker to be untouched, got %d failures", client.breaker.consecutive)
+	}
+}
+
+func TestGetEnvSecrets_DecryptLimitFailsRead(t *testing.T) {
+	client, _ := newBudgetTestClient(5, 20)
+
+	values, err := client.GetEnvSecrets(context.Background(), "org")
+	if !errors.Is(err, ErrDecryptLimit) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+	if values != nil {
+		t.Errorf("expected no values, got %d", len(values))
+	}
+}
+
+func TestProviderConfigure_MaxDecryptedSecrets(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	tests := []struct {
+		value   tftypes.Value
+		limit   int
+		wantErr bool
+	}{
+		{tftypes.NewValue(tftypes.Number, nil), defaultMaxDecryptedSecrets, false},
+		{tftypes.NewValue(tftypes.Number, 50), 50, false},
+		{tftypes.NewValue(tftypes.Number, 0), 0, false},
+		{tftypes.NewValue(tftypes.Number, -1), 0, true},
+	}
+	for _, tt := range tests {
+		resp := &provider.ConfigureResponse{}
+		p.Configure(ctx, provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{"max_decrypted_secrets": tt.value}),
+		}, resp)
+
+		if resp.Diagnostics.HasError() != tt.wantErr {
+			t.Errorf("%v: unexpected diagnostics %v", tt.value, resp.Diagnostics)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if limit := resp.EphemeralResourceData.(*GopassClient).budget.limit; limit != tt.limit {
+			t.Errorf("%v: expected limit %d, got %d", tt.value, tt.limit, limit)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// defaultSecretCacheTTL is how long a decrypted secret is reused unless
+// cache_ttl says otherwise. It covers a typical plan or apply.
+const defaultSecretCacheTTL = 5 * time.Minute
+
+// secretCache keeps recently decrypted secrets for a limited time, so that
+// repeated reads of the same path during a run decrypt (and touch a hardware
+// token) only once. Entries are keyed by path and revision.
+//
+// Only the serialized form of a secret is kept and every lookup parses a
+// fresh copy, so callers modifying a secret never change the cached one. In
+// secure mode the serialized form is locked, but the parsed copies handed out
+// live on the regular heap like any other read.
+type secretCache struct {
+	// ttl is how long entries are served, 0 disables the cache
+	ttl time.Duration
+	// secure keeps entries in locked memory (secure_memory)
+	secure bool
+
+	mu      sync.Mutex
+	entries map[string]cachedSecret
+}
+
+// cachedSecret is a single cache entry: either body or, in secure mode,
+// locked holds the serialized secret.
+type cachedSecret struct {
+	body    []byte
+	locked  *lockedBuffer
+	expires time.Time
+}
+
+// cacheKey returns the key of the given revision of path.
+func cacheKey(path, revision string) string {
+	return path + "@" + revision
+}
+
+// get returns the cached revision of path if it has not expired at now.
+func (s *secretCache) get(path, revision string, now time.Time) (gopass.Secret, bool) {
+	if s.ttl <= 0 {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := cacheKey(path, revision)
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(entry.expires) {
+		entry.free()
+		delete(s.entries, key)
+		return nil, false
+	}
+
+	if entry.locked != nil {
+		secret := entry.locked.secret()
+		return secret, secret != nil
+	}
+	return parseSecret(entry.body), true
+}
+
+// put caches the revision of path read at now. In secure mode secrets that
+// cannot be locked into memory are not cached at all.
+func (s *secretCache) put(ctx context.Context, c *GopassClient, path, revision string, secret gopass.Secret, now time.Time) {
+	if s.ttl <= 0 {
+		return
+	}
+
+	entry := cachedSecret{expires: now.Add(s.ttl)}
+	if s.secure {
+		buf, err := newLockedBuffer(secret.Bytes())
+		if err != nil {
+			tflog.Debug(ctx, "Not caching secret that cannot be locked in memory", map[string]interface{}{
+				"path":  c.logPath(path),
+				"error": err.Error(),
+			})
+			return
+		}
+		entry.locked = buf
+	} else {
+		entry.body = secret.Bytes()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries == nil {
+		s.entries = make(map[string]cachedSecret)
+	}
+	key := cacheKey(path, revision)
+	if old, ok := s.entries[key]; ok {
+		old.free()
+	}
+	s.entries[key] = entry
+}
+
+// forgetPath drops every cached revision of path, e.g. after it was overwritten.
+func (s *secretCache) forgetPath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.entries {
+		// Revisions never contain "@", paths might
+		if key[:strings.LastIndex(key, "@")] == path {
+			entry.free()
+			delete(s.entries, key)
+		}
+	}
+}
+
+// forget drops all cached secrets. Later reads decrypt again.
+func (s *secretCache) forget() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		entry.free()
+	}
+	clear(s.entries)
+}
+
+// free wipes the locked memory of the entry, if any.
+func (e cachedSecret) free() {
+	if e.locked != nil {
+		e.locked.free()
+	}
+}
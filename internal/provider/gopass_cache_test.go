@@ -0,0 +1,232 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newCacheTestClient returns a client with a one-minute secret cache whose
+// clock the test advances through the returned pointer.
+func newCacheTestClient() (*GopassClient, *mockCountingStore, *time.Time) {
+	store := &mockCountingStore{SecretStore: NewMemoryStore(map[string]string{"app/db": "s3cret\nuser: admin"})}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	client := NewGopassClientWithStore(store)
+	client.cache.ttl = time.Minute
+	client.now = func() time.Time { return now }
+	return client, store, &now
+}
+
+func TestSecretCache_GetPut(t *testing.T) {
+	cache := &secretCache{ttl: time.Minute}
+	ctx := context.Background()
+	client := NewGopassClient("")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := cache.get("app/db", "latest", now); ok {
+		t.Fatal("expected an empty cache")
+	}
+	cache.put(ctx, client, "app/db", "latest", newSecret("s3cret", nil), now)
+
+	secret, ok := cache.get("app/db", "latest", now.Add(59*time.Second))
+	if !ok || secret.Password() != "s3cret" {
+		t.Fatalf("expected the cached secret, got %v", ok)
+	}
+	if _, ok := cache.get("app/db", "c1", now); ok {
+		t.Error("expected revisions to be cached separately")
+	}
+
+	// Lookups hand out copies
+	secret.SetPassword("modified")
+	if secret, _ := cache.get("app/db", "latest", now); secret.Password() != "s3cret" {
+		t.Errorf("expected the cached secret to be unchanged, got %q", secret.Password())
+	}
+
+	if _, ok := cache.get("app/db", "latest", now.Add(time.Minute)); ok {
+		t.Error("expected the entry to expire after the ttl")
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("expected the expired entry to be dropped, got %d", len(cache.entries))
+	}
+}
+
+func TestSecretCache_Disabled(t *testing.T) {
+	cache := &secretCache{}
+	now := time.Now()
+
+	cache.put(context.Background(), NewGopassClient(""), "app/db", "latest", newSecret("s3cret", nil), now)
+	if _, ok := cache.get("app/db", "latest", now); ok {
+		t.Error("expected a cache without ttl to keep nothing")
+	}
+}
+
+func TestSecretCache_Forget(t *testing.T) {
+	cache := &secretCache{ttl: time.Minute}
+	ctx := context.Background()
+	client := NewGopassClient("")
+	now := time.Now()
+
+	for _, path := range []string{"app/db", "app/db@prod", "app/dbx"} {
+		cache.put(ctx, client, path, "latest", newSecret("s3cret", nil), now)
+	}
+	cache.put(ctx, client, "app/db", "c1", newSecret("old", nil), now)
+
+	cache.forgetPath("app/db")
+	if _, ok := cache.get("app/db", "latest", now); ok {
+		t.Error("expected the latest revision to be forgotten")
+	}
+	if _, ok := cache.get("app/db", "c1", now); ok {
+		t.Error("expected older revisions to be forgotten")
+	}
+	for _, path := range []string{"app/db@prod", "app/dbx"} {
+		if _, ok := cache.get(path, "latest", now); !ok {
+			t.Errorf("expected %q to stay cached", path)
+		}
+	}
+
+	cache.forget()
+	if len(cache.entries) != 0 {
+		t.Errorf("expected an empty cache, got %d entries", len(cache.entries))
+	}
+}
+
+func TestSecretCache_SecureMemory(t *testing.T) {
+	newTestLockedBuffer(t, []byte("probe")).free()
+
+	cache := &secretCache{ttl: time.Minute, secure: true}
+	now := time.Now()
+	cache.put(context.Background(), NewGopassClient(""), "app/db", "latest", newSecret("s3cret", nil), now)
+
+	entry := cache.entries[cacheKey("app/db", "latest")]
+	if entry.locked == nil || entry.body != nil {
+		t.Fatalf("expected the secret in locked memory only, got %+v", entry)
+	}
+	if secret, ok := cache.get("app/db", "latest", now); !ok || secret.Password() != "s3cret" {
+		t.Errorf("expected the cached secret, got %v", ok)
+	}
+
+	cache.forget()
+	if entry.locked.bytes() != nil {
+		t.Error("expected forget to free the locked buffer")
+	}
+}
+
+func TestGopassClient_SecretCache(t *testing.T) {
+	client, store, now := newCacheTestClient()
+	ctx := context.Background()
+
+	for range 3 {
+		if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+			t.Fatalf("unexpected result %q (%v)", value, err)
+		}
+	}
+	if got := store.readCount("app/db"); got != 1 {
+		t.Errorf("expected one decryption, got %d", got)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := store.readCount("app/db"); got != 2 {
+		t.Errorf("expected a new decryption after the ttl, got %d", got)
+	}
+}
+
+func TestGopassClient_SecretCache_Writes(t *testing.T) {
+	client, store, _ := newCacheTestClient()
+	ctx := context.Background()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.SetSecret(ctx, "app/db", "rotated"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "rotated" {
+		t.Errorf("expected the written value, got %q (%v)", value, err)
+	}
+
+	if err := client.RemoveSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "app/db"); err == nil {
+		t.Error("expected the removed secret to be gone")
+	}
+
+	if err := store.Set(ctx, "app/db", newPasswordSecret("external")); err != nil {
+		t.Fatal(err)
+	}
+	client.Invalidate(ctx)
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "external" {
+		t.Errorf("expected Invalidate to drop the cache, got %q (%v)", value, err)
+	}
+}
+
+func TestGopassClient_SecretCache_PolicyStillEnforced(t *testing.T) {
+	client, _, _ := newCacheTestClient()
+	ctx := context.Background()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.checksumOnly = true
+	if _, err := client.GetSecret(ctx, "app/db"); err == nil {
+		t.Error("expected cached secrets to be refused in checksum-only mode")
+	}
+}
+
+func TestProviderConfigure_SecretCache(t *testing.T) {
+	tests := map[string]struct {
+		config map[string]tftypes.Value
+		ttl    time.Duration
+	}{
+		"default": {nil, defaultSecretCacheTTL},
+		"ttl": {map[string]tftypes.Value{
+			"cache_ttl": tftypes.NewValue(tftypes.String, "30s"),
+		}, 30 * time.Second},
+		"disabled": {map[string]tftypes.Value{
+			"cache_secrets": tftypes.NewValue(tftypes.Bool, false),
+			"cache_ttl":     tftypes.NewValue(tftypes.String, "30s"),
+		}, 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &GopassProvider{version: "test"}
+			resp := &provider.ConfigureResponse{}
+			p.Configure(context.Background(), provider.ConfigureRequest{
+				Config: newProviderConfig(t, p, tt.config),
+			}, resp)
+
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+			if got := resp.EphemeralResourceData.(*GopassClient).cache.ttl; got != tt.ttl {
+				t.Errorf("expected ttl %v, got %v", tt.ttl, got)
+			}
+		})
+	}
+}
+
+func TestProviderConfigure_InvalidCacheTTL(t *testing.T) {
+	for _, ttl := range []string{"soon", "0s", "-1m"} {
+		p := &GopassProvider{version: "test"}
+		resp := &provider.ConfigureResponse{}
+		p.Configure(context.Background(), provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{
+				"cache_ttl": tftypes.NewValue(tftypes.String, ttl),
+			}),
+		}, resp)
+
+		if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "Invalid cache_ttl" {
+			t.Errorf("%q: expected an invalid cache_ttl error, got %v", ttl, resp.Diagnostics)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// Default per-operation deadlines. They are generous on purpose: a read may
+// include a PIN prompt or a hardware token touch, which takes human time.
+const (
+	defaultInitTimeout  = 2 * time.Minute
+	defaultReadTimeout  = 2 * time.Minute
+	defaultListTimeout  = 1 * time.Minute
+	defaultWriteTimeout = 2 * time.Minute
+)
+
+// operationTimeouts holds the deadline applied to each kind of store call.
+// A zero duration disables the deadline for that operation (the caller's
+// context still applies).
+type operationTimeouts struct {
+	Init  time.Duration
+	Read  time.Duration
+	List  time.Duration
+	Write time.Duration
+	Sync  time.Duration
+}
+
+// defaultOperationTimeouts returns the deadlines used by NewGopassClient.
+func defaultOperationTimeouts() operationTimeouts {
+	return operationTimeouts{
+		Init:  defaultInitTimeout,
+		Read:  defaultReadTimeout,
+		List:  defaultListTimeout,
+		Write: defaultWriteTimeout,
+		Sync:  defaultSyncTimeout,
+	}
+}
+
+// callWithDeadline runs fn with a context bounded by timeout and returns as soon
+// as either fn completes or the context is done.
+//
+// The gopass library does not reliably observe context cancellation while it is
+// waiting on gpg (e.g. for a pinentry dialog), so fn runs in its own goroutine.
+// If the context ends first the call is abandoned: the derived context is
+// cancelled so gpg subprocesses started with it are killed, and the eventual
+// result is discarded. A slot held with holdUntilReturned stays taken until
+// the abandoned call returns.
+func callWithDeadline[T any](ctx context.Context, timeout time.Duration, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	if err := ctx.Err(); err != nil {
+		return zero, contextError(op, 0, err)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+
+	tracker, _ := ctx.Value(callTrackerKey{}).(*callTracker)
+	tracker.start()
+	go func() {
+		value, err := fn(ctx)
+		tracker.finish()
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, contextError(op, timeout, ctx.Err())
+	}
+}
+
+// callTracker counts the calls of callWithDeadline running under a context
+// from holdUntilReturned, including abandoned ones.
+type callTracker struct {
+	mu      sync.Mutex
+	running int
+	release func() // set once the holder is done while calls still run
+}
+
+type callTrackerKey struct{}
+
+func (t *callTracker) start() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.running++
+	t.mu.Unlock()
+}
+
+func (t *callTracker) finish() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.running--
+	var release func()
+	if t.running == 0 {
+		release, t.release = t.release, nil
+	}
+	t.mu.Unlock()
+	if release != nil {
+		release()
+	}
+}
+
+// holdUntilReturned ties release, which frees a slot such as a write or
+// hardware token slot, to the calls made with the returned context. The
+// returned function calls release at once unless one of them was abandoned
+// and is still running: a call abandoned on timeout still holds the token or
+// writes the store, so the slot is freed only when it returns.
+func holdUntilReturned(ctx context.Context, release func()) (context.Context, func()) {
+	t := &callTracker{}
+	return context.WithValue(ctx, callTrackerKey{}, t), func() {
+		t.mu.Lock()
+		if t.running > 0 {
+			t.release = release
+			t.mu.Unlock()
+			return
+		}
+		t.mu.Unlock()
+		release()
+	}
+}
+
+// contextError describes why an operation was aborted, keeping the context
+// error in the chain for errors.Is checks. Expired deadlines are ErrTimeout.
+func contextError(op string, timeout time.Duration, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		if timeout > 0 {
+			return classify(ErrTimeout, fmt.Errorf("%s timed out after %s: %w", op, timeout, err))
+		}
+		return classify(ErrTimeout, fmt.Errorf("%s aborted: %w", op, err))
+	}
+	return fmt.Errorf("%s aborted: %w", op, err)
+}
+
+// operation describes a single store call.
+type operation struct {
+	kind    string // one of the op* constants
+	path    string // secret path, empty for store-wide operations
+	desc    string // human-readable description used in errors and logs
+	timeout time.Duration
+}
+
+// call runs a single store operation with its deadline, retrying transient
+// failures. Each attempt gets its own deadline. The overall duration,
+// including retries, is recorded in the client metrics and traced as one span.
+func call[T any](ctx context.Context, c *GopassClient, op operation, fn func(ctx context.Context) (T, error)) (T, error) {
+	start := time.Now()
+	ctx, span := c.tracer.startSpan(ctx, "gopass."+op.kind, op.path)
+	if op.kind == opWrite || op.kind == opRemove {
+		// gopass commits (and may push) mutations to the store's git repository
+		span.setAttribute("gopass.git", "commit")
+	}
+
+	value, err := withRetry(ctx, c, op.desc, func() (T, error) {
+		return callWithDeadline(ctx, op.timeout, op.desc, fn)
+	})
+
+	duration := time.Since(start)
+	span.end(err)
+	c.metrics.observe(op.kind, duration, err)
+	logOperation(ctx, op.kind, duration, err)
+
+	return value, err
+}
+
+// openStore initializes a new store handle within the init deadline.
+func (c *GopassClient) openStore(ctx context.Context) (SecretStore, error) {
+	op := operation{kind: opInit, desc: "store initialization", timeout: c.timeouts.Init}
+	return call(ctx, c, op, c.newStore)
+}
+
+// storeGet reads the latest revision of a secret, see storeGetRevision.
+func (c *GopassClient) storeGet(ctx context.Context, store SecretStore, path string) (gopass.Secret, error) {
+	return c.storeGetRevision(ctx, store, path, "latest")
+}
+
+// storeGetRevision reads a revision of a secret, serving it from the prefetch
+// pass (latest revisions only) or the secret cache if they have it.
+// Concurrent reads of the same revision share a single decryption.
+func (c *GopassClient) storeGetRevision(ctx context.Context, store SecretStore, path, revision string) (secret gopass.Secret, err error) {
+	defer func() { c.audit.record(ctx, c, path, accessRead, err) }()
+
+	if err := c.enforcePolicy(ctx, path, accessRead); err != nil {
+		return nil, err
+	}
+	c.access.record(ctx, path, accessRead)
+	c.redactor.addPath(path)
+
+	if err := c.integrity.verify(ctx, c); err != nil {
+		return nil, err
+	}
+
+	if c.prefetch != nil && revision == "latest" {
+		c.prefetch.run(ctx, c)
+		start := time.Now()
+		if prefetched, ok := c.prefetch.lookup(path); ok {
+			c.metrics.cacheHit()
+			c.redactor.addValues(prefetched.Password())
+			logRead(ctx, c.logPath(path), readSourcePrefetch, time.Since(start), nil, nil)
+			return prefetched, nil
+		}
+	}
+
+	start := time.Now()
+	if cached, ok := c.cache.get(path, revision, c.now()); ok {
+		c.metrics.cacheHit()
+		c.redactor.addValues(cached.Password())
+		logRead(ctx, c.logPath(path), readSourceCache, time.Since(start), nil, nil)
+		return cached, nil
+	}
+
+	ctx, trace := withReadTrace(ctx)
+	secret, shared, err := c.reads.do(ctx, cacheKey(path, revision), func() (gopass.Secret, error) {
+		return c.decryptSecret(ctx, store, path, revision)
+	})
+	source := readSourceStore
+	if shared {
+		c.metrics.coalesced()
+		source = readSourceShared
+	}
+	if err == nil && secret != nil {
+		c.redactor.addValues(secret.Password())
+		if !shared {
+			c.cache.put(ctx, c, path, revision, secret, c.now())
+		}
+	}
+	logRead(ctx, c.logPath(path), source, time.Since(start), trace, err)
+	return secret, err
+}
+
+// decryptSecret reads a revision of a secret from the store within the read
+// deadline. Reads are refused without touching the store once the circuit
+// breaker is open or the run has decrypted as many secrets as it may.
+func (c *GopassClient) decryptSecret(ctx context.Context, store SecretStore, path, revision string) (gopass.Secret, error) {
+	if err := c.breaker.allow(path); err != nil {
+		return nil, err
+	}
+	if err := c.budget.admit(path); err != nil {
+		return nil, err
+	}
+
+	trace := readTraceFrom(ctx)
+
+	waitStart := time.Now()
+	release, err := c.acquireDecryptSlot(ctx)
+	if err != nil {
+		return nil, contextError(fmt.Sprintf("waiting for hardware token to read %q", path), 0, err)
+	}
+	// A read abandoned on timeout may still be waiting for a touch
+	ctx, release = holdUntilReturned(ctx, release)
+	defer release()
+	if trace != nil {
+		trace.tokenWait.Store(int64(time.Since(waitStart)))
+	}
+
+	desc := fmt.Sprintf("reading secret %q", path)
+	if revision != "latest" {
+		desc = fmt.Sprintf("reading revision %q of secret %q", revision, path)
+	}
+	op := operation{kind: opRead, path: path, desc: desc, timeout: c.timeouts.Read}
+	secret, err := call(ctx, c, op, func(ctx context.Context) (gopass.Secret, error) {
+		start := time.Now()
+		defer func() {
+			// Only the last attempt counts, earlier ones failed fast
+			if trace != nil {
+				trace.decrypt.Store(int64(time.Since(start)))
+			}
+		}()
+		return store.Get(ctx, path, revision)
+	})
+	err = classifyReadError(err)
+	if c.decryptSlots != nil && errors.Is(err, ErrTimeout) {
+		err = &tokenTimeoutError{err: err, timeout: c.timeouts.Read}
+	}
+	if c.nonInteractive && errors.Is(err, ErrDecryptionFailed) && needsUnlock(err) {
+		err = &nonInteractiveError{err: err}
+	}
+	if c.decryptSlots != nil && err == nil {
+		c.forecast.record(path)
+	}
+	c.breaker.record(err)
+	return secret, err
+}
+
+// storeList lists all secrets within the list deadline.
+func (c *GopassClient) storeList(ctx context.Context, store SecretStore) ([]string, error) {
+	op := operation{kind: opList, desc: "listing secrets", timeout: c.timeouts.List}
+	return call(ctx, c, op, store.List)
+}
+
+// storeSet writes a secret within the write deadline. Writes are serialized
+// through the client's write queue. vet, if set, checks the value once the
+// write is known to be allowed, before it waits for the queue.
+func (c *GopassClient) storeSet(ctx context.Context, store SecretStore, path string, secret gopass.Byter, vet func(ctx context.Context) error) (err error) {
+	defer func() { c.audit.record(ctx, c, path, accessWrite, err) }()
+
+	if err := c.checkWritable(fmt.Sprintf("write secret %q", path)); err != nil {
+		return err
+	}
+	if err := c.enforcePolicy(ctx, path, accessWrite); err != nil {
+		return err
+	}
+	if err := c.checkRecipients(path); err != nil {
+		return err
+	}
+	if vet != nil {
+		if err := vet(ctx); err != nil {
+			return err
+		}
+	}
+
+	release, err := c.writes.acquire(ctx)
+	if err != nil {
+		return contextError(fmt.Sprintf("waiting to write secret %q", path), 0, err)
+	}
+	ctx, release = holdUntilReturned(ctx, release)
+	defer release()
+
+	c.access.record(ctx, path, accessWrite)
+	c.redactor.addPath(path)
+	op := operation{kind: opWrite, path: path, desc: fmt.Sprintf("writing secret %q", path), timeout: c.timeouts.Write}
+	_, err = call(ctx, c, op, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, store.Set(ctx, path, secret)
+	})
+	return err
+}
+
+// storeRemove removes a secret within the write deadline. Removals are
+// serialized through the client's write queue.
+func (c *GopassClient) storeRemove(ctx context.Context, store SecretStore, path string) (err error) {
+	defer func() { c.audit.record(ctx, c, path, accessRemove, err) }()
+
+	if err := c.checkWritable(fmt.Sprintf("remove secret %q", path)); err != nil {
+		return err
+	}
+	if err := c.enforcePolicy(ctx, path, accessRemove); err != nil {
+		return err
+	}
+
+	release, err := c.writes.acquire(ctx)
+	if err != nil {
+		return contextError(fmt.Sprintf("waiting to remove secret %q", path), 0, err)
+	}
+	ctx, release = holdUntilReturned(ctx, release)
+	defer release()
+
+	c.access.record(ctx, path, accessRemove)
+	c.redactor.addPath(path)
+	op := operation{kind: opRemove, path: path, desc: fmt.Sprintf("removing secret %q", path), timeout: c.timeouts.Write}
+	_, err = call(ctx, c, op, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, store.Remove(ctx, path)
+	})
+	return err
+}
+
+// storeRevisions lists the revisions of a secret within the read deadline.
+func (c *GopassClient) storeRevisions(ctx context.Context, store SecretStore, path string) ([]string, error) {
+	op := operation{kind: opRevisions, path: path, desc: fmt.Sprintf("listing revisions of %q", path), timeout: c.timeouts.Read}
+	return call(ctx, c, op, func(ctx context.Context) ([]string, error) {
+		return store.Revisions(ctx, path)
+	})
+}
@@ -0,0 +1,191 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// mockBlockingStore blocks every Get until the context is done
+type mockBlockingStore struct {
+	*mockStore
+}
+
+func (m *mockBlockingStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// mockSlowPathStore blocks Get for selected paths until the context is done
+type mockSlowPathStore struct {
+	*mockStore
+	slow map[string]bool
+}
+
+func (m *mockSlowPathStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	if m.slow[name] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return m.mockStore.Get(ctx, name, revision)
+}
+
+func TestCallWithDeadline_Success(t *testing.T) {
+	value, err := callWithDeadline(context.Background(), time.Second, "test op", func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if value != "ok" {
+		t.Errorf("expected 'ok', got %q", value)
+	}
+}
+
+func TestCallWithDeadline_PropagatesError(t *testing.T) {
+	opErr := errors.New("op failed")
+	_, err := callWithDeadline(context.Background(), time.Second, "test op", func(ctx context.Context) (string, error) {
+		return "", opErr
+	})
+	if !errors.Is(err, opErr) {
+		t.Errorf("expected op error, got %v", err)
+	}
+}
+
+func TestCallWithDeadline_Timeout(t *testing.T) {
+	start := time.Now()
+	_, err := callWithDeadline(context.Background(), 20*time.Millisecond, "test op", func(ctx context.Context) (string, error) {
+		// Ignore the context entirely, like a gpg call stuck on pinentry
+		time.Sleep(time.Second)
+		return "late", nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "test op timed out after 20ms") {
+		t.Errorf("expected timeout message, got %q", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected call to return promptly, took %s", elapsed)
+	}
+}
+
+func TestCallWithDeadline_AlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	_, err := callWithDeadline(ctx, time.Second, "test op", func(ctx context.Context) (string, error) {
+		called = true
+		return "", nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+	if called {
+		t.Error("expected fn not to be called with a cancelled context")
+	}
+}
+
+func TestGopassClient_GetSecret_Cancellation(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = &mockBlockingStore{mockStore: newMockStore()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := client.GetSecret(ctx, "test/secret")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+}
+
+func TestGopassClient_GetSecret_ReadTimeout(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = &mockBlockingStore{mockStore: newMockStore()}
+	client.timeouts.Read = 20 * time.Millisecond
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestGopassClient_EnsureStore_InitTimeout(t *testing.T) {
+	client := NewGopassClient("")
+	client.timeouts.Init = 20 * time.Millisecond
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	err := client.ensureStore(context.Background())
+	if err == nil {
+		t.Fatal("expected error but got none")
+	}
+	if !strings.Contains(err.Error(), "store initialization timed out") {
+		t.Errorf("expected init timeout error, got %v", err)
+	}
+	if client.store != nil {
+		t.Error("expected store to remain nil after timeout")
+	}
+}
+
+func TestGopassClient_GetEnvSecrets_PartialOnTimeout(t *testing.T) {
+	store := &mockSlowPathStore{mockStore: newMockStore(), slow: map[string]bool{"env/SLOW": true}}
+	for _, name := range []string{"env/FAST", "env/SLOW"} {
+		secret := secrets.New()
+		secret.SetPassword("value")
+		store.secrets[name] = secret
+	}
+
+	client := NewGopassClient("")
+	client.store = store
+	client.timeouts.Read = 20 * time.Millisecond
+
+	values, err := client.GetEnvSecrets(context.Background(), "env")
+
+	var partial *PartialResultError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected PartialResultError, got %v", err)
+	}
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected partial result to match ErrTimeout, got %v", err)
+	}
+	if len(partial.TimedOut) != 1 || partial.TimedOut[0] != "env/SLOW" {
+		t.Errorf("expected env/SLOW to be reported, got %v", partial.TimedOut)
+	}
+	if values["FAST"] != "value" || len(values) != 1 {
+		t.Errorf("expected the fast secret only, got %v", values)
+	}
+}
+
+func TestGopassClient_GetEnvSecrets_OtherFailuresNotPartial(t *testing.T) {
+	store := newMockStoreWithSelectiveFailure()
+	secret := secrets.New()
+	secret.SetPassword("value")
+	store.secrets["env/BROKEN"] = secret
+	store.failOnGet["env/BROKEN"] = true
+
+	client := NewGopassClient("")
+	client.store = store
+
+	values, err := client.GetEnvSecrets(context.Background(), "env")
+	if err != nil {
+		t.Fatalf("expected failed reads to be skipped silently, got %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("expected no values, got %v", values)
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"filippo.io/age"
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Backends recording the gopass store's responses to a cassette, and
+// replaying them from one instead of using a store.
+const (
+	backendRecord = "record"
+	backendReplay = "replay"
+)
+
+// Environment variables selecting the cassette file and the passphrase it is
+// encrypted with. The passphrase is only read from the environment, so it
+// never appears in a configuration.
+const (
+	cassetteEnv           = "GOPASS_PROVIDER_CASSETTE"
+	cassettePassphraseEnv = "GOPASS_PROVIDER_CASSETTE_PASSPHRASE"
+)
+
+// cassetteVersion is the version of the cassette format.
+const cassetteVersion = 1
+
+// Store operations recorded on a cassette.
+const (
+	cassetteGet       = "get"
+	cassetteList      = "list"
+	cassetteSet       = "set"
+	cassetteRemove    = "remove"
+	cassetteRevisions = "revisions"
+)
+
+// ErrNotRecorded is returned in replay mode for store operations the cassette
+// holds no response for.
+var ErrNotRecorded = errors.New("store operation not recorded on the cassette")
+
+// cassette is the decrypted content of a cassette file.
+type cassette struct {
+	Version      int                   `json:"version"`
+	Interactions []cassetteInteraction `json:"interactions"`
+}
+
+// cassetteInteraction is one store operation and the store's response.
+type cassetteInteraction struct {
+	Op       string `json:"op"`
+	Name     string `json:"name,omitempty"` // secret path; the mount prefix for list
+	Revision string `json:"revision,omitempty"`
+	// Body is the entry returned by get. Bodies written by set are not recorded
+	Body     string   `json:"body,omitempty"`
+	Names    []string `json:"names,omitempty"` // result of list and revisions
+	Error    string   `json:"error,omitempty"`
+	NotFound bool     `json:"not_found,omitempty"`
+}
+
+// key identifies the requests an interaction answers on replay.
+func (i *cassetteInteraction) key() string {
+	return i.Op + "\x00" + i.Name + "\x00" + i.Revision
+}
+
+// err returns the recorded error, keeping a not found error recognizable.
+func (i *cassetteInteraction) err() error {
+	switch {
+	case i.NotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, i.Error)
+	case i.Error != "":
+		return errors.New(i.Error)
+	default:
+		return nil
+	}
+}
+
+// newInteraction returns the interaction for an operation with its error.
+func newInteraction(op, name, revision string, err error) cassetteInteraction {
+	i := cassetteInteraction{Op: op, Name: name, Revision: revision}
+	if err != nil {
+		i.Error = err.Error()
+		i.NotFound = isNotFound(err)
+	}
+	return i
+}
+
+// cassetteRecorder collects the interactions of a run and writes them to an
+// encrypted cassette file when the client closes.
+type cassetteRecorder struct {
+	path       string
+	passphrase string
+	workFactor int // scrypt work factor; 0 uses age's default
+
+	mu           sync.Mutex
+	interactions []cassetteInteraction
+}
+
+// add appends an interaction.
+func (r *cassetteRecorder) add(i cassetteInteraction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, i)
+}
+
+// wrap returns store recording its responses, with prefix prepended to the
+// secret paths of a mounted store. It returns store as is on a nil recorder.
+func (r *cassetteRecorder) wrap(store SecretStore, prefix string) SecretStore {
+	if r == nil || store == nil {
+		return store
+	}
+	return &recordingStore{recorder: r, prefix: prefix, inner: store}
+}
+
+// save writes all interactions recorded so far to the cassette. Failures are
+// logged; they must not fail a run that succeeded. It is safe on a nil
+// recorder.
+func (r *cassetteRecorder) save(ctx context.Context) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	count := len(r.interactions)
+	data, err := json.Marshal(cassette{Version: cassetteVersion, Interactions: r.interactions})
+	r.mu.Unlock()
+	if err == nil {
+		err = writeCassette(r.path, r.passphrase, r.workFactor, data)
+	}
+	if err != nil {
+		tflog.Warn(ctx, "Could not write the cassette", map[string]interface{}{
+			"path":  r.path,
+			"error": err.Error(),
+		})
+		return
+	}
+	tflog.Info(ctx, "Recorded gopass store interactions", map[string]interface{}{
+		"path":         r.path,
+		"interactions": count,
+	})
+}
+
+// writeCassette encrypts data with passphrase and replaces the file at path.
+func writeCassette(path, passphrase string, workFactor int, data []byte) error {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return err
+	}
+	if workFactor > 0 {
+		recipient.SetWorkFactor(workFactor)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cassette-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w, err := age.Encrypt(tmp, recipient)
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readCassette decrypts the cassette at path with passphrase.
+func readCassette(path, passphrase string) (*cassette, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from the provider configuration
+	if err != nil {
+		return nil, err
+	}
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt cassette %s, check %s: %w", path, cassettePassphraseEnv, err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cassette
+	if err := json.Unmarshal(plain, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if c.Version != cassetteVersion {
+		return nil, fmt.Errorf("invalid cassette %s: unsupported version %d", path, c.Version)
+	}
+	return &c, nil
+}
+
+// recordingStore passes operations to a store and records its responses.
+type recordingStore struct {
+	recorder *cassetteRecorder
+	prefix   string
+	inner    SecretStore
+}
+
+func (s *recordingStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	secret, err := s.inner.Get(ctx, name, revision)
+	i := newInteraction(cassetteGet, s.prefix+name, revision, err)
+	if err == nil {
+		i.Body = string(secret.Bytes())
+	}
+	s.recorder.add(i)
+	return secret, err
+}
+
+func (s *recordingStore) List(ctx context.Context) ([]string, error) {
+	names, err := s.inner.List(ctx)
+	i := newInteraction(cassetteList, s.prefix, "", err)
+	for _, name := range names {
+		i.Names = append(i.Names, s.prefix+name)
+	}
+	s.recorder.add(i)
+	return names, err
+}
+
+func (s *recordingStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	err := s.inner.Set(ctx, name, sec)
+	s.recorder.add(newInteraction(cassetteSet, s.prefix+name, "", err))
+	return err
+}
+
+func (s *recordingStore) Remove(ctx context.Context, name string) error {
+	err := s.inner.Remove(ctx, name)
+	s.recorder.add(newInteraction(cassetteRemove, s.prefix+name, "", err))
+	return err
+}
+
+func (s *recordingStore) Revisions(ctx context.Context, name string) ([]string, error) {
+	revisions, err := s.inner.Revisions(ctx, name)
+	i := newInteraction(cassetteRevisions, s.prefix+name, "", err)
+	i.Names = revisions
+	s.recorder.add(i)
+	return revisions, err
+}
+
+// Close closes the recorded store if it supports closing.
+func (s *recordingStore) Close(ctx context.Context) error {
+	if closer, ok := s.inner.(interface{ Close(context.Context) error }); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// ReplayStore is a SecretStore answering from a recorded cassette. Each
+// request gets the responses recorded for it in order; once they are used up,
+// the last one repeats, so a secret read again after a write returns what the
+// store returned then. Mounted stores are part of the cassette. It is safe for
+// concurrent use.
+type ReplayStore struct {
+	mu        sync.Mutex
+	responses map[string][]cassetteInteraction
+	// scopes holds the list responses of the root store and each mount
+	scopes map[string]bool
+}
+
+// Ensure ReplayStore satisfies SecretStore.
+var _ SecretStore = (*ReplayStore)(nil)
+
+// LoadReplayStore returns a ReplayStore answering from the cassette at path,
+// decrypted with passphrase.
+func LoadReplayStore(path, passphrase string) (*ReplayStore, error) {
+	c, err := readCassette(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ReplayStore{responses: make(map[string][]cassetteInteraction), scopes: make(map[string]bool)}
+	for _, i := range c.Interactions {
+		s.responses[i.key()] = append(s.responses[i.key()], i)
+		if i.Op == cassetteList {
+			s.scopes[i.Name] = true
+		}
+	}
+	return s, nil
+}
+
+// next returns the next response recorded for the request.
+func (s *ReplayStore) next(op, name, revision string) (cassetteInteraction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := (&cassetteInteraction{Op: op, Name: name, Revision: revision}).key()
+	responses := s.responses[key]
+	if len(responses) == 0 {
+		return cassetteInteraction{}, fmt.Errorf("%w: %s %q", ErrNotRecorded, op, name)
+	}
+	if len(responses) > 1 {
+		s.responses[key] = responses[1:]
+	}
+	return responses[0], nil
+}
+
+func (s *ReplayStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	i, err := s.next(cassetteGet, name, revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.err(); err != nil {
+		return nil, err
+	}
+	return parseSecret([]byte(i.Body)), nil
+}
+
+// List returns the recorded listings of the root store and all mounts.
+func (s *ReplayStore) List(ctx context.Context) ([]string, error) {
+	scopes := make([]string, 0, len(s.scopes))
+	for scope := range s.scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, cassetteList)
+	}
+
+	var names []string
+	for _, scope := range scopes {
+		i, err := s.next(cassetteList, scope, "")
+		if err != nil {
+			return nil, err
+		}
+		if err := i.err(); err != nil {
+			return nil, err
+		}
+		names = append(names, i.Names...)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *ReplayStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	i, err := s.next(cassetteSet, name, "")
+	if err != nil {
+		return err
+	}
+	return i.err()
+}
+
+func (s *ReplayStore) Remove(ctx context.Context, name string) error {
+	i, err := s.next(cassetteRemove, name, "")
+	if err != nil {
+		return err
+	}
+	return i.err()
+}
+
+func (s *ReplayStore) Revisions(ctx context.Context, name string) ([]string, error) {
+	i, err := s.next(cassetteRevisions, name, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := i.err(); err != nil {
+		return nil, err
+	}
+	return i.Names, nil
+}
@@ -0,0 +1,243 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+const cassetteTestPassphrase = "correct horse battery staple"
+
+// newTestRecorder returns a recorder for path with a cheap key derivation.
+func newTestRecorder(path string) *cassetteRecorder {
+	return &cassetteRecorder{path: path, passphrase: cassetteTestPassphrase, workFactor: 10}
+}
+
+// cassetteScenario runs the same operations against a client, recording or
+// replaying, and returns their results.
+func cassetteScenario(t *testing.T, client *GopassClient) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	var results []string
+	add := func(value string, err error) {
+		if err != nil {
+			value = errorSummary(err, "error")
+		}
+		results = append(results, value)
+	}
+
+	add(client.GetSecret(ctx, "app/db"))
+	add(client.GetSecret(ctx, "app/missing"))
+	_, fields, err := client.GetSecretFull(ctx, "app/db")
+	add(fields["username"], err)
+	add("", client.SetSecret(ctx, "app/db", "changed"))
+	add(client.GetSecret(ctx, "app/db"))
+	env, err := client.GetEnvSecrets(ctx, "app/env")
+	add(env["TOKEN"], err)
+	names, err := client.ListSecrets(ctx, "app")
+	add(strings.Join(names, ","), err)
+	add("", client.RemoveSecret(ctx, "app/env/TOKEN"))
+	exists, err := client.SecretExists(ctx, "app/env/TOKEN")
+	if exists {
+		add("exists", err)
+	} else {
+		add("gone", err)
+	}
+	return results
+}
+
+func TestCassette_RecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+
+	recording := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"app/db":          "s3cret\nusername: admin",
+		"app/env/TOKEN":   "token-value",
+		"app/env/API_KEY": "key-value",
+	}))
+	recording.cassette = newTestRecorder(path)
+	recorded := cassetteScenario(t, recording)
+	recording.Close(context.Background())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected a cassette: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") || strings.Contains(string(data), "app/db") {
+		t.Error("expected the cassette to be encrypted")
+	}
+
+	store, err := LoadReplayStore(path, cassetteTestPassphrase)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replayed := cassetteScenario(t, NewGopassClientWithStore(store))
+
+	if !slices.Equal(recorded, replayed) {
+		t.Errorf("expected the replay to match the recording:\nrecorded %q\nreplayed %q", recorded, replayed)
+	}
+	if recorded[0] != "s3cret" || recorded[1] != "Secret not found" || recorded[4] != "changed" {
+		t.Errorf("unexpected recording %q", recorded)
+	}
+}
+
+func TestReplayStore_NotRecorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	recorder := newTestRecorder(path)
+	recorder.add(cassetteInteraction{Op: cassetteGet, Name: "app/db", Revision: "latest", Body: "s3cret"})
+	recorder.save(context.Background())
+
+	store, err := LoadReplayStore(path, cassetteTestPassphrase)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := NewGopassClientWithStore(store)
+	ctx := context.Background()
+
+	_, err = client.GetSecret(ctx, "app/other")
+	if !errors.Is(err, ErrNotRecorded) {
+		t.Fatalf("expected a not recorded error, got %v", err)
+	}
+	if got := errorSummary(err, "fallback"); got != "Store operation not recorded" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if client.breaker.consecutive != 0 {
+		t.Error("expected a missing recording not to count as a decryption failure")
+	}
+	if err := client.SetSecret(ctx, "app/db", "value"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected an unrecorded write to fail, got %v", err)
+	}
+
+	// The last response repeats once the recorded ones are used up
+	for range 3 {
+		if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+			t.Fatalf("expected the recorded value, got %q (%v)", value, err)
+		}
+	}
+}
+
+func TestCassette_Mounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+
+	recording := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "root"}))
+	team := NewMemoryStore(map[string]string{"KEY": "team-value"})
+	recording.addMount("team/", func(ctx context.Context) (SecretStore, error) { return team, nil })
+	recording.cassette = newTestRecorder(path)
+	ctx := context.Background()
+	if value, err := recording.GetSecret(ctx, "team/KEY"); err != nil || value != "team-value" {
+		t.Fatalf("unexpected read %q (%v)", value, err)
+	}
+	if _, err := recording.ListSecrets(ctx, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recording.Close(ctx)
+
+	store, err := LoadReplayStore(path, cassetteTestPassphrase)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret, err := store.Get(ctx, "team/KEY", "latest"); err != nil || secret.Password() != "team-value" {
+		t.Errorf("expected the mounted secret at its full path, got %v (%v)", secret, err)
+	}
+	names, err := store.List(ctx)
+	if err != nil || !slices.Equal(names, []string{"app/db", "team/KEY"}) {
+		t.Errorf("expected the listings of all stores, got %v (%v)", names, err)
+	}
+}
+
+func TestLoadReplayStore_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	newTestRecorder(path).save(context.Background())
+
+	if _, err := LoadReplayStore(path, "wrong"); err == nil || !strings.Contains(err.Error(), cassettePassphraseEnv) {
+		t.Errorf("expected a hint at the passphrase, got %v", err)
+	}
+	if _, err := LoadReplayStore(writeTemp(t, `{"version":1}`), cassetteTestPassphrase); err == nil {
+		t.Error("expected an unencrypted cassette to be rejected")
+	}
+	if _, err := LoadReplayStore(filepath.Join(t.TempDir(), "missing"), cassetteTestPassphrase); err == nil {
+		t.Error("expected a missing cassette to fail")
+	}
+}
+
+func TestProviderConfigure_Cassette(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.cassette")
+	t.Setenv(cassettePassphraseEnv, cassetteTestPassphrase)
+
+	resp := configureTestProvider(t, map[string]tftypes.Value{
+		"backend":  tftypes.NewValue(tftypes.String, "record"),
+		"cassette": tftypes.NewValue(tftypes.String, path),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.EphemeralResourceData.(*GopassClient); client.cassette == nil || client.cassette.path != path {
+		t.Fatalf("expected recording to %s, got %+v", path, client.cassette)
+	}
+
+	recorder := newTestRecorder(path)
+	recorder.add(cassetteInteraction{Op: cassetteGet, Name: "app/db", Revision: "latest", Body: "s3cret"})
+	recorder.save(context.Background())
+
+	t.Setenv(backendEnv, "replay")
+	t.Setenv(cassetteEnv, path)
+	resp = configureTestProvider(t, map[string]tftypes.Value{
+		"store_path": tftypes.NewValue(tftypes.String, "/nonexistent/store"),
+		"mounts": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+			"team/": tftypes.NewValue(tftypes.String, "/nonexistent/team"),
+		}),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	client := resp.EphemeralResourceData.(*GopassClient)
+	if client.storePath != "" || len(client.mounts) != 0 {
+		t.Errorf("expected no store on disk, got %q and %d mount(s)", client.storePath, len(client.mounts))
+	}
+	if value, err := client.GetSecret(context.Background(), "app/db"); err != nil || value != "s3cret" {
+		t.Errorf("expected the recorded value, got %q (%v)", value, err)
+	}
+}
+
+func TestProviderConfigure_CassetteErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cassette")
+	tests := map[string]struct {
+		passphrase string
+		config     map[string]tftypes.Value
+	}{
+		"cassette without record or replay": {cassetteTestPassphrase, map[string]tftypes.Value{
+			"cassette": tftypes.NewValue(tftypes.String, path),
+		}},
+		"missing cassette": {cassetteTestPassphrase, map[string]tftypes.Value{
+			"backend": tftypes.NewValue(tftypes.String, "record"),
+		}},
+		"missing passphrase": {"", map[string]tftypes.Value{
+			"backend":  tftypes.NewValue(tftypes.String, "record"),
+			"cassette": tftypes.NewValue(tftypes.String, path),
+		}},
+		"missing directory": {cassetteTestPassphrase, map[string]tftypes.Value{
+			"backend":  tftypes.NewValue(tftypes.String, "record"),
+			"cassette": tftypes.NewValue(tftypes.String, "/nonexistent/run.cassette"),
+		}},
+		"replay of missing cassette": {cassetteTestPassphrase, map[string]tftypes.Value{
+			"backend":  tftypes.NewValue(tftypes.String, "replay"),
+			"cassette": tftypes.NewValue(tftypes.String, path),
+		}},
+	}
+	for name, tt := range tests {
+		t.Setenv(cassettePassphraseEnv, tt.passphrase)
+		if resp := configureTestProvider(t, tt.config); !resp.Diagnostics.HasError() {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
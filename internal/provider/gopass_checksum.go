@@ -0,0 +1,84 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ErrChecksumOnly is returned for plaintext reads refused because the
+// provider is configured checksum-only.
+var ErrChecksumOnly = errors.New("provider is configured checksum-only")
+
+// checkPlaintext returns an error wrapping ErrChecksumOnly if the client must
+// not hand out secret content. Every read returning values goes through it,
+// so no resource can see plaintext through a checksum-only provider.
+func (c *GopassClient) checkPlaintext(path string) error {
+	if !c.checksumOnly {
+		return nil
+	}
+	return fmt.Errorf("%w: refusing to read the value of %q.\n\n"+
+		"This provider only exposes checksums and metadata. Use the gopass_secret_checksum "+
+		"data source to check the secret, or a separate provider alias without checksum_only "+
+		"to read values", ErrChecksumOnly, path)
+}
+
+// secretChecksum returns the SHA-256 of the whole secret body, including
+// its key-value lines, so that any change to the entry changes it.
+func secretChecksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// SecretDigest holds what can be derived from a secret without exposing it.
+type SecretDigest struct {
+	Checksum string    // see secretChecksum
+	Length   int       // length of the password in characters
+	Keys     []string  // sorted key names, without their values
+	Expires  time.Time // see secretExpiry, zero if there is none
+}
+
+// SecretDigest returns the derived outputs of the secret at path and whether
+// it exists. A missing secret is not an error. The secret is decrypted to
+// compute them, but its content never leaves the client, so this works in
+// checksum-only mode. An unusable expiry is reported as a warning.
+func (c *GopassClient) SecretDigest(ctx context.Context, path string) (digest SecretDigest, exists bool, err error) {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return SecretDigest{}, false, err
+	}
+	defer release()
+
+	tflog.Debug(ctx, "Computing secret digest", map[string]interface{}{
+		"path": c.logPath(path),
+	})
+
+	secret, err := c.storeGet(ctx, store, path)
+	if errors.Is(err, ErrNotFound) {
+		return SecretDigest{}, false, nil
+	}
+	if err != nil {
+		return SecretDigest{}, false, c.readError(ctx, store, path, err)
+	}
+
+	digest = SecretDigest{
+		Checksum: secretChecksum(secret.Bytes()),
+		Length:   utf8.RuneCountInString(secret.Password()),
+		Keys:     append([]string{}, secret.Keys()...),
+	}
+	slices.Sort(digest.Keys)
+	digest.Keys = slices.Compact(digest.Keys)
+
+	digest.Expires = c.expiryOf(ctx, path, secret)
+	return digest, true, nil
+}
@@ -0,0 +1,271 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func newChecksumTestClient() (*GopassClient, *mockCountingStore) {
+	store := &mockCountingStore{SecretStore: NewMemoryStore(map[string]string{
+		"app/db":      "s3cret\nuser: admin",
+		"app/env/KEY": "key-value",
+	})}
+
+	client := NewGopassClientWithStore(store)
+	client.checksumOnly = true
+	return client, store
+}
+
+func TestChecksumOnly_RefusesPlaintextReads(t *testing.T) {
+	client, store := newChecksumTestClient()
+	ctx := context.Background()
+
+	reads := map[string]func() error{
+		"GetSecret": func() error {
+			_, err := client.GetSecret(ctx, "app/db")
+			return err
+		},
+		"GetSecretFull": func() error {
+			_, _, err := client.GetSecretFull(ctx, "app/db")
+			return err
+		},
+		"GetSecretFields": func() error {
+			_, _, err := client.GetSecretFields(ctx, "app/db", "user")
+			return err
+		},
+		"GetSecretBase64": func() error {
+			_, err := client.GetSecretBase64(ctx, "app/db")
+			return err
+		},
+		"GetEnvSecrets": func() error {
+			_, err := client.GetEnvSecrets(ctx, "app/env")
+			return err
+		},
+	}
+	for name, read := range reads {
+		err := read()
+		if !errors.Is(err, ErrChecksumOnly) {
+			t.Errorf("%s: expected checksum-only error, got %v", name, err)
+			continue
+		}
+		if got := errorSummary(err, "fallback"); got != "Provider is checksum-only" {
+			t.Errorf("%s: unexpected summary %q", name, got)
+		}
+	}
+	if len(store.reads) != 0 {
+		t.Errorf("expected no decryption, got reads %v", store.reads)
+	}
+}
+
+func TestSecretDigest(t *testing.T) {
+	client, store := newChecksumTestClient()
+	ctx := context.Background()
+
+	digest, exists, err := client.SecretDigest(ctx, "app/db")
+	if err != nil || !exists {
+		t.Fatalf("expected an existing secret, got %v (%v)", exists, err)
+	}
+	if !strings.HasPrefix(digest.Checksum, "sha256:") || len(digest.Checksum) != len("sha256:")+64 {
+		t.Errorf("unexpected checksum format %q", digest.Checksum)
+	}
+	if strings.Contains(digest.Checksum, "s3cret") {
+		t.Error("checksum contains the value")
+	}
+	if digest.Length != len("s3cret") || !slices.Equal(digest.Keys, []string{"user"}) || !digest.Expires.IsZero() {
+		t.Errorf("unexpected digest %+v", digest)
+	}
+
+	// A change to any key changes the checksum, not only the password
+	if err := store.Set(ctx, "app/db", newSecret("s3cret", map[string]string{"user": "root"})); err != nil {
+		t.Fatal(err)
+	}
+	changed, _, err := client.SecretDigest(ctx, "app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed.Checksum == digest.Checksum {
+		t.Error("expected the checksum to change with the secret")
+	}
+
+	digest, exists, err = client.SecretDigest(ctx, "app/missing")
+	if err != nil || exists || digest.Checksum != "" {
+		t.Errorf("expected a missing secret without error, got %+v %v (%v)", digest, exists, err)
+	}
+}
+
+func TestSecretDigest_Derived(t *testing.T) {
+	client, store := newChecksumTestClient()
+	secret := newSecret("pässwörd", map[string]string{"username": "admin", "expires": "2030-06-01", "host": "db.internal"})
+	if err := store.Set(context.Background(), "app/api", secret); err != nil {
+		t.Fatal(err)
+	}
+
+	digest, _, err := client.SecretDigest(context.Background(), "app/api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digest.Length != 8 {
+		t.Errorf("expected the length in characters, got %d", digest.Length)
+	}
+	if want := []string{"expires", "host", "username"}; !slices.Equal(digest.Keys, want) {
+		t.Errorf("expected keys %v, got %v", want, digest.Keys)
+	}
+	if want := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC); !digest.Expires.Equal(want) {
+		t.Errorf("expected expiry %v, got %v", want, digest.Expires)
+	}
+	if warnings := client.takeWarnings(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestSecretDigest_InvalidExpiry(t *testing.T) {
+	client, store := newChecksumTestClient()
+	if err := store.Set(context.Background(), "app/db", newSecret("s3cret", map[string]string{"user": "admin", "expires": "next tuesday"})); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		digest, _, err := client.SecretDigest(context.Background(), "app/db")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !digest.Expires.IsZero() {
+			t.Errorf("expected no expiry, got %v", digest.Expires)
+		}
+	}
+	warnings := client.takeWarnings()
+	if len(warnings) != 1 || warnings[0].Summary() != "Secret expiry ignored" {
+		t.Errorf("expected one warning, got %v", warnings)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	tests := map[string]time.Time{
+		"2030-06-01":                time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC),
+		" 2030-06-01T12:00:00Z ":    time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC),
+		"2030-06-01T14:00:00+02:00": time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		if got, err := parseExpiry(value); err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("%q: expected %v, got %v (%v)", value, want, got, err)
+		}
+	}
+	for _, value := range []string{"", "01.06.2030", "2030-13-01"} {
+		if _, err := parseExpiry(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestSecretEphemeralResource_Open_ChecksumOnly(t *testing.T) {
+	client, _ := newChecksumTestClient()
+
+	resp := openSecretEphemeral(t, client, "app/db")
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected the read to be refused")
+	}
+	if got := resp.Diagnostics.Errors()[0].Summary(); got != "Provider is checksum-only" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+// readChecksumDataSource reads a gopass_secret_checksum data source for path.
+func readChecksumDataSource(t *testing.T, client *GopassClient, path string) (*datasource.ReadResponse, SecretChecksumModel) {
+	t.Helper()
+
+	d := &SecretChecksumDataSource{client: client}
+	ctx := context.Background()
+	schemaResp := &datasource.SchemaResponse{}
+	d.Schema(ctx, datasource.SchemaRequest{}, schemaResp)
+
+	objectType := schemaResp.Schema.Type().TerraformType(ctx)
+	req := datasource.ReadRequest{
+		Config: tfsdk.Config{
+			Schema: schemaResp.Schema,
+			Raw: tftypes.NewValue(objectType, map[string]tftypes.Value{
+				"path":           tftypes.NewValue(tftypes.String, path),
+				"policy":         tftypes.NewValue(tftypes.String, nil),
+				"exists":         tftypes.NewValue(tftypes.Bool, nil),
+				"checksum":       tftypes.NewValue(tftypes.String, nil),
+				"length":         tftypes.NewValue(tftypes.Number, nil),
+				"keys":           tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+				"expires":        tftypes.NewValue(tftypes.String, nil),
+				"revision_count": tftypes.NewValue(tftypes.Number, nil),
+			}),
+		},
+	}
+	resp := &datasource.ReadResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)},
+	}
+
+	d.Read(ctx, req, resp)
+
+	var data SecretChecksumModel
+	if !resp.Diagnostics.HasError() {
+		resp.State.Get(ctx, &data)
+	}
+	return resp, data
+}
+
+func TestSecretChecksumDataSource_Read(t *testing.T) {
+	client, store := newChecksumTestClient()
+
+	resp, data := readChecksumDataSource(t, client, "app/db")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	want, _, _ := client.SecretDigest(context.Background(), "app/db")
+	if !data.Exists.ValueBool() || data.Checksum.ValueString() != want.Checksum || data.RevisionCount.ValueInt64() != 1 {
+		t.Errorf("unexpected result %+v", data)
+	}
+	if data.Length.ValueInt64() != 6 || len(data.Keys.Elements()) != 1 || !data.Expires.IsNull() {
+		t.Errorf("unexpected derived outputs %+v", data)
+	}
+
+	if err := store.Set(context.Background(), "app/db", newSecret("s3cret", map[string]string{"user": "admin", "expires": "2030-06-01"})); err != nil {
+		t.Fatal(err)
+	}
+	_, data = readChecksumDataSource(t, client, "app/db")
+	if data.Expires.ValueString() != "2030-06-01T00:00:00Z" {
+		t.Errorf("unexpected expiry %q", data.Expires.ValueString())
+	}
+
+	resp, data = readChecksumDataSource(t, client, "app/missing")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if data.Exists.ValueBool() || !data.Checksum.IsNull() || !data.Length.IsNull() || !data.Keys.IsNull() ||
+		data.RevisionCount.ValueInt64() != 0 {
+		t.Errorf("expected a missing secret, got %+v", data)
+	}
+}
+
+func TestProviderConfigure_ChecksumOnly(t *testing.T) {
+	p := &GopassProvider{version: "test"}
+	resp := &provider.ConfigureResponse{}
+	p.Configure(context.Background(), provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"checksum_only": tftypes.NewValue(tftypes.Bool, true),
+		}),
+	}, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if !resp.DataSourceData.(*GopassClient).checksumOnly {
+		t.Error("expected the client to refuse plaintext reads")
+	}
+}
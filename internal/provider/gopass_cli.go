@@ -0,0 +1,71 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"os"
+)
+
+// Modes of resolving secrets.
+const (
+	modeLibrary = "library"
+	modeCLI     = "cli"
+)
+
+// gopassBinary is the gopass command line client, looked up in PATH.
+const gopassBinary = "gopass"
+
+// useCLI makes the client resolve secrets through the local gopass command
+// line client instead of the gopass library, for setups the library does not
+// cover, such as custom pinentry wrappers.
+func (c *GopassClient) useCLI() {
+	c.cli = true
+	c.newStore = func(ctx context.Context) (SecretStore, error) {
+		dir := ""
+		if c.storePath != "" {
+			expanded, err := c.expandHome(c.storePath)
+			if err != nil {
+				return nil, err
+			}
+			dir = expanded
+		}
+		env, err := c.cliEnv(dir)
+		if err != nil {
+			return nil, err
+		}
+		return &cliStore{command: []string{gopassBinary}, env: env}, nil
+	}
+}
+
+// cliEnv returns the variables gopass runs with for the store in dir, "" for
+// the one the gopass configuration selects. They are passed to each process
+// explicitly rather than read from the process environment, which opening
+// library stores changes while other stores are in use.
+func (c *GopassClient) cliEnv(dir string) ([]string, error) {
+	var env []string
+	if dir != "" {
+		env = append(env, "PASSWORD_STORE_DIR="+dir)
+	}
+	if c.homeDir != "" {
+		home, err := c.expandHome(c.homeDir)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, "GOPASS_HOMEDIR="+home)
+	}
+	if c.gnupg != nil && c.gnupg.dir != "" {
+		env = append(env, "GNUPGHOME="+c.gnupg.dir)
+	}
+	if c.nonInteractive {
+		gopassOpts := os.Getenv(gopassGPGOptsEnv)
+		if gopassOpts == "" {
+			gopassOpts = os.Getenv(passGPGOptsEnv)
+		}
+		env = append(env,
+			gopassGPGOptsEnv+"="+withNonInteractiveOpts(gopassOpts),
+			passGPGOptsEnv+"="+withNonInteractiveOpts(os.Getenv(passGPGOptsEnv)))
+	}
+	return env, nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestGopassClient_CLI(t *testing.T) {
+	var calls []string
+	previous := runCLI
+	runCLI = func(ctx context.Context, env []string, stdin []byte, argv ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(append(env, argv...), " "))
+		return []byte("s3cret\nusername: admin\n"), nil
+	}
+	t.Cleanup(func() { runCLI = previous })
+
+	t.Setenv(gopassGPGOptsEnv, "")
+	t.Setenv(passGPGOptsEnv, "")
+	root, mounted, home := t.TempDir(), t.TempDir(), t.TempDir()
+	client := NewGopassClient(root)
+	client.homeDir = home
+	client.nonInteractive = true
+	client.useCLI()
+	client.addStoreMount("team", mounted)
+	defer client.Close(context.Background())
+
+	ctx := context.Background()
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+		t.Errorf("unexpected value %q: %v", value, err)
+	}
+	if value, err := client.GetSecret(ctx, "team/token"); err != nil || value != "s3cret" {
+		t.Errorf("unexpected value %q: %v", value, err)
+	}
+	// Every store gets its own environment, whatever the process' is
+	env := "GOPASS_HOMEDIR=" + home + " GOPASS_GPG_OPTS=--batch --no-tty --pinentry-mode=error PASSWORD_STORE_GPG_OPTS=--batch --no-tty --pinentry-mode=error"
+	want := []string{
+		"PASSWORD_STORE_DIR=" + root + " " + env + " gopass show --noparsing --unsafe -- app/db",
+		"PASSWORD_STORE_DIR=" + mounted + " " + env + " gopass show --noparsing --unsafe -- token",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestProviderConfigure_Mode(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	tests := []struct {
+		name    string
+		config  map[string]tftypes.Value
+		wantCLI bool
+		wantErr string
+	}{
+		{
+			name:   "library",
+			config: map[string]tftypes.Value{"mode": tftypes.NewValue(tftypes.String, "library")},
+		},
+		{
+			name:    "cli",
+			config:  map[string]tftypes.Value{"mode": tftypes.NewValue(tftypes.String, "cli")},
+			wantCLI: true,
+		},
+		{
+			name:    "unknown",
+			config:  map[string]tftypes.Value{"mode": tftypes.NewValue(tftypes.String, "rpc")},
+			wantErr: "Invalid mode",
+		},
+		{
+			name: "pass store",
+			config: map[string]tftypes.Value{
+				"mode":         tftypes.NewValue(tftypes.String, "cli"),
+				"store_format": tftypes.NewValue(tftypes.String, "pass"),
+			},
+			wantErr: "Invalid mode",
+		},
+		{
+			name: "wsl",
+			config: map[string]tftypes.Value{
+				"mode": tftypes.NewValue(tftypes.String, "cli"),
+				"wsl":  tftypes.NewValue(tftypes.Bool, true),
+			},
+			wantErr: "Invalid mode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &provider.ConfigureResponse{}
+			p.Configure(ctx, provider.ConfigureRequest{Config: newProviderConfig(t, p, tt.config)}, resp)
+			if tt.wantErr != "" {
+				if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tt.wantErr {
+					t.Fatalf("expected %q, got %v", tt.wantErr, resp.Diagnostics)
+				}
+				return
+			}
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+			if client := resp.EphemeralResourceData.(*GopassClient); client.cli != tt.wantCLI {
+				t.Errorf("expected cli %v, got %v", tt.wantCLI, client.cli)
+			}
+		})
+	}
+}
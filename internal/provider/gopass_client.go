@@ -5,26 +5,101 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/gopasspw/gopass/pkg/gopass"
 	"github.com/gopasspw/gopass/pkg/gopass/api"
-	"github.com/gopasspw/gopass/pkg/gopass/secrets"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // GopassClient wraps the gopass library for secret access.
 // It maintains a single store instance for the lifetime of the provider.
+//
+// The store handle is guarded by a read-write mutex: initialization and Close
+// take the write lock, while reads only need the read lock, so concurrent
+// ephemeral opens can proceed in parallel once the store is initialized.
 type GopassClient struct {
-	store       gopass.Store
-	storePath   string
-	mu          sync.Mutex
-	userHomeDir func() (string, error)                          // injectable for testing
-	apiNew      func(ctx context.Context) (gopass.Store, error) // injectable for testing
+	store     SecretStore
+	storePath string
+	mu        sync.RWMutex
+	lifecycle clientLifecycle
+
+	timeouts   operationTimeouts
+	retry      retryPolicy
+	breaker    circuitBreaker
+	budget     decryptBudget
+	prefetch   *prefetcher
+	integrity  *integrityCheck
+	metrics    *clientMetrics
+	tracer     *tracer
+	listing    listingCache
+	cache      secretCache
+	mounts     []*mount // longest prefix first
+	reads      readGroup
+	sync       gitSync
+	warnings   warningQueue
+	writes     writeQueue
+	access     *accessLog // nil unless access_summary is enabled
+	redactor   redactor
+	policies   policySet
+	recipients recipientRules
+	// recipientEdits serializes changes of recipient files
+	recipientEdits sync.Mutex
+	// configEdits serializes changes of the gopass configuration
+	configEdits sync.Mutex
+	audit       *auditLog          // nil unless audit_log is set
+	gnupg       *isolatedGnupgHome // nil unless isolated_gnupg_home is set
+	pwned       *pwnedChecker      // nil unless a pwned password check is configured
+	cassette    *cassetteRecorder  // nil unless the record backend is selected
+	faults      *faultInjector     // nil unless GOPASS_PROVIDER_FAULTS is set
+	wsl         *wslHost           // nil unless secrets are resolved inside WSL
+
+	// decryptSlots serializes decryptions when non-nil (hardware token mode)
+	decryptSlots chan struct{}
+	forecast     interactionForecast
+	// metricsSummary logs aggregated operation statistics on Close
+	metricsSummary bool
+	// failOnEmptyValue turns empty password warnings into errors
+	failOnEmptyValue bool
+	// failOnExpired turns expired secret warnings into errors
+	failOnExpired bool
+	// readOnly refuses every write, removal, commit and git sync
+	readOnly bool
+	// checksumOnly refuses every read that would return secret content
+	checksumOnly bool
+	// secureMemory keeps the values handed to Terraform in locked memory
+	// (secure_memory)
+	secureMemory bool
+	// storeFormat is storeFormatPass or storeFormatPassage, "" for gopass stores
+	storeFormat string
+	// passageIdentities is the identities file passage stores decrypt with
+	passageIdentities string
+	// ageStores reads gopass age stores with the age library, decrypting with
+	// passageIdentities, instead of through gopass (age_identities_file)
+	ageStores bool
+	// ageRecipients is the recipients file age and passage stores encrypt
+	// to, "" for the recipients passage would pick
+	ageRecipients string
+	// cli resolves secrets through the local gopass command line client
+	// instead of the gopass library (mode "cli")
+	cli bool
+	// nonInteractive makes gpg fail instead of prompting for a passphrase or PIN
+	nonInteractive bool
+	// homeDir replaces the home directory gopass reads its configuration
+	// from (GOPASS_HOMEDIR), "" to keep the environment's
+	homeDir string
+
+	userHomeDir func() (string, error)                           // injectable for testing
+	newStore    func(ctx context.Context) (SecretStore, error)   // opens the backend; injectable
+	sleep       func(ctx context.Context, d time.Duration) error // injectable for testing
+	now         func() time.Time                                 // injectable for testing
 }
 
 // NewGopassClient creates a new gopass client.
@@ -33,16 +108,34 @@ type GopassClient struct {
 func NewGopassClient(storePath string) *GopassClient {
 	return &GopassClient{
 		storePath:   storePath,
+		timeouts:    defaultOperationTimeouts(),
+		retry:       defaultRetryPolicy(),
+		breaker:     circuitBreaker{threshold: defaultMaxDecryptFailures},
+		budget:      decryptBudget{limit: defaultMaxDecryptedSecrets},
+		metrics:     newClientMetrics(),
+		writes:      newWriteQueue(),
+		lifecycle:   clientLifecycle{idleTimeout: defaultStoreIdleTimeout},
 		userHomeDir: os.UserHomeDir,
-		apiNew:      func(ctx context.Context) (gopass.Store, error) { return api.New(ctx) },
+		newStore:    openGopassStore,
+		sleep:       sleepContext,
+		now:         time.Now,
 	}
 }
 
 // ensureStore initializes the gopass store if not already done.
 func (c *GopassClient) ensureStore(ctx context.Context) error {
+	// Fast path: the store is already initialized
+	c.mu.RLock()
+	initialized := c.store != nil
+	c.mu.RUnlock()
+	if initialized {
+		return nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	// Another goroutine may have initialized the store while we waited for the lock
 	if c.store != nil {
 		return nil
 	}
@@ -51,17 +144,18 @@ func (c *GopassClient) ensureStore(ctx context.Context) error {
 		"configured_path": c.storePath,
 	})
 
+	// Mounted stores temporarily point PASSWORD_STORE_DIR elsewhere while opening
+	storeDirMu.Lock()
+	defer storeDirMu.Unlock()
+
 	// If a custom store path is configured, set PASSWORD_STORE_DIR
-	// This is the standard way to tell gopass/pass where to find the store
-	if c.storePath != "" {
+	// This is the standard way to tell gopass/pass where to find the store.
+	// Inside WSL, the path belongs to the distribution and is passed there.
+	if c.storePath != "" && c.wsl == nil {
 		// Expand ~ if present
-		expandedPath := c.storePath
-		if strings.HasPrefix(expandedPath, "~/") {
-			home, err := c.userHomeDir()
-			if err != nil {
-				return fmt.Errorf("failed to expand home directory: %w", err)
-			}
-			expandedPath = filepath.Join(home, expandedPath[2:])
+		expandedPath, err := c.expandHome(c.storePath)
+		if err != nil {
+			return err
 		}
 
 		// Verify the path exists
@@ -77,19 +171,94 @@ func (c *GopassClient) ensureStore(ctx context.Context) error {
 		os.Setenv("PASSWORD_STORE_DIR", expandedPath)
 	}
 
-	store, err := c.apiNew(ctx)
+	// The gopass configuration is looked up below GOPASS_HOMEDIR, if set
+	if c.homeDir != "" && c.wsl == nil {
+		expandedHome, err := c.expandHome(c.homeDir)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(expandedHome); err != nil || !info.IsDir() {
+			return fmt.Errorf("gopass home directory not found at configured path: %s\n\n"+
+				"Please verify the directory exists and contains the gopass configuration in .config/gopass, "+
+				"or remove the home_dir configuration to use gopass defaults", expandedHome)
+		}
+
+		tflog.Debug(ctx, "Setting GOPASS_HOMEDIR", map[string]interface{}{
+			"path": expandedHome,
+		})
+		os.Setenv("GOPASS_HOMEDIR", expandedHome)
+	}
+
+	if err := c.gnupg.setup(ctx); err != nil {
+		return err
+	}
+	if c.nonInteractive && c.wsl == nil {
+		setNonInteractiveEnv()
+	}
+
+	store, err := c.openStore(ctx)
 	if err != nil {
 		// Provide helpful error message
 		return c.wrapStoreError(err)
 	}
 
-	c.store = store
+	if err := c.syncStore(ctx, "the password store", c.storeDir(), store); err != nil {
+		closeHandle(ctx, store)
+		return err
+	}
+
+	c.store = c.cassette.wrap(c.faults.wrap(store), "")
+	registerClient(c)
 	tflog.Debug(ctx, "Gopass store initialized successfully")
 	return nil
 }
 
+// expandHome expands a leading "~/" to the user's home directory. On
+// Windows, it also expands "~\" and %NAME% environment variable references.
+func (c *GopassClient) expandHome(path string) (string, error) {
+	if hostOS == "windows" {
+		path = expandWindowsEnv(path)
+	}
+	rest, ok := homeRelative(path)
+	if !ok {
+		return path, nil
+	}
+	home, err := c.userHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to expand home directory: %w", err)
+	}
+	return filepath.Join(home, rest), nil
+}
+
+// getStore returns the initialized store handle, initializing it on first use,
+// and takes a reference on it. Callers must call release when done and must use
+// the returned handle rather than reading c.store directly, so that a concurrent
+// Close cannot swap the handle out mid-operation.
+func (c *GopassClient) getStore(ctx context.Context) (store SecretStore, release func(), err error) {
+	c.retain()
+	release = func() { c.release(ctx) }
+
+	if err := c.ensureStore(ctx); err != nil {
+		release()
+		return nil, nil, classify(ErrStoreUninitialized, err)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.store == nil {
+		release()
+		return nil, nil, fmt.Errorf("gopass store was closed")
+	}
+	return c.store, release, nil
+}
+
 // wrapStoreError provides helpful context for common gopass initialization errors.
 func (c *GopassClient) wrapStoreError(err error) error {
+	if errors.Is(err, api.ErrNotInitialized) {
+		return storeNotInitializedError(c.storeLocation(), err)
+	}
+
 	errStr := err.Error()
 
 	// Check for common error patterns and provide helpful messages
@@ -131,122 +300,351 @@ func (c *GopassClient) wrapStoreError(err error) error {
 		"  }", err)
 }
 
-// Close closes the gopass store and releases resources.
-func (c *GopassClient) Close(ctx context.Context) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.store == nil {
-		return
-	}
-
-	// The gopass API store implements io.Closer through the api.Gopass type
-	if closer, ok := c.store.(interface{ Close(context.Context) error }); ok {
-		if err := closer.Close(ctx); err != nil {
-			tflog.Warn(ctx, "Error closing gopass store", map[string]interface{}{
-				"error": err.Error(),
-			})
+// storeLocation describes where gopass looked for the password store.
+func (c *GopassClient) storeLocation() string {
+	if c.storePath != "" {
+		if expanded, err := c.expandHome(c.storePath); err == nil {
+			return fmt.Sprintf("%s (from store_path)", expanded)
 		}
+		return fmt.Sprintf("%s (from store_path)", c.storePath)
 	}
-	c.store = nil
+	if dir := os.Getenv("PASSWORD_STORE_DIR"); dir != "" {
+		return fmt.Sprintf("%s (from PASSWORD_STORE_DIR)", dir)
+	}
+	if c.homeDir != "" {
+		return fmt.Sprintf("the location from the gopass configuration in %s (from home_dir)", c.homeDir)
+	}
+	return "the location from the gopass configuration"
+}
+
+// storeNotInitializedError explains how to point the provider at an existing
+// store when gopass found no initialized store at location.
+func storeNotInitializedError(location string, err error) error {
+	return fmt.Errorf("no initialized gopass store at %s: %w\n\n"+
+		"gopass opens a store only if it contains a .gpg-id (or .age-recipients) file. "+
+		"Point the provider at an existing store:\n\n"+
+		"1. Set store_path in the provider configuration:\n"+
+		"   provider \"gopass\" {\n"+
+		"     store_path = \"~/.local/share/gopass/stores/root\"\n"+
+		"   }\n\n"+
+		"2. Or export PASSWORD_STORE_DIR=/path/to/store before running Terraform.\n\n"+
+		"3. If gopass itself works in your shell, check that Terraform sees the same "+
+		"HOME, GOPASS_HOMEDIR and GOPASS_CONFIG environment variables (\"gopass config path\").\n\n"+
+		"To create a new store instead, run \"gopass setup\".", location, err)
 }
 
 // GetSecret retrieves a single secret by path.
 // Returns the password (first line) of the secret.
 func (c *GopassClient) GetSecret(ctx context.Context, path string) (string, error) {
-	if err := c.ensureStore(ctx); err != nil {
-		return "", err
+	password, _, err := c.GetSecretWithFields(ctx, path)
+	return password, err
+}
+
+// GetSecretWithFields retrieves the password of a secret like GetSecret, along
+// with the named keys, in one decryption. Keys the secret does not have are
+// left out of the result.
+func (c *GopassClient) GetSecretWithFields(ctx context.Context, path string, keys ...string) (string, map[string]string, error) {
+	return c.GetSecretAtRevision(ctx, path, "latest", keys...)
+}
+
+// GetSecretAtRevision reads a revision of a secret like GetSecretWithFields:
+// "latest" or a revision id as gopass history lists it, e.g. a git commit
+// hash. Expiry is only checked for the latest revision, as older ones are
+// superseded anyway.
+func (c *GopassClient) GetSecretAtRevision(ctx context.Context, path, revision string, keys ...string) (string, map[string]string, error) {
+	if err := c.checkPlaintext(path); err != nil {
+		return "", nil, err
 	}
 
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer release()
+
 	tflog.Debug(ctx, "Reading secret", map[string]interface{}{
-		"path": path,
+		"path":     c.logPath(path),
+		"revision": revision,
 	})
 
-	// Get secret with "latest" revision
-	secret, err := c.store.Get(ctx, path, "latest")
+	secret, err := c.storeGetRevision(ctx, store, path, revision)
+	if err != nil && revision != "latest" {
+		// The path may well exist, so suggesting other paths would mislead
+		return "", nil, fmt.Errorf("failed to get revision %q of secret %q: %w", revision, path, err)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get secret %q: %w", path, err)
+		return "", nil, c.readError(ctx, store, path, err)
+	}
+	if revision == "latest" {
+		if err := c.checkExpiry(ctx, path, secret); err != nil {
+			return "", nil, err
+		}
 	}
 
 	// Password() returns the first line (the actual password)
 	password := secret.Password()
+	c.warnDiscardedContent(path, secret)
+
+	var fields map[string]string
+	if len(keys) > 0 {
+		c.warnKeyConflicts(path, secret, keys)
+		fields = make(map[string]string, len(keys))
+		for _, key := range keys {
+			if value, ok := secret.Get(key); ok {
+				fields[key] = value
+			}
+		}
+		c.redactor.addFields(fields)
+	}
 
 	tflog.Debug(ctx, "Successfully read secret", map[string]interface{}{
-		"path": path,
+		"path": c.logPath(path),
 	})
 
-	return password, nil
+	return password, fields, nil
 }
 
 // GetSecretFull retrieves a secret with all its key-value pairs.
 // Returns the password and a map of additional fields.
 func (c *GopassClient) GetSecretFull(ctx context.Context, path string) (password string, fields map[string]string, err error) {
-	err = c.ensureStore(ctx)
+	_, password, fields, err = c.GetSecretWithBody(ctx, path)
+	return password, fields, err
+}
+
+// GetSecretWithBody retrieves a secret like GetSecretFull, along with its
+// whole body: the password line, the fields and any other lines, as stored.
+func (c *GopassClient) GetSecretWithBody(ctx context.Context, path string) (body, password string, fields map[string]string, err error) {
+	if err := c.checkPlaintext(path); err != nil {
+		return "", "", nil, err
+	}
+
+	store, release, err := c.storeFor(ctx, path)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
+	defer release()
 
-	secret, err := c.store.Get(ctx, path, "latest")
+	secret, err := c.storeGet(ctx, store, path)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to get secret %q: %w", path, err)
+		return "", "", nil, c.readError(ctx, store, path, err)
+	}
+	if err := c.checkExpiry(ctx, path, secret); err != nil {
+		return "", "", nil, err
 	}
 
 	password = secret.Password()
-	fields = make(map[string]string)
+	c.warnKeyConflicts(path, secret, secret.Keys())
+	fields = secretFields(secret)
+
+	c.redactor.addFields(fields)
+	return string(secret.Bytes()), password, fields, nil
+}
 
-	// Get all keys and their values
-	for _, key := range secret.Keys() {
+// GetSecretFields retrieves a secret like GetSecretFull, but only the named
+// keys of it. Keys the secret does not have are left out of the result.
+//
+// Unlike GetSecretFull, this looks up each key directly instead of copying the
+// whole body, which matters for secrets with many keys when only a few are used.
+func (c *GopassClient) GetSecretFields(ctx context.Context, path string, keys ...string) (password string, fields map[string]string, err error) {
+	if err := c.checkPlaintext(path); err != nil {
+		return "", nil, err
+	}
+
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer release()
+
+	secret, err := c.storeGet(ctx, store, path)
+	if err != nil {
+		return "", nil, c.readError(ctx, store, path, err)
+	}
+	if err := c.checkExpiry(ctx, path, secret); err != nil {
+		return "", nil, err
+	}
+
+	// Only the requested keys are checked, listing all of them is what this avoids
+	c.warnKeyConflicts(path, secret, keys)
+	fields = make(map[string]string, len(keys))
+	for _, key := range keys {
 		if value, ok := secret.Get(key); ok {
 			fields[key] = value
 		}
 	}
 
-	return password, fields, nil
+	c.redactor.addFields(fields)
+	return secret.Password(), fields, nil
 }
 
-// ListSecrets lists all secrets under a given prefix.
-// Returns only immediate children (not recursive).
-func (c *GopassClient) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
-	if err := c.ensureStore(ctx); err != nil {
-		return nil, err
+// ErrStopWalk can be returned by a WalkSecrets callback to end the walk early.
+// WalkSecrets then returns nil instead of the error.
+var ErrStopWalk = errors.New("stop walk")
+
+// errSkipDir can be returned by a WalkSecrets callback to skip the remaining
+// entries of the directory directly below the walk prefix that contains the
+// current entry. Walks over a sorted listing jump past the directory with a
+// binary search; streaming walks just ignore the entries.
+var errSkipDir = errors.New("skip directory")
+
+// secretWalker is implemented by stores that can enumerate entries
+// incrementally instead of returning the full listing in one slice.
+type secretWalker interface {
+	Walk(ctx context.Context, prefix string, fn func(path string) error) error
+}
+
+// WalkSecrets calls fn for every secret under prefix, recursively.
+// An empty prefix walks the entire store.
+//
+// Entries are streamed to fn one at a time, so callers never need to hold the
+// filtered result set in memory. If the underlying store supports incremental
+// enumeration it is used directly; otherwise the store listing is walked in place.
+// Returning ErrStopWalk from fn stops the walk without an error; any other
+// error aborts the walk and is returned unchanged.
+func (c *GopassClient) WalkSecrets(ctx context.Context, prefix string, fn func(path string) error) error {
+	prefixWithSlash := normalizePrefix(prefix)
+
+	visit := func(secretPath string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !underPrefix(secretPath, prefixWithSlash) {
+			return nil
+		}
+		return fn(secretPath)
 	}
 
-	// Normalize prefix
-	prefix = strings.TrimSuffix(prefix, "/")
+	// Walk the store owning the prefix, then every store mounted below it
+	owner := c.mountFor(prefixWithSlash)
+	err := c.walkStore(ctx, owner, prefixWithSlash, visit)
+	for _, m := range c.mounts {
+		if err != nil {
+			break
+		}
+		if m != owner && withinPrefix(m.prefix, prefixWithSlash) {
+			err = c.walkStore(ctx, m, prefixWithSlash, visit)
+		}
+	}
 
-	tflog.Debug(ctx, "Listing secrets", map[string]interface{}{
-		"prefix": prefix,
-	})
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
 
-	// List all secrets
-	allSecrets, err := c.store.List(ctx)
+// walkStore visits the secrets of one store: the mount m, or the root store if
+// m is nil. Entries shadowed by a more specific mount are skipped.
+func (c *GopassClient) walkStore(ctx context.Context, m *mount, prefixWithSlash string, visit func(path string) error) error {
+	var (
+		store   SecretStore
+		release func()
+		err     error
+	)
+	if m == nil {
+		store, release, err = c.getStore(ctx)
+	} else {
+		store, release, err = c.mountedStore(ctx, m)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to list secrets: %w", err)
+		return err
 	}
+	defer release()
 
-	// Filter to immediate children of prefix
-	var results []string
-	prefixWithSlash := prefix + "/"
+	if len(c.mounts) > 0 {
+		unfiltered := visit
+		visit = func(secretPath string) error {
+			if c.mountFor(secretPath) != m {
+				return nil
+			}
+			return unfiltered(secretPath)
+		}
+	}
 
-	for _, secretPath := range allSecrets {
-		// Must start with prefix
-		if !strings.HasPrefix(secretPath, prefixWithSlash) {
-			continue
+	if walker, ok := store.(secretWalker); ok {
+		_, err = callWithDeadline(ctx, c.timeouts.List, "walking secrets", func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, walker.Walk(ctx, prefixWithSlash, func(secretPath string) error {
+				if err := visit(secretPath); !errors.Is(err, errSkipDir) {
+					return err
+				}
+				return nil
+			})
+		})
+		return err
+	}
+
+	allSecrets, err := c.cachedList(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	// The listing is sorted: only the matching range needs to be visited,
+	// and skipped directories are contiguous runs within it
+	entries := prefixRange(allSecrets, prefixWithSlash)
+	for i := 0; i < len(entries); {
+		err := visit(entries[i])
+		switch {
+		case errors.Is(err, errSkipDir):
+			i += skipDir(entries[i:], prefixWithSlash)
+		case err != nil:
+			return err
+		default:
+			i++
 		}
+	}
+	return nil
+}
 
-		// Get relative path
-		relativePath := strings.TrimPrefix(secretPath, prefixWithSlash)
+// skipDir returns the number of leading entries of a sorted listing that lie
+// in the same directory below prefix as the first entry. It is at least 1.
+func skipDir(sorted []string, prefix string) int {
+	rest := sorted[0][len(prefix):]
+	slash := strings.IndexByte(rest, '/')
+	if slash < 0 {
+		return 1
+	}
 
-		// Skip nested paths (only immediate children)
-		if strings.Contains(relativePath, "/") {
-			continue
+	// Every path below "dir/" sorts before "dir0", as '0' follows '/'
+	end := prefix + rest[:slash] + "0"
+	return max(1, sort.SearchStrings(sorted, end))
+}
+
+// ListSecrets lists all secrets under a given prefix.
+// Returns only immediate children (not recursive).
+func (c *GopassClient) ListSecrets(ctx context.Context, prefix string) ([]string, error) {
+	return c.listSecrets(ctx, prefix, false)
+}
+
+// ListSecretsRecursive lists all secrets anywhere below a given prefix.
+func (c *GopassClient) ListSecretsRecursive(ctx context.Context, prefix string) ([]string, error) {
+	return c.listSecrets(ctx, prefix, true)
+}
+
+// listSecrets lists the secrets below prefix, descending into subdirectories
+// if recursive is set.
+func (c *GopassClient) listSecrets(ctx context.Context, prefix string, recursive bool) ([]string, error) {
+	prefix = normalizePath(prefix)
+
+	tflog.Debug(ctx, "Listing secrets", map[string]interface{}{
+		"prefix":    c.logPath(prefix),
+		"recursive": recursive,
+	})
+
+	// Filter to immediate children of prefix unless recursive
+	var results []string
+	err := c.WalkSecrets(ctx, prefix, func(secretPath string) error {
+		if !recursive && !isImmediateChild(secretPath, prefix) {
+			return errSkipDir
 		}
 
 		results = append(results, secretPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	tflog.Debug(ctx, "Listed secrets", map[string]interface{}{
-		"prefix": prefix,
+		"prefix": c.logPath(prefix),
 		"count":  len(results),
 	})
 
@@ -255,57 +653,181 @@ func (c *GopassClient) ListSecrets(ctx context.Context, prefix string) ([]string
 
 // GetEnvSecrets reads all immediate child secrets under a path and returns them as a map.
 // The map keys are the secret names (relative to prefix), values are the passwords.
+// Up to envReadWorkers secrets are decrypted at the same time.
+//
+// Secrets that fail to read are skipped. If any of them timed out, the secrets
+// that could be read are returned together with a *PartialResultError. A
+// secret the path policy denies, an expired one with expired_secrets = "error",
+// or hitting max_decrypted_secrets, fails the whole read; the errors of all
+// such secrets are joined.
 func (c *GopassClient) GetEnvSecrets(ctx context.Context, prefix string) (map[string]string, error) {
-	secretPaths, err := c.ListSecrets(ctx, prefix)
+	return c.getEnvSecrets(ctx, prefix, false, nil)
+}
+
+// GetEnvSecretsRecursive reads all secrets anywhere below a path like
+// GetEnvSecrets. The map keys are the paths relative to prefix, e.g.
+// "db/primary/password".
+func (c *GopassClient) GetEnvSecretsRecursive(ctx context.Context, prefix string) (map[string]string, error) {
+	return c.getEnvSecrets(ctx, prefix, true, nil)
+}
+
+// GetFilteredEnvSecrets reads the secrets below a path like GetEnvSecrets, or
+// GetEnvSecretsRecursive if recursive is set, but only those filter selects.
+// The others are never decrypted.
+func (c *GopassClient) GetFilteredEnvSecrets(ctx context.Context, prefix string, recursive bool, filter *envFilter) (map[string]string, error) {
+	return c.getEnvSecrets(ctx, prefix, recursive, filter)
+}
+
+// envReadWorkers bounds the number of secrets getEnvSecrets decrypts at the
+// same time. In hardware token mode decryptions are serialized regardless.
+const envReadWorkers = 8
+
+// envRead is the outcome of reading one secret in getEnvSecrets.
+type envRead struct {
+	value string
+	err   error
+}
+
+// failsEnvRead reports whether err fails a whole getEnvSecrets read: a prefix
+// reaching outside the resource's contract, bulk-decrypting the store or
+// holding expired secrets fails as a whole.
+func failsEnvRead(err error) bool {
+	return errors.Is(err, ErrPolicyViolation) || errors.Is(err, ErrDecryptLimit) || errors.Is(err, ErrSecretExpired)
+}
+
+// getEnvSecrets reads the secrets listSecrets finds below prefix that filter
+// selects.
+func (c *GopassClient) getEnvSecrets(ctx context.Context, prefix string, recursive bool, filter *envFilter) (map[string]string, error) {
+	if err := c.checkPlaintext(prefix); err != nil {
+		return nil, err
+	}
+
+	secretPaths, err := c.listSecrets(ctx, prefix, recursive)
 	if err != nil {
 		return nil, err
 	}
+	if filter != nil {
+		selected := secretPaths[:0:0]
+		for _, fullPath := range secretPaths {
+			if key, _ := relativeKey(fullPath, prefix); filter.selects(key) {
+				selected = append(selected, fullPath)
+			}
+		}
+		secretPaths = selected
+	}
+
+	reads := c.readConcurrently(ctx, secretPaths, failsEnvRead)
+
+	// Handle the outcomes in listing order, so warnings and errors are stable
+	var failed []error
+	for _, read := range reads {
+		if failsEnvRead(read.err) {
+			failed = append(failed, read.err)
+		}
+	}
+	if len(failed) > 0 {
+		return nil, errors.Join(failed...)
+	}
 
-	prefix = strings.TrimSuffix(prefix, "/")
+	prefix = normalizePath(prefix)
 	result := make(map[string]string)
+	var timedOut []string
 
-	for _, fullPath := range secretPaths {
-		// Extract key name from path
-		key := strings.TrimPrefix(fullPath, prefix+"/")
+	for i, fullPath := range secretPaths {
+		key, _ := relativeKey(fullPath, prefix)
 
-		// Get the secret value
-		value, err := c.GetSecret(ctx, fullPath)
-		if err != nil {
+		if err := reads[i].err; err != nil {
 			tflog.Warn(ctx, "Failed to read secret, skipping", map[string]interface{}{
-				"path":  fullPath,
-				"error": err.Error(),
+				"path":  c.logPath(fullPath),
+				"error": c.logError(err),
 			})
+			if errors.Is(err, ErrTimeout) {
+				timedOut = append(timedOut, fullPath)
+			}
 			continue
 		}
 
-		result[key] = value
+		result[key] = reads[i].value
 	}
 
+	if len(timedOut) > 0 {
+		return result, &PartialResultError{Prefix: prefix, TimedOut: timedOut}
+	}
 	return result, nil
 }
 
+// readConcurrently reads the passwords of paths with up to envReadWorkers
+// workers and returns the outcomes in the order of paths. Once a read fails
+// the whole batch, as decided by fails, no further reads are started and the
+// remaining outcomes are left empty.
+func (c *GopassClient) readConcurrently(ctx context.Context, paths []string, fails func(error) bool) []envRead {
+	reads := make([]envRead, len(paths))
+	jobs := make(chan int)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+
+	for range min(envReadWorkers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				value, err := c.GetSecret(ctx, paths[i])
+				reads[i] = envRead{value: value, err: err}
+				if fails(err) {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	for i := range paths {
+		if failed.Load() {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return reads
+}
+
 // SetSecret writes a secret to the gopass store.
 // The value becomes the first line (password) of the secret.
 func (c *GopassClient) SetSecret(ctx context.Context, path, value string) error {
-	if err := c.ensureStore(ctx); err != nil {
+	return c.SetSecretFull(ctx, path, value, nil)
+}
+
+// SetSecretFull creates or overwrites a secret with value as its password on
+// the first line and the "key: value" fields below it, in key order.
+func (c *GopassClient) SetSecretFull(ctx context.Context, path, value string, fields map[string]string) error {
+	c.redactor.addValues(value)
+	c.redactor.addFields(fields)
+
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
 		return err
 	}
+	defer release()
 
 	tflog.Debug(ctx, "Writing secret", map[string]interface{}{
-		"path": path,
+		"path": c.logPath(path),
 	})
 
-	// Create a new secret object and set the password
-	secret := secrets.New()
-	secret.SetPassword(value)
-
-	// Set the secret in the store
-	if err := c.store.Set(ctx, path, secret); err != nil {
+	// Only writes that are allowed at all query the breach database
+	pwned := func(ctx context.Context) error { return c.pwned.check(ctx, path, value) }
+	err = c.storeSet(ctx, store, path, newSecret(value, fields), pwned)
+	if errors.Is(err, ErrPwnedPassword) {
+		return err
+	}
+	// Even a failed write may have changed the store
+	c.invalidatePath(path)
+	if err != nil {
 		return fmt.Errorf("failed to write secret %q: %w", path, err)
 	}
 
 	tflog.Debug(ctx, "Successfully wrote secret", map[string]interface{}{
-		"path": path,
+		"path": c.logPath(path),
 	})
 
 	return nil
@@ -313,20 +835,25 @@ func (c *GopassClient) SetSecret(ctx context.Context, path, value string) error
 
 // RemoveSecret removes a secret from the gopass store.
 func (c *GopassClient) RemoveSecret(ctx context.Context, path string) error {
-	if err := c.ensureStore(ctx); err != nil {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
 		return err
 	}
+	defer release()
 
 	tflog.Debug(ctx, "Removing secret", map[string]interface{}{
-		"path": path,
+		"path": c.logPath(path),
 	})
 
-	if err := c.store.Remove(ctx, path); err != nil {
+	err = c.storeRemove(ctx, store, path)
+	// Even a failed write may have changed the store
+	c.invalidatePath(path)
+	if err != nil {
 		return fmt.Errorf("failed to remove secret %q: %w", path, err)
 	}
 
 	tflog.Debug(ctx, "Successfully removed secret", map[string]interface{}{
-		"path": path,
+		"path": c.logPath(path),
 	})
 
 	return nil
@@ -334,15 +861,17 @@ func (c *GopassClient) RemoveSecret(ctx context.Context, path string) error {
 
 // SecretExists checks if a secret exists at the given path.
 func (c *GopassClient) SecretExists(ctx context.Context, path string) (bool, error) {
-	if err := c.ensureStore(ctx); err != nil {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
 		return false, err
 	}
+	defer release()
 
-	exists, err := c.store.Get(ctx, path, "latest")
+	exists, err := c.storeGet(ctx, store, path)
 	if err != nil {
 		// If the error indicates the secret doesn't exist, that's not an error condition
 		// for this function - it just means the secret doesn't exist
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, ErrNotFound) {
 			return false, nil
 		}
 		return false, fmt.Errorf("failed to check if secret %q exists: %w", path, err)
@@ -362,16 +891,18 @@ func (c *GopassClient) SecretExists(ctx context.Context, path string) (bool, err
 // Errors from the Revisions() call are logged but not returned - we fall back to
 // existence check in that case, as not all backends support revision history.
 func (c *GopassClient) GetRevisionCount(ctx context.Context, path string) (int64, error) {
-	if err := c.ensureStore(ctx); err != nil {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
 		return 0, err
 	}
+	defer release()
 
 	// First check if secret exists
-	exists, err := c.store.Get(ctx, path, "latest")
+	exists, err := c.storeGet(ctx, store, path)
 	if err != nil {
 		// If the error indicates the secret doesn't exist, that's not an error condition
 		// for this function - it just means the secret doesn't exist
-		if strings.Contains(err.Error(), "not found") {
+		if errors.Is(err, ErrNotFound) {
 			return 0, nil
 		}
 		return 0, fmt.Errorf("failed to check if secret %q exists: %w", path, err)
@@ -382,13 +913,13 @@ func (c *GopassClient) GetRevisionCount(ctx context.Context, path string) (int64
 
 	// Try to get revision count - not all backends support this.
 	// Currently, this is also not yet implemented in the API.
-	revisions, err := c.store.Revisions(ctx, path)
+	revisions, err := c.storeRevisions(ctx, store, path)
 	if err != nil {
 		// Backend doesn't support revisions or other error
 		// Fall back to "1" (exists but no version info)
 		tflog.Debug(ctx, "Revisions() not supported or failed, falling back to existence check", map[string]interface{}{
-			"path":  path,
-			"error": err.Error(),
+			"path":  c.logPath(path),
+			"error": c.logError(err),
 		})
 		return 1, nil
 	}
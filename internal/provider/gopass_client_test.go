@@ -8,10 +8,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/api"
 	"github.com/gopasspw/gopass/pkg/gopass/secrets"
 )
 
@@ -37,7 +42,7 @@ func (m *mockStore) Get(ctx context.Context, name, revision string) (gopass.Secr
 
 	secret, exists := m.secrets[name]
 	if !exists {
-		return nil, fmt.Errorf("secret %q not found", name)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
 	}
 	return secret, nil
 }
@@ -83,7 +88,7 @@ func (m *mockStore) Remove(ctx context.Context, name string) error {
 	}
 
 	if _, exists := m.secrets[name]; !exists {
-		return fmt.Errorf("secret %q not found", name)
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
 	}
 
 	delete(m.secrets, name)
@@ -124,7 +129,7 @@ func (m *mockStore) Rename(ctx context.Context, src, dest string) error {
 
 	secret, exists := m.secrets[src]
 	if !exists {
-		return fmt.Errorf("secret %q not found", src)
+		return fmt.Errorf("%w: %s", ErrNotFound, src)
 	}
 
 	m.secrets[dest] = secret
@@ -303,6 +308,102 @@ func TestGopassClient_GetSecretFull(t *testing.T) {
 	}
 }
 
+func TestGopassClient_GetSecretFields(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+
+	secret := secrets.New()
+	secret.SetPassword("test-password")
+	secret.Set("username", "testuser")
+	secret.Set("url", "https://example.com")
+	mockStore.secrets["test/path"] = secret
+
+	password, fields, err := client.GetSecretFields(context.Background(), "test/path", "username", "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if password != "test-password" {
+		t.Errorf("expected password %q, got %q", "test-password", password)
+	}
+	if len(fields) != 1 || fields["username"] != "testuser" {
+		t.Errorf("expected only the requested, existing field, got %v", fields)
+	}
+}
+
+func TestGopassClient_GetSecretFields_NotFound(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = newMockStore()
+
+	_, _, err := client.GetSecretFields(context.Background(), "nonexistent", "username")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGopassClient_GetSecretWithFields(t *testing.T) {
+	store := &mockCountingStore{SecretStore: NewMemoryStore(map[string]string{
+		"test/path": "test-password\nuser: testuser",
+	})}
+	client := NewGopassClientWithStore(store)
+
+	password, fields, err := client.GetSecretWithFields(context.Background(), "test/path", "username", "user")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "test-password" || len(fields) != 1 || fields["user"] != "testuser" {
+		t.Errorf("expected the password and only the existing field, got %q, %v", password, fields)
+	}
+	if count := store.readCount("test/path"); count != 1 {
+		t.Errorf("expected a single read, got %d", count)
+	}
+
+	if _, fields, _ := client.GetSecretWithFields(context.Background(), "test/path"); fields != nil {
+		t.Errorf("expected no fields without keys, got %v", fields)
+	}
+}
+
+func TestGopassClient_GetSecretAtRevision(t *testing.T) {
+	store := NewMemoryStore(map[string]string{"app/db": "first\nuser: admin"})
+	store.Set(context.Background(), "app/db", newPasswordSecret("second"))
+	client := NewGopassClientWithStore(store)
+	client.cache.ttl = time.Minute
+	ctx := context.Background()
+
+	password, fields, err := client.GetSecretAtRevision(ctx, "app/db", "1", "user")
+	if err != nil || password != "first" || fields["user"] != "admin" {
+		t.Fatalf("expected the first revision, got %q, %v (%v)", password, fields, err)
+	}
+	// Revisions are cached separately from the latest one
+	if password, err := client.GetSecret(ctx, "app/db"); err != nil || password != "second" {
+		t.Errorf("expected the latest revision, got %q (%v)", password, err)
+	}
+
+	_, _, err = client.GetSecretAtRevision(ctx, "app/db", "7")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `revision "7" of secret "app/db"`) || strings.Contains(err.Error(), "Did you mean") {
+		t.Errorf("expected the revision in the error without suggestions, got %q", err.Error())
+	}
+}
+
+func TestGopassClient_GetSecretAtRevision_SkipsExpiry(t *testing.T) {
+	store := NewMemoryStore(map[string]string{"app/db": "old\nexpires: 2026-01-01"})
+	client := NewGopassClientWithStore(store)
+	client.now = func() time.Time { return time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC) }
+	client.failOnExpired = true
+	ctx := context.Background()
+
+	if _, _, err := client.GetSecretAtRevision(ctx, "app/db", "1"); err != nil {
+		t.Errorf("expected a pinned revision to be read regardless of expiry, got %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "app/db"); !errors.Is(err, ErrSecretExpired) {
+		t.Errorf("expected the latest revision to be expired, got %v", err)
+	}
+}
+
 func TestGopassClient_ListSecrets(t *testing.T) {
 	client := NewGopassClient("")
 	mockStore := newMockStore()
@@ -561,6 +662,11 @@ func TestGopassClient_WrapStoreError(t *testing.T) {
 			inputError:     errors.New("gpg: error"),
 			expectedSubstr: "GPG error during gopass initialization",
 		},
+		{
+			name:           "not initialized",
+			inputError:     fmt.Errorf("open: %w", api.ErrNotInitialized),
+			expectedSubstr: "no initialized gopass store",
+		},
 		{
 			name:           "generic error",
 			inputError:     errors.New("some other error"),
@@ -666,6 +772,48 @@ func TestGopassClient_EnsureStore_NonExistentPath(t *testing.T) {
 	}
 }
 
+func TestGopassClient_EnsureStore_HomeDir(t *testing.T) {
+	t.Setenv("GOPASS_HOMEDIR", "")
+	home := t.TempDir()
+	if err := os.MkdirAll(home+"/gopass-home", 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen string
+	client := NewGopassClient("")
+	client.homeDir = "~/gopass-home"
+	client.userHomeDir = func() (string, error) { return home, nil }
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		seen = os.Getenv("GOPASS_HOMEDIR")
+		return NewMemoryStore(nil), nil
+	}
+
+	if err := client.ensureStore(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen != home+"/gopass-home" {
+		t.Errorf("expected the store to open with GOPASS_HOMEDIR %q, got %q", home+"/gopass-home", seen)
+	}
+}
+
+func TestGopassClient_EnsureStore_HomeDirNotFound(t *testing.T) {
+	t.Setenv("GOPASS_HOMEDIR", "")
+	client := NewGopassClient("")
+	client.homeDir = "/definitely/does/not/exist"
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		t.Fatal("expected the store not to be opened")
+		return nil, nil
+	}
+
+	err := client.ensureStore(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "gopass home directory not found at configured path") {
+		t.Errorf("expected a home directory error, got %v", err)
+	}
+	if os.Getenv("GOPASS_HOMEDIR") != "" {
+		t.Error("expected GOPASS_HOMEDIR to stay unset")
+	}
+}
+
 func TestGopassClient_EnsureStore_UserHomeDirError(t *testing.T) {
 	// Create client with tilde path to trigger home expansion
 	client := NewGopassClient("~/some/path")
@@ -714,9 +862,9 @@ func TestGopassClient_EnsureStore_SuccessfulInit(t *testing.T) {
 	// Create client with no store path (uses default gopass config)
 	client := NewGopassClient("")
 
-	// Inject a mock apiNew that returns a simple mock store
+	// Inject a mock newStore that returns a simple mock store
 	injectedMockStore := newMockStore()
-	client.apiNew = func(ctx context.Context) (gopass.Store, error) {
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
 		return injectedMockStore, nil
 	}
 
@@ -740,6 +888,65 @@ func TestGopassClient_EnsureStore_SuccessfulInit(t *testing.T) {
 	}
 }
 
+func TestGopassClient_EnsureStore_ConcurrentInit(t *testing.T) {
+	client := NewGopassClient("")
+
+	var initCalls int32
+	injectedMockStore := newMockStore()
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		atomic.AddInt32(&initCalls, 1)
+		return injectedMockStore, nil
+	}
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.ensureStore(ctx); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The store must only be initialized once, even under concurrent access
+	if calls := atomic.LoadInt32(&initCalls); calls != 1 {
+		t.Errorf("expected newStore to be called once, got %d", calls)
+	}
+}
+
+func TestGopassClient_GetSecret_ConcurrentReads(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+
+	secret := secrets.New()
+	secret.SetPassword("shared-password")
+	mockStore.secrets["test/shared"] = secret
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			password, err := client.GetSecret(ctx, "test/shared")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if password != "shared-password" {
+				t.Errorf("expected password 'shared-password', got %q", password)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestGopassClient_Close_NilStore(t *testing.T) {
 	client := NewGopassClient("")
 	client.store = nil
@@ -808,6 +1015,258 @@ func TestGopassClient_ListSecrets_Error(t *testing.T) {
 	}
 }
 
+func TestGopassClient_WalkSecrets(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+
+	secret := secrets.New()
+	secret.SetPassword("pass")
+	mockStore.secrets["env/test/secret1"] = secret
+	mockStore.secrets["env/test/sub/secret2"] = secret
+	mockStore.secrets["env/testing/secret3"] = secret
+	mockStore.secrets["other/secret4"] = secret
+
+	ctx := context.Background()
+
+	visited := make(map[string]bool)
+	err := client.WalkSecrets(ctx, "env/test/", func(path string) error {
+		visited[path] = true
+		return nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Walk is recursive but must not match sibling prefixes like env/testing
+	expected := []string{"env/test/secret1", "env/test/sub/secret2"}
+	if len(visited) != len(expected) {
+		t.Errorf("expected %d secrets, got %d: %v", len(expected), len(visited), visited)
+	}
+	for _, path := range expected {
+		if !visited[path] {
+			t.Errorf("expected to visit %q", path)
+		}
+	}
+}
+
+func TestGopassClient_WalkSecrets_EmptyPrefix(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+
+	secret := secrets.New()
+	mockStore.secrets["a"] = secret
+	mockStore.secrets["b/c"] = secret
+
+	count := 0
+	err := client.WalkSecrets(context.Background(), "", func(path string) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected to visit 2 secrets, got %d", count)
+	}
+}
+
+func TestGopassClient_WalkSecrets_StopWalk(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+
+	secret := secrets.New()
+	for i := 0; i < 10; i++ {
+		mockStore.secrets[fmt.Sprintf("env/KEY%d", i)] = secret
+	}
+
+	count := 0
+	err := client.WalkSecrets(context.Background(), "env", func(path string) error {
+		count++
+		if count == 3 {
+			return ErrStopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected ErrStopWalk to be swallowed, got %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected walk to stop after 3 entries, got %d", count)
+	}
+}
+
+func TestGopassClient_WalkSecrets_CallbackError(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+	mockStore.secrets["env/KEY"] = secrets.New()
+
+	callbackErr := errors.New("callback failed")
+	err := client.WalkSecrets(context.Background(), "env", func(path string) error {
+		return callbackErr
+	})
+	if !errors.Is(err, callbackErr) {
+		t.Errorf("expected callback error, got %v", err)
+	}
+}
+
+func TestGopassClient_WalkSecrets_ListError(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	mockStore.shouldFail = true
+	mockStore.failMsg = "list error"
+	client.store = mockStore
+
+	err := client.WalkSecrets(context.Background(), "env", func(path string) error {
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to list secrets") {
+		t.Errorf("expected wrapped list error, got %v", err)
+	}
+}
+
+// mockWalkingStore supports incremental enumeration via Walk
+type mockWalkingStore struct {
+	*mockStore
+	walkCalls int
+}
+
+func (m *mockWalkingStore) Walk(ctx context.Context, prefix string, fn func(path string) error) error {
+	m.walkCalls++
+	for name := range m.secrets {
+		if err := fn(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockWalkingStore) List(ctx context.Context) ([]string, error) {
+	return nil, errors.New("List must not be called when Walk is available")
+}
+
+func TestGopassClient_ListSecrets_StoreWalker(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := &mockWalkingStore{mockStore: newMockStore()}
+	client.store = mockStore
+	for _, name := range []string{"env/KEY1", "env/nested/KEY2", "env/nested/KEY3", "env/KEY4"} {
+		mockStore.secrets[name] = secrets.New()
+	}
+
+	paths, err := client.ListSecrets(context.Background(), "env")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	sort.Strings(paths)
+	if fmt.Sprint(paths) != "[env/KEY1 env/KEY4]" {
+		t.Errorf("expected immediate children only, got %v", paths)
+	}
+}
+
+func TestGopassClient_ListSecrets_SkipsNestedDirectories(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+
+	// Names chosen to sit right next to directory boundaries in sort order
+	for _, name := range []string{
+		"a/b", "a/b.c", "a/b/c", "a/b/d/e", "a/b0", "a/b-1", "a/c/x", "a/c/y", "a/d", "ab/x", "a.txt",
+	} {
+		mockStore.secrets[name] = secrets.New()
+	}
+
+	paths, err := client.ListSecrets(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	want := "[a/b a/b-1 a/b.c a/b0 a/d]"
+	if fmt.Sprint(paths) != want {
+		t.Errorf("expected %s, got %v", want, paths)
+	}
+
+	top, err := client.ListSecrets(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if fmt.Sprint(top) != "[a.txt]" {
+		t.Errorf("expected only top-level secrets, got %v", top)
+	}
+}
+
+func TestGopassClient_ListSecretsRecursive(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newMockStore()
+	client.store = mockStore
+
+	for _, name := range []string{"a/b", "a/b/c", "a/b/d/e", "a/c/x", "ab/x", "a.txt"} {
+		mockStore.secrets[name] = secrets.New()
+	}
+
+	paths, err := client.ListSecretsRecursive(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("ListSecretsRecursive() error = %v", err)
+	}
+	if want := "[a/b a/b/c a/b/d/e a/c/x]"; fmt.Sprint(paths) != want {
+		t.Errorf("expected %s, got %v", want, paths)
+	}
+}
+
+func TestGopassClient_GetEnvSecretsRecursive(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"app/env/API_KEY":             "k3y",
+		"app/env/db/primary/password": "s3cret",
+		"app/env/db/replica/password": "r3plica",
+		"app/other/TOKEN":             "t0ken",
+	}))
+
+	values, err := client.GetEnvSecretsRecursive(context.Background(), "app/env/")
+	if err != nil {
+		t.Fatalf("GetEnvSecretsRecursive() error = %v", err)
+	}
+	want := map[string]string{"API_KEY": "k3y", "db/primary/password": "s3cret", "db/replica/password": "r3plica"}
+	if fmt.Sprint(values) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+
+	shallow, err := client.GetEnvSecrets(context.Background(), "app/env")
+	if err != nil || len(shallow) != 1 || shallow["API_KEY"] != "k3y" {
+		t.Errorf("expected only the immediate child without recursion, got %v (%v)", shallow, err)
+	}
+}
+
+func TestSkipDir(t *testing.T) {
+	sorted := []string{"p/a/1", "p/a/2", "p/a0", "p/b"}
+
+	if n := skipDir(sorted, "p/"); n != 2 {
+		t.Errorf("expected to skip 2 entries of p/a/, got %d", n)
+	}
+	if n := skipDir(sorted[2:], "p/"); n != 1 {
+		t.Errorf("expected a plain secret to skip only itself, got %d", n)
+	}
+}
+
+func TestGopassClient_WalkSecrets_UsesStoreWalker(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := &mockWalkingStore{mockStore: newMockStore()}
+	client.store = mockStore
+	mockStore.secrets["env/KEY1"] = secrets.New()
+	mockStore.secrets["other/KEY2"] = secrets.New()
+
+	results, err := client.ListSecrets(context.Background(), "env")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mockStore.walkCalls != 1 {
+		t.Errorf("expected Walk to be called once, got %d", mockStore.walkCalls)
+	}
+	if len(results) != 1 || results[0] != "env/KEY1" {
+		t.Errorf("expected [env/KEY1], got %v", results)
+	}
+}
+
 // mockStoreWithSelectiveFailure allows failing only specific operations
 type mockStoreWithSelectiveFailure struct {
 	*mockStore
@@ -862,6 +1321,74 @@ func TestGopassClient_GetEnvSecrets_PartialFailure(t *testing.T) {
 	}
 }
 
+// mockSlowStore delays every Get and records how many ran at the same time.
+type mockSlowStore struct {
+	*mockStore
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (m *mockSlowStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	n := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	for {
+		peak := m.maxInFlight.Load()
+		if n <= peak || m.maxInFlight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return m.mockStore.Get(ctx, name, revision)
+}
+
+func TestGopassClient_GetEnvSecrets_Concurrent(t *testing.T) {
+	client := NewGopassClient("")
+	store := &mockSlowStore{mockStore: newMockStore()}
+	client.store = store
+	want := make(map[string]string)
+	for i := range 3 * envReadWorkers {
+		key := fmt.Sprintf("KEY%02d", i)
+		store.secrets["env/test/"+key] = newMockSecret("value-" + key)
+		want[key] = "value-" + key
+	}
+
+	values, err := client.GetEnvSecrets(context.Background(), "env/test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(values) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, values)
+	}
+	if peak := store.maxInFlight.Load(); peak < 2 || peak > envReadWorkers {
+		t.Errorf("expected between 2 and %d concurrent reads, got %d", envReadWorkers, peak)
+	}
+}
+
+func TestGopassClient_GetEnvSecrets_JoinsFailures(t *testing.T) {
+	// Slow reads, so all three are in flight before the first one fails
+	client := NewGopassClient("")
+	store := &mockSlowStore{mockStore: newMockStore()}
+	client.store = store
+	client.now = func() time.Time { return time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC) }
+	client.failOnExpired = true
+	for _, key := range []string{"KEY1", "KEY2", "KEY3"} {
+		secret := secrets.New()
+		secret.SetPassword("value")
+		if key != "KEY2" {
+			secret.Set("expires", "2026-01-01")
+		}
+		store.secrets["env/test/"+key] = secret
+	}
+
+	values, err := client.GetEnvSecrets(context.Background(), "env/test")
+	if !errors.Is(err, ErrSecretExpired) || values != nil {
+		t.Fatalf("expected the read to fail as a whole, got %v (%v)", values, err)
+	}
+	if !strings.Contains(err.Error(), `"env/test/KEY1"`) || !strings.Contains(err.Error(), `"env/test/KEY3"`) {
+		t.Errorf("expected both expired secrets in the error, got %q", err.Error())
+	}
+}
+
 func TestGopassClient_SecretExists_OtherError(t *testing.T) {
 	client := NewGopassClient("")
 	mockStore := newMockStore()
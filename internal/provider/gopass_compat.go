@@ -0,0 +1,58 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"sort"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// The gopass library does not promise a stable Go API, and how it parses a
+// secret body has changed between releases before. Everything the provider
+// relies on beyond the gopass.Store and gopass.Secret interfaces goes through
+// the adapters below, so an upgrade touches this file only, and
+// gopass_compat_test.go pins the parsing semantics users depend on. Run
+// "make compat" to check them against other library versions.
+
+// parseSecret parses a whole entry body: the password on the first line,
+// "key: value" fields below.
+func parseSecret(body []byte) gopass.Secret {
+	return secrets.ParseAKV(body)
+}
+
+// newPasswordSecret returns a secret holding only password.
+func newPasswordSecret(password string) gopass.Secret {
+	return newSecret(password, nil)
+}
+
+// newSecret returns a secret holding password and the "key: value" fields,
+// in key order.
+func newSecret(password string, fields map[string]string) gopass.Secret {
+	secret := secrets.New()
+	secret.SetPassword(password)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// Set only fails for values that are not strings
+		_ = secret.Set(key, fields[key])
+	}
+	return secret
+}
+
+// secretFields returns the "key: value" fields of secret. A key appearing
+// more than once maps to its first value.
+func secretFields(secret gopass.Secret) map[string]string {
+	fields := make(map[string]string)
+	for _, key := range secret.Keys() {
+		if value, ok := secret.Get(key); ok {
+			fields[key] = value
+		}
+	}
+	return fields
+}
@@ -0,0 +1,115 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"maps"
+	"testing"
+)
+
+// The TestGopassCompat tests pin how the gopass library parses and writes
+// secret bodies. "make compat" runs them against several library versions; a
+// failure there means an upgrade would change what users read.
+
+func TestGopassCompat_Parse(t *testing.T) {
+	tests := map[string]struct {
+		body     string
+		password string
+		fields   map[string]string
+	}{
+		"password only": {
+			body:     "s3cret",
+			password: "s3cret",
+			fields:   map[string]string{},
+		},
+		"trailing newline": {
+			body:     "s3cret\n",
+			password: "s3cret",
+			fields:   map[string]string{},
+		},
+		"fields": {
+			body:     "s3cret\nusername: admin\nurl: https://db.example.com:5432/app",
+			password: "s3cret",
+			fields:   map[string]string{"username": "admin", "url": "https://db.example.com:5432/app"},
+		},
+		"empty password": {
+			body:     "\nusername: admin",
+			password: "",
+			fields:   map[string]string{"username": "admin"},
+		},
+		"keys are case-sensitive": {
+			body:     "s3cret\nUser: upper\nuser: lower",
+			password: "s3cret",
+			fields:   map[string]string{"User": "upper", "user": "lower"},
+		},
+		"first value of a duplicate key wins": {
+			body:     "s3cret\nuser: first\nuser: second",
+			password: "s3cret",
+			fields:   map[string]string{"user": "first"},
+		},
+		"key and value are trimmed": {
+			body:     "s3cret\n  user  :  admin  ",
+			password: "s3cret",
+			fields:   map[string]string{"user": "admin"},
+		},
+		"a field needs a space after the colon": {
+			body:     "s3cret\nuser:admin\nempty:",
+			password: "s3cret",
+			fields:   map[string]string{},
+		},
+		"free text and separators are no fields": {
+			body:     "s3cret\n---\nsome notes\nuser: admin",
+			password: "s3cret",
+			fields:   map[string]string{"user": "admin"},
+		},
+		"the password line is never a field": {
+			body:     "user: admin",
+			password: "user: admin",
+			fields:   map[string]string{},
+		},
+	}
+	for name, tt := range tests {
+		secret := parseSecret([]byte(tt.body))
+		if got := secret.Password(); got != tt.password {
+			t.Errorf("%s: password %q, want %q", name, got, tt.password)
+		}
+		if got := secretFields(secret); !maps.Equal(got, tt.fields) {
+			t.Errorf("%s: fields %v, want %v", name, got, tt.fields)
+		}
+	}
+}
+
+func TestGopassCompat_Bytes(t *testing.T) {
+	tests := map[string]string{
+		"s3cret":                   "s3cret\n",
+		"s3cret\n":                 "s3cret\n",
+		"s3cret\nuser: admin":      "s3cret\nuser: admin\n",
+		"s3cret\n\nnotes\n":        "s3cret\n\nnotes\n",
+		"s3cret\n  user  : admin ": "s3cret\n  user  : admin \n",
+	}
+	for body, want := range tests {
+		if got := string(parseSecret([]byte(body)).Bytes()); got != want {
+			t.Errorf("%q: body %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestGopassCompat_SetPassword(t *testing.T) {
+	if got := string(newPasswordSecret("s3cret").Bytes()); got != "s3cret\n" {
+		t.Errorf("unexpected body %q", got)
+	}
+
+	secret := parseSecret([]byte("old\nuser: admin"))
+	secret.SetPassword("new")
+	if got := string(secret.Bytes()); got != "new\nuser: admin\n" {
+		t.Errorf("expected the fields to be kept, got %q", got)
+	}
+}
+
+func TestGopassCompat_NewSecret(t *testing.T) {
+	got := string(newSecret("s3cret", map[string]string{"username": "admin", "host": "db.internal"}).Bytes())
+	if got != "s3cret\nhost: db.internal\nusername: admin\n" {
+		t.Errorf("expected the fields in key order, got %q", got)
+	}
+}
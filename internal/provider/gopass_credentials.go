@@ -0,0 +1,84 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Keys under which secrets conventionally keep the parts of a login besides
+// the password, most common first. gopass keys are case-sensitive, so only
+// these exact spellings are recognized.
+var (
+	usernameKeys = []string{"username", "user", "login"}
+	hostKeys     = []string{"host", "hostname"}
+	portKeys     = []string{"port"}
+	databaseKeys = []string{"database", "dbname", "db"}
+	machineKeys  = []string{"machine", "host", "hostname"}
+
+	// All keys the connection string and .pgpass renderers, and the .netrc
+	// renderer read; only these are looked up in a secret
+	connectionKeys = slices.Concat(hostKeys, portKeys, databaseKeys, usernameKeys)
+	netrcKeys      = slices.Concat(machineKeys, usernameKeys)
+)
+
+// credentialField returns the value of the first of keys that fields has, and
+// false if it has none of them.
+func credentialField(fields map[string]string, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// configuredOrField returns the configured value if set, or else the first of
+// keys found in fields, or "".
+func configuredOrField(configured types.String, fields map[string]string, keys []string) string {
+	if !configured.IsNull() && !configured.IsUnknown() {
+		return configured.ValueString()
+	}
+	value, _ := credentialField(fields, keys)
+	return value
+}
+
+// basicAuthHeader returns the value of an HTTP Authorization header for basic
+// authentication (RFC 7617) as username and password. The username cannot
+// contain a colon, as the first one separates it from the password.
+func basicAuthHeader(username, password string) (string, error) {
+	if strings.Contains(username, ":") {
+		return "", errors.New("the username contains a colon, which basic authentication cannot represent")
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+}
+
+// percentEncode escapes every byte of s but the RFC 3986 unreserved
+// characters, so the result is safe in any part of a URL: user info, path,
+// query or fragment. net/url's escapers leave characters such as "+", ":" or
+// "@" as they are where the URL syntax allows them, which breaks
+// connection strings built by concatenation.
+func percentEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0x0f])
+	}
+	return b.String()
+}
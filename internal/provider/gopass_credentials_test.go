@@ -0,0 +1,59 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCredentialField(t *testing.T) {
+	fields := map[string]string{"user": "bob", "login": "robert", "Host": "ignored"}
+
+	if value, ok := credentialField(fields, usernameKeys); !ok || value != "bob" {
+		t.Errorf("expected the first matching key to win, got %q (%v)", value, ok)
+	}
+	if value, ok := credentialField(fields, hostKeys); ok || value != "" {
+		t.Errorf("expected keys to be case-sensitive, got %q (%v)", value, ok)
+	}
+	if value, ok := credentialField(map[string]string{"port": ""}, portKeys); !ok || value != "" {
+		t.Errorf("expected an empty value to be found, got %q (%v)", value, ok)
+	}
+}
+
+func TestBasicAuthHeader(t *testing.T) {
+	// The example from RFC 7617
+	header, err := basicAuthHeader("Aladdin", "open sesame")
+	if err != nil || header != "Basic QWxhZGRpbjpvcGVuIHNlc2FtZQ==" {
+		t.Errorf("unexpected header %q (%v)", header, err)
+	}
+
+	// Only the first colon separates, so the password may contain more
+	if header, err := basicAuthHeader("ci", "a:b"); err != nil || header != "Basic Y2k6YTpi" {
+		t.Errorf("unexpected header %q (%v)", header, err)
+	}
+
+	if _, err := basicAuthHeader("c:i", "token"); err == nil {
+		t.Error("expected a username with a colon to be refused")
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	tests := map[string]string{
+		"":                  "",
+		"Safe-._~09az":      "Safe-._~09az",
+		"p@ss:w/rd":         "p%40ss%3Aw%2Frd",
+		"a b+c&d=e?f#g%h":   "a%20b%2Bc%26d%3De%3Ff%23g%25h",
+		"ümlaut":            "%C3%BCmlaut",
+		"[::1];'\"\\,!$*()": "%5B%3A%3A1%5D%3B%27%22%5C%2C%21%24%2A%28%29",
+	}
+	for in, want := range tests {
+		if got := percentEncode(in); got != want {
+			t.Errorf("percentEncode(%q) = %q, want %q", in, got, want)
+		}
+		if decoded, err := url.PathUnescape(percentEncode(in)); err != nil || decoded != in {
+			t.Errorf("%q does not round-trip: %q (%v)", in, decoded, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// storeUnavailable reports whether err means the store or the key to
+// decrypt it is not available on this machine, as opposed to a problem with
+// the requested secret itself.
+func storeUnavailable(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return false
+	}
+	if errors.Is(err, ErrStoreUninitialized) || errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	problem, ok := classifyGPGError(err)
+	return ok && problem.unavailable
+}
+
+// deferOpen defers an ephemeral resource that could not be opened because
+// the store is unavailable, if Terraform supports deferred actions. The
+// plan then completes with the resource's values unknown instead of failing,
+// e.g. on a machine without the hardware token. It reports whether Open
+// was deferred.
+func (c *GopassClient) deferOpen(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse, err error) bool {
+	if !req.ClientCapabilities.DeferralAllowed || !storeUnavailable(err) {
+		return false
+	}
+
+	tflog.Warn(ctx, "Deferring ephemeral resource, gopass store is unavailable", map[string]interface{}{
+		"error": c.logError(err),
+	})
+
+	resp.Result.Raw = tftypes.NewValue(resp.Result.Schema.Type().TerraformType(ctx), tftypes.UnknownValue)
+	resp.Deferred = &ephemeral.Deferred{Reason: ephemeral.DeferredReasonAbsentPrereq}
+	return true
+}
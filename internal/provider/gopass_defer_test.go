@@ -0,0 +1,103 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+)
+
+var deferralAllowed = ephemeral.OpenClientCapabilities{DeferralAllowed: true}
+
+func newUnavailableStoreClient() *GopassClient {
+	client := NewGopassClient("")
+	client.retry.MaxAttempts = 1
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		return nil, api.ErrNotInitialized
+	}
+	return client
+}
+
+func TestStoreUnavailable(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "uninitialized store", err: classify(ErrStoreUninitialized, api.ErrNotInitialized), want: true},
+		{name: "circuit open", err: fmt.Errorf("reading: %w", ErrCircuitOpen), want: true},
+		{name: "agent not running", err: errors.New("gpg: can't connect to the agent"), want: true},
+		{name: "token missing", err: errors.New("gpg: selecting card failed: Card not present"), want: true},
+		{name: "no secret key", err: errors.New("gpg: decryption failed: No secret key"), want: true},
+		{name: "expired key", err: errors.New("gpg: skipped: Unusable public key"), want: false},
+		{name: "not found", err: classify(ErrNotFound, errors.New("no secret key for not found")), want: false},
+		{name: "other", err: errors.New("disk full"), want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := storeUnavailable(tc.err); got != tc.want {
+				t.Errorf("storeUnavailable(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSecretEphemeralResource_Open_DefersUnavailableStore(t *testing.T) {
+	resp := openSecretEphemeralWith(t, newUnavailableStoreClient(), "app/db", deferralAllowed)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("expected no error when deferred, got %v", resp.Diagnostics)
+	}
+	if resp.Deferred == nil || resp.Deferred.Reason != ephemeral.DeferredReasonAbsentPrereq {
+		t.Fatalf("expected deferral with absent prerequisite, got %v", resp.Deferred)
+	}
+	if resp.Result.Raw.IsKnown() {
+		t.Errorf("expected unknown result, got %v", resp.Result.Raw)
+	}
+}
+
+func TestSecretEphemeralResource_Open_NoDeferralWithoutCapability(t *testing.T) {
+	resp := openSecretEphemeral(t, newUnavailableStoreClient(), "app/db")
+
+	if resp.Deferred != nil {
+		t.Errorf("expected no deferral, got %v", resp.Deferred)
+	}
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error when Terraform does not support deferral")
+	}
+}
+
+func TestSecretEphemeralResource_Open_NoDeferralForMissingSecret(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = newMockStore()
+
+	resp := openSecretEphemeralWith(t, client, "app/missing", deferralAllowed)
+
+	if resp.Deferred != nil {
+		t.Errorf("expected missing secrets not to be deferred, got %v", resp.Deferred)
+	}
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected a not found error")
+	}
+}
+
+func TestEnvEphemeralResource_Open_DefersUnavailableStore(t *testing.T) {
+	resp := openEnvEphemeralWith(t, newUnavailableStoreClient(), "app", deferralAllowed)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("expected no error when deferred, got %v", resp.Diagnostics)
+	}
+	if resp.Deferred == nil {
+		t.Fatal("expected deferral")
+	}
+	if resp.Result.Raw.IsKnown() {
+		t.Errorf("expected unknown result, got %v", resp.Result.Raw)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// agentDialTimeout bounds the connection attempt to gpg-agent's socket.
+const agentDialTimeout = 2 * time.Second
+
+// DiagnoseOptions configures Diagnose. The fields correspond to the provider
+// arguments of the same name.
+type DiagnoseOptions struct {
+	StorePath  string
+	Mounts     map[string]string
+	WarmUpPath string // secret to decrypt as an end-to-end check, optional
+}
+
+// Check outcomes, in the order of severity.
+const (
+	checkOK   = "ok"
+	checkInfo = "info"
+	checkWarn = "warn"
+	checkFail = "FAIL"
+)
+
+// diagnosticCheck is one line of the diagnose report.
+type diagnosticCheck struct {
+	name   string
+	status string
+	detail string
+}
+
+// Diagnose checks that the password store, its crypto backend, gpg-agent and
+// all mounts are usable, going through the same client code that serves
+// Terraform, and writes a report to w. It reports whether all checks passed.
+func Diagnose(ctx context.Context, w io.Writer, opts DiagnoseOptions) bool {
+	client := NewGopassClient(opts.StorePath)
+	for prefix, dir := range opts.Mounts {
+		client.addStoreMount(prefix, dir)
+	}
+	defer client.Close(ctx)
+
+	return writeReport(w, client.diagnose(ctx, opts))
+}
+
+// diagnose runs all checks against c.
+func (c *GopassClient) diagnose(ctx context.Context, opts DiagnoseOptions) []diagnosticCheck {
+	var checks []diagnosticCheck
+
+	location := c.storeLocation()
+	if store, release, err := c.getStore(ctx); err != nil {
+		checks = append(checks, diagnosticCheck{"store", checkFail, err.Error()})
+	} else {
+		checks = append(checks, diagnosticCheck{"store", checkOK, "opened " + location})
+		if entries, err := c.cachedList(ctx, store); err != nil {
+			checks = append(checks, diagnosticCheck{"listing", checkFail, err.Error()})
+		} else {
+			checks = append(checks, diagnosticCheck{"listing", checkOK, fmt.Sprintf("%d secret(s)", len(entries))})
+		}
+		release()
+	}
+
+	backend := storeBackend(c.storeDir())
+	checks = append(checks, diagnosticCheck{"crypto backend", checkInfo, backend})
+	if backend != "age" {
+		checks = append(checks, c.diagnoseAgent(ctx))
+	}
+
+	prefixes := make([]string, 0, len(c.mounts))
+	byPrefix := make(map[string]*mount, len(c.mounts))
+	for _, m := range c.mounts {
+		prefixes = append(prefixes, m.prefix)
+		byPrefix[m.prefix] = m
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		name := fmt.Sprintf("mount %q", normalizePath(prefix))
+		if _, err := c.mountStore(ctx, byPrefix[prefix]); err != nil {
+			checks = append(checks, diagnosticCheck{name, checkFail, err.Error()})
+			continue
+		}
+		checks = append(checks, diagnosticCheck{name, checkOK, "opened"})
+	}
+
+	if opts.WarmUpPath != "" {
+		name := fmt.Sprintf("decrypt %q", opts.WarmUpPath)
+		if err := c.warmUp(ctx, opts.WarmUpPath); err != nil {
+			checks = append(checks, diagnosticCheck{name, checkFail, errorDetail(err.Error(), err)})
+		} else {
+			checks = append(checks, diagnosticCheck{name, checkOK, "decrypted"})
+		}
+	}
+
+	for _, warning := range c.takeWarnings() {
+		checks = append(checks, diagnosticCheck{warning.Summary(), checkWarn, warning.Detail()})
+	}
+	return checks
+}
+
+// storeDir returns the root store directory if it is known without asking
+// gopass, i.e. if it comes from store_path or PASSWORD_STORE_DIR.
+func (c *GopassClient) storeDir() string {
+	if c.storePath != "" {
+		if expanded, err := c.expandHome(c.storePath); err == nil {
+			return expanded
+		}
+	}
+	return os.Getenv("PASSWORD_STORE_DIR")
+}
+
+// storeBackend names the crypto backend of the store in dir by its
+// recipients file.
+func storeBackend(dir string) string {
+	if dir == "" {
+		return "unknown (store location comes from the gopass configuration)"
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".age-recipients")); err == nil {
+		return "age"
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".gpg-id")); err == nil {
+		return "gpg"
+	}
+	return "unknown (no .gpg-id or .age-recipients in " + dir + ")"
+}
+
+// agentSocket returns the path of gpg-agent's socket; injectable for testing.
+var agentSocket = func(ctx context.Context) (string, error) {
+	gpgconf, err := findGPGTool("gpgconf")
+	if err != nil {
+		gpgconf = "gpgconf"
+	}
+	out, err := exec.CommandContext(ctx, gpgconf, "--list-dirs", "agent-socket").Output()
+	if err != nil {
+		return "", fmt.Errorf("gpgconf: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// diagnoseAgent checks that gpg-agent accepts connections and whether
+// decryptions go through a hardware token.
+func (c *GopassClient) diagnoseAgent(ctx context.Context) diagnosticCheck {
+	socket, err := agentSocket(ctx)
+	if err != nil {
+		return diagnosticCheck{"gpg-agent", checkWarn, "could not locate the agent socket: " + err.Error()}
+	}
+
+	conn, err := dialAgent(ctx, socket)
+	if err != nil {
+		return diagnosticCheck{"gpg-agent", checkFail, fmt.Sprintf("%s: %s\n\n"+
+			"Start the agent with \"gpgconf --launch gpg-agent\".", socket, err)}
+	}
+	conn.Close()
+
+	detail := "reachable at " + socket
+	if home, err := gnupgHomeDir(c.userHomeDir); err == nil && hasSmartcardKeys(home) {
+		detail += "; smartcard-backed key detected"
+	}
+	return diagnosticCheck{"gpg-agent", checkOK, detail}
+}
+
+// writeReport prints checks to w and reports whether none of them failed.
+func writeReport(w io.Writer, checks []diagnosticCheck) bool {
+	passed := true
+	for _, check := range checks {
+		if check.status == checkFail {
+			passed = false
+		}
+		detail := strings.ReplaceAll(check.detail, "\n", "\n       ")
+		fmt.Fprintf(w, "[%-4s] %s: %s\n", check.status, check.name, detail)
+	}
+
+	if passed {
+		fmt.Fprintln(w, "\nAll checks passed.")
+	} else {
+		fmt.Fprintln(w, "\nSome checks failed. Include this report when opening an issue.")
+	}
+	return passed
+}
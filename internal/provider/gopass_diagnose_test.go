@@ -0,0 +1,180 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubAgentSocket points agentSocket at socket for the duration of the test.
+func stubAgentSocket(t *testing.T, socket string, err error) {
+	t.Helper()
+	original := agentSocket
+	agentSocket = func(ctx context.Context) (string, error) { return socket, err }
+	t.Cleanup(func() { agentSocket = original })
+}
+
+// listenAgent serves a unix socket standing in for gpg-agent.
+func listenAgent(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "S.gpg-agent")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return socket
+}
+
+func newDiagnoseTestClient(t *testing.T) *GopassClient {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".gpg-id"), []byte("0x1234\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PASSWORD_STORE_DIR", dir)
+
+	team := &mockClosingStore{SecretStore: NewMemoryStore(nil)}
+
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.retry.MaxAttempts = 1
+	client.addMount("team", func(ctx context.Context) (SecretStore, error) { return team, nil })
+	return client
+}
+
+func findCheck(checks []diagnosticCheck, name string) (diagnosticCheck, bool) {
+	for _, check := range checks {
+		if check.name == name {
+			return check, true
+		}
+	}
+	return diagnosticCheck{}, false
+}
+
+func TestDiagnose_AllChecksPass(t *testing.T) {
+	client := newDiagnoseTestClient(t)
+	stubAgentSocket(t, listenAgent(t), nil)
+
+	checks := client.diagnose(context.Background(), DiagnoseOptions{WarmUpPath: "app/db"})
+
+	want := map[string]string{
+		"store":            checkOK,
+		"listing":          checkOK,
+		"crypto backend":   checkInfo,
+		"gpg-agent":        checkOK,
+		`mount "team"`:     checkOK,
+		`decrypt "app/db"`: checkOK,
+	}
+	for name, status := range want {
+		check, ok := findCheck(checks, name)
+		if !ok {
+			t.Errorf("missing check %q in %v", name, checks)
+			continue
+		}
+		if check.status != status {
+			t.Errorf("%s: expected %s, got %s (%s)", name, status, check.status, check.detail)
+		}
+	}
+	if check, _ := findCheck(checks, "crypto backend"); check.detail != "gpg" {
+		t.Errorf("expected gpg backend, got %q", check.detail)
+	}
+
+	var report bytes.Buffer
+	if !writeReport(&report, checks) {
+		t.Errorf("expected report to pass, got\n%s", report.String())
+	}
+}
+
+func TestDiagnose_Failures(t *testing.T) {
+	client := newDiagnoseTestClient(t)
+	client.addMount("broken", func(ctx context.Context) (SecretStore, error) {
+		return nil, errors.New("permission denied")
+	})
+	stubAgentSocket(t, filepath.Join(t.TempDir(), "missing"), nil)
+
+	checks := client.diagnose(context.Background(), DiagnoseOptions{WarmUpPath: "app/missing"})
+
+	for _, name := range []string{"gpg-agent", `mount "broken"`, `decrypt "app/missing"`} {
+		if check, _ := findCheck(checks, name); check.status != checkFail {
+			t.Errorf("%s: expected failure, got %+v", name, check)
+		}
+	}
+	if check, _ := findCheck(checks, `mount "team"`); check.status != checkOK {
+		t.Errorf("expected healthy mount to pass, got %+v", check)
+	}
+
+	var report bytes.Buffer
+	if writeReport(&report, checks) {
+		t.Error("expected report to fail")
+	}
+	if !strings.Contains(report.String(), "[FAIL] gpg-agent:") || !strings.Contains(report.String(), "gpgconf --launch") {
+		t.Errorf("expected agent failure with hint, got\n%s", report.String())
+	}
+}
+
+func TestDiagnose_AgentNotLocated(t *testing.T) {
+	client := newDiagnoseTestClient(t)
+	stubAgentSocket(t, "", errors.New("executable file not found in $PATH"))
+
+	check := client.diagnoseAgent(context.Background())
+	if check.status != checkWarn {
+		t.Errorf("expected a warning when gpgconf is missing, got %+v", check)
+	}
+}
+
+func TestDiagnose_UninitializedStore(t *testing.T) {
+	stubAgentSocket(t, "", errors.New("not needed"))
+	// Opening the store points PASSWORD_STORE_DIR at store_path
+	t.Setenv("PASSWORD_STORE_DIR", "")
+
+	var report bytes.Buffer
+	passed := Diagnose(context.Background(), &report, DiagnoseOptions{StorePath: t.TempDir()})
+
+	if passed {
+		t.Fatalf("expected diagnosis to fail, got\n%s", report.String())
+	}
+	if !strings.Contains(report.String(), "[FAIL] store:") || !strings.Contains(report.String(), "no initialized gopass store") {
+		t.Errorf("expected store failure with guidance, got\n%s", report.String())
+	}
+}
+
+func TestStoreBackend(t *testing.T) {
+	gpgDir, ageDir, emptyDir := t.TempDir(), t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(gpgDir, ".gpg-id"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ageDir, ".age-recipients"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := storeBackend(gpgDir); got != "gpg" {
+		t.Errorf("expected gpg, got %q", got)
+	}
+	if got := storeBackend(ageDir); got != "age" {
+		t.Errorf("expected age, got %q", got)
+	}
+	if got := storeBackend(emptyDir); !strings.HasPrefix(got, "unknown") {
+		t.Errorf("expected unknown, got %q", got)
+	}
+	if got := storeBackend(""); !strings.Contains(got, "gopass configuration") {
+		t.Errorf("expected unknown location, got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// documentValue converts a decoded YAML or JSON value into a Terraform value:
+// mappings become objects, sequences tuples, and scalars strings, numbers or
+// bools. Every string, keys excepted, is passed through protect. Nulls become
+// null strings, since Terraform needs a type even for null values.
+func documentValue(ctx context.Context, v any, protect func(string) string) (attr.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return types.StringNull(), nil
+	case string:
+		return types.StringValue(protect(v)), nil
+	case bool:
+		return types.BoolValue(v), nil
+	case int:
+		return types.NumberValue(new(big.Float).SetInt64(int64(v))), nil
+	case int64:
+		return types.NumberValue(new(big.Float).SetInt64(v)), nil
+	case uint64:
+		return types.NumberValue(new(big.Float).SetUint64(v)), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%v cannot be represented as a Terraform number", v)
+		}
+		return types.NumberValue(big.NewFloat(v)), nil
+	case json.Number:
+		// Keep the literal's full precision
+		f, _, err := big.ParseFloat(v.String(), 10, 512, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("%s cannot be represented as a Terraform number", v)
+		}
+		return types.NumberValue(f), nil
+	case time.Time:
+		return types.StringValue(protect(v.Format(time.RFC3339Nano))), nil
+	case []any:
+		elemTypes := make([]attr.Type, len(v))
+		elems := make([]attr.Value, len(v))
+		for i, item := range v {
+			elem, err := documentValue(ctx, item, protect)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			elemTypes[i] = elem.Type(ctx)
+			elems[i] = elem
+		}
+		return types.TupleValueMust(elemTypes, elems), nil
+	case map[string]any:
+		return documentObject(ctx, v, protect)
+	case map[any]any:
+		// Mappings with non-string keys, e.g. numbers
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = item
+		}
+		return documentObject(ctx, m, protect)
+	default:
+		return types.StringValue(protect(fmt.Sprint(v))), nil
+	}
+}
+
+// documentObject converts a mapping into an object value.
+func documentObject(ctx context.Context, m map[string]any, protect func(string) string) (attr.Value, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // report the first failing key deterministically
+
+	attrTypes := make(map[string]attr.Type, len(m))
+	attrs := make(map[string]attr.Value, len(m))
+	for _, key := range keys {
+		value, err := documentValue(ctx, m[key], protect)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		attrTypes[key] = value.Type(ctx)
+		attrs[key] = value
+	}
+	return types.ObjectValueMust(attrTypes, attrs), nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestDocumentValue(t *testing.T) {
+	ctx := context.Background()
+	doc, err := secretYAML("s3cret\n---\nname: app\nport: 8080\nratio: 0.5\nenabled: true\n" +
+		"missing: null\nhosts: [a, 2]\n1: numeric key\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var protected []string
+	value, err := documentValue(ctx, doc, func(s string) string {
+		protected = append(protected, s)
+		return s
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := value.(types.Object).Attributes()
+	want := map[string]attr.Value{
+		"name":    types.StringValue("app"),
+		"port":    types.NumberValue(big.NewFloat(8080)),
+		"ratio":   types.NumberValue(big.NewFloat(0.5)),
+		"enabled": types.BoolValue(true),
+		"missing": types.StringNull(),
+		"hosts": types.TupleValueMust([]attr.Type{types.StringType, types.NumberType},
+			[]attr.Value{types.StringValue("a"), types.NumberValue(big.NewFloat(2))}),
+		"1": types.StringValue("numeric key"),
+	}
+	if len(attrs) != len(want) {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+	for key, expected := range want {
+		if !attrs[key].Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", key, expected, attrs[key])
+		}
+	}
+	if len(protected) != 3 {
+		t.Errorf("expected the three string values to be protected, got %q", protected)
+	}
+}
+
+func TestDocumentValue_NonFinite(t *testing.T) {
+	doc, err := secretYAML("s3cret\nlimits:\n  max: .inf\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = documentValue(context.Background(), doc, func(s string) string { return s })
+	if err == nil || err.Error() != "limits: max: +Inf cannot be represented as a Terraform number" {
+		t.Errorf("expected an error naming the value, got %v", err)
+	}
+}
+
+func TestDocumentValue_JSONNumber(t *testing.T) {
+	value, err := documentValue(context.Background(), json.Number("12345678901234567890.5"), func(s string) string { return s })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := value.(types.Number).ValueBigFloat().Text('f', 1); got != "12345678901234567890.5" {
+		t.Errorf("expected full precision, got %s", got)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// What to do when a read returns an empty password.
+const (
+	emptyValueWarn  = "warn"
+	emptyValueError = "error"
+)
+
+// emptyValueDiagnostics reports secrets that were read with an empty password.
+//
+// Empty credentials almost always come from a malformed entry, typically one
+// whose first line is blank so the intended value ended up in the body. They
+// are reported as warnings, or as errors if the provider is configured so.
+func (c *GopassClient) emptyValueDiagnostics(paths []string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if len(paths) == 0 {
+		return diags
+	}
+
+	summary := "Secret value is empty"
+	if len(paths) > 1 {
+		summary = "Secret values are empty"
+	}
+	detail := fmt.Sprintf("The password (first line) of %s is empty. Check that the value is on the "+
+		"first line of the entry: gopass show %s", strings.Join(quoteAll(paths), ", "), paths[0])
+
+	if c.failOnEmptyValue {
+		diags.AddError(summary, detail)
+	} else {
+		diags.AddWarning(summary, detail+"\n\nSet empty_value = \"error\" in the provider configuration "+
+			"to fail on empty values instead.")
+	}
+	return diags
+}
+
+// quoteAll returns paths quoted with %q.
+func quoteAll(paths []string) []string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = fmt.Sprintf("%q", p)
+	}
+	return quoted
+}
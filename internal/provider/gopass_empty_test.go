@@ -0,0 +1,97 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// emptyValueTestEntries holds a secret with an empty password next to one
+// with a value.
+var emptyValueTestEntries = map[string]string{"app/USER": "admin", "app/PASSWORD": ""}
+
+func TestEmptyValueDiagnostics(t *testing.T) {
+	client := NewGopassClient("")
+
+	if diags := client.emptyValueDiagnostics(nil); len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+
+	diags := client.emptyValueDiagnostics([]string{"app/a", "app/b"})
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Fatalf("expected one warning, got %v", diags)
+	}
+	if !strings.Contains(diags[0].Detail(), `"app/a", "app/b"`) {
+		t.Errorf("expected both paths in detail, got %q", diags[0].Detail())
+	}
+
+	client.failOnEmptyValue = true
+	if diags := client.emptyValueDiagnostics([]string{"app/a"}); !diags.HasError() {
+		t.Errorf("expected an error, got %v", diags)
+	}
+}
+
+func TestSecretEphemeralResource_Open_EmptyValue(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(emptyValueTestEntries))
+
+	resp := openSecretEphemeral(t, client, "app/PASSWORD")
+	if resp.Diagnostics.HasError() || resp.Diagnostics.WarningsCount() != 1 {
+		t.Fatalf("expected an empty value warning, got %v", resp.Diagnostics)
+	}
+
+	if resp := openSecretEphemeral(t, client, "app/USER"); len(resp.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics for a non-empty value, got %v", resp.Diagnostics)
+	}
+
+	client.failOnEmptyValue = true
+	if resp := openSecretEphemeral(t, client, "app/PASSWORD"); !resp.Diagnostics.HasError() {
+		t.Errorf("expected an error with empty_value = \"error\", got %v", resp.Diagnostics)
+	}
+}
+
+func TestEnvEphemeralResource_Open_EmptyValue(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(emptyValueTestEntries))
+
+	resp := openEnvEphemeral(t, client, "app")
+	if resp.Diagnostics.HasError() || resp.Diagnostics.WarningsCount() != 1 {
+		t.Fatalf("expected an empty value warning, got %v", resp.Diagnostics)
+	}
+	if detail := resp.Diagnostics.Warnings()[0].Detail(); !strings.Contains(detail, `"app/PASSWORD"`) {
+		t.Errorf("expected the empty entry in the warning, got %q", detail)
+	}
+
+	client.failOnEmptyValue = true
+	if resp := openEnvEphemeral(t, client, "app"); !resp.Diagnostics.HasError() {
+		t.Errorf("expected an error with empty_value = \"error\", got %v", resp.Diagnostics)
+	}
+}
+
+func TestProviderConfigure_EmptyValue(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	for value, wantErr := range map[string]bool{"warn": false, "error": false, "ignore": true} {
+		req := provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{
+				"empty_value": tftypes.NewValue(tftypes.String, value),
+			}),
+		}
+		resp := &provider.ConfigureResponse{}
+
+		p.Configure(ctx, req, resp)
+
+		if resp.Diagnostics.HasError() != wantErr {
+			t.Errorf("empty_value = %q: expected error %v, got %v", value, wantErr, resp.Diagnostics)
+			continue
+		}
+		if client, ok := resp.EphemeralResourceData.(*GopassClient); ok && client.failOnEmptyValue != (value == "error") {
+			t.Errorf("empty_value = %q: unexpected failOnEmptyValue %v", value, client.failOnEmptyValue)
+		}
+	}
+}
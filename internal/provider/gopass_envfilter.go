@@ -0,0 +1,48 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"regexp"
+)
+
+// envFilter selects the secrets gopass_env reads by their keys, the paths
+// relative to the resource's path, before anything is decrypted. A key is
+// selected if it matches any include glob (or there are none) and no exclude
+// glob. The nil filter selects every key.
+type envFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// compileEnvGlob compiles a glob of an envFilter, with the syntax of
+// compileGlob.
+func compileEnvGlob(glob string) (*regexp.Regexp, error) {
+	search, err := compileGlob(glob)
+	if err != nil {
+		return nil, err
+	}
+	return search.re, nil
+}
+
+// selects reports whether the secret with key is read.
+func (f *envFilter) selects(key string) bool {
+	if f == nil {
+		return true
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(key) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
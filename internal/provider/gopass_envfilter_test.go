@@ -0,0 +1,108 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+func TestEnvFilter_Selects(t *testing.T) {
+	compile := func(globs ...string) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, glob := range globs {
+			re, err := compileEnvGlob(glob)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			res = append(res, re)
+		}
+		return res
+	}
+
+	testCases := []struct {
+		name   string
+		filter *envFilter
+		key    string
+		want   bool
+	}{
+		{name: "nil filter", filter: nil, key: "README", want: true},
+		{name: "included", filter: &envFilter{include: compile("*_TOKEN")}, key: "CI_TOKEN", want: true},
+		{name: "not included", filter: &envFilter{include: compile("*_TOKEN")}, key: "CI_USER", want: false},
+		{name: "any include", filter: &envFilter{include: compile("*_TOKEN", "*_KEY")}, key: "API_KEY", want: true},
+		{name: "excluded", filter: &envFilter{exclude: compile("README")}, key: "README", want: false},
+		{name: "not excluded", filter: &envFilter{exclude: compile("README")}, key: "API_KEY", want: true},
+		{
+			name:   "exclude wins",
+			filter: &envFilter{include: compile("*_TOKEN"), exclude: compile("OLD_*")},
+			key:    "OLD_TOKEN",
+			want:   false,
+		},
+		{name: "star stays in level", filter: &envFilter{include: compile("*_TOKEN")}, key: "db/CI_TOKEN", want: false},
+		{name: "double star", filter: &envFilter{include: compile("**/*_TOKEN")}, key: "db/CI_TOKEN", want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.selects(tc.key); got != tc.want {
+				t.Errorf("selects(%q) = %v, want %v", tc.key, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompileEnvGlob_Invalid(t *testing.T) {
+	if _, err := compileEnvGlob("[A-Z"); err == nil {
+		t.Error("expected an error for an unterminated character class")
+	}
+}
+
+// mockRecordingStore records the secrets read from it.
+type mockRecordingStore struct {
+	*mockStore
+	mu   sync.Mutex
+	read []string
+}
+
+func (m *mockRecordingStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	m.mu.Lock()
+	m.read = append(m.read, name)
+	m.mu.Unlock()
+	return m.mockStore.Get(ctx, name, revision)
+}
+
+func TestGopassClient_GetFilteredEnvSecrets(t *testing.T) {
+	store := &mockRecordingStore{mockStore: newMockStore()}
+	for _, name := range []string{"env/ci/CI_TOKEN", "env/ci/NPM_TOKEN", "env/ci/README", "env/ci/OLD_TOKEN"} {
+		secret := secrets.New()
+		secret.SetPassword("value of " + name)
+		store.secrets[name] = secret
+	}
+	client := NewGopassClient("")
+	client.store = store
+
+	include, _ := compileEnvGlob("*_TOKEN")
+	exclude, _ := compileEnvGlob("OLD_*")
+	filter := &envFilter{include: []*regexp.Regexp{include}, exclude: []*regexp.Regexp{exclude}}
+
+	values, err := client.GetFilteredEnvSecrets(context.Background(), "env/ci", false, filter)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 2 || values["CI_TOKEN"] != "value of env/ci/CI_TOKEN" || values["NPM_TOKEN"] == "" {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	// Filtered secrets are never decrypted
+	sort.Strings(store.read)
+	if len(store.read) != 2 || store.read[0] != "env/ci/CI_TOKEN" || store.read[1] != "env/ci/NPM_TOKEN" {
+		t.Errorf("expected only the selected secrets to be read, got %v", store.read)
+	}
+}
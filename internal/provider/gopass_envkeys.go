@@ -0,0 +1,70 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sanitizeEnvKey turns a gopass_env key into an environment variable name:
+// letters are uppercased, the separators "-", "." and "/" become "_" and every
+// other character outside [A-Z0-9_] is dropped. A name starting with a digit
+// gets a leading "_". It returns "" if nothing is left.
+func sanitizeEnvKey(key string) string {
+	var name strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			name.WriteRune(r)
+		case r == '-', r == '.', r == '/':
+			name.WriteByte('_')
+		}
+	}
+	s := name.String()
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
+
+// sanitizeEnvKeys renames the keys of values with sanitizeEnvKey. It fails if
+// a key sanitizes to nothing or several keys to the same name, naming all of
+// them, rather than silently dropping secrets.
+func sanitizeEnvKeys(values map[string]string) (map[string]string, error) {
+	sources := make(map[string][]string, len(values))
+	var unnamed []string
+	for key := range values {
+		name := sanitizeEnvKey(key)
+		if name == "" {
+			unnamed = append(unnamed, key)
+			continue
+		}
+		sources[name] = append(sources[name], key)
+	}
+
+	var problems []string
+	if len(unnamed) > 0 {
+		sort.Strings(unnamed)
+		problems = append(problems, strings.Join(quoteAll(unnamed), ", ")+" leave no valid characters")
+	}
+	for name, keys := range sources {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			problems = append(problems, fmt.Sprintf("%s all become %s", strings.Join(quoteAll(keys), ", "), name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("secret names do not map to distinct environment variables: %s",
+			strings.Join(problems, "; "))
+	}
+
+	sanitized := make(map[string]string, len(values))
+	for name, keys := range sources {
+		sanitized[name] = values[keys[0]]
+	}
+	return sanitized, nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeEnvKey(t *testing.T) {
+	testCases := []struct {
+		key  string
+		want string
+	}{
+		{key: "DB_PASSWORD", want: "DB_PASSWORD"},
+		{key: "api-key", want: "API_KEY"},
+		{key: "db/primary/password", want: "DB_PRIMARY_PASSWORD"},
+		{key: "tls.cert", want: "TLS_CERT"},
+		{key: "token (old)!", want: "TOKENOLD"},
+		{key: "2fa-secret", want: "_2FA_SECRET"},
+		{key: "ümlaut", want: "MLAUT"},
+		{key: "!!", want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if got := sanitizeEnvKey(tc.key); got != tc.want {
+				t.Errorf("sanitizeEnvKey(%q) = %q, want %q", tc.key, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeEnvKeys(t *testing.T) {
+	values, err := sanitizeEnvKeys(map[string]string{"api-key": "a", "db/password": "b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 2 || values["API_KEY"] != "a" || values["DB_PASSWORD"] != "b" {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
+func TestSanitizeEnvKeys_Collisions(t *testing.T) {
+	_, err := sanitizeEnvKeys(map[string]string{
+		"api-key": "a",
+		"API_KEY": "b",
+		"api.key": "c",
+		"other":   "d",
+		"!!":      "e",
+	})
+	if err == nil {
+		t.Fatal("expected an error for colliding keys")
+	}
+	for _, want := range []string{`"!!" leave no valid characters`, `"API_KEY", "api-key", "api.key" all become API_KEY`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err.Error())
+		}
+	}
+	if strings.Contains(err.Error(), "other") {
+		t.Errorf("expected only the conflicting keys to be named, got %q", err.Error())
+	}
+}
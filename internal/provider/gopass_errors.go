@@ -0,0 +1,150 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned by GopassClient, for use with errors.Is. The error messages
+// themselves keep the detail gopass reported; these only classify it.
+var (
+	// ErrNotFound means the requested secret does not exist in the store.
+	ErrNotFound = errors.New("secret not found")
+
+	// ErrDecryptionFailed means a secret exists but could not be decrypted,
+	// e.g. because the GPG key is missing or the agent refused to unlock it.
+	ErrDecryptionFailed = errors.New("secret could not be decrypted")
+
+	// ErrStoreUninitialized means the password store could not be opened.
+	ErrStoreUninitialized = errors.New("gopass store not initialized")
+
+	// ErrTimeout means an operation did not finish within its deadline.
+	ErrTimeout = errors.New("gopass operation timed out")
+
+	// ErrNoSecretKey means a secret is not encrypted for any GPG key or age
+	// identity at hand. It is an ErrDecryptionFailed as well.
+	ErrNoSecretKey = errors.New("no key to decrypt the secret")
+
+	// ErrAgentUnavailable means gpg-agent could not be reached to decrypt a
+	// secret. It is an ErrDecryptionFailed as well.
+	ErrAgentUnavailable = errors.New("gpg-agent not available")
+)
+
+// PartialResultError is returned together with the results of a batch read
+// when some secrets could not be read in time. It matches ErrTimeout.
+type PartialResultError struct {
+	Prefix   string
+	TimedOut []string // paths skipped because their read timed out
+}
+
+func (e *PartialResultError) Error() string {
+	return fmt.Sprintf("reading %d secret(s) under %q timed out: %s",
+		len(e.TimedOut), e.Prefix, strings.Join(e.TimedOut, ", "))
+}
+
+func (e *PartialResultError) Unwrap() error {
+	return ErrTimeout
+}
+
+// classifiedError tags an error with one of the exported error kinds without
+// changing its message.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// classify tags err with kind. Errors already of that kind are returned as is.
+func classify(kind, err error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &classifiedError{kind: kind, err: err}
+}
+
+// classifyReadError tags a failed read as ErrNotFound or ErrDecryptionFailed,
+// the latter also as ErrNoSecretKey or ErrAgentUnavailable if gpg or age said
+// so. Timeouts, cancellations, reads refused by the circuit breaker and reads
+// missing from a replayed cassette keep their own classification.
+func classifyReadError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrTimeout), errors.Is(err, ErrCircuitOpen), errors.Is(err, context.Canceled),
+		errors.Is(err, ErrNotRecorded):
+		return err
+	case isNotFound(err):
+		return classify(ErrNotFound, err)
+	}
+	err = classify(ErrDecryptionFailed, err)
+	if problem, ok := classifyGPGError(err); ok && problem.kind != nil {
+		err = classify(problem.kind, err)
+	}
+	return err
+}
+
+// isNotFound reports whether err means that a secret does not exist.
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// errorSummary returns a diagnostic summary for a client error, falling back
+// to fallback for errors without a more precise classification.
+func errorSummary(err error, fallback string) string {
+	var tokenErr *tokenTimeoutError
+	if errors.As(err, &tokenErr) {
+		return tokenTimeoutSummary
+	}
+	var unlockErr *nonInteractiveError
+	if errors.As(err, &unlockErr) {
+		return nonInteractiveSummary
+	}
+	if problem, ok := classifyGPGError(err); ok && !errors.Is(err, ErrNotFound) {
+		return problem.summary
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return "Secret not found"
+	case errors.Is(err, ErrTimeout):
+		return "Gopass operation timed out"
+	case errors.Is(err, ErrNotRecorded):
+		return "Store operation not recorded"
+	case errors.Is(err, ErrCircuitOpen), errors.Is(err, ErrDecryptionFailed):
+		return "Failed to decrypt secret"
+	case errors.Is(err, ErrStoreUninitialized):
+		return "Gopass store not initialized"
+	case errors.Is(err, ErrDecryptLimit):
+		return "Too many secrets decrypted"
+	case errors.Is(err, ErrReadOnly):
+		return "Provider is read-only"
+	case errors.Is(err, ErrPwnedPassword):
+		return "Password found in data breaches"
+	case errors.Is(err, ErrRecipientPolicy):
+		return "Unexpected secret recipients"
+	case errors.Is(err, ErrStoreIntegrity):
+		return "Password store failed verification"
+	case errors.Is(err, ErrChecksumOnly):
+		return "Provider is checksum-only"
+	case errors.Is(err, ErrSecretExpired):
+		return "Secret expired"
+	case errors.Is(err, ErrPolicyViolation):
+		return "Secret path not allowed by policy"
+	case errors.Is(err, ErrInvalidPath):
+		return "Invalid secret path"
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+)
+
+func TestClassifyReadError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{name: "not found", err: fmt.Errorf("%w: x", ErrNotFound), kind: ErrNotFound},
+		{name: "not found text", err: errors.New("gpg: pinentry-mac not found"), kind: ErrDecryptionFailed},
+		{name: "gpg error", err: errors.New("gpg: decryption failed: No secret key"), kind: ErrDecryptionFailed},
+		{name: "no secret key", err: errors.New("gpg: decryption failed: No secret key"), kind: ErrNoSecretKey},
+		{name: "no age identity", err: errors.New("age: no identity matched any of the recipients"), kind: ErrNoSecretKey},
+		{name: "agent unavailable", err: errors.New("gpg: can't connect to the agent: IPC connect call failed"), kind: ErrAgentUnavailable},
+		{name: "gopass decrypt", err: errors.New("failed to decrypt"), kind: ErrDecryptionFailed},
+		{name: "timeout", err: contextError("reading", time.Second, context.DeadlineExceeded), kind: ErrTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := classifyReadError(tc.err)
+			if !errors.Is(err, tc.kind) {
+				t.Errorf("classifyReadError(%v) is not %v", tc.err, tc.kind)
+			}
+			if err.Error() != tc.err.Error() {
+				t.Errorf("classification changed the message: %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestClassifyReadError_KeepsOwnKinds(t *testing.T) {
+	cancelled := fmt.Errorf("reading aborted: %w", context.Canceled)
+	if err := classifyReadError(cancelled); errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("cancelled read classified as decryption failure: %v", err)
+	}
+
+	circuit := fmt.Errorf("%w: skipped", ErrCircuitOpen)
+	if err := classifyReadError(circuit); errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("rejected read classified as decryption failure: %v", err)
+	}
+
+	notFound := classifyReadError(fmt.Errorf("%w: x", ErrNotFound))
+	if errors.Is(notFound, ErrDecryptionFailed) || errors.Is(notFound, ErrNoSecretKey) {
+		t.Errorf("missing secret classified as decryption failure: %v", notFound)
+	}
+
+	other := classifyReadError(errors.New("failed to decrypt"))
+	if errors.Is(other, ErrNoSecretKey) || errors.Is(other, ErrAgentUnavailable) {
+		t.Errorf("unknown decryption failure classified too narrowly: %v", other)
+	}
+
+	if err := classifyReadError(nil); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
+
+func TestClassify_KeepsOriginalChain(t *testing.T) {
+	err := classify(ErrStoreUninitialized, fmt.Errorf("init: %w", api.ErrNotInitialized))
+	if !errors.Is(err, ErrStoreUninitialized) || !errors.Is(err, api.ErrNotInitialized) {
+		t.Errorf("expected both kinds in chain, got %v", err)
+	}
+	if again := classify(ErrStoreUninitialized, err); again != err {
+		t.Error("expected already classified error to be returned unchanged")
+	}
+}
+
+func TestGopassClient_GetSecret_ErrNotFound(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = newMockStore()
+
+	_, err := client.GetSecret(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("missing secret classified as decryption failure: %v", err)
+	}
+}
+
+func TestGopassClient_GetSecret_ErrDecryptionFailed(t *testing.T) {
+	client := NewGopassClient("")
+	store := newMockStore()
+	store.shouldFail = true
+	store.failMsg = "gpg: decryption failed: No secret key"
+	client.store = store
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected ErrDecryptionFailed, got %v", err)
+	}
+}
+
+func TestGopassClient_GetSecret_ErrTimeout(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = &mockBlockingStore{mockStore: newMockStore()}
+	client.timeouts.Read = 20 * time.Millisecond
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestGopassClient_ErrStoreUninitialized(t *testing.T) {
+	client := NewGopassClient("")
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		return nil, api.ErrNotInitialized
+	}
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if !errors.Is(err, ErrStoreUninitialized) {
+		t.Errorf("expected ErrStoreUninitialized, got %v", err)
+	}
+	if !errors.Is(err, api.ErrNotInitialized) {
+		t.Errorf("expected gopass error to stay in the chain, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "no initialized gopass store") || !strings.Contains(err.Error(), "store_path") {
+		t.Errorf("expected guidance on pointing at the store, got %v", err)
+	}
+}
+
+func TestGopassClient_StoreLocation(t *testing.T) {
+	t.Setenv("PASSWORD_STORE_DIR", "/srv/env-store")
+
+	client := NewGopassClient("~/stores/root")
+	client.userHomeDir = func() (string, error) { return "/home/user", nil }
+	if got, want := client.storeLocation(), "/home/user/stores/root (from store_path)"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	client = NewGopassClient("")
+	if got, want := client.storeLocation(), "/srv/env-store (from PASSWORD_STORE_DIR)"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	os.Unsetenv("PASSWORD_STORE_DIR")
+	if got := client.storeLocation(); !strings.Contains(got, "gopass configuration") {
+		t.Errorf("expected gopass configuration as location, got %q", got)
+	}
+}
+
+func TestErrorSummary(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want string
+	}{
+		{err: classify(ErrNotFound, errors.New("x")), want: "Secret not found"},
+		{err: classify(ErrDecryptionFailed, errors.New("x")), want: "Failed to decrypt secret"},
+		{err: fmt.Errorf("%w: skipped", ErrCircuitOpen), want: "Failed to decrypt secret"},
+		{err: classify(ErrStoreUninitialized, errors.New("x")), want: "Gopass store not initialized"},
+		{err: classify(ErrTimeout, errors.New("x")), want: "Gopass operation timed out"},
+		{err: errors.New("x"), want: "Failed to read secret"},
+	}
+
+	for _, tc := range testCases {
+		if got := errorSummary(tc.err, "Failed to read secret"); got != tc.want {
+			t.Errorf("errorSummary(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// What to do when a read returns an expired secret.
+const (
+	expiredSecretWarn  = "warn"
+	expiredSecretError = "error"
+)
+
+// Conventional keys holding a secret's expiry: an absolute date, or a
+// lifetime counted from the last write of the entry. expires wins if a secret
+// has both.
+const (
+	expiresKey = "expires"
+	ttlKey     = "ttl"
+)
+
+// ErrSecretExpired is returned for reads of secrets past their expiry when
+// expired_secrets is "error".
+var ErrSecretExpired = errors.New("secret expired")
+
+// expiryLayouts are the accepted formats of the expires key.
+var expiryLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// parseExpiry parses the value of an expires key.
+func parseExpiry(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range expiryLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected an RFC 3339 timestamp or a YYYY-MM-DD date, got %q", value)
+}
+
+// parseTTL parses the value of a ttl key: a Go duration such as "720h", or
+// a number of days such as "90d".
+func parseTTL(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if ttl, err := time.ParseDuration(value); err == nil && ttl > 0 {
+		return ttl, nil
+	}
+	return 0, fmt.Errorf("expected a positive duration such as \"720h\" or \"90d\", got %q", value)
+}
+
+// secretModTime returns when the entry at path was last written, from the
+// modification time of its encrypted file. Like recipientsFor, it only works
+// for stores on disk.
+func (c *GopassClient) secretModTime(path string) (time.Time, error) {
+	root, rel, err := c.storeDirFor(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, ext := range []string{".gpg", ".age"} {
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(rel)+ext))
+		if err == nil {
+			return info.ModTime().UTC(), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return time.Time{}, err
+		}
+	}
+	return time.Time{}, fmt.Errorf("no encrypted file for %q in %s", path, root)
+}
+
+// secretWriteTime returns when the entry at path was last written: the commit
+// time of its last change if the store is a git repository, otherwise the
+// modification time of its encrypted file, which a checkout or copy resets.
+func (c *GopassClient) secretWriteTime(ctx context.Context, path string) (time.Time, error) {
+	dir, _, err := c.storeDirFor(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !isGitRepo(dir) {
+		return c.secretModTime(path)
+	}
+	commits, err := c.secretHistory(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return commits[0].time.UTC(), nil
+}
+
+// secretExpiry returns when the secret at path expires, or the zero time if
+// it has neither an expires nor a ttl key.
+func (c *GopassClient) secretExpiry(ctx context.Context, path string, secret gopass.Secret) (time.Time, error) {
+	if value, ok := secret.Get(expiresKey); ok {
+		return parseExpiry(value)
+	}
+	value, ok := secret.Get(ttlKey)
+	if !ok {
+		return time.Time{}, nil
+	}
+	ttl, err := parseTTL(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	written, err := c.secretWriteTime(ctx, path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("the ttl is counted from the last write, which is unknown: %w", err)
+	}
+	return written.Add(ttl), nil
+}
+
+// expiryOf is secretExpiry reporting problems as a warning, once per secret,
+// and returning the zero time for them. Quoting the key values is safe: an
+// expiry is not secret.
+func (c *GopassClient) expiryOf(ctx context.Context, path string, secret gopass.Secret) time.Time {
+	expires, err := c.secretExpiry(ctx, path, secret)
+	if err != nil {
+		c.warnings.addOnce("expiry:"+path, "Secret expiry ignored",
+			fmt.Sprintf("The expiry of secret %q is ignored: %s", path, err.Error()))
+		return time.Time{}
+	}
+	return expires
+}
+
+// checkExpiry reports a read of a secret past its expiry: with a warning, or
+// with an error wrapping ErrSecretExpired if expired_secrets is "error".
+func (c *GopassClient) checkExpiry(ctx context.Context, path string, secret gopass.Secret) error {
+	expires := c.expiryOf(ctx, path, secret)
+	if expires.IsZero() || c.now().Before(expires) {
+		return nil
+	}
+
+	detail := fmt.Sprintf("secret %q expired on %s. Rotate it, or update its %s or %s key if it is still valid",
+		path, expires.Format(time.RFC3339), expiresKey, ttlKey)
+	if c.failOnExpired {
+		return fmt.Errorf("%w: %s", ErrSecretExpired, detail)
+	}
+	c.warnings.addOnce("expired:"+path, "Secret expired", strings.ToUpper(detail[:1])+detail[1:]+
+		".\n\nSet expired_secrets = \"error\" in the provider configuration to fail such reads instead.")
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+var expiryTestNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newExpiryTestClient returns a client at expiryTestNow whose store lives in
+// a temporary directory, holding app/db with the given key-value lines.
+func newExpiryTestClient(t *testing.T, keys map[string]string) (*GopassClient, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	secret := secrets.New()
+	secret.SetPassword("db-value")
+	for key, value := range keys {
+		secret.Set(key, value)
+	}
+	store := newMockStore()
+	store.secrets["app/db"] = secret
+	store.secrets["app/env/KEY"] = secret
+
+	client := NewGopassClient(dir)
+	client.store = store
+	client.now = func() time.Time { return expiryTestNow }
+	return client, dir
+}
+
+func TestParseTTL(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90d":    90 * 24 * time.Hour,
+		" 720h ": 720 * time.Hour,
+		"1h30m":  90 * time.Minute,
+	}
+	for value, want := range tests {
+		if got, err := parseTTL(value); err != nil || got != want {
+			t.Errorf("%q: expected %v, got %v (%v)", value, want, got, err)
+		}
+	}
+	for _, value := range []string{"", "0d", "-1h", "d", "3w", "0"} {
+		if _, err := parseTTL(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestSecretExpiry(t *testing.T) {
+	client, dir := newExpiryTestClient(t, nil)
+	writeStoreFile(t, dir, "app/db.gpg", "ciphertext")
+	written := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "app", "db.gpg"), written, written); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		keys map[string]string
+		want time.Time
+	}{
+		{"none", nil, time.Time{}},
+		{"expires", map[string]string{"expires": "2026-02-01"}, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"ttl", map[string]string{"ttl": "30d"}, written.Add(30 * 24 * time.Hour)},
+		{"expires wins", map[string]string{"expires": "2027-01-01", "ttl": "1d"}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		secret := secrets.New()
+		for key, value := range tt.keys {
+			secret.Set(key, value)
+		}
+		got, err := client.secretExpiry(context.Background(), "app/db", secret)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%s: expected %v, got %v (%v)", tt.name, tt.want, got, err)
+		}
+	}
+
+	secret := secrets.New()
+	secret.Set("ttl", "30d")
+	if _, err := client.secretExpiry(context.Background(), "app/missing", secret); err == nil {
+		t.Error("expected an error for a ttl without an encrypted file")
+	}
+}
+
+func TestSecretExpiry_GitCommitTime(t *testing.T) {
+	client, _ := newExpiryTestClient(t, nil)
+	dir := newGitStore(t)
+	client.storePath = dir
+	committed := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	commitFile(t, dir, "app/db.gpg", "ciphertext", committed)
+	// A fresh checkout or copy gives the file a new modification time
+	if err := os.Chtimes(filepath.Join(dir, "app", "db.gpg"), expiryTestNow, expiryTestNow); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := secrets.New()
+	secret.Set("ttl", "30d")
+	got, err := client.secretExpiry(context.Background(), "app/db", secret)
+	if err != nil || !got.Equal(committed.Add(30*24*time.Hour)) {
+		t.Errorf("expected the ttl to count from the commit, got %v (%v)", got, err)
+	}
+
+	// Not committed yet
+	writeStoreFile(t, dir, "app/new.gpg", "ciphertext")
+	if _, err := client.secretExpiry(context.Background(), "app/new", secret); err == nil {
+		t.Error("expected an error for an entry without commits in a git store")
+	}
+}
+
+func TestCheckExpiry_Warns(t *testing.T) {
+	client, _ := newExpiryTestClient(t, map[string]string{"expires": "2026-02-01"})
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	warnings := client.takeWarnings()
+	if len(warnings) != 1 || warnings[0].Summary() != "Secret expired" {
+		t.Fatalf("expected one expiry warning, got %v", warnings)
+	}
+	if detail := warnings[0].Detail(); !strings.Contains(detail, "2026-02-01T00:00:00Z") ||
+		!strings.Contains(detail, `expired_secrets = "error"`) {
+		t.Errorf("unexpected detail %q", detail)
+	}
+}
+
+func TestCheckExpiry_Fails(t *testing.T) {
+	client, _ := newExpiryTestClient(t, map[string]string{"expires": "2026-02-01"})
+	client.failOnExpired = true
+	ctx := context.Background()
+
+	reads := map[string]func() error{
+		"GetSecret": func() error {
+			_, err := client.GetSecret(ctx, "app/db")
+			return err
+		},
+		"GetSecretFull": func() error {
+			_, _, err := client.GetSecretFull(ctx, "app/db")
+			return err
+		},
+		"GetSecretFields": func() error {
+			_, _, err := client.GetSecretFields(ctx, "app/db", "expires")
+			return err
+		},
+		"GetSecretBase64": func() error {
+			_, err := client.GetSecretBase64(ctx, "app/db")
+			return err
+		},
+		"GetEnvSecrets": func() error {
+			_, err := client.GetEnvSecrets(ctx, "app/env")
+			return err
+		},
+	}
+	for name, read := range reads {
+		err := read()
+		if !errors.Is(err, ErrSecretExpired) {
+			t.Errorf("%s: expected an expiry error, got %v", name, err)
+			continue
+		}
+		if got := errorSummary(err, "fallback"); got != "Secret expired" {
+			t.Errorf("%s: unexpected summary %q", name, got)
+		}
+	}
+
+	// Derived outputs stay available, the expiry is one of them
+	digest, _, err := client.SecretDigest(ctx, "app/db")
+	if err != nil || digest.Expires.IsZero() {
+		t.Errorf("expected the digest with its expiry, got %+v (%v)", digest, err)
+	}
+}
+
+func TestCheckExpiry_NotYetExpired(t *testing.T) {
+	client, _ := newExpiryTestClient(t, map[string]string{"expires": "2026-03-02"})
+	client.failOnExpired = true
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if warnings := client.takeWarnings(); len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestCheckExpiry_UnusableExpiryIsIgnored(t *testing.T) {
+	// The store directory holds no encrypted files, so the ttl cannot be counted
+	client, _ := newExpiryTestClient(t, map[string]string{"ttl": "1d"})
+	client.failOnExpired = true
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	warnings := client.takeWarnings()
+	if len(warnings) != 1 || warnings[0].Summary() != "Secret expiry ignored" {
+		t.Errorf("expected a warning, got %v", warnings)
+	}
+}
+
+func TestProviderConfigure_ExpiredSecrets(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	for value, wantErr := range map[string]bool{"warn": false, "error": false, "ignore": true} {
+		resp := &provider.ConfigureResponse{}
+		p.Configure(ctx, provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{
+				"expired_secrets": tftypes.NewValue(tftypes.String, value),
+			}),
+		}, resp)
+
+		if resp.Diagnostics.HasError() != wantErr {
+			t.Errorf("expired_secrets = %q: expected error %v, got %v", value, wantErr, resp.Diagnostics)
+			continue
+		}
+		if client, ok := resp.EphemeralResourceData.(*GopassClient); ok && client.failOnExpired != (value == "error") {
+			t.Errorf("expired_secrets = %q: unexpected failOnExpired %v", value, client.failOnExpired)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// faultsEnv enables fault injection: store operations fail or stall at random,
+// the way they do with a flaky gpg-agent, a slow hardware token or a busy git
+// repository. It exists to test the retry, circuit breaker and timeout
+// handling, and must never be set for real runs.
+//
+// The value is a comma-separated list of faults and their probabilities,
+// e.g. "transient=0.3,slow=0.5,delay=2s,seed=1":
+//
+//	transient  operations fail with a gpg-agent connection error (retried)
+//	git_lock   writes fail because the git index is locked (retried)
+//	decrypt    reads fail to decrypt (counted by the circuit breaker)
+//	slow       operations stall for delay first (hitting timeouts)
+//	delay      how long slow operations stall, default 1s
+//	seed       seeds the random choices, for reproducible runs
+const faultsEnv = "GOPASS_PROVIDER_FAULTS"
+
+// Errors returned by injected faults. They mimic the messages of the real
+// failures, so the client classifies them the same way.
+const (
+	faultTransientMessage = "gpg: can't connect to the agent: IPC connect call failed (injected fault)"
+	faultGitLockMessage   = "fatal: Unable to create '.git/index.lock': File exists (injected fault)"
+	faultDecryptMessage   = "failed to decrypt (injected fault)"
+)
+
+// defaultFaultDelay is how long slow operations stall unless delay is set.
+const defaultFaultDelay = time.Second
+
+// faultInjector decides which store operations fail.
+type faultInjector struct {
+	transient float64
+	gitLock   float64
+	decrypt   float64
+	slow      float64
+	delay     time.Duration
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// parseFaults parses a faultsEnv value. It returns nil for an empty spec.
+func parseFaults(spec string) (*faultInjector, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	f := &faultInjector{delay: defaultFaultDelay}
+	seed := uint64(time.Now().UnixNano())
+	for _, item := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=value", item)
+		}
+
+		var err error
+		switch name {
+		case "transient":
+			f.transient, err = parseProbability(value)
+		case "git_lock":
+			f.gitLock, err = parseProbability(value)
+		case "decrypt":
+			f.decrypt, err = parseProbability(value)
+		case "slow":
+			f.slow, err = parseProbability(value)
+		case "delay":
+			f.delay, err = time.ParseDuration(value)
+			if err == nil && f.delay < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "seed":
+			seed, err = strconv.ParseUint(value, 10, 64)
+		default:
+			return nil, fmt.Errorf("unknown fault %q, expected transient, git_lock, decrypt, slow, delay or seed", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+
+	f.rng = rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // faults need reproducibility, not secrecy
+	return f, nil
+}
+
+// parseProbability parses a probability between 0 and 1.
+func parseProbability(value string) (float64, error) {
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 1 {
+		return 0, fmt.Errorf("must be between 0 and 1")
+	}
+	return p, nil
+}
+
+// hit reports whether a fault of probability p occurs.
+func (f *faultInjector) hit(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rng.Float64() < p
+}
+
+// before is run ahead of every store operation. It stalls slow operations and
+// returns the error of an injected failure, if any.
+func (f *faultInjector) before(ctx context.Context, write bool) error {
+	if f.hit(f.slow) {
+		if err := sleepContext(ctx, f.delay); err != nil {
+			return err
+		}
+	}
+	if f.hit(f.transient) {
+		return errors.New(faultTransientMessage)
+	}
+	if write && f.hit(f.gitLock) {
+		return errors.New(faultGitLockMessage)
+	}
+	return nil
+}
+
+// wrap returns store with faults injected into its operations. It returns
+// store itself on a nil injector.
+func (f *faultInjector) wrap(store SecretStore) SecretStore {
+	if f == nil || store == nil {
+		return store
+	}
+	return &faultyStore{faults: f, inner: store}
+}
+
+// configureFaults enables fault injection if faultsEnv is set.
+func configureFaults(client *GopassClient) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	faults, err := parseFaults(os.Getenv(faultsEnv))
+	if err != nil {
+		diags.AddError("Invalid fault injection settings",
+			fmt.Sprintf("%s: %s.", faultsEnv, err.Error()))
+		return diags
+	}
+	if faults != nil {
+		client.faults = faults
+		diags.AddWarning("Fault injection enabled",
+			fmt.Sprintf("%s is set: store operations will fail or stall at random. It is meant for testing "+
+				"the provider only; unset it for real runs.", faultsEnv))
+	}
+	return diags
+}
+
+// faultyStore injects faults into the operations of another store.
+type faultyStore struct {
+	faults *faultInjector
+	inner  SecretStore
+}
+
+func (s *faultyStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	if err := s.faults.before(ctx, false); err != nil {
+		return nil, err
+	}
+	if s.faults.hit(s.faults.decrypt) {
+		return nil, fmt.Errorf("%s", faultDecryptMessage)
+	}
+	return s.inner.Get(ctx, name, revision)
+}
+
+func (s *faultyStore) List(ctx context.Context) ([]string, error) {
+	if err := s.faults.before(ctx, false); err != nil {
+		return nil, err
+	}
+	return s.inner.List(ctx)
+}
+
+func (s *faultyStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	if err := s.faults.before(ctx, true); err != nil {
+		return err
+	}
+	return s.inner.Set(ctx, name, sec)
+}
+
+func (s *faultyStore) Remove(ctx context.Context, name string) error {
+	if err := s.faults.before(ctx, true); err != nil {
+		return err
+	}
+	return s.inner.Remove(ctx, name)
+}
+
+func (s *faultyStore) Revisions(ctx context.Context, name string) ([]string, error) {
+	if err := s.faults.before(ctx, false); err != nil {
+		return nil, err
+	}
+	return s.inner.Revisions(ctx, name)
+}
+
+// Close closes the wrapped store if it supports closing.
+func (s *faultyStore) Close(ctx context.Context) error {
+	if closer, ok := s.inner.(interface{ Close(context.Context) error }); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newFaultyClient returns a client for a memory store with the faults of spec
+// injected, recording backoff delays instead of sleeping.
+func newFaultyClient(t *testing.T, spec string, entries map[string]string) (*GopassClient, *[]time.Duration) {
+	t.Helper()
+
+	faults, err := parseFaults(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := NewGopassClientWithStore(NewMemoryStore(entries))
+	client.faults = faults
+
+	var delays []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return client, &delays
+}
+
+func TestParseFaults(t *testing.T) {
+	f, err := parseFaults(" transient=0.25, git_lock=1,decrypt=0 ,slow=0.5,delay=250ms,seed=7 ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.transient != 0.25 || f.gitLock != 1 || f.decrypt != 0 || f.slow != 0.5 || f.delay != 250*time.Millisecond {
+		t.Errorf("unexpected faults %+v", f)
+	}
+
+	if f, err := parseFaults("  "); f != nil || err != nil {
+		t.Errorf("expected no faults for an empty spec, got %+v (%v)", f, err)
+	}
+	if f, _ := parseFaults("slow=1"); f.delay != defaultFaultDelay {
+		t.Errorf("expected the default delay, got %s", f.delay)
+	}
+
+	for _, spec := range []string{"transient", "transient=1.5", "decrypt=-0.1", "slow=often", "delay=-1s", "seed=x", "flaky=1"} {
+		if _, err := parseFaults(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestFaults_TransientRetried(t *testing.T) {
+	client, delays := newFaultyClient(t, "transient=1", map[string]string{"app/db": "s3cret"})
+
+	_, err := client.GetSecret(context.Background(), "app/db")
+	if err == nil || !strings.Contains(err.Error(), "still failing after 4 attempts") {
+		t.Fatalf("expected the retries to give up, got %v", err)
+	}
+	if len(*delays) != defaultRetryAttempts-1 {
+		t.Errorf("expected %d backoffs, got %v", defaultRetryAttempts-1, *delays)
+	}
+	if client.breaker.consecutive != 1 {
+		t.Errorf("expected one failed read for the breaker, got %d", client.breaker.consecutive)
+	}
+}
+
+func TestFaults_TransientRecovers(t *testing.T) {
+	client, delays := newFaultyClient(t, "transient=0.5,seed=3", map[string]string{"app/db": "s3cret"})
+	client.breaker.threshold = 0
+
+	succeeded := 0
+	for range 20 {
+		if value, err := client.GetSecret(context.Background(), "app/db"); err == nil && value == "s3cret" {
+			succeeded++
+		}
+	}
+	if len(*delays) == 0 {
+		t.Error("expected some reads to be retried")
+	}
+	if succeeded < 15 {
+		t.Errorf("expected retries to absorb most faults, only %d of 20 reads succeeded", succeeded)
+	}
+}
+
+func TestFaults_GitLockOnlyAffectsWrites(t *testing.T) {
+	client, delays := newFaultyClient(t, "git_lock=1", map[string]string{"app/db": "s3cret"})
+	ctx := context.Background()
+
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+		t.Fatalf("expected reads to work, got %q (%v)", value, err)
+	}
+	err := client.SetSecret(ctx, "app/db", "changed")
+	if err == nil || !strings.Contains(err.Error(), "index.lock") {
+		t.Fatalf("expected the write to fail on the lock, got %v", err)
+	}
+	if len(*delays) == 0 {
+		t.Error("expected the write to be retried")
+	}
+	if value, _ := client.GetSecret(ctx, "app/db"); value != "s3cret" {
+		t.Errorf("expected the secret to be unchanged, got %q", value)
+	}
+}
+
+func TestFaults_DecryptOpensBreaker(t *testing.T) {
+	client, delays := newFaultyClient(t, "decrypt=1", map[string]string{"app/db": "s3cret"})
+	ctx := context.Background()
+
+	for range defaultMaxDecryptFailures {
+		if _, err := client.GetSecret(ctx, "app/db"); !errors.Is(err, ErrDecryptionFailed) {
+			t.Fatalf("expected a decryption failure, got %v", err)
+		}
+	}
+	if _, err := client.GetSecret(ctx, "app/db"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the breaker to open, got %v", err)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("expected decryption failures not to be retried, got %v", *delays)
+	}
+}
+
+func TestFaults_SlowHitsTimeout(t *testing.T) {
+	client, _ := newFaultyClient(t, "slow=1,delay=1h", map[string]string{"app/db": "s3cret"})
+	client.timeouts.Read = 20 * time.Millisecond
+
+	start := time.Now()
+	_, err := client.GetSecret(context.Background(), "app/db")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the read to be abandoned at its deadline, took %s", elapsed)
+	}
+}
+
+func TestFaults_Reproducible(t *testing.T) {
+	outcomes := func() string {
+		client, _ := newFaultyClient(t, "decrypt=0.5,seed=42", map[string]string{"app/db": "s3cret"})
+		client.breaker.threshold = 0
+
+		var b strings.Builder
+		for range 32 {
+			if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+				b.WriteByte('x')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		return b.String()
+	}
+
+	first, second := outcomes(), outcomes()
+	if first != second {
+		t.Errorf("expected the same seed to inject the same faults:\n%s\n%s", first, second)
+	}
+	if !strings.Contains(first, "x") || !strings.Contains(first, ".") {
+		t.Errorf("expected a mix of failures and successes, got %s", first)
+	}
+}
+
+func TestProviderConfigure_Faults(t *testing.T) {
+	t.Setenv(faultsEnv, "transient=0.1,seed=1")
+	resp := configureTestProvider(t, map[string]tftypes.Value{
+		"backend": tftypes.NewValue(tftypes.String, "mock"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.EphemeralResourceData.(*GopassClient); client.faults == nil || client.faults.transient != 0.1 {
+		t.Errorf("expected faults to be injected, got %+v", client.faults)
+	}
+	warnings := resp.Diagnostics.Warnings()
+	if len(warnings) != 1 || warnings[0].Summary() != "Fault injection enabled" {
+		t.Errorf("expected a warning about fault injection, got %v", warnings)
+	}
+
+	t.Setenv(faultsEnv, "transient=2")
+	if resp := configureTestProvider(t, nil); !resp.Diagnostics.HasError() {
+		t.Error("expected invalid faults to be rejected")
+	}
+}
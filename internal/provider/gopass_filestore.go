@@ -0,0 +1,235 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// gitShow returns the content of file at revision in the git repository at
+// dir; injectable for testing.
+var gitShow = func(ctx context.Context, dir, revision, file string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "show", revision+":"+filepath.ToSlash(file))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// gitCommitFiles stages the given files in the git repository at dir, removed
+// ones included, and commits them with message; injectable for testing.
+var gitCommitFiles = func(ctx context.Context, dir, message string, files ...string) error {
+	for _, args := range [][]string{
+		append([]string{"-C", dir, "add", "--all", "--"}, files...),
+		{"-C", dir, "commit", "--quiet", "-m", message},
+	} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %w: %s", args[2], err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// entryFiles is the on-disk layout pass and passage share: every entry is an
+// encrypted file named after its path plus ext below dir, and changes are
+// committed with pass's messages if dir is a git repository, whose commits
+// are then the revisions of an entry.
+type entryFiles struct {
+	dir string
+	ext string // e.g. ".gpg"
+}
+
+// openEntryFiles returns the layout for the store in dir, which must exist.
+func openEntryFiles(kind, dir, ext string) (entryFiles, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return entryFiles{}, fmt.Errorf("%s store: %w", kind, err)
+	}
+	if !info.IsDir() {
+		return entryFiles{}, fmt.Errorf("%s store: %s is not a directory", kind, dir)
+	}
+	return entryFiles{dir: dir, ext: ext}, nil
+}
+
+// file returns the path of the entry name, relative to the store directory.
+func (s entryFiles) file(name string) (string, error) {
+	if name == "" || normalizePath(name) != name || slices.Contains(strings.Split(name, "/"), "..") {
+		return "", fmt.Errorf("invalid secret path %q", name)
+	}
+	return filepath.FromSlash(name) + s.ext, nil
+}
+
+// read returns the encrypted content of the entry name at revision: "latest"
+// or "" for the file on disk, otherwise a git commit hash.
+func (s entryFiles) read(ctx context.Context, name, revision string) ([]byte, error) {
+	file, err := s.file(name)
+	if err != nil {
+		return nil, err
+	}
+	if revision != "latest" && revision != "" {
+		content, err := gitShow(ctx, s.dir, revision, file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: revision %q of %s: %s", ErrNotFound, revision, name, err.Error())
+		}
+		return content, nil
+	}
+	content, err := os.ReadFile(filepath.Join(s.dir, file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return content, err
+}
+
+// List returns the paths of all entries, sorted. Hidden files and
+// directories, like .git, are skipped.
+func (s entryFiles) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(s.dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != s.dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), s.ext) {
+			return ctx.Err()
+		}
+		rel, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return err
+		}
+		names = append(names, strings.TrimSuffix(filepath.ToSlash(rel), s.ext))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// nearest returns the file named base in the directory of file or the
+// closest directory above it within the store, or "" if there is none.
+func (s entryFiles) nearest(file, base string) string {
+	for dir := filepath.Dir(filepath.Join(s.dir, file)); ; dir = filepath.Dir(dir) {
+		candidate := filepath.Join(dir, base)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if rel, err := filepath.Rel(s.dir, dir); err != nil || rel == "." {
+			return ""
+		}
+	}
+}
+
+// write replaces the entry name with the encrypted content and commits it.
+func (s entryFiles) write(ctx context.Context, name string, content []byte) error {
+	file, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(s.dir, file)
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return err
+	}
+	_, existed := os.Stat(target)
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Add given password for %s to store.", name)
+	if existed == nil {
+		message = fmt.Sprintf("Edit password for %s.", name)
+	}
+	return s.commit(ctx, message, file)
+}
+
+// Remove deletes the entry name and the directories it leaves empty, and
+// commits the removal.
+func (s entryFiles) Remove(ctx context.Context, name string) error {
+	file, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.dir, file)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return err
+	}
+	for dir := filepath.Dir(filepath.Join(s.dir, file)); ; dir = filepath.Dir(dir) {
+		if rel, err := filepath.Rel(s.dir, dir); err != nil || rel == "." || os.Remove(dir) != nil {
+			break
+		}
+	}
+	return s.commit(ctx, fmt.Sprintf("Remove %s from store.", name), file)
+}
+
+// Revisions returns the hashes of the git commits that changed the entry
+// name, newest first, or "latest" alone if the store is not a git repository.
+func (s entryFiles) Revisions(ctx context.Context, name string) ([]string, error) {
+	file, err := s.file(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, file)); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if !s.isGitRepository() {
+		return []string{"latest"}, nil
+	}
+	commits, err := gitLog(ctx, s.dir, file)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]string, len(commits))
+	for i, commit := range commits {
+		revisions[i] = commit.hash
+	}
+	return revisions, nil
+}
+
+// isGitRepository reports whether the store directory is a git repository.
+func (s entryFiles) isGitRepository() bool {
+	_, err := os.Stat(filepath.Join(s.dir, ".git"))
+	return err == nil
+}
+
+// commit commits the change to file like pass does, if the store is a git
+// repository.
+func (s entryFiles) commit(ctx context.Context, message, file string) error {
+	if !s.isGitRepository() {
+		return nil
+	}
+	return gitCommitFiles(ctx, s.dir, message, file)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// interactionForecast counts the distinct secrets decrypted with a hardware
+// token, to tell the user how many touch or PIN prompts applying a plan takes.
+// Terraform opens ephemeral resources again during apply, so every secret
+// decrypted while planning is decrypted once more.
+type interactionForecast struct {
+	mu    sync.Mutex
+	paths map[string]bool
+	shown bool
+}
+
+// record counts a decryption of path.
+func (f *interactionForecast) record(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.paths == nil {
+		f.paths = make(map[string]bool)
+	}
+	f.paths[path] = true
+}
+
+// interactionForecast returns a warning forecasting the hardware token
+// interactions of an apply, the first time it is called in hardware token
+// mode after a decryption. Later calls return nothing, so a plan with many
+// changes shows the forecast once.
+func (c *GopassClient) interactionForecast() diag.Diagnostics {
+	if c == nil || c.decryptSlots == nil {
+		return nil
+	}
+
+	f := &c.forecast
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.shown || len(f.paths) == 0 {
+		return nil
+	}
+	f.shown = true
+
+	var diags diag.Diagnostics
+	diags.AddWarning(
+		fmt.Sprintf("Expect up to %d hardware token interaction(s)", len(f.paths)),
+		fmt.Sprintf("Planning decrypted %d distinct secret(s) with the hardware token. Applying the "+
+			"changes decrypts them again, one touch or PIN prompt each unless gpg-agent caches the PIN, "+
+			"and waits for every prompt to be answered. Keep the token connected until the apply "+
+			"finishes.\n\n"+
+			"Secrets first needed by resources planned later are not counted yet. To be prompted less "+
+			"often, list the secrets in prefetch_paths or apply only what you need with -target.", len(f.paths)),
+	)
+	return diags
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// forecastTestEntries holds the secrets the forecast tests decrypt.
+var forecastTestEntries = map[string]string{"app/db": "db-value", "app/api": "api-value"}
+
+func TestInteractionForecast(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(forecastTestEntries))
+	client.decryptSlots = make(chan struct{}, 1)
+	ctx := context.Background()
+
+	if diags := client.interactionForecast(); len(diags) != 0 {
+		t.Errorf("expected no forecast before any decryption, got %v", diags)
+	}
+	for _, path := range []string{"app/db", "app/api", "app/db"} {
+		if _, err := client.GetSecret(ctx, path); err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+	}
+	if _, err := client.GetSecret(ctx, "app/missing"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+
+	diags := client.interactionForecast()
+	if len(diags) != 1 || diags[0].Summary() != "Expect up to 2 hardware token interaction(s)" {
+		t.Fatalf("expected a forecast of 2 interactions, got %v", diags)
+	}
+	if !strings.Contains(diags[0].Detail(), "prefetch_paths") {
+		t.Errorf("expected a hint at prefetch_paths, got %q", diags[0].Detail())
+	}
+	if diags := client.interactionForecast(); len(diags) != 0 {
+		t.Errorf("expected the forecast to be shown once, got %v", diags)
+	}
+}
+
+func TestInteractionForecast_WithoutHardwareToken(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(forecastTestEntries))
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diags := client.interactionForecast(); len(diags) != 0 {
+		t.Errorf("expected no forecast, got %v", diags)
+	}
+
+	var none *GopassClient
+	if diags := none.interactionForecast(); len(diags) != 0 {
+		t.Errorf("expected no forecast on a nil client, got %v", diags)
+	}
+}
+
+func TestSecretResource_ModifyPlan_Forecast(t *testing.T) {
+	ctx := context.Background()
+	r := &SecretResource{}
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx)
+
+	object := func(revisions int) tftypes.Value {
+		return tftypes.NewValue(objectType, map[string]tftypes.Value{
+			"id":               tftypes.NewValue(tftypes.String, "app/new"),
+			"path":             tftypes.NewValue(tftypes.String, "app/new"),
+			"value_wo":         tftypes.NewValue(tftypes.String, nil),
+			"value_wo_version": tftypes.NewValue(tftypes.Number, 1),
+			"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
+			"revision_count":   tftypes.NewValue(tftypes.Number, revisions),
+			"policy":           tftypes.NewValue(tftypes.String, nil),
+			"password":         tftypes.NewValue(tftypes.String, nil),
+			"fields":           tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
+		})
+	}
+	modifyPlan := func(client *GopassClient, state, plan tftypes.Value) *resource.ModifyPlanResponse {
+		r.client = client
+		resp := &resource.ModifyPlanResponse{Plan: tfsdk.Plan{Schema: schemaResp.Schema, Raw: plan}}
+		r.ModifyPlan(ctx, resource.ModifyPlanRequest{
+			State: tfsdk.State{Schema: schemaResp.Schema, Raw: state},
+			Plan:  tfsdk.Plan{Schema: schemaResp.Schema, Raw: plan},
+		}, resp)
+		return resp
+	}
+	none := tftypes.NewValue(objectType, nil)
+
+	tests := map[string]struct {
+		state, plan tftypes.Value
+		want        int
+	}{
+		"create":  {none, object(1), 1},
+		"update":  {object(1), object(2), 1},
+		"no-op":   {object(1), object(1), 0},
+		"destroy": {object(1), none, 0},
+	}
+	for name, tt := range tests {
+		client := NewGopassClientWithStore(NewMemoryStore(forecastTestEntries))
+		client.decryptSlots = make(chan struct{}, 1)
+		if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp := modifyPlan(client, tt.state, tt.plan); len(resp.Diagnostics) != tt.want {
+			t.Errorf("%s: expected %d warning(s), got %v", name, tt.want, resp.Diagnostics)
+		}
+	}
+
+	// Without a configured provider there is nothing to forecast
+	if resp := modifyPlan(nil, none, object(1)); len(resp.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %v", resp.Diagnostics)
+	}
+}
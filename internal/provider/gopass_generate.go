@@ -0,0 +1,126 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Character classes of generated passwords. defaultSpecialChars are the
+// symbols "gopass generate --symbols" uses.
+const (
+	upperChars          = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lowerChars          = "abcdefghijklmnopqrstuvwxyz"
+	numericChars        = "0123456789"
+	defaultSpecialChars = "!@#$%&*()-_=+[]{}<>:?"
+)
+
+// defaultPasswordLength is the length of generated passwords unless length
+// says otherwise, as with "gopass generate".
+const defaultPasswordLength = 24
+
+// passwordClass is a character class a generated password draws from, and
+// how many of its characters it must contain at least.
+type passwordClass struct {
+	name  string
+	chars string
+	min   int
+}
+
+// passwordRules describe a password to generate.
+type passwordRules struct {
+	length  int
+	classes []passwordClass // enabled classes only
+}
+
+// validate reports why no password can satisfy the rules, if so.
+func (r passwordRules) validate() error {
+	if r.length < 1 {
+		return fmt.Errorf("length must be at least 1, got %d", r.length)
+	}
+	if len(r.classes) == 0 {
+		return errors.New("at least one character class must be enabled")
+	}
+	required := 0
+	for _, class := range r.classes {
+		if class.chars == "" {
+			return fmt.Errorf("the %s character class has no characters", class.name)
+		}
+		required += class.min
+	}
+	if required > r.length {
+		return fmt.Errorf("the minimum counts add up to %d characters, more than the length of %d", required, r.length)
+	}
+	return nil
+}
+
+// generatePassword returns a random password following rules, drawn from
+// crypto/rand: the minimum number of characters of each class, filled up
+// with characters of all enabled classes and shuffled.
+func generatePassword(rules passwordRules) (string, error) {
+	if err := rules.validate(); err != nil {
+		return "", err
+	}
+
+	var all []rune
+	password := make([]rune, 0, rules.length)
+	for _, class := range rules.classes {
+		chars := []rune(class.chars)
+		all = append(all, chars...)
+		for range class.min {
+			c, err := randomRune(chars)
+			if err != nil {
+				return "", err
+			}
+			password = append(password, c)
+		}
+	}
+	for len(password) < rules.length {
+		c, err := randomRune(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Fisher-Yates, so the required characters are not all at the front
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+// randomRune returns a uniformly chosen element of chars.
+func randomRune(chars []rune) (rune, error) {
+	i, err := randomIndex(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+// randomIndex returns a uniformly chosen integer in [0, n).
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate password: %w", err)
+	}
+	return int(i.Int64()), nil
+}
+
+// passwordSHA256 returns the hex SHA-256 of password, the only trace of a
+// generated password kept in state.
+func passwordSHA256(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
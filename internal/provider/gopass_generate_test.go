@@ -0,0 +1,133 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	rules := passwordRules{
+		length: 40,
+		classes: []passwordClass{
+			{name: "upper", chars: upperChars, min: 5},
+			{name: "numeric", chars: numericChars, min: 5},
+			{name: "special", chars: "#%", min: 5},
+		},
+	}
+
+	seen := make(map[string]bool)
+	for range 50 {
+		password, err := generatePassword(rules)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(password) != 40 {
+			t.Fatalf("expected 40 characters, got %d", len(password))
+		}
+		if strings.Trim(password, upperChars+numericChars+"#%") != "" {
+			t.Fatalf("unexpected characters in %q", password)
+		}
+		for _, class := range rules.classes {
+			count := 0
+			for _, c := range password {
+				if strings.ContainsRune(class.chars, c) {
+					count++
+				}
+			}
+			if count < class.min {
+				t.Fatalf("expected at least %d %s characters in %q, got %d", class.min, class.name, password, count)
+			}
+		}
+		seen[password] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("expected 50 distinct passwords, got %d", len(seen))
+	}
+}
+
+func TestGeneratePassword_MinimumsFillLength(t *testing.T) {
+	password, err := generatePassword(passwordRules{
+		length: 4,
+		classes: []passwordClass{
+			{name: "lower", chars: lowerChars, min: 2},
+			{name: "numeric", chars: numericChars, min: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.ContainsAny(password, lowerChars) || !strings.ContainsAny(password, numericChars) {
+		t.Errorf("expected letters and digits in %q", password)
+	}
+}
+
+func TestGeneratePassword_MultiByteSymbols(t *testing.T) {
+	password, err := generatePassword(passwordRules{
+		length:  12,
+		classes: []passwordClass{{name: "special", chars: "§€", min: 0}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := utf8.RuneCountInString(password); n != 12 {
+		t.Errorf("expected 12 characters, got %d in %q", n, password)
+	}
+	if strings.Trim(password, "§€") != "" {
+		t.Errorf("unexpected characters in %q", password)
+	}
+}
+
+func TestPasswordRules_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules passwordRules
+		want  string
+	}{
+		{
+			name:  "zero length",
+			rules: passwordRules{length: 0, classes: []passwordClass{{name: "lower", chars: lowerChars}}},
+			want:  "length must be at least 1",
+		},
+		{
+			name:  "no classes",
+			rules: passwordRules{length: 8},
+			want:  "at least one character class",
+		},
+		{
+			name:  "empty class",
+			rules: passwordRules{length: 8, classes: []passwordClass{{name: "special"}}},
+			want:  "the special character class has no characters",
+		},
+		{
+			name: "minimums exceed length",
+			rules: passwordRules{length: 3, classes: []passwordClass{
+				{name: "upper", chars: upperChars, min: 2},
+				{name: "lower", chars: lowerChars, min: 2},
+			}},
+			want: "add up to 4 characters, more than the length of 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+			if _, err := generatePassword(tt.rules); err == nil {
+				t.Error("expected generatePassword to refuse the rules")
+			}
+		})
+	}
+}
+
+func TestPasswordSHA256(t *testing.T) {
+	// sha256("abc") from FIPS 180-2
+	want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := passwordSHA256("abc"); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// isolatedGnupgHome is a temporary GnuPG home created from a keyring
+// directory for the lifetime of the open stores, so that gpg-agent state,
+// including cached passphrases, never outlives the run. It is created when the
+// first store is opened and removed again when the client closes its stores.
+type isolatedGnupgHome struct {
+	keyring string // directory the keyring files are copied from
+
+	dir      string // temporary GNUPGHOME, empty while not set up
+	previous string
+	wasSet   bool
+}
+
+// setup creates the temporary home and points GNUPGHOME at it, unless that
+// was already done. Callers hold storeDirMu, as the environment is shared by
+// the whole process. It is safe on a nil home.
+func (h *isolatedGnupgHome) setup(ctx context.Context) error {
+	if h == nil || h.dir != "" {
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "terraform-provider-gopass-gnupg-")
+	if err != nil {
+		return fmt.Errorf("failed to create isolated GnuPG home: %w", err)
+	}
+	if err := copyKeyring(h.keyring, dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return fmt.Errorf("failed to copy keyring from %s into isolated GnuPG home: %w", h.keyring, err)
+	}
+
+	h.previous, h.wasSet = os.LookupEnv("GNUPGHOME")
+	os.Setenv("GNUPGHOME", dir)
+	h.dir = dir
+
+	tflog.Debug(ctx, "Created isolated GnuPG home", map[string]interface{}{
+		"gnupg_home": dir,
+		"keyring":    h.keyring,
+	})
+	return nil
+}
+
+// teardown removes the temporary home and restores GNUPGHOME. A gpg-agent
+// started for the home exits on its own once its socket is gone, taking its
+// passphrase cache with it. It is safe on a nil home and without setup.
+func (h *isolatedGnupgHome) teardown(ctx context.Context) {
+	if h == nil || h.dir == "" {
+		return
+	}
+
+	storeDirMu.Lock()
+	defer storeDirMu.Unlock()
+
+	if h.wasSet {
+		os.Setenv("GNUPGHOME", h.previous)
+	} else {
+		os.Unsetenv("GNUPGHOME")
+	}
+	if err := os.RemoveAll(h.dir); err != nil {
+		tflog.Warn(ctx, "Failed to remove isolated GnuPG home", map[string]interface{}{
+			"gnupg_home": h.dir,
+			"error":      err.Error(),
+		})
+	} else {
+		tflog.Debug(ctx, "Removed isolated GnuPG home", map[string]interface{}{
+			"gnupg_home": h.dir,
+		})
+	}
+	h.dir = ""
+}
+
+// copyKeyring copies the regular files below src into dst, which must exist.
+// Sockets and lock files of agents running on src are left behind, so the
+// copy starts without any agent state.
+func copyKeyring(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+
+		switch {
+		case rel == ".":
+			return nil
+		case entry.IsDir():
+			return os.Mkdir(filepath.Join(dst, rel), 0o700)
+		case !entry.Type().IsRegular(), strings.HasPrefix(name, "S."),
+			strings.HasPrefix(name, ".#lk"), strings.HasSuffix(name, ".lock"):
+			return nil
+		}
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+// copyFile copies src to a new file dst readable only by the owner.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src) //nolint:gosec // path is below the configured keyring directory
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec // path is below the temporary home
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,186 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newTestKeyring returns a keyring directory with a running agent's leftovers.
+func newTestKeyring(t *testing.T) string {
+	t.Helper()
+	keyring := t.TempDir()
+	writeKeyStub(t, keyring, "ABCD.key", "Key: (private-key (rsa))")
+	for name, content := range map[string]string{
+		"pubring.kbx":      "public keys",
+		"trustdb.gpg":      "trust",
+		"S.gpg-agent":      "stale socket",
+		"pubring.kbx.lock": "",
+		".#lk0x1234":       "",
+	} {
+		if err := os.WriteFile(filepath.Join(keyring, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keyring
+}
+
+func TestCopyKeyring(t *testing.T) {
+	keyring := newTestKeyring(t)
+	// A real socket, as a running gpg-agent leaves behind
+	if listener, err := net.Listen("unix", filepath.Join(keyring, "S.gpg-agent.ssh")); err == nil {
+		defer listener.Close()
+	}
+
+	dst := t.TempDir()
+	if err := copyKeyring(keyring, dst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"pubring.kbx", "trustdb.gpg", filepath.Join("private-keys-v1.d", "ABCD.key")} {
+		info, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Errorf("expected %s to be copied: %v", name, err)
+			continue
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("expected %s to be private, got %v", name, info.Mode().Perm())
+		}
+	}
+	for _, name := range []string{"S.gpg-agent", "S.gpg-agent.ssh", "pubring.kbx.lock", ".#lk0x1234"} {
+		if _, err := os.Lstat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+			t.Errorf("expected agent state %s not to be copied", name)
+		}
+	}
+}
+
+func TestIsolatedGnupgHome_SetupAndTeardown(t *testing.T) {
+	t.Setenv("GNUPGHOME", "/original/gnupg")
+	ctx := context.Background()
+	home := &isolatedGnupgHome{keyring: newTestKeyring(t)}
+
+	if err := home.setup(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dir := home.dir
+	if os.Getenv("GNUPGHOME") != dir {
+		t.Errorf("expected GNUPGHOME to point to %s, got %s", dir, os.Getenv("GNUPGHOME"))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pubring.kbx")); err != nil {
+		t.Errorf("expected the keyring in the isolated home: %v", err)
+	}
+
+	// Opening further stores reuses the home
+	if err := home.setup(ctx); err != nil || home.dir != dir {
+		t.Errorf("expected setup to be idempotent, got %s (%v)", home.dir, err)
+	}
+
+	home.teardown(ctx)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("expected the isolated home to be removed")
+	}
+	if os.Getenv("GNUPGHOME") != "/original/gnupg" {
+		t.Errorf("expected GNUPGHOME to be restored, got %s", os.Getenv("GNUPGHOME"))
+	}
+	home.teardown(ctx)
+
+	var none *isolatedGnupgHome
+	if err := none.setup(ctx); err != nil {
+		t.Errorf("unexpected error on nil home: %v", err)
+	}
+	none.teardown(ctx)
+}
+
+func TestIsolatedGnupgHome_MissingKeyring(t *testing.T) {
+	home := &isolatedGnupgHome{keyring: filepath.Join(t.TempDir(), "missing")}
+
+	if err := home.setup(context.Background()); err == nil {
+		t.Fatal("expected an error for a missing keyring")
+	}
+	if home.dir != "" {
+		t.Error("expected no home to be left behind")
+	}
+}
+
+func TestGopassClient_IsolatedGnupgHome_LivesWithStore(t *testing.T) {
+	t.Setenv("GNUPGHOME", "/original/gnupg")
+	ctx := context.Background()
+
+	client := NewGopassClient("")
+	client.gnupg = &isolatedGnupgHome{keyring: newTestKeyring(t)}
+	var openedWith string
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		openedWith = os.Getenv("GNUPGHOME")
+		store := newMockStore()
+		store.secrets["app/db"] = newMockSecret("value")
+		return store, nil
+	}
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if openedWith == "/original/gnupg" || openedWith != client.gnupg.dir {
+		t.Errorf("expected the store to be opened with the isolated home, got %s", openedWith)
+	}
+
+	client.Close(ctx)
+	if _, err := os.Stat(openedWith); !os.IsNotExist(err) {
+		t.Error("expected the isolated home to be removed on close")
+	}
+	if os.Getenv("GNUPGHOME") != "/original/gnupg" {
+		t.Errorf("expected GNUPGHOME to be restored, got %s", os.Getenv("GNUPGHOME"))
+	}
+}
+
+func TestConfigureHardwareToken_DetectsIsolatedKeyring(t *testing.T) {
+	t.Setenv("GNUPGHOME", t.TempDir())
+	keyring := t.TempDir()
+	writeKeyStub(t, keyring, "EF01.key", "Key: (shadowed-private-key (rsa))")
+
+	client := NewGopassClient("")
+	client.gnupg = &isolatedGnupgHome{keyring: keyring}
+	client.configureHardwareToken(context.Background(), nil)
+
+	if client.decryptSlots == nil {
+		t.Error("expected the smartcard key in the isolated keyring to be detected")
+	}
+}
+
+func TestProviderConfigure_IsolatedGnupgHome(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+	keyring := t.TempDir()
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"isolated_gnupg_home": tftypes.NewValue(tftypes.String, keyring),
+		}),
+	}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.EphemeralResourceData.(*GopassClient); client.gnupg == nil || client.gnupg.keyring != keyring {
+		t.Errorf("expected an isolated home from %s", keyring)
+	}
+
+	for _, invalid := range []string{filepath.Join(keyring, "missing"), writeTemp(t, "not a directory")} {
+		resp = &provider.ConfigureResponse{}
+		p.Configure(ctx, provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{
+				"isolated_gnupg_home": tftypes.NewValue(tftypes.String, invalid),
+			}),
+		}, resp)
+		if !resp.Diagnostics.HasError() {
+			t.Errorf("%s: expected an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// gpgProblem is a common GPG failure, recognized by the messages gpg and
+// gpg-agent print for it, with a hint on how to fix it.
+type gpgProblem struct {
+	summary  string
+	patterns []string // lower-case substrings of a gpg, gpg-agent or age diagnostic
+	hint     string
+	// unavailable marks problems where the key cannot be used on this
+	// machine at all, e.g. in CI without the hardware token
+	unavailable bool
+	// kind is the error kind failed reads are tagged with, if any
+	kind error
+}
+
+// noPinentrySummary is the diagnostic summary for prompts gpg-agent could
+// not show.
+const noPinentrySummary = "No pinentry program available"
+
+// gpgProblems lists the GPG failures users run into most, most specific first.
+var gpgProblems = []gpgProblem{
+	{
+		summary: "gpg-agent is not running",
+		patterns: []string{
+			"no gpg-agent running",
+			"can't connect to the agent",
+			"gpg-agent is not available",
+			"no agent running",
+		},
+		hint: "Start the agent with \"gpgconf --launch gpg-agent\" and make sure GNUPGHOME points to the " +
+			"same directory for the agent and for Terraform.",
+		unavailable: true,
+		kind:        ErrAgentUnavailable,
+	},
+	{
+		summary: "Hardware token not available",
+		patterns: []string{
+			"card not present",
+			"no smartcard daemon",
+			"card removed",
+		},
+		hint: "The key is on a smartcard or hardware token that gpg cannot reach. Connect the token and " +
+			"check it with \"gpg --card-status\".",
+		unavailable: true,
+	},
+	{
+		summary: tokenTimeoutSummary,
+		patterns: []string{
+			"decryption failed: timeout",
+		},
+		hint: tokenTimeoutHint(0),
+	},
+	{
+		summary: "PIN or passphrase entry was cancelled",
+		patterns: []string{
+			"operation cancelled",
+			"operation canceled",
+			"cancelled by user",
+			"canceled by user",
+		},
+		hint: "The passphrase or PIN prompt was dismissed or timed out. Run the command again and answer " +
+			"the prompt, or unlock the key beforehand, e.g. with the provider's warm_up_path.",
+	},
+	{
+		summary: noPinentrySummary,
+		patterns: []string{
+			"no pinentry",
+			"inappropriate ioctl for device",
+		},
+		hint: "gpg-agent could not ask for the passphrase. Configure a graphical pinentry-program in " +
+			"gpg-agent.conf, or export GPG_TTY=$(tty) before running Terraform in a terminal.",
+		unavailable: true,
+	},
+	{
+		summary: "No secret key to decrypt the secret",
+		patterns: []string{
+			"no secret key",
+			"secret key not available",
+		},
+		hint: "The secret is not encrypted for any key in your keyring. Check \"gpg --list-secret-keys\"; if " +
+			"the key is on a hardware token, make sure it is connected. Otherwise ask a store member to add " +
+			"your key as a recipient and re-encrypt (gopass recipients add).",
+		unavailable: true,
+		kind:        ErrNoSecretKey,
+	},
+	{
+		summary: "No age identity to decrypt the secret",
+		patterns: []string{
+			"no identity matched any of the recipients",
+		},
+		hint: "The secret is not encrypted for any of your age identities. Check \"gopass age identities list\", " +
+			"or the file in age_identities_file. Otherwise ask a store member to add your recipient and " +
+			"re-encrypt (gopass recipients add).",
+		unavailable: true,
+		kind:        ErrNoSecretKey,
+	},
+	{
+		summary: "GPG key has expired",
+		patterns: []string{
+			"key expired",
+			"key has expired",
+			"expired key",
+			"expired at",
+			"unusable public key",
+		},
+		hint: "Extend the key's expiry date with \"gpg --quick-set-expire <fingerprint> 1y\" and distribute " +
+			"the updated public key to the other store members.",
+	},
+}
+
+// commandError is a failed run of gpg or gopass, keeping what the command
+// printed to stderr apart from the error it is reported in.
+type commandError struct {
+	name   string
+	err    error
+	stderr string
+}
+
+func (e *commandError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.name, e.err, e.stderr)
+}
+
+func (e *commandError) Unwrap() error { return e.err }
+
+// gpgDiagnostic matches a line gpg, one of its daemons or age printed: the
+// program name, for daemons with their process id, then the message.
+var gpgDiagnostic = regexp.MustCompile(`^(?:gpg|gpg-agent|scdaemon|dirmngr|age)(?:\[\d+\])?: (.*)$`)
+
+// gpgDiagnostics returns the messages gpg, its daemons and age printed for
+// err. They come from the stderr of a failed command, or else from the
+// innermost errors, which the store returns before any caller adds the secret
+// path, so that a path never passes for one.
+func gpgDiagnostics(err error) []string {
+	var lines []string
+	switch e := err.(type) {
+	case *commandError:
+		lines = strings.Split(e.stderr, "\n")
+	case *age.NoIdentityMatchError:
+		return []string{e.Error()}
+	case interface{ Unwrap() []error }:
+		var diagnostics []string
+		for _, inner := range e.Unwrap() {
+			diagnostics = append(diagnostics, gpgDiagnostics(inner)...)
+		}
+		return diagnostics
+	default:
+		if inner := errors.Unwrap(err); inner != nil {
+			return gpgDiagnostics(inner)
+		}
+		lines = strings.Split(err.Error(), "\n")
+	}
+
+	var diagnostics []string
+	for _, line := range lines {
+		if m := gpgDiagnostic.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			diagnostics = append(diagnostics, m[1])
+		}
+	}
+	return diagnostics
+}
+
+// classifyGPGError returns the known GPG problem behind err, if any.
+func classifyGPGError(err error) (gpgProblem, bool) {
+	if err == nil {
+		return gpgProblem{}, false
+	}
+
+	diagnostics := gpgDiagnostics(err)
+	for _, problem := range gpgProblems {
+		for _, diagnostic := range diagnostics {
+			diagnostic = strings.ToLower(diagnostic)
+			for _, pattern := range problem.patterns {
+				if strings.Contains(diagnostic, pattern) {
+					return problem, true
+				}
+			}
+		}
+	}
+	return gpgProblem{}, false
+}
+
+// errorDetail appends a remediation hint for known GPG problems and hardware
+// token timeouts to detail.
+func errorDetail(detail string, err error) string {
+	var tokenErr *tokenTimeoutError
+	if errors.As(err, &tokenErr) {
+		return detail + "\n\n" + tokenTimeoutHint(tokenErr.timeout)
+	}
+	var unlockErr *nonInteractiveError
+	if errors.As(err, &unlockErr) {
+		return detail + "\n\n" + nonInteractiveHint
+	}
+	if problem, ok := classifyGPGError(err); ok {
+		return detail + "\n\n" + problem.hint
+	}
+	return detail
+}
@@ -0,0 +1,120 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestClassifyGPGError(t *testing.T) {
+	testCases := []struct {
+		msg     string
+		summary string
+	}{
+		{msg: "gpg: can't connect to the agent: IPC connect call failed", summary: "gpg-agent is not running"},
+		{msg: "gpg: selecting card failed: Card not present", summary: "Hardware token not available"},
+		{msg: "gpg: decryption failed: No secret key", summary: "No secret key to decrypt the secret"},
+		{msg: "age: no identity matched any of the recipients", summary: "No age identity to decrypt the secret"},
+		{msg: "gpg: public key decryption failed: Operation cancelled", summary: "PIN or passphrase entry was cancelled"},
+		{msg: "gpg: public key decryption failed: Inappropriate ioctl for device", summary: "No pinentry program available"},
+		{msg: "gpg: 0x1234: skipped: Unusable public key", summary: "GPG key has expired"},
+		{msg: "gpg: Note: secret key 0x1234 expired at Mon Jan  1 00:00:00 2024 UTC", summary: "GPG key has expired"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.summary, func(t *testing.T) {
+			err := fmt.Errorf("reading secret %q: %w", "app/db", errors.New(tc.msg))
+			problem, ok := classifyGPGError(err)
+			if !ok {
+				t.Fatalf("expected %q to be classified", tc.msg)
+			}
+			if problem.summary != tc.summary {
+				t.Errorf("classifyGPGError(%q) = %q, want %q", tc.msg, problem.summary, tc.summary)
+			}
+			if problem.hint == "" {
+				t.Error("expected a remediation hint")
+			}
+		})
+	}
+}
+
+func TestClassifyGPGError_Unknown(t *testing.T) {
+	for _, err := range []error{nil, errors.New("disk full")} {
+		if _, ok := classifyGPGError(err); ok {
+			t.Errorf("expected %v not to be classified", err)
+		}
+	}
+}
+
+func TestClassifyGPGError_OnlyGPGDiagnostics(t *testing.T) {
+	// The secret path and the messages around gpg's are never matched
+	for _, err := range []error{
+		fmt.Errorf("reading secret %q: %w", "infra/no secret key", errors.New("exit status 2")),
+		fmt.Errorf("reading secret %q: %w", "gpg: card not present", errors.New("exit status 2")),
+		errors.New("custom pinentry wrapper failed"),
+		&commandError{name: "gopass", err: errors.New("exit status 1"), stderr: "Error: pinentry-mac not found"},
+	} {
+		if problem, ok := classifyGPGError(err); ok {
+			t.Errorf("expected %q not to be classified, got %q", err, problem.summary)
+		}
+	}
+}
+
+func TestClassifyGPGError_CommandStderr(t *testing.T) {
+	err := fmt.Errorf("reading secret %q: %w", "app/db", &commandError{
+		name:   "gpg",
+		err:    errors.New("exit status 2"),
+		stderr: "gpg: encrypted with rsa4096 key, ID 0x1234\ngpg: public key decryption failed: No pinentry\ngpg: decryption failed: No secret key",
+	})
+	problem, ok := classifyGPGError(err)
+	if !ok || problem.summary != noPinentrySummary {
+		t.Errorf("expected %q, got %q (%v)", noPinentrySummary, problem.summary, ok)
+	}
+}
+
+func TestClassifyGPGError_AgeLibrary(t *testing.T) {
+	err := fmt.Errorf("%w: %s: %w", ErrDecryptionFailed, "app/db", &age.NoIdentityMatchError{})
+	if problem, ok := classifyGPGError(err); !ok || problem.kind != ErrNoSecretKey {
+		t.Errorf("expected no age identity, got %q (%v)", problem.summary, ok)
+	}
+}
+
+func TestErrorDetail(t *testing.T) {
+	err := classify(ErrDecryptionFailed, errors.New("gpg: decryption failed: No secret key"))
+
+	detail := errorDetail("Could not read secret", err)
+	if !strings.HasPrefix(detail, "Could not read secret\n\n") || !strings.Contains(detail, "gpg --list-secret-keys") {
+		t.Errorf("expected hint appended, got %q", detail)
+	}
+	if got := errorSummary(err, "Failed to read secret"); got != "No secret key to decrypt the secret" {
+		t.Errorf("expected GPG summary, got %q", got)
+	}
+
+	if detail := errorDetail("Could not read secret", errors.New("disk full")); detail != "Could not read secret" {
+		t.Errorf("expected detail unchanged, got %q", detail)
+	}
+}
+
+func TestGopassClient_GetSecret_GPGHint(t *testing.T) {
+	client := NewGopassClient("")
+	store := newMockStore()
+	store.shouldFail = true
+	store.failMsg = "gpg: can't connect to the agent: IPC connect call failed"
+	client.store = store
+	client.retry.MaxAttempts = 1
+
+	_, err := client.GetSecret(context.Background(), "app/db")
+	if got := errorSummary(err, "Failed to read secret"); got != "gpg-agent is not running" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if !strings.Contains(errorDetail("", err), "gpgconf --launch gpg-agent") {
+		t.Errorf("expected agent hint for %v", err)
+	}
+}
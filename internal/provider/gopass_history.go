@@ -0,0 +1,169 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// secretEntryExtensions are the file extensions of encrypted entries, by backend.
+var secretEntryExtensions = []string{".gpg", ".age"}
+
+// commit is one git commit that changed a secret.
+type commit struct {
+	hash   string
+	time   time.Time
+	author string // "Name <email>"
+}
+
+// gitLog returns the commits that changed file in the git repository at dir,
+// newest first; injectable for testing.
+var gitLog = func(ctx context.Context, dir, file string) ([]commit, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "log", "--format=%H %cI %an <%ae>", "--", file)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var commits []commit
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		hash, rest, ok := strings.Cut(line, " ")
+		date, author, _ := strings.Cut(rest, " ")
+		t, err := time.Parse(time.RFC3339, date)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("git log: unexpected line %q", line)
+		}
+		commits = append(commits, commit{hash: hash, time: t, author: author})
+	}
+	return commits, nil
+}
+
+// secretHistory returns the commits that changed the secret at path, newest
+// first. It fails for stores the provider cannot locate on disk, entries
+// without a file and stores that are not git repositories.
+func (c *GopassClient) secretHistory(ctx context.Context, path string) ([]commit, error) {
+	dir, rel, err := c.storeDirFor(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ext := range secretEntryExtensions {
+		file := filepath.FromSlash(rel) + ext
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			continue
+		}
+		commits, err := gitLog(ctx, dir, file)
+		if err != nil {
+			return nil, err
+		}
+		if len(commits) == 0 {
+			return nil, fmt.Errorf("%s is not committed", file)
+		}
+		return commits, nil
+	}
+	return nil, fmt.Errorf("no %s file for %q in %s", strings.Join(secretEntryExtensions, " or "), path, dir)
+}
+
+// secretVersion returns the version of the existing secret at path, counted
+// from 1 for its first revision, and when that version was created. It prefers
+// the git history of the entry's file, and falls back to the store's revisions
+// with an unknown (zero) time. Unlike GetRevisionCount, it never decrypts the
+// secret; stores without revisions count it as one version.
+func (c *GopassClient) secretVersion(ctx context.Context, path string) (int64, time.Time, error) {
+	commits, err := c.secretHistory(ctx, path)
+	if err == nil {
+		return int64(len(commits)), commits[0].time, nil
+	}
+	if ctx.Err() != nil {
+		return 0, time.Time{}, ctx.Err()
+	}
+	tflog.Debug(ctx, "No git history for secret, counting store revisions", map[string]interface{}{
+		"path":  c.logPath(path),
+		"error": c.logError(err),
+	})
+
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer release()
+
+	revisions, err := c.storeRevisions(ctx, store, path)
+	if err != nil || len(revisions) == 0 {
+		return 1, time.Time{}, nil
+	}
+	return int64(len(revisions)), time.Time{}, nil
+}
+
+// SecretRevision is one revision of a secret.
+type SecretRevision struct {
+	ID     string    // revision id, as the revision attribute of gopass_secret takes it
+	Time   time.Time // commit time, zero if it is unknown
+	Author string    // commit author as "Name <email>", empty if it is unknown
+}
+
+// SecretRevisions returns the revisions of the secret at path, newest first,
+// and whether it exists. Like secretVersion it prefers the git history of the
+// entry's file and falls back to the store's revisions without times and
+// authors. Secrets in stores without revisions have a single "latest" one.
+// Only existence may require decrypting the secret.
+func (c *GopassClient) SecretRevisions(ctx context.Context, path string) ([]SecretRevision, bool, error) {
+	if err := c.enforcePolicy(ctx, path, accessRead); err != nil {
+		return nil, false, err
+	}
+
+	commits, err := c.secretHistory(ctx, path)
+	if err == nil {
+		revisions := make([]SecretRevision, len(commits))
+		for i, commit := range commits {
+			revisions[i] = SecretRevision{ID: commit.hash, Time: commit.time, Author: commit.author}
+		}
+		return revisions, true, nil
+	}
+	if ctx.Err() != nil {
+		return nil, false, ctx.Err()
+	}
+	tflog.Debug(ctx, "No git history for secret, listing store revisions", map[string]interface{}{
+		"path":  c.logPath(path),
+		"error": c.logError(err),
+	})
+
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return nil, false, err
+	}
+	ids, err := c.storeRevisions(ctx, store, path)
+	release()
+	if errors.Is(err, ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil || len(ids) == 0 {
+		exists, err := c.SecretExists(ctx, path)
+		if err != nil || !exists {
+			return nil, false, err
+		}
+		return []SecretRevision{{ID: "latest"}}, true, nil
+	}
+
+	revisions := make([]SecretRevision, len(ids))
+	for i, id := range ids {
+		revisions[i] = SecretRevision{ID: id}
+	}
+	return revisions, true, nil
+}
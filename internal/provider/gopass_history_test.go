@@ -0,0 +1,266 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newGitStore returns a git repository to be used as a store directory.
+func newGitStore(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, nil, "init", "--quiet")
+	return dir
+}
+
+// commitFile writes content to file in the repository at dir and commits it
+// with date as commit time.
+func commitFile(t *testing.T, dir, file, content string, date time.Time) {
+	t.Helper()
+
+	full := filepath.Join(dir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(full), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, nil, "add", file)
+	runGit(t, dir, []string{
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_AUTHOR_DATE=" + date.Format(time.RFC3339),
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_COMMITTER_DATE=" + date.Format(time.RFC3339),
+	}, "commit", "--quiet", "--no-gpg-sign", "-m", "Update "+file)
+}
+
+// runGit runs git in dir with env added to the environment.
+func runGit(t *testing.T, dir string, env []string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func TestGitLog(t *testing.T) {
+	dir := newGitStore(t)
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := time.Date(2025, 7, 14, 8, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	commitFile(t, dir, "app/db.age", "v1", first)
+	commitFile(t, dir, "app/other.age", "x", first)
+	commitFile(t, dir, "app/db.age", "v2", second)
+
+	commits, err := gitLog(context.Background(), dir, filepath.FromSlash("app/db.age"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %+v", commits)
+	}
+	if !commits[0].time.Equal(second) || !commits[1].time.Equal(first) || len(commits[0].hash) < 40 {
+		t.Errorf("expected the commits newest first, got %+v", commits)
+	}
+	if commits[0].author != "test <test@example.com>" {
+		t.Errorf("unexpected author %q", commits[0].author)
+	}
+
+	if _, err := gitLog(context.Background(), t.TempDir(), "app/db.age"); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
+
+func TestSecretVersion_GitHistory(t *testing.T) {
+	dir := newGitStore(t)
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	commitFile(t, dir, "app/db.gpg", "v1", created.Add(-time.Hour))
+	commitFile(t, dir, "app/db.gpg", "v2", created)
+
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.storePath = dir
+
+	version, at, err := client.secretVersion(context.Background(), "app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version != 2 || !at.Equal(created) {
+		t.Errorf("expected version 2 created at %s, got %d at %s", created, version, at)
+	}
+}
+
+func TestSecretVersion_FallsBackToRevisions(t *testing.T) {
+	store := NewMemoryStore(map[string]string{"app/db": "v1"})
+	store.Set(context.Background(), "app/db", parseSecret([]byte("v2")))
+	ctx := context.Background()
+
+	// No store directory at all
+	client := NewGopassClientWithStore(store)
+	if version, at, err := client.secretVersion(ctx, "app/db"); err != nil || version != 2 || !at.IsZero() {
+		t.Errorf("expected version 2 without a time, got %d at %s (%v)", version, at, err)
+	}
+
+	// A store directory that is not a git repository
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "app"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app", "db.age"), []byte("encrypted"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client.storePath = dir
+	if version, at, err := client.secretVersion(ctx, "app/db"); err != nil || version != 2 || !at.IsZero() {
+		t.Errorf("expected version 2 without a time, got %d at %s (%v)", version, at, err)
+	}
+
+	// Stores without revisions count one version
+	client = NewGopassClientWithStore(newMockStore())
+	if version, _, err := client.secretVersion(ctx, "app/db"); err != nil || version != 1 {
+		t.Errorf("expected version 1, got %d (%v)", version, err)
+	}
+}
+
+func TestGopassClient_SecretRevisions_GitHistory(t *testing.T) {
+	dir := newGitStore(t)
+	first := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	commitFile(t, dir, "app/db.gpg", "v1", first)
+	commitFile(t, dir, "app/db.gpg", "v2", first.Add(time.Hour))
+
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	client.storePath = dir
+
+	revisions, exists, err := client.SecretRevisions(context.Background(), "app/db")
+	if err != nil || !exists || len(revisions) != 2 {
+		t.Fatalf("expected two revisions, got %+v, %v (%v)", revisions, exists, err)
+	}
+	if !revisions[0].Time.Equal(first.Add(time.Hour)) || revisions[0].Author != "test <test@example.com>" ||
+		len(revisions[0].ID) < 40 {
+		t.Errorf("unexpected newest revision %+v", revisions[0])
+	}
+}
+
+func TestGopassClient_SecretRevisions_StoreRevisions(t *testing.T) {
+	store := NewMemoryStore(map[string]string{"app/db": "v1"})
+	store.Set(context.Background(), "app/db", parseSecret([]byte("v2")))
+	client := NewGopassClientWithStore(store)
+	ctx := context.Background()
+
+	revisions, exists, err := client.SecretRevisions(ctx, "app/db")
+	if err != nil || !exists {
+		t.Fatalf("expected the secret to exist, got %v (%v)", exists, err)
+	}
+	want := []SecretRevision{{ID: "2"}, {ID: "1"}}
+	if fmt.Sprint(revisions) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, revisions)
+	}
+
+	if revisions, exists, err := client.SecretRevisions(ctx, "app/missing"); err != nil || exists || revisions != nil {
+		t.Errorf("expected a missing secret, got %v, %v (%v)", revisions, exists, err)
+	}
+}
+
+func TestGopassClient_SecretRevisions_NoHistory(t *testing.T) {
+	store := newMockStore()
+	store.secrets["app/db"] = newMockSecret("s3cret")
+	client := NewGopassClientWithStore(store)
+	ctx := context.Background()
+
+	revisions, exists, err := client.SecretRevisions(ctx, "app/db")
+	if err != nil || !exists || len(revisions) != 1 || revisions[0].ID != "latest" {
+		t.Errorf("expected a single latest revision, got %+v, %v (%v)", revisions, exists, err)
+	}
+	if _, exists, err := client.SecretRevisions(ctx, "app/missing"); err != nil || exists {
+		t.Errorf("expected a missing secret, got %v (%v)", exists, err)
+	}
+}
+
+func TestGopassClient_SecretRevisions_Policy(t *testing.T) {
+	client := newPolicyTestClient()
+
+	if _, _, err := client.SecretRevisions(context.Background(), "app/admin/root"); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("expected a policy violation, got %v", err)
+	}
+}
+
+// readRevisionsDataSource reads a gopass_revisions data source for path.
+func readRevisionsDataSource(t *testing.T, client *GopassClient, path string) (*datasource.ReadResponse, RevisionsModel) {
+	t.Helper()
+
+	d := &RevisionsDataSource{client: client}
+	ctx := context.Background()
+	schemaResp := &datasource.SchemaResponse{}
+	d.Schema(ctx, datasource.SchemaRequest{}, schemaResp)
+
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	values := map[string]tftypes.Value{}
+	for name, attrType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attrType, nil)
+	}
+	values["path"] = tftypes.NewValue(tftypes.String, path)
+
+	req := datasource.ReadRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)},
+	}
+	resp := &datasource.ReadResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)},
+	}
+
+	d.Read(ctx, req, resp)
+
+	var data RevisionsModel
+	if !resp.Diagnostics.HasError() {
+		resp.State.Get(ctx, &data)
+	}
+	return resp, data
+}
+
+func TestRevisionsDataSource_Read(t *testing.T) {
+	dir := newGitStore(t)
+	written := time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 60*60))
+	commitFile(t, dir, "app/db.gpg", "v1", written)
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret", "app/api": "t0ken"}))
+	client.storePath = dir
+
+	resp, data := readRevisionsDataSource(t, client, "app/db")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	var revisions []RevisionModel
+	data.Revisions.ElementsAs(context.Background(), &revisions, false)
+	if !data.Exists.ValueBool() || len(revisions) != 1 {
+		t.Fatalf("expected one revision, got %+v", data)
+	}
+	if revisions[0].Time.ValueString() != "2025-01-02T02:04:05Z" || revisions[0].Author.ValueString() != "test <test@example.com>" {
+		t.Errorf("unexpected revision %+v", revisions[0])
+	}
+
+	// Not committed: store revisions without time and author
+	_, data = readRevisionsDataSource(t, client, "app/api")
+	data.Revisions.ElementsAs(context.Background(), &revisions, false)
+	if len(revisions) != 1 || revisions[0].ID.ValueString() != "1" || !revisions[0].Time.IsNull() || !revisions[0].Author.IsNull() {
+		t.Errorf("unexpected revisions %+v", revisions)
+	}
+
+	resp, data = readRevisionsDataSource(t, client, "app/missing")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if data.Exists.ValueBool() || len(data.Revisions.Elements()) != 0 {
+		t.Errorf("expected a missing secret, got %+v", data)
+	}
+}
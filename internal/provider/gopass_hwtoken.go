@@ -0,0 +1,141 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// hardwareTokenReadTimeout is the read deadline used when decryptions go
+// through a smartcard. It leaves room for a PIN prompt and a touch.
+const hardwareTokenReadTimeout = 5 * time.Minute
+
+// shadowedKeyMarker identifies gpg-agent key stubs whose private part lives
+// on a smartcard. Both the legacy S-expression format and the extended key
+// format contain it.
+var shadowedKeyMarker = []byte("shadowed-private-key")
+
+// gnupgHomeDir returns the GnuPG home directory gpg would use.
+func gnupgHomeDir(userHomeDir func() (string, error)) (string, error) {
+	if dir := os.Getenv("GNUPGHOME"); dir != "" {
+		return dir, nil
+	}
+	if hostOS == "windows" {
+		return windowsGnupgHome(userHomeDir)
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".gnupg"), nil
+}
+
+// hasSmartcardKeys reports whether the GnuPG home contains private key stubs
+// that point to a smartcard (YubiKey, Nitrokey, OpenPGP card, ...).
+// It only inspects files on disk and never talks to gpg-agent or scdaemon.
+func hasSmartcardKeys(gnupgHome string) bool {
+	keyFiles, err := filepath.Glob(filepath.Join(gnupgHome, "private-keys-v1.d", "*.key"))
+	if err != nil {
+		return false
+	}
+
+	for _, keyFile := range keyFiles {
+		data, err := os.ReadFile(keyFile) //nolint:gosec // path comes from the user's own GnuPG home
+		if err != nil {
+			continue
+		}
+		if bytes.Contains(data, shadowedKeyMarker) {
+			return true
+		}
+	}
+	return false
+}
+
+// configureHardwareToken enables serialized decryption when enabled is true,
+// or when enabled is nil and a smartcard-backed key is detected.
+//
+// A smartcard can only perform one operation at a time; parallel resource
+// opens racing for it fail with "card busy" errors or stacked PIN prompts.
+func (c *GopassClient) configureHardwareToken(ctx context.Context, enabled *bool) {
+	useToken := false
+	if enabled != nil {
+		useToken = *enabled
+	} else if home, err := gnupgHomeDir(c.userHomeDir); err == nil {
+		if c.gnupg != nil {
+			// The isolated home is not created yet, but will hold the same keys
+			home = c.gnupg.keyring
+		}
+		useToken = hasSmartcardKeys(home)
+		if useToken {
+			tflog.Debug(ctx, "Detected smartcard-backed GPG key", map[string]interface{}{
+				"gnupg_home": home,
+			})
+		}
+	}
+
+	if !useToken {
+		return
+	}
+
+	c.decryptSlots = make(chan struct{}, 1)
+	if c.timeouts.Read < hardwareTokenReadTimeout {
+		c.timeouts.Read = hardwareTokenReadTimeout
+	}
+
+	tflog.Info(ctx, "Hardware token mode enabled: decryptions are serialized", map[string]interface{}{
+		"read_timeout": c.timeouts.Read.String(),
+	})
+}
+
+// acquireDecryptSlot blocks until this goroutine may decrypt, when decryptions
+// are serialized. The returned function releases the slot.
+func (c *GopassClient) acquireDecryptSlot(ctx context.Context) (func(), error) {
+	if c.decryptSlots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.decryptSlots <- struct{}{}:
+		return func() { <-c.decryptSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// tokenTimeoutSummary is the diagnostic summary for reads that timed out
+// waiting for the hardware token.
+const tokenTimeoutSummary = "Hardware token interaction timed out"
+
+// tokenTimeoutError marks a read that timed out in hardware token mode, where
+// a timeout almost always means a touch or PIN prompt went unanswered.
+type tokenTimeoutError struct {
+	err     error
+	timeout time.Duration
+}
+
+func (e *tokenTimeoutError) Error() string { return e.err.Error() }
+
+func (e *tokenTimeoutError) Unwrap() error { return e.err }
+
+// tokenTimeoutHint explains how to get past an unanswered token prompt.
+// timeout is the read deadline that expired, or 0 if it is not known.
+func tokenTimeoutHint(timeout time.Duration) string {
+	current := ""
+	if timeout > 0 {
+		current = fmt.Sprintf(" (currently %s)", timeout)
+	}
+	return "The read waited for the hardware token to be touched or for its PIN to be entered, and " +
+		"nobody did in time. Make sure the token is connected and watch for its prompt; a blinking " +
+		"token is waiting for a touch.\n\n" +
+		"To allow more time, raise read_timeout in the provider configuration" + current + ". " +
+		"To be prompted less often, apply only what you need with -target, or list the secrets in " +
+		"prefetch_paths so they are decrypted in one pass at the start of the run."
+}
@@ -0,0 +1,259 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// mockConcurrencyStore tracks how many Get calls run at the same time
+type mockConcurrencyStore struct {
+	*mockStore
+	active    int32
+	maxActive int32
+}
+
+func (m *mockConcurrencyStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	active := atomic.AddInt32(&m.active, 1)
+	defer atomic.AddInt32(&m.active, -1)
+
+	for {
+		current := atomic.LoadInt32(&m.maxActive)
+		if active <= current || atomic.CompareAndSwapInt32(&m.maxActive, current, active) {
+			break
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	return m.mockStore.Get(ctx, name, revision)
+}
+
+func writeKeyStub(t *testing.T, gnupgHome, name, content string) {
+	t.Helper()
+	dir := filepath.Join(gnupgHome, "private-keys-v1.d")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatalf("failed to create key directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write key stub: %v", err)
+	}
+}
+
+func TestHasSmartcardKeys(t *testing.T) {
+	softwareHome := t.TempDir()
+	writeKeyStub(t, softwareHome, "ABCD.key", "Key: (private-key (rsa (n #00...#)))")
+	if hasSmartcardKeys(softwareHome) {
+		t.Error("expected software key not to be detected as smartcard")
+	}
+
+	cardHome := t.TempDir()
+	writeKeyStub(t, cardHome, "ABCD.key", "Key: (private-key (rsa (n #00...#)))")
+	writeKeyStub(t, cardHome, "EF01.key", "Key: (shadowed-private-key (rsa (n #00...#)(shadowed t1-v1 (#D276...#))))")
+	if !hasSmartcardKeys(cardHome) {
+		t.Error("expected shadowed key stub to be detected as smartcard")
+	}
+
+	if hasSmartcardKeys(filepath.Join(t.TempDir(), "missing")) {
+		t.Error("expected missing GnuPG home not to be detected as smartcard")
+	}
+}
+
+func TestGnupgHomeDir(t *testing.T) {
+	t.Setenv("GNUPGHOME", "/custom/gnupg")
+	dir, err := gnupgHomeDir(func() (string, error) { return "/home/test", nil })
+	if err != nil || dir != "/custom/gnupg" {
+		t.Errorf("expected GNUPGHOME to win, got %q (%v)", dir, err)
+	}
+
+	t.Setenv("GNUPGHOME", "")
+	dir, err = gnupgHomeDir(func() (string, error) { return "/home/test", nil })
+	if err != nil || dir != filepath.Join("/home/test", ".gnupg") {
+		t.Errorf("expected default GnuPG home, got %q (%v)", dir, err)
+	}
+
+	_, err = gnupgHomeDir(func() (string, error) { return "", errors.New("no home") })
+	if err == nil {
+		t.Error("expected error when home directory is unknown")
+	}
+}
+
+func TestGopassClient_ConfigureHardwareToken(t *testing.T) {
+	ctx := context.Background()
+	enabled, disabled := true, false
+
+	client := NewGopassClient("")
+	client.configureHardwareToken(ctx, &enabled)
+	if client.decryptSlots == nil {
+		t.Error("expected decryptions to be serialized when explicitly enabled")
+	}
+	if client.timeouts.Read != hardwareTokenReadTimeout {
+		t.Errorf("expected read timeout %s, got %s", hardwareTokenReadTimeout, client.timeouts.Read)
+	}
+
+	client = NewGopassClient("")
+	client.configureHardwareToken(ctx, &disabled)
+	if client.decryptSlots != nil {
+		t.Error("expected decryptions not to be serialized when explicitly disabled")
+	}
+
+	cardHome := t.TempDir()
+	writeKeyStub(t, cardHome, "EF01.key", "Key: (shadowed-private-key (rsa))")
+	t.Setenv("GNUPGHOME", cardHome)
+
+	client = NewGopassClient("")
+	client.configureHardwareToken(ctx, nil)
+	if client.decryptSlots == nil {
+		t.Error("expected decryptions to be serialized when a smartcard key is detected")
+	}
+}
+
+func TestGopassClient_HardwareToken_SerializesReads(t *testing.T) {
+	enabled := true
+	client := NewGopassClient("")
+	mockStore := &mockConcurrencyStore{mockStore: newMockStore()}
+	client.store = mockStore
+	client.configureHardwareToken(context.Background(), &enabled)
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		secret := secrets.New()
+		secret.SetPassword(name)
+		mockStore.secrets[name] = secret
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if _, err := client.GetSecret(context.Background(), name); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if maxActive := atomic.LoadInt32(&mockStore.maxActive); maxActive != 1 {
+		t.Errorf("expected at most 1 concurrent decryption, got %d", maxActive)
+	}
+}
+
+func TestGopassClient_HardwareToken_WaitCancelled(t *testing.T) {
+	enabled := true
+	client := NewGopassClient("")
+	client.store = newMockStore()
+	client.configureHardwareToken(context.Background(), &enabled)
+
+	// Occupy the only slot
+	client.decryptSlots <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetSecret(ctx, "test/secret")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded while waiting for the token, got %v", err)
+	}
+}
+
+func TestGopassClient_HardwareToken_TimeoutDiagnostic(t *testing.T) {
+	enabled := true
+	client := NewGopassClient("")
+	client.store = &mockBlockingStore{mockStore: newMockStore()}
+	client.retry.MaxAttempts = 1
+	client.configureHardwareToken(context.Background(), &enabled)
+	client.timeouts.Read = 20 * time.Millisecond
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	if got := errorSummary(err, "fallback"); got != tokenTimeoutSummary {
+		t.Errorf("expected summary %q, got %q", tokenTimeoutSummary, got)
+	}
+	detail := errorDetail("Failed to read secret.", err)
+	for _, want := range []string{"touched", "read_timeout", "(currently 20ms)", "-target", "prefetch_paths"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("expected detail to mention %q, got:\n%s", want, detail)
+		}
+	}
+}
+
+// mockStuckReadStore blocks every Get until unblock is closed, ignoring the
+// context like a gpg decrypt waiting for a token touch.
+type mockStuckReadStore struct {
+	*mockStore
+	unblock chan struct{}
+}
+
+func (m *mockStuckReadStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	<-m.unblock
+	return m.mockStore.Get(ctx, name, revision)
+}
+
+func TestGopassClient_HardwareToken_AbandonedReadHoldsSlot(t *testing.T) {
+	enabled := true
+	store := &mockStuckReadStore{mockStore: newMockStore(), unblock: make(chan struct{})}
+	store.secrets["test/secret"] = secrets.New()
+	client := NewGopassClient("")
+	client.store = store
+	client.retry.MaxAttempts = 1
+	client.configureHardwareToken(context.Background(), &enabled)
+	client.timeouts.Read = 10 * time.Millisecond
+
+	if _, err := client.GetSecret(context.Background(), "test/secret"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	// The abandoned decrypt still holds the token
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.acquireDecryptSlot(ctx); err == nil {
+		t.Fatal("expected the slot to be held by the abandoned read")
+	}
+
+	close(store.unblock)
+	release, err := client.acquireDecryptSlot(context.Background())
+	if err != nil {
+		t.Fatalf("expected the slot to be freed once the read returned, got %v", err)
+	}
+	release()
+}
+
+func TestGopassClient_TimeoutWithoutHardwareToken(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = &mockBlockingStore{mockStore: newMockStore()}
+	client.retry.MaxAttempts = 1
+	client.timeouts.Read = 20 * time.Millisecond
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if got := errorSummary(err, "fallback"); got == tokenTimeoutSummary {
+		t.Error("expected a plain timeout outside hardware token mode")
+	}
+}
+
+func TestClassifyGPGError_PinentryTimeout(t *testing.T) {
+	err := errors.New("gpg: public key decryption failed: Timeout")
+
+	if got := errorSummary(err, "fallback"); got != tokenTimeoutSummary {
+		t.Errorf("expected summary %q, got %q", tokenTimeoutSummary, got)
+	}
+	if detail := errorDetail("", err); strings.Contains(detail, "currently") || !strings.Contains(detail, "read_timeout") {
+		t.Errorf("expected hint without a current timeout, got:\n%s", detail)
+	}
+}
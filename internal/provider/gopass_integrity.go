@@ -0,0 +1,146 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ErrStoreIntegrity is returned for reads refused because verify_paths found
+// the store inconsistent.
+var ErrStoreIntegrity = errors.New("password store failed verification")
+
+// Checks reported in an IntegrityProblem.
+const (
+	integrityList       = "list"
+	integrityRecipients = "recipients"
+	integrityDecrypt    = "decrypt"
+)
+
+// IntegrityProblem is one finding of the store verification.
+type IntegrityProblem struct {
+	Path   string // secret path, or the prefix that could not be listed
+	Check  string // "list", "recipients" or "decrypt"
+	Detail string
+}
+
+// IntegrityError is returned for every read once the store verification
+// found problems. It matches ErrStoreIntegrity.
+type IntegrityError struct {
+	Checked  int // number of secrets verified
+	Problems []IntegrityProblem
+}
+
+func (e *IntegrityError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d problem(s) in %d secret(s):", ErrStoreIntegrity, len(e.Problems), e.Checked)
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  - %s [%s]: %s", p.Path, p.Check, p.Detail)
+	}
+	b.WriteString("\n\nRun \"gopass fsck\" to repair the store, or remove the affected entries from verify_paths")
+	return b.String()
+}
+
+func (e *IntegrityError) Unwrap() error {
+	return ErrStoreIntegrity
+}
+
+// integrityCheck verifies the subtrees about to be read once, before the
+// first read: every entry must have a non-empty recipient file and must
+// decrypt. Finding any problem fails every read with the full report, so an
+// inconsistent store stops the run before the first resource sees a value.
+type integrityCheck struct {
+	// paths are secret paths; entries ending in "/" select every secret below that prefix.
+	paths []string
+
+	once sync.Once
+	err  error
+}
+
+// newIntegrityCheck returns a check for the given paths, or nil if there is nothing to verify.
+func newIntegrityCheck(paths []string) *integrityCheck {
+	if len(paths) == 0 {
+		return nil
+	}
+	return &integrityCheck{paths: paths}
+}
+
+// verify runs the verification exactly once and returns its result, an
+// *IntegrityError or nil. It is safe on a nil check.
+func (v *integrityCheck) verify(ctx context.Context, c *GopassClient) error {
+	if v == nil {
+		return nil
+	}
+	v.once.Do(func() {
+		v.err = v.run(ctx, c)
+	})
+	return v.err
+}
+
+func (v *integrityCheck) run(ctx context.Context, c *GopassClient) error {
+	start := time.Now()
+	targets, unlisted := expandPaths(ctx, c, v.paths)
+
+	var problems []IntegrityProblem
+	for prefix, err := range unlisted {
+		problems = append(problems, IntegrityProblem{Path: prefix, Check: integrityList, Detail: err.Error()})
+	}
+
+	recipientsChecked := true
+	for _, path := range targets {
+		set, err := c.recipientsFor(path)
+		switch {
+		case errors.Is(err, errNoStoreDir):
+			if recipientsChecked {
+				tflog.Debug(ctx, "Skipping recipient verification", map[string]interface{}{
+					"reason": err.Error(),
+				})
+			}
+			recipientsChecked = false
+		case err != nil:
+			problems = append(problems, IntegrityProblem{Path: path, Check: integrityRecipients, Detail: err.Error()})
+		case len(set.recipients) == 0:
+			problems = append(problems, IntegrityProblem{Path: path, Check: integrityRecipients,
+				Detail: fmt.Sprintf("%s lists no recipients", set.file)})
+		}
+
+		if err := v.decrypt(ctx, c, path); err != nil {
+			problems = append(problems, IntegrityProblem{Path: path, Check: integrityDecrypt,
+				Detail: errorSummary(err, "Failed to decrypt secret") + ": " + err.Error()})
+		}
+	}
+
+	tflog.Info(ctx, "Verified gopass store", map[string]interface{}{
+		"secrets":    len(targets),
+		"problems":   len(problems),
+		"recipients": recipientsChecked,
+		"duration":   time.Since(start).String(),
+	})
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+	return &IntegrityError{Checked: len(targets), Problems: problems}
+}
+
+// decrypt decrypts the secret at path and discards it.
+func (v *integrityCheck) decrypt(ctx context.Context, c *GopassClient, path string) error {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = c.decryptSecret(ctx, store, path, "latest")
+	return err
+}
@@ -0,0 +1,180 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// writeStoreFile writes a file below the store directory dir.
+func writeStoreFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	file := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newIntegrityTestClient returns a client whose root store lives in a
+// temporary directory with a .gpg-id, holding app/db and app/api.
+func newIntegrityTestClient(t *testing.T, verifyPaths ...string) (*GopassClient, *mockStoreWithSelectiveFailure, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeStoreFile(t, dir, ".gpg-id", "# team key\n0xDEADBEEF\n")
+
+	store := newMockStoreWithSelectiveFailure()
+	store.secrets["app/db"] = newMockSecret("db-value")
+	store.secrets["app/api"] = newMockSecret("api-value")
+
+	client := NewGopassClient(dir)
+	client.store = store
+	client.retry.MaxAttempts = 1
+	client.integrity = newIntegrityCheck(verifyPaths)
+	return client, store, dir
+}
+
+func TestRecipientsFor(t *testing.T) {
+	client, _, dir := newIntegrityTestClient(t)
+	writeStoreFile(t, dir, "team/.age-recipients", "age1abc\n\nage1def\n")
+
+	set, err := client.recipientsFor("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if set.file != filepath.Join(dir, ".gpg-id") || len(set.recipients) != 1 || set.recipients[0] != "0xDEADBEEF" {
+		t.Errorf("unexpected root recipients %+v", set)
+	}
+
+	// The nearest recipient file wins
+	set, err = client.recipientsFor("team/nested/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if set.file != filepath.Join(dir, "team", ".age-recipients") || len(set.recipients) != 2 {
+		t.Errorf("unexpected subtree recipients %+v", set)
+	}
+
+	// Mounted stores have their own root
+	mountDir := t.TempDir()
+	client.addStoreMount("shared", mountDir)
+	if _, err := client.recipientsFor("shared/key"); err == nil || errors.Is(err, errNoStoreDir) {
+		t.Errorf("expected a missing recipient file in the mount, got %v", err)
+	}
+	writeStoreFile(t, mountDir, ".gpg-id", "0xCAFE\n")
+	if set, err := client.recipientsFor("shared/key"); err != nil || set.recipients[0] != "0xCAFE" {
+		t.Errorf("expected the mount's recipients, got %+v (%v)", set, err)
+	}
+
+	client.addMount("custom", func(ctx context.Context) (SecretStore, error) { return newMockStore(), nil })
+	if _, err := client.recipientsFor("custom/key"); !errors.Is(err, errNoStoreDir) {
+		t.Errorf("expected an unknown store directory for a custom backend, got %v", err)
+	}
+}
+
+func TestIntegrityCheck_Passes(t *testing.T) {
+	client, _, _ := newIntegrityTestClient(t, "app/")
+
+	value, err := client.GetSecret(context.Background(), "app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "db-value" {
+		t.Errorf("unexpected value %q", value)
+	}
+	if client.integrity.err != nil {
+		t.Errorf("expected the store to pass verification, got %v", client.integrity.err)
+	}
+}
+
+func TestIntegrityCheck_ReportsProblems(t *testing.T) {
+	client, store, dir := newIntegrityTestClient(t, "app/", "team/key")
+	store.failOnGet["app/api"] = true
+	store.secrets["team/key"] = newMockSecret("team-value")
+	writeStoreFile(t, dir, "team/.gpg-id", "\n# nobody\n")
+
+	_, err := client.GetSecret(context.Background(), "app/db")
+
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) || !errors.Is(err, ErrStoreIntegrity) {
+		t.Fatalf("expected an integrity error, got %v", err)
+	}
+	if integrityErr.Checked != 3 {
+		t.Errorf("expected 3 secrets checked, got %d", integrityErr.Checked)
+	}
+	want := map[string]string{
+		"app/api":  integrityDecrypt,
+		"team/key": integrityRecipients,
+	}
+	if len(integrityErr.Problems) != len(want) {
+		t.Fatalf("expected %d problems, got %+v", len(want), integrityErr.Problems)
+	}
+	for _, p := range integrityErr.Problems {
+		if want[p.Path] != p.Check {
+			t.Errorf("unexpected problem %+v", p)
+		}
+	}
+	if got := errorSummary(err, "fallback"); got != "Password store failed verification" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	for _, part := range []string{"2 problem(s) in 3 secret(s)", "app/api [decrypt]", "lists no recipients", "gopass fsck"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("expected report to contain %q, got:\n%s", part, err.Error())
+		}
+	}
+
+	// Every later read fails with the same report
+	if _, err := client.GetSecret(context.Background(), "team/key"); !errors.Is(err, ErrStoreIntegrity) {
+		t.Errorf("expected later reads to fail too, got %v", err)
+	}
+}
+
+func TestIntegrityCheck_UnknownStoreDirSkipsRecipients(t *testing.T) {
+	t.Setenv("PASSWORD_STORE_DIR", "")
+	client, _, _ := newIntegrityTestClient(t, "app/db")
+	client.storePath = ""
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Errorf("expected decryption checks only, got %v", err)
+	}
+}
+
+func TestIntegrityCheck_Nil(t *testing.T) {
+	if check := newIntegrityCheck(nil); check != nil {
+		t.Error("expected no check without paths")
+	}
+	var check *integrityCheck
+	if err := check.verify(context.Background(), NewGopassClient("")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestProviderConfigure_VerifyPaths(t *testing.T) {
+	p := &GopassProvider{version: "test"}
+	resp := &provider.ConfigureResponse{}
+	p.Configure(context.Background(), provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"verify_paths": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+				tftypes.NewValue(tftypes.String, "app/"),
+			}),
+		}),
+	}, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if check := resp.EphemeralResourceData.(*GopassClient).integrity; check == nil || len(check.paths) != 1 {
+		t.Errorf("expected a check for 1 path, got %+v", check)
+	}
+}
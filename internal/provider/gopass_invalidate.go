@@ -0,0 +1,132 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// listingCache holds the full store listing per store handle.
+//
+// Listing walks the whole store tree on disk, and gopass_env or prefix
+// prefetches may list many times per run. Entries are tied to the handle they
+// were filled from, so replacing a handle implicitly invalidates its listing.
+type listingCache struct {
+	mu      sync.Mutex
+	entries map[SecretStore][]string
+}
+
+// get returns the cached listing for store, if any.
+func (l *listingCache) get(store SecretStore) ([]string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, ok := l.entries[store]
+	return entries, ok
+}
+
+// put caches the listing for store and returns the cached copy. Cached
+// listings are kept sorted so that prefix lookups can use binary search.
+func (l *listingCache) put(store SecretStore, entries []string) []string {
+	if !sort.StringsAreSorted(entries) {
+		entries = append([]string(nil), entries...)
+		sort.Strings(entries)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.entries == nil {
+		l.entries = make(map[SecretStore][]string)
+	}
+	l.entries[store] = entries
+	return entries
+}
+
+// reset drops all cached listings.
+func (l *listingCache) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
+
+// prefixRange returns the entries of a sorted listing that start with prefix.
+func prefixRange(sorted []string, prefix string) []string {
+	if prefix == "" {
+		return sorted
+	}
+
+	start := sort.SearchStrings(sorted, prefix)
+	end := start
+	for end < len(sorted) && strings.HasPrefix(sorted[end], prefix) {
+		end++
+	}
+	return sorted[start:end]
+}
+
+// cachedList lists all secrets, serving repeated calls from the listing cache.
+// The returned listing is sorted and must not be modified.
+func (c *GopassClient) cachedList(ctx context.Context, store SecretStore) ([]string, error) {
+	if entries, ok := c.listing.get(store); ok {
+		c.metrics.cacheHit()
+		return entries, nil
+	}
+
+	entries, err := c.storeList(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []string{}
+	}
+
+	return c.listing.put(store, entries), nil
+}
+
+// invalidatePath drops everything cached about path after it was written or removed.
+func (c *GopassClient) invalidatePath(path string) {
+	c.listing.reset()
+	c.prefetch.forgetPath(path)
+	c.cache.forgetPath(path)
+}
+
+// Invalidate discards the store handle and everything cached from it, so the
+// next operation re-initializes the store and sees its current content.
+//
+// Writes through this client invalidate the affected entries automatically.
+// Call Invalidate after changes the client cannot see: a git sync, a mount
+// being added or removed, or secrets written by another process during the run.
+//
+// Operations already in progress keep using the old handle; it is closed once
+// they have released it.
+func (c *GopassClient) Invalidate(ctx context.Context) {
+	c.mu.Lock()
+	old := c.detachMounts()
+	if c.store != nil {
+		old = append(old, c.store)
+	}
+	c.store = nil
+	c.mu.Unlock()
+
+	c.listing.reset()
+	c.prefetch.forget()
+	c.cache.forget()
+
+	if len(old) == 0 {
+		return
+	}
+
+	tflog.Debug(ctx, "Invalidated gopass store handles", map[string]interface{}{
+		"handles": len(old),
+	})
+	for _, store := range old {
+		c.retireStore(ctx, store)
+	}
+}
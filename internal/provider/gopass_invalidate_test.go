@@ -0,0 +1,218 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// mockListCountingStore counts List calls
+type mockListCountingStore struct {
+	*mockStore
+	lists atomic.Int32
+}
+
+func (m *mockListCountingStore) List(ctx context.Context) ([]string, error) {
+	m.lists.Add(1)
+	return m.mockStore.List(ctx)
+}
+
+func newListCountingStore() *mockListCountingStore {
+	store := &mockListCountingStore{mockStore: newMockStore()}
+	for _, path := range []string{"app/env/KEY1", "app/env/KEY2"} {
+		secret := secrets.New()
+		secret.SetPassword("value-of-" + path)
+		store.secrets[path] = secret
+	}
+	return store
+}
+
+func TestGopassClient_ListingCache(t *testing.T) {
+	ctx := context.Background()
+	client := NewGopassClient("")
+	store := newListCountingStore()
+	client.store = store
+
+	for range 3 {
+		paths, err := client.ListSecrets(ctx, "app/env")
+		if err != nil {
+			t.Fatalf("ListSecrets() error = %v", err)
+		}
+		if len(paths) != 2 {
+			t.Fatalf("expected 2 secrets, got %v", paths)
+		}
+	}
+
+	if got := store.lists.Load(); got != 1 {
+		t.Errorf("expected the store to be listed once, got %d", got)
+	}
+}
+
+func TestGopassClient_SetSecret_InvalidatesListing(t *testing.T) {
+	ctx := context.Background()
+	client := NewGopassClient("")
+	client.store = newListCountingStore()
+
+	if _, err := client.ListSecrets(ctx, "app/env"); err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if err := client.SetSecret(ctx, "app/env/KEY3", "new"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+
+	values, err := client.GetEnvSecrets(ctx, "app/env")
+	if err != nil {
+		t.Fatalf("GetEnvSecrets() error = %v", err)
+	}
+	if values["KEY3"] != "new" {
+		t.Errorf("expected secret written earlier in the run to be listed, got %v", values)
+	}
+}
+
+func TestGopassClient_RemoveSecret_InvalidatesListing(t *testing.T) {
+	ctx := context.Background()
+	client := NewGopassClient("")
+	client.store = newListCountingStore()
+
+	if _, err := client.ListSecrets(ctx, "app/env"); err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if err := client.RemoveSecret(ctx, "app/env/KEY1"); err != nil {
+		t.Fatalf("RemoveSecret() error = %v", err)
+	}
+
+	paths, err := client.ListSecrets(ctx, "app/env")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "app/env/KEY2" {
+		t.Errorf("expected removed secret to disappear from listing, got %v", paths)
+	}
+}
+
+func TestGopassClient_SetSecret_InvalidatesPrefetch(t *testing.T) {
+	ctx := context.Background()
+	client := NewGopassClient("")
+	client.store = newPrefetchTestStore()
+	client.prefetch = newPrefetcher([]string{"app/db/password"})
+
+	if _, err := client.GetSecret(ctx, "app/db/password"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if err := client.SetSecret(ctx, "app/db/password", "rotated"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+
+	value, err := client.GetSecret(ctx, "app/db/password")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if value != "rotated" {
+		t.Errorf("expected rotated value, got %q", value)
+	}
+}
+
+func TestGopassClient_Invalidate_Reinitializes(t *testing.T) {
+	ctx := context.Background()
+	client, first := newLifecycleTestClient()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	// Simulate a git sync pulling in a new secret, visible only to a fresh handle
+	second := &mockClosingStore{SecretStore: NewMemoryStore(map[string]string{"app/db": "from-remote"})}
+	client.newStore = func(ctx context.Context) (SecretStore, error) { return second, nil }
+
+	client.Invalidate(ctx)
+
+	if first.closed.Load() != 1 {
+		t.Errorf("expected old handle to be closed, got %d closes", first.closed.Load())
+	}
+
+	value, err := client.GetSecret(ctx, "app/db")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if value != "from-remote" {
+		t.Errorf("expected value from re-initialized store, got %q", value)
+	}
+
+	client.Close(ctx)
+	if second.closed.Load() != 1 {
+		t.Errorf("expected new handle to be closed on Close, got %d closes", second.closed.Load())
+	}
+}
+
+func TestGopassClient_Invalidate_DefersCloseWhileInUse(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	client.retain()
+	client.Invalidate(ctx)
+
+	if store.closed.Load() != 0 {
+		t.Fatal("expected old handle to stay open while in use")
+	}
+
+	client.release(ctx)
+
+	if store.closed.Load() != 1 {
+		t.Errorf("expected old handle closed after release, got %d closes", store.closed.Load())
+	}
+}
+
+func TestGopassClient_Invalidate_NoStore(t *testing.T) {
+	client := NewGopassClient("")
+	client.Invalidate(context.Background())
+}
+
+func TestPrefixRange(t *testing.T) {
+	sorted := []string{"a/x", "app/env/KEY1", "app/env/KEY2", "app/envx/KEY", "b/y"}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", sorted},
+		{"app/env/", []string{"app/env/KEY1", "app/env/KEY2"}},
+		{"app/", []string{"app/env/KEY1", "app/env/KEY2", "app/envx/KEY"}},
+		{"c/", []string{}},
+		{"0/", []string{}},
+	}
+
+	for _, tt := range tests {
+		got := prefixRange(sorted, tt.prefix)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("prefixRange(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestListingCache_SortsUnsortedListing(t *testing.T) {
+	var cache listingCache
+	store := newMockStore()
+	unsorted := []string{"b", "a", "c"}
+
+	cached := cache.put(store, unsorted)
+
+	if fmt.Sprint(cached) != "[a b c]" {
+		t.Errorf("expected sorted listing, got %v", cached)
+	}
+	if fmt.Sprint(unsorted) != "[b a c]" {
+		t.Error("expected the store's listing not to be modified")
+	}
+	if _, ok := cache.get(newMockStore()); ok {
+		t.Error("expected listing of another store handle not to be served")
+	}
+}
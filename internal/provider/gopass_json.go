@@ -0,0 +1,37 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// secretJSON decodes the JSON document held by body: the whole entry if it
+// starts with one, as gopass stores documents inserted with "gopass insert -m",
+// otherwise the lines below the password. Numbers are kept as json.Number.
+// It returns nil if there is nothing to decode.
+func secretJSON(body string) (any, error) {
+	doc := strings.TrimSpace(body)
+	if !strings.HasPrefix(doc, "{") && !strings.HasPrefix(doc, "[") {
+		_, rest, _ := strings.Cut(body, "\n")
+		doc = strings.TrimSpace(rest)
+	}
+	if doc == "" {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(new(any)); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected content after the JSON document")
+	}
+	return v, nil
+}
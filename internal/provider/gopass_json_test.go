@@ -0,0 +1,37 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSecretJSON(t *testing.T) {
+	tests := map[string]string{
+		"whole entry":    "{\n  \"type\": \"service_account\",\n  \"port\": 8443\n}\n",
+		"below password": "s3cret\n{\"type\": \"service_account\", \"port\": 8443}\n",
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc, err := secretJSON(body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			m, _ := doc.(map[string]any)
+			if m["type"] != "service_account" || m["port"] != json.Number("8443") {
+				t.Errorf("unexpected document %v", doc)
+			}
+		})
+	}
+
+	if doc, err := secretJSON("s3cret\n"); err != nil || doc != nil {
+		t.Errorf("expected no document, got %v (%v)", doc, err)
+	}
+	for _, body := range []string{"s3cret\n{\"a\": ", "{\"a\": 1}\n{\"b\": 2}\n", "s3cret\nuser: admin\n"} {
+		if _, err := secretJSON(body); err == nil {
+			t.Errorf("%q: expected an error", body)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// keyConflicts checks the given keys of secret. It returns those that appear
+// on more than one line, with their number of occurrences, and the groups of
+// keys that differ only in case, sorted.
+func keyConflicts(secret gopass.Secret, keys []string) (duplicates map[string]int, caseVariants [][]string) {
+	byFold := make(map[string][]string)
+	for _, key := range keys {
+		if _, ok := secret.Get(key); !ok {
+			continue
+		}
+		if values, ok := secret.Values(key); ok && len(values) > 1 {
+			if duplicates == nil {
+				duplicates = make(map[string]int)
+			}
+			duplicates[key] = len(values)
+		}
+		folded := strings.ToLower(key)
+		byFold[folded] = append(byFold[folded], key)
+	}
+
+	for _, variants := range byFold {
+		if len(variants) > 1 {
+			sort.Strings(variants)
+			caseVariants = append(caseVariants, variants)
+		}
+	}
+	sort.Slice(caseVariants, func(i, j int) bool { return caseVariants[i][0] < caseVariants[j][0] })
+	return duplicates, caseVariants
+}
+
+// warnKeyConflicts queues a warning, once per path, if any of the given keys
+// of secret is duplicated or differs from another only in case. gopass
+// silently uses the first occurrence of a duplicate key, which is rarely what
+// the author expected.
+func (c *GopassClient) warnKeyConflicts(path string, secret gopass.Secret, keys []string) {
+	duplicates, caseVariants := keyConflicts(secret, keys)
+	if len(duplicates) == 0 && len(caseVariants) == 0 {
+		return
+	}
+
+	var problems []string
+	duplicated := make([]string, 0, len(duplicates))
+	for key := range duplicates {
+		duplicated = append(duplicated, key)
+	}
+	sort.Strings(duplicated)
+	for _, key := range duplicated {
+		problems = append(problems, fmt.Sprintf("key %q appears %d times, only the first value is used", key, duplicates[key]))
+	}
+	for _, variants := range caseVariants {
+		problems = append(problems, fmt.Sprintf("keys %s differ only in case and are read as separate fields",
+			strings.Join(quoteAll(variants), ", ")))
+	}
+
+	c.warnings.addOnce("conflicts:"+path, "Secret has conflicting keys",
+		fmt.Sprintf("The secret at %q has conflicting keys:\n  - %s\n\nEdit the secret with \"gopass edit %s\" "+
+			"so that each key appears once.", path, strings.Join(problems, "\n  - "), path))
+}
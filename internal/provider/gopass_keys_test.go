@@ -0,0 +1,97 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+func TestKeyConflicts(t *testing.T) {
+	secret := secrets.ParseAKV([]byte("s3cret\nuser: a\nuser: b\nHost: x\nhost: y\nHOST: z\nport: 1\n"))
+
+	duplicates, caseVariants := keyConflicts(secret, secret.Keys())
+
+	if len(duplicates) != 1 || duplicates["user"] != 2 {
+		t.Errorf("expected user twice, got %v", duplicates)
+	}
+	if len(caseVariants) != 1 || !slices.Equal(caseVariants[0], []string{"HOST", "Host", "host"}) {
+		t.Errorf("expected host case variants, got %v", caseVariants)
+	}
+}
+
+func TestKeyConflicts_None(t *testing.T) {
+	secret := secrets.ParseAKV([]byte("s3cret\nuser: a\nhost: x\n"))
+	duplicates, caseVariants := keyConflicts(secret, secret.Keys())
+	if len(duplicates) != 0 || len(caseVariants) != 0 {
+		t.Errorf("expected no conflicts, got %v and %v", duplicates, caseVariants)
+	}
+}
+
+func TestKeyConflicts_OnlyGivenKeys(t *testing.T) {
+	secret := secrets.ParseAKV([]byte("s3cret\nuser: a\nuser: b\nHost: x\nhost: y\n"))
+
+	duplicates, caseVariants := keyConflicts(secret, []string{"host", "missing"})
+	if len(duplicates) != 0 || len(caseVariants) != 0 {
+		t.Errorf("expected conflicts outside the given keys to be ignored, got %v and %v", duplicates, caseVariants)
+	}
+
+	duplicates, _ = keyConflicts(secret, []string{"user"})
+	if duplicates["user"] != 2 {
+		t.Errorf("expected user twice, got %v", duplicates)
+	}
+}
+
+func TestGetSecretFull_WarnsAboutKeyConflicts(t *testing.T) {
+	store := newMockStore()
+	store.secrets["app/db"] = secrets.ParseAKV([]byte("s3cret\nuser: first\nuser: second\nUrl: a\nurl: b\n"))
+	client := NewGopassClient("")
+	client.store = store
+	ctx := context.Background()
+
+	_, fields, err := client.GetSecretFull(ctx, "app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fields["user"] != "first" {
+		t.Errorf("expected the first value to win, got %q", fields["user"])
+	}
+	if _, _, err := client.GetSecretFields(ctx, "app/db", "user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	diags := client.takeWarnings()
+	var conflicts []string
+	for _, d := range diags {
+		if d.Summary() == "Secret has conflicting keys" {
+			conflicts = append(conflicts, d.Detail())
+		}
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("expected one conflict warning, got %v", diags)
+	}
+	for _, want := range []string{`key "user" appears 2 times`, `keys "Url", "url" differ only in case`, "gopass edit app/db"} {
+		if !strings.Contains(conflicts[0], want) {
+			t.Errorf("expected %q in detail, got %q", want, conflicts[0])
+		}
+	}
+}
+
+func TestGetSecretFields_NoWarningWithoutConflicts(t *testing.T) {
+	store := newMockStore()
+	store.secrets["app/db"] = secrets.ParseAKV([]byte("s3cret\nuser: admin\n"))
+	client := NewGopassClient("")
+	client.store = store
+
+	if _, _, err := client.GetSecretFields(context.Background(), "app/db", "user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diags := client.takeWarnings(); len(diags) != 0 {
+		t.Errorf("expected no warnings, got %v", diags)
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// defaultStoreIdleTimeout is how long a store nobody uses stays open. gpg-agent
+// connections and temporary files are released when it is closed.
+const defaultStoreIdleTimeout = time.Minute
+
+// storeLeaseKey is the ephemeral resource private state key recording that
+// Open took a reference on the store which Close must release. Its value is
+// the JSON-encoded lease ID.
+const storeLeaseKey = "store_lease"
+
+// clientLifecycle tracks who is using a client's store, so the store is only
+// closed once nobody needs it anymore.
+//
+// References are held by in-flight operations and by open ephemeral resources.
+// Close marks the client as closing; the store is released as soon as the last
+// reference is dropped, which may be immediately. Otherwise the store is closed
+// once it has not been referenced for idleTimeout, and reopened by the next
+// operation.
+type clientLifecycle struct {
+	mu      sync.Mutex
+	refs    int
+	closing bool
+	// idleTimeout is how long an unreferenced store stays open, 0 for ever
+	idleTimeout time.Duration
+	idleTimer   *time.Timer
+	idleGen     int // bumped whenever a pending idle close is cancelled
+	// leases maps ephemeral resource lease IDs to the buffers backing their results
+	leases map[string]*secretBuffers
+	// retired are invalidated store handles waiting for their users to finish
+	retired []SecretStore
+}
+
+// liveClients holds every configured client so the plugin can release their
+// stores when the server shuts down. Terraform does not tell providers when a
+// run ends; the end of providerserver.Serve is the last reliable hook.
+var liveClients = struct {
+	mu      sync.Mutex
+	clients map[*GopassClient]struct{}
+}{clients: make(map[*GopassClient]struct{})}
+
+// registerClient adds c to the clients released by Shutdown.
+func registerClient(c *GopassClient) {
+	liveClients.mu.Lock()
+	defer liveClients.mu.Unlock()
+	liveClients.clients[c] = struct{}{}
+}
+
+// unregisterClient removes c from the clients released by Shutdown.
+func unregisterClient(c *GopassClient) {
+	liveClients.mu.Lock()
+	defer liveClients.mu.Unlock()
+	delete(liveClients.clients, c)
+}
+
+// Shutdown closes every client configured in this process. It is meant to be
+// called by main once the plugin server has stopped serving.
+func Shutdown(ctx context.Context) {
+	liveClients.mu.Lock()
+	clients := make([]*GopassClient, 0, len(liveClients.clients))
+	for c := range liveClients.clients {
+		clients = append(clients, c)
+	}
+	liveClients.mu.Unlock()
+
+	for _, c := range clients {
+		// Terraform is gone; nobody will close the remaining ephemeral resources
+		c.expireLeases(ctx)
+		c.Close(ctx)
+	}
+}
+
+// retain takes a reference on the client's store.
+func (c *GopassClient) retain() {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+	c.lifecycle.refs++
+	c.stopIdleTimer()
+}
+
+// release drops a reference taken by retain. Dropping the last reference of a
+// closing client closes its store; otherwise the store is closed if it stays
+// unreferenced for the idle timeout.
+func (c *GopassClient) release(ctx context.Context) {
+	c.lifecycle.mu.Lock()
+	if c.lifecycle.refs > 0 {
+		c.lifecycle.refs--
+	}
+	idle := c.lifecycle.refs == 0
+	closeNow := idle && c.lifecycle.closing
+	var retired []SecretStore
+	if idle {
+		retired = c.lifecycle.retired
+		c.lifecycle.retired = nil
+	}
+	if idle && !closeNow && c.lifecycle.idleTimeout > 0 {
+		c.stopIdleTimer()
+		gen := c.lifecycle.idleGen
+		// The timer outlives the operation that dropped the reference
+		ctx := context.WithoutCancel(ctx)
+		c.lifecycle.idleTimer = time.AfterFunc(c.lifecycle.idleTimeout, func() { c.closeIdle(ctx, gen) })
+	}
+	c.lifecycle.mu.Unlock()
+
+	for _, store := range retired {
+		closeHandle(ctx, store)
+	}
+	if closeNow {
+		c.closeStore(ctx)
+	}
+}
+
+// stopIdleTimer cancels a pending idle close. c.lifecycle.mu must be held.
+func (c *GopassClient) stopIdleTimer() {
+	if c.lifecycle.idleTimer == nil {
+		return
+	}
+	c.lifecycle.idleTimer.Stop()
+	c.lifecycle.idleTimer = nil
+	c.lifecycle.idleGen++
+}
+
+// closeIdle closes the store handles once the idle timeout has passed without
+// a new reference, unless the idle close of generation gen was cancelled.
+func (c *GopassClient) closeIdle(ctx context.Context, gen int) {
+	c.lifecycle.mu.Lock()
+	if c.lifecycle.refs > 0 || c.lifecycle.idleGen != gen {
+		c.lifecycle.mu.Unlock()
+		return
+	}
+	c.lifecycle.idleTimer = nil
+	c.lifecycle.mu.Unlock()
+
+	tflog.Debug(ctx, "Closing idle gopass store", map[string]interface{}{
+		"idle_timeout": c.lifecycle.idleTimeout.String(),
+	})
+	// An operation starting meanwhile opens a new handle, the old one is
+	// closed once it is released
+	c.Invalidate(ctx)
+}
+
+// retireStore closes a replaced store handle once no operation uses it anymore.
+func (c *GopassClient) retireStore(ctx context.Context, store SecretStore) {
+	c.lifecycle.mu.Lock()
+	if c.lifecycle.refs > 0 {
+		c.lifecycle.retired = append(c.lifecycle.retired, store)
+		c.lifecycle.mu.Unlock()
+		return
+	}
+	c.lifecycle.mu.Unlock()
+
+	closeHandle(ctx, store)
+}
+
+// references returns the number of outstanding references.
+func (c *GopassClient) references() int {
+	c.lifecycle.mu.Lock()
+	defer c.lifecycle.mu.Unlock()
+	return c.lifecycle.refs
+}
+
+// Close releases the gopass store and flushes instrumentation. If operations
+// or ephemeral resources still hold the store, closing is deferred until the
+// last of them releases it.
+func (c *GopassClient) Close(ctx context.Context) {
+	c.lifecycle.mu.Lock()
+	c.lifecycle.closing = true
+	refs := c.lifecycle.refs
+	c.lifecycle.mu.Unlock()
+
+	if refs > 0 {
+		tflog.Debug(ctx, "Deferring gopass store close until in-use references are released", map[string]interface{}{
+			"references": refs,
+		})
+		return
+	}
+
+	c.closeStore(ctx)
+}
+
+// closeStore closes the store handle unconditionally.
+func (c *GopassClient) closeStore(ctx context.Context) {
+	unregisterClient(c)
+	if c.metricsSummary {
+		c.metrics.logSummary(ctx)
+	}
+	c.access.logSummary(ctx)
+	c.policies.logViolations(ctx)
+	c.audit.logHead(ctx)
+	c.cassette.save(ctx)
+	c.tracer.flush(ctx)
+	// Don't keep decrypted secrets around longer than the store they came from
+	c.prefetch.forget()
+	c.cache.forget()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Once every handle below is closed, nothing needs the isolated GnuPG home
+	defer c.gnupg.teardown(ctx)
+
+	// A later operation may reopen the store; it must be closed again then
+	c.lifecycle.mu.Lock()
+	c.lifecycle.closing = false
+	c.stopIdleTimer()
+	retired := c.lifecycle.retired
+	c.lifecycle.retired = nil
+	c.lifecycle.mu.Unlock()
+
+	for _, store := range retired {
+		closeHandle(ctx, store)
+	}
+	for _, store := range c.detachMounts() {
+		closeHandle(ctx, store)
+	}
+
+	if c.store == nil {
+		return
+	}
+
+	closeHandle(ctx, c.store)
+	c.store = nil
+	c.listing.reset()
+
+	tflog.Debug(ctx, "Gopass store closed")
+}
+
+// closeHandle closes a store handle, logging failures.
+func closeHandle(ctx context.Context, store SecretStore) {
+	// The gopass API store implements io.Closer through the api.Gopass type
+	if closer, ok := store.(interface{ Close(context.Context) error }); ok {
+		if err := closer.Close(ctx); err != nil {
+			tflog.Warn(ctx, "Error closing gopass store", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// privateSetter is implemented by the private state of ephemeral.OpenResponse.
+type privateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// privateGetter is implemented by the private state of ephemeral.CloseRequest.
+type privateGetter interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+// openLease takes a reference on the store for the lifetime of an ephemeral
+// resource and records it in the resource's private state. The buffers are
+// wiped when the lease is closed. If some of them could not be locked into
+// memory, it warns once per run.
+func (c *GopassClient) openLease(ctx context.Context, private privateSetter, buffers *secretBuffers) diag.Diagnostics {
+	id := randomHex(8)
+	value, _ := json.Marshal(id) // a hex string always encodes
+
+	diags := private.SetKey(ctx, storeLeaseKey, value)
+	if diags.HasError() {
+		return diags
+	}
+
+	buffers.mu.Lock()
+	lockErr := buffers.lockErr
+	buffers.mu.Unlock()
+	if lockErr != nil {
+		c.warnings.addOnce("secure-memory-results", "Secure memory unavailable",
+			fmt.Sprintf("secure_memory is enabled, but secret values handed to OpenTofu could not be locked into "+
+				"memory: %s. They are kept in regular memory instead, which the operating system may swap to disk. "+
+				"On Linux, raise the locked memory limit (ulimit -l) of the process running OpenTofu.", lockErr))
+	}
+
+	c.lifecycle.mu.Lock()
+	if c.lifecycle.leases == nil {
+		c.lifecycle.leases = make(map[string]*secretBuffers)
+	}
+	c.lifecycle.leases[id] = buffers
+	c.lifecycle.refs++
+	c.lifecycle.mu.Unlock()
+
+	return diags
+}
+
+// closeLease wipes the buffers of the lease recorded by openLease and releases
+// its store reference, if there is one.
+func (c *GopassClient) closeLease(ctx context.Context, private privateGetter) diag.Diagnostics {
+	value, diags := private.GetKey(ctx, storeLeaseKey)
+	if diags.HasError() || value == nil {
+		return diags
+	}
+
+	var id string
+	if err := json.Unmarshal(value, &id); err != nil {
+		diags.AddWarning("Invalid ephemeral resource private state",
+			"Could not decode the store lease; secret buffers will be wiped when the provider shuts down.")
+		return diags
+	}
+
+	c.lifecycle.mu.Lock()
+	buffers, ok := c.lifecycle.leases[id]
+	delete(c.lifecycle.leases, id)
+	c.lifecycle.mu.Unlock()
+
+	if !ok {
+		return diags
+	}
+
+	wiped := buffers.wipe()
+	tflog.Debug(ctx, "Wiped ephemeral secret buffers", map[string]interface{}{
+		"buffers": wiped,
+	})
+	c.release(ctx)
+	return diags
+}
+
+// expireLeases wipes and releases all open leases.
+func (c *GopassClient) expireLeases(ctx context.Context) {
+	c.lifecycle.mu.Lock()
+	leases := c.lifecycle.leases
+	c.lifecycle.leases = nil
+	c.lifecycle.mu.Unlock()
+
+	for _, buffers := range leases {
+		buffers.wipe()
+		c.release(ctx)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+)
+
+// mockClosingStore counts Close calls
+type mockClosingStore struct {
+	SecretStore
+	closed atomic.Int32
+}
+
+func (m *mockClosingStore) Close(ctx context.Context) error {
+	m.closed.Add(1)
+	return nil
+}
+
+// mockPrivateState is an in-memory stand-in for ephemeral resource private state
+type mockPrivateState map[string][]byte
+
+func (m mockPrivateState) SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics {
+	m[key] = value
+	return nil
+}
+
+func (m mockPrivateState) GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics) {
+	return m[key], nil
+}
+
+func newLifecycleTestClient() (*GopassClient, *mockClosingStore) {
+	store := &mockClosingStore{SecretStore: NewMemoryStore(map[string]string{"app/db": "s3cret"})}
+	return NewGopassClientWithStore(store), store
+}
+
+func TestGopassClient_OperationsReleaseReferences(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newLifecycleTestClient()
+	defer client.Close(ctx)
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "missing"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+
+	if refs := client.references(); refs != 0 {
+		t.Errorf("expected no outstanding references, got %d", refs)
+	}
+}
+
+func TestGopassClient_Close_DeferredWhileReferenced(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	client.retain()
+	client.Close(ctx)
+
+	if store.closed.Load() != 0 {
+		t.Fatal("expected store to stay open while referenced")
+	}
+
+	client.release(ctx)
+
+	if store.closed.Load() != 1 {
+		t.Errorf("expected store to be closed once on last release, got %d", store.closed.Load())
+	}
+	if client.store != nil {
+		t.Error("expected store handle to be cleared")
+	}
+
+	// Further releases must not close again
+	client.release(ctx)
+	if store.closed.Load() != 1 {
+		t.Errorf("expected a single close, got %d", store.closed.Load())
+	}
+}
+
+func TestGopassClient_ReleaseWithoutClose_KeepsStoreOpen(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+	defer client.Close(ctx)
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	client.retain()
+	client.release(ctx)
+
+	if store.closed.Load() != 0 {
+		t.Error("expected store to stay open when the client is not closing")
+	}
+}
+
+func TestGopassClient_IdleStoreClosed(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+	client.lifecycle.idleTimeout = 10 * time.Millisecond
+	defer client.Close(ctx)
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for store.closed.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if store.closed.Load() != 1 {
+		t.Fatalf("expected the idle store to be closed once, got %d", store.closed.Load())
+	}
+
+	// The next operation reopens it
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() after idle close error = %v", err)
+	}
+	client.mu.RLock()
+	reopened := client.store != nil
+	client.mu.RUnlock()
+	if !reopened {
+		t.Error("expected the store to be reopened")
+	}
+}
+
+func TestGopassClient_ReferencedStoreNotClosedWhenIdle(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+	client.lifecycle.idleTimeout = 10 * time.Millisecond
+	defer client.Close(ctx)
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	client.retain()
+	defer client.release(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+	if store.closed.Load() != 0 {
+		t.Error("expected a referenced store to stay open")
+	}
+}
+
+func TestGopassClient_ReopenAfterClose(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	client.Close(ctx)
+
+	// A late operation reopens the store, which must be closable again
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() after Close error = %v", err)
+	}
+	client.Close(ctx)
+
+	if store.closed.Load() != 2 {
+		t.Errorf("expected two closes, got %d", store.closed.Load())
+	}
+}
+
+func TestShutdown_ClosesOpenClients(t *testing.T) {
+	ctx := context.Background()
+	first, firstStore := newLifecycleTestClient()
+	second, secondStore := newLifecycleTestClient()
+	_, unusedStore := newLifecycleTestClient()
+
+	for _, client := range []*GopassClient{first, second} {
+		if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+			t.Fatalf("GetSecret() error = %v", err)
+		}
+	}
+
+	Shutdown(ctx)
+
+	if firstStore.closed.Load() != 1 || secondStore.closed.Load() != 1 {
+		t.Errorf("expected both stores closed, got %d and %d", firstStore.closed.Load(), secondStore.closed.Load())
+	}
+	if unusedStore.closed.Load() != 0 {
+		t.Error("expected never-opened store not to be touched")
+	}
+
+	liveClients.mu.Lock()
+	remaining := len(liveClients.clients)
+	liveClients.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("expected no registered clients after Shutdown, got %d", remaining)
+	}
+}
+
+func TestGopassClient_Leases(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	private := mockPrivateState{}
+	if diags := client.openLease(ctx, private, &secretBuffers{}); diags.HasError() {
+		t.Fatalf("openLease() error = %v", diags)
+	}
+	if client.references() != 1 {
+		t.Fatalf("expected one reference, got %d", client.references())
+	}
+
+	client.Close(ctx)
+	if store.closed.Load() != 0 {
+		t.Fatal("expected store to stay open while an ephemeral resource is open")
+	}
+
+	if diags := client.closeLease(ctx, private); diags.HasError() {
+		t.Fatalf("closeLease() error = %v", diags)
+	}
+	if store.closed.Load() != 1 {
+		t.Error("expected store closed when the last lease is released")
+	}
+}
+
+func TestGopassClient_CloseLease_WithoutLease(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newLifecycleTestClient()
+	client.retain()
+
+	if diags := client.closeLease(ctx, mockPrivateState{}); diags.HasError() {
+		t.Fatalf("closeLease() error = %v", diags)
+	}
+	if client.references() != 1 {
+		t.Error("expected closeLease without a lease not to drop references")
+	}
+}
+
+func TestEphemeralResources_Close_NoPrivateState(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newLifecycleTestClient()
+
+	for _, r := range []ephemeral.EphemeralResourceWithClose{
+		&SecretEphemeralResource{client: client},
+		&EnvEphemeralResource{client: client},
+		&SecretEphemeralResource{},
+	} {
+		resp := &ephemeral.CloseResponse{}
+		r.Close(ctx, ephemeral.CloseRequest{}, resp)
+		if resp.Diagnostics.HasError() {
+			t.Errorf("Close() returned errors: %v", resp.Diagnostics)
+		}
+	}
+}
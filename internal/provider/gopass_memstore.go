@@ -0,0 +1,143 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// Backends the provider can run against.
+const (
+	backendGopass = "gopass"
+	backendMock   = "mock"
+)
+
+// Environment variables selecting the backend and the mock fixture when the
+// provider configuration does not, so test pipelines can switch a whole
+// configuration to the mock backend without editing it.
+const (
+	backendEnv     = "GOPASS_PROVIDER_BACKEND"
+	mockFixtureEnv = "GOPASS_PROVIDER_MOCK_FIXTURE"
+)
+
+// MemoryStore is a SecretStore holding secrets in memory, without GPG, git or
+// a store on disk. Entries are parsed like gopass parses decrypted entries:
+// the first line is the password, "key: value" lines below it are fields.
+// Every Set adds a revision. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string][][]byte // path -> bodies, newest first
+}
+
+// Ensure MemoryStore satisfies SecretStore.
+var _ SecretStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a MemoryStore holding the given entries, as path
+// to whole entry body.
+func NewMemoryStore(entries map[string]string) *MemoryStore {
+	s := &MemoryStore{entries: make(map[string][][]byte, len(entries))}
+	for name, body := range entries {
+		s.entries[name] = [][]byte{[]byte(body)}
+	}
+	return s
+}
+
+// LoadMemoryStore returns a MemoryStore seeded from a JSON fixture file: an
+// object mapping secret paths to entry bodies, e.g.
+// {"app/db": "s3cret\nusername: admin"}.
+func LoadMemoryStore(path string) (*MemoryStore, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path comes from the provider configuration
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: expected a JSON object of secret paths to entries: %w", path, err)
+	}
+	for name := range entries {
+		if name == "" || normalizePath(name) != name {
+			return nil, fmt.Errorf("invalid fixture %s: invalid secret path %q", path, name)
+		}
+	}
+	return NewMemoryStore(entries), nil
+}
+
+// Get returns the given revision of a secret. Revisions are numbered from 1,
+// the oldest; "latest" and "" select the newest.
+func (s *MemoryStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bodies, ok := s.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	index := 0
+	if revision != "latest" && revision != "" {
+		n, err := strconv.Atoi(revision)
+		if err != nil || n < 1 || n > len(bodies) {
+			return nil, fmt.Errorf("%w: revision %q of %s", ErrNotFound, revision, name)
+		}
+		index = len(bodies) - n
+	}
+	return parseSecret(append([]byte(nil), bodies[index]...)), nil
+}
+
+// List returns the paths of all secrets, sorted.
+func (s *MemoryStore) List(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Set adds a revision to the secret, creating it if needed.
+func (s *MemoryStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[name] = append([][]byte{append([]byte(nil), sec.Bytes()...)}, s.entries[name]...)
+	return nil
+}
+
+// Remove deletes a secret with all its revisions.
+func (s *MemoryStore) Remove(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.entries, name)
+	return nil
+}
+
+// Revisions returns the revision identifiers of a secret, newest first.
+func (s *MemoryStore) Revisions(ctx context.Context, name string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bodies, ok := s.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	revisions := make([]string, len(bodies))
+	for i := range bodies {
+		revisions[i] = strconv.Itoa(len(bodies) - i)
+	}
+	return revisions, nil
+}
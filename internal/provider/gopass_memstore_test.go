@@ -0,0 +1,207 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+const memoryTestFixture = `{
+  "app/db": "s3cret\nusername: admin\nport: 5432",
+  "app/env/API_KEY": "key-value",
+  "app/env/TOKEN": "token-value"
+}`
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(map[string]string{"app/db": "s3cret\nusername: admin"})
+
+	secret, err := store.Get(ctx, "app/db", "latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user, _ := secret.Get("username"); secret.Password() != "s3cret" || user != "admin" {
+		t.Errorf("unexpected secret %q", secret.Bytes())
+	}
+
+	// Changing a returned secret does not change the store
+	secret.SetPassword("changed")
+	if again, _ := store.Get(ctx, "app/db", "latest"); again.Password() != "s3cret" {
+		t.Error("expected the stored entry to be unchanged")
+	}
+
+	updated := secrets.New()
+	updated.SetPassword("rotated")
+	if err := store.Set(ctx, "app/db", updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	revisions, err := store.Revisions(ctx, "app/db")
+	if err != nil || !slices.Equal(revisions, []string{"2", "1"}) {
+		t.Errorf("expected revisions [2 1], got %v (%v)", revisions, err)
+	}
+	for revision, want := range map[string]string{"latest": "rotated", "2": "rotated", "1": "s3cret"} {
+		if secret, err := store.Get(ctx, "app/db", revision); err != nil || secret.Password() != want {
+			t.Errorf("revision %s: expected %q, got %v (%v)", revision, want, secret, err)
+		}
+	}
+	if _, err := store.Get(ctx, "app/db", "3"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a missing revision, got %v", err)
+	}
+
+	if err := store.Set(ctx, "app/new", updated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names, _ := store.List(ctx); !slices.Equal(names, []string{"app/db", "app/new"}) {
+		t.Errorf("unexpected listing %v", names)
+	}
+
+	if err := store.Remove(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get(ctx, "app/db", "latest"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the secret to be gone, got %v", err)
+	}
+	if err := store.Remove(ctx, "app/db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := store.Revisions(ctx, "app/db"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestLoadMemoryStore(t *testing.T) {
+	store, err := LoadMemoryStore(writeTemp(t, memoryTestFixture))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names, _ := store.List(context.Background()); len(names) != 3 {
+		t.Errorf("expected 3 secrets, got %v", names)
+	}
+
+	for name, fixture := range map[string]string{
+		"not json":      "app/db: s3cret",
+		"not an object": `["app/db"]`,
+		"nested":        `{"app": {"db": "s3cret"}}`,
+		"empty path":    `{"": "s3cret"}`,
+		"directory":     `{"app/": "s3cret"}`,
+	} {
+		if _, err := LoadMemoryStore(writeTemp(t, fixture)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestGopassClient_MemoryStore(t *testing.T) {
+	store, _ := LoadMemoryStore(writeTemp(t, memoryTestFixture))
+	client := NewGopassClientWithStore(store)
+	ctx := context.Background()
+
+	password, fields, err := client.GetSecretFull(ctx, "app/db")
+	if err != nil || password != "s3cret" || fields["port"] != "5432" {
+		t.Errorf("unexpected secret %q %v (%v)", password, fields, err)
+	}
+	values, err := client.GetEnvSecrets(ctx, "app/env")
+	if err != nil || len(values) != 2 || values["TOKEN"] != "token-value" {
+		t.Errorf("unexpected env %v (%v)", values, err)
+	}
+
+	if err := client.SetSecret(ctx, "app/db", "rotated"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count, err := client.GetRevisionCount(ctx, "app/db"); err != nil || count != 2 {
+		t.Errorf("expected 2 revisions, got %d (%v)", count, err)
+	}
+	if err := client.RemoveSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exists, err := client.SecretExists(ctx, "app/db"); err != nil || exists {
+		t.Errorf("expected the secret to be removed, got %v (%v)", exists, err)
+	}
+}
+
+func configureTestProvider(t *testing.T, config map[string]tftypes.Value) *provider.ConfigureResponse {
+	t.Helper()
+	p := &GopassProvider{version: "test"}
+	resp := &provider.ConfigureResponse{}
+	p.Configure(context.Background(), provider.ConfigureRequest{Config: newProviderConfig(t, p, config)}, resp)
+	return resp
+}
+
+func TestProviderConfigure_MockBackend(t *testing.T) {
+	fixture := writeTemp(t, memoryTestFixture)
+
+	resp := configureTestProvider(t, map[string]tftypes.Value{
+		"backend":      tftypes.NewValue(tftypes.String, "mock"),
+		"mock_fixture": tftypes.NewValue(tftypes.String, fixture),
+		"store_path":   tftypes.NewValue(tftypes.String, "/nonexistent/store"),
+		"mounts": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+			"app/env": tftypes.NewValue(tftypes.String, "/nonexistent/team"),
+		}),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	client := resp.EphemeralResourceData.(*GopassClient)
+	if client.storePath != "" || len(client.mounts) != 0 {
+		t.Errorf("expected no store on disk, got %q and %d mount(s)", client.storePath, len(client.mounts))
+	}
+	ctx := context.Background()
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+		t.Errorf("expected the fixture value, got %q (%v)", value, err)
+	}
+	if value, err := client.GetSecret(ctx, "app/env/TOKEN"); err != nil || value != "token-value" {
+		t.Errorf("expected mounted paths from the fixture, got %q (%v)", value, err)
+	}
+}
+
+func TestProviderConfigure_MockBackendFromEnv(t *testing.T) {
+	t.Setenv(backendEnv, "mock")
+	t.Setenv(mockFixtureEnv, writeTemp(t, memoryTestFixture))
+
+	resp := configureTestProvider(t, nil)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	client := resp.EphemeralResourceData.(*GopassClient)
+	if value, err := client.GetSecret(context.Background(), "app/env/API_KEY"); err != nil || value != "key-value" {
+		t.Errorf("expected the fixture value, got %q (%v)", value, err)
+	}
+
+	// The configuration wins over the environment
+	resp = configureTestProvider(t, map[string]tftypes.Value{
+		"backend":    tftypes.NewValue(tftypes.String, "gopass"),
+		"store_path": tftypes.NewValue(tftypes.String, "/srv/store"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.EphemeralResourceData.(*GopassClient); client.storePath != "/srv/store" {
+		t.Errorf("expected the gopass backend at store_path, got %q", client.storePath)
+	}
+}
+
+func TestProviderConfigure_MockBackendErrors(t *testing.T) {
+	tests := map[string]map[string]tftypes.Value{
+		"unknown backend": {"backend": tftypes.NewValue(tftypes.String, "vault")},
+		"fixture without mock backend": {
+			"mock_fixture": tftypes.NewValue(tftypes.String, writeTemp(t, "{}")),
+		},
+		"missing fixture": {
+			"backend":      tftypes.NewValue(tftypes.String, "mock"),
+			"mock_fixture": tftypes.NewValue(tftypes.String, "/nonexistent/fixture.json"),
+		},
+	}
+	for name, config := range tests {
+		if resp := configureTestProvider(t, config); !resp.Diagnostics.HasError() {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
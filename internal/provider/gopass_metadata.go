@@ -0,0 +1,70 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// SecretMetadata holds what is known about a secret besides its content.
+type SecretMetadata struct {
+	Keys          []string  // sorted key names, without their values
+	RevisionCount int64     // see GetRevisionCount
+	LastModified  time.Time // see secretModTime, zero if it is unknown
+}
+
+// SecretMetadata returns the metadata of the secret at path and whether it
+// exists. A missing secret is not an error. Like SecretDigest, the secret is
+// decrypted to list its keys but its content never leaves the client, so this
+// works in checksum-only mode.
+func (c *GopassClient) SecretMetadata(ctx context.Context, path string) (meta SecretMetadata, exists bool, err error) {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return SecretMetadata{}, false, err
+	}
+	defer release()
+
+	tflog.Debug(ctx, "Reading secret metadata", map[string]interface{}{
+		"path": c.logPath(path),
+	})
+
+	secret, err := c.storeGet(ctx, store, path)
+	if errors.Is(err, ErrNotFound) {
+		return SecretMetadata{}, false, nil
+	}
+	if err != nil {
+		return SecretMetadata{}, false, c.readError(ctx, store, path, err)
+	}
+
+	meta.Keys = append([]string{}, secret.Keys()...)
+	slices.Sort(meta.Keys)
+	meta.Keys = slices.Compact(meta.Keys)
+
+	// Not all backends keep a history, as in GetRevisionCount
+	meta.RevisionCount = 1
+	if revisions, err := c.storeRevisions(ctx, store, path); err != nil {
+		tflog.Debug(ctx, "Revisions() not supported or failed, reporting a single revision", map[string]interface{}{
+			"path":  c.logPath(path),
+			"error": c.logError(err),
+		})
+	} else if len(revisions) > 0 {
+		meta.RevisionCount = int64(len(revisions))
+	}
+
+	if modified, err := c.secretModTime(path); err != nil {
+		tflog.Debug(ctx, "Last modification time unknown", map[string]interface{}{
+			"path":  c.logPath(path),
+			"error": c.logError(err),
+		})
+	} else {
+		meta.LastModified = modified
+	}
+
+	return meta, true, nil
+}
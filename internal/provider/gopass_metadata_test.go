@@ -0,0 +1,145 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newMetadataTestClient returns a checksum-only client whose store lives in
+// a temporary directory, holding app/db with three revisions and an
+// encrypted file written at the returned time.
+func newMetadataTestClient(t *testing.T) (*GopassClient, time.Time) {
+	t.Helper()
+	client, dir := newExpiryTestClient(t, map[string]string{"user": "admin", "host": "db.internal"})
+	client.store.(*mockStore).revisions["app/db"] = []string{"c3", "c2", "c1"}
+	client.checksumOnly = true
+
+	writeStoreFile(t, dir, "app/db.gpg", "ciphertext")
+	written := time.Date(2026, 1, 1, 8, 30, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "app", "db.gpg"), written, written); err != nil {
+		t.Fatal(err)
+	}
+	return client, written
+}
+
+func TestGopassClient_SecretMetadata(t *testing.T) {
+	client, written := newMetadataTestClient(t)
+	ctx := context.Background()
+
+	meta, exists, err := client.SecretMetadata(ctx, "app/db")
+	if err != nil || !exists {
+		t.Fatalf("expected the secret to exist, got %v (%v)", exists, err)
+	}
+	if !slices.Equal(meta.Keys, []string{"host", "user"}) {
+		t.Errorf("unexpected keys %v", meta.Keys)
+	}
+	if meta.RevisionCount != 3 || !meta.LastModified.Equal(written) {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+
+	// No history and no encrypted file: a single revision, modification unknown
+	meta, exists, err = client.SecretMetadata(ctx, "app/env/KEY")
+	if err != nil || !exists {
+		t.Fatalf("expected the secret to exist, got %v (%v)", exists, err)
+	}
+	if meta.RevisionCount != 1 || !meta.LastModified.IsZero() {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+
+	if _, exists, err = client.SecretMetadata(ctx, "app/missing"); err != nil || exists {
+		t.Errorf("expected a missing secret without error, got %v (%v)", exists, err)
+	}
+}
+
+func TestGopassClient_SecretMetadata_Error(t *testing.T) {
+	client, _ := newMetadataTestClient(t)
+	store := client.store.(*mockStore)
+	store.shouldFail = true
+	store.failMsg = "backend unavailable"
+
+	if _, _, err := client.SecretMetadata(context.Background(), "app/db"); err == nil {
+		t.Error("expected the read error to be returned")
+	}
+}
+
+// readMetadataDataSource reads a gopass_secret_metadata data source for path.
+func readMetadataDataSource(t *testing.T, client *GopassClient, path string) (*datasource.ReadResponse, SecretMetadataModel) {
+	t.Helper()
+
+	d := &SecretMetadataDataSource{client: client}
+	ctx := context.Background()
+	schemaResp := &datasource.SchemaResponse{}
+	d.Schema(ctx, datasource.SchemaRequest{}, schemaResp)
+
+	objectType := schemaResp.Schema.Type().TerraformType(ctx)
+	req := datasource.ReadRequest{
+		Config: tfsdk.Config{
+			Schema: schemaResp.Schema,
+			Raw: tftypes.NewValue(objectType, map[string]tftypes.Value{
+				"path":           tftypes.NewValue(tftypes.String, path),
+				"policy":         tftypes.NewValue(tftypes.String, nil),
+				"exists":         tftypes.NewValue(tftypes.Bool, nil),
+				"keys":           tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil),
+				"revision_count": tftypes.NewValue(tftypes.Number, nil),
+				"last_modified":  tftypes.NewValue(tftypes.String, nil),
+			}),
+		},
+	}
+	resp := &datasource.ReadResponse{
+		State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, nil)},
+	}
+
+	d.Read(ctx, req, resp)
+
+	var data SecretMetadataModel
+	if !resp.Diagnostics.HasError() {
+		resp.State.Get(ctx, &data)
+	}
+	return resp, data
+}
+
+func TestSecretMetadataDataSource_Read(t *testing.T) {
+	client, _ := newMetadataTestClient(t)
+
+	resp, data := readMetadataDataSource(t, client, "app/db")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if !data.Exists.ValueBool() || len(data.Keys.Elements()) != 2 || data.RevisionCount.ValueInt64() != 3 {
+		t.Errorf("unexpected result %+v", data)
+	}
+	if got := data.LastModified.ValueString(); got != "2026-01-01T08:30:00Z" {
+		t.Errorf("unexpected last_modified %q", got)
+	}
+
+	resp, data = readMetadataDataSource(t, client, "app/missing")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if data.Exists.ValueBool() || !data.Keys.IsNull() || data.RevisionCount.ValueInt64() != 0 || !data.LastModified.IsNull() {
+		t.Errorf("expected a missing secret, got %+v", data)
+	}
+}
+
+func TestSecretMetadataDataSource_Read_Error(t *testing.T) {
+	client, _ := newMetadataTestClient(t)
+	store := client.store.(*mockStore)
+	store.shouldFail = true
+	store.failMsg = "backend unavailable"
+
+	resp, _ := readMetadataDataSource(t, client, "app/db")
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error")
+	}
+}
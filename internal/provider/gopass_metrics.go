@@ -0,0 +1,198 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// metricsSubsystem is the tflog subsystem used for client instrumentation.
+// Enable it with TF_LOG_PROVIDER_GOPASS_METRICS=DEBUG.
+const metricsSubsystem = "metrics"
+
+// Operation kinds tracked by clientMetrics.
+const (
+	opInit      = "init"
+	opRead      = "read"
+	opList      = "list"
+	opWrite     = "write"
+	opRemove    = "remove"
+	opRevisions = "revisions"
+	opSync      = "sync"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram buckets.
+// Durations above the last bound land in an implicit overflow bucket.
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	30 * time.Second,
+}
+
+// operationStats aggregates the outcome of one kind of store operation.
+type operationStats struct {
+	Count   int64
+	Errors  int64
+	Total   time.Duration
+	Max     time.Duration
+	Buckets []int64 // len(latencyBuckets)+1, last is overflow
+}
+
+// clientMetrics collects counters and latency histograms for a GopassClient.
+type clientMetrics struct {
+	mu        sync.Mutex
+	ops       map[string]*operationStats
+	cacheHits int64
+	shared    int64
+	retries   int64
+}
+
+func newClientMetrics() *clientMetrics {
+	return &clientMetrics{ops: make(map[string]*operationStats)}
+}
+
+// observe records a completed store operation.
+func (m *clientMetrics) observe(kind string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.ops[kind]
+	if !ok {
+		stats = &operationStats{Buckets: make([]int64, len(latencyBuckets)+1)}
+		m.ops[kind] = stats
+	}
+
+	stats.Count++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.Total += duration
+	if duration > stats.Max {
+		stats.Max = duration
+	}
+
+	bucket := sort.Search(len(latencyBuckets), func(i int) bool {
+		return duration <= latencyBuckets[i]
+	})
+	stats.Buckets[bucket]++
+}
+
+// cacheHit records a read served without decrypting.
+func (m *clientMetrics) cacheHit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheHits++
+}
+
+// coalesced records a read that shared another caller's decryption.
+func (m *clientMetrics) coalesced() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shared++
+}
+
+// retry records a retried operation attempt.
+func (m *clientMetrics) retry() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries++
+}
+
+// snapshot returns a copy of the current statistics.
+func (m *clientMetrics) snapshot() (ops map[string]operationStats, cacheHits, retries int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ops = make(map[string]operationStats, len(m.ops))
+	for kind, stats := range m.ops {
+		copied := *stats
+		copied.Buckets = append([]int64(nil), stats.Buckets...)
+		ops[kind] = copied
+	}
+	return ops, m.cacheHits, m.retries
+}
+
+// coalescedReads returns the number of reads that shared another caller's decryption.
+func (m *clientMetrics) coalescedReads() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.shared
+}
+
+// histogramFields renders histogram buckets as log fields, e.g. "le_100ms".
+func histogramFields(buckets []int64) map[string]interface{} {
+	fields := make(map[string]interface{}, len(buckets))
+	for i, count := range buckets {
+		if count == 0 {
+			continue
+		}
+		if i < len(latencyBuckets) {
+			fields[fmt.Sprintf("le_%s", latencyBuckets[i])] = count
+		} else {
+			fields[fmt.Sprintf("gt_%s", latencyBuckets[len(latencyBuckets)-1])] = count
+		}
+	}
+	return fields
+}
+
+// metricsContext registers the metrics log subsystem on ctx.
+func metricsContext(ctx context.Context) context.Context {
+	return tflog.NewSubsystem(ctx, metricsSubsystem, tflog.WithLevelFromEnv("TF_LOG_PROVIDER_GOPASS", metricsSubsystem))
+}
+
+// logOperation emits a debug line for a single operation on the metrics subsystem.
+func logOperation(ctx context.Context, kind string, duration time.Duration, err error) {
+	fields := map[string]interface{}{
+		"operation":   kind,
+		"duration_ms": duration.Milliseconds(),
+		"success":     err == nil,
+	}
+	tflog.SubsystemDebug(metricsContext(ctx), metricsSubsystem, "gopass operation completed", fields)
+}
+
+// logSummary writes the aggregated statistics to the metrics subsystem.
+func (m *clientMetrics) logSummary(ctx context.Context) {
+	ctx = metricsContext(ctx)
+	ops, cacheHits, retries := m.snapshot()
+
+	var totalTime time.Duration
+	kinds := make([]string, 0, len(ops))
+	for kind, stats := range ops {
+		kinds = append(kinds, kind)
+		totalTime += stats.Total
+	}
+	sort.Strings(kinds)
+
+	tflog.SubsystemInfo(ctx, metricsSubsystem, "gopass secret resolution summary", map[string]interface{}{
+		"total_time_ms": totalTime.Milliseconds(),
+		"cache_hits":    cacheHits,
+		"coalesced":     m.coalescedReads(),
+		"retries":       retries,
+	})
+
+	for _, kind := range kinds {
+		stats := ops[kind]
+		fields := map[string]interface{}{
+			"operation": kind,
+			"count":     stats.Count,
+			"errors":    stats.Errors,
+			"total_ms":  stats.Total.Milliseconds(),
+			"avg_ms":    (stats.Total / time.Duration(stats.Count)).Milliseconds(),
+			"max_ms":    stats.Max.Milliseconds(),
+		}
+		for key, value := range histogramFields(stats.Buckets) {
+			fields[key] = value
+		}
+		tflog.SubsystemInfo(ctx, metricsSubsystem, "gopass operation statistics", fields)
+	}
+}
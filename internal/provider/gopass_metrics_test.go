@@ -0,0 +1,156 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestClientMetrics_Observe(t *testing.T) {
+	m := newClientMetrics()
+
+	m.observe(opRead, 5*time.Millisecond, nil)
+	m.observe(opRead, 200*time.Millisecond, nil)
+	m.observe(opRead, time.Minute, errors.New("boom"))
+
+	ops, _, _ := m.snapshot()
+	stats, ok := ops[opRead]
+	if !ok {
+		t.Fatal("expected read statistics")
+	}
+
+	if stats.Count != 3 {
+		t.Errorf("expected count 3, got %d", stats.Count)
+	}
+	if stats.Errors != 1 {
+		t.Errorf("expected 1 error, got %d", stats.Errors)
+	}
+	if stats.Max != time.Minute {
+		t.Errorf("expected max 1m, got %s", stats.Max)
+	}
+
+	// 5ms -> first bucket, 200ms -> "500ms" bucket, 1m -> overflow
+	if stats.Buckets[0] != 1 || stats.Buckets[2] != 1 || stats.Buckets[len(latencyBuckets)] != 1 {
+		t.Errorf("unexpected histogram %v", stats.Buckets)
+	}
+}
+
+func TestClientMetrics_SnapshotIsCopy(t *testing.T) {
+	m := newClientMetrics()
+	m.observe(opList, time.Millisecond, nil)
+
+	ops, _, _ := m.snapshot()
+	ops[opList].Buckets[0] = 42
+
+	again, _, _ := m.snapshot()
+	if again[opList].Buckets[0] != 1 {
+		t.Error("expected snapshot to be independent of internal state")
+	}
+}
+
+func TestHistogramFields(t *testing.T) {
+	buckets := make([]int64, len(latencyBuckets)+1)
+	buckets[1] = 2
+	buckets[len(latencyBuckets)] = 1
+
+	fields := histogramFields(buckets)
+	if fields["le_100ms"] != int64(2) {
+		t.Errorf("expected le_100ms=2, got %v", fields)
+	}
+	if fields["gt_30s"] != int64(1) {
+		t.Errorf("expected gt_30s=1, got %v", fields)
+	}
+	if len(fields) != 2 {
+		t.Errorf("expected empty buckets to be omitted, got %v", fields)
+	}
+}
+
+func TestGopassClient_Metrics_RecordsOperations(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newPrefetchTestStore()
+	client.store = mockStore
+	client.prefetch = newPrefetcher([]string{"app/db/password"})
+
+	ctx := context.Background()
+
+	// First read triggers the prefetch (one decryption), second is a cache hit
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetSecret(ctx, "app/db/password"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := client.ListSecrets(ctx, "app/env"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ops, cacheHits, _ := client.metrics.snapshot()
+	if ops[opRead].Count != 1 {
+		t.Errorf("expected 1 decryption, got %d", ops[opRead].Count)
+	}
+	if ops[opList].Count != 1 {
+		t.Errorf("expected 1 list, got %d", ops[opList].Count)
+	}
+	if cacheHits != 2 {
+		t.Errorf("expected 2 cache hits, got %d", cacheHits)
+	}
+}
+
+func TestGopassClient_Metrics_CountsRetries(t *testing.T) {
+	mockStore := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failures:  2,
+		failErr:   errors.New("card busy"),
+	}
+	secret := secrets.New()
+	secret.SetPassword("value")
+	mockStore.secrets["test/secret"] = secret
+
+	client, _ := newRetryTestClient(mockStore)
+
+	if _, err := client.GetSecret(context.Background(), "test/secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, _, retries := client.metrics.snapshot()
+	if retries != 2 {
+		t.Errorf("expected 2 retries, got %d", retries)
+	}
+}
+
+func TestGopassClient_Close_LogsMetricsSummary(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	client := NewGopassClient("")
+	client.store = newMockStore()
+	client.metricsSummary = true
+	client.metrics.observe(opRead, 10*time.Millisecond, nil)
+
+	client.Close(ctx)
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("failed to decode log output: %v", err)
+	}
+
+	found := false
+	for _, entry := range entries {
+		if entry["@message"] == "gopass operation statistics" && entry["operation"] == opRead {
+			found = true
+			if entry["@module"] != "provider."+metricsSubsystem {
+				t.Errorf("expected metrics subsystem module, got %v", entry["@module"])
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected read statistics in summary, got %v", entries)
+	}
+}
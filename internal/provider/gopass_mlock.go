@@ -0,0 +1,76 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// errMlockUnsupported is returned by allocLocked on platforms without mlock.
+var errMlockUnsupported = errors.New("locked memory is not supported on this platform")
+
+// lockedBuffer holds secret bytes in memory that is locked into RAM, so it is
+// never written to swap, and that is wiped when the buffer is freed. It lives
+// outside the Go heap, so the garbage collector never copies it either.
+type lockedBuffer struct {
+	mu   sync.Mutex
+	mem  []byte // whole locked allocation
+	size int    // bytes of mem in use
+}
+
+// newLockedBuffer copies data into a new locked buffer. It fails if the
+// platform cannot lock memory or the locked memory limit is reached.
+func newLockedBuffer(data []byte) (*lockedBuffer, error) {
+	mem, err := allocLocked(len(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate locked memory: %w", err)
+	}
+	copy(mem, data)
+	return &lockedBuffer{mem: mem, size: len(data)}, nil
+}
+
+// bytes returns a copy of the buffer contents on the regular heap, or nil
+// after free.
+func (b *lockedBuffer) bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.mem == nil {
+		return nil
+	}
+	data := make([]byte, b.size)
+	copy(data, b.mem)
+	return data
+}
+
+// secret parses the buffer contents into a secret on the regular heap, or
+// returns nil after free. The intermediate copy of the contents is wiped; the
+// parsed secret is a regular Go value, out of reach of locking and wiping.
+func (b *lockedBuffer) secret() gopass.Secret {
+	data := b.bytes()
+	if data == nil {
+		return nil
+	}
+	secret := parseSecret(data)
+	clear(data)
+	return secret
+}
+
+// free wipes the buffer and releases its memory. It is safe to call twice.
+func (b *lockedBuffer) free() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.mem == nil {
+		return
+	}
+	clear(b.mem)
+	freeLocked(b.mem)
+	b.mem = nil
+	b.size = 0
+}
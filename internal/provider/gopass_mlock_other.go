@@ -0,0 +1,14 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package provider
+
+// allocLocked always fails: this platform has no mlock.
+func allocLocked(n int) ([]byte, error) {
+	return nil, errMlockUnsupported
+}
+
+// freeLocked is never called on this platform.
+func freeLocked(mem []byte) {}
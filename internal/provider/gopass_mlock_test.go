@@ -0,0 +1,168 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newTestLockedBuffer skips the test where the environment cannot lock memory.
+func newTestLockedBuffer(t *testing.T, data []byte) *lockedBuffer {
+	t.Helper()
+	buf, err := newLockedBuffer(data)
+	if err != nil {
+		t.Skipf("locked memory unavailable: %v", err)
+	}
+	return buf
+}
+
+func TestLockedBuffer_RoundTripAndFree(t *testing.T) {
+	buf := newTestLockedBuffer(t, []byte("s3cret\nuser: admin\n"))
+
+	got := buf.bytes()
+	if string(got) != "s3cret\nuser: admin\n" {
+		t.Fatalf("unexpected contents %q", got)
+	}
+	got[0] = 'X'
+	if string(buf.bytes()) != "s3cret\nuser: admin\n" {
+		t.Error("expected bytes to return a copy")
+	}
+
+	buf.free()
+	buf.free()
+	if buf.bytes() != nil {
+		t.Error("expected no contents after free")
+	}
+}
+
+func TestLockedBuffer_Empty(t *testing.T) {
+	buf := newTestLockedBuffer(t, nil)
+	defer buf.free()
+
+	if got := buf.bytes(); got == nil || len(got) != 0 {
+		t.Errorf("expected empty, non-nil contents, got %v", got)
+	}
+}
+
+func TestGopassClient_Prefetch_SecureMemory(t *testing.T) {
+	ctx := context.Background()
+	store := newPrefetchTestStore()
+	if err := store.Set(ctx, "app/db/password", newSecret("db-pass", map[string]string{"user": "admin"})); err != nil {
+		t.Fatal(err)
+	}
+	client := NewGopassClientWithStore(store)
+	client.prefetch = newPrefetcher([]string{"app/db/password", "app/env/"})
+	client.prefetch.secure = true
+
+	password, fields, err := client.GetSecretFull(ctx, "app/db/password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "db-pass" || fields["user"] != "admin" {
+		t.Errorf("unexpected secret %q %v", password, fields)
+	}
+	if store.readCount("app/db/password") != 1 {
+		t.Error("expected the secret to be served from the prefetch cache")
+	}
+
+	warnings := client.takeWarnings()
+	if len(client.prefetch.locked) == 0 {
+		// Locking is not possible here, so the cache falls back with a warning
+		if len(warnings) != 1 || warnings[0].Summary() != "Secure memory unavailable" {
+			t.Fatalf("expected a fallback warning, got %v", warnings)
+		}
+		t.Skip("locked memory unavailable, fallback verified")
+	}
+
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if len(client.prefetch.secrets) != 0 {
+		t.Errorf("expected no secrets in regular memory, got %d", len(client.prefetch.secrets))
+	}
+
+	buf := client.prefetch.locked["app/env/KEY1"]
+	client.prefetch.forgetPath("app/env/KEY1")
+	if buf.bytes() != nil || client.prefetch.locked["app/env/KEY1"] != nil {
+		t.Error("expected forgetPath to free the locked buffer")
+	}
+
+	buf = client.prefetch.locked["app/db/password"]
+	client.prefetch.forget()
+	if buf.bytes() != nil || len(client.prefetch.locked) != 0 {
+		t.Error("expected forget to free every locked buffer")
+	}
+}
+
+func TestSecretBuffers_Secure(t *testing.T) {
+	buffers := &secretBuffers{secure: true}
+	value := buffers.protect("s3cret")
+	if buffers.lockErr != nil {
+		t.Skipf("locked memory unavailable: %v", buffers.lockErr)
+	}
+	if value != "s3cret" {
+		t.Fatalf("unexpected protected value %q", value)
+	}
+	buf := buffers.buffers[0]
+	if !buf.locked {
+		t.Fatal("expected the value in locked memory")
+	}
+
+	if n := buffers.wipe(); n != 1 {
+		t.Errorf("expected 1 wiped buffer, got %d", n)
+	}
+	if buf.data != nil || buf.String() != "" {
+		t.Error("expected the locked memory to be released")
+	}
+}
+
+func TestSecretBuffers_SecureFallback(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newLifecycleTestClient()
+	defer client.Close(ctx)
+
+	// Where memory cannot be locked, values stay readable in regular memory
+	buffers := &secretBuffers{secure: true, lockErr: errMlockUnsupported}
+	if value := buffers.protect("s3cret"); value != "s3cret" {
+		t.Fatalf("unexpected protected value %q", value)
+	}
+
+	for range 2 {
+		if diags := client.openLease(ctx, mockPrivateState{}, buffers); diags.HasError() {
+			t.Fatalf("openLease() error = %v", diags)
+		}
+	}
+	warnings := client.takeWarnings()
+	if warnings.WarningsCount() != 1 || warnings[0].Summary() != "Secure memory unavailable" {
+		t.Errorf("expected one secure memory warning, got %v", warnings)
+	}
+}
+
+func TestProviderConfigure_SecureMemory(t *testing.T) {
+	p := &GopassProvider{version: "test"}
+	resp := &provider.ConfigureResponse{}
+	p.Configure(context.Background(), provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"prefetch_paths": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+				tftypes.NewValue(tftypes.String, "app/db"),
+			}),
+			"secure_memory": tftypes.NewValue(tftypes.Bool, true),
+		}),
+	}, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	client := resp.EphemeralResourceData.(*GopassClient)
+	if !client.prefetch.secure {
+		t.Error("expected the prefetch cache to use locked memory")
+	}
+	if !client.newSecretBuffers().secure {
+		t.Error("expected ephemeral results to use locked memory")
+	}
+}
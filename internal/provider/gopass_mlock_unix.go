@@ -0,0 +1,35 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package provider
+
+import (
+	"os"
+	"syscall"
+)
+
+// allocLocked maps anonymous memory for at least n bytes and locks it into RAM.
+func allocLocked(n int) ([]byte, error) {
+	pageSize := os.Getpagesize()
+	size := max(pageSize, (n+pageSize-1)/pageSize*pageSize)
+
+	mem, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Mlock(mem); err != nil {
+		// Usually RLIMIT_MEMLOCK, see ulimit -l
+		_ = syscall.Munmap(mem)
+		return nil, err
+	}
+	return mem[:n], nil
+}
+
+// freeLocked unlocks and unmaps memory from allocLocked. The caller wipes it.
+func freeLocked(mem []byte) {
+	mem = mem[:cap(mem)]
+	_ = syscall.Munlock(mem)
+	_ = syscall.Munmap(mem)
+}
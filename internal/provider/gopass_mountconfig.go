@@ -0,0 +1,152 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/appdir"
+	"github.com/gopasspw/gopass/pkg/gitconfig"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// The gopass configuration lists the mounted stores of the root store as
+// "mounts.<prefix>.path" entries, like "gopass mounts add" writes them. The
+// provider's own mounts argument is separate: it mounts stores for the
+// provider alone, without touching the configuration.
+
+// gopassConfigFile returns the gopass configuration file: below home_dir if
+// set, otherwise where gopass looks for it.
+func (c *GopassClient) gopassConfigFile() (string, error) {
+	if c.wsl != nil || c.storeFormat != "" {
+		return "", errors.New("the gopass configuration is only managed for local gopass stores, " +
+			"not with wsl or a pass or passage store_format")
+	}
+	if c.homeDir != "" {
+		home, err := c.expandHome(c.homeDir)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".config", "gopass", "config"), nil
+	}
+	return filepath.Join(appdir.UserConfig(), "config"), nil
+}
+
+// mountConfigKey returns the configuration key of the mount at prefix.
+func mountConfigKey(prefix string) string {
+	return "mounts." + prefix + ".path"
+}
+
+// validateMountPrefix returns why prefix cannot name a gopass mount, if so.
+func validateMountPrefix(prefix string) error {
+	switch {
+	case prefix == "":
+		return errors.New("prefix must not be empty, the root store is not a mount")
+	case prefix != normalizePath(prefix):
+		return fmt.Errorf("prefix %q must not have leading, trailing or repeated slashes", prefix)
+	case strings.ContainsAny(prefix, "\"\\\n\r\t ") || strings.HasPrefix(prefix, "."):
+		return fmt.Errorf("prefix %q must not contain quotes, backslashes or whitespace, nor start with a dot", prefix)
+	}
+	return nil
+}
+
+// loadGopassConfig loads the gopass configuration file.
+func (c *GopassClient) loadGopassConfig() (*gitconfig.Config, error) {
+	file, err := c.gopassConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := gitconfig.LoadConfig(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no gopass configuration at %s; set up gopass with gopass setup first", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the gopass configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// ConfiguredMount returns the directory the gopass configuration mounts at
+// prefix, and false if it mounts nothing there.
+func (c *GopassClient) ConfiguredMount(prefix string) (string, bool, error) {
+	cfg, err := c.loadGopassConfig()
+	if err != nil {
+		return "", false, err
+	}
+	dir, ok := cfg.Get(mountConfigKey(prefix))
+	return dir, ok, nil
+}
+
+// SetConfiguredMount mounts the store in dir at prefix in the gopass
+// configuration, or moves the mount at prefix to dir. dir must hold an
+// initialized store.
+func (c *GopassClient) SetConfiguredMount(ctx context.Context, prefix, dir string) error {
+	if err := c.checkWritable(fmt.Sprintf("mount %q", prefix)); err != nil {
+		return err
+	}
+	if err := validateMountPrefix(prefix); err != nil {
+		return err
+	}
+	expanded, err := c.expandHome(dir)
+	if err != nil {
+		return err
+	}
+	initialized := false
+	for _, name := range recipientFileNames {
+		if _, err := os.Stat(filepath.Join(expanded, name)); err == nil {
+			initialized = true
+		}
+	}
+	if !initialized {
+		return fmt.Errorf("no initialized gopass store in %s: it has no %s file. "+
+			"Initialize it with gopass init --store %s first", expanded, strings.Join(recipientFileNames, " or "), prefix)
+	}
+
+	c.configEdits.Lock()
+	defer c.configEdits.Unlock()
+
+	cfg, err := c.loadGopassConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Set(mountConfigKey(prefix), dir); err != nil {
+		return fmt.Errorf("failed to write the gopass configuration: %w", err)
+	}
+	tflog.Info(ctx, "Mounted store in the gopass configuration", map[string]interface{}{
+		"prefix": prefix,
+		"path":   dir,
+	})
+	// The open store handle still has the old mounts
+	c.Invalidate(ctx)
+	return nil
+}
+
+// RemoveConfiguredMount unmounts the store at prefix from the gopass
+// configuration. The store itself is left alone.
+func (c *GopassClient) RemoveConfiguredMount(ctx context.Context, prefix string) error {
+	if err := c.checkWritable(fmt.Sprintf("unmount %q", prefix)); err != nil {
+		return err
+	}
+
+	c.configEdits.Lock()
+	defer c.configEdits.Unlock()
+
+	cfg, err := c.loadGopassConfig()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Unset(mountConfigKey(prefix)); err != nil {
+		return fmt.Errorf("failed to write the gopass configuration: %w", err)
+	}
+	tflog.Info(ctx, "Unmounted store in the gopass configuration", map[string]interface{}{
+		"prefix": prefix,
+	})
+	c.Invalidate(ctx)
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+)
+
+// newSubstore creates an initialized, empty age store to mount.
+func newSubstore(t *testing.T, recipient string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".age-recipients"), []byte(recipient+"\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return dir
+}
+
+func TestValidateMountPrefix(t *testing.T) {
+	for _, prefix := range []string{"team", "team/ops", "team-1"} {
+		if err := validateMountPrefix(prefix); err != nil {
+			t.Errorf("expected %q to be valid, got %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"", "/team", "team/", "team//ops", "my team", "te\"am", ".hidden"} {
+		if err := validateMountPrefix(prefix); err == nil {
+			t.Errorf("expected %q to be refused", prefix)
+		}
+	}
+}
+
+func TestConfiguredMount_Lifecycle(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	client := NewGopassClient(store.Dir)
+	dir := newSubstore(t, store.Recipient)
+
+	if _, ok, err := client.ConfiguredMount("team"); err != nil || ok {
+		t.Fatalf("expected no mount before it is added, got %v, %v", ok, err)
+	}
+	if err := client.SetConfiguredMount(context.Background(), "team", dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, ok, err := client.ConfiguredMount("team"); err != nil || !ok || got != dir {
+		t.Errorf("expected %q to be mounted, got %q, %v, %v", dir, got, ok, err)
+	}
+	config := readFile(t, filepath.Join(store.Home, ".config", "gopass", "config"))
+	if !strings.Contains(config, `[mounts "team"]`) || !strings.Contains(config, "autoimport = false") {
+		t.Errorf("expected the mount to be added to the configuration, got:\n%s", config)
+	}
+
+	if err := client.RemoveConfiguredMount(context.Background(), "team"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, err := client.ConfiguredMount("team"); err != nil || ok {
+		t.Errorf("expected the mount to be removed, got %v, %v", ok, err)
+	}
+	if config := readFile(t, filepath.Join(store.Home, ".config", "gopass", "config")); !strings.Contains(config, "path = "+store.Dir) {
+		t.Errorf("expected the root store to stay configured, got:\n%s", config)
+	}
+}
+
+func TestConfiguredMount_ReadThroughNewMount(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret"})
+	client := NewGopassClient(store.Dir)
+	t.Cleanup(func() { client.Close(context.Background()) })
+	dir := newSubstore(t, store.Recipient)
+	ctx := context.Background()
+
+	// Open the store before the mount exists
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.SetConfiguredMount(ctx, "team", dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store.Set("team/api", "t0ken")
+	if _, err := os.Stat(filepath.Join(dir, "api.age")); err != nil {
+		t.Fatalf("expected the secret to be written to the mounted store: %v", err)
+	}
+
+	if value, err := client.GetSecret(ctx, "team/api"); err != nil || value != "t0ken" {
+		t.Errorf("expected to read through the new mount, got %q (%v)", value, err)
+	}
+
+	if err := client.RemoveConfiguredMount(ctx, "team"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "team/api"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the unmounted secret to be gone, got %v", err)
+	}
+}
+
+func TestSetConfiguredMount_Uninitialized(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	client := NewGopassClient(store.Dir)
+
+	err := client.SetConfiguredMount(context.Background(), "team", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "no initialized gopass store") {
+		t.Errorf("expected an uninitialized store to be refused, got %v", err)
+	}
+}
+
+func TestSetConfiguredMount_ReadOnly(t *testing.T) {
+	client := NewGopassClient(t.TempDir())
+	client.readOnly = true
+
+	if err := client.SetConfiguredMount(context.Background(), "team", t.TempDir()); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected a read-only provider to refuse, got %v", err)
+	}
+	if err := client.RemoveConfiguredMount(context.Background(), "team"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected a read-only provider to refuse, got %v", err)
+	}
+}
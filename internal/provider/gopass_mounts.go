@@ -0,0 +1,181 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// mount is a store mounted below a path prefix, with its own lazily opened
+// handle. Reads below the prefix are routed to it without ever touching the
+// root store, and opening one mount never blocks reads from another.
+type mount struct {
+	prefix string // always ends in "/"
+	open   func(ctx context.Context) (SecretStore, error)
+	dir    string // store directory, empty for stores not read from disk
+
+	mu    sync.Mutex
+	store SecretStore // prefixedStore around the opened handle
+}
+
+// addMount routes every path below prefix to the store returned by open.
+// Paths handed to that store are relative to the prefix. It returns the new mount.
+func (c *GopassClient) addMount(prefix string, open func(ctx context.Context) (SecretStore, error)) *mount {
+	prefix = normalizePrefix(prefix)
+	m := &mount{prefix: prefix, open: open}
+	c.mounts = append(c.mounts, m)
+
+	// Longest prefix first, so nested mounts win over their parents
+	sort.SliceStable(c.mounts, func(i, j int) bool {
+		return len(c.mounts[i].prefix) > len(c.mounts[j].prefix)
+	})
+	return m
+}
+
+// mountFor returns the mount owning path, or nil if path belongs to the root store.
+func (c *GopassClient) mountFor(path string) *mount {
+	for _, m := range c.mounts {
+		if withinPrefix(path, m.prefix) {
+			return m
+		}
+	}
+	return nil
+}
+
+// storeFor returns the store responsible for path and takes a reference on it,
+// like getStore. Paths below a mount go to the mount's own handle. Malformed
+// paths are refused with ErrInvalidPath.
+func (c *GopassClient) storeFor(ctx context.Context, path string) (store SecretStore, release func(), err error) {
+	if err := validateSecretPath(path); err != nil {
+		return nil, nil, err
+	}
+	m := c.mountFor(path)
+	if m == nil {
+		return c.getStore(ctx)
+	}
+	return c.mountedStore(ctx, m)
+}
+
+// mountedStore returns the store of the mount m, like storeFor.
+func (c *GopassClient) mountedStore(ctx context.Context, m *mount) (store SecretStore, release func(), err error) {
+	c.retain()
+	release = func() { c.release(ctx) }
+
+	store, err = c.mountStore(ctx, m)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return store, release, nil
+}
+
+// mountStore returns the mount's handle, opening it on first use.
+func (c *GopassClient) mountStore(ctx context.Context, m *mount) (SecretStore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.store != nil {
+		return m.store, nil
+	}
+
+	tflog.Debug(ctx, "Initializing mounted gopass store", map[string]interface{}{
+		"mount": m.prefix,
+	})
+
+	op := operation{kind: opInit, path: m.prefix, desc: fmt.Sprintf("initialization of store mounted at %q", m.prefix), timeout: c.timeouts.Init}
+	inner, err := call(ctx, c, op, m.open)
+	if err != nil && errors.Is(err, api.ErrNotInitialized) {
+		return nil, classify(ErrStoreUninitialized, fmt.Errorf("mount %q: no initialized gopass store in the "+
+			"mounted directory: %w\n\nCheck that the directory in the provider's mounts points at the root "+
+			"of a store, i.e. the directory that contains its .gpg-id file.", normalizePath(m.prefix), err))
+	}
+	if err != nil {
+		return nil, classify(ErrStoreUninitialized,
+			fmt.Errorf("mount %q: %w", normalizePath(m.prefix), c.wrapStoreError(err)))
+	}
+
+	dir := ""
+	if m.dir != "" && c.wsl == nil {
+		dir, _ = c.expandHome(m.dir)
+	}
+	if err := c.syncStore(ctx, fmt.Sprintf("the store mounted at %q", m.prefix), dir, inner); err != nil {
+		closeHandle(ctx, inner)
+		return nil, err
+	}
+
+	m.store = &prefixedStore{prefix: m.prefix, inner: c.cassette.wrap(c.faults.wrap(inner), m.prefix)}
+	registerClient(c)
+	return m.store, nil
+}
+
+// detachMounts clears all opened mount handles and returns them for closing.
+func (c *GopassClient) detachMounts() []SecretStore {
+	var stores []SecretStore
+	for _, m := range c.mounts {
+		m.mu.Lock()
+		if m.store != nil {
+			stores = append(stores, m.store)
+			m.store = nil
+		}
+		m.mu.Unlock()
+	}
+	return stores
+}
+
+// prefixedStore presents a store's secrets below a mount prefix.
+type prefixedStore struct {
+	prefix string
+	inner  SecretStore
+}
+
+// key returns the path of name in the mounted store.
+func (p *prefixedStore) key(name string) string {
+	key, _ := relativeKey(name, p.prefix)
+	return key
+}
+
+func (p *prefixedStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	return p.inner.Get(ctx, p.key(name), revision)
+}
+
+func (p *prefixedStore) List(ctx context.Context) ([]string, error) {
+	names, err := p.inner.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefixed := make([]string, len(names))
+	for i, name := range names {
+		prefixed[i] = joinPath(p.prefix, name)
+	}
+	return prefixed, nil
+}
+
+func (p *prefixedStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	return p.inner.Set(ctx, p.key(name), sec)
+}
+
+func (p *prefixedStore) Remove(ctx context.Context, name string) error {
+	return p.inner.Remove(ctx, p.key(name))
+}
+
+func (p *prefixedStore) Revisions(ctx context.Context, name string) ([]string, error) {
+	return p.inner.Revisions(ctx, p.key(name))
+}
+
+// Close closes the mounted store if it supports closing.
+func (p *prefixedStore) Close(ctx context.Context) error {
+	if closer, ok := p.inner.(interface{ Close(context.Context) error }); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+)
+
+// newMountTestClient returns a client with a root store and a store mounted at "team"
+func newMountTestClient(t *testing.T) (client *GopassClient, root *mockClosingStore, team *mockClosingStore) {
+	t.Helper()
+
+	root = &mockClosingStore{SecretStore: NewMemoryStore(map[string]string{
+		"app/token":     "root-app/token",
+		"team/shadowed": "root-team/shadowed",
+	})}
+	team = &mockClosingStore{SecretStore: NewMemoryStore(map[string]string{
+		"db/password": "team-db/password",
+		"db/user":     "team-db/user",
+		"api":         "team-api",
+	})}
+
+	client = NewGopassClientWithStore(root)
+	client.addMount("team", func(ctx context.Context) (SecretStore, error) { return team, nil })
+	return client, root, team
+}
+
+func TestGopassClient_Mounts_RoutesReads(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMountTestClient(t)
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		return nil, errors.New("root store must not be opened")
+	}
+
+	value, err := client.GetSecret(ctx, "team/db/password")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if value != "team-db/password" {
+		t.Errorf("expected value from mounted store, got %q", value)
+	}
+}
+
+func TestGopassClient_Mounts_ListAndEnv(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMountTestClient(t)
+
+	paths, err := client.ListSecrets(ctx, "team/db")
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if fmt.Sprint(paths) != "[team/db/password team/db/user]" {
+		t.Errorf("unexpected listing %v", paths)
+	}
+
+	values, err := client.GetEnvSecrets(ctx, "team/db")
+	if err != nil {
+		t.Fatalf("GetEnvSecrets() error = %v", err)
+	}
+	if values["user"] != "team-db/user" || len(values) != 2 {
+		t.Errorf("unexpected values %v", values)
+	}
+}
+
+func TestGopassClient_Mounts_WalkAll(t *testing.T) {
+	ctx := context.Background()
+	client, _, _ := newMountTestClient(t)
+
+	var walked []string
+	err := client.WalkSecrets(ctx, "", func(path string) error {
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkSecrets() error = %v", err)
+	}
+
+	// The root store's own "team/" entries are shadowed by the mount
+	want := "[app/token team/api team/db/password team/db/user]"
+	if fmt.Sprint(walked) != want {
+		t.Errorf("expected %s, got %v", want, walked)
+	}
+}
+
+func TestGopassClient_Mounts_Nested(t *testing.T) {
+	ctx := context.Background()
+	client, _, team := newMountTestClient(t)
+	if err := team.Set(ctx, "infra/shadowed", newPasswordSecret("team-shadowed")); err != nil {
+		t.Fatal(err)
+	}
+
+	infra := NewMemoryStore(map[string]string{"key": "infra-key"})
+	client.addMount("team/infra/", func(ctx context.Context) (SecretStore, error) { return infra, nil })
+
+	value, err := client.GetSecret(ctx, "team/infra/key")
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if value != "infra-key" {
+		t.Errorf("expected nested mount to win, got %q", value)
+	}
+
+	var walked []string
+	err = client.WalkSecrets(ctx, "team", func(path string) error {
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkSecrets() error = %v", err)
+	}
+	want := "[team/api team/db/password team/db/user team/infra/key]"
+	if fmt.Sprint(walked) != want {
+		t.Errorf("expected %s, got %v", want, walked)
+	}
+}
+
+func TestGopassClient_Mounts_Writes(t *testing.T) {
+	ctx := context.Background()
+	client, root, team := newMountTestClient(t)
+
+	if err := client.SetSecret(ctx, "team/new", "value"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	if _, err := team.Get(ctx, "new", "latest"); err != nil {
+		t.Error("expected secret written to the mounted store under its relative path")
+	}
+	if _, err := root.Get(ctx, "team/new", "latest"); err == nil {
+		t.Error("expected root store to be untouched")
+	}
+
+	if err := client.RemoveSecret(ctx, "team/api"); err != nil {
+		t.Fatalf("RemoveSecret() error = %v", err)
+	}
+	if _, err := team.Get(ctx, "api", "latest"); err == nil {
+		t.Error("expected secret removed from the mounted store")
+	}
+}
+
+func TestGopassClient_Mounts_CloseAndInvalidate(t *testing.T) {
+	ctx := context.Background()
+	client, root, team := newMountTestClient(t)
+
+	if _, err := client.GetSecret(ctx, "team/api"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	client.Invalidate(ctx)
+	if team.closed.Load() != 1 {
+		t.Errorf("expected mounted store closed on Invalidate, got %d", team.closed.Load())
+	}
+
+	if _, err := client.GetSecret(ctx, "team/api"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "app/token"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	client.Close(ctx)
+
+	if team.closed.Load() != 2 || root.closed.Load() != 1 {
+		t.Errorf("expected all stores closed, got team=%d root=%d", team.closed.Load(), root.closed.Load())
+	}
+}
+
+func TestGopassClient_Mounts_OpenError(t *testing.T) {
+	ctx := context.Background()
+	client := NewGopassClient("")
+	client.retry.MaxAttempts = 1
+	client.addMount("broken", func(ctx context.Context) (SecretStore, error) {
+		return nil, errors.New("permission denied")
+	})
+
+	_, err := client.GetSecret(ctx, "broken/secret")
+	if err == nil {
+		t.Fatal("expected error for unopenable mount")
+	}
+	if !strings.Contains(err.Error(), `mount "broken"`) || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected mount name and guidance in error, got %v", err)
+	}
+	if client.references() != 0 {
+		t.Errorf("expected reference released on error, got %d", client.references())
+	}
+}
+
+func TestGopassClient_Mounts_NotInitialized(t *testing.T) {
+	client := NewGopassClient("")
+	client.addMount("team", func(ctx context.Context) (SecretStore, error) {
+		return nil, api.ErrNotInitialized
+	})
+
+	_, err := client.GetSecret(context.Background(), "team/secret")
+	if !errors.Is(err, ErrStoreUninitialized) || !errors.Is(err, api.ErrNotInitialized) {
+		t.Fatalf("expected uninitialized store error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `mount "team"`) || !strings.Contains(err.Error(), "mounts") {
+		t.Errorf("expected mount name and guidance in error, got %v", err)
+	}
+}
+
+func TestGopassClient_GopassStoreAt(t *testing.T) {
+	t.Setenv("PASSWORD_STORE_DIR", "/root/store")
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	client := NewGopassClient("")
+	var seen string
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		seen = os.Getenv("PASSWORD_STORE_DIR")
+		return newMockStore(), nil
+	}
+
+	if _, err := client.gopassStoreAt(dir)(ctx); err != nil {
+		t.Fatalf("opener error = %v", err)
+	}
+	if seen != dir {
+		t.Errorf("expected store opened with PASSWORD_STORE_DIR=%q, got %q", dir, seen)
+	}
+	if got := os.Getenv("PASSWORD_STORE_DIR"); got != "/root/store" {
+		t.Errorf("expected PASSWORD_STORE_DIR restored, got %q", got)
+	}
+
+	if _, err := client.gopassStoreAt(dir + "/missing")(ctx); err == nil {
+		t.Error("expected error for missing store directory")
+	}
+}
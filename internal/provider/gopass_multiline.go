@@ -0,0 +1,56 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// A secret read for its password alone triggers a warning if it has at least
+// this many keys or non-empty lines after the password. A URL or username
+// line next to the password is common enough not to warn about.
+const (
+	discardedKeysThreshold  = 2
+	discardedLinesThreshold = 3
+)
+
+// discardedContent returns what reading only the password of secret leaves
+// out: the number of non-empty lines after the first, and the secret's keys.
+func discardedContent(secret gopass.Secret) (lines int, keys []string) {
+	_, body, _ := bytes.Cut(secret.Bytes(), []byte("\n"))
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines++
+		}
+	}
+
+	keys = secret.Keys()
+	sort.Strings(keys)
+	return lines, keys
+}
+
+// warnDiscardedContent queues a warning, once per path, if secret holds
+// substantial content besides the password that a read of path drops.
+// Users often do not notice that everything after the first line is lost.
+func (c *GopassClient) warnDiscardedContent(path string, secret gopass.Secret) {
+	lines, keys := discardedContent(secret)
+	if len(keys) < discardedKeysThreshold && lines < discardedLinesThreshold {
+		return
+	}
+
+	detail := fmt.Sprintf("The secret at %q has %d more line(s) after the password", path, lines)
+	if len(keys) > 0 {
+		detail += fmt.Sprintf(", including the key(s) %s", strings.Join(quoteAll(keys), ", "))
+	}
+	detail += ". Only the first line is available as the secret's value; the rest is dropped. " +
+		"If Terraform needs those values, store them as separate entries, e.g. one per key " +
+		"under a common path read with gopass_env."
+
+	c.warnings.addOnce("discarded:"+path, "Secret has content beyond the first line", detail)
+}
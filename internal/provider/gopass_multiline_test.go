@@ -0,0 +1,92 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// multiLineTestEntries holds secrets with fields, free text, or both.
+var multiLineTestEntries = map[string]string{
+	"app/db":    "s3cret\nuser: admin\nhost: db.internal\nport: 5432\n",
+	"app/notes": "s3cret\nfirst note\nsecond note\n\nthird note\n",
+	"app/web":   "s3cret\nurl: https://example.com\n",
+}
+
+func TestDiscardedContent(t *testing.T) {
+	lines, keys := discardedContent(secrets.ParseAKV([]byte("s3cret\nuser: admin\nhost: db\n\nfree text\n")))
+	if lines != 3 {
+		t.Errorf("expected 3 non-empty lines, got %d", lines)
+	}
+	if !slices.Equal(keys, []string{"host", "user"}) {
+		t.Errorf("expected sorted keys, got %v", keys)
+	}
+
+	lines, keys = discardedContent(secrets.ParseAKV([]byte("s3cret")))
+	if lines != 0 || len(keys) != 0 {
+		t.Errorf("expected nothing discarded, got %d lines and keys %v", lines, keys)
+	}
+}
+
+func TestGetSecret_WarnsAboutDiscardedContent(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(multiLineTestEntries))
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	diags := client.takeWarnings()
+	if len(diags) != 1 {
+		t.Fatalf("expected one warning for repeated reads, got %v", diags)
+	}
+	if diags[0].Summary() != "Secret has content beyond the first line" {
+		t.Errorf("unexpected summary %q", diags[0].Summary())
+	}
+	for _, want := range []string{`"app/db"`, `"host", "port", "user"`, "gopass_env"} {
+		if !strings.Contains(diags[0].Detail(), want) {
+			t.Errorf("expected %q in detail, got %q", want, diags[0].Detail())
+		}
+	}
+}
+
+func TestGetSecret_WarnsAboutLongBody(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(multiLineTestEntries))
+
+	if _, err := client.GetSecret(context.Background(), "app/notes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diags := client.takeWarnings(); len(diags) != 1 {
+		t.Errorf("expected a warning for a long body, got %v", diags)
+	}
+}
+
+func TestGetSecret_NoWarningForShortBody(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(multiLineTestEntries))
+
+	if _, err := client.GetSecret(context.Background(), "app/web"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diags := client.takeWarnings(); len(diags) != 0 {
+		t.Errorf("expected no warning for a single url line, got %v", diags)
+	}
+}
+
+func TestSecretEphemeralResource_Open_WarnsAboutDiscardedContent(t *testing.T) {
+	resp := openSecretEphemeral(t, NewGopassClientWithStore(NewMemoryStore(multiLineTestEntries)), "app/db")
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if resp.Diagnostics.WarningsCount() != 1 {
+		t.Errorf("expected the warning on the resource, got %v", resp.Diagnostics)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// netrcEntry is one machine of a .netrc file.
+type netrcEntry struct {
+	machine, login, password string
+}
+
+// renderNetrc returns the .netrc lines of entries, "machine m login l password
+// p", each followed by a newline. An empty login or password is left out.
+//
+// .netrc has no escaping that curl, git, Python and inetutils all agree on, so
+// values that would need it are refused: whitespace, which separates tokens,
+// and a leading quote, which some parsers take to start a quoted token. A
+// machine is required, since an entry without one would change the meaning of
+// the next.
+func renderNetrc(entries []netrcEntry) (string, error) {
+	var b strings.Builder
+	for i, e := range entries {
+		if e.machine == "" {
+			return "", fmt.Errorf("entry %d: machine is empty", i)
+		}
+		for _, token := range []struct{ name, value string }{
+			{"machine", e.machine}, {"login", e.login}, {"password", e.password},
+		} {
+			if token.value == "" {
+				continue
+			}
+			if strings.IndexFunc(token.value, isNetrcSpace) >= 0 {
+				return "", fmt.Errorf("entry %d: %s contains whitespace, which .netrc cannot represent", i, token.name)
+			}
+			if strings.HasPrefix(token.value, `"`) {
+				return "", fmt.Errorf("entry %d: %s starts with a quote, which .netrc parsers disagree on", i, token.name)
+			}
+			if token.name != "machine" {
+				b.WriteByte(' ')
+			}
+			b.WriteString(token.name)
+			b.WriteByte(' ')
+			b.WriteString(token.value)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// isNetrcSpace reports whether r separates .netrc tokens.
+func isNetrcSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f'
+}
@@ -0,0 +1,112 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+)
+
+func TestRenderNetrc(t *testing.T) {
+	got, err := renderNetrc([]netrcEntry{
+		{machine: "registry.example.com", login: "ci", password: `p#ss"word`},
+		{machine: "git.example.com", password: "token"},
+		{machine: "anon.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `machine registry.example.com login ci password p#ss"word` + "\n" +
+		"machine git.example.com password token\n" +
+		"machine anon.example.com\n"
+	if got != want {
+		t.Errorf("unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+
+	for _, tt := range []struct {
+		entry netrcEntry
+		want  string
+	}{
+		{netrcEntry{login: "ci", password: "token"}, "entry 1: machine is empty"},
+		{netrcEntry{machine: "h", password: "two words"}, "entry 1: password contains whitespace"},
+		{netrcEntry{machine: "h", login: "tab\tbed"}, "entry 1: login contains whitespace"},
+		{netrcEntry{machine: "h\n", password: "token"}, "entry 1: machine contains whitespace"},
+		{netrcEntry{machine: "h", password: `"quoted"`}, "entry 1: password starts with a quote"},
+	} {
+		_, err := renderNetrc([]netrcEntry{{machine: "ok"}, tt.entry})
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%+v: expected %q, got %v", tt.entry, tt.want, err)
+		}
+	}
+}
+
+func TestNetrcEphemeralResource_Metadata(t *testing.T) {
+	resp := &ephemeral.MetadataResponse{}
+	NewNetrcEphemeralResource().Metadata(context.Background(), ephemeral.MetadataRequest{ProviderTypeName: "gopass"}, resp)
+	if resp.TypeName != "gopass_netrc" {
+		t.Errorf("unexpected type name %q", resp.TypeName)
+	}
+}
+
+func TestNetrcEphemeralResource_Open(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"ci/registry": "r3gistry\nuser: ci",
+		"ci/git":      "t0ken\nhost: git.example.com\nmachine: git.internal\nlogin: bot",
+	}))
+
+	resp := openNetrcEphemeral(t, client, []map[string]string{
+		{"path": "ci/registry", "machine": "registry.example.com"},
+		{"path": "ci/git", "login": "deploy"},
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data NetrcModel
+	resp.Diagnostics.Append(resp.Result.Get(context.Background(), &data)...)
+	want := "machine registry.example.com login ci password r3gistry\n" +
+		"machine git.internal login deploy password t0ken\n"
+	if got := data.Content.ValueString(); got != want {
+		t.Errorf("unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNetrcEphemeralResource_Open_Errors(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"ci/nohost": "t0ken\nuser: ci",
+		"ci/spaced": "two words\nhost: example.com",
+	}))
+
+	tests := map[string]struct {
+		entries []map[string]string
+		summary string
+	}{
+		"not found":  {[]map[string]string{{"path": "ci/missing"}}, "Secret not found"},
+		"no entries": {[]map[string]string{}, "No entries"},
+		"no machine": {[]map[string]string{{"path": "ci/nohost"}}, "Invalid .netrc entry"},
+		"whitespace": {[]map[string]string{{"path": "ci/spaced"}}, "Invalid .netrc entry"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := openNetrcEphemeral(t, client, tt.entries)
+			errs := resp.Diagnostics.Errors()
+			if len(errs) != 1 || errs[0].Summary() != tt.summary {
+				t.Errorf("expected %q, got %v", tt.summary, resp.Diagnostics)
+			}
+			if strings.Contains(resp.Diagnostics[0].Detail(), "two words") {
+				t.Errorf("expected the password not to be disclosed, got %v", resp.Diagnostics)
+			}
+		})
+	}
+}
+
+// openNetrcEphemeral opens a gopass_netrc ephemeral resource for entries,
+// each a map of its configured attributes.
+func openNetrcEphemeral(t *testing.T, client *GopassClient, entries []map[string]string) *ephemeral.OpenResponse {
+	t.Helper()
+	return openEntriesEphemeral(t, &NetrcEphemeralResource{client: client}, []string{"path", "machine", "login"}, entries)
+}
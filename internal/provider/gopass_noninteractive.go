@@ -0,0 +1,69 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// nonInteractiveGPGOpts make gpg fail right away when a key needs a
+// passphrase or PIN that gpg-agent has not cached, instead of starting
+// pinentry.
+var nonInteractiveGPGOpts = []string{"--batch", "--no-tty", "--pinentry-mode=error"}
+
+// gopassGPGOptsEnv holds extra gpg options for gopass, which falls back to
+// pass' PASSWORD_STORE_GPG_OPTS if it is not set.
+const gopassGPGOptsEnv = "GOPASS_GPG_OPTS"
+
+// nonInteractiveSummary is the diagnostic summary for reads that failed in
+// non-interactive mode.
+const nonInteractiveSummary = "Interactive unlock required"
+
+// nonInteractiveHint explains how to get past a key that needs unlocking.
+const nonInteractiveHint = "The provider runs with non_interactive = true, so gpg was not allowed to ask for the " +
+	"passphrase or PIN of the key. Unlock the key in gpg-agent before the run, e.g. by decrypting any secret " +
+	"with \"gopass show\" or presetting the passphrase with gpg-preset-passphrase, or use a key without a " +
+	"passphrase for automation."
+
+// nonInteractiveError marks a decryption that failed in non-interactive
+// mode, where it most likely needed a passphrase or PIN.
+type nonInteractiveError struct {
+	err error
+}
+
+func (e *nonInteractiveError) Error() string { return e.err.Error() }
+
+func (e *nonInteractiveError) Unwrap() error { return e.err }
+
+// needsUnlock reports whether a failed decryption needed a passphrase or
+// PIN, i.e. gpg reported that it could not prompt. A failure without gpg's
+// diagnostics, as the gopass library reports them, is not assumed to be one.
+func needsUnlock(err error) bool {
+	problem, ok := classifyGPGError(err)
+	return ok && problem.summary == noPinentrySummary
+}
+
+// setNonInteractiveEnv adds nonInteractiveGPGOpts to the gpg options gopass
+// and pass read from the environment. Callers hold storeDirMu, as the
+// environment is shared by the process.
+func setNonInteractiveEnv() {
+	gopassOpts := os.Getenv(gopassGPGOptsEnv)
+	if gopassOpts == "" {
+		gopassOpts = os.Getenv(passGPGOptsEnv)
+	}
+	os.Setenv(gopassGPGOptsEnv, withNonInteractiveOpts(gopassOpts))
+	os.Setenv(passGPGOptsEnv, withNonInteractiveOpts(os.Getenv(passGPGOptsEnv)))
+}
+
+// withNonInteractiveOpts appends nonInteractiveGPGOpts to the gpg options
+// opts unless they are there already.
+func withNonInteractiveOpts(opts string) string {
+	fields := strings.Fields(opts)
+	if slices.Contains(fields, "--pinentry-mode=error") {
+		return opts
+	}
+	return strings.Join(append(fields, nonInteractiveGPGOpts...), " ")
+}
@@ -0,0 +1,116 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestSetNonInteractiveEnv(t *testing.T) {
+	t.Setenv(gopassGPGOptsEnv, "")
+	t.Setenv(passGPGOptsEnv, "--trust-model=always")
+
+	setNonInteractiveEnv()
+	setNonInteractiveEnv()
+
+	want := "--trust-model=always --batch --no-tty --pinentry-mode=error"
+	for _, name := range []string{gopassGPGOptsEnv, passGPGOptsEnv} {
+		if got := os.Getenv(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSetNonInteractiveEnv_KeepsGopassOptions(t *testing.T) {
+	t.Setenv(gopassGPGOptsEnv, "--homedir /srv/gnupg")
+	t.Setenv(passGPGOptsEnv, "")
+
+	setNonInteractiveEnv()
+
+	if got := os.Getenv(gopassGPGOptsEnv); got != "--homedir /srv/gnupg --batch --no-tty --pinentry-mode=error" {
+		t.Errorf("unexpected %s %q", gopassGPGOptsEnv, got)
+	}
+	if got := os.Getenv(passGPGOptsEnv); got != "--batch --no-tty --pinentry-mode=error" {
+		t.Errorf("unexpected %s %q", passGPGOptsEnv, got)
+	}
+}
+
+func TestGopassClient_NonInteractive_EnsureStore(t *testing.T) {
+	t.Setenv(gopassGPGOptsEnv, "")
+	t.Setenv(passGPGOptsEnv, "")
+
+	var seen string
+	client := NewGopassClient("")
+	client.nonInteractive = true
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		seen = os.Getenv(gopassGPGOptsEnv)
+		return NewMemoryStore(nil), nil
+	}
+	if err := client.ensureStore(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(seen, "--pinentry-mode=error") {
+		t.Errorf("expected the store to open with non-interactive gpg options, got %q", seen)
+	}
+}
+
+func TestGopassClient_NonInteractive_ReadErrors(t *testing.T) {
+	t.Setenv(gopassGPGOptsEnv, "")
+	t.Setenv(passGPGOptsEnv, "")
+
+	tests := []struct {
+		failMsg string
+		want    string
+	}{
+		{failMsg: "failed to decrypt", want: "Failed to decrypt secret"},
+		{failMsg: "gpg: public key decryption failed: No pinentry", want: nonInteractiveSummary},
+		{failMsg: "gpg: decryption failed: No secret key", want: "No secret key to decrypt the secret"},
+		{want: "Secret not found"},
+	}
+	for _, tt := range tests {
+		store := newMockStore()
+		store.shouldFail = tt.failMsg != ""
+		store.failMsg = tt.failMsg
+		client := NewGopassClientWithStore(store)
+		client.nonInteractive = true
+
+		_, err := client.GetSecret(context.Background(), "app/db")
+		if got := errorSummary(err, "fallback"); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.failMsg, tt.want, got)
+		}
+		var unlockErr *nonInteractiveError
+		if errors.As(err, &unlockErr) && !strings.Contains(errorDetail("detail", err), "non_interactive = true") {
+			t.Errorf("%q: expected the hint in the detail, got %q", tt.failMsg, errorDetail("detail", err))
+		}
+	}
+}
+
+func TestProviderConfigure_NonInteractive(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{Config: newProviderConfig(t, p, map[string]tftypes.Value{
+		"non_interactive": tftypes.NewValue(tftypes.Bool, true),
+		"wsl":             tftypes.NewValue(tftypes.Bool, true),
+	})}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	client := resp.EphemeralResourceData.(*GopassClient)
+	if !client.nonInteractive {
+		t.Error("expected non-interactive mode")
+	}
+	if got := strings.Join(client.wsl.command(""), " "); !strings.Contains(got, "env GOPASS_GPG_OPTS=--batch --no-tty --pinentry-mode=error gopass") {
+		t.Errorf("expected gopass inside WSL to run non-interactively, got %q", got)
+	}
+}
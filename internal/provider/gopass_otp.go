@@ -0,0 +1,150 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // TOTP mandates HMAC-SHA1 by default
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// totpDefaultPeriod is the time step of TOTP codes unless the key sets one.
+const totpDefaultPeriod = 30 * time.Second
+
+// totpAlgorithms are the HMAC hashes otpauth URLs may name.
+var totpAlgorithms = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// totpKey is a TOTP key as gopass stores it: in an otpauth:// URL, or as a
+// bare base32 secret under the totp key.
+type totpKey struct {
+	secret    []byte
+	algorithm string // a key of totpAlgorithms
+	digits    int
+	period    time.Duration
+	issuer    string
+	account   string
+}
+
+// findTOTPKey finds the TOTP key in a secret the way gopass otp does: an
+// otpauth:// URL on any line of body, or else a base32 secret in the totp
+// field.
+func findTOTPKey(body string, fields map[string]string) (*totpKey, error) {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if _, rest, ok := strings.Cut(line, ":"); ok && strings.HasPrefix(strings.TrimSpace(rest), "otpauth://") {
+			// An otpauth field, e.g. "otpauth: otpauth://totp/..."
+			line = strings.TrimSpace(rest)
+		}
+		if strings.HasPrefix(line, "otpauth://") {
+			return parseOTPAuthURL(line)
+		}
+	}
+	if secret, ok := fields["totp"]; ok {
+		decoded, err := decodeTOTPSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		return &totpKey{secret: decoded, algorithm: "SHA1", digits: 6, period: totpDefaultPeriod}, nil
+	}
+	return nil, errors.New("the secret has no otpauth:// URL and no totp field")
+}
+
+// parseOTPAuthURL parses a Key URI as Google Authenticator defines it.
+func parseOTPAuthURL(raw string) (*totpKey, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URL: %w", err)
+	}
+	switch u.Host {
+	case "totp":
+	case "hotp":
+		return nil, errors.New("HOTP keys are not supported: their counter would have to be written back with every code")
+	default:
+		return nil, fmt.Errorf("unsupported otpauth type %q", u.Host)
+	}
+
+	query := u.Query()
+	secret, err := decodeTOTPSecret(query.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+	key := &totpKey{secret: secret, algorithm: "SHA1", digits: 6, period: totpDefaultPeriod}
+
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		key.algorithm = strings.ToUpper(algorithm)
+		if _, ok := totpAlgorithms[key.algorithm]; !ok {
+			return nil, fmt.Errorf("unsupported otpauth algorithm %q", algorithm)
+		}
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if key.digits, err = strconv.Atoi(digits); err != nil || key.digits < 6 || key.digits > 10 {
+			return nil, fmt.Errorf("invalid otpauth digits %q", digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid otpauth period %q", period)
+		}
+		key.period = time.Duration(seconds) * time.Second
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		key.issuer, key.account = issuer, strings.TrimSpace(account)
+	} else {
+		key.account = label
+	}
+	if issuer := query.Get("issuer"); issuer != "" {
+		key.issuer = issuer
+	}
+	return key, nil
+}
+
+// decodeTOTPSecret decodes a base32 TOTP secret, tolerating lower case,
+// spaces and missing padding.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Join(strings.Fields(secret), ""))
+	secret = strings.TrimRight(secret, "=")
+	if secret == "" {
+		return nil, errors.New("the TOTP secret is empty")
+	}
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, errors.New("the TOTP secret is not valid base32")
+	}
+	return decoded, nil
+}
+
+// code returns the code valid at t and when it expires (RFC 6238).
+func (k *totpKey) code(t time.Time) (string, time.Time) {
+	step := int64(k.period / time.Second)
+	counter := t.Unix() / step
+
+	mac := hmac.New(totpAlgorithms[k.algorithm], k.secret)
+	binary.Write(mac, binary.BigEndian, uint64(counter)) //nolint:errcheck // hash writes never fail
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := uint64(binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff)
+	modulus := uint64(1)
+	for range k.digits {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", k.digits, value%modulus), time.Unix((counter+1)*step, 0)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret returns the RFC 6238 test seed for algorithm, base32 encoded.
+func rfc6238Secret(algorithm string) string {
+	seed := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}[algorithm]
+	return base32.StdEncoding.EncodeToString([]byte(seed))
+}
+
+func TestTOTPKey_Code_RFC6238(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		at        int64
+		want      string
+	}{
+		{"SHA1", 59, "94287082"},
+		{"SHA256", 59, "46119246"},
+		{"SHA512", 59, "90693936"},
+		{"SHA1", 1111111109, "07081804"},
+		{"SHA256", 1111111109, "68084774"},
+		{"SHA512", 1234567890, "93441116"},
+		{"SHA1", 20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		key, err := parseOTPAuthURL("otpauth://totp/test?digits=8&algorithm=" + tt.algorithm +
+			"&secret=" + rfc6238Secret(tt.algorithm))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.algorithm, err)
+		}
+		code, expires := key.code(time.Unix(tt.at, 0))
+		if code != tt.want {
+			t.Errorf("%s at %d: expected %s, got %s", tt.algorithm, tt.at, tt.want, code)
+		}
+		if want := (tt.at/30 + 1) * 30; expires.Unix() != want {
+			t.Errorf("%s at %d: expected expiry %d, got %d", tt.algorithm, tt.at, want, expires.Unix())
+		}
+	}
+}
+
+func TestParseOTPAuthURL(t *testing.T) {
+	key, err := parseOTPAuthURL("otpauth://totp/Example:alice@example.com?secret=jbsw%20y3dp&period=60&digits=7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.issuer != "Example" || key.account != "alice@example.com" {
+		t.Errorf("unexpected label %q %q", key.issuer, key.account)
+	}
+	if key.period != time.Minute || key.digits != 7 || key.algorithm != "SHA1" {
+		t.Errorf("unexpected parameters %+v", key)
+	}
+	if code, _ := key.code(time.Unix(0, 0)); len(code) != 7 {
+		t.Errorf("expected a 7 digit code, got %q", code)
+	}
+
+	key, err = parseOTPAuthURL("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&issuer=ACME")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.issuer != "ACME" || key.account != "alice" {
+		t.Errorf("expected the issuer parameter to apply, got %q %q", key.issuer, key.account)
+	}
+}
+
+func TestParseOTPAuthURL_Errors(t *testing.T) {
+	tests := map[string]string{
+		"otpauth://hotp/x?secret=JBSWY3DP&counter=1":     "HOTP keys are not supported",
+		"otpauth://foo/x?secret=JBSWY3DP":                "unsupported otpauth type",
+		"otpauth://totp/x":                               "empty",
+		"otpauth://totp/x?secret=not-base32!":            "not valid base32",
+		"otpauth://totp/x?secret=JBSWY3DP&algorithm=MD5": "unsupported otpauth algorithm",
+		"otpauth://totp/x?secret=JBSWY3DP&digits=4":      "invalid otpauth digits",
+		"otpauth://totp/x?secret=JBSWY3DP&period=0":      "invalid otpauth period",
+	}
+	for raw, want := range tests {
+		if _, err := parseOTPAuthURL(raw); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", raw, want, err)
+		}
+	}
+}
+
+func TestFindTOTPKey(t *testing.T) {
+	url := "otpauth://totp/ACME:bob?secret=JBSWY3DPEHPK3PXP"
+	tests := map[string]struct {
+		body   string
+		fields map[string]string
+	}{
+		"url line":  {body: "s3cret\nnotes\n" + url + "\n"},
+		"url field": {body: "s3cret\notpauth: " + url + "\n", fields: map[string]string{"otpauth": url}},
+		"totp field": {
+			body:   "s3cret\ntotp: jbsw y3dp ehpk 3pxp\n",
+			fields: map[string]string{"totp": "jbsw y3dp ehpk 3pxp"},
+		},
+	}
+	for name, tt := range tests {
+		key, err := findTOTPKey(tt.body, tt.fields)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if string(key.secret) != "Hello!\xde\xad\xbe\xef" || key.period != totpDefaultPeriod || key.digits != 6 {
+			t.Errorf("%s: unexpected key %+v", name, key)
+		}
+	}
+
+	if _, err := findTOTPKey("s3cret\nusername: bob\n", map[string]string{"username": "bob"}); err == nil {
+		t.Error("expected an error for a secret without a TOTP key")
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// storeFormatPass reads stores managed by the original pass.
+const storeFormatPass = "pass"
+
+// Environment variables pass reads its layout and gpg settings from.
+const (
+	passStoreDirEnv   = "PASSWORD_STORE_DIR"
+	passKeyEnv        = "PASSWORD_STORE_KEY"
+	passGPGOptsEnv    = "PASSWORD_STORE_GPG_OPTS"
+	passSigningKeyEnv = "PASSWORD_STORE_SIGNING_KEY"
+)
+
+// passRecipientsFile is the per-directory recipient file of pass stores.
+const passRecipientsFile = ".gpg-id"
+
+// passValidSig extracts the primary key fingerprint from gpg's VALIDSIG
+// status line, as pass does to check signed .gpg-id files.
+var passValidSig = regexp.MustCompile(`(?m)^\[GNUPG:\] VALIDSIG [A-F0-9]{40} .* ([A-F0-9]{40})$`)
+
+// runGPG runs the gpg binary with args and stdin, returning its output;
+// injectable for testing.
+var runGPG = func(ctx context.Context, binary string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, &commandError{name: binary, err: err, stderr: strings.TrimSpace(stderr.String())}
+	}
+	return out, nil
+}
+
+// PassStore is a SecretStore for stores managed by pass, the standard unix
+// password manager, that keeps them byte-compatible: every entry is a
+// ".gpg" file, decrypted and encrypted with the gpg binary and the options
+// pass uses, to the recipients of the nearest .gpg-id file. Neither the gopass
+// configuration nor anything else outside the store is read or written.
+type PassStore struct {
+	entryFiles
+	gpg string
+}
+
+// Ensure PassStore satisfies SecretStore.
+var _ SecretStore = (*PassStore)(nil)
+
+// passDefaultDir returns the store directory pass uses: PASSWORD_STORE_DIR,
+// or else ~/.password-store.
+func passDefaultDir(userHomeDir func() (string, error)) (string, error) {
+	if dir := os.Getenv(passStoreDirEnv); dir != "" {
+		return dir, nil
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the password store: %w", err)
+	}
+	return filepath.Join(home, ".password-store"), nil
+}
+
+// NewPassStore returns a PassStore for the store in dir. Like pass, it runs
+// gpg2 if installed, and gpg otherwise.
+func NewPassStore(dir string) (*PassStore, error) {
+	files, err := openEntryFiles("pass", dir, ".gpg")
+	if err != nil {
+		return nil, err
+	}
+	binary, err := findGPGTool("gpg2", "gpg")
+	if err != nil {
+		binary = "gpg"
+	}
+	return &PassStore{entryFiles: files, gpg: binary}, nil
+}
+
+// usePass makes the client open its store and mounts as pass stores.
+func (c *GopassClient) usePass() {
+	c.storeFormat = storeFormatPass
+	c.newStore = func(ctx context.Context) (SecretStore, error) {
+		dir, err := c.expandHome(c.storePath)
+		if err != nil {
+			return nil, err
+		}
+		return NewPassStore(dir)
+	}
+}
+
+// gpgOptions returns the options pass passes to every gpg call.
+func (s *PassStore) gpgOptions() []string {
+	opts := strings.Fields(os.Getenv(passGPGOptsEnv))
+	return append(opts, "--quiet", "--yes", "--compress-algo=none", "--no-encrypt-to", "--batch", "--use-agent")
+}
+
+// Get decrypts the secret name. Revisions other than "latest" are git commit
+// hashes of the store's repository.
+func (s *PassStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	content, err := s.read(ctx, name, revision)
+	if err != nil {
+		return nil, err
+	}
+	body, err := runGPG(ctx, s.gpg, content, append([]string{"--decrypt"}, s.gpgOptions()...)...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrDecryptionFailed, name, err.Error())
+	}
+	return parseSecret(body), nil
+}
+
+// recipients returns the GPG ids pass encrypts file to: those in
+// PASSWORD_STORE_KEY, or else in the nearest .gpg-id file. With
+// PASSWORD_STORE_SIGNING_KEY set, the .gpg-id file must carry a valid
+// signature by one of those keys, as pass requires.
+func (s *PassStore) recipients(ctx context.Context, file string) ([]string, error) {
+	if ids := strings.Fields(os.Getenv(passKeyEnv)); len(ids) > 0 {
+		return ids, nil
+	}
+
+	idFile := s.nearest(file, passRecipientsFile)
+	if idFile == "" {
+		return nil, fmt.Errorf("no %s file applies to %s, initialize the store with \"pass init\"", passRecipientsFile, file)
+	}
+	if err := s.verifyRecipients(ctx, idFile); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(idFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		id, _, _ := strings.Cut(scanner.Text(), "#")
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%s lists no GPG ids", idFile)
+	}
+	return ids, nil
+}
+
+// verifyRecipients checks the signature of idFile if PASSWORD_STORE_SIGNING_KEY
+// is set.
+func (s *PassStore) verifyRecipients(ctx context.Context, idFile string) error {
+	signingKeys := strings.Fields(os.Getenv(passSigningKeyEnv))
+	if len(signingKeys) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(idFile + ".sig"); err != nil {
+		return fmt.Errorf("signature for %s does not exist", idFile)
+	}
+
+	args := append(strings.Fields(os.Getenv(passGPGOptsEnv)), "--verify", "--status-fd=1", idFile+".sig", idFile)
+	out, _ := runGPG(ctx, s.gpg, nil, args...)
+	for _, m := range passValidSig.FindAllSubmatch(out, -1) {
+		for _, key := range signingKeys {
+			if string(m[1]) == key {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("signature for %s is invalid", idFile)
+}
+
+// Set encrypts sec to the entry name, creating it if needed, and commits it.
+func (s *PassStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	file, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	ids, err := s.recipients(ctx, file)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--encrypt"}
+	for _, id := range ids {
+		args = append(args, "--recipient", id)
+	}
+	content, err := runGPG(ctx, s.gpg, sec.Bytes(), append(args, s.gpgOptions()...)...)
+	if err != nil {
+		return err
+	}
+	if len(content) == 0 {
+		return errors.New("gpg returned no ciphertext")
+	}
+	return s.write(ctx, name, content)
+}
@@ -0,0 +1,249 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newGPGHome points GNUPGHOME at a new keyring holding an unprotected key
+// for uid, and returns its fingerprint. The test is skipped without gpg.
+func newGPGHome(t *testing.T, uid string) string {
+	t.Helper()
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	home, err := os.MkdirTemp("", "gpg-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+	t.Setenv("GNUPGHOME", home)
+
+	gpg := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("gpg", append([]string{"--batch", "--pinentry-mode", "loopback", "--passphrase", ""}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("gpg %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	gpg("--quick-gen-key", uid, "future-default", "default", "never")
+	for _, line := range strings.Split(gpg("--with-colons", "--list-secret-keys", uid), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" {
+			return fields[9]
+		}
+	}
+	t.Fatal("no fingerprint for the generated key")
+	return ""
+}
+
+// stubGPG replaces runGPG with fn for the test.
+func stubGPG(t *testing.T, fn func(args []string, stdin []byte) ([]byte, error)) {
+	t.Helper()
+	previous := runGPG
+	runGPG = func(ctx context.Context, binary string, stdin []byte, args ...string) ([]byte, error) {
+		return fn(args, stdin)
+	}
+	t.Cleanup(func() { runGPG = previous })
+}
+
+func TestPassStore_GPG(t *testing.T) {
+	fingerprint := newGPGHome(t, "Pass Test <pass@example.com>")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, passRecipientsFile), []byte(fingerprint+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewPassStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "app/db", parseSecret([]byte("s3cret\nusername: admin\n"))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The entry is a plain gpg message pass can read
+	out, err := exec.Command("gpg", "--batch", "--quiet", "--decrypt", filepath.Join(dir, "app", "db.gpg")).Output()
+	if err != nil || string(out) != "s3cret\nusername: admin\n" {
+		t.Errorf("unexpected entry %q: %v", out, err)
+	}
+
+	secret, err := store.Get(ctx, "app/db", "latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if username, _ := secret.Get("username"); secret.Password() != "s3cret" || username != "admin" {
+		t.Errorf("unexpected secret %q", secret.Bytes())
+	}
+
+	names, err := store.List(ctx)
+	if err != nil || len(names) != 1 || names[0] != "app/db" {
+		t.Errorf("unexpected listing %v: %v", names, err)
+	}
+}
+
+func TestPassStore_Get_Errors(t *testing.T) {
+	stubGPG(t, func(args []string, stdin []byte) ([]byte, error) {
+		return nil, errors.New("gpg: decryption failed: No secret key")
+	})
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "token.gpg"), []byte("ciphertext"), 0o600)
+	store, _ := NewPassStore(dir)
+
+	if _, err := store.Get(context.Background(), "token", "latest"); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected ErrDecryptionFailed, got %v", err)
+	}
+	if _, err := store.Get(context.Background(), "missing", "latest"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPassStore_Set_Recipients(t *testing.T) {
+	var calls [][]string
+	stubGPG(t, func(args []string, stdin []byte) ([]byte, error) {
+		calls = append(calls, args)
+		return []byte("ciphertext"), nil
+	})
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, passRecipientsFile), []byte("root@example.com\n"), 0o600)
+	os.MkdirAll(filepath.Join(dir, "team"), 0o700)
+	os.WriteFile(filepath.Join(dir, "team", passRecipientsFile), []byte("# team keys\nalice@example.com\n  bob@example.com # laptop\n\n"), 0o600)
+	t.Setenv(passGPGOptsEnv, "--trust-model always")
+	store, _ := NewPassStore(dir)
+	ctx := context.Background()
+
+	tests := []struct {
+		name, key, want string
+	}{
+		{name: "team/app/token", want: "--encrypt --recipient alice@example.com --recipient bob@example.com"},
+		{name: "other/token", want: "--encrypt --recipient root@example.com"},
+		{name: "team/token", key: "override@example.com", want: "--encrypt --recipient override@example.com"},
+	}
+	for _, tt := range tests {
+		t.Setenv(passKeyEnv, tt.key)
+		calls = nil
+		if err := store.Set(ctx, tt.name, newPasswordSecret("x")); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		got := strings.Join(calls[0], " ")
+		if !strings.HasPrefix(got, tt.want+" --trust-model always --quiet --yes --compress-algo=none --no-encrypt-to") {
+			t.Errorf("%s: unexpected gpg call %q", tt.name, got)
+		}
+		if content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.name)+".gpg")); err != nil || string(content) != "ciphertext" {
+			t.Errorf("%s: unexpected entry %q: %v", tt.name, content, err)
+		}
+	}
+}
+
+func TestPassStore_Set_NoRecipients(t *testing.T) {
+	stubGPG(t, func(args []string, stdin []byte) ([]byte, error) {
+		t.Error("gpg must not run without recipients")
+		return nil, nil
+	})
+	t.Setenv(passKeyEnv, "")
+	store, _ := NewPassStore(t.TempDir())
+
+	if err := store.Set(context.Background(), "token", newPasswordSecret("x")); err == nil || !strings.Contains(err.Error(), "pass init") {
+		t.Errorf("expected a missing .gpg-id error, got %v", err)
+	}
+}
+
+func TestPassStore_Set_SignedRecipients(t *testing.T) {
+	const signer = "0123456789ABCDEF0123456789ABCDEF01234567"
+	var validSig string
+	stubGPG(t, func(args []string, stdin []byte) ([]byte, error) {
+		if args[0] == "--verify" {
+			return []byte("[GNUPG:] NEWSIG\n[GNUPG:] VALIDSIG " + strings.Repeat("A", 40) + " 2025-01-02 1735787045 0 4 0 22 10 00 " + validSig + "\n"), nil
+		}
+		return []byte("ciphertext"), nil
+	})
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, passRecipientsFile), []byte("root@example.com\n"), 0o600)
+	t.Setenv(passKeyEnv, "")
+	t.Setenv(passSigningKeyEnv, signer)
+	store, _ := NewPassStore(dir)
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "token", newPasswordSecret("x")); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected a missing signature error, got %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, passRecipientsFile+".sig"), []byte("sig"), 0o600)
+	validSig = strings.Repeat("B", 40)
+	if err := store.Set(ctx, "token", newPasswordSecret("x")); err == nil || !strings.Contains(err.Error(), "is invalid") {
+		t.Errorf("expected an invalid signature error, got %v", err)
+	}
+
+	validSig = signer
+	if err := store.Set(ctx, "token", newPasswordSecret("x")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestPassDefaultDir(t *testing.T) {
+	t.Setenv(passStoreDirEnv, "")
+	dir, err := passDefaultDir(func() (string, error) { return "/home/user", nil })
+	if err != nil || dir != filepath.Join("/home/user", ".password-store") {
+		t.Errorf("unexpected default %s: %v", dir, err)
+	}
+
+	t.Setenv(passStoreDirEnv, "/srv/store")
+	if dir, err := passDefaultDir(nil); err != nil || dir != "/srv/store" {
+		t.Errorf("expected PASSWORD_STORE_DIR, got %s: %v", dir, err)
+	}
+}
+
+func TestGopassClient_Pass(t *testing.T) {
+	stubGPG(t, func(args []string, stdin []byte) ([]byte, error) {
+		return []byte("s3cret\n"), nil
+	})
+	dir, mounted := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.gpg"), []byte("ciphertext"), 0o600)
+	os.WriteFile(filepath.Join(mounted, "token.gpg"), []byte("ciphertext"), 0o600)
+
+	client := NewGopassClient(dir)
+	client.usePass()
+	client.addStoreMount("team", mounted)
+	defer client.Close(context.Background())
+
+	ctx := context.Background()
+	for _, secretPath := range []string{"app", "team/token"} {
+		if value, err := client.GetSecret(ctx, secretPath); err != nil || value != "s3cret" {
+			t.Errorf("%s: unexpected value %q: %v", secretPath, value, err)
+		}
+	}
+}
+
+func TestProviderConfigure_StoreFormatPass(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+	t.Setenv(passStoreDirEnv, "/srv/store")
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"store_format": tftypes.NewValue(tftypes.String, "pass"),
+		}),
+	}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.EphemeralResourceData.(*GopassClient); client.storeFormat != storeFormatPass || client.storePath != "/srv/store" {
+		t.Errorf("unexpected client setup %q %q", client.storeFormat, client.storePath)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// Store formats the provider reads.
+const (
+	storeFormatGopass  = "gopass"
+	storeFormatPassage = "passage"
+)
+
+// Environment variables passage reads its layout and keys from.
+const (
+	passageDirEnv            = "PASSAGE_DIR"
+	passageIdentitiesFileEnv = "PASSAGE_IDENTITIES_FILE"
+	passageRecipientsEnv     = "PASSAGE_RECIPIENTS"
+	passageRecipientsFileEnv = "PASSAGE_RECIPIENTS_FILE"
+)
+
+// passageRecipientsFile is the per-directory recipient file of passage stores.
+const passageRecipientsFile = ".age-recipients"
+
+// PassageStore is a SecretStore for stores managed by passage, the age-based
+// fork of pass: every entry is an age-encrypted ".age" file, decrypted with
+// the identities from the identities file and encrypted to the recipients
+// passage would use.
+type PassageStore struct {
+	entryFiles
+	identitiesFile string
+	// recipientsFile replaces the recipients passage would pick, if set
+	recipientsFile string
+
+	mu         sync.Mutex
+	identities []age.Identity // loaded on first use
+}
+
+// Ensure PassageStore satisfies SecretStore.
+var _ SecretStore = (*PassageStore)(nil)
+
+// passageDefaults returns the store directory and identities file passage
+// uses: those from PASSAGE_DIR and PASSAGE_IDENTITIES_FILE, or else the
+// ones in ~/.passage.
+func passageDefaults(userHomeDir func() (string, error)) (dir, identitiesFile string, err error) {
+	dir, identitiesFile = os.Getenv(passageDirEnv), os.Getenv(passageIdentitiesFileEnv)
+	if dir != "" && identitiesFile != "" {
+		return dir, identitiesFile, nil
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to locate the passage directory: %w", err)
+	}
+	if dir == "" {
+		dir = filepath.Join(home, ".passage", "store")
+	}
+	if identitiesFile == "" {
+		identitiesFile = filepath.Join(home, ".passage", "identities")
+	}
+	return dir, identitiesFile, nil
+}
+
+// NewPassageStore returns a PassageStore for the store in dir, decrypting
+// with the identities in identitiesFile.
+func NewPassageStore(dir, identitiesFile string) (*PassageStore, error) {
+	files, err := openEntryFiles("passage", dir, ".age")
+	if err != nil {
+		return nil, err
+	}
+	return &PassageStore{entryFiles: files, identitiesFile: identitiesFile}, nil
+}
+
+// usePassage makes the client open its store and mounts as passage stores,
+// decrypting with the identities in identitiesFile.
+func (c *GopassClient) usePassage(identitiesFile string) {
+	c.storeFormat = storeFormatPassage
+	c.passageIdentities = identitiesFile
+	c.newStore = func(ctx context.Context) (SecretStore, error) {
+		dir, err := c.expandHome(c.storePath)
+		if err != nil {
+			return nil, err
+		}
+		return c.passageStoreAt(dir)
+	}
+}
+
+// useAgeIdentities makes the client read gopass age stores with the age
+// library, decrypting with the identities in identitiesFile. gopass age stores
+// share the layout of passage stores and are opened as such, bypassing the
+// identity discovery of gopass: its passphrase-protected keyring, SSH keys and
+// ~/.passage/identities. The gopass configuration is not read either, so the
+// root store is the one in store_path or PASSWORD_STORE_DIR. For passage
+// stores, identitiesFile only replaces the default identities file.
+func (c *GopassClient) useAgeIdentities(identitiesFile string) {
+	c.passageIdentities = identitiesFile
+	if c.storeFormat == storeFormatPassage {
+		return
+	}
+	c.ageStores = true
+	c.newStore = func(ctx context.Context) (SecretStore, error) {
+		dir := c.storeDir()
+		if dir == "" {
+			return nil, errors.New("age_identities_file requires store_path or PASSWORD_STORE_DIR to locate the store")
+		}
+		return c.passageStoreAt(dir)
+	}
+}
+
+// passageStoreAt opens the passage store in dir.
+func (c *GopassClient) passageStoreAt(dir string) (SecretStore, error) {
+	identitiesFile, err := c.expandHome(c.passageIdentities)
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewPassageStore(dir, identitiesFile)
+	if err != nil {
+		return nil, err
+	}
+	if c.ageRecipients != "" {
+		if store.recipientsFile, err = c.expandHome(c.ageRecipients); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// loadIdentities returns the identities from the identities file, reading
+// it on first use.
+func (s *PassageStore) loadIdentities() ([]age.Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.identities != nil {
+		return s.identities, nil
+	}
+	f, err := os.Open(s.identitiesFile)
+	if err != nil {
+		return nil, fmt.Errorf("passage identities: %w", err)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("passage identities in %s: %w (plugin identities are not supported)", s.identitiesFile, err)
+	}
+	s.identities = identities
+	return identities, nil
+}
+
+// Get decrypts the secret name. Revisions other than "latest" are git commit
+// hashes of the store's repository.
+func (s *PassageStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	content, err := s.read(ctx, name, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.loadIdentities()
+	if err != nil {
+		return nil, err
+	}
+	var in io.Reader = bytes.NewReader(content)
+	if bytes.HasPrefix(content, []byte(armor.Header)) {
+		in = armor.NewReader(in)
+	}
+	r, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrDecryptionFailed, name, err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrDecryptionFailed, name, err.Error())
+	}
+	return parseSecret(body), nil
+}
+
+// recipients returns the recipients passage encrypts file to: those from
+// PASSAGE_RECIPIENTS or PASSAGE_RECIPIENTS_FILE, or else from the nearest
+// .age-recipients file, or else the recipients of the identities. A
+// configured recipients file takes precedence over all of them.
+func (s *PassageStore) recipients(file string) ([]age.Recipient, error) {
+	if s.recipientsFile != "" {
+		return readRecipientsFile(s.recipientsFile)
+	}
+	if list := os.Getenv(passageRecipientsEnv); list != "" {
+		recipients, err := age.ParseRecipients(strings.NewReader(strings.Join(strings.Fields(list), "\n")))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", passageRecipientsEnv, err)
+		}
+		return recipients, nil
+	}
+
+	recipientsFile := os.Getenv(passageRecipientsFileEnv)
+	if recipientsFile == "" {
+		recipientsFile = s.nearest(file, passageRecipientsFile)
+	}
+	if recipientsFile != "" {
+		return readRecipientsFile(recipientsFile)
+	}
+
+	identities, err := s.loadIdentities()
+	if err != nil {
+		return nil, err
+	}
+	recipients := make([]age.Recipient, 0, len(identities))
+	for _, identity := range identities {
+		x25519, ok := identity.(*age.X25519Identity)
+		if !ok {
+			return nil, fmt.Errorf("cannot derive a recipient from the identities in %s, add a %s file",
+				s.identitiesFile, passageRecipientsFile)
+		}
+		recipients = append(recipients, x25519.Recipient())
+	}
+	return recipients, nil
+}
+
+// readRecipientsFile parses the age recipients listed in file.
+func readRecipientsFile(file string) ([]age.Recipient, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("passage recipients: %w", err)
+	}
+	defer f.Close()
+	recipients, err := age.ParseRecipients(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("passage recipients in %s: %w", file, err)
+	}
+	return recipients, nil
+}
+
+// Set encrypts sec to the entry name, creating it if needed, and commits it.
+func (s *PassageStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	file, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	recipients, err := s.recipients(file)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(sec.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return s.write(ctx, name, buf.Bytes())
+}
@@ -0,0 +1,499 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newPassageStore returns a passage store in dir (a new directory if empty)
+// with a new identity in its identities file.
+func newPassageStore(t *testing.T, dir string) (*PassageStore, *age.X25519Identity) {
+	t.Helper()
+	if dir == "" {
+		dir = t.TempDir()
+	}
+	id := newAgeIdentity(t)
+	identitiesFile := filepath.Join(t.TempDir(), "identities")
+	if err := os.WriteFile(identitiesFile, []byte("# created: 2025-01-02T03:04:05Z\n"+id.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewPassageStore(dir, identitiesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, id
+}
+
+// writePassageEntry encrypts body to recipient at file in dir, armored if asked to.
+func writePassageEntry(t *testing.T, dir, file, body string, recipient age.Recipient, armored bool) {
+	t.Helper()
+	var buf bytes.Buffer
+	var out io.WriteCloser = nopWriteCloser{&buf}
+	if armored {
+		out = armor.NewWriter(&buf)
+	}
+	w, err := age.Encrypt(out, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, body)
+	w.Close()
+	out.Close()
+
+	full := filepath.Join(dir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(full), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// decryptPassageFile decrypts the entry file in dir with id.
+func decryptPassageFile(t *testing.T, dir, file string, id age.Identity) (string, error) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(content), id)
+	if err != nil {
+		return "", err
+	}
+	body, err := io.ReadAll(r)
+	return string(body), err
+}
+
+func TestPassageStore_Get(t *testing.T) {
+	store, id := newPassageStore(t, "")
+	writePassageEntry(t, store.dir, "app/db.age", "s3cret\nusername: admin\n", id.Recipient(), false)
+	writePassageEntry(t, store.dir, "app/token.age", "t0ken\n", id.Recipient(), true)
+
+	ctx := context.Background()
+	secret, err := store.Get(ctx, "app/db", "latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if username, _ := secret.Get("username"); secret.Password() != "s3cret" || username != "admin" {
+		t.Errorf("unexpected secret %q", secret.Bytes())
+	}
+
+	secret, err = store.Get(ctx, "app/token", "")
+	if err != nil || secret.Password() != "t0ken" {
+		t.Errorf("expected the armored entry to decrypt, got %v", err)
+	}
+
+	if _, err := store.Get(ctx, "app/missing", "latest"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Get(ctx, "../outside", "latest"); err == nil || !strings.Contains(err.Error(), "invalid secret path") {
+		t.Errorf("expected an invalid path error, got %v", err)
+	}
+
+	writePassageEntry(t, store.dir, "app/foreign.age", "x", newAgeIdentity(t).Recipient(), false)
+	if _, err := store.Get(ctx, "app/foreign", "latest"); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected ErrDecryptionFailed, got %v", err)
+	}
+}
+
+func TestPassageStore_Get_MissingIdentities(t *testing.T) {
+	store, id := newPassageStore(t, "")
+	writePassageEntry(t, store.dir, "token.age", "t0ken", id.Recipient(), false)
+	store.identitiesFile = filepath.Join(t.TempDir(), "missing")
+
+	if _, err := store.Get(context.Background(), "token", "latest"); err == nil || !strings.Contains(err.Error(), "passage identities") {
+		t.Errorf("expected an identities error, got %v", err)
+	}
+}
+
+func TestPassageStore_List(t *testing.T) {
+	store, id := newPassageStore(t, "")
+	for _, file := range []string{"b.age", "a/x.age", "a/y/z.age", ".git/objects/k.age", ".hidden.age"} {
+		writePassageEntry(t, store.dir, file, "v", id.Recipient(), false)
+	}
+	os.WriteFile(filepath.Join(store.dir, "a", passageRecipientsFile), []byte(id.Recipient().String()), 0o600)
+	os.WriteFile(filepath.Join(store.dir, "notes.txt"), []byte("x"), 0o600)
+
+	names, err := store.List(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(names, ","); got != "a/x,a/y/z,b" {
+		t.Errorf("unexpected listing %s", got)
+	}
+}
+
+func TestPassageStore_SetRemove(t *testing.T) {
+	store, id := newPassageStore(t, "")
+	ctx := context.Background()
+
+	if err := store.Set(ctx, "team/app/token", newPasswordSecret("t0ken")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Without recipient files, entries are encrypted to the identities
+	if body, err := decryptPassageFile(t, store.dir, "team/app/token.age", id); err != nil || !strings.HasPrefix(body, "t0ken") {
+		t.Errorf("unexpected entry %q: %v", body, err)
+	}
+	if secret, err := store.Get(ctx, "team/app/token", "latest"); err != nil || secret.Password() != "t0ken" {
+		t.Errorf("expected to read back the entry, got %v", err)
+	}
+
+	if err := store.Remove(ctx, "team/app/token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.dir, "team")); !os.IsNotExist(err) {
+		t.Errorf("expected the emptied directories to be removed, got %v", err)
+	}
+	if _, err := os.Stat(store.dir); err != nil {
+		t.Errorf("expected the store directory to stay, got %v", err)
+	}
+	if err := store.Remove(ctx, "team/app/token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPassageStore_Recipients(t *testing.T) {
+	store, _ := newPassageStore(t, "")
+	team, other, fromFile := newAgeIdentity(t), newAgeIdentity(t), newAgeIdentity(t)
+	os.MkdirAll(filepath.Join(store.dir, "team"), 0o700)
+	os.WriteFile(filepath.Join(store.dir, "team", passageRecipientsFile),
+		[]byte("# team\n"+team.Recipient().String()+"\n"), 0o600)
+	ctx := context.Background()
+
+	// The nearest recipient file applies
+	if err := store.Set(ctx, "team/app/token", newPasswordSecret("t0ken")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := decryptPassageFile(t, store.dir, "team/app/token.age", team); err != nil {
+		t.Errorf("expected the entry to be encrypted to the team recipient: %v", err)
+	}
+
+	// PASSAGE_RECIPIENTS_FILE overrides the recipient files
+	recipientsFile := filepath.Join(t.TempDir(), "recipients")
+	os.WriteFile(recipientsFile, []byte(fromFile.Recipient().String()), 0o600)
+	t.Setenv(passageRecipientsFileEnv, recipientsFile)
+	if err := store.Set(ctx, "team/other", newPasswordSecret("x")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := decryptPassageFile(t, store.dir, "team/other.age", fromFile); err != nil {
+		t.Errorf("expected the entry to be encrypted to the recipients file: %v", err)
+	}
+
+	// PASSAGE_RECIPIENTS overrides both
+	t.Setenv(passageRecipientsEnv, other.Recipient().String()+" "+team.Recipient().String())
+	if err := store.Set(ctx, "team/app/token", newPasswordSecret("t0ken")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, id := range []*age.X25519Identity{other, team} {
+		if _, err := decryptPassageFile(t, store.dir, "team/app/token.age", id); err != nil {
+			t.Errorf("expected the entry to be encrypted to %s: %v", id.Recipient(), err)
+		}
+	}
+
+	t.Setenv(passageRecipientsEnv, "not-a-recipient")
+	if err := store.Set(ctx, "team/app/token", newPasswordSecret("t0ken")); err == nil {
+		t.Error("expected an error for an invalid recipient")
+	}
+}
+
+func TestPassageStore_Git(t *testing.T) {
+	dir := newGitStore(t)
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "test")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "test@example.com")
+	}
+	store, _ := newPassageStore(t, dir)
+	ctx := context.Background()
+
+	for _, value := range []string{"v1", "v2"} {
+		if err := store.Set(ctx, "app/token", newPasswordSecret(value)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	revisions, err := store.Revisions(ctx, "app/token")
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("expected 2 revisions, got %v: %v", revisions, err)
+	}
+	secret, err := store.Get(ctx, "app/token", revisions[1])
+	if err != nil || secret.Password() != "v1" {
+		t.Errorf("expected the first revision, got %v", err)
+	}
+	if _, err := store.Get(ctx, "app/token", "0000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown revision, got %v", err)
+	}
+
+	if err := store.Remove(ctx, "app/token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	commits, err := gitLog(ctx, dir, "app/token.age")
+	if err != nil || len(commits) != 3 {
+		t.Errorf("expected the removal to be committed, got %v: %v", commits, err)
+	}
+}
+
+func TestPassageStore_Revisions_WithoutGit(t *testing.T) {
+	store, id := newPassageStore(t, "")
+	writePassageEntry(t, store.dir, "token.age", "t0ken", id.Recipient(), false)
+
+	revisions, err := store.Revisions(context.Background(), "token")
+	if err != nil || len(revisions) != 1 || revisions[0] != "latest" {
+		t.Errorf("expected the latest revision only, got %v: %v", revisions, err)
+	}
+	if _, err := store.Revisions(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPassageDefaults(t *testing.T) {
+	home := func() (string, error) { return "/home/user", nil }
+
+	t.Setenv(passageDirEnv, "")
+	t.Setenv(passageIdentitiesFileEnv, "")
+	dir, identities, err := passageDefaults(home)
+	if err != nil || dir != filepath.Join("/home/user", ".passage", "store") || identities != filepath.Join("/home/user", ".passage", "identities") {
+		t.Errorf("unexpected defaults %s, %s: %v", dir, identities, err)
+	}
+
+	t.Setenv(passageDirEnv, "/srv/store")
+	t.Setenv(passageIdentitiesFileEnv, "/srv/identities")
+	dir, identities, err = passageDefaults(func() (string, error) { return "", errors.New("no home") })
+	if err != nil || dir != "/srv/store" || identities != "/srv/identities" {
+		t.Errorf("expected the environment to win, got %s, %s: %v", dir, identities, err)
+	}
+}
+
+func TestGopassClient_Passage(t *testing.T) {
+	store, id := newPassageStore(t, "")
+	writePassageEntry(t, store.dir, "app/db.age", "s3cret\nusername: admin", id.Recipient(), false)
+	mounted := t.TempDir()
+	writePassageEntry(t, mounted, "token.age", "t0ken", id.Recipient(), false)
+
+	client := NewGopassClient(store.dir)
+	client.usePassage(store.identitiesFile)
+	client.addStoreMount("team", mounted)
+	defer client.Close(context.Background())
+
+	ctx := context.Background()
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+		t.Errorf("unexpected value %q: %v", value, err)
+	}
+	if value, err := client.GetSecret(ctx, "team/token"); err != nil || value != "t0ken" {
+		t.Errorf("expected the mount to be a passage store, got %q: %v", value, err)
+	}
+	if err := client.SetSecret(ctx, "app/new", "n3w"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body, err := decryptPassageFile(t, store.dir, "app/new.age", id); err != nil || !strings.HasPrefix(body, "n3w") {
+		t.Errorf("unexpected entry %q: %v", body, err)
+	}
+}
+
+func TestProviderConfigure_StoreFormat(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+	storeDir := t.TempDir()
+	t.Setenv(passageDirEnv, storeDir)
+	t.Setenv(passageIdentitiesFileEnv, "/srv/identities")
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"store_format": tftypes.NewValue(tftypes.String, "passage"),
+		}),
+	}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	client := resp.EphemeralResourceData.(*GopassClient)
+	if client.storeFormat != storeFormatPassage || client.storePath != storeDir || client.passageIdentities != "/srv/identities" {
+		t.Errorf("unexpected client setup %q %q %q", client.storeFormat, client.storePath, client.passageIdentities)
+	}
+
+	resp = &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"store_format": tftypes.NewValue(tftypes.String, "passage"),
+			"store_path":   tftypes.NewValue(tftypes.String, "/srv/other"),
+		}),
+	}, resp)
+	if client := resp.EphemeralResourceData.(*GopassClient); client.storePath != "/srv/other" {
+		t.Errorf("expected store_path to win over PASSAGE_DIR, got %q", client.storePath)
+	}
+
+	resp = &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"store_format": tftypes.NewValue(tftypes.String, "keepass"),
+		}),
+	}, resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error for an unknown store format")
+	}
+}
+
+func TestPassageStore_RecipientsFileOption(t *testing.T) {
+	store, _ := newPassageStore(t, "")
+	configured, other := newAgeIdentity(t), newAgeIdentity(t)
+	store.recipientsFile = filepath.Join(t.TempDir(), "recipients")
+	os.WriteFile(store.recipientsFile, []byte(configured.Recipient().String()+"\n"), 0o600)
+	os.WriteFile(filepath.Join(store.dir, passageRecipientsFile), []byte(other.Recipient().String()+"\n"), 0o600)
+	t.Setenv(passageRecipientsEnv, other.Recipient().String())
+
+	// The configured file wins over the environment and the recipient files
+	if err := store.Set(context.Background(), "app/token", newPasswordSecret("t0ken")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := decryptPassageFile(t, store.dir, "app/token.age", configured); err != nil {
+		t.Errorf("expected the entry to be encrypted to the configured recipients: %v", err)
+	}
+	if _, err := decryptPassageFile(t, store.dir, "app/token.age", other); err == nil {
+		t.Error("expected the entry not to be encrypted to other recipients")
+	}
+}
+
+func TestGopassClient_AgeIdentities(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret\nusername: admin"})
+
+	// Move the identity out of the places gopass looks for it
+	identitiesFile := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.Rename(filepath.Join(store.Home, ".passage", "identities"), identitiesFile); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewGopassClient(store.Dir)
+	client.useAgeIdentities(identitiesFile)
+	defer client.Close(context.Background())
+
+	ctx := context.Background()
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+		t.Errorf("unexpected value %q: %v", value, err)
+	}
+	if err := client.SetSecret(ctx, "app/new", "n3w"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Entries stay readable by gopass
+	if err := os.Rename(identitiesFile, filepath.Join(store.Home, ".passage", "identities")); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := store.Get("app/new")
+	if err != nil || secret.Password() != "n3w" {
+		t.Errorf("expected gopass to read the written entry, got %v", err)
+	}
+}
+
+func TestProviderConfigure_Age(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+	identitiesFile := filepath.Join(t.TempDir(), "key.txt")
+	os.WriteFile(identitiesFile, []byte(newAgeIdentity(t).String()+"\n"), 0o600)
+	t.Setenv("PASSWORD_STORE_DIR", "")
+
+	tests := []struct {
+		name    string
+		config  map[string]tftypes.Value
+		wantErr string
+	}{
+		{
+			name: "gopass store",
+			config: map[string]tftypes.Value{
+				"store_path":          tftypes.NewValue(tftypes.String, "/srv/store"),
+				"age_identities_file": tftypes.NewValue(tftypes.String, identitiesFile),
+				"age_recipients_file": tftypes.NewValue(tftypes.String, "/srv/recipients"),
+			},
+		},
+		{
+			name: "passage store",
+			config: map[string]tftypes.Value{
+				"store_path":          tftypes.NewValue(tftypes.String, "/srv/store"),
+				"store_format":        tftypes.NewValue(tftypes.String, "passage"),
+				"age_identities_file": tftypes.NewValue(tftypes.String, identitiesFile),
+				"age_recipients_file": tftypes.NewValue(tftypes.String, "/srv/recipients"),
+			},
+		},
+		{
+			name:    "without store location",
+			config:  map[string]tftypes.Value{"age_identities_file": tftypes.NewValue(tftypes.String, identitiesFile)},
+			wantErr: "Invalid age_identities_file",
+		},
+		{
+			name: "missing file",
+			config: map[string]tftypes.Value{
+				"store_path":          tftypes.NewValue(tftypes.String, "/srv/store"),
+				"age_identities_file": tftypes.NewValue(tftypes.String, "/srv/missing"),
+			},
+			wantErr: "Invalid age_identities_file",
+		},
+		{
+			name: "pass store",
+			config: map[string]tftypes.Value{
+				"store_path":          tftypes.NewValue(tftypes.String, "/srv/store"),
+				"store_format":        tftypes.NewValue(tftypes.String, "pass"),
+				"age_identities_file": tftypes.NewValue(tftypes.String, identitiesFile),
+			},
+			wantErr: "Invalid age_identities_file",
+		},
+		{
+			name: "home_dir",
+			config: map[string]tftypes.Value{
+				"store_path":          tftypes.NewValue(tftypes.String, "/srv/store"),
+				"home_dir":            tftypes.NewValue(tftypes.String, "/srv/ci/gopass"),
+				"age_identities_file": tftypes.NewValue(tftypes.String, identitiesFile),
+			},
+			wantErr: "Invalid age_identities_file",
+		},
+		{
+			name: "recipients without identities",
+			config: map[string]tftypes.Value{
+				"store_path":          tftypes.NewValue(tftypes.String, "/srv/store"),
+				"age_recipients_file": tftypes.NewValue(tftypes.String, "/srv/recipients"),
+			},
+			wantErr: "Invalid age_recipients_file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &provider.ConfigureResponse{}
+			p.Configure(ctx, provider.ConfigureRequest{Config: newProviderConfig(t, p, tt.config)}, resp)
+			if tt.wantErr != "" {
+				if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tt.wantErr {
+					t.Fatalf("expected %q, got %v", tt.wantErr, resp.Diagnostics)
+				}
+				return
+			}
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+			client := resp.EphemeralResourceData.(*GopassClient)
+			if client.passageIdentities != identitiesFile || client.ageRecipients != "/srv/recipients" {
+				t.Errorf("unexpected client setup %q %q", client.passageIdentities, client.ageRecipients)
+			}
+			if wantAge := tt.name == "gopass store"; client.ageStores != wantAge {
+				t.Errorf("expected ageStores %v, got %v", wantAge, client.ageStores)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// ErrInvalidPath means a secret path is malformed, e.g. "../app/db".
+var ErrInvalidPath = errors.New("invalid secret path")
+
+// Secret paths and prefixes are handled here, so every part of the client
+// agrees on what "below a prefix" means. A normalized path has no leading,
+// trailing or repeated slashes: "/app//db/" becomes "app/db". A normalized
+// prefix is a normalized path with a trailing slash, or "" for the whole
+// store, so a plain string prefix test never matches "application" against
+// "app".
+
+// normalizePath returns p without leading, trailing or repeated slashes.
+func normalizePath(p string) string {
+	if !strings.HasPrefix(p, "/") && !strings.HasSuffix(p, "/") && !strings.Contains(p, "//") {
+		return p
+	}
+
+	segments := strings.Split(p, "/")
+	kept := segments[:0]
+	for _, s := range segments {
+		if s != "" {
+			kept = append(kept, s)
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+// normalizePrefix returns p as a prefix: normalized with a trailing slash, or
+// "" if p names the whole store.
+func normalizePrefix(p string) string {
+	p = normalizePath(p)
+	if p == "" {
+		return ""
+	}
+	return p + "/"
+}
+
+// joinPath returns the path of key below prefix.
+func joinPath(prefix, key string) string {
+	return normalizePath(normalizePrefix(prefix) + key)
+}
+
+// relativeKey returns the part of path below prefix, and false if path is
+// not below it. A path is not below itself.
+func relativeKey(path, prefix string) (string, bool) {
+	path, prefix = normalizePath(path), normalizePrefix(prefix)
+	if path == "" || !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	return path[len(prefix):], true
+}
+
+// underPrefix reports whether path is below prefix. Every path is below the
+// empty prefix.
+func underPrefix(path, prefix string) bool {
+	_, ok := relativeKey(path, prefix)
+	return ok
+}
+
+// withinPrefix reports whether path is prefix itself or below it, the way
+// gopass resolves mount points. Every path is within the empty prefix.
+func withinPrefix(path, prefix string) bool {
+	return strings.HasPrefix(normalizePrefix(path), normalizePrefix(prefix))
+}
+
+// isImmediateChild reports whether path is directly below prefix, not in a
+// subdirectory of it.
+func isImmediateChild(path, prefix string) bool {
+	key, ok := relativeKey(path, prefix)
+	return ok && !strings.Contains(key, "/")
+}
+
+// pathProblem returns why p is malformed as a secret path or, with prefix
+// set, as a prefix, "" if it is not. Prefixes may be empty and end with a
+// slash, and keep their leading and repeated slashes normalized away as
+// before; secret paths must be written out in normalized form.
+func pathProblem(p string, prefix bool) string {
+	if strings.IndexFunc(p, unicode.IsControl) >= 0 {
+		return "must not contain newlines or other control characters"
+	}
+	if prefix {
+		p = normalizePath(p)
+	}
+	switch {
+	case p == "" && !prefix:
+		return "must not be empty"
+	case strings.HasPrefix(p, "/"):
+		return "must not start with a slash"
+	case strings.HasSuffix(p, "/"):
+		return "must not end with a slash"
+	case strings.Contains(p, "//"):
+		return "must not contain empty segments (\"//\")"
+	}
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return fmt.Sprintf("must not contain %q segments", segment)
+		}
+	}
+	return ""
+}
+
+// validateSecretPath returns an ErrInvalidPath error if p is not a
+// well-formed secret path, before a malformed path reaches gopass.
+func validateSecretPath(p string) error {
+	if problem := pathProblem(p, false); problem != "" {
+		return fmt.Errorf("%w %q: %s", ErrInvalidPath, p, problem)
+	}
+	return nil
+}
+
+// secretPathValidator rejects malformed secret paths, or prefixes if prefix
+// is set, in the configuration.
+type secretPathValidator struct {
+	prefix bool
+}
+
+var _ validator.String = secretPathValidator{}
+
+func (v secretPathValidator) Description(ctx context.Context) string {
+	if v.prefix {
+		return "must be a secret path prefix without . or .. segments and control characters"
+	}
+	return "must be a secret path without leading or trailing slashes, empty, . or .. segments and control characters"
+}
+
+func (v secretPathValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v secretPathValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	p := req.ConfigValue.ValueString()
+	if problem := pathProblem(p, v.prefix); problem != "" {
+		kind := "Secret paths"
+		if v.prefix {
+			kind = "Path prefixes"
+		}
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid secret path",
+			fmt.Sprintf("%s %s, got %q.", kind, problem, p))
+	}
+}
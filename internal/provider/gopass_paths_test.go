@@ -0,0 +1,276 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"/":           "",
+		"//":          "",
+		"app/db":      "app/db",
+		"/app/db":     "app/db",
+		"app/db/":     "app/db",
+		"app//db":     "app/db",
+		"//app///db/": "app/db",
+		"app":         "app",
+		" app / db ":  " app / db ",
+	}
+	for in, want := range tests {
+		if got := normalizePath(in); got != want {
+			t.Errorf("normalizePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizePrefix(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"/":        "",
+		"app":      "app/",
+		"app/":     "app/",
+		"/app//":   "app/",
+		"app//env": "app/env/",
+	}
+	for in, want := range tests {
+		if got := normalizePrefix(in); got != want {
+			t.Errorf("normalizePrefix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRelativeKey(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		key          string
+		ok           bool
+	}{
+		{"app/db", "", "app/db", true},
+		{"app/db", "/", "app/db", true},
+		{"app/db", "app", "db", true},
+		{"app/db", "app/", "db", true},
+		{"app//db", "/app", "db", true},
+		{"app/env/KEY", "app", "env/KEY", true},
+		{"app", "app", "", false},
+		{"app", "app/", "", false},
+		{"application/db", "app", "", false},
+		{"other/db", "app", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		key, ok := relativeKey(tt.path, tt.prefix)
+		if key != tt.key || ok != tt.ok {
+			t.Errorf("relativeKey(%q, %q) = %q, %v, want %q, %v", tt.path, tt.prefix, key, ok, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestWithinPrefix(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"team/KEY", "team/", true},
+		{"team/", "team/", true},
+		{"team", "team/", true},
+		{"team//KEY", "/team", true},
+		{"teams/KEY", "team/", false},
+		{"anything", "", true},
+	}
+	for _, tt := range tests {
+		if got := withinPrefix(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("withinPrefix(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestIsImmediateChild(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"app/KEY", "app", true},
+		{"app/env/KEY", "app", false},
+		{"KEY", "", true},
+		{"app/KEY", "", false},
+		{"app", "app", false},
+	}
+	for _, tt := range tests {
+		if got := isImmediateChild(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("isImmediateChild(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	tests := []struct{ prefix, key, want string }{
+		{"app", "KEY", "app/KEY"},
+		{"app/", "KEY", "app/KEY"},
+		{"", "KEY", "KEY"},
+		{"/", "KEY", "KEY"},
+		{"app//env/", "/KEY", "app/env/KEY"},
+	}
+	for _, tt := range tests {
+		if got := joinPath(tt.prefix, tt.key); got != tt.want {
+			t.Errorf("joinPath(%q, %q) = %q, want %q", tt.prefix, tt.key, got, tt.want)
+		}
+	}
+}
+
+func FuzzNormalizePath(f *testing.F) {
+	for _, seed := range []string{"", "/", "app/db", "//app///db/", "a/b/c/", " / "} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		got := normalizePath(p)
+		if normalizePath(got) != got {
+			t.Errorf("not idempotent: %q -> %q -> %q", p, got, normalizePath(got))
+		}
+		if strings.HasPrefix(got, "/") || strings.HasSuffix(got, "/") || strings.Contains(got, "//") {
+			t.Errorf("normalizePath(%q) = %q keeps stray slashes", p, got)
+		}
+		if strings.ReplaceAll(got, "/", "") != strings.ReplaceAll(p, "/", "") {
+			t.Errorf("normalizePath(%q) = %q changed more than slashes", p, got)
+		}
+	})
+}
+
+func FuzzRelativeKey(f *testing.F) {
+	for _, seed := range [][2]string{{"app/db", "app"}, {"app", "app/"}, {"//a//b", "a/"}, {"application", "app"}, {"x", ""}} {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, path, prefix string) {
+		key, ok := relativeKey(path, prefix)
+		if !ok {
+			if key != "" {
+				t.Errorf("relativeKey(%q, %q) returned key %q without ok", path, prefix, key)
+			}
+			if underPrefix(path, prefix) {
+				t.Errorf("underPrefix(%q, %q) disagrees with relativeKey", path, prefix)
+			}
+			return
+		}
+
+		if key == "" || normalizePath(key) != key {
+			t.Errorf("relativeKey(%q, %q) = %q is not a normalized path", path, prefix, key)
+		}
+		if joined := joinPath(prefix, key); joined != normalizePath(path) {
+			t.Errorf("joinPath(%q, %q) = %q, want %q", prefix, key, joined, normalizePath(path))
+		}
+		if !withinPrefix(path, prefix) {
+			t.Errorf("withinPrefix(%q, %q) is false for a path below it", path, prefix)
+		}
+		if isImmediateChild(path, prefix) == strings.Contains(key, "/") {
+			t.Errorf("isImmediateChild(%q, %q) disagrees with key %q", path, prefix, key)
+		}
+	})
+}
+
+func TestMatchesPathPattern_NoSlashBypass(t *testing.T) {
+	policy := pathPolicy{denied: []string{"app/db", "prod/"}}
+
+	for _, p := range []string{"app//db", "/app/db", "app/db/", "prod//db", "/prod/db"} {
+		if policy.check(p) == "" {
+			t.Errorf("expected %q to be denied", p)
+		}
+	}
+	if reason := policy.check("production/db"); reason != "" {
+		t.Errorf("expected a sibling of the denied prefix to be allowed, got %q", reason)
+	}
+}
+
+func TestListSecrets_UnnormalizedPrefix(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"app/env/KEY":   "value",
+		"app/env/x/KEY": "nested",
+		"application/X": "other",
+	}))
+	ctx := context.Background()
+
+	for _, prefix := range []string{"app/env", "app//env/", "/app/env"} {
+		names, err := client.ListSecrets(ctx, prefix)
+		if err != nil || !slices.Equal(names, []string{"app/env/KEY"}) {
+			t.Errorf("%q: expected the immediate child, got %v (%v)", prefix, names, err)
+		}
+	}
+
+	values, err := client.GetEnvSecrets(ctx, "app//env/")
+	if err != nil || len(values) != 1 || values["KEY"] != "value" {
+		t.Errorf("expected the key relative to the prefix, got %v (%v)", values, err)
+	}
+
+	var partial *PartialResultError
+	if errors.As(err, &partial) {
+		t.Errorf("unexpected partial result %v", partial)
+	}
+}
+
+func TestMounts_UnnormalizedPrefix(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "root"}))
+	team := NewMemoryStore(map[string]string{"KEY": "team-value"})
+	client.addMount("//team//", func(ctx context.Context) (SecretStore, error) { return team, nil })
+	ctx := context.Background()
+
+	if value, err := client.GetSecret(ctx, "team/KEY"); err != nil || value != "team-value" {
+		t.Errorf("expected the mounted secret, got %q (%v)", value, err)
+	}
+	names, err := client.ListSecrets(ctx, "team")
+	if err != nil || !slices.Equal(names, []string{"team/KEY"}) {
+		t.Errorf("expected the mounted listing, got %v (%v)", names, err)
+	}
+}
+
+func TestPathProblem(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix bool
+		valid  bool
+	}{
+		{path: "app/db", valid: true},
+		{path: "app/db.v2/..hidden", valid: true},
+		{path: ""},
+		{path: "/app/db"},
+		{path: "app/db/"},
+		{path: "app//db"},
+		{path: "../app/db"},
+		{path: "app/./db"},
+		{path: "app/db\nother"},
+		{path: "app/db\x00"},
+		{path: "", prefix: true, valid: true},
+		{path: "app/", prefix: true, valid: true},
+		{path: "/app//env/", prefix: true, valid: true},
+		{path: "app/../env", prefix: true},
+		{path: "app\r\n", prefix: true},
+	}
+	for _, tt := range tests {
+		problem := pathProblem(tt.path, tt.prefix)
+		if valid := problem == ""; valid != tt.valid {
+			t.Errorf("pathProblem(%q, %v) = %q, want valid %v", tt.path, tt.prefix, problem, tt.valid)
+		}
+	}
+}
+
+func TestStoreFor_InvalidPath(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+	ctx := context.Background()
+
+	for _, p := range []string{"/app/db", "app/../db", "app/db\n"} {
+		if _, err := client.GetSecret(ctx, p); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%q: expected an invalid path error, got %v", p, err)
+		}
+		if err := client.SetSecret(ctx, p, "value"); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("%q: expected an invalid path error, got %v", p, err)
+		}
+	}
+	if got := errorSummary(validateSecretPath("../db"), "fallback"); got != "Invalid secret path" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pgpassWildcard matches any value in a .pgpass field.
+const pgpassWildcard = "*"
+
+// pgpassEntry is one line of a PostgreSQL password file.
+type pgpassEntry struct {
+	host, port, database, username, password string
+}
+
+// pgpassEscaper escapes the characters libpq treats specially in .pgpass.
+var pgpassEscaper = strings.NewReplacer(`\`, `\\`, `:`, `\:`)
+
+// renderPgpass returns the .pgpass lines of entries,
+// hostname:port:database:username:password, each followed by a newline.
+// Empty fields become wildcards; a literal "*" cannot be escaped and always
+// is one. Line breaks cannot be represented and are refused.
+func renderPgpass(entries []pgpassEntry) (string, error) {
+	var b strings.Builder
+	for i, e := range entries {
+		for j, value := range []string{e.host, e.port, e.database, e.username, e.password} {
+			if strings.ContainsAny(value, "\r\n") {
+				return "", fmt.Errorf("entry %d: %s contains a line break, which .pgpass cannot represent",
+					i, pgpassFieldNames[j])
+			}
+			if j > 0 {
+				b.WriteByte(':')
+			}
+			if value == "" && j < len(pgpassFieldNames)-1 {
+				value = pgpassWildcard
+			}
+			b.WriteString(pgpassEscaper.Replace(value))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// pgpassFieldNames names the fields of a .pgpass line in order.
+var pgpassFieldNames = []string{"host", "port", "database", "username", "password"}
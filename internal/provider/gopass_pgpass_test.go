@@ -0,0 +1,178 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestRenderPgpass(t *testing.T) {
+	got, err := renderPgpass([]pgpassEntry{
+		{host: "db.internal", port: "5432", database: "app", username: "admin", password: "s3cret"},
+		{username: "ro", password: `a:b\c`},
+		{host: "db", port: "5432", database: "app", username: "nopass"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "db.internal:5432:app:admin:s3cret\n" +
+		`*:*:*:ro:a\:b\\c` + "\n" +
+		"db:5432:app:nopass:\n"
+	if got != want {
+		t.Errorf("unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got, _ := renderPgpass(nil); got != "" {
+		t.Errorf("expected no content for no entries, got %q", got)
+	}
+
+	for _, entry := range []pgpassEntry{
+		{password: "line\nbreak"},
+		{host: "db\r", password: "s3cret"},
+	} {
+		_, err := renderPgpass([]pgpassEntry{{password: "ok"}, entry})
+		if err == nil || !strings.Contains(err.Error(), "entry 1: ") {
+			t.Errorf("%+v: expected a line break error for entry 1, got %v", entry, err)
+		}
+	}
+}
+
+func TestPgpassEphemeralResource_Metadata(t *testing.T) {
+	resp := &ephemeral.MetadataResponse{}
+	NewPgpassEphemeralResource().Metadata(context.Background(), ephemeral.MetadataRequest{ProviderTypeName: "gopass"}, resp)
+	if resp.TypeName != "gopass_pgpass" {
+		t.Errorf("unexpected type name %q", resp.TypeName)
+	}
+}
+
+func TestPgpassEphemeralResource_Open(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"db/admin":   "s3:cret\nhost: db.internal\nport: 5432\ndbname: app\nuser: admin",
+		"db/replica": "r3plica\nusername: ro",
+	}))
+
+	resp := openPgpassEphemeral(t, client, []map[string]string{
+		{"path": "db/admin"},
+		{"path": "db/replica", "host": "replica.internal", "database": "app"},
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data PgpassModel
+	resp.Diagnostics.Append(resp.Result.Get(context.Background(), &data)...)
+	want := `db.internal:5432:app:admin:s3\:cret` + "\n" +
+		"replica.internal:*:app:ro:r3plica\n"
+	if got := data.Content.ValueString(); got != want {
+		t.Errorf("unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPgpassEphemeralResource_Open_Errors(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"db/admin": "s3cret\nuser: admin",
+		"db/empty": "\nuser: nobody",
+	}))
+
+	tests := map[string]struct {
+		entries []map[string]string
+		summary string
+	}{
+		"not found": {
+			entries: []map[string]string{{"path": "db/admin"}, {"path": "db/missing"}},
+			summary: "Secret not found",
+		},
+		"no entries": {
+			entries: []map[string]string{},
+			summary: "No entries",
+		},
+		"line break": {
+			entries: []map[string]string{{"path": "db/admin", "host": "db\nevil"}},
+			summary: "Invalid .pgpass entry",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := openPgpassEphemeral(t, client, tt.entries)
+			errs := resp.Diagnostics.Errors()
+			if len(errs) != 1 || errs[0].Summary() != tt.summary {
+				t.Errorf("expected %q, got %v", tt.summary, resp.Diagnostics)
+			}
+		})
+	}
+
+	resp := openPgpassEphemeral(t, client, []map[string]string{{"path": "db/empty"}})
+	if resp.Diagnostics.HasError() || resp.Diagnostics.WarningsCount() != 1 {
+		t.Errorf("expected a warning about the empty password, got %v", resp.Diagnostics)
+	}
+}
+
+// openPgpassEphemeral opens a gopass_pgpass ephemeral resource for entries,
+// each a map of its configured attributes.
+func openPgpassEphemeral(t *testing.T, client *GopassClient, entries []map[string]string) *ephemeral.OpenResponse {
+	t.Helper()
+	return openEntriesEphemeral(t, &PgpassEphemeralResource{client: client},
+		[]string{"path", "host", "port", "database", "username"}, entries)
+}
+
+// openEntriesEphemeral opens an ephemeral resource with a list of string
+// attribute objects named entries, besides policy and the computed content.
+func openEntriesEphemeral(t *testing.T, r ephemeral.EphemeralResource, attrs []string, entries []map[string]string) *ephemeral.OpenResponse {
+	t.Helper()
+
+	ctx := context.Background()
+	schemaResp := &ephemeral.SchemaResponse{}
+	r.Schema(ctx, ephemeral.SchemaRequest{}, schemaResp)
+
+	entryType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{}}
+	for _, name := range attrs {
+		entryType.AttributeTypes[name] = tftypes.String
+	}
+	objectType := tftypes.Object{
+		AttributeTypes: map[string]tftypes.Type{
+			"entries": tftypes.List{ElementType: entryType},
+			"policy":  tftypes.String,
+			"content": tftypes.String,
+		},
+	}
+
+	values := make([]tftypes.Value, len(entries))
+	for i, entry := range entries {
+		object := map[string]tftypes.Value{}
+		for _, name := range attrs {
+			if value, ok := entry[name]; ok {
+				object[name] = tftypes.NewValue(tftypes.String, value)
+			} else {
+				object[name] = tftypes.NewValue(tftypes.String, nil)
+			}
+		}
+		values[i] = tftypes.NewValue(entryType, object)
+	}
+
+	req := ephemeral.OpenRequest{
+		Config: tfsdk.Config{
+			Schema: schemaResp.Schema,
+			Raw: tftypes.NewValue(objectType, map[string]tftypes.Value{
+				"entries": tftypes.NewValue(tftypes.List{ElementType: entryType}, values),
+				"policy":  tftypes.NewValue(tftypes.String, nil),
+				"content": tftypes.NewValue(tftypes.String, nil),
+			}),
+		},
+	}
+	resp := &ephemeral.OpenResponse{
+		Result: tfsdk.EphemeralResultData{
+			Schema: schemaResp.Schema,
+			Raw:    tftypes.NewValue(objectType, nil),
+		},
+	}
+
+	r.Open(ctx, req, resp)
+	return resp
+}
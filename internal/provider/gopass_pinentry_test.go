@@ -0,0 +1,152 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+)
+
+const pinentryTestPassphrase = "1234"
+
+// newPinentryClient returns a client for a GPG store whose passphrase prompts
+// are answered by the returned pinentry.
+func newPinentryClient(t *testing.T, entries map[string]string) (*GopassClient, *gopasstest.Pinentry) {
+	t.Helper()
+
+	store, pinentry := gopasstest.NewGPG(t, pinentryTestPassphrase, entries)
+	client := NewGopassClient(store.Dir)
+	t.Cleanup(func() { client.Close(context.Background()) })
+	return client, pinentry
+}
+
+func TestPinentry_CorrectPIN(t *testing.T) {
+	client, pinentry := newPinentryClient(t, map[string]string{"app/db": "s3cret", "app/api": "key"})
+	ctx := context.Background()
+
+	pinentry.Script(gopasstest.PIN(pinentryTestPassphrase))
+	for path, want := range map[string]string{"app/db": "s3cret", "app/api": "key"} {
+		if value, err := client.GetSecret(ctx, path); err != nil || value != want {
+			t.Fatalf("%s: expected %q, got %q (%v)", path, want, value, err)
+		}
+	}
+	if n := pinentry.Prompts(); n != 1 {
+		t.Errorf("expected the agent to cache the passphrase after one prompt, got %d prompts", n)
+	}
+
+	pinentry.Forget()
+	pinentry.Script(gopasstest.PIN(pinentryTestPassphrase))
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := pinentry.Prompts(); n != 1 {
+		t.Errorf("expected a new prompt once the agent forgot the passphrase, got %d", n)
+	}
+}
+
+func TestPinentry_Cancelled(t *testing.T) {
+	client, pinentry := newPinentryClient(t, map[string]string{"app/db": "s3cret"})
+
+	pinentry.Script(gopasstest.Cancel)
+	_, err := client.GetSecret(context.Background(), "app/db")
+	// gopass prints gpg's own message to stderr and only returns "failed to
+	// decrypt", so a dismissed prompt cannot be told apart from a missing key
+	if !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected a decryption failure, got %v", err)
+	}
+	if n := pinentry.Prompts(); n != 1 {
+		t.Errorf("expected a dismissed prompt not to be retried, got %d prompts", n)
+	}
+}
+
+func TestPinentry_Hang(t *testing.T) {
+	client, pinentry := newPinentryClient(t, map[string]string{"app/db": "s3cret"})
+	client.timeouts.Read = time.Second
+
+	pinentry.Script(gopasstest.Hang)
+	start := time.Now()
+	_, err := client.GetSecret(context.Background(), "app/db")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the read to give up after its deadline, took %s", elapsed)
+	}
+	if got := errorSummary(err, "fallback"); got != "Gopass operation timed out" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestPinentry_WrongPINOpensBreaker(t *testing.T) {
+	paths := []string{"a", "b", "c", "d", "e"}
+	entries := make(map[string]string, len(paths))
+	for _, p := range paths {
+		entries[p] = "value-" + p
+	}
+	client, pinentry := newPinentryClient(t, entries)
+	ctx := context.Background()
+
+	pinentry.Script(gopasstest.PIN("wrong"))
+	var prompts int
+	for i, p := range paths {
+		_, err := client.GetSecret(ctx, p)
+		if i < defaultMaxDecryptFailures {
+			if !errors.Is(err, ErrDecryptionFailed) {
+				t.Fatalf("%s: expected a decryption failure, got %v", p, err)
+			}
+			prompts = pinentry.Prompts()
+			continue
+		}
+		if !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("%s: expected the breaker to be open, got %v", p, err)
+		}
+	}
+	if prompts == 0 {
+		t.Fatal("expected the wrong PIN to be asked for")
+	}
+	if n := pinentry.Prompts(); n != prompts {
+		t.Errorf("expected no prompts once the breaker opened, got %d more", n-prompts)
+	}
+}
+
+func TestPinentry_NonInteractive(t *testing.T) {
+	t.Setenv(gopassGPGOptsEnv, "")
+	t.Setenv(passGPGOptsEnv, "")
+	store, pinentry := gopasstest.NewGPG(t, pinentryTestPassphrase, map[string]string{"app/db": "s3cret"})
+	ctx := context.Background()
+
+	client := NewGopassClient(store.Dir)
+	client.nonInteractive = true
+	t.Cleanup(func() { client.Close(context.Background()) })
+
+	pinentry.Script(gopasstest.PIN(pinentryTestPassphrase))
+	_, err := client.GetSecret(ctx, "app/db")
+	if !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected a decryption failure, got %v", err)
+	}
+	// The library does not pass gpg's diagnostics on, so the failure is not
+	// known to be a refused prompt
+	if got := errorSummary(err, "fallback"); got == nonInteractiveSummary {
+		t.Errorf("unexpected summary %q without gpg's diagnostics", got)
+	}
+	if n := pinentry.Prompts(); n != 0 {
+		t.Fatalf("expected no prompt, got %d", n)
+	}
+
+	// Once the agent has the passphrase, non-interactive reads succeed
+	if _, err := runGPG(ctx, "gpg", nil, "--batch", "--pinentry-mode=loopback", "--passphrase", pinentryTestPassphrase,
+		"--decrypt", store.Dir+"/app/db.gpg"); err != nil {
+		t.Fatalf("unlocking the key: %v", err)
+	}
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+		t.Fatalf("expected the unlocked key to decrypt, got %q (%v)", value, err)
+	}
+	if n := pinentry.Prompts(); n != 0 {
+		t.Errorf("expected no prompt, got %d", n)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// hostOS is the operating system the provider runs on; injectable for testing.
+var hostOS = runtime.GOOS
+
+// lookPath finds a binary in PATH; injectable for testing.
+var lookPath = exec.LookPath
+
+// windowsEnvVar matches a %NAME% reference in a Windows path.
+var windowsEnvVar = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// expandWindowsEnv replaces %NAME% references in path with the values of the
+// environment variables, leaving unset ones as they are, like cmd.exe does.
+func expandWindowsEnv(path string) string {
+	return windowsEnvVar.ReplaceAllStringFunc(path, func(ref string) string {
+		if value, ok := os.LookupEnv(ref[1 : len(ref)-1]); ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// homeRelative returns the part of path after a leading "~/", or "~\" on
+// Windows, and whether there is one.
+func homeRelative(path string) (string, bool) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return rest, true
+	}
+	if hostOS == "windows" {
+		return strings.CutPrefix(path, `~\`)
+	}
+	return "", false
+}
+
+// windowsGnupgHome returns the GnuPG home Gpg4win uses: %APPDATA%\gnupg.
+func windowsGnupgHome(userHomeDir func() (string, error)) (string, error) {
+	if appData := os.Getenv("APPDATA"); appData != "" {
+		return filepath.Join(appData, "gnupg"), nil
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "AppData", "Roaming", "gnupg"), nil
+}
+
+// gpg4winDirs returns the directories Gpg4win and GnuPG for Windows install
+// their binaries to, which the installers do not always add to PATH.
+func gpg4winDirs() []string {
+	var dirs []string
+	for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles", "ProgramW6432"} {
+		if root := os.Getenv(env); root != "" {
+			dirs = append(dirs, filepath.Join(root, "GnuPG", "bin"), filepath.Join(root, "Gpg4win", "bin"))
+		}
+	}
+	return dirs
+}
+
+// findGPGTool returns the first of the GnuPG binaries names found in PATH
+// or, on Windows, in the Gpg4win install directories.
+func findGPGTool(names ...string) (string, error) {
+	for _, name := range names {
+		if file, err := lookPath(name); err == nil {
+			return file, nil
+		}
+	}
+	if hostOS == "windows" {
+		for _, dir := range gpg4winDirs() {
+			for _, name := range names {
+				candidate := filepath.Join(dir, name+".exe")
+				if _, err := os.Stat(candidate); err == nil {
+					return candidate, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("%s not found in PATH", strings.Join(names, " or "))
+}
+
+// dialAgent connects to gpg-agent's socket. On Windows, GnuPG emulates Unix
+// sockets with a file holding a localhost TCP port and a nonce that the
+// client sends first.
+func dialAgent(ctx context.Context, socket string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: agentDialTimeout}
+	if hostOS != "windows" {
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+
+	content, err := os.ReadFile(socket)
+	if err != nil {
+		return nil, err
+	}
+	portLine, nonce, ok := bytes.Cut(content, []byte("\n"))
+	port, err := strconv.Atoi(strings.TrimSpace(string(portLine)))
+	if !ok || err != nil || len(nonce) != 16 {
+		return nil, fmt.Errorf("%s is not a GnuPG socket file", socket)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stubHostOS makes the provider behave as if it ran on goos.
+func stubHostOS(t *testing.T, goos string) {
+	t.Helper()
+	previous := hostOS
+	hostOS = goos
+	t.Cleanup(func() { hostOS = previous })
+}
+
+func TestExpandHome_Windows(t *testing.T) {
+	stubHostOS(t, "windows")
+	t.Setenv("LOCALAPPDATA", `C:\Users\dev\AppData\Local`)
+	client := NewGopassClient("")
+	client.userHomeDir = func() (string, error) { return "/home/dev", nil }
+
+	tests := map[string]string{
+		`%LOCALAPPDATA%\gopass\stores\root`:      `C:\Users\dev\AppData\Local\gopass\stores\root`,
+		`%UNSET_PROVIDER_VAR%\store`:             `%UNSET_PROVIDER_VAR%\store`,
+		`~\store`:                                filepath.Join("/home/dev", "store"),
+		"~/store":                                filepath.Join("/home/dev", "store"),
+		`\\wsl$\Ubuntu\home\dev\.password-store`: `\\wsl$\Ubuntu\home\dev\.password-store`,
+	}
+	for path, want := range tests {
+		if got, err := client.expandHome(path); err != nil || got != want {
+			t.Errorf("expandHome(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+}
+
+func TestExpandHome_NotWindows(t *testing.T) {
+	stubHostOS(t, "linux")
+	t.Setenv("HOME_PROVIDER_VAR", "/x")
+	client := NewGopassClient("")
+	client.userHomeDir = func() (string, error) { return "/home/dev", nil }
+
+	for _, path := range []string{`~\store`, "%HOME_PROVIDER_VAR%/store"} {
+		if got, _ := client.expandHome(path); got != path {
+			t.Errorf("expandHome(%q) = %q, want it unchanged", path, got)
+		}
+	}
+}
+
+func TestGnupgHomeDir_Windows(t *testing.T) {
+	stubHostOS(t, "windows")
+	t.Setenv("GNUPGHOME", "")
+	home := func() (string, error) { return "/home/dev", nil }
+
+	t.Setenv("APPDATA", "/appdata")
+	if dir, err := gnupgHomeDir(home); err != nil || dir != filepath.Join("/appdata", "gnupg") {
+		t.Errorf("unexpected GnuPG home %q: %v", dir, err)
+	}
+	t.Setenv("APPDATA", "")
+	if dir, err := gnupgHomeDir(home); err != nil || dir != filepath.Join("/home/dev", "AppData", "Roaming", "gnupg") {
+		t.Errorf("unexpected GnuPG home %q: %v", dir, err)
+	}
+	t.Setenv("GNUPGHOME", "/custom")
+	if dir, _ := gnupgHomeDir(home); dir != "/custom" {
+		t.Errorf("expected GNUPGHOME to win, got %q", dir)
+	}
+}
+
+func TestFindGPGTool(t *testing.T) {
+	previous := lookPath
+	t.Cleanup(func() { lookPath = previous })
+	lookPath = func(name string) (string, error) {
+		if name == "gpg" {
+			return "/usr/bin/gpg", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	if file, err := findGPGTool("gpg2", "gpg"); err != nil || file != "/usr/bin/gpg" {
+		t.Errorf("unexpected binary %q: %v", file, err)
+	}
+
+	programFiles := t.TempDir()
+	bin := filepath.Join(programFiles, "GnuPG", "bin")
+	os.MkdirAll(bin, 0o700)
+	os.WriteFile(filepath.Join(bin, "gpgconf.exe"), nil, 0o700)
+	t.Setenv("ProgramFiles(x86)", programFiles)
+
+	stubHostOS(t, "linux")
+	if _, err := findGPGTool("gpgconf"); err == nil {
+		t.Error("expected the Gpg4win directories to be searched on Windows only")
+	}
+	stubHostOS(t, "windows")
+	if file, err := findGPGTool("gpgconf"); err != nil || file != filepath.Join(bin, "gpgconf.exe") {
+		t.Errorf("unexpected binary %q: %v", file, err)
+	}
+}
+
+func TestDialAgent_WindowsSocketFile(t *testing.T) {
+	stubHostOS(t, "windows")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp not available: %v", err)
+	}
+	defer listener.Close()
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		nonce := make([]byte, 16)
+		io.ReadFull(conn, nonce)
+		received <- nonce
+	}()
+
+	nonce := []byte("0123456789abcdef")
+	socket := filepath.Join(t.TempDir(), "S.gpg-agent")
+	content := append([]byte(fmt.Sprintf("%d\n", listener.Addr().(*net.TCPAddr).Port)), nonce...)
+	os.WriteFile(socket, content, 0o600)
+
+	conn, err := dialAgent(context.Background(), socket)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conn.Close()
+	if got := <-received; !bytes.Equal(got, nonce) {
+		t.Errorf("agent received %q, want the nonce", got)
+	}
+
+	os.WriteFile(socket, []byte("not a socket file"), 0o600)
+	if _, err := dialAgent(context.Background(), socket); err == nil {
+		t.Error("expected an error for a malformed socket file")
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// ErrPolicyViolation is returned for accesses to secret paths outside the
+// provider's path policy or the policy a resource declared.
+var ErrPolicyViolation = errors.New("secret path not allowed by policy")
+
+// pathPolicy restricts the secret paths that may be accessed. Entries ending
+// in "/" match every secret below that prefix, other entries match exactly.
+// Denied entries win over allowed ones; an empty allow list allows every
+// path that is not denied.
+type pathPolicy struct {
+	allowed []string
+	denied  []string
+}
+
+// matchesPathPattern reports whether path matches a policy entry.
+func matchesPathPattern(path, pattern string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		return underPrefix(path, pattern)
+	}
+	return normalizePath(path) == normalizePath(pattern)
+}
+
+// check returns the reason path is not permitted, or "" if it is.
+func (p pathPolicy) check(path string) string {
+	for _, pattern := range p.denied {
+		if matchesPathPattern(path, pattern) {
+			return fmt.Sprintf("denied by %q", pattern)
+		}
+	}
+	if len(p.allowed) == 0 {
+		return ""
+	}
+	for _, pattern := range p.allowed {
+		if matchesPathPattern(path, pattern) {
+			return ""
+		}
+	}
+	return "not in the allowed paths"
+}
+
+// policyKey is the context key for the named policy a resource runs under.
+type policyKey struct{}
+
+// withPolicy records in ctx the named policy the following store accesses
+// must satisfy, in addition to the provider's own. An empty name leaves ctx
+// unchanged.
+func withPolicy(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, policyKey{}, name)
+}
+
+// policyFrom returns the policy name recorded by withPolicy, or "".
+func policyFrom(ctx context.Context) string {
+	name, _ := ctx.Value(policyKey{}).(string)
+	return name
+}
+
+// policySet holds the provider-wide path policy and the named policies
+// resources can declare.
+type policySet struct {
+	provider pathPolicy
+	named    map[string]pathPolicy
+	// violations records denied accesses per resource; nil until a policy is configured
+	violations *accessLog
+}
+
+// enabled reports whether any policy is configured.
+func (s *policySet) enabled() bool {
+	return s.violations != nil
+}
+
+// enforcePolicy returns an error wrapping ErrPolicyViolation if the resource
+// in ctx may not access path, and records the violation for the summary.
+func (c *GopassClient) enforcePolicy(ctx context.Context, path, kind string) error {
+	name := policyFrom(ctx)
+	if !c.policies.enabled() && name == "" {
+		return nil
+	}
+
+	reason := c.policies.provider.check(path)
+	scope := "the provider's path policy"
+	if reason == "" && name != "" {
+		policy, ok := c.policies.named[name]
+		if !ok {
+			return fmt.Errorf("%w: %s declares policy %q, which is not configured in the provider's policies",
+				ErrPolicyViolation, accessorFrom(ctx), name)
+		}
+		reason = policy.check(path)
+		scope = fmt.Sprintf("policy %q", name)
+	}
+	if reason == "" {
+		return nil
+	}
+
+	c.policies.violations.record(ctx, path, kind)
+	tflog.Warn(ctx, "gopass policy violation", map[string]interface{}{
+		"resource": accessorFrom(ctx),
+		"path":     c.logPath(path),
+		"access":   kind,
+		"reason":   reason,
+	})
+	return fmt.Errorf("%w: %s may not %s %q under %s: %s",
+		ErrPolicyViolation, accessorFrom(ctx), kind, path, scope, reason)
+}
+
+// permitted reports whether the resource in ctx may access path, without
+// recording anything. Used to keep policy-protected paths out of suggestions.
+func (c *GopassClient) permitted(ctx context.Context, path string) bool {
+	if c.policies.provider.check(path) != "" {
+		return false
+	}
+	name := policyFrom(ctx)
+	if name == "" {
+		return true
+	}
+	policy, ok := c.policies.named[name]
+	return ok && policy.check(path) == ""
+}
+
+// logViolations logs every denied access of the run, grouped by resource.
+func (s *policySet) logViolations(ctx context.Context) {
+	if fields := s.violations.summaryFields("violations"); fields != nil {
+		tflog.Warn(ctx, "gopass policy violations", fields)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func newPolicyTestClient() *GopassClient {
+	entries := make(map[string]string)
+	for _, path := range []string{"app/db", "app/admin/root", "billing/api", "billing/db"} {
+		entries[path] = "value-of-" + path
+	}
+
+	client := NewGopassClientWithStore(NewMemoryStore(entries))
+	client.policies = policySet{
+		provider: pathPolicy{denied: []string{"app/admin/"}},
+		named: map[string]pathPolicy{
+			"billing": {allowed: []string{"billing/"}, denied: []string{"billing/db"}},
+		},
+		violations: &accessLog{},
+	}
+	return client
+}
+
+func TestPathPolicy_Check(t *testing.T) {
+	policy := pathPolicy{
+		allowed: []string{"app/", "shared/token"},
+		denied:  []string{"app/admin/"},
+	}
+
+	tests := []struct {
+		path      string
+		permitted bool
+	}{
+		{"app/db", true},
+		{"app/nested/db", true},
+		{"shared/token", true},
+		{"shared/token2", false},
+		{"app/admin/root", false},
+		{"application/db", false},
+		{"other", false},
+	}
+	for _, tt := range tests {
+		if got := policy.check(tt.path) == ""; got != tt.permitted {
+			t.Errorf("%s: expected permitted=%v, reason %q", tt.path, tt.permitted, policy.check(tt.path))
+		}
+	}
+
+	if reason := (pathPolicy{}).check("anything"); reason != "" {
+		t.Errorf("expected empty policy to allow everything, got %q", reason)
+	}
+}
+
+func TestGopassClient_ProviderPolicy(t *testing.T) {
+	client := newPolicyTestClient()
+	ctx := withAccessor(context.Background(), "ephemeral.gopass_secret", "app/admin/root")
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := client.GetSecret(ctx, "app/admin/root")
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("expected policy violation, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `ephemeral.gopass_secret "app/admin/root"`) || !strings.Contains(err.Error(), `denied by "app/admin/"`) {
+		t.Errorf("expected resource and reason in error, got %q", err.Error())
+	}
+	if got := errorSummary(err, "fallback"); got != "Secret path not allowed by policy" {
+		t.Errorf("unexpected summary %q", got)
+	}
+
+	if err := client.SetSecret(ctx, "app/admin/new", "value"); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("expected write to be refused, got %v", err)
+	}
+	if _, err := client.store.Get(ctx, "app/admin/new", "latest"); err == nil {
+		t.Error("expected refused write not to reach the store")
+	}
+	if err := client.RemoveSecret(ctx, "app/admin/root"); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("expected removal to be refused, got %v", err)
+	}
+	if _, err := client.store.Get(ctx, "app/admin/root", "latest"); err != nil {
+		t.Error("expected refused removal not to reach the store")
+	}
+}
+
+func TestGopassClient_NamedPolicy(t *testing.T) {
+	client := newPolicyTestClient()
+	ctx := withPolicy(withAccessor(context.Background(), "ephemeral.gopass_env", "billing"), "billing")
+
+	if _, err := client.GetSecret(ctx, "billing/api"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, path := range []string{"app/db", "billing/db", "app/admin/root"} {
+		if _, err := client.GetSecret(ctx, path); !errors.Is(err, ErrPolicyViolation) {
+			t.Errorf("%s: expected policy violation, got %v", path, err)
+		}
+	}
+
+	// Resources without a policy are only bound by the provider's lists
+	if _, err := client.GetSecret(context.Background(), "billing/db"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := client.GetSecret(withPolicy(ctx, "unknown"), "billing/api")
+	if !errors.Is(err, ErrPolicyViolation) || !strings.Contains(err.Error(), `policy "unknown"`) {
+		t.Errorf("expected unknown policy to be refused, got %v", err)
+	}
+}
+
+func TestGopassClient_NamedPolicyWithoutProviderLists(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = newMockStore()
+
+	_, err := client.GetSecret(withPolicy(context.Background(), "billing"), "app/db")
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("expected a declared but unconfigured policy to be refused, got %v", err)
+	}
+}
+
+func TestGetEnvSecrets_PolicyViolationFailsRead(t *testing.T) {
+	client := newPolicyTestClient()
+	ctx := withPolicy(context.Background(), "billing")
+
+	values, err := client.GetEnvSecrets(ctx, "billing")
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("expected policy violation, got %v (values %v)", err, values)
+	}
+	if values != nil {
+		t.Errorf("expected no values, got %v", values)
+	}
+}
+
+func TestReadError_SuggestionsRespectPolicy(t *testing.T) {
+	client := newPolicyTestClient()
+	client.policies.provider = pathPolicy{denied: []string{"billing/api"}}
+
+	_, err := client.GetSecret(context.Background(), "billing/apii")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if strings.Contains(err.Error(), "- billing/api") {
+		t.Errorf("expected denied path not to be suggested, got %q", err.Error())
+	}
+}
+
+func TestGopassClient_Close_LogsPolicyViolations(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	client := newPolicyTestClient()
+	resourceCtx := withPolicy(withAccessor(ctx, "gopass_secret", "app/db"), "billing")
+	if _, err := client.GetSecret(resourceCtx, "app/db"); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("expected policy violation, got %v", err)
+	}
+	if err := client.SetSecret(resourceCtx, "app/db", "value"); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("expected policy violation, got %v", err)
+	}
+
+	if summary := client.policies.violations.summary(); !slices.Equal(summary[`gopass_secret "app/db"`], []string{"app/db (read, write)"}) {
+		t.Errorf("unexpected violations %v", summary)
+	}
+
+	client.Close(ctx)
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatalf("failed to decode log output: %v", err)
+	}
+	for _, entry := range entries {
+		if entry["@message"] != "gopass policy violations" {
+			continue
+		}
+		if entry["@level"] != "warn" || entry["violations"] != float64(1) {
+			t.Errorf("unexpected summary entry %v", entry)
+		}
+		return
+	}
+	t.Errorf("expected policy violations in log, got %v", entries)
+}
+
+func TestProviderConfigure_Policies(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	list := tftypes.List{ElementType: tftypes.String}
+	pathList := func(values ...string) tftypes.Value {
+		elements := make([]tftypes.Value, 0, len(values))
+		for _, value := range values {
+			elements = append(elements, tftypes.NewValue(tftypes.String, value))
+		}
+		return tftypes.NewValue(list, elements)
+	}
+	policyType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"allowed_paths": list,
+		"denied_paths":  list,
+	}}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"denied_paths": pathList("app/admin/"),
+			"policies": tftypes.NewValue(tftypes.Map{ElementType: policyType}, map[string]tftypes.Value{
+				"billing": tftypes.NewValue(policyType, map[string]tftypes.Value{
+					"allowed_paths": pathList("billing/"),
+					"denied_paths":  tftypes.NewValue(list, nil),
+				}),
+			}),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	client := resp.EphemeralResourceData.(*GopassClient)
+	if !client.policies.enabled() {
+		t.Error("expected policies to be enforced")
+	}
+	if !slices.Equal(client.policies.provider.denied, []string{"app/admin/"}) {
+		t.Errorf("unexpected provider policy %+v", client.policies.provider)
+	}
+	if billing := client.policies.named["billing"]; !slices.Equal(billing.allowed, []string{"billing/"}) || billing.denied != nil {
+		t.Errorf("unexpected billing policy %+v", billing)
+	}
+
+	// Without any lists nothing is enforced
+	resp = &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{Config: newProviderConfig(t, p, nil)}, resp)
+	if client := resp.EphemeralResourceData.(*GopassClient); client.policies.enabled() {
+		t.Error("expected no policy without configuration")
+	}
+}
+
+func TestProviderConfigure_InvalidPolicyEntry(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"allowed_paths": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+				tftypes.NewValue(tftypes.String, "/"),
+			}),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error for an empty policy entry")
+	}
+	if got := resp.Diagnostics.Errors()[0].Summary(); got != "Invalid path policy entry" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
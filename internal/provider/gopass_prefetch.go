@@ -0,0 +1,201 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// prefetcher resolves a configured set of secrets in one pass before the
+// first regular read, so that all PIN prompts and token touches happen
+// together at the start of a run instead of being spread across it.
+type prefetcher struct {
+	// paths are secret paths; entries ending in "/" select every secret below that prefix.
+	paths []string
+	// secure keeps prefetched secrets in locked memory (secure_memory)
+	secure bool
+
+	once    sync.Once
+	mu      sync.RWMutex
+	secrets map[string]gopass.Secret
+	locked  map[string]*lockedBuffer
+}
+
+// newPrefetcher returns a prefetcher for the given paths, or nil if there is nothing to prefetch.
+func newPrefetcher(paths []string) *prefetcher {
+	if len(paths) == 0 {
+		return nil
+	}
+	return &prefetcher{
+		paths:   paths,
+		secrets: make(map[string]gopass.Secret),
+		locked:  make(map[string]*lockedBuffer),
+	}
+}
+
+// run performs the prefetch pass exactly once. Concurrent callers wait until
+// the pass has finished so they can benefit from its results. Every target
+// is read through the store it belongs to, which with mounts is not
+// necessarily the store of the read that started the pass.
+func (p *prefetcher) run(ctx context.Context, c *GopassClient) {
+	p.once.Do(func() {
+		start := time.Now()
+
+		targets, _ := expandPaths(ctx, c, p.paths)
+
+		tflog.Info(ctx, "Prefetching gopass secrets", map[string]interface{}{
+			"count": len(targets),
+		})
+
+		failed := 0
+		for _, path := range targets {
+			secret, err := p.fetch(ctx, c, path)
+			if err != nil {
+				// Not fatal: the resource reading this path will surface the error
+				failed++
+				tflog.Warn(ctx, "Failed to prefetch secret", map[string]interface{}{
+					"path":  c.logPath(path),
+					"error": c.logError(err),
+				})
+				continue
+			}
+
+			p.keep(ctx, c, path, secret)
+		}
+
+		tflog.Info(ctx, "Prefetched gopass secrets", map[string]interface{}{
+			"count":    len(targets) - failed,
+			"failed":   failed,
+			"duration": time.Since(start).String(),
+		})
+	})
+}
+
+// fetch decrypts the latest revision of path from its store.
+func (p *prefetcher) fetch(ctx context.Context, c *GopassClient, path string) (gopass.Secret, error) {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.decryptSecret(ctx, store, path, "latest")
+}
+
+// expandPaths resolves entries in the prefetch_paths format into secret
+// paths: entries ending in "/" select every secret below that prefix, others
+// are taken as they are. Prefixes that cannot be listed are logged, skipped
+// and returned with their errors.
+func expandPaths(ctx context.Context, c *GopassClient, entries []string) (targets []string, unlisted map[string]error) {
+	seen := make(map[string]bool)
+
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			targets = append(targets, path)
+		}
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry, "/") {
+			add(entry)
+			continue
+		}
+
+		err := c.WalkSecrets(ctx, entry, func(path string) error {
+			add(path)
+			return nil
+		})
+		if err != nil {
+			tflog.Warn(ctx, "Failed to expand path prefix", map[string]interface{}{
+				"prefix": c.logPath(entry),
+				"error":  c.logError(err),
+			})
+			if unlisted == nil {
+				unlisted = make(map[string]error)
+			}
+			unlisted[entry] = err
+		}
+	}
+
+	return targets, unlisted
+}
+
+// keep stores a prefetched secret. In secure mode only its serialized form is
+// kept, in a locked buffer; if the platform or the locked memory limit does
+// not allow that, the secret is kept in regular memory with a warning.
+func (p *prefetcher) keep(ctx context.Context, c *GopassClient, path string, secret gopass.Secret) {
+	if p.secure {
+		buf, err := newLockedBuffer(secret.Bytes())
+		if err == nil {
+			p.mu.Lock()
+			p.locked[path] = buf
+			p.mu.Unlock()
+			return
+		}
+
+		tflog.Warn(ctx, "Failed to lock prefetched secret in memory", map[string]interface{}{
+			"path":  c.logPath(path),
+			"error": err.Error(),
+		})
+		c.warnings.addOnce("secure-memory", "Secure memory unavailable",
+			fmt.Sprintf("secure_memory is enabled, but prefetched secrets could not be locked into memory: %s. "+
+				"They are kept in regular memory instead, which the operating system may swap to disk. "+
+				"On Linux, raise the locked memory limit (ulimit -l) of the process running OpenTofu.", err))
+	}
+
+	p.mu.Lock()
+	p.secrets[path] = secret
+	p.mu.Unlock()
+}
+
+// lookup returns a prefetched secret. Secrets held in locked memory are
+// parsed into a fresh copy on every lookup.
+func (p *prefetcher) lookup(path string) (gopass.Secret, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if buf, ok := p.locked[path]; ok {
+		secret := buf.secret()
+		return secret, secret != nil
+	}
+	secret, ok := p.secrets[path]
+	return secret, ok
+}
+
+// forget drops all prefetched secrets. Later reads decrypt again.
+func (p *prefetcher) forget() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.secrets)
+	for _, buf := range p.locked {
+		buf.free()
+	}
+	clear(p.locked)
+}
+
+// forgetPath drops a single prefetched secret, e.g. after it was overwritten.
+func (p *prefetcher) forgetPath(path string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.secrets, path)
+	if buf, ok := p.locked[path]; ok {
+		buf.free()
+		delete(p.locked, path)
+	}
+}
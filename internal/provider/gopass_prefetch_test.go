@@ -0,0 +1,141 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// mockCountingStore records the order of Get calls
+type mockCountingStore struct {
+	SecretStore
+	mu    sync.Mutex
+	reads []string
+}
+
+func (m *mockCountingStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	m.mu.Lock()
+	m.reads = append(m.reads, name)
+	m.mu.Unlock()
+	return m.SecretStore.Get(ctx, name, revision)
+}
+
+func (m *mockCountingStore) readCount(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, read := range m.reads {
+		if read == name {
+			count++
+		}
+	}
+	return count
+}
+
+func newPrefetchTestStore() *mockCountingStore {
+	entries := make(map[string]string)
+	for _, path := range []string{"app/db/password", "app/env/KEY1", "app/env/KEY2", "other/token"} {
+		entries[path] = "value-of-" + path
+	}
+	return &mockCountingStore{SecretStore: NewMemoryStore(entries)}
+}
+
+func TestNewPrefetcher_Empty(t *testing.T) {
+	if p := newPrefetcher(nil); p != nil {
+		t.Error("expected nil prefetcher for empty path list")
+	}
+}
+
+func TestGopassClient_Prefetch_ResolvesAllPathsOnFirstRead(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newPrefetchTestStore()
+	client.store = mockStore
+	client.prefetch = newPrefetcher([]string{"app/db/password", "app/env/"})
+
+	ctx := context.Background()
+
+	// The first read triggers the prefetch pass for all configured paths
+	value, err := client.GetSecret(ctx, "app/env/KEY2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "value-of-app/env/KEY2" {
+		t.Errorf("unexpected value %q", value)
+	}
+	if len(mockStore.reads) != 3 {
+		t.Errorf("expected 3 reads during prefetch, got %d: %v", len(mockStore.reads), mockStore.reads)
+	}
+
+	// Subsequent reads of prefetched paths don't touch the store again
+	for _, path := range []string{"app/db/password", "app/env/KEY1", "app/env/KEY2"} {
+		if _, err := client.GetSecret(ctx, path); err != nil {
+			t.Errorf("unexpected error for %q: %v", path, err)
+		}
+		if count := mockStore.readCount(path); count != 1 {
+			t.Errorf("expected %q to be decrypted once, got %d", path, count)
+		}
+	}
+
+	// Paths outside the prefetch set are read on demand
+	if _, err := client.GetSecret(ctx, "other/token"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if count := mockStore.readCount("other/token"); count != 1 {
+		t.Errorf("expected on-demand read of other/token, got %d reads", count)
+	}
+}
+
+func TestGopassClient_Prefetch_MissingPathIsNotFatal(t *testing.T) {
+	client := NewGopassClient("")
+	mockStore := newPrefetchTestStore()
+	client.store = mockStore
+	client.prefetch = newPrefetcher([]string{"does/not/exist", "app/db/password"})
+
+	value, err := client.GetSecret(context.Background(), "app/db/password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "value-of-app/db/password" {
+		t.Errorf("unexpected value %q", value)
+	}
+
+	// The missing path still fails when it is actually requested
+	if _, err := client.GetSecret(context.Background(), "does/not/exist"); err == nil {
+		t.Error("expected error for missing secret")
+	}
+}
+
+func TestGopassClient_Prefetch_AcrossMounts(t *testing.T) {
+	root := &mockCountingStore{SecretStore: NewMemoryStore(map[string]string{"app/token": "root-token"})}
+	team := &mockCountingStore{SecretStore: NewMemoryStore(map[string]string{"db/password": "team-db", "api": "team-api"})}
+	ops := &mockCountingStore{SecretStore: NewMemoryStore(map[string]string{"key": "ops-key"})}
+
+	client := NewGopassClientWithStore(root)
+	client.addMount("team", func(ctx context.Context) (SecretStore, error) { return team, nil })
+	client.addMount("ops", func(ctx context.Context) (SecretStore, error) { return ops, nil })
+	client.prefetch = newPrefetcher([]string{"app/token", "team/db/password", "ops/key"})
+	ctx := context.Background()
+
+	// A read through the team mount prefetches from every store
+	if value, err := client.GetSecret(ctx, "team/api"); err != nil || value != "team-api" {
+		t.Fatalf("unexpected value %q: %v", value, err)
+	}
+	if root.readCount("app/token") != 1 || team.readCount("db/password") != 1 || ops.readCount("key") != 1 {
+		t.Errorf("expected each target read from its own store, got root %v, team %v, ops %v", root.reads, team.reads, ops.reads)
+	}
+
+	for path, want := range map[string]string{"app/token": "root-token", "team/db/password": "team-db", "ops/key": "ops-key"} {
+		if value, err := client.GetSecret(ctx, path); err != nil || value != want {
+			t.Errorf("%s: unexpected value %q: %v", path, value, err)
+		}
+	}
+	if root.readCount("app/token") != 1 || team.readCount("db/password") != 1 || ops.readCount("key") != 1 {
+		t.Errorf("expected prefetched secrets not to be read again, got root %v, team %v, ops %v", root.reads, team.reads, ops.reads)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA-1 is what the Pwned Passwords corpus is keyed by
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// pwnedPasswordsAPI is the Have I Been Pwned range endpoint. Only the first
+// five hex digits of a password's SHA-1 are sent to it (k-anonymity).
+const pwnedPasswordsAPI = "https://api.pwnedpasswords.com/range/"
+
+// pwnedCheckTimeout bounds a single range request.
+const pwnedCheckTimeout = 10 * time.Second
+
+// ErrPwnedPassword is returned for writes refused because the password
+// appears in the Pwned Passwords corpus, or because that could not be checked.
+var ErrPwnedPassword = errors.New("password appears in a breach corpus")
+
+// pwnedChecker refuses passwords known from data breaches, looking them up
+// online, in a local hash list or both. A nil *pwnedChecker accepts every
+// password.
+type pwnedChecker struct {
+	apiURL     string // range endpoint, empty unless the online check is enabled
+	httpClient *http.Client
+	file       string // local SHA-1 list ordered by hash, empty unless set
+}
+
+// check returns an error wrapping ErrPwnedPassword if password is pwned.
+// A check that cannot be completed refuses the password as well: a gate
+// that opens whenever the network is down protects nothing.
+func (p *pwnedChecker) check(ctx context.Context, path, password string) error {
+	if p == nil || password == "" {
+		return nil
+	}
+
+	sum := sha1.Sum([]byte(password)) //nolint:gosec // see import
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	for _, source := range []struct {
+		name   string
+		lookup func() (int, error)
+		used   bool
+	}{
+		{"local hash list", func() (int, error) { return lookupHashFile(p.file, hash) }, p.file != ""},
+		{"Pwned Passwords API", func() (int, error) { return p.lookupAPI(ctx, hash) }, p.apiURL != ""},
+	} {
+		if !source.used {
+			continue
+		}
+		count, err := source.lookup()
+		if err != nil {
+			return fmt.Errorf("%w: refusing to write secret %q, the %s could not be checked: %s", ErrPwnedPassword, path, source.name, err.Error())
+		}
+		if count > 0 {
+			tflog.Warn(ctx, "Refused pwned password", map[string]interface{}{
+				"source": source.name,
+				"count":  count,
+			})
+			return fmt.Errorf("%w: refusing to write secret %q, its password was seen %d time(s) in data breaches "+
+				"according to the %s. Use a generated password instead", ErrPwnedPassword, path, count, source.name)
+		}
+	}
+	return nil
+}
+
+// lookupAPI returns how often the password with the given SHA-1 was seen,
+// sending only the first five hex digits of hash.
+func (p *pwnedChecker) lookupAPI(ctx context.Context, hash string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, pwnedCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+hash[:5], http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+	// Padding hides the number of matches from anyone watching the traffic
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "terraform-provider-gopass")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if suffix, count, ok := parseHashCount(scanner.Text()); ok && suffix == hash[5:] {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// parseHashCount parses a "HASH:COUNT" line of the Pwned Passwords formats.
+func parseHashCount(line string) (hash string, count int, ok bool) {
+	hash, countText, found := strings.Cut(strings.TrimSpace(line), ":")
+	if !found {
+		return "", 0, false
+	}
+	count, err := strconv.Atoi(countText)
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.ToUpper(hash), count, true
+}
+
+// lookupHashFile returns how often the password with the given SHA-1 was
+// seen according to file, a "HASH:COUNT" list ordered by hash as published
+// by Have I Been Pwned. The file is binary searched, so even the full corpus
+// takes a few dozen reads.
+func lookupHashFile(file, hash string) (int, error) {
+	f, err := os.Open(file) //nolint:gosec // path comes from the provider configuration
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// lo is always the start of a line; the line for hash, if any, starts in [lo, hi)
+	lo, hi := int64(0), info.Size()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := lineAt(f, mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		lineHash, count, ok := parseHashCount(line)
+		if !ok {
+			return 0, fmt.Errorf("%s: invalid line at offset %d", file, start)
+		}
+		switch {
+		case lineHash == hash:
+			return count, nil
+		case lineHash < hash:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// lineAt returns the first line of f starting at or after off, and its
+// offset. At the end of f it returns an empty line starting at the size.
+func lineAt(f *os.File, off int64) (int64, string, error) {
+	start := off
+	if off > 0 {
+		// off starts a line if the byte before it ends one
+		start = off - 1
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(f, start, 1<<62))
+	if off > 0 {
+		skipped, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return start + int64(len(skipped)), "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, "", err
+	}
+	return start, strings.TrimSuffix(line, "\n"), nil
+}
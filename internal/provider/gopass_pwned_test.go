@@ -0,0 +1,207 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // test data for the Pwned Passwords format
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s)) //nolint:gosec // see import
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// writeHashList writes a Pwned Passwords style list of the given passwords,
+// each seen i+1 times, and returns its path.
+func writeHashList(t *testing.T, passwords []string, lineEnd string) string {
+	t.Helper()
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		lines = append(lines, fmt.Sprintf("%s:%d", sha1Hex(password), i+1))
+	}
+	sort.Strings(lines)
+	return writeTemp(t, strings.Join(lines, lineEnd))
+}
+
+func TestLookupHashFile(t *testing.T) {
+	var passwords []string
+	for i := range 500 {
+		passwords = append(passwords, fmt.Sprintf("password-%d", i))
+	}
+
+	for _, lineEnd := range []string{"\n", "\r\n"} {
+		file := writeHashList(t, passwords, lineEnd)
+		for i, password := range passwords {
+			count, err := lookupHashFile(file, sha1Hex(password))
+			if err != nil || count != i+1 {
+				t.Fatalf("%q: expected count %d, got %d (%v)", password, i+1, count, err)
+			}
+		}
+		for _, password := range []string{"not-pwned", "", "password-500"} {
+			if count, err := lookupHashFile(file, sha1Hex(password)); err != nil || count != 0 {
+				t.Errorf("%q: expected no match, got %d (%v)", password, count, err)
+			}
+		}
+	}
+
+	if count, err := lookupHashFile(writeTemp(t, ""), sha1Hex("x")); err != nil || count != 0 {
+		t.Errorf("expected no match in an empty list, got %d (%v)", count, err)
+	}
+	if _, err := lookupHashFile(writeTemp(t, "garbage\n"), sha1Hex("x")); err == nil {
+		t.Error("expected an error for an invalid list")
+	}
+}
+
+// newPwnedServer serves the range API for the given pwned passwords, with
+// padding entries like the real service.
+func newPwnedServer(t *testing.T, pwned ...string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		requested = append(requested, prefix)
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Error("expected padded responses to be requested")
+		}
+		fmt.Fprintf(w, "0000000000000000000000000000000000A:0\r\n")
+		for _, password := range pwned {
+			if hash := sha1Hex(password); strings.HasPrefix(hash, prefix) {
+				fmt.Fprintf(w, "%s:42\r\n", hash[5:])
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requested
+}
+
+func TestPwnedChecker_API(t *testing.T) {
+	server, requested := newPwnedServer(t, "hunter2")
+	checker := &pwnedChecker{apiURL: server.URL + "/range/", httpClient: server.Client()}
+	ctx := context.Background()
+
+	err := checker.check(ctx, "app/db", "hunter2")
+	if !errors.Is(err, ErrPwnedPassword) || !strings.Contains(err.Error(), "42 time(s)") {
+		t.Fatalf("expected a pwned password error, got %v", err)
+	}
+	if err := checker.check(ctx, "app/db", "correct horse battery staple 9431"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, prefix := range *requested {
+		if len(prefix) != 5 {
+			t.Errorf("expected only 5 hash digits to be sent, got %q", prefix)
+		}
+	}
+}
+
+func TestPwnedChecker_FailsClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	checker := &pwnedChecker{apiURL: server.URL + "/range/", httpClient: server.Client()}
+
+	err := checker.check(context.Background(), "app/db", "anything")
+	if !errors.Is(err, ErrPwnedPassword) || !strings.Contains(err.Error(), "could not be checked") {
+		t.Errorf("expected the write to be refused, got %v", err)
+	}
+}
+
+func TestPwnedChecker_NilAndEmpty(t *testing.T) {
+	var none *pwnedChecker
+	if err := none.check(context.Background(), "app/db", "hunter2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	checker := &pwnedChecker{file: writeHashList(t, []string{""}, "\n")}
+	if err := checker.check(context.Background(), "app/db", ""); err != nil {
+		t.Errorf("expected empty values not to be checked, got %v", err)
+	}
+}
+
+func TestSetSecret_PwnedPasswordRefused(t *testing.T) {
+	store := newMockStore()
+	client := NewGopassClient("")
+	client.store = store
+	client.pwned = &pwnedChecker{file: writeHashList(t, []string{"hunter2", "123456"}, "\n")}
+	ctx := context.Background()
+
+	err := client.SetSecret(ctx, "app/db", "hunter2")
+	if !errors.Is(err, ErrPwnedPassword) {
+		t.Fatalf("expected a pwned password error, got %v", err)
+	}
+	if got := errorSummary(err, "fallback"); got != "Password found in data breaches" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Error("expected the error not to quote the password")
+	}
+	if _, exists := store.secrets["app/db"]; exists {
+		t.Error("expected the secret not to be written")
+	}
+
+	if err := client.SetSecret(ctx, "app/db", "Xk9#mQ2$vL7!"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetSecret_RefusedWritesNotChecked(t *testing.T) {
+	server, requested := newPwnedServer(t)
+	client := newPolicyTestClient()
+	client.pwned = &pwnedChecker{apiURL: server.URL + "/range/", httpClient: server.Client()}
+	ctx := context.Background()
+
+	if err := client.SetSecret(ctx, "app/admin/root", "Xk9#mQ2$vL7!"); !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("expected a policy violation, got %v", err)
+	}
+	client.readOnly = true
+	if err := client.SetSecret(ctx, "app/db", "Xk9#mQ2$vL7!"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected a read-only error, got %v", err)
+	}
+
+	if len(*requested) != 0 {
+		t.Errorf("expected refused writes not to query the breach database, got %v", *requested)
+	}
+}
+
+func TestProviderConfigure_PwnedPasswords(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+	file := writeHashList(t, []string{"hunter2"}, "\n")
+
+	resp := &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"pwned_passwords_api":  tftypes.NewValue(tftypes.Bool, true),
+			"pwned_passwords_file": tftypes.NewValue(tftypes.String, file),
+		}),
+	}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	checker := resp.ResourceData.(*GopassClient).pwned
+	if checker == nil || checker.apiURL != pwnedPasswordsAPI || checker.file != file {
+		t.Errorf("expected both checks, got %+v", checker)
+	}
+
+	resp = &provider.ConfigureResponse{}
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"pwned_passwords_file": tftypes.NewValue(tftypes.String, file+".missing"),
+		}),
+	}, resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error for a missing hash list")
+	}
+}
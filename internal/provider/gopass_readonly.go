@@ -0,0 +1,25 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReadOnly is returned for mutations refused because the provider is
+// configured read-only.
+var ErrReadOnly = errors.New("provider is configured read-only")
+
+// checkWritable returns an error wrapping ErrReadOnly if the client must not
+// change any store. Every mutation goes through it, independently of what the
+// resource schemas allow, so a resource bug cannot write to a read-only store.
+func (c *GopassClient) checkWritable(action string) error {
+	if !c.readOnly {
+		return nil
+	}
+	return fmt.Errorf("%w: refusing to %s.\n\n"+
+		"Remove read_only from the provider configuration, or use a separate "+
+		"provider alias without it for resources that write secrets", ErrReadOnly, action)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestGopassClient_ReadOnly_RefusesMutations(t *testing.T) {
+	store := newMockStore()
+	store.secrets["app/db"] = newMockSecret("s3cret")
+	client := NewGopassClient("")
+	client.store = store
+	client.readOnly = true
+	ctx := context.Background()
+
+	err := client.SetSecret(ctx, "app/new", "value")
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected write to be refused, got %v", err)
+	}
+	if got := errorSummary(err, "fallback"); got != "Provider is read-only" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if !strings.Contains(err.Error(), `write secret "app/new"`) {
+		t.Errorf("expected the refused write in the error, got %q", err.Error())
+	}
+	if _, ok := store.secrets["app/new"]; ok {
+		t.Error("expected refused write not to reach the store")
+	}
+
+	if err := client.RemoveSecret(ctx, "app/db"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected removal to be refused, got %v", err)
+	}
+	if _, ok := store.secrets["app/db"]; !ok {
+		t.Error("expected refused removal not to reach the store")
+	}
+
+	// Reads are unaffected
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "s3cret" {
+		t.Errorf("expected read to succeed, got %q (%v)", value, err)
+	}
+}
+
+func TestGopassClient_ReadOnly_SkipsGitSync(t *testing.T) {
+	client, store := newSyncTestClient(nil)
+	client.readOnly = true
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := store.syncs.Load(); n != 0 {
+		t.Errorf("expected no sync in read-only mode, got %d", n)
+	}
+
+	warnings := client.takeWarnings()
+	if len(warnings) != 1 || warnings[0].Summary() != "Git sync skipped" {
+		t.Errorf("expected a skipped sync warning, got %v", warnings)
+	}
+}
+
+func TestProviderConfigure_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"read_only": tftypes.NewValue(tftypes.Bool, true),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.ResourceData.(*GopassClient); !client.readOnly {
+		t.Error("expected the client to be read-only")
+	}
+}
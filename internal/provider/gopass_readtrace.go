@@ -0,0 +1,65 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// interactiveReadThreshold is the decryption time above which a read most
+// likely waited for gpg-agent to prompt for a PIN or a token touch. An
+// unattended decryption with a cached key takes milliseconds.
+const interactiveReadThreshold = time.Second
+
+// Where a secret read was served from.
+const (
+	readSourceStore    = "store"
+	readSourcePrefetch = "prefetch"
+	readSourceCache    = "cache"
+	readSourceShared   = "shared" // coalesced with a concurrent read of the same path
+)
+
+// readTrace collects what happened during a single secret read, for the
+// per-read debug line. Abandoned attempts may still update it after the
+// read returned, so the fields are atomic.
+type readTrace struct {
+	retries   atomic.Int32
+	tokenWait atomic.Int64 // nanoseconds spent waiting for the hardware token
+	decrypt   atomic.Int64 // nanoseconds the last decryption attempt took
+}
+
+type readTraceKey struct{}
+
+// withReadTrace attaches a new readTrace to ctx.
+func withReadTrace(ctx context.Context) (context.Context, *readTrace) {
+	trace := &readTrace{}
+	return context.WithValue(ctx, readTraceKey{}, trace), trace
+}
+
+// readTraceFrom returns the readTrace attached to ctx, or nil.
+func readTraceFrom(ctx context.Context) *readTrace {
+	trace, _ := ctx.Value(readTraceKey{}).(*readTrace)
+	return trace
+}
+
+// logRead emits a debug line on the metrics subsystem for a single secret
+// read, so slow entries can be told apart from the aggregate statistics.
+func logRead(ctx context.Context, path, source string, duration time.Duration, trace *readTrace, err error) {
+	fields := map[string]interface{}{
+		"path":        path,
+		"source":      source,
+		"duration_ms": duration.Milliseconds(),
+		"success":     err == nil,
+	}
+	if source == readSourceStore {
+		fields["retries"] = trace.retries.Load()
+		fields["token_wait_ms"] = time.Duration(trace.tokenWait.Load()).Milliseconds()
+		fields["likely_interactive"] = time.Duration(trace.decrypt.Load()) >= interactiveReadThreshold
+	}
+	tflog.SubsystemDebug(metricsContext(ctx), metricsSubsystem, "gopass secret read", fields)
+}
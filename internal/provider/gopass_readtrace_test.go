@@ -0,0 +1,109 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+// readLogEntries returns the per-read debug lines logged to output.
+func readLogEntries(t *testing.T, output *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	entries, err := tflogtest.MultilineJSONDecode(output)
+	if err != nil {
+		t.Fatalf("failed to decode log output: %v", err)
+	}
+
+	var reads []map[string]interface{}
+	for _, entry := range entries {
+		if entry["@message"] == "gopass secret read" {
+			reads = append(reads, entry)
+		}
+	}
+	return reads
+}
+
+func TestLogRead_StoreReadWithRetries(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	store := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failures:  2,
+		failErr:   errors.New("card busy"),
+	}
+	secret := secrets.New()
+	secret.SetPassword("value")
+	store.secrets["app/db"] = secret
+
+	client, _ := newRetryTestClient(store)
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reads := readLogEntries(t, &output)
+	if len(reads) != 1 {
+		t.Fatalf("expected one read line, got %v", reads)
+	}
+	read := reads[0]
+	if read["path"] != "app/db" || read["source"] != readSourceStore || read["success"] != true {
+		t.Errorf("unexpected read line %v", read)
+	}
+	if read["retries"] != float64(2) {
+		t.Errorf("expected 2 retries, got %v", read["retries"])
+	}
+	if read["likely_interactive"] != false {
+		t.Errorf("expected a fast read not to count as interactive, got %v", read["likely_interactive"])
+	}
+	if _, ok := read["token_wait_ms"]; !ok {
+		t.Errorf("expected token wait in read line, got %v", read)
+	}
+}
+
+func TestLogRead_PrefetchHit(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	store := newMockStore()
+	store.secrets["app/db"] = newMockSecret("value")
+	client := NewGopassClient("")
+	client.store = store
+	client.prefetch = newPrefetcher([]string{"app/db"})
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reads := readLogEntries(t, &output)
+	if len(reads) != 1 || reads[0]["source"] != readSourcePrefetch {
+		t.Fatalf("expected one prefetch hit, got %v", reads)
+	}
+	if _, ok := reads[0]["retries"]; ok {
+		t.Errorf("expected no decryption details for a cache hit, got %v", reads[0])
+	}
+}
+
+func TestLogRead_Failure(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	client := NewGopassClient("")
+	client.store = newMockStore()
+
+	if _, err := client.GetSecret(ctx, "app/missing"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+
+	reads := readLogEntries(t, &output)
+	if len(reads) != 1 || reads[0]["success"] != false {
+		t.Fatalf("expected one failed read, got %v", reads)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrRecipientPolicy is returned for writes refused because the target's
+// recipients do not match the configured recipient policy.
+var ErrRecipientPolicy = errors.New("recipients do not match the recipient policy")
+
+// recipientRule declares the recipients secrets below prefix must be
+// encrypted to.
+type recipientRule struct {
+	prefix     string   // "" for the whole store, otherwise ending in "/"
+	recipients []string // exact set, normalized; nil accepts any set
+	minCount   int      // 0 accepts any number
+}
+
+// recipientRules holds the configured rules, longest prefix first.
+type recipientRules []recipientRule
+
+// add inserts a rule, keeping the longest prefix first so nested subtrees
+// override their parents.
+func (r *recipientRules) add(rule recipientRule) {
+	*r = append(*r, rule)
+	sort.SliceStable(*r, func(i, j int) bool {
+		return len((*r)[i].prefix) > len((*r)[j].prefix)
+	})
+}
+
+// ruleFor returns the rule governing path, or nil if none does.
+func (r recipientRules) ruleFor(path string) *recipientRule {
+	for i := range r {
+		if underPrefix(path, r[i].prefix) {
+			return &r[i]
+		}
+	}
+	return nil
+}
+
+// normalizeRecipient makes recipients comparable: gpg key IDs are matched
+// case-insensitively and with or without a leading "0x".
+func normalizeRecipient(recipient string) string {
+	recipient = strings.ToLower(strings.TrimSpace(recipient))
+	return strings.TrimPrefix(recipient, "0x")
+}
+
+// check returns why recipients violate the rule, or "" if they satisfy it.
+func (rule *recipientRule) check(recipients []string) string {
+	actual := make([]string, 0, len(recipients))
+	for _, recipient := range recipients {
+		actual = append(actual, normalizeRecipient(recipient))
+	}
+	slices.Sort(actual)
+	actual = slices.Compact(actual)
+
+	if len(actual) < rule.minCount {
+		return fmt.Sprintf("%d recipient(s), at least %d required", len(actual), rule.minCount)
+	}
+	if rule.recipients == nil {
+		return ""
+	}
+
+	var unexpected, missing []string
+	for _, recipient := range actual {
+		if !slices.Contains(rule.recipients, recipient) {
+			unexpected = append(unexpected, recipient)
+		}
+	}
+	for _, recipient := range rule.recipients {
+		if !slices.Contains(actual, recipient) {
+			missing = append(missing, recipient)
+		}
+	}
+
+	var reasons []string
+	if len(unexpected) > 0 {
+		reasons = append(reasons, "unexpected "+strings.Join(unexpected, ", "))
+	}
+	if len(missing) > 0 {
+		reasons = append(reasons, "missing "+strings.Join(missing, ", "))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// checkRecipients returns an error wrapping ErrRecipientPolicy if a secret
+// written to path would be encrypted to recipients the recipient policy does
+// not allow. Recipients that cannot be determined fail the check, so a secret
+// is never written without knowing who can read it.
+func (c *GopassClient) checkRecipients(path string) error {
+	rule := c.recipients.ruleFor(path)
+	if rule == nil {
+		return nil
+	}
+
+	set, err := c.recipientsFor(path)
+	if err != nil {
+		return fmt.Errorf("%w: refusing to write secret %q, its recipients cannot be determined: %s", ErrRecipientPolicy, path, err.Error())
+	}
+	if reason := rule.check(set.recipients); reason != "" {
+		return fmt.Errorf("%w: refusing to write secret %q, %s has %s.\n\n"+
+			"Fix the recipients with \"gopass recipients\", or update recipient_policies if the change is intended",
+			ErrRecipientPolicy, path, set.file, reason)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestRecipientRule_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       recipientRule
+		recipients []string
+		want       string
+	}{
+		{"exact", recipientRule{recipients: []string{"deadbeef", "cafe"}}, []string{"0xCAFE", "DEADBEEF"}, ""},
+		{"duplicates", recipientRule{recipients: []string{"cafe"}}, []string{"cafe", "0xcafe"}, ""},
+		{"unexpected", recipientRule{recipients: []string{"cafe"}}, []string{"cafe", "f00d"}, "unexpected f00d"},
+		{"missing", recipientRule{recipients: []string{"cafe", "f00d"}}, []string{"cafe"}, "missing f00d"},
+		{"both", recipientRule{recipients: []string{"cafe"}}, []string{"f00d"}, "unexpected f00d; missing cafe"},
+		{"min met", recipientRule{minCount: 2}, []string{"a", "b"}, ""},
+		{"min not met", recipientRule{minCount: 2}, []string{"a", "A"}, "1 recipient(s), at least 2 required"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.check(tt.recipients); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRecipientRules_LongestPrefixWins(t *testing.T) {
+	var rules recipientRules
+	rules.add(recipientRule{prefix: "", minCount: 1})
+	rules.add(recipientRule{prefix: "infra/prod/", minCount: 3})
+	rules.add(recipientRule{prefix: "infra/", minCount: 2})
+
+	for path, want := range map[string]int{"app/db": 1, "infra/db": 2, "infra/prod/db": 3} {
+		if rule := rules.ruleFor(path); rule == nil || rule.minCount != want {
+			t.Errorf("%s: expected the rule requiring %d, got %+v", path, want, rule)
+		}
+	}
+
+	var none recipientRules
+	if none.ruleFor("app/db") != nil {
+		t.Error("expected no rule without configuration")
+	}
+}
+
+func TestSetSecret_RecipientPolicy(t *testing.T) {
+	client, store, dir := newIntegrityTestClient(t)
+	writeStoreFile(t, dir, "team/.gpg-id", "0xDEADBEEF\n0xF00D\n")
+	client.recipients.add(recipientRule{prefix: "", recipients: []string{"deadbeef"}})
+	ctx := context.Background()
+
+	if err := client.SetSecret(ctx, "app/new", "value"); err != nil {
+		t.Fatalf("expected the write to match the policy, got %v", err)
+	}
+
+	err := client.SetSecret(ctx, "team/new", "value")
+	if !errors.Is(err, ErrRecipientPolicy) {
+		t.Fatalf("expected a recipient policy error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "unexpected f00d") || !strings.Contains(err.Error(), "team") {
+		t.Errorf("expected the offending recipient and file, got %q", err.Error())
+	}
+	if got := errorSummary(err, "fallback"); got != "Unexpected secret recipients" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if _, exists := store.secrets["team/new"]; exists {
+		t.Error("expected the secret not to be written")
+	}
+}
+
+func TestSetSecret_RecipientPolicyUnknownRecipients(t *testing.T) {
+	client, store, _ := newIntegrityTestClient(t)
+	client.recipients.add(recipientRule{prefix: "custom/", minCount: 1})
+	client.addMount("custom", func(ctx context.Context) (SecretStore, error) { return store, nil })
+
+	err := client.SetSecret(context.Background(), "custom/new", "value")
+	if !errors.Is(err, ErrRecipientPolicy) || !strings.Contains(err.Error(), "cannot be determined") {
+		t.Errorf("expected writes with unknown recipients to be refused, got %v", err)
+	}
+}
+
+func TestProviderConfigure_RecipientPolicies(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+	policyType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"recipients":     tftypes.List{ElementType: tftypes.String},
+		"min_recipients": tftypes.Number,
+	}}
+	policy := func(recipients []string, minRecipients interface{}) tftypes.Value {
+		list := tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, nil)
+		if recipients != nil {
+			values := []tftypes.Value{}
+			for _, recipient := range recipients {
+				values = append(values, tftypes.NewValue(tftypes.String, recipient))
+			}
+			list = tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, values)
+		}
+		return tftypes.NewValue(policyType, map[string]tftypes.Value{
+			"recipients":     list,
+			"min_recipients": tftypes.NewValue(tftypes.Number, minRecipients),
+		})
+	}
+	configure := func(policies map[string]tftypes.Value) *provider.ConfigureResponse {
+		resp := &provider.ConfigureResponse{}
+		p.Configure(ctx, provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{
+				"recipient_policies": tftypes.NewValue(tftypes.Map{ElementType: policyType}, policies),
+			}),
+		}, resp)
+		return resp
+	}
+
+	resp := configure(map[string]tftypes.Value{
+		"/":      policy([]string{"0xDEADBEEF"}, nil),
+		"infra/": policy(nil, 2),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	rules := resp.EphemeralResourceData.(*GopassClient).recipients
+	if len(rules) != 2 || rules[0].prefix != "infra/" || rules[0].minCount != 2 {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+	if rules[1].prefix != "" || len(rules[1].recipients) != 1 || rules[1].recipients[0] != "deadbeef" {
+		t.Errorf("expected a normalized whole-store rule, got %+v", rules[1])
+	}
+
+	for name, invalid := range map[string]tftypes.Value{
+		"empty":        policy(nil, nil),
+		"empty list":   policy([]string{}, nil),
+		"zero minimum": policy(nil, 0),
+	} {
+		if resp := configure(map[string]tftypes.Value{"app/": invalid}); !resp.Diagnostics.HasError() {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// recipientFileNames are the files gopass reads the recipients of a directory
+// from: .gpg-id for the gpg backend, .age-recipients for age. The one nearest
+// to an entry, walking up to the store root, applies to it.
+var recipientFileNames = []string{".gpg-id", ".age-recipients"}
+
+// errNoStoreDir is returned for stores the provider cannot locate on disk,
+// such as custom backends or a root store configured only in gopass.
+var errNoStoreDir = errors.New("store directory unknown")
+
+// storeDirFor returns the directory of the store holding path and path
+// relative to it. Like storeDir, it only knows the root store's directory if
+// it comes from store_path or PASSWORD_STORE_DIR, and returns errNoStoreDir
+// otherwise.
+func (c *GopassClient) storeDirFor(path string) (dir, rel string, err error) {
+	if m := c.mountFor(path); m != nil {
+		if m.dir == "" {
+			return "", "", errNoStoreDir
+		}
+		dir, err = c.expandHome(m.dir)
+		key, _ := relativeKey(path, m.prefix)
+		return dir, key, err
+	}
+	if dir = c.storeDir(); dir == "" {
+		return "", "", fmt.Errorf("%w: the store location comes from the gopass configuration, set store_path", errNoStoreDir)
+	}
+	return dir, path, nil
+}
+
+// recipientSet is the recipient file that applies to an entry.
+type recipientSet struct {
+	file       string // path of the recipient file
+	recipients []string
+}
+
+// recipientsFor returns the recipients gopass encrypts the entry at path to,
+// read from the nearest recipient file. It returns errNoStoreDir for stores
+// not on disk and an error if no recipient file applies.
+func (c *GopassClient) recipientsFor(path string) (recipientSet, error) {
+	root, rel, err := c.storeDirFor(path)
+	if err != nil {
+		return recipientSet{}, err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return recipientSet{}, fmt.Errorf("%w: %s is not a directory", errNoStoreDir, root)
+	}
+
+	dir := filepath.Dir(filepath.Join(root, filepath.FromSlash(rel)))
+	for {
+		for _, name := range recipientFileNames {
+			file := filepath.Join(dir, name)
+			recipients, err := readRecipientFile(file)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return recipientSet{}, err
+			}
+			return recipientSet{file: file, recipients: recipients}, nil
+		}
+		if dir == root || !strings.HasPrefix(dir, root) {
+			return recipientSet{}, fmt.Errorf("no %s in %s or any directory above it", strings.Join(recipientFileNames, " or "), root)
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readRecipientFile returns the recipients listed in a recipient file,
+// skipping blank lines and comments.
+func readRecipientFile(file string) ([]string, error) {
+	f, err := os.Open(file) //nolint:gosec // path is below the store directory
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var recipients []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		recipients = append(recipients, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return recipients, nil
+}
+
+// recipientEdit changes the recipients of a store: it edits the recipient
+// file at the store's root and re-encrypts the store's entries to the new
+// set, like "gopass recipients add" and "gopass recipients remove".
+type recipientEdit struct {
+	store     string // mount prefix, "" for the root store
+	recipient string
+	add       bool
+	reencrypt bool
+}
+
+// storeRoot returns the directory of the store mounted at store, or of the
+// root store if store is "". Like storeDirFor, it only knows stores on disk.
+func (c *GopassClient) storeRoot(store string) (string, error) {
+	prefix := normalizePrefix(store)
+	if prefix == "" {
+		if dir := c.storeDir(); dir != "" {
+			return dir, nil
+		}
+		return "", fmt.Errorf("%w: the store location comes from the gopass configuration, set store_path", errNoStoreDir)
+	}
+	for _, m := range c.mounts {
+		if m.prefix != prefix {
+			continue
+		}
+		if m.dir == "" {
+			return "", fmt.Errorf("%w: the store mounted at %q is not read from disk", errNoStoreDir, normalizePath(prefix))
+		}
+		return c.expandHome(m.dir)
+	}
+	return "", fmt.Errorf("no store is mounted at %q; configure it in the provider's mounts", normalizePath(prefix))
+}
+
+// storeRecipientFile returns the directory of store and its recipient file.
+func (c *GopassClient) storeRecipientFile(store string) (dir, file string, err error) {
+	dir, err = c.storeRoot(store)
+	if err != nil {
+		return "", "", err
+	}
+	for _, name := range recipientFileNames {
+		file = filepath.Join(dir, name)
+		if _, err := os.Stat(file); err == nil {
+			return dir, file, nil
+		}
+	}
+	return "", "", fmt.Errorf("no %s in %s; initialize the store with gopass init first",
+		strings.Join(recipientFileNames, " or "), dir)
+}
+
+// StoreRecipients returns the recipients listed at the root of store, the
+// mount prefix or "" for the root store.
+func (c *GopassClient) StoreRecipients(store string) ([]string, error) {
+	_, file, err := c.storeRecipientFile(store)
+	if err != nil {
+		return nil, err
+	}
+	return readRecipientFile(file)
+}
+
+// hasRecipient reports whether recipients holds recipient, compared the way
+// recipient policies compare them.
+func hasRecipient(recipients []string, recipient string) bool {
+	for _, r := range recipients {
+		if normalizeRecipient(r) == normalizeRecipient(recipient) {
+			return true
+		}
+	}
+	return false
+}
+
+// EditRecipients adds or removes a recipient of a store and, if asked to,
+// re-encrypts the store's entries. It returns the number of entries
+// re-encrypted. Adding a present or removing an absent recipient leaves the
+// recipient file alone but still re-encrypts, so a retry completes an
+// interrupted re-encryption.
+func (c *GopassClient) EditRecipients(ctx context.Context, edit recipientEdit) (int, error) {
+	action := "remove"
+	if edit.add {
+		action = "add"
+	}
+	if err := c.checkWritable(fmt.Sprintf("%s recipient %q", action, edit.recipient)); err != nil {
+		return 0, err
+	}
+	if edit.recipient == "" || strings.ContainsAny(edit.recipient, " \t\r\n#") {
+		return 0, fmt.Errorf("invalid recipient %q: expected a single key ID, fingerprint or age recipient", edit.recipient)
+	}
+
+	c.recipientEdits.Lock()
+	defer c.recipientEdits.Unlock()
+
+	dir, file, err := c.storeRecipientFile(edit.store)
+	if err != nil {
+		return 0, err
+	}
+	changed, err := editRecipientFile(file, edit.recipient, edit.add)
+	if err != nil {
+		return 0, err
+	}
+	if changed {
+		tflog.Info(ctx, "Changed store recipients", map[string]interface{}{
+			"store":     normalizePath(edit.store),
+			"recipient": edit.recipient,
+			"action":    action,
+		})
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			message := fmt.Sprintf("Added Recipient %s", edit.recipient)
+			if !edit.add {
+				message = fmt.Sprintf("Removed Recipient %s", edit.recipient)
+			}
+			if err := gitCommitFiles(ctx, dir, message, filepath.Base(file)); err != nil {
+				return 0, fmt.Errorf("changed %s, but failed to commit it: %w", file, err)
+			}
+		}
+	}
+
+	if !edit.reencrypt {
+		return 0, nil
+	}
+	return c.reencryptStore(ctx, edit.store)
+}
+
+// editRecipientFile adds recipient to or removes it from the recipient file,
+// keeping comments and the order of the other recipients. It reports whether
+// the file changed.
+func editRecipientFile(file, recipient string, add bool) (bool, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(file) //nolint:gosec // path is the store's recipient file
+	if err != nil {
+		return false, err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	kept := lines[:0]
+	var remaining []string
+	found := false
+	for _, line := range lines {
+		entry := strings.TrimSpace(line)
+		if entry != "" && !strings.HasPrefix(entry, "#") {
+			if normalizeRecipient(entry) == normalizeRecipient(recipient) {
+				found = true
+				if !add {
+					continue
+				}
+			}
+			remaining = append(remaining, entry)
+		}
+		kept = append(kept, line)
+	}
+
+	switch {
+	case add && found, !add && !found:
+		return false, nil
+	case add:
+		kept = append(kept, recipient)
+	case len(remaining) == 0:
+		return false, fmt.Errorf("refusing to remove %q, the last recipient in %s", recipient, file)
+	}
+	content := strings.TrimLeft(strings.Join(kept, "\n"), "\n") + "\n"
+	if err := os.WriteFile(file, []byte(content), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// reencryptStore rewrites every entry of store, which encrypts it to the
+// store's current recipients. Entries of stores mounted inside it are left
+// alone; they have recipients of their own.
+func (c *GopassClient) reencryptStore(ctx context.Context, store string) (int, error) {
+	prefix := normalizePrefix(store)
+	var m *mount
+	if prefix != "" {
+		m = c.mountFor(prefix)
+	}
+
+	var paths []string
+	if err := c.walkStore(ctx, m, prefix, func(secretPath string) error {
+		paths = append(paths, secretPath)
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("failed to list secrets to re-encrypt: %w", err)
+	}
+
+	for i, secretPath := range paths {
+		if err := c.reencryptSecret(ctx, secretPath); err != nil {
+			return i, fmt.Errorf("re-encrypted %d of %d secrets: %w", i, len(paths), err)
+		}
+	}
+	tflog.Info(ctx, "Re-encrypted store", map[string]interface{}{
+		"store": normalizePath(store),
+		"count": len(paths),
+	})
+	return len(paths), nil
+}
+
+// reencryptSecret rewrites the entry at path unchanged.
+func (c *GopassClient) reencryptSecret(ctx context.Context, path string) error {
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	secret, err := c.storeGet(ctx, store, path)
+	if err != nil {
+		return fmt.Errorf("failed to read secret %q: %w", path, err)
+	}
+	err = c.storeSet(ctx, store, path, secret, nil)
+	c.invalidatePath(path)
+	if err != nil {
+		return fmt.Errorf("failed to write secret %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRecipientFile(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), ".gpg-id")
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return file
+}
+
+func readFile(t *testing.T, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(data)
+}
+
+func TestEditRecipientFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		recipient   string
+		add         bool
+		wantChanged bool
+		want        string
+	}{
+		{
+			name:        "add",
+			content:     "# team\nAAAA1111\n",
+			recipient:   "BBBB2222",
+			add:         true,
+			wantChanged: true,
+			want:        "# team\nAAAA1111\nBBBB2222\n",
+		},
+		{
+			name:      "add present recipient",
+			content:   "AAAA1111\n",
+			recipient: "0xaaaa1111",
+			add:       true,
+			want:      "AAAA1111\n",
+		},
+		{
+			name:        "remove",
+			content:     "# team\nAAAA1111\nBBBB2222\n",
+			recipient:   "0xbbbb2222",
+			wantChanged: true,
+			want:        "# team\nAAAA1111\n",
+		},
+		{
+			name:      "remove absent recipient",
+			content:   "AAAA1111\n",
+			recipient: "CCCC3333",
+			want:      "AAAA1111\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeRecipientFile(t, tt.content)
+			changed, err := editRecipientFile(file, tt.recipient, tt.add)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("expected changed %v, got %v", tt.wantChanged, changed)
+			}
+			if got := readFile(t, file); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEditRecipientFile_LastRecipient(t *testing.T) {
+	file := writeRecipientFile(t, "# only one\nAAAA1111\n")
+	_, err := editRecipientFile(file, "AAAA1111", false)
+	if err == nil || !strings.Contains(err.Error(), "last recipient") {
+		t.Errorf("expected removing the last recipient to be refused, got %v", err)
+	}
+	if got := readFile(t, file); got != "# only one\nAAAA1111\n" {
+		t.Errorf("expected the file to be unchanged, got %q", got)
+	}
+}
+
+func TestStoreRoot(t *testing.T) {
+	client := NewGopassClient("/stores/root")
+	client.addStoreMount("team", "/stores/team")
+	client.addMount("remote", nil)
+
+	if dir, err := client.storeRoot(""); err != nil || dir != "/stores/root" {
+		t.Errorf("expected the root store directory, got %q, %v", dir, err)
+	}
+	if dir, err := client.storeRoot("/team/"); err != nil || dir != "/stores/team" {
+		t.Errorf("expected the mounted store directory, got %q, %v", dir, err)
+	}
+	if _, err := client.storeRoot("remote"); !errors.Is(err, errNoStoreDir) {
+		t.Errorf("expected a store not on disk to be refused, got %v", err)
+	}
+	if _, err := client.storeRoot("other"); err == nil || !strings.Contains(err.Error(), "no store is mounted") {
+		t.Errorf("expected an unknown mount to be refused, got %v", err)
+	}
+}
+
+func TestEditRecipients_ReadOnly(t *testing.T) {
+	client := NewGopassClient(t.TempDir())
+	client.readOnly = true
+
+	_, err := client.EditRecipients(context.Background(), recipientEdit{recipient: "AAAA1111", add: true})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected a read-only provider to refuse, got %v", err)
+	}
+}
+
+func TestEditRecipients_InvalidRecipient(t *testing.T) {
+	client := NewGopassClient(t.TempDir())
+
+	for _, recipient := range []string{"", "two words", "AAAA1111\nBBBB2222", "# comment"} {
+		if _, err := client.EditRecipients(context.Background(), recipientEdit{recipient: recipient, add: true}); err == nil {
+			t.Errorf("expected %q to be refused", recipient)
+		}
+	}
+}
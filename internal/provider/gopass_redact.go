@@ -0,0 +1,196 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// minRedactLength is the length below which secret values are not redacted:
+// masking one- or two-character values would garble every log line.
+const minRedactLength = 4
+
+// redactedValue replaces secret values in log output, like tflog's masking.
+const redactedValue = "***"
+
+// sensitiveFieldKeys are log field keys whose values are always masked, in
+// case a value is ever logged under its attribute name.
+var sensitiveFieldKeys = []string{"value", "values", "password"}
+
+// redactor keeps secret values out of log output. Every value the client
+// hands out or writes is registered, and error texts and diagnostics, which
+// may quote a value, are scrubbed of all registered values.
+//
+// Values are kept only as salted hashes, grouped by length, so the redactor
+// does not keep plaintext secrets for the lifetime of the process; finding
+// them in a text means hashing every substring of a registered length.
+//
+// With hashPaths set, secret paths are logged as hashes as well.
+type redactor struct {
+	mu        sync.RWMutex
+	salt      []byte
+	values    map[int]map[[sha256.Size]byte]bool // value hashes by value length
+	lengths   []int                              // keys of values, longest first
+	paths     map[string]bool                    // paths seen so far, only with hashPaths
+	hashPaths bool
+}
+
+// hashValue returns the salted hash of value. r.salt must be set.
+func (r *redactor) hashValue(value string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(r.salt)
+	h.Write([]byte(value))
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// addValues registers secret values for redaction.
+func (r *redactor) addValues(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range values {
+		if len(value) < minRedactLength {
+			continue
+		}
+		if r.salt == nil {
+			// An all-zero salt, should reading fail, still redacts
+			r.salt = make([]byte, 32)
+			_, _ = rand.Read(r.salt)
+			r.values = make(map[int]map[[sha256.Size]byte]bool)
+		}
+		n := len(value)
+		if r.values[n] == nil {
+			r.values[n] = make(map[[sha256.Size]byte]bool)
+			r.lengths = append(r.lengths, n)
+			sort.Sort(sort.Reverse(sort.IntSlice(r.lengths)))
+		}
+		r.values[n][r.hashValue(value)] = true
+	}
+}
+
+// addFields registers the values of secret fields for redaction.
+func (r *redactor) addFields(fields map[string]string) {
+	values := make([]string, 0, len(fields))
+	for _, value := range fields {
+		values = append(values, value)
+	}
+	r.addValues(values...)
+}
+
+// addPath registers a secret path for hashing in error texts.
+func (r *redactor) addPath(path string) {
+	if !r.hashPaths || len(path) < minRedactLength {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.paths == nil {
+		r.paths = make(map[string]bool)
+	}
+	r.paths[path] = true
+}
+
+// replaceValues replaces all registered values in s, the longest one at each
+// position. r.mu must be held.
+func (r *redactor) replaceValues(s string) string {
+	if len(r.lengths) == 0 || len(s) < r.lengths[len(r.lengths)-1] {
+		return s
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		matched := 0
+		for _, n := range r.lengths {
+			if i+n <= len(s) && r.values[n][r.hashValue(s[i:i+n])] {
+				matched = n
+				break
+			}
+		}
+		if matched == 0 {
+			out.WriteByte(s[i])
+			i++
+			continue
+		}
+		out.WriteString(redactedValue)
+		i += matched
+	}
+	return out.String()
+}
+
+// redact replaces all registered values in s, and registered paths if paths
+// are hashed.
+func (r *redactor) redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s = r.replaceValues(s)
+	for _, path := range longestFirst(r.paths) {
+		s = strings.ReplaceAll(s, path, pathID(path))
+	}
+	return s
+}
+
+// longestFirst returns the keys of set ordered by decreasing length.
+func longestFirst(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// pathID is the identifier logged in place of a path when paths are hashed.
+func pathID(path string) string {
+	return "sha256:" + hashPath(path)
+}
+
+// logContext prepares ctx so that tflog masks fields named like secret
+// attributes. Secret values themselves are only known as hashes, which tflog
+// cannot mask: texts that may quote one go through logError or logText.
+// It is safe on a nil client.
+func (c *GopassClient) logContext(ctx context.Context) context.Context {
+	return tflog.MaskFieldValuesWithFieldKeys(ctx, sensitiveFieldKeys...)
+}
+
+// logPath returns path as it may appear in logs: unchanged, or as a hash if
+// hash_log_paths is set. It is safe on a nil client.
+func (c *GopassClient) logPath(path string) string {
+	if c == nil || !c.redactor.hashPaths {
+		return path
+	}
+	c.redactor.addPath(path)
+	return pathID(path)
+}
+
+// logError returns the text of err with secret values and, if paths are
+// hashed, secret paths replaced. It is safe on a nil client.
+func (c *GopassClient) logError(err error) string {
+	return c.logText(err.Error())
+}
+
+// logText returns s with secret values and, if paths are hashed, secret
+// paths replaced. It is safe on a nil client.
+func (c *GopassClient) logText(s string) string {
+	if c == nil {
+		return s
+	}
+	return c.redactor.redact(s)
+}
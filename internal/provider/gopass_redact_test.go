@@ -0,0 +1,172 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+const redactTestValue = "hunter2-correct-horse"
+
+// newRedactTestClient returns a client whose store holds app/db with
+// redactTestValue. Reads can be made to fail with an error quoting it.
+func newRedactTestClient() *GopassClient {
+	store := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failErr:   errors.New("card busy while parsing " + redactTestValue),
+	}
+	store.secrets["app/db"] = secrets.ParseAKV([]byte(redactTestValue + "\nuser: admin-user\n"))
+
+	client, _ := newRetryTestClient(store)
+	return client
+}
+
+func TestRedactor_Redact(t *testing.T) {
+	var r redactor
+	r.addValues("abc", "secret", "secret-longer")
+
+	got := r.redact("values: secret-longer, secret, abc")
+	if want := "values: ***, ***, abc"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRedactor_KeepsNoPlaintext(t *testing.T) {
+	var r redactor
+	r.addValues(redactTestValue)
+	r.addFields(map[string]string{"user": "admin-user"})
+
+	if dump := fmt.Sprintf("%v %v", r.values, r.lengths); strings.Contains(dump, redactTestValue) ||
+		strings.Contains(dump, "admin-user") || strings.Contains(dump, fmt.Sprint([]byte(redactTestValue))) {
+		t.Errorf("expected only hashes to be kept, got %s", dump)
+	}
+	if got := r.redact("user admin-user, password " + redactTestValue + "!"); got != "user ***, password ***!" {
+		t.Errorf("unexpected redaction %q", got)
+	}
+}
+
+func TestRedactor_HashedPaths(t *testing.T) {
+	r := redactor{hashPaths: true}
+	r.addPath("app/db")
+	r.addPath("db")
+
+	got := r.redact(`failed to get secret "app/db"`)
+	if want := `failed to get secret "` + pathID("app/db") + `"`; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	plain := redactor{}
+	plain.addPath("app/db")
+	if got := plain.redact("app/db"); got != "app/db" {
+		t.Errorf("expected paths to stay when not hashing, got %q", got)
+	}
+}
+
+func TestLogs_NeverContainSecretValues(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	client := newRedactTestClient()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := client.GetSecretFields(ctx, "app/db", "user"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The value is known now: an error quoting it is redacted when logged,
+	// here by the retry of a transient failure
+	store := client.store.(*mockFlakyStore)
+	store.secrets["app/flaky"] = newMockSecret("other-value")
+	store.failures = store.getCalls + 1
+	if _, err := client.GetSecret(ctx, "app/flaky"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Texts are scrubbed through logText, contexts prepared for a resource
+	// mask fields named like secret attributes
+	resourceCtx := client.logContext(ctx)
+	tflog.Debug(resourceCtx, client.logText("leaking "+redactTestValue), map[string]interface{}{
+		"detail": client.logText("contains " + redactTestValue),
+		"note":   client.logText("user is admin-user"),
+		"value":  "anything",
+	})
+
+	logs := output.String()
+	for _, leaked := range []string{redactTestValue, "admin-user", "anything"} {
+		if strings.Contains(logs, leaked) {
+			t.Errorf("log output contains %q:\n%s", leaked, logs)
+		}
+	}
+	if !strings.Contains(logs, redactedValue) {
+		t.Errorf("expected redaction markers in log output:\n%s", logs)
+	}
+}
+
+func TestLogs_HashedPaths(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	client := newRedactTestClient()
+	client.redactor.hashPaths = true
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "app/missing"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+
+	logs := output.String()
+	if strings.Contains(logs, "app/db") || strings.Contains(logs, "app/missing") {
+		t.Errorf("log output contains plain paths:\n%s", logs)
+	}
+	if !strings.Contains(logs, pathID("app/db")) {
+		t.Errorf("expected hashed path in log output:\n%s", logs)
+	}
+}
+
+func TestLogHelpers_NilClient(t *testing.T) {
+	var client *GopassClient
+	if got := client.logPath("app/db"); got != "app/db" {
+		t.Errorf("expected plain path, got %q", got)
+	}
+	if got := client.logError(errors.New("boom")); got != "boom" {
+		t.Errorf("expected error text, got %q", got)
+	}
+	if ctx := client.logContext(context.Background()); ctx == nil {
+		t.Error("expected a context")
+	}
+}
+
+func TestProviderConfigure_HashLogPaths(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"hash_log_paths": tftypes.NewValue(tftypes.Bool, true),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	if client := resp.EphemeralResourceData.(*GopassClient); !client.redactor.hashPaths {
+		t.Error("expected paths to be hashed")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Default retry behavior for transient store failures.
+const (
+	defaultRetryAttempts  = 4
+	defaultRetryBaseDelay = 250 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+// retryPolicy controls how transient store failures are retried.
+type retryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// defaultRetryPolicy returns the policy used by NewGopassClient.
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{
+		MaxAttempts: defaultRetryAttempts,
+		BaseDelay:   defaultRetryBaseDelay,
+		MaxDelay:    defaultRetryMaxDelay,
+	}
+}
+
+// transientErrorPatterns are error fragments that indicate a temporary
+// condition which typically clears up on its own within seconds.
+var transientErrorPatterns = []string{
+	// gpg-agent is still starting up or was just restarted
+	"can't connect to the agent",
+	"ipc connect call failed",
+	"agent not ready",
+	// Smartcard is in use by another decryption or by scdaemon itself
+	"card busy",
+	"device or resource busy",
+	// A concurrent git commit holds the index lock
+	"index.lock",
+	"resource temporarily unavailable",
+}
+
+// isTransientError reports whether err is worth retrying.
+// Context cancellation and deadlines are never retried.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	errStr := strings.ToLower(err.Error())
+	for _, pattern := range transientErrorPatterns {
+		if strings.Contains(errStr, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry (1-based), using
+// exponential growth capped at MaxDelay with "equal jitter": half of the
+// delay is fixed and the other half is random.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half) //nolint:gosec // jitter does not need cryptographic randomness
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withRetry runs fn, retrying transient failures according to the client's
+// retry policy. Non-transient errors are returned immediately.
+func withRetry[T any](ctx context.Context, c *GopassClient, op string, fn func() (T, error)) (T, error) {
+	value, err := fn()
+
+	// attempt counts the calls made so far
+	for attempt := 1; isTransientError(err); attempt++ {
+		if attempt >= c.retry.MaxAttempts {
+			if attempt > 1 {
+				err = fmt.Errorf("%s still failing after %d attempts: %w", op, attempt, err)
+			}
+			break
+		}
+
+		delay := c.retry.backoff(attempt)
+		c.metrics.retry()
+		if trace := readTraceFrom(ctx); trace != nil {
+			trace.retries.Add(1)
+		}
+
+		tflog.Debug(ctx, "Transient gopass error, retrying", map[string]interface{}{
+			"operation": c.logText(op),
+			"attempt":   attempt,
+			"delay":     delay.String(),
+			"error":     c.logError(err),
+		})
+
+		if sleepErr := c.sleep(ctx, delay); sleepErr != nil {
+			return value, contextError(op, 0, sleepErr)
+		}
+
+		value, err = fn()
+	}
+
+	return value, err
+}
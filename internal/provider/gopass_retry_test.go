@@ -0,0 +1,199 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// mockFlakyStore fails the first failures calls to Get with failErr
+type mockFlakyStore struct {
+	*mockStore
+	failures int
+	failErr  error
+	getCalls int
+}
+
+func (m *mockFlakyStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	m.getCalls++
+	if m.getCalls <= m.failures {
+		return nil, m.failErr
+	}
+	return m.mockStore.Get(ctx, name, revision)
+}
+
+// newRetryTestClient returns a client that records backoff delays instead of sleeping
+func newRetryTestClient(store SecretStore) (*GopassClient, *[]time.Duration) {
+	client := NewGopassClient("")
+	client.store = store
+
+	var delays []time.Duration
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return client, &delays
+}
+
+func TestIsTransientError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "nil", err: nil, transient: false},
+		{name: "agent not reachable", err: errors.New("gpg: can't connect to the agent: IPC connect call failed"), transient: true},
+		{name: "card busy", err: errors.New("gpg: selecting card failed: Device or resource busy"), transient: true},
+		{name: "git index lock", err: errors.New("fatal: Unable to create '/store/.git/index.lock': File exists"), transient: true},
+		{name: "not found", err: errors.New("secret \"x\" not found"), transient: false},
+		{name: "no secret key", err: errors.New("gpg: decryption failed: No secret key"), transient: false},
+		{name: "deadline", err: fmt.Errorf("agent not ready: %w", context.DeadlineExceeded), transient: false},
+		{name: "cancelled", err: context.Canceled, transient: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isTransientError(tc.err); got != tc.transient {
+				t.Errorf("isTransientError(%v) = %v, want %v", tc.err, got, tc.transient)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := retryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for retry := 1; retry <= 8; retry++ {
+		ceiling := policy.BaseDelay << (retry - 1)
+		if ceiling > policy.MaxDelay {
+			ceiling = policy.MaxDelay
+		}
+
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(retry)
+			if delay < ceiling/2 || delay > ceiling {
+				t.Fatalf("retry %d: delay %s outside [%s, %s]", retry, delay, ceiling/2, ceiling)
+			}
+		}
+	}
+}
+
+func TestGopassClient_GetSecret_RetriesTransientError(t *testing.T) {
+	mockStore := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failures:  2,
+		failErr:   errors.New("gpg: can't connect to the agent"),
+	}
+	secret := secrets.New()
+	secret.SetPassword("recovered")
+	mockStore.secrets["test/secret"] = secret
+
+	client, delays := newRetryTestClient(mockStore)
+
+	password, err := client.GetSecret(context.Background(), "test/secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "recovered" {
+		t.Errorf("expected password 'recovered', got %q", password)
+	}
+	if mockStore.getCalls != 3 {
+		t.Errorf("expected 3 Get calls, got %d", mockStore.getCalls)
+	}
+	if len(*delays) != 2 {
+		t.Errorf("expected 2 backoff sleeps, got %d", len(*delays))
+	}
+}
+
+func TestGopassClient_GetSecret_NoRetryOnPermanentError(t *testing.T) {
+	mockStore := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failures:  5,
+		failErr:   errors.New("gpg: decryption failed: No secret key"),
+	}
+	client, delays := newRetryTestClient(mockStore)
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if err == nil {
+		t.Fatal("expected error but got none")
+	}
+	if mockStore.getCalls != 1 {
+		t.Errorf("expected a single Get call, got %d", mockStore.getCalls)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("expected no backoff sleeps, got %d", len(*delays))
+	}
+}
+
+func TestGopassClient_GetSecret_RetriesExhausted(t *testing.T) {
+	mockStore := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failures:  100,
+		failErr:   errors.New("gpg: selecting card failed: card busy"),
+	}
+	client, _ := newRetryTestClient(mockStore)
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if err == nil {
+		t.Fatal("expected error but got none")
+	}
+	if mockStore.getCalls != defaultRetryAttempts {
+		t.Errorf("expected %d Get calls, got %d", defaultRetryAttempts, mockStore.getCalls)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("still failing after %d attempts", defaultRetryAttempts)) {
+		t.Errorf("expected attempts in error message, got %q", err.Error())
+	}
+	if !strings.Contains(err.Error(), "card busy") {
+		t.Errorf("expected original error to be preserved, got %q", err.Error())
+	}
+}
+
+func TestGopassClient_GetSecret_RetryDisabled(t *testing.T) {
+	mockStore := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failures:  100,
+		failErr:   errors.New("card busy"),
+	}
+	client, _ := newRetryTestClient(mockStore)
+	client.retry.MaxAttempts = 1
+
+	_, err := client.GetSecret(context.Background(), "test/secret")
+	if err == nil {
+		t.Fatal("expected error but got none")
+	}
+	if mockStore.getCalls != 1 {
+		t.Errorf("expected a single Get call, got %d", mockStore.getCalls)
+	}
+}
+
+func TestGopassClient_GetSecret_RetryCancelledDuringBackoff(t *testing.T) {
+	mockStore := &mockFlakyStore{
+		mockStore: newMockStore(),
+		failures:  100,
+		failErr:   errors.New("card busy"),
+	}
+	client := NewGopassClient("")
+	client.store = mockStore
+	client.retry.BaseDelay = time.Minute
+	client.retry.MaxDelay = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := client.GetSecret(ctx, "test/secret")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// redactValues replaces all registered values in s. Unlike redact it keeps
+// paths, for text shown to the user rather than logged.
+func (r *redactor) redactValues(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.replaceValues(s)
+}
+
+// sanitizeDiagnostics returns diags with every secret value the client has
+// handed out or written replaced, in summaries and details alike. Error texts
+// from gopass and its backends can quote secret content, e.g. a parse error
+// showing the line it failed on, so diagnostics are never trusted to be free
+// of values. It is safe on a nil client.
+func (c *GopassClient) sanitizeDiagnostics(diags diag.Diagnostics) diag.Diagnostics {
+	if c == nil || len(diags) == 0 {
+		return diags
+	}
+
+	sanitized := make(diag.Diagnostics, 0, len(diags))
+	for _, d := range diags {
+		summary := c.redactor.redactValues(d.Summary())
+		detail := c.redactor.redactValues(d.Detail())
+		if summary == d.Summary() && detail == d.Detail() {
+			sanitized = append(sanitized, d)
+			continue
+		}
+
+		var clean diag.Diagnostic
+		if d.Severity() == diag.SeverityError {
+			clean = diag.NewErrorDiagnostic(summary, detail)
+		} else {
+			clean = diag.NewWarningDiagnostic(summary, detail)
+		}
+		if withPath, ok := d.(diag.DiagnosticWithPath); ok {
+			clean = diag.WithPath(withPath.Path(), clean)
+		}
+		sanitized = append(sanitized, clean)
+	}
+	return sanitized
+}
+
+// finishDiagnostics completes the diagnostics of a resource operation: it
+// adds the warnings the client queued along the way, e.g. a failed git sync,
+// and removes secret values from all of them. Resources defer it first thing,
+// so no diagnostic leaves the provider without passing through it. It is safe
+// on a nil client.
+func (c *GopassClient) finishDiagnostics(diags diag.Diagnostics) diag.Diagnostics {
+	diags.Append(c.takeWarnings()...)
+	return c.sanitizeDiagnostics(diags)
+}
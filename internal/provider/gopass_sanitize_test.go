@@ -0,0 +1,125 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// mockEchoingStore fails every write with an error quoting the secret body,
+// like a backend reporting what it could not encode.
+type mockEchoingStore struct {
+	*mockStore
+}
+
+func (m *mockEchoingStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	return fmt.Errorf("cannot encode entry %q", sec.Bytes())
+}
+
+// assertNoValue fails if any diagnostic mentions value.
+func assertNoValue(t *testing.T, diags diag.Diagnostics, value string) {
+	t.Helper()
+	for _, d := range diags {
+		if strings.Contains(d.Summary(), value) || strings.Contains(d.Detail(), value) {
+			t.Errorf("diagnostic contains a secret value: %s: %s", d.Summary(), d.Detail())
+		}
+	}
+}
+
+func TestSanitizeDiagnostics(t *testing.T) {
+	client := NewGopassClient("")
+	client.redactor.addValues(redactTestValue)
+
+	var diags diag.Diagnostics
+	diags.AddAttributeError(path.Root("path"), "Failed", "backend said: "+redactTestValue)
+	diags.AddWarning("Warning about "+redactTestValue, "no value here")
+	diags.AddWarning("Clean", "nothing to redact in app/db")
+
+	sanitized := client.sanitizeDiagnostics(diags)
+
+	if len(sanitized) != 3 {
+		t.Fatalf("expected 3 diagnostics, got %d", len(sanitized))
+	}
+	assertNoValue(t, sanitized, redactTestValue)
+	if sanitized[0].Severity() != diag.SeverityError || sanitized[1].Severity() != diag.SeverityWarning {
+		t.Error("expected severities to be kept")
+	}
+	if withPath, ok := sanitized[0].(diag.DiagnosticWithPath); !ok || !withPath.Path().Equal(path.Root("path")) {
+		t.Errorf("expected the attribute path to be kept, got %v", sanitized[0])
+	}
+	if sanitized[0].Detail() != "backend said: "+redactedValue {
+		t.Errorf("unexpected detail %q", sanitized[0].Detail())
+	}
+	if !sanitized[2].Equal(diags[2]) {
+		t.Error("expected clean diagnostics to be kept as they are")
+	}
+}
+
+func TestSanitizeDiagnostics_KeepsPaths(t *testing.T) {
+	client := NewGopassClient("")
+	client.redactor.hashPaths = true
+	client.redactor.addPath("app/db")
+
+	var diags diag.Diagnostics
+	diags.AddError("Secret not found", `failed to get secret "app/db"`)
+
+	if got := client.sanitizeDiagnostics(diags)[0].Detail(); got != `failed to get secret "app/db"` {
+		t.Errorf("expected paths to stay readable in diagnostics, got %q", got)
+	}
+}
+
+func TestFinishDiagnostics_NilClient(t *testing.T) {
+	var client *GopassClient
+	var diags diag.Diagnostics
+	diags.AddError("Failed", "detail")
+
+	if got := client.finishDiagnostics(diags); len(got) != 1 || got[0].Detail() != "detail" {
+		t.Errorf("expected diagnostics unchanged, got %v", got)
+	}
+}
+
+func TestSecretEphemeralResource_Open_ErrorDoesNotLeakValue(t *testing.T) {
+	client := newRedactTestClient()
+	client.retry.MaxAttempts = 1
+
+	// Reading app/db makes its value known, then a failing read quotes it
+	if resp := openSecretEphemeral(t, client, "app/db"); resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	store := client.store.(*mockFlakyStore)
+	store.secrets["app/other"] = newMockSecret("other-value")
+	store.failures = store.getCalls + 1
+
+	resp := openSecretEphemeral(t, client, "app/other")
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected the read to fail")
+	}
+	assertNoValue(t, resp.Diagnostics, redactTestValue)
+	if !strings.Contains(resp.Diagnostics.Errors()[0].Detail(), redactedValue) {
+		t.Errorf("expected a redaction marker, got %q", resp.Diagnostics.Errors()[0].Detail())
+	}
+}
+
+func TestSetSecret_ErrorDoesNotLeakValue(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = &mockEchoingStore{mockStore: newMockStore()}
+	client.retry.MaxAttempts = 1
+
+	err := client.SetSecret(context.Background(), "app/new", redactTestValue)
+	if err == nil || !strings.Contains(err.Error(), redactTestValue) {
+		t.Fatalf("expected the backend error to quote the value, got %v", err)
+	}
+
+	var diags diag.Diagnostics
+	diags.AddError(errorSummary(err, "Failed to create secret"), errorDetail(err.Error(), err))
+	assertNoValue(t, client.finishDiagnostics(diags), redactTestValue)
+}
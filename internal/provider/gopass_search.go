@@ -0,0 +1,124 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// secretSearch is a compiled search pattern: the expression secret paths
+// must match as a whole, and the directory prefix every match lies below,
+// which limits the walk.
+type secretSearch struct {
+	re     *regexp.Regexp
+	prefix string
+}
+
+// compileGlob compiles a glob over secret paths. "*" matches any characters
+// but "/", "?" a single one of them and "[...]" a character class ("[!...]"
+// negates it); "**" matches across directories, "**/" also no directory at all.
+func compileGlob(glob string) (*secretSearch, error) {
+	glob = normalizePath(glob)
+	if glob == "" {
+		return nil, errors.New("glob must not be empty")
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if !strings.HasPrefix(glob[i:], "**") {
+				expr.WriteString("[^/]*")
+				continue
+			}
+			i++
+			if strings.HasPrefix(glob[i+1:], "/") {
+				expr.WriteString("(?:.*/)?")
+				i++
+			} else {
+				expr.WriteString(".*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+	literal := glob[:strings.IndexAny(glob+"*", "*?[")]
+	return &secretSearch{re: re, prefix: dirPrefix(literal)}, nil
+}
+
+// compileRegex compiles a regular expression that secret paths must match
+// as a whole.
+func compileRegex(expr string) (*secretSearch, error) {
+	unanchored, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	// Every match starts with the literal prefix; the anchored expression
+	// does not report one. A valid expression is balanced, so it cannot
+	// escape the anchoring group.
+	literal, _ := unanchored.LiteralPrefix()
+	re := regexp.MustCompile("^(?:" + expr + ")$")
+	return &secretSearch{re: re, prefix: dirPrefix(literal)}, nil
+}
+
+// dirPrefix returns the directories of p up to its last "/", or "".
+func dirPrefix(p string) string {
+	return p[:strings.LastIndexByte(p, '/')+1]
+}
+
+// SearchSecrets returns the sorted paths of all secrets matching search that
+// the resource in ctx may access. Paths a policy denies are left out rather
+// than failing the search.
+func (c *GopassClient) SearchSecrets(ctx context.Context, search *secretSearch) ([]string, error) {
+	tflog.Debug(ctx, "Searching secrets", map[string]interface{}{
+		"prefix": c.logPath(search.prefix),
+	})
+
+	var matches []string
+	err := c.WalkSecrets(ctx, search.prefix, func(secretPath string) error {
+		if search.re.MatchString(secretPath) && c.permitted(ctx, secretPath) {
+			matches = append(matches, secretPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Mounted stores are walked after the store owning the prefix
+	sort.Strings(matches)
+
+	tflog.Debug(ctx, "Searched secrets", map[string]interface{}{
+		"prefix": c.logPath(search.prefix),
+		"count":  len(matches),
+	})
+	return matches, nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob    string
+		prefix  string
+		matches []string
+		misses  []string
+	}{
+		{"services/*/prod/password", "services/",
+			[]string{"services/api/prod/password"},
+			[]string{"services/api/staging/password", "services/a/b/prod/password", "services/api/prod/password2"}},
+		{"services/**/password", "services/",
+			[]string{"services/password", "services/a/password", "services/a/b/password"},
+			[]string{"services/a/password/old", "other/password"}},
+		{"**", "",
+			[]string{"a", "a/b/c"}, nil},
+		{"app/db?", "app/",
+			[]string{"app/db1"},
+			[]string{"app/db", "app/db12", "app/db/"}},
+		{"certs/[!x]*.pem", "certs/",
+			[]string{"certs/a.pem"},
+			[]string{"certs/x.pem", "certs/apem"}},
+		{"/team/api/", "team/",
+			[]string{"team/api"}, nil},
+	}
+	for _, tt := range tests {
+		search, err := compileGlob(tt.glob)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.glob, err)
+		}
+		if search.prefix != tt.prefix {
+			t.Errorf("%s: expected prefix %q, got %q", tt.glob, tt.prefix, search.prefix)
+		}
+		for _, p := range tt.matches {
+			if !search.re.MatchString(p) {
+				t.Errorf("%s: expected %q to match", tt.glob, p)
+			}
+		}
+		for _, p := range tt.misses {
+			if search.re.MatchString(p) {
+				t.Errorf("%s: expected %q not to match", tt.glob, p)
+			}
+		}
+	}
+
+	for _, glob := range []string{"", "/", "certs/[a.pem"} {
+		if _, err := compileGlob(glob); err == nil {
+			t.Errorf("%q: expected an error", glob)
+		}
+	}
+}
+
+func TestCompileRegex(t *testing.T) {
+	search, err := compileRegex(`certs/prod/.+\.(crt|pem)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if search.prefix != "certs/prod/" {
+		t.Errorf("unexpected prefix %q", search.prefix)
+	}
+	if !search.re.MatchString("certs/prod/api.pem") || search.re.MatchString("old/certs/prod/api.pem") {
+		t.Error("expected the expression to match whole paths only")
+	}
+
+	// Alternatives stay within the anchors
+	search, err = compileRegex("a|b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if search.re.MatchString("ab") || search.prefix != "" {
+		t.Errorf("expected an anchored match without prefix, got prefix %q", search.prefix)
+	}
+
+	// Case-insensitive expressions cannot narrow the walk
+	if search, _ = compileRegex("(?i)certs/.*"); search.prefix != "" || !search.re.MatchString("CERTS/a") {
+		t.Errorf("expected a case-insensitive match without prefix, got prefix %q", search.prefix)
+	}
+
+	for _, expr := range []string{"a(", "a)|(b"} {
+		if _, err := compileRegex(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestGopassClient_SearchSecrets(t *testing.T) {
+	client := newPolicyTestClient()
+	ctx := context.Background()
+
+	search, err := compileGlob("app/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := client.SearchSecrets(ctx, search)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// app/admin/root is denied by the provider policy
+	if !slices.Equal(paths, []string{"app/db"}) {
+		t.Errorf("unexpected paths %v", paths)
+	}
+
+	search, _ = compileRegex("billing/.*")
+	paths, err = client.SearchSecrets(withPolicy(ctx, "billing"), search)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(paths, []string{"billing/api"}) {
+		t.Errorf("expected the named policy to apply, got %v", paths)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// secureBuffer holds a private copy of secret bytes that can be wiped.
+//
+// Zeroization is best-effort: the gopass library, gpg, the plugin protocol
+// and Terraform all keep their own copies, which this provider cannot reach.
+// What it can do is make sure the copy it keeps for an ephemeral resource
+// does not outlive the resource.
+type secureBuffer struct {
+	mu   sync.Mutex
+	data []byte
+	// locked marks data as allocated by allocLocked, freed on Wipe
+	locked bool
+}
+
+// newSecureBuffer copies value into a new wipeable buffer.
+func newSecureBuffer(value string) *secureBuffer {
+	data := make([]byte, len(value))
+	copy(data, value)
+	return &secureBuffer{data: data}
+}
+
+// newLockedSecureBuffer copies value into a new wipeable buffer in memory
+// locked into RAM, like a lockedBuffer. It fails if the platform cannot lock
+// memory or the locked memory limit is reached.
+func newLockedSecureBuffer(value string) (*secureBuffer, error) {
+	mem, err := allocLocked(len(value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate locked memory: %w", err)
+	}
+	copy(mem, value)
+	return &secureBuffer{data: mem, locked: true}, nil
+}
+
+// String returns a copy of the buffer contents. Strings are immutable, so it
+// never shares the buffer's memory: wiping the buffer leaves strings already
+// handed out intact, and those are up to the garbage collector.
+func (b *secureBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return string(b.data)
+}
+
+// Wipe overwrites the buffer with zeros and releases it. Nothing else points
+// into the buffer, so locked memory is unlocked and unmapped right away.
+func (b *secureBuffer) Wipe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	clear(b.data)
+	// Keep the compiler from treating the clear as a dead store
+	runtime.KeepAlive(b.data)
+	if b.locked && b.data != nil {
+		freeLocked(b.data)
+	}
+	b.data = nil
+}
+
+// secretBuffers collects the buffers backing one ephemeral resource's result.
+type secretBuffers struct {
+	mu      sync.Mutex
+	buffers []*secureBuffer
+	// secure allocates the buffers in locked memory (secure_memory)
+	secure bool
+	// lockErr is why a buffer had to fall back to regular memory, if one did
+	lockErr error
+}
+
+// newSecretBuffers returns the buffers for the result of an ephemeral
+// resource, in locked memory if secure_memory is enabled.
+func (c *GopassClient) newSecretBuffers() *secretBuffers {
+	return &secretBuffers{secure: c.secureMemory}
+}
+
+// protect copies value into a tracked buffer, wiped with the others, and
+// returns the value.
+// In secure mode a value that cannot be locked into memory is kept in regular
+// memory, and the error recorded.
+func (s *secretBuffers) protect(value string) string {
+	var buf *secureBuffer
+	if s.secure && value != "" {
+		locked, err := newLockedSecureBuffer(value)
+		if err == nil {
+			buf = locked
+		} else {
+			s.mu.Lock()
+			if s.lockErr == nil {
+				s.lockErr = err
+			}
+			s.mu.Unlock()
+		}
+	}
+	// Only values that are not locked get a copy on the regular heap
+	if buf == nil {
+		buf = newSecureBuffer(value)
+	}
+
+	s.mu.Lock()
+	s.buffers = append(s.buffers, buf)
+	s.mu.Unlock()
+
+	return buf.String()
+}
+
+// wipe zeroes all tracked buffers and returns how many were wiped.
+func (s *secretBuffers) wipe() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, buf := range s.buffers {
+		buf.Wipe()
+	}
+	n := len(s.buffers)
+	s.buffers = nil
+	return n
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestSecureBuffer_Wipe(t *testing.T) {
+	buf := newSecureBuffer("hunter2")
+	backing := buf.data
+
+	if got := buf.String(); got != "hunter2" {
+		t.Fatalf("expected %q, got %q", "hunter2", got)
+	}
+
+	buf.Wipe()
+
+	if !bytes.Equal(backing, make([]byte, len("hunter2"))) {
+		t.Errorf("expected backing memory to be zeroed, got %q", backing)
+	}
+	if got := buf.String(); got != "" {
+		t.Errorf("expected empty string after wipe, got %q", got)
+	}
+}
+
+func TestSecureBuffer_WipeLocked(t *testing.T) {
+	buf, err := newLockedSecureBuffer("hunter2")
+	if err != nil {
+		t.Skipf("locked memory unavailable: %v", err)
+	}
+	value := buf.String()
+
+	buf.Wipe()
+
+	// The string is a copy, so unmapping the locked memory leaves it intact
+	if value != "hunter2" {
+		t.Errorf("expected the string to stay intact after wipe, got %q", value)
+	}
+	if buf.data != nil || buf.String() != "" {
+		t.Error("expected the locked memory to be released")
+	}
+}
+
+func TestSecureBuffer_CopiesInput(t *testing.T) {
+	input := []byte("s3cret")
+	buf := newSecureBuffer(string(input))
+	buf.Wipe()
+
+	if string(input) != "s3cret" {
+		t.Error("expected wiping not to affect the caller's data")
+	}
+}
+
+func TestSecureBuffer_Empty(t *testing.T) {
+	buf := newSecureBuffer("")
+	if buf.String() != "" {
+		t.Error("expected empty string")
+	}
+	buf.Wipe()
+}
+
+func TestSecretBuffers_ProtectAndWipe(t *testing.T) {
+	var buffers secretBuffers
+
+	first := buffers.protect("alpha")
+	second := buffers.protect("beta")
+	if first != "alpha" || second != "beta" {
+		t.Fatalf("unexpected protected values %q, %q", first, second)
+	}
+	backing := buffers.buffers[0].data
+
+	if n := buffers.wipe(); n != 2 {
+		t.Errorf("expected 2 wiped buffers, got %d", n)
+	}
+
+	if !bytes.Equal(backing, make([]byte, len("alpha"))) {
+		t.Errorf("expected buffer memory to be zeroed, got %q", backing)
+	}
+	// The returned strings are copies and stay valid
+	if first != "alpha" || second != "beta" {
+		t.Errorf("expected protected strings to stay intact, got %q, %q", first, second)
+	}
+
+	if n := buffers.wipe(); n != 0 {
+		t.Errorf("expected nothing left to wipe, got %d", n)
+	}
+}
+
+func TestGopassClient_CloseLease_WipesBuffers(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newLifecycleTestClient()
+	defer client.Close(ctx)
+
+	buffers := &secretBuffers{}
+	buffers.protect("s3cret")
+	backing := buffers.buffers[0].data
+
+	private := mockPrivateState{}
+	if diags := client.openLease(ctx, private, buffers); diags.HasError() {
+		t.Fatalf("openLease() error = %v", diags)
+	}
+	if string(backing) != "s3cret" {
+		t.Fatal("expected buffer to stay intact while the lease is open")
+	}
+
+	if diags := client.closeLease(ctx, private); diags.HasError() {
+		t.Fatalf("closeLease() error = %v", diags)
+	}
+	if !bytes.Equal(backing, make([]byte, len("s3cret"))) {
+		t.Errorf("expected buffer to be wiped on close, got %q", backing)
+	}
+}
+
+func TestShutdown_WipesOpenLeases(t *testing.T) {
+	ctx := context.Background()
+	client, store := newLifecycleTestClient()
+
+	if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+
+	buffers := &secretBuffers{}
+	buffers.protect("s3cret")
+	backing := buffers.buffers[0].data
+	if diags := client.openLease(ctx, mockPrivateState{}, buffers); diags.HasError() {
+		t.Fatalf("openLease() error = %v", diags)
+	}
+
+	Shutdown(ctx)
+
+	if !bytes.Equal(backing, make([]byte, len("s3cret"))) {
+		t.Errorf("expected unclosed lease to be wiped on shutdown, got %q", backing)
+	}
+	if store.closed.Load() != 1 {
+		t.Errorf("expected store closed on shutdown, got %d closes", store.closed.Load())
+	}
+}
+
+func TestGopassClient_CloseLease_InvalidState(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newLifecycleTestClient()
+
+	diags := client.closeLease(ctx, mockPrivateState{storeLeaseKey: []byte("{")})
+	if diags.HasError() {
+		t.Fatalf("expected only a warning, got %v", diags)
+	}
+	if diags.WarningsCount() != 1 {
+		t.Errorf("expected a warning for undecodable lease, got %v", diags)
+	}
+}
+
+func TestPrefetcher_ForgetOnClose(t *testing.T) {
+	ctx := context.Background()
+	client := NewGopassClient("")
+	client.store = newPrefetchTestStore()
+	client.prefetch = newPrefetcher([]string{"app/db/password"})
+
+	if _, err := client.GetSecret(ctx, "app/db/password"); err != nil {
+		t.Fatalf("GetSecret() error = %v", err)
+	}
+	if _, ok := client.prefetch.lookup("app/db/password"); !ok {
+		t.Fatal("expected secret to be prefetched")
+	}
+
+	client.Close(ctx)
+
+	if _, ok := client.prefetch.lookup("app/db/password"); ok {
+		t.Error("expected prefetched secrets to be dropped on Close")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// readGroup coalesces concurrent reads of the same secret, so that when many
+// resources open one path at the same time only a single decryption runs and
+// every caller shares its result. Unlike the prefetch cache nothing is kept
+// once the read completes: a later read decrypts again.
+//
+// With a hardware token this turns N touches/PIN prompts for the same secret
+// into one.
+type readGroup struct {
+	mu      sync.Mutex
+	flights map[string]*readFlight
+}
+
+// readFlight is a read in progress.
+type readFlight struct {
+	done   chan struct{}
+	secret gopass.Secret
+	err    error
+}
+
+// do runs fn for key unless a call for key is already in flight, in which case
+// it waits for that call and returns its result. shared reports whether the
+// result came from another caller's call.
+//
+// A waiting caller gives up when its own context is done; the call it waited
+// for keeps running for the remaining callers.
+func (g *readGroup) do(ctx context.Context, key string, fn func() (gopass.Secret, error)) (secret gopass.Secret, shared bool, err error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*readFlight)
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+
+		select {
+		case <-f.done:
+			return f.secret, true, f.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+
+	f := &readFlight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.secret, f.err = fn()
+	return f.secret, false, f.err
+}
@@ -0,0 +1,195 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// mockGatedStore blocks every Get until the gate is closed and counts calls
+type mockGatedStore struct {
+	*mockStore
+	gate  chan struct{}
+	calls atomic.Int32
+}
+
+func (m *mockGatedStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	m.calls.Add(1)
+	<-m.gate
+	return m.mockStore.Get(ctx, name, revision)
+}
+
+func TestReadGroup_CoalescesConcurrentCalls(t *testing.T) {
+	var g readGroup
+	var calls atomic.Int32
+	gate := make(chan struct{})
+	want := newMockSecret("shared")
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]gopass.Secret, callers)
+	sharedCount := atomic.Int32{}
+
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			secret, shared, err := g.do(context.Background(), "app/db", func() (gopass.Secret, error) {
+				calls.Add(1)
+				<-gate
+				return want, nil
+			})
+			if err != nil {
+				t.Errorf("do() error = %v", err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+			results[i] = secret
+		}()
+	}
+
+	// Give every caller time to join the flight before it completes
+	time.Sleep(20 * time.Millisecond)
+	close(gate)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected a single call, got %d", calls.Load())
+	}
+	if sharedCount.Load() != callers-1 {
+		t.Errorf("expected %d shared results, got %d", callers-1, sharedCount.Load())
+	}
+	for i, secret := range results {
+		if secret != want {
+			t.Errorf("caller %d got a different result", i)
+		}
+	}
+}
+
+func TestReadGroup_SharesErrors(t *testing.T) {
+	var g readGroup
+	gate := make(chan struct{})
+	readErr := errors.New("decryption failed")
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(context.Background(), "app/db", func() (gopass.Secret, error) {
+			<-gate
+			return nil, readErr
+		})
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, _, err := g.do(context.Background(), "app/db", func() (gopass.Secret, error) {
+			t.Error("expected waiter not to run its own call")
+			return nil, nil
+		})
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(gate)
+
+	if err := <-done; !errors.Is(err, readErr) {
+		t.Errorf("expected leader error, got %v", err)
+	}
+	if err := <-waiter; !errors.Is(err, readErr) {
+		t.Errorf("expected shared error, got %v", err)
+	}
+}
+
+func TestReadGroup_WaiterCancelled(t *testing.T) {
+	var g readGroup
+	gate := make(chan struct{})
+	defer close(gate)
+
+	go func() {
+		_, _, _ = g.do(context.Background(), "app/db", func() (gopass.Secret, error) {
+			<-gate
+			return nil, nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, shared, err := g.do(ctx, "app/db", func() (gopass.Secret, error) {
+		t.Error("expected waiter not to run its own call")
+		return nil, nil
+	})
+	if !shared || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected waiter to give up with its own deadline, got shared=%v err=%v", shared, err)
+	}
+}
+
+func TestReadGroup_SequentialCallsNotShared(t *testing.T) {
+	var g readGroup
+	calls := 0
+
+	for range 3 {
+		_, shared, _ := g.do(context.Background(), "app/db", func() (gopass.Secret, error) {
+			calls++
+			return nil, nil
+		})
+		if shared {
+			t.Error("expected sequential calls not to be shared")
+		}
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestGopassClient_ConcurrentReadsOfSamePath_DecryptOnce(t *testing.T) {
+	ctx := context.Background()
+	store := &mockGatedStore{mockStore: newMockStore(), gate: make(chan struct{})}
+	store.secrets["app/db"] = newMockSecret("s3cret")
+	store.secrets["app/other"] = newMockSecret("other")
+
+	client := NewGopassClient("")
+	client.store = store
+
+	const readers = 6
+	var wg sync.WaitGroup
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := client.GetSecret(ctx, "app/db")
+			if err != nil || value != "s3cret" {
+				t.Errorf("GetSecret() = %q, %v", value, err)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := client.GetSecret(ctx, "app/other"); err != nil {
+			t.Errorf("GetSecret() error = %v", err)
+		}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(store.gate)
+	wg.Wait()
+
+	// One decryption per distinct path
+	if got := store.calls.Load(); got != 2 {
+		t.Errorf("expected 2 store reads, got %d", got)
+	}
+	if got := client.metrics.coalescedReads(); got != readers-1 {
+		t.Errorf("expected %d coalesced reads, got %d", readers-1, got)
+	}
+}
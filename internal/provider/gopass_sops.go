@@ -0,0 +1,556 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"gopkg.in/yaml.v3"
+)
+
+// sopsFormats lists the file formats gopass_sops_file decrypts.
+var sopsFormats = []string{"json", "yaml"}
+
+// sopsValue matches a value SOPS encrypted.
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]+),tag:([^,]+),type:([a-z]+)\]$`)
+
+// sopsMetadataKey is the top-level key SOPS keeps its metadata under.
+const sopsMetadataKey = "sops"
+
+// sopsMetadata is the part of a file's SOPS metadata needed to decrypt it.
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	PGP []struct {
+		Fingerprint string `yaml:"fp"`
+		Enc         string `yaml:"enc"`
+	} `yaml:"pgp"`
+	KeyGroups         []yaml.Node `yaml:"key_groups"`
+	LastModified      string      `yaml:"lastmodified"`
+	MAC               string      `yaml:"mac"`
+	UnencryptedSuffix string      `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string      `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string      `yaml:"unencrypted_regex"`
+	EncryptedRegex    string      `yaml:"encrypted_regex"`
+	MACOnlyEncrypted  bool        `yaml:"mac_only_encrypted"`
+
+	unencryptedRegex, encryptedRegex *regexp.Regexp
+}
+
+// sopsFile is a parsed SOPS-encrypted JSON or YAML file.
+type sopsFile struct {
+	format string
+	docs   []*yaml.Node
+	meta   sopsMetadata
+}
+
+// sopsKeys is the key material a sopsFile is decrypted with.
+type sopsKeys struct {
+	age []age.Identity
+	pgp openpgp.EntityList
+}
+
+// sopsFormat returns the format of the SOPS file at source: format if set,
+// or else the one its extension implies.
+func sopsFormat(source, format string) (string, error) {
+	if format != "" {
+		for _, f := range sopsFormats {
+			if f == format {
+				return format, nil
+			}
+		}
+		return "", fmt.Errorf("unsupported format %q, expected one of %s", format, strings.Join(sopsFormats, ", "))
+	}
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".json":
+		return "json", nil
+	case ".yaml", ".yml":
+		return "yaml", nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %q from its extension, set format to one of %s",
+		source, strings.Join(sopsFormats, ", "))
+}
+
+// parseSopsFile parses content, a SOPS-encrypted file in format, and takes
+// its metadata out of the document tree.
+func parseSopsFile(content []byte, format string) (*sopsFile, error) {
+	f := &sopsFile{format: format}
+	found := false
+
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", format, err)
+		}
+		if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+			return nil, errors.New("not a SOPS file: the document is not a map")
+		}
+
+		root := doc.Content[0]
+		for i := 0; i < len(root.Content); i += 2 {
+			if root.Content[i].Value != sopsMetadataKey {
+				continue
+			}
+			if !found {
+				if err := root.Content[i+1].Decode(&f.meta); err != nil {
+					return nil, fmt.Errorf("invalid SOPS metadata: %w", err)
+				}
+				found = true
+			}
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			break
+		}
+		f.docs = append(f.docs, &doc)
+	}
+
+	switch {
+	case !found:
+		return nil, fmt.Errorf("not a SOPS file: no %q key", sopsMetadataKey)
+	case format == "json" && len(f.docs) != 1:
+		return nil, errors.New("invalid json: more than one document")
+	case len(f.meta.KeyGroups) > 0:
+		return nil, errors.New("files with key groups (Shamir secret sharing) are not supported")
+	case f.meta.MAC == "":
+		return nil, errors.New("the SOPS metadata has no MAC")
+	}
+
+	var err error
+	if f.meta.UnencryptedRegex != "" {
+		if f.meta.unencryptedRegex, err = regexp.Compile(f.meta.UnencryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid unencrypted_regex: %w", err)
+		}
+	}
+	if f.meta.EncryptedRegex != "" {
+		if f.meta.encryptedRegex, err = regexp.Compile(f.meta.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("invalid encrypted_regex: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// encrypted reports whether the value at path is encrypted, the way SOPS
+// decides it from the suffix and regex settings in the metadata.
+func (m *sopsMetadata) encrypted(path []string) bool {
+	matches := func(match func(string) bool) bool {
+		for _, key := range path {
+			if match(key) {
+				return true
+			}
+		}
+		return false
+	}
+
+	encrypted := true
+	if m.UnencryptedSuffix != "" && matches(func(k string) bool { return strings.HasSuffix(k, m.UnencryptedSuffix) }) {
+		encrypted = false
+	}
+	if m.EncryptedSuffix != "" {
+		encrypted = matches(func(k string) bool { return strings.HasSuffix(k, m.EncryptedSuffix) })
+	}
+	if m.unencryptedRegex != nil && matches(m.unencryptedRegex.MatchString) {
+		encrypted = false
+	}
+	if m.encryptedRegex != nil {
+		encrypted = matches(m.encryptedRegex.MatchString)
+	}
+	return encrypted
+}
+
+// dataKey decrypts the file's data key with the first age or PGP key that
+// was one of its recipients.
+func (f *sopsFile) dataKey(keys sopsKeys) ([]byte, error) {
+	var errs []error
+	if len(keys.age) > 0 {
+		for _, stanza := range f.meta.Age {
+			r, err := age.Decrypt(armor.NewReader(strings.NewReader(stanza.Enc)), keys.age...)
+			if err == nil {
+				return io.ReadAll(r)
+			}
+			errs = append(errs, fmt.Errorf("age recipient %s: %w", stanza.Recipient, err))
+		}
+	}
+	if len(keys.pgp) > 0 {
+		for _, stanza := range f.meta.PGP {
+			block, err := pgparmor.Decode(strings.NewReader(stanza.Enc))
+			if err == nil {
+				var md *openpgp.MessageDetails
+				if md, err = openpgp.ReadMessage(block.Body, keys.pgp, nil, nil); err == nil {
+					return io.ReadAll(md.UnverifiedBody)
+				}
+			}
+			errs = append(errs, fmt.Errorf("PGP key %s: %w", stanza.Fingerprint, err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("the file is encrypted for none of the given kinds of keys")
+	}
+	return nil, fmt.Errorf("no given key decrypts the file: %w", errors.Join(errs...))
+}
+
+// decrypt decrypts the file's values in place with keys and checks its MAC.
+func (f *sopsFile) decrypt(keys sopsKeys) error {
+	key, err := f.dataKey(keys)
+	if err != nil {
+		return err
+	}
+	defer clear(key)
+	if len(key) != 32 {
+		return fmt.Errorf("the data key has %d bytes, expected 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	d := &sopsDecrypter{meta: &f.meta, block: block, mac: sha512.New()}
+	for _, doc := range f.docs {
+		if err := d.walk(doc.Content[0], nil); err != nil {
+			return err
+		}
+	}
+
+	stored, typ, err := d.decryptValue(f.meta.MAC, f.meta.LastModified)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt the MAC: %w", err)
+	}
+	if typ != "str" || !hmac.Equal([]byte(stored), []byte(fmt.Sprintf("%X", d.mac.Sum(nil)))) {
+		return errors.New("MAC mismatch: the file was changed after it was encrypted")
+	}
+	return nil
+}
+
+// sopsDecrypter walks a document tree, decrypting values and hashing them
+// into the MAC in the order SOPS does.
+type sopsDecrypter struct {
+	meta  *sopsMetadata
+	block cipher.Block
+	mac   hash.Hash
+}
+
+func (d *sopsDecrypter) walk(node *yaml.Node, path []string) error {
+	d.decryptComments(node, path)
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			d.decryptComments(node.Content[i], path)
+			if err := d.walk(node.Content[i+1], append(path[:len(path):len(path)], node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		// List items share the path of the list
+		for _, item := range node.Content {
+			if err := d.walk(item, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return d.leaf(node, path)
+	case yaml.AliasNode:
+		return fmt.Errorf("%s: YAML aliases are not supported", strings.Join(path, "."))
+	}
+	return nil
+}
+
+// leaf decrypts the scalar node at path if it is encrypted and adds it to the MAC.
+func (d *sopsDecrypter) leaf(node *yaml.Node, path []string) error {
+	encrypted := d.meta.encrypted(path)
+	if encrypted && node.Value != "" && node.ShortTag() != "!!null" {
+		value, typ, err := d.decryptValue(node.Value, strings.Join(path, ":")+":")
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+		}
+		node.Value, node.Style = value, 0
+		switch typ {
+		case "int":
+			node.Tag = "!!int"
+		case "float":
+			node.Tag = "!!float"
+		case "bool":
+			node.Tag = "!!bool"
+		default:
+			node.Tag = "!!str"
+		}
+	}
+	if encrypted || !d.meta.MACOnlyEncrypted {
+		if value, ok := sopsMACValue(node); ok {
+			d.mac.Write([]byte(value))
+		}
+	}
+	return nil
+}
+
+// decryptComments decrypts the comments SOPS encrypted on node.
+// Comments are not part of the MAC; ones that do not decrypt are kept.
+func (d *sopsDecrypter) decryptComments(node *yaml.Node, path []string) {
+	aad := strings.Join(path, ":") + ":"
+	for _, comment := range []*string{&node.HeadComment, &node.LineComment, &node.FootComment} {
+		if *comment == "" {
+			continue
+		}
+		lines := strings.Split(*comment, "\n")
+		for i, line := range lines {
+			text, ok := strings.CutPrefix(strings.TrimSpace(line), "#")
+			if !ok {
+				continue
+			}
+			if value, typ, err := d.decryptValue(strings.TrimSpace(text), aad); err == nil && typ == "comment" {
+				lines[i] = "#" + value
+			}
+		}
+		*comment = strings.Join(lines, "\n")
+	}
+}
+
+// decryptValue decrypts a value in SOPS's ENC[AES256_GCM,...] form and
+// returns it with its type.
+func (d *sopsDecrypter) decryptValue(value, aad string) (string, string, error) {
+	m := sopsValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", errors.New("value is not encrypted by SOPS")
+	}
+	var parts [3][]byte
+	for i, s := range m[1:4] {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid encrypted value: %w", err)
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+	if len(iv) == 0 {
+		return "", "", errors.New("invalid encrypted value: empty iv")
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(d.block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(aad))
+	if err != nil {
+		return "", "", errors.New("could not decrypt value: wrong data key or the value was moved or changed")
+	}
+	return string(plain), m[4], nil
+}
+
+// sopsMACValue returns how SOPS writes the scalar node into the MAC, and
+// false for nulls, which it leaves out.
+func sopsMACValue(node *yaml.Node) (string, bool) {
+	switch node.ShortTag() {
+	case "!!null":
+		return "", false
+	case "!!bool":
+		if b, err := strconv.ParseBool(strings.ToLower(node.Value)); err == nil {
+			if b {
+				return "True", true
+			}
+			return "False", true
+		}
+	case "!!int":
+		if i, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			return strconv.FormatInt(i, 10), true
+		}
+	case "!!float":
+		if f, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64), true
+		}
+	}
+	return node.Value, true
+}
+
+// flatten returns the file's decrypted scalar values keyed by their path,
+// joined with dots; list items are keyed by their index. Files with more
+// than one document have the document index in front.
+func (f *sopsFile) flatten() map[string]string {
+	values := make(map[string]string)
+	var walk func(node *yaml.Node, key string)
+	walk = func(node *yaml.Node, key string) {
+		join := func(k string) string {
+			if key == "" {
+				return k
+			}
+			return key + "." + k
+		}
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i < len(node.Content); i += 2 {
+				walk(node.Content[i+1], join(node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for i, item := range node.Content {
+				walk(item, join(strconv.Itoa(i)))
+			}
+		case yaml.ScalarNode:
+			if node.ShortTag() != "!!null" {
+				values[key] = node.Value
+			}
+		}
+	}
+	for i, doc := range f.docs {
+		prefix := ""
+		if len(f.docs) > 1 {
+			prefix = strconv.Itoa(i)
+		}
+		walk(doc.Content[0], prefix)
+	}
+	return values
+}
+
+// render returns the decrypted file without its SOPS metadata, in its format.
+func (f *sopsFile) render() ([]byte, error) {
+	var buf bytes.Buffer
+	if f.format == "json" {
+		var compact bytes.Buffer
+		if err := writeJSONNode(&compact, f.docs[0].Content[0]); err != nil {
+			return nil, err
+		}
+		if err := json.Indent(&buf, compact.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		clear(compact.Bytes())
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	}
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range f.docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeJSONNode writes node as compact JSON, keeping the order of map keys.
+func writeJSONNode(w *bytes.Buffer, node *yaml.Node) error {
+	writeString := func(s string) error {
+		b, err := json.Marshal(s)
+		w.Write(b)
+		return err
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		w.WriteByte('{')
+		for i := 0; i < len(node.Content); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeString(node.Content[i].Value); err != nil {
+				return err
+			}
+			w.WriteByte(':')
+			if err := writeJSONNode(w, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('}')
+	case yaml.SequenceNode:
+		w.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := writeJSONNode(w, item); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			w.WriteString("null")
+		case "!!bool", "!!int", "!!float":
+			value, _ := sopsMACValue(node)
+			if node.ShortTag() == "!!bool" {
+				value = strings.ToLower(value)
+			}
+			if !json.Valid([]byte(value)) {
+				return writeString(node.Value)
+			}
+			w.WriteString(value)
+		default:
+			return writeString(node.Value)
+		}
+	default:
+		return fmt.Errorf("cannot write YAML node kind %d as JSON", node.Kind)
+	}
+	return nil
+}
+
+// parseSopsAgeKeys parses the age identities in content, a gopass secret
+// as written by age-keygen.
+func parseSopsAgeKeys(content []byte) ([]age.Identity, error) {
+	ids, err := age.ParseIdentities(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("no age identity: %w", err)
+	}
+	return ids, nil
+}
+
+// parseSopsPGPKeys parses the armored private key in content and unlocks it
+// with passphrase if it is protected.
+func parseSopsPGPKeys(content, passphrase []byte) (openpgp.EntityList, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("no armored PGP key: %w", err)
+	}
+
+	hasPrivate := false
+	for _, entity := range keyring {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		hasPrivate = true
+		if !entity.PrivateKey.Encrypted && !hasEncryptedSubkey(entity) {
+			continue
+		}
+		if len(passphrase) == 0 {
+			return nil, errors.New("the PGP key is protected by a passphrase, set pgp_passphrase")
+		}
+		if err := entity.DecryptPrivateKeys(passphrase); err != nil {
+			return nil, fmt.Errorf("cannot unlock the PGP key: %w", err)
+		}
+	}
+	if !hasPrivate {
+		return nil, errors.New("the PGP key has no private key")
+	}
+	return keyring, nil
+}
+
+// hasEncryptedSubkey reports whether any private subkey of entity is locked.
+func hasEncryptedSubkey(entity *openpgp.Entity) bool {
+	for _, sub := range entity.Subkeys {
+		if sub.PrivateKey != nil && sub.PrivateKey.Encrypted {
+			return true
+		}
+	}
+	return false
+}
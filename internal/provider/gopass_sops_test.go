@@ -0,0 +1,519 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgparmor "github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"gopkg.in/yaml.v3"
+)
+
+// sopsTestOptions configures sopsEncrypt.
+type sopsTestOptions struct {
+	age              []*age.X25519Identity
+	pgp              []*openpgp.Entity
+	encryptedRegex   string
+	macOnlyEncrypted bool
+}
+
+// sopsEncrypt encrypts plain, a JSON or YAML document, the way sops does,
+// independently of the decryption code under test: values are encrypted with
+// AES-GCM under their key path, the MAC covers all values in document order
+// and the data key is encrypted for the given age and PGP keys.
+func sopsEncrypt(t *testing.T, plain, format string, opts sopsTestOptions) []byte {
+	t.Helper()
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(plain), &doc); err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(value, typ, aad string) string {
+		iv := make([]byte, 32)
+		rand.Read(iv)
+		gcm, err := cipher.NewGCMWithNonceSize(block, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sealed := gcm.Seal(nil, iv, []byte(value), []byte(aad))
+		data, tag := sealed[:len(sealed)-16], sealed[len(sealed)-16:]
+		enc := base64.StdEncoding.EncodeToString
+		return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", enc(data), enc(iv), enc(tag), typ)
+	}
+
+	mac := sha512.New()
+	var walk func(node *yaml.Node, path []string)
+	walk = func(node *yaml.Node, path []string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i < len(node.Content); i += 2 {
+				walk(node.Content[i+1], append(append([]string{}, path...), node.Content[i].Value))
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				walk(item, path)
+			}
+		case yaml.ScalarNode:
+			if node.ShortTag() == "!!null" {
+				return
+			}
+			encrypted := true
+			for _, k := range path {
+				if strings.HasSuffix(k, "_unencrypted") {
+					encrypted = false
+				}
+			}
+			if opts.encryptedRegex != "" {
+				encrypted = false
+				for _, k := range path {
+					if strings.Contains(k, opts.encryptedRegex) {
+						encrypted = true
+					}
+				}
+			}
+
+			value, typ, hashed := node.Value, "str", node.Value
+			switch node.ShortTag() {
+			case "!!int":
+				i, _ := strconv.ParseInt(node.Value, 0, 64)
+				value, typ, hashed = strconv.FormatInt(i, 10), "int", strconv.FormatInt(i, 10)
+			case "!!float":
+				f, _ := strconv.ParseFloat(node.Value, 64)
+				value, typ = strconv.FormatFloat(f, 'f', -1, 64), "float"
+				hashed = value
+			case "!!bool":
+				b, _ := strconv.ParseBool(node.Value)
+				value, typ, hashed = strconv.FormatBool(b), "bool", "False"
+				if b {
+					hashed = "True"
+				}
+			}
+			if encrypted || !opts.macOnlyEncrypted {
+				mac.Write([]byte(hashed))
+			}
+			if encrypted {
+				node.Value = encrypt(value, typ, strings.Join(path, ":")+":")
+				node.Tag, node.Style = "!!str", 0
+			}
+		}
+	}
+	root := doc.Content[0]
+	walk(root, nil)
+
+	lastModified := "2025-01-02T03:04:05Z"
+	meta := map[string]interface{}{
+		"lastmodified":       lastModified,
+		"mac":                encrypt(fmt.Sprintf("%X", mac.Sum(nil)), "str", lastModified),
+		"unencrypted_suffix": "_unencrypted",
+		"version":            "3.9.0",
+	}
+	if opts.encryptedRegex != "" {
+		delete(meta, "unencrypted_suffix")
+		meta["encrypted_regex"] = opts.encryptedRegex
+	}
+	if opts.macOnlyEncrypted {
+		meta["mac_only_encrypted"] = true
+	}
+	var ageStanzas []map[string]string
+	for _, id := range opts.age {
+		var buf bytes.Buffer
+		a := armor.NewWriter(&buf)
+		w, err := age.Encrypt(a, id.Recipient())
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(key)
+		w.Close()
+		a.Close()
+		ageStanzas = append(ageStanzas, map[string]string{"recipient": id.Recipient().String(), "enc": buf.String()})
+	}
+	if ageStanzas != nil {
+		meta["age"] = ageStanzas
+	}
+	var pgpStanzas []map[string]string
+	for _, entity := range opts.pgp {
+		var buf bytes.Buffer
+		a, err := pgparmor.Encode(&buf, "PGP MESSAGE", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := openpgp.Encrypt(a, openpgp.EntityList{entity}, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(key)
+		w.Close()
+		a.Close()
+		pgpStanzas = append(pgpStanzas, map[string]string{"fp": fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint), "enc": buf.String()})
+	}
+	if pgpStanzas != nil {
+		meta["pgp"] = pgpStanzas
+	}
+
+	var metaNode yaml.Node
+	if err := metaNode.Encode(meta); err != nil {
+		t.Fatal(err)
+	}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: sopsMetadataKey}, &metaNode)
+
+	var buf bytes.Buffer
+	if format == "json" {
+		if err := writeJSONNode(&buf, root); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	if err := yaml.NewEncoder(&buf).Encode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newAgeIdentity returns a new age identity for tests.
+func newAgeIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// newPGPKey returns a new PGP key and its armored private key, locked with
+// passphrase if it is not empty.
+func newPGPKey(t *testing.T, passphrase string) (*openpgp.Entity, []byte) {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := pgparmor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if passphrase == "" {
+		err = entity.SerializePrivate(w, nil)
+	} else {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatal(err)
+		}
+		err = entity.SerializePrivateWithoutSigning(w, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return entity, buf.Bytes()
+}
+
+// decryptSops parses and decrypts an encrypted file, failing the test on errors.
+func decryptSops(t *testing.T, content []byte, format string, keys sopsKeys) *sopsFile {
+	t.Helper()
+	file, err := parseSopsFile(content, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.decrypt(keys); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+const sopsTestYAML = `# application secrets
+database:
+  host: db.internal
+  port: 5432
+  password: "s3cr:t"
+  replicas:
+    - a.db.internal
+    - b.db.internal
+features:
+  enabled: true
+  ratio: 0.25
+  code: "0123"
+owner_unencrypted: platform
+`
+
+func TestSopsFormat(t *testing.T) {
+	tests := []struct {
+		source, format, want string
+		wantErr              bool
+	}{
+		{source: "secrets.enc.json", want: "json"},
+		{source: "secrets.yaml", want: "yaml"},
+		{source: "SECRETS.YML", want: "yaml"},
+		{source: "secrets.env", wantErr: true},
+		{source: "secrets.env", format: "yaml", want: "yaml"},
+		{source: "secrets.json", format: "ini", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := sopsFormat(tt.source, tt.format)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("sopsFormat(%q, %q) = %q, %v", tt.source, tt.format, got, err)
+		}
+	}
+}
+
+func TestSopsFile_YAML(t *testing.T) {
+	id := newAgeIdentity(t)
+	content := sopsEncrypt(t, sopsTestYAML, "yaml", sopsTestOptions{age: []*age.X25519Identity{id}})
+	if bytes.Contains(content, []byte("s3cr:t")) || !bytes.Contains(content, []byte("owner_unencrypted: platform")) {
+		t.Fatalf("test file not encrypted as expected:\n%s", content)
+	}
+
+	file := decryptSops(t, content, "yaml", sopsKeys{age: []age.Identity{id}})
+
+	want := map[string]string{
+		"database.host":       "db.internal",
+		"database.port":       "5432",
+		"database.password":   "s3cr:t",
+		"database.replicas.0": "a.db.internal",
+		"database.replicas.1": "b.db.internal",
+		"features.enabled":    "true",
+		"features.ratio":      "0.25",
+		"features.code":       "0123",
+		"owner_unencrypted":   "platform",
+	}
+	got := file.flatten()
+	if len(got) != len(want) {
+		t.Errorf("got %d values, want %d: %v", len(got), len(want), got)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+
+	raw, err := file.render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("sops:")) || bytes.Contains(raw, []byte("ENC[")) {
+		t.Errorf("raw still has SOPS data:\n%s", raw)
+	}
+	// Types survive: the string "0123" stays quoted, the port is a number
+	var decoded struct {
+		Database map[string]interface{}
+		Features map[string]interface{}
+	}
+	if err := yaml.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Features["code"] != "0123" || decoded.Database["port"] != 5432 || decoded.Features["enabled"] != true {
+		t.Errorf("raw lost value types:\n%s", raw)
+	}
+}
+
+func TestSopsFile_JSON(t *testing.T) {
+	plain := `{"api":{"token":"t0ken","retries":3,"debug":false,"scopes":["read","write"]},"note":null}`
+	id := newAgeIdentity(t)
+	content := sopsEncrypt(t, plain, "json", sopsTestOptions{age: []*age.X25519Identity{id}})
+
+	file := decryptSops(t, content, "json", sopsKeys{age: []age.Identity{id}})
+	got := file.flatten()
+	if got["api.token"] != "t0ken" || got["api.retries"] != "3" || got["api.scopes.1"] != "write" || len(got) != 5 {
+		t.Errorf("unexpected values %v", got)
+	}
+
+	raw, err := file.render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, decoded interface{}
+	json.Unmarshal([]byte(plain), &want)
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("raw is not JSON: %v\n%s", err, raw)
+	}
+	if fmt.Sprint(decoded) != fmt.Sprint(want) {
+		t.Errorf("raw = %s, want %s", raw, plain)
+	}
+	if !strings.HasPrefix(string(raw), "{\n  \"api\": {\n    \"token\"") {
+		t.Errorf("raw does not keep the key order:\n%s", raw)
+	}
+}
+
+func TestSopsFile_EncryptedRegex(t *testing.T) {
+	id := newAgeIdentity(t)
+	plain := "user: admin\npassword: hunter2\n"
+	for _, macOnlyEncrypted := range []bool{false, true} {
+		content := sopsEncrypt(t, plain, "yaml", sopsTestOptions{
+			age:              []*age.X25519Identity{id},
+			encryptedRegex:   "password",
+			macOnlyEncrypted: macOnlyEncrypted,
+		})
+		if !bytes.Contains(content, []byte("user: admin")) {
+			t.Fatalf("expected user to stay in plain text:\n%s", content)
+		}
+
+		got := decryptSops(t, content, "yaml", sopsKeys{age: []age.Identity{id}}).flatten()
+		if got["user"] != "admin" || got["password"] != "hunter2" {
+			t.Errorf("mac_only_encrypted=%v: unexpected values %v", macOnlyEncrypted, got)
+		}
+	}
+}
+
+func TestSopsFile_PGP(t *testing.T) {
+	for _, passphrase := range []string{"", "unl0ck"} {
+		entity, armored := newPGPKey(t, passphrase)
+		content := sopsEncrypt(t, "token: t0ken\n", "yaml", sopsTestOptions{pgp: []*openpgp.Entity{entity}})
+
+		keyring, err := parseSopsPGPKeys(armored, []byte(passphrase))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := decryptSops(t, content, "yaml", sopsKeys{pgp: keyring}).flatten()
+		if got["token"] != "t0ken" {
+			t.Errorf("passphrase %q: unexpected values %v", passphrase, got)
+		}
+	}
+}
+
+func TestParseSopsPGPKeys_Errors(t *testing.T) {
+	entity, locked := newPGPKey(t, "unl0ck")
+
+	if _, err := parseSopsPGPKeys(locked, nil); err == nil || !strings.Contains(err.Error(), "set pgp_passphrase") {
+		t.Errorf("expected a passphrase error, got %v", err)
+	}
+	if _, err := parseSopsPGPKeys(locked, []byte("wrong")); err == nil || !strings.Contains(err.Error(), "cannot unlock") {
+		t.Errorf("expected an unlock error, got %v", err)
+	}
+
+	var public bytes.Buffer
+	w, _ := pgparmor.Encode(&public, openpgp.PublicKeyType, nil)
+	entity.Serialize(w)
+	w.Close()
+	if _, err := parseSopsPGPKeys(public.Bytes(), nil); err == nil || !strings.Contains(err.Error(), "no private key") {
+		t.Errorf("expected a missing private key error, got %v", err)
+	}
+
+	if _, err := parseSopsPGPKeys([]byte("not a key"), nil); err == nil {
+		t.Error("expected an error for a secret without a key")
+	}
+}
+
+func TestParseSopsAgeKeys(t *testing.T) {
+	id := newAgeIdentity(t)
+	ids, err := parseSopsAgeKeys([]byte("# created: 2025-01-02T03:04:05Z\n# public key: " +
+		id.Recipient().String() + "\n" + id.String() + "\n"))
+	if err != nil || len(ids) != 1 {
+		t.Errorf("expected one identity, got %d: %v", len(ids), err)
+	}
+	if _, err := parseSopsAgeKeys([]byte("s3cret\n")); err == nil {
+		t.Error("expected an error for a secret without an identity")
+	}
+}
+
+func TestSopsFile_Errors(t *testing.T) {
+	id := newAgeIdentity(t)
+	keys := sopsKeys{age: []age.Identity{id}}
+	content := string(sopsEncrypt(t, "a: one\nb: two\nc_unencrypted: three\n", "yaml", sopsTestOptions{age: []*age.X25519Identity{id}}))
+
+	var doc map[string]interface{}
+	yaml.Unmarshal([]byte(content), &doc)
+	swapped := strings.NewReplacer(doc["a"].(string), doc["b"].(string), doc["b"].(string), doc["a"].(string)).Replace(content)
+
+	tests := []struct {
+		name    string
+		content string
+		keys    sopsKeys
+		wantErr string
+	}{
+		{
+			name:    "changed plain text value",
+			content: strings.Replace(content, "c_unencrypted: three", "c_unencrypted: four", 1),
+			keys:    keys,
+			wantErr: "MAC mismatch",
+		},
+		{
+			name:    "swapped values",
+			content: swapped,
+			keys:    keys,
+			wantErr: "a: could not decrypt value",
+		},
+		{
+			name:    "wrong age identity",
+			content: content,
+			keys:    sopsKeys{age: []age.Identity{newAgeIdentity(t)}},
+			wantErr: "no given key decrypts the file",
+		},
+		{
+			name:    "no age recipients for a PGP key",
+			content: content,
+			keys:    sopsKeys{pgp: openpgp.EntityList{&openpgp.Entity{}}},
+			wantErr: "none of the given kinds of keys",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parseSopsFile([]byte(tt.content), "yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := file.decrypt(tt.keys); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseSopsFile_Errors(t *testing.T) {
+	tests := []struct {
+		name, content, format, wantErr string
+	}{
+		{name: "not a SOPS file", content: "a: 1\n", format: "yaml", wantErr: `no "sops" key`},
+		{name: "not a map", content: "- a\n", format: "yaml", wantErr: "not a map"},
+		{name: "invalid json", content: `{"a":`, format: "json", wantErr: "invalid json"},
+		{name: "key groups", content: "a: 1\nsops:\n  mac: x\n  key_groups:\n    - age: []\n", format: "yaml", wantErr: "key groups"},
+		{name: "no MAC", content: "a: 1\nsops:\n  lastmodified: x\n", format: "yaml", wantErr: "no MAC"},
+		{name: "invalid regex", content: "a: 1\nsops:\n  mac: x\n  encrypted_regex: \"(\"\n", format: "yaml", wantErr: "encrypted_regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseSopsFile([]byte(tt.content), tt.format); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSopsMetadata_Encrypted(t *testing.T) {
+	tests := []struct {
+		name string
+		meta sopsMetadata
+		path []string
+		want bool
+	}{
+		{name: "default", meta: sopsMetadata{UnencryptedSuffix: "_unencrypted"}, path: []string{"db", "password"}, want: true},
+		{name: "unencrypted suffix on parent", meta: sopsMetadata{UnencryptedSuffix: "_unencrypted"}, path: []string{"db_unencrypted", "host"}, want: false},
+		{name: "encrypted suffix", meta: sopsMetadata{EncryptedSuffix: "_secret"}, path: []string{"token_secret"}, want: true},
+		{name: "no encrypted suffix", meta: sopsMetadata{EncryptedSuffix: "_secret"}, path: []string{"host"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.meta.encrypted(tt.path); got != tt.want {
+			t.Errorf("%s: encrypted(%v) = %v, want %v", tt.name, tt.path, got, tt.want)
+		}
+	}
+}
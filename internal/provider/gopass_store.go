@@ -0,0 +1,226 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gopasspw/gopass/pkg/appdir"
+	"github.com/gopasspw/gopass/pkg/gitconfig"
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+)
+
+// SecretStore is the storage backend behind GopassClient.
+//
+// It is the subset of gopass.Store the provider actually uses, so any
+// gopass.Store (including the store returned by api.New) satisfies it as is.
+// Alternative backends only need to implement these five methods; optional
+// capabilities such as incremental listing (secretWalker) or Close are
+// discovered through type assertions. Get and Remove report missing secrets
+// with an error wrapping ErrNotFound; libraryStore does so for the library.
+type SecretStore interface {
+	// Get returns the given revision of a secret; "latest" selects the current one.
+	Get(ctx context.Context, name, revision string) (gopass.Secret, error)
+	// List returns the paths of all secrets in the store.
+	List(ctx context.Context) ([]string, error)
+	// Set creates or overwrites a secret.
+	Set(ctx context.Context, name string, sec gopass.Byter) error
+	// Remove deletes a secret.
+	Remove(ctx context.Context, name string) error
+	// Revisions returns the revision identifiers of a secret, newest first.
+	Revisions(ctx context.Context, name string) ([]string, error)
+}
+
+// Ensure the gopass library store satisfies SecretStore.
+var _ SecretStore = gopass.Store(nil)
+
+// openGopassStore opens the default backend: the gopass library store
+// configured from the user's gopass configuration.
+func openGopassStore(ctx context.Context) (SecretStore, error) {
+	store, err := api.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dir := os.Getenv("PASSWORD_STORE_DIR")
+	return &libraryStore{
+		Store:    store,
+		dir:      dir,
+		notFound: libraryNotFound(ctx, store),
+		batch:    newCommitBatch(dir, loadLibraryConfig()),
+	}, nil
+}
+
+// loadLibraryConfig loads the gopass configuration the library reads, nil if
+// there is none.
+func loadLibraryConfig() *gitconfig.Config {
+	cfg, err := gitconfig.LoadConfig(filepath.Join(appdir.UserConfig(), "config"))
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// libraryNotFoundProbe is a secret name no store can hold: the NUL byte is
+// invalid in file names, so reading it fails before anything is decrypted.
+const libraryNotFoundProbe = "\x00"
+
+// libraryNotFound returns the error the gopass library reports for secrets
+// that do not exist. The library returns its internal store.ErrNotFound as
+// is, but does not export it, so it is taken from a read that cannot succeed.
+func libraryNotFound(ctx context.Context, store gopass.Store) error {
+	_, err := store.Get(ctx, libraryNotFoundProbe, "latest")
+	return err
+}
+
+// libraryStore is the gopass library store with working revision reads. The
+// library ignores the revision of Get and returns the latest one (gopass
+// v1.15), so older revisions are read from the git history of the store
+// directory instead: gpg entries are decrypted like pass does. The library
+// cannot decrypt age entries outside of its own store, so their revisions
+// are refused; age_identities_file reads them with the age library.
+type libraryStore struct {
+	gopass.Store
+	// dir is the store directory, "" if it comes from the gopass configuration
+	dir string
+	// notFound is the library's error for missing secrets, see libraryNotFound
+	notFound error
+	// batch collects writes to commit on Close, nil to commit each write
+	batch *commitBatch
+}
+
+// wrapNotFound maps the library's error for the missing secret name to
+// ErrNotFound.
+func (s *libraryStore) wrapNotFound(err error, name string) error {
+	if err != nil && s.notFound != nil && errors.Is(err, s.notFound) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return err
+}
+
+// Get returns the given revision of a secret.
+func (s *libraryStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	if revision == "latest" || revision == "" {
+		secret, err := s.Store.Get(ctx, name, revision)
+		return secret, s.wrapNotFound(err, name)
+	}
+	if s.dir == "" {
+		return nil, fmt.Errorf("reading revisions needs the store directory, which comes from the gopass " +
+			"configuration; set store_path")
+	}
+	if backend := storeBackend(s.dir); backend != "gpg" {
+		return nil, fmt.Errorf("the gopass library cannot read revisions of %s stores; set age_identities_file "+
+			"to read age stores with the age library instead", backend)
+	}
+	pass, err := NewPassStore(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	return pass.Get(ctx, name, revision)
+}
+
+// Set creates or overwrites a secret, leaving the commit to Close if writes
+// are batched.
+func (s *libraryStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	ctx, held := s.batch.hold(ctx, name)
+	if err := s.Store.Set(ctx, name, sec); err != nil {
+		return err
+	}
+	if held {
+		s.batch.add(name)
+	}
+	return nil
+}
+
+// Remove deletes a secret, leaving the commit to Close if writes are batched.
+func (s *libraryStore) Remove(ctx context.Context, name string) error {
+	ctx, held := s.batch.hold(ctx, name)
+	if err := s.Store.Remove(ctx, name); err != nil {
+		return s.wrapNotFound(err, name)
+	}
+	if held {
+		s.batch.add(name)
+	}
+	return nil
+}
+
+// Close commits the batched writes and closes the store.
+func (s *libraryStore) Close(ctx context.Context) error {
+	return errors.Join(s.batch.commit(ctx), s.Store.Close(ctx))
+}
+
+// storeDirMu serializes opening gopass stores, which select their directory
+// through the process-wide PASSWORD_STORE_DIR environment variable.
+var storeDirMu sync.Mutex
+
+// addStoreMount mounts the gopass store in dir below prefix.
+func (c *GopassClient) addStoreMount(prefix, dir string) {
+	c.addMount(prefix, c.gopassStoreAt(dir)).dir = dir
+}
+
+// gopassStoreAt returns an opener for the gopass store in dir, used for mounts.
+// PASSWORD_STORE_DIR is pointed at dir only while the store is being opened.
+func (c *GopassClient) gopassStoreAt(dir string) func(ctx context.Context) (SecretStore, error) {
+	return func(ctx context.Context) (SecretStore, error) {
+		if c.wsl != nil {
+			return &cliStore{command: c.wsl.command(dir)}, nil
+		}
+		expanded, err := c.expandHome(dir)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(expanded); err != nil {
+			return nil, fmt.Errorf("mounted store directory: %w", err)
+		}
+
+		storeDirMu.Lock()
+		defer storeDirMu.Unlock()
+
+		if err := c.gnupg.setup(ctx); err != nil {
+			return nil, err
+		}
+		if c.nonInteractive {
+			setNonInteractiveEnv()
+		}
+		if c.cli {
+			env, err := c.cliEnv(expanded)
+			if err != nil {
+				return nil, err
+			}
+			return &cliStore{command: []string{gopassBinary}, env: env}, nil
+		}
+		switch {
+		case c.storeFormat == storeFormatPassage, c.ageStores:
+			return c.passageStoreAt(expanded)
+		case c.storeFormat == storeFormatPass:
+			return NewPassStore(expanded)
+		}
+
+		previous, wasSet := os.LookupEnv("PASSWORD_STORE_DIR")
+		defer func() {
+			if wasSet {
+				os.Setenv("PASSWORD_STORE_DIR", previous)
+			} else {
+				os.Unsetenv("PASSWORD_STORE_DIR")
+			}
+		}()
+		os.Setenv("PASSWORD_STORE_DIR", expanded)
+
+		return c.newStore(ctx)
+	}
+}
+
+// NewGopassClientWithStore creates a client backed by an already opened store
+// instead of the gopass library. The client takes ownership of store and
+// closes it on Close if it implements Close(context.Context) error.
+func NewGopassClientWithStore(store SecretStore) *GopassClient {
+	client := NewGopassClient("")
+	client.newStore = func(ctx context.Context) (SecretStore, error) { return store, nil }
+	return client
+}
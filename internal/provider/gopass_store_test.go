@@ -0,0 +1,253 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/gopasspw/gopass/pkg/gopass"
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+// mapStore is a minimal SecretStore that implements nothing beyond the
+// interface, like a third-party backend would
+type mapStore struct {
+	mu      sync.Mutex
+	secrets map[string][]byte
+}
+
+func (m *mapStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.secrets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return secrets.ParseAKV(data), nil
+}
+
+func (m *mapStore) List(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.secrets))
+	for name := range m.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m *mapStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[name] = sec.Bytes()
+	return nil
+}
+
+func (m *mapStore) Remove(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.secrets, name)
+	return nil
+}
+
+func (m *mapStore) Revisions(ctx context.Context, name string) ([]string, error) {
+	return []string{"latest"}, nil
+}
+
+func TestNewGopassClientWithStore(t *testing.T) {
+	ctx := context.Background()
+	store := &mapStore{secrets: map[string][]byte{
+		"app/env/USER": []byte("admin\n"),
+	}}
+	client := NewGopassClientWithStore(store)
+	defer client.Close(ctx)
+
+	if err := client.SetSecret(ctx, "app/env/PASSWORD", "s3cret"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+
+	values, err := client.GetEnvSecrets(ctx, "app/env")
+	if err != nil {
+		t.Fatalf("GetEnvSecrets() error = %v", err)
+	}
+	if values["USER"] != "admin" || values["PASSWORD"] != "s3cret" {
+		t.Errorf("unexpected values %v", values)
+	}
+
+	count, err := client.GetRevisionCount(ctx, "app/env/USER")
+	if err != nil {
+		t.Fatalf("GetRevisionCount() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 revision, got %d", count)
+	}
+
+	if err := client.RemoveSecret(ctx, "app/env/USER"); err != nil {
+		t.Fatalf("RemoveSecret() error = %v", err)
+	}
+	exists, err := client.SecretExists(ctx, "app/env/USER")
+	if err != nil {
+		t.Fatalf("SecretExists() error = %v", err)
+	}
+	if exists {
+		t.Error("expected removed secret not to exist")
+	}
+}
+
+func TestNewGopassClientWithStore_CloseWithoutCloser(t *testing.T) {
+	ctx := context.Background()
+	client := NewGopassClientWithStore(&mapStore{secrets: map[string][]byte{}})
+
+	if _, err := client.ListSecrets(ctx, ""); err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+
+	// Backends without Close must be accepted
+	client.Close(ctx)
+	if client.store != nil {
+		t.Error("expected store handle to be released")
+	}
+}
+
+func TestNewGopassClientWithStore_ClosesStore(t *testing.T) {
+	ctx := context.Background()
+	store := &mockClosingStore{SecretStore: NewMemoryStore(nil)}
+	client := NewGopassClientWithStore(store)
+
+	if _, err := client.ListSecrets(ctx, ""); err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	client.Close(ctx)
+
+	if store.closed.Load() != 1 {
+		t.Errorf("expected backend to be closed once, got %d", store.closed.Load())
+	}
+}
+
+// initGitStore turns the store in dir into a git repository, with an identity
+// for the commits gopass makes on writes.
+func initGitStore(t *testing.T, dir string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	runGit(t, dir, nil, "init", "--quiet")
+	runGit(t, dir, nil, "config", "user.name", "test")
+	runGit(t, dir, nil, "config", "user.email", "test@example.com")
+}
+
+// commitStore commits every file of the store in dir and returns the commit
+// hash: a new commit, or the one gopass made when writing.
+func commitStore(t *testing.T, dir, message string) string {
+	t.Helper()
+	runGit(t, dir, nil, "add", "--all")
+	if err := exec.Command("git", "-C", dir, "diff", "--cached", "--quiet").Run(); err != nil {
+		runGit(t, dir, nil, "commit", "--quiet", "--no-gpg-sign", "-m", message)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse: %v", err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestLibraryStore_GetRevision_GPG(t *testing.T) {
+	store, pinentry := gopasstest.NewGPG(t, pinentryTestPassphrase, map[string]string{"app/db": "first"})
+	initGitStore(t, store.Dir)
+	first := commitStore(t, store.Dir, "Add app/db")
+	store.Set("app/db", "second")
+	commitStore(t, store.Dir, "Rotate app/db")
+
+	client := NewGopassClient(store.Dir)
+	t.Cleanup(func() { client.Close(context.Background()) })
+	ctx := context.Background()
+
+	pinentry.Script(gopasstest.PIN(pinentryTestPassphrase))
+	if value, err := client.GetSecret(ctx, "app/db"); err != nil || value != "second" {
+		t.Fatalf("expected the latest revision, got %q (%v)", value, err)
+	}
+	value, _, err := client.GetSecretAtRevision(ctx, "app/db", first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "first" {
+		t.Errorf("expected the first revision, got %q", value)
+	}
+}
+
+func TestLibraryStore_GetRevision_AgeRefused(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "first"})
+	initGitStore(t, store.Dir)
+	first := commitStore(t, store.Dir, "Add app/db")
+	store.Set("app/db", "second")
+	commitStore(t, store.Dir, "Rotate app/db")
+
+	client := NewGopassClient(store.Dir)
+	t.Cleanup(func() { client.Close(context.Background()) })
+
+	value, _, err := client.GetSecretAtRevision(context.Background(), "app/db", first)
+	if err == nil {
+		t.Fatalf("expected revisions of age stores to be refused, got %q", value)
+	}
+	if !strings.Contains(err.Error(), "age_identities_file") {
+		t.Errorf("expected a hint at age_identities_file, got %v", err)
+	}
+}
+
+// libraryTestStore fails like the gopass library: with the same unexported
+// error value for every missing secret.
+type libraryTestStore struct {
+	gopass.Store
+	notFound error
+}
+
+func (s *libraryTestStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	if name == "locked" {
+		return nil, errors.New("failed to decrypt: entry is not in the password store")
+	}
+	return nil, s.notFound
+}
+
+func (s *libraryTestStore) Remove(ctx context.Context, name string) error {
+	return s.notFound
+}
+
+func (s *libraryTestStore) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestLibraryStore_NotFound(t *testing.T) {
+	ctx := context.Background()
+	lib := &libraryTestStore{notFound: errors.New("entry is not in the password store")}
+	store := &libraryStore{Store: lib, notFound: libraryNotFound(ctx, lib)}
+
+	if _, err := store.Get(ctx, "missing", "latest"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.Remove(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	// Only the library's error value counts, not its text
+	if _, err := store.Get(ctx, "locked", "latest"); isNotFound(err) {
+		t.Errorf("expected a failure other than not found, got %v", err)
+	}
+
+	client := NewGopassClientWithStore(store)
+	defer client.Close(ctx)
+	exists, err := client.SecretExists(ctx, "missing")
+	if err != nil || exists {
+		t.Errorf("expected a missing secret without error, got %v (%v)", exists, err)
+	}
+}
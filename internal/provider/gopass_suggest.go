@@ -0,0 +1,153 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// maxSuggestions is the number of close matches offered for a missing secret.
+const maxSuggestions = 3
+
+// readError wraps a failed read of path. If the secret does not exist,
+// close matches from the store listing are appended as suggestions.
+func (c *GopassClient) readError(ctx context.Context, store SecretStore, path string, err error) error {
+	if !isNotFound(err) {
+		return fmt.Errorf("failed to get secret %q: %w", path, err)
+	}
+
+	entries, listErr := c.cachedList(ctx, store)
+	if listErr != nil {
+		tflog.Debug(ctx, "Could not list secrets for suggestions", map[string]interface{}{
+			"error": c.logError(listErr),
+		})
+		return fmt.Errorf("failed to get secret %q: %w", path, err)
+	}
+
+	// Don't point a resource at secrets outside its policy
+	if c.policies.enabled() || policyFrom(ctx) != "" {
+		permitted := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if c.permitted(ctx, entry) {
+				permitted = append(permitted, entry)
+			}
+		}
+		entries = permitted
+	}
+
+	suggestions := suggestPaths(path, entries, maxSuggestions)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("failed to get secret %q: %w", path, err)
+	}
+	return fmt.Errorf("failed to get secret %q: %w\n\nDid you mean:\n  - %s",
+		path, err, strings.Join(suggestions, "\n  - "))
+}
+
+// suggestPaths returns up to limit entries that look like likely intended
+// spellings of path: the same path in different case, with segments in a
+// different order or with singular/plural segment names, or within a small
+// edit distance. Closer matches come first.
+func suggestPaths(path string, entries []string, limit int) []string {
+	type candidate struct {
+		entry string
+		score int
+	}
+
+	want := strings.ToLower(strings.Trim(path, "/"))
+	wantNorm := normalizeSegments(want)
+	wantSorted := sortedCopy(wantNorm)
+	maxDistance := max(2, len(want)/5)
+
+	var candidates []candidate
+	for _, entry := range entries {
+		if entry == path {
+			continue
+		}
+
+		got := strings.ToLower(entry)
+		gotNorm := normalizeSegments(got)
+		switch {
+		case slices.Equal(gotNorm, wantNorm):
+			// Differs only in case or singular/plural
+			candidates = append(candidates, candidate{entry, 0})
+		case len(gotNorm) == len(wantNorm) && slices.Equal(sortedCopy(gotNorm), wantSorted):
+			// Same segments in a different order
+			candidates = append(candidates, candidate{entry, 1})
+		default:
+			if d := levenshtein(want, got, maxDistance); d <= maxDistance {
+				candidates = append(candidates, candidate{entry, 1 + d})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		return candidates[i].entry < candidates[j].entry
+	})
+
+	suggestions := make([]string, 0, min(limit, len(candidates)))
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		suggestions = append(suggestions, c.entry)
+	}
+	return suggestions
+}
+
+// normalizeSegments splits a lower-cased path into segments with a trailing
+// plural "s" removed, so "databases/prod" and "database/prod" compare equal.
+func normalizeSegments(path string) []string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if len(s) > 3 && strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss") {
+			segments[i] = s[:len(s)-1]
+		}
+	}
+	return segments
+}
+
+// sortedCopy returns a sorted copy of s.
+func sortedCopy(s []string) []string {
+	sorted := append([]string(nil), s...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// levenshtein returns the edit distance between a and b, or a value larger
+// than limit as soon as the distance is known to exceed it.
+func levenshtein(a, b string, limit int) int {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gopasspw/gopass/pkg/gopass/secrets"
+)
+
+func TestSuggestPaths(t *testing.T) {
+	entries := []string{
+		"infra/databases/prod/password",
+		"infra/Prod/api_key",
+		"app/github/token",
+		"app/gitlab/token",
+		"personal/email",
+	}
+
+	testCases := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{name: "case", path: "infra/prod/api_key", want: []string{"infra/Prod/api_key"}},
+		{name: "plural", path: "infra/database/prod/password", want: []string{"infra/databases/prod/password"}},
+		{name: "transposed", path: "infra/prod/databases/password", want: []string{"infra/databases/prod/password"}},
+		{name: "typo", path: "app/githb/token", want: []string{"app/github/token", "app/gitlab/token"}},
+		{name: "nothing close", path: "legacy/ftp/password", want: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := suggestPaths(tc.path, entries, maxSuggestions)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("suggestPaths(%q) = %v, want %v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSuggestPaths_Limit(t *testing.T) {
+	entries := []string{"app/key1", "app/key2", "app/key3", "app/key4", "app/key5"}
+
+	got := suggestPaths("app/key", entries, maxSuggestions)
+	if len(got) != maxSuggestions {
+		t.Errorf("expected %d suggestions, got %v", maxSuggestions, got)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "abd", 1},
+		{"github", "githb", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tc := range testCases {
+		if got := levenshtein(tc.a, tc.b, 10); got != tc.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+
+	if got := levenshtein("kitten", "sitting", 1); got != 2 {
+		t.Errorf("expected early exit above the limit, got %d", got)
+	}
+}
+
+func TestGopassClient_GetSecret_DidYouMean(t *testing.T) {
+	client := NewGopassClient("")
+	store := newMockStore()
+	secret := secrets.New()
+	secret.SetPassword("s3cret")
+	store.secrets["infra/Prod/api_key"] = secret
+	client.store = store
+
+	_, err := client.GetSecret(context.Background(), "infra/prod/api_key")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Did you mean:\n  - infra/Prod/api_key") {
+		t.Errorf("expected suggestion in error, got %q", err.Error())
+	}
+}
+
+func TestGopassClient_GetSecret_NoSuggestionForOtherErrors(t *testing.T) {
+	client := NewGopassClient("")
+	store := newMockStore()
+	store.shouldFail = true
+	store.failMsg = "gpg: decryption failed: No secret key"
+	client.store = store
+
+	_, err := client.GetSecret(context.Background(), "infra/prod/api_key")
+	if err == nil || strings.Contains(err.Error(), "Did you mean") {
+		t.Errorf("expected plain decryption error, got %v", err)
+	}
+}
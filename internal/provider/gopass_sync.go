@@ -0,0 +1,196 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// defaultSyncTimeout bounds a git sync. A pull over SSH to an unreachable host
+// can otherwise hang until the TCP connect times out.
+const defaultSyncTimeout = 1 * time.Minute
+
+// What to do when the git remote cannot be reached.
+const (
+	syncFailureWarn  = "warn"
+	syncFailureError = "error"
+)
+
+// storeSyncer is implemented by stores that can pull from and push to their
+// git remotes, like the gopass API store.
+type storeSyncer interface {
+	Sync(ctx context.Context) error
+}
+
+// gitSync configures syncing stores with their git remotes when they are opened.
+type gitSync struct {
+	enabled     bool
+	failOnError bool // fail the operation instead of warning and using local contents
+}
+
+// gitPullPush pulls the current branch of the git repository at dir from its
+// upstream, rebasing local commits, and pushes them. A repository without
+// remotes has nothing to sync. Injectable for testing.
+var gitPullPush = func(ctx context.Context, dir string) error {
+	remotes, err := exec.CommandContext(ctx, "git", "-C", dir, "remote").Output()
+	if err != nil {
+		return fmt.Errorf("git remote: %w", err)
+	}
+	if strings.TrimSpace(string(remotes)) == "" {
+		tflog.Debug(ctx, "Store has no git remotes, nothing to sync", map[string]interface{}{
+			"dir": dir,
+		})
+		return nil
+	}
+	for _, args := range [][]string{
+		{"-C", dir, "pull", "--quiet", "--rebase"},
+		{"-C", dir, "push", "--quiet"},
+	} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %w: %s", args[2], err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// isGitRepo reports whether dir is the root of a git repository.
+func isGitRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// syncStore syncs a freshly opened store with its git remotes: through the
+// store if it can sync itself, otherwise with git in dir, the store
+// directory ("" if unknown). The gopass library store cannot sync (gopass
+// v1.15), so it is synced with git.
+//
+// A failed sync is usually a network blip or a VPN that is not up. Unless
+// configured otherwise, the store is used with its local contents and a
+// warning is queued for the next diagnostics. So is a store that cannot be
+// synced at all.
+func (c *GopassClient) syncStore(ctx context.Context, name, dir string, store SecretStore) error {
+	if !c.sync.enabled {
+		return nil
+	}
+	if c.readOnly {
+		// Sync pushes as well as pulls, and the API offers no pull-only variant
+		c.warnings.addOnce("readonly-sync", "Git sync skipped",
+			"git_sync is ignored because the provider is configured read-only: a sync also pushes to "+
+				"the remotes. Secrets are read from the local store contents, which may be out of date.")
+		return nil
+	}
+
+	op := operation{kind: opSync, desc: fmt.Sprintf("git sync of %s", name), timeout: c.timeouts.Sync}
+	err := api.ErrNotImplemented
+	if syncer, ok := store.(storeSyncer); ok {
+		_, err = call(ctx, c, op, func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, syncer.Sync(ctx)
+		})
+	}
+	if errors.Is(err, api.ErrNotImplemented) {
+		if dir == "" || !isGitRepo(dir) {
+			return c.syncUnavailable(ctx, name, dir)
+		}
+		_, err = call(ctx, c, op, func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, gitPullPush(ctx, dir)
+		})
+	}
+	if err == nil {
+		return nil
+	}
+
+	if c.sync.failOnError {
+		return fmt.Errorf("%s failed: %w\n\n"+
+			"Set git_sync_failure = \"warn\" in the provider configuration to continue "+
+			"with the local store contents when the remote is unreachable", op.desc, err)
+	}
+
+	tflog.Warn(ctx, "Git sync failed, using local store contents", map[string]interface{}{
+		"store": name,
+		"error": c.logError(err),
+	})
+	c.warnings.add("Git sync failed",
+		fmt.Sprintf("The %s failed, so secrets are read from the local store contents, "+
+			"which may be out of date: %s", op.desc, err))
+	return nil
+}
+
+// syncUnavailable reports a store git_sync cannot sync: one that cannot sync
+// itself and whose directory is unknown or not a git repository.
+func (c *GopassClient) syncUnavailable(ctx context.Context, name, dir string) error {
+	reason := "its directory comes from the gopass configuration; set store_path"
+	if dir != "" {
+		reason = fmt.Sprintf("%s is not a git repository", dir)
+	}
+	if c.sync.failOnError {
+		return fmt.Errorf("git_sync cannot sync %s: %s", name, reason)
+	}
+	tflog.Warn(ctx, "Store cannot be synced, using local store contents", map[string]interface{}{
+		"store":  name,
+		"reason": reason,
+	})
+	c.warnings.addOnce("sync-unavailable:"+name, "Git sync unavailable",
+		fmt.Sprintf("git_sync cannot sync %s: %s. Secrets are read from the local store contents, "+
+			"which may be out of date.", name, reason))
+	return nil
+}
+
+// warningQueue collects warnings raised inside the client that are not tied
+// to a single operation's result, until a resource reports them.
+type warningQueue struct {
+	mu    sync.Mutex
+	diags diag.Diagnostics
+	seen  map[string]bool // keys passed to addOnce
+}
+
+// add queues a warning.
+func (q *warningQueue) add(summary, detail string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.diags.AddWarning(summary, detail)
+}
+
+// addOnce queues a warning unless one with the same key was queued before,
+// for warnings about a secret that may be read many times in a run.
+func (q *warningQueue) addOnce(key, summary, detail string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.seen[key] {
+		return
+	}
+	if q.seen == nil {
+		q.seen = make(map[string]bool)
+	}
+	q.seen[key] = true
+	q.diags.AddWarning(summary, detail)
+}
+
+// takeWarnings returns the queued warnings and clears the queue, so that each
+// warning is reported by exactly one resource. It is safe on a nil client.
+func (c *GopassClient) takeWarnings() diag.Diagnostics {
+	if c == nil {
+		return nil
+	}
+
+	c.warnings.mu.Lock()
+	defer c.warnings.mu.Unlock()
+
+	diags := c.warnings.diags
+	c.warnings.diags = nil
+	return diags
+}
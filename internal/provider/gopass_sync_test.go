@@ -0,0 +1,182 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/gopasspw/gopass/pkg/gopass/api"
+)
+
+// mockSyncStore records sync calls and fails them with err, if set.
+type mockSyncStore struct {
+	*mockClosingStore
+	err   error
+	syncs atomic.Int32
+}
+
+func (m *mockSyncStore) Sync(ctx context.Context) error {
+	m.syncs.Add(1)
+	return m.err
+}
+
+func newSyncTestClient(syncErr error) (*GopassClient, *mockSyncStore) {
+	store := &mockSyncStore{
+		mockClosingStore: &mockClosingStore{SecretStore: NewMemoryStore(map[string]string{"app/db": "s3cret"})},
+		err:              syncErr,
+	}
+
+	client := NewGopassClientWithStore(store)
+	client.retry.MaxAttempts = 1
+	client.sync.enabled = true
+	return client, store
+}
+
+func TestSyncStore_Disabled(t *testing.T) {
+	client, store := newSyncTestClient(nil)
+	client.sync.enabled = false
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := store.syncs.Load(); n != 0 {
+		t.Errorf("expected no sync when disabled, got %d", n)
+	}
+}
+
+func TestSyncStore_SyncsOnceOnOpen(t *testing.T) {
+	client, store := newSyncTestClient(nil)
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := client.GetSecret(ctx, "app/db"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := store.syncs.Load(); n != 1 {
+		t.Errorf("expected one sync, got %d", n)
+	}
+	if diags := client.takeWarnings(); len(diags) != 0 {
+		t.Errorf("expected no warnings, got %v", diags)
+	}
+}
+
+func TestSyncStore_UnreachableRemoteWarns(t *testing.T) {
+	client, _ := newSyncTestClient(errors.New("ssh: connect to host git.example.com port 22: Network is unreachable"))
+
+	value, err := client.GetSecret(context.Background(), "app/db")
+	if err != nil {
+		t.Fatalf("expected read from local contents, got %v", err)
+	}
+	if value != "s3cret" {
+		t.Errorf("expected local value, got %q", value)
+	}
+
+	diags := client.takeWarnings()
+	if len(diags) != 1 || diags[0].Summary() != "Git sync failed" {
+		t.Fatalf("expected one git sync warning, got %v", diags)
+	}
+	if !strings.Contains(diags[0].Detail(), "Network is unreachable") {
+		t.Errorf("expected sync error in warning, got %q", diags[0].Detail())
+	}
+
+	// Each warning is reported once
+	if diags := client.takeWarnings(); len(diags) != 0 {
+		t.Errorf("expected warnings to be drained, got %v", diags)
+	}
+}
+
+func TestSyncStore_UnreachableRemoteFails(t *testing.T) {
+	client, store := newSyncTestClient(errors.New("Network is unreachable"))
+	client.sync.failOnError = true
+
+	_, err := client.GetSecret(context.Background(), "app/db")
+	if err == nil {
+		t.Fatal("expected sync failure to fail the read")
+	}
+	if !strings.Contains(err.Error(), "git_sync_failure") {
+		t.Errorf("expected hint about git_sync_failure, got %v", err)
+	}
+	if client.store != nil {
+		t.Error("expected store to stay uninitialized")
+	}
+	if n := store.closed.Load(); n != 1 {
+		t.Errorf("expected the opened handle to be closed, got %d closes", n)
+	}
+}
+
+func TestSyncStore_NotImplemented(t *testing.T) {
+	client, _ := newSyncTestClient(api.ErrNotImplemented)
+
+	if _, err := client.GetSecret(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diags := client.takeWarnings(); len(diags) != 1 || diags[0].Summary() != "Git sync unavailable" {
+		t.Errorf("expected a warning for a store that cannot be synced, got %v", diags)
+	}
+}
+
+func TestSyncStore_NotImplementedFails(t *testing.T) {
+	client, _ := newSyncTestClient(api.ErrNotImplemented)
+	client.sync.failOnError = true
+
+	_, err := client.GetSecret(context.Background(), "app/db")
+	if err == nil || !strings.Contains(err.Error(), "git_sync cannot sync the password store") {
+		t.Fatalf("expected an error for a store that cannot be synced, got %v", err)
+	}
+}
+
+func TestSyncStore_GitFallback(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "first"})
+	initGitStore(t, store.Dir)
+	commitStore(t, store.Dir, "Add app/db")
+
+	remote := t.TempDir()
+	runGit(t, remote, nil, "init", "--quiet", "--bare")
+	runGit(t, store.Dir, nil, "remote", "add", "origin", remote)
+	runGit(t, store.Dir, nil, "push", "--quiet", "--set-upstream", "origin", "HEAD")
+
+	// The remote is one commit ahead, the local store has a commit of its own
+	store.Set("app/db", "second")
+	commitStore(t, store.Dir, "Rotate app/db")
+	runGit(t, store.Dir, nil, "push", "--quiet")
+	runGit(t, store.Dir, nil, "reset", "--quiet", "--hard", "HEAD~1")
+	if err := os.WriteFile(filepath.Join(store.Dir, "notes.txt"), []byte("local"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	commitStore(t, store.Dir, "Add notes")
+
+	client := NewGopassClient(store.Dir)
+	client.sync.enabled = true
+	t.Cleanup(func() { client.Close(context.Background()) })
+
+	value, err := client.GetSecret(context.Background(), "app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "second" {
+		t.Errorf("expected the pulled value, got %q", value)
+	}
+	if diags := client.takeWarnings(); len(diags) != 0 {
+		t.Errorf("unexpected warnings %v", diags)
+	}
+	if err := exec.Command("git", "--git-dir", remote, "cat-file", "-e", "HEAD:notes.txt").Run(); err != nil {
+		t.Errorf("expected the local commit to be pushed: %v", err)
+	}
+}
+
+func TestTakeWarnings_NilClient(t *testing.T) {
+	var client *GopassClient
+	if diags := client.takeWarnings(); diags != nil {
+		t.Errorf("expected nil, got %v", diags)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+)
+
+func TestGopassClient_TemporaryStore(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{
+		"app/db":  "s3cret\nusername: admin",
+		"org/KEY": "value",
+	})
+	client := NewGopassClient(store.Dir)
+	ctx := context.Background()
+	defer client.Close(ctx)
+
+	password, fields, err := client.GetSecretFull(ctx, "app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "s3cret" || fields["username"] != "admin" {
+		t.Errorf("unexpected secret %q %v", password, fields)
+	}
+
+	if err := client.SetSecret(ctx, "app/new", "written"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret, err := store.Get("app/new")
+	if err != nil {
+		t.Fatalf("expected the write to reach the store: %v", err)
+	}
+	if secret.Password() != "written" {
+		t.Errorf("unexpected password %q", secret.Password())
+	}
+
+	if err := client.RemoveSecret(ctx, "app/new"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.Exists("app/new") {
+		t.Error("expected the entry to be removed")
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Tracing defaults.
+const (
+	tracingServiceName  = "terraform-provider-gopass"
+	tracingExportPath   = "/v1/traces"
+	tracingBatchSize    = 64
+	tracingFlushTimeout = 10 * time.Second
+)
+
+// OTLP span kind and status codes (see opentelemetry-proto trace.proto).
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+// tracer records spans around store operations and exports them to an
+// OpenTelemetry collector using OTLP over HTTP with JSON encoding.
+//
+// It intentionally implements just enough of the protocol for this provider,
+// so tracing does not pull the OpenTelemetry SDK into the plugin binary.
+// A nil *tracer is valid and records nothing.
+type tracer struct {
+	endpoint   string
+	httpClient *http.Client
+	traceID    string // one trace per provider run
+
+	mu    sync.Mutex
+	spans []finishedSpan
+}
+
+// finishedSpan is a completed span waiting to be exported.
+type finishedSpan struct {
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	pathHash string
+	attrs    []otlpAttribute
+	err      bool
+}
+
+// activeSpan is a span that has been started but not ended.
+// A nil *activeSpan is valid and ending it is a no-op.
+type activeSpan struct {
+	t    *tracer
+	span finishedSpan
+}
+
+type spanContextKey struct{}
+
+// newTracer returns a tracer exporting to endpoint (e.g. "http://localhost:4318"),
+// or nil if endpoint is empty.
+func newTracer(endpoint string) *tracer {
+	if endpoint == "" {
+		return nil
+	}
+	return &tracer{
+		endpoint:   strings.TrimSuffix(endpoint, "/") + tracingExportPath,
+		httpClient: &http.Client{Timeout: tracingFlushTimeout},
+		traceID:    randomHex(16),
+	}
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// hashPath returns a stable identifier for a secret path that does not reveal it.
+func hashPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:8])
+}
+
+// startSpan starts a span as a child of the span in ctx, if any.
+// The secret path is only ever recorded as a hash.
+func (t *tracer) startSpan(ctx context.Context, name, path string) (context.Context, *activeSpan) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &activeSpan{
+		t: t,
+		span: finishedSpan{
+			spanID: randomHex(8),
+			name:   name,
+			start:  time.Now(),
+		},
+	}
+	if path != "" {
+		span.span.pathHash = hashPath(path)
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(*activeSpan); ok {
+		span.span.parentID = parent.span.spanID
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// setAttribute adds a string attribute to the span.
+func (s *activeSpan) setAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.span.attrs = append(s.span.attrs, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+}
+
+// end finishes the span and queues it for export.
+func (s *activeSpan) end(err error) {
+	if s == nil {
+		return
+	}
+
+	s.span.end = time.Now()
+	s.span.err = err != nil
+
+	s.t.mu.Lock()
+	s.t.spans = append(s.t.spans, s.span)
+	full := len(s.t.spans) >= tracingBatchSize
+	s.t.mu.Unlock()
+
+	// Export full batches right away: the provider process may be stopped
+	// without the client ever being closed
+	if full {
+		go s.t.flush(context.Background())
+	}
+}
+
+// flush exports all buffered spans. Export failures are logged, never returned:
+// tracing must not break a plan.
+func (t *tracer) flush(ctx context.Context) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	spans := t.spans
+	t.spans = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+
+	if err := t.export(ctx, spans); err != nil {
+		tflog.Warn(ctx, "Failed to export gopass traces", map[string]interface{}{
+			"endpoint": t.endpoint,
+			"spans":    len(spans),
+			"error":    err.Error(),
+		})
+		return
+	}
+
+	tflog.Debug(ctx, "Exported gopass traces", map[string]interface{}{
+		"endpoint": t.endpoint,
+		"spans":    len(spans),
+	})
+}
+
+// export sends spans to the collector as an OTLP ExportTraceServiceRequest.
+func (t *tracer) export(ctx context.Context, spans []finishedSpan) error {
+	body, err := json.Marshal(t.otlpRequest(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tracingFlushTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send spans: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP/JSON payload types. Only the fields this provider uses are modeled.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue string `json:"stringValue"`
+	}
+	otlpStatus struct {
+		Code int `json:"code"`
+	}
+)
+
+// otlpRequest converts spans into the OTLP JSON request structure.
+func (t *tracer) otlpRequest(spans []finishedSpan) otlpRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if s.pathHash != "" {
+			span.Attributes = append(span.Attributes, otlpAttribute{
+				Key:   "gopass.path_hash",
+				Value: otlpValue{StringValue: s.pathHash},
+			})
+		}
+		span.Attributes = append(span.Attributes, s.attrs...)
+		if s.err {
+			span.Status.Code = otlpStatusError
+		}
+		otlpSpans = append(otlpSpans, span)
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: []otlpAttribute{{
+				Key:   "service.name",
+				Value: otlpValue{StringValue: tracingServiceName},
+			}}},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: tracingServiceName},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
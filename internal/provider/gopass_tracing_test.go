@@ -0,0 +1,211 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// otlpCollector is a minimal OTLP/HTTP receiver recording exported spans.
+type otlpCollector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+	status   int
+}
+
+func newOTLPCollector(t *testing.T) (*otlpCollector, *httptest.Server) {
+	t.Helper()
+	collector := &otlpCollector{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tracingExportPath || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		collector.requests = append(collector.requests, req)
+		w.WriteHeader(collector.status)
+	}))
+	t.Cleanup(server.Close)
+	return collector, server
+}
+
+func (c *otlpCollector) spans() []otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var spans []otlpSpan
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func spanAttribute(span otlpSpan, key string) (string, bool) {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value.StringValue, true
+		}
+	}
+	return "", false
+}
+
+func TestNewTracer_EmptyEndpoint(t *testing.T) {
+	if newTracer("") != nil {
+		t.Error("expected nil tracer for empty endpoint")
+	}
+}
+
+func TestTracer_NilIsNoop(t *testing.T) {
+	var tr *tracer
+	ctx, span := tr.startSpan(context.Background(), "gopass.read", "app/db")
+	span.setAttribute("key", "value")
+	span.end(nil)
+	tr.flush(ctx)
+}
+
+func TestHashPath(t *testing.T) {
+	hash := hashPath("app/db/password")
+	if len(hash) != 16 {
+		t.Errorf("expected 16 hex characters, got %q", hash)
+	}
+	if hash != hashPath("app/db/password") {
+		t.Error("expected hash to be stable")
+	}
+	if hash == hashPath("app/db/username") {
+		t.Error("expected different paths to hash differently")
+	}
+}
+
+func TestTracer_ParentChild(t *testing.T) {
+	tr := newTracer("http://localhost:4318")
+
+	ctx, parent := tr.startSpan(context.Background(), "outer", "")
+	_, child := tr.startSpan(ctx, "inner", "")
+	child.end(nil)
+	parent.end(nil)
+
+	if child.span.parentID != parent.span.spanID {
+		t.Errorf("expected child parent %q, got %q", parent.span.spanID, child.span.parentID)
+	}
+	if parent.span.parentID != "" {
+		t.Errorf("expected root span without parent, got %q", parent.span.parentID)
+	}
+}
+
+func TestGopassClient_Tracing_ExportsSpans(t *testing.T) {
+	collector, server := newOTLPCollector(t)
+	ctx := context.Background()
+
+	client := NewGopassClient("")
+	client.store = newPrefetchTestStore()
+	client.tracer = newTracer(server.URL)
+
+	if _, err := client.GetEnvSecrets(ctx, "app/env"); err != nil {
+		t.Fatalf("GetEnvSecrets() error = %v", err)
+	}
+	if err := client.SetSecret(ctx, "app/new", "value"); err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "missing"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+
+	client.Close(ctx)
+
+	spans := collector.spans()
+	if len(spans) == 0 {
+		t.Fatal("expected spans to be exported on Close")
+	}
+
+	names := make(map[string]int)
+	for _, span := range spans {
+		names[span.Name]++
+
+		if len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+			t.Errorf("unexpected IDs in span %+v", span)
+		}
+		for _, attr := range span.Attributes {
+			if strings.Contains(attr.Value.StringValue, "app/") {
+				t.Errorf("span attribute %q leaks a secret path: %q", attr.Key, attr.Value.StringValue)
+			}
+		}
+	}
+
+	if names["gopass.list"] == 0 || names["gopass.read"] < 2 || names["gopass.write"] != 1 {
+		t.Errorf("unexpected span names %v", names)
+	}
+
+	var sawError, sawGit bool
+	for _, span := range spans {
+		if span.Name == "gopass.read" && span.Status.Code == otlpStatusError {
+			sawError = true
+			if hash, _ := spanAttribute(span, "gopass.path_hash"); hash != hashPath("missing") {
+				t.Errorf("expected failed read to carry hash of %q, got %q", "missing", hash)
+			}
+		}
+		if span.Name == "gopass.write" {
+			if value, _ := spanAttribute(span, "gopass.git"); value == "commit" {
+				sawGit = true
+			}
+		}
+	}
+	if !sawError {
+		t.Error("expected failed read span with error status")
+	}
+	if !sawGit {
+		t.Error("expected write span to be marked as git commit")
+	}
+}
+
+func TestTracer_FlushesFullBatch(t *testing.T) {
+	collector, server := newOTLPCollector(t)
+	tr := newTracer(server.URL)
+
+	for range tracingBatchSize {
+		_, span := tr.startSpan(context.Background(), "gopass.read", "app/db")
+		span.end(nil)
+	}
+
+	// The full batch is exported in the background without an explicit flush
+	for i := 0; i < 100 && len(collector.spans()) < tracingBatchSize; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := len(collector.spans()); got != tracingBatchSize {
+		t.Errorf("expected %d exported spans, got %d", tracingBatchSize, got)
+	}
+}
+
+func TestTracer_ExportFailureIsNotFatal(t *testing.T) {
+	collector, server := newOTLPCollector(t)
+	collector.status = http.StatusServiceUnavailable
+	tr := newTracer(server.URL)
+
+	_, span := tr.startSpan(context.Background(), "gopass.read", "app/db")
+	span.end(errors.New("decryption failed"))
+
+	spans := []finishedSpan{span.span}
+	if err := tr.export(context.Background(), spans); err == nil {
+		t.Error("expected export error for HTTP 503")
+	}
+
+	// flush only logs the failure
+	tr.flush(context.Background())
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// invalidUTF8Offset returns the byte offset of the first invalid UTF-8
+// sequence in s, or -1 if s is valid UTF-8.
+func invalidUTF8Offset(s string) int {
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// nonUTF8Diagnostics reports values, keyed by secret path, that are not
+// valid UTF-8.
+//
+// Terraform strings must be UTF-8. Handing it anything else fails deep in the
+// plugin protocol with an error that names neither the secret nor the cause,
+// so such values are rejected here with a pointer to the base64 route instead.
+func nonUTF8Diagnostics(values map[string]string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var invalid []string
+	for path, value := range values {
+		if offset := invalidUTF8Offset(value); offset >= 0 {
+			invalid = append(invalid, fmt.Sprintf("%q (first invalid byte at offset %d)", path, offset))
+		}
+	}
+	if len(invalid) == 0 {
+		return diags
+	}
+	sort.Strings(invalid)
+
+	summary := "Secret value is not valid UTF-8"
+	if len(invalid) > 1 {
+		summary = "Secret values are not valid UTF-8"
+	}
+	diags.AddError(summary, fmt.Sprintf("The value of %s contains bytes that are not valid UTF-8, "+
+		"which Terraform strings cannot hold. This usually means the secret holds binary data, such as a "+
+		"keystore or a DER certificate.\n\n"+
+		"Read binary secrets with the gopass_binary ephemeral resource instead, which returns them "+
+		"base64 encoded, and decode them where they are needed with Terraform's base64decode() function.", strings.Join(invalid, ", ")))
+	return diags
+}
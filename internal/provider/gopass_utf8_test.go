@@ -0,0 +1,76 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInvalidUTF8Offset(t *testing.T) {
+	testCases := map[string]int{
+		"":                   -1,
+		"plain":              -1,
+		"grüße €":            -1,
+		"� literal":          -1, // a valid encoding of the replacement character
+		"ok\xffrest":         2,
+		"\xc3":               0, // truncated sequence
+		"prefix\xed\xa0\x80": 6, // encoded surrogate
+	}
+
+	for value, want := range testCases {
+		if got := invalidUTF8Offset(value); got != want {
+			t.Errorf("invalidUTF8Offset(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestNonUTF8Diagnostics(t *testing.T) {
+	if diags := nonUTF8Diagnostics(map[string]string{"app/db": "s3cret"}); diags.HasError() {
+		t.Errorf("expected valid values to pass, got %v", diags)
+	}
+
+	diags := nonUTF8Diagnostics(map[string]string{"app/cert": "\x30\x82\xff", "app/key": "ok\xfe", "app/db": "s3cret"})
+	if !diags.HasError() || len(diags) != 1 {
+		t.Fatalf("expected one error, got %v", diags)
+	}
+	if diags[0].Summary() != "Secret values are not valid UTF-8" {
+		t.Errorf("unexpected summary %q", diags[0].Summary())
+	}
+	detail := diags[0].Detail()
+	for _, want := range []string{`"app/cert" (first invalid byte at offset 1)`, `"app/key" (first invalid byte at offset 2)`, "base64decode"} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("expected %q in detail, got %q", want, detail)
+		}
+	}
+	if strings.Contains(detail, "app/db") {
+		t.Errorf("expected valid values not to be listed, got %q", detail)
+	}
+}
+
+// binaryValueTestEntries holds a secret that is not valid UTF-8 next to one
+// that is.
+var binaryValueTestEntries = map[string]string{"app/keystore": "\x00\x01\xfe\xff", "app/user": "admin"}
+
+func TestSecretEphemeralResource_Open_NonUTF8(t *testing.T) {
+	resp := openSecretEphemeral(t, NewGopassClientWithStore(NewMemoryStore(binaryValueTestEntries)), "app/keystore")
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error for a binary value")
+	}
+	if got := resp.Diagnostics.Errors()[0].Summary(); got != "Secret value is not valid UTF-8" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestEnvEphemeralResource_Open_NonUTF8(t *testing.T) {
+	resp := openEnvEphemeral(t, NewGopassClientWithStore(NewMemoryStore(binaryValueTestEntries)), "app")
+
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error for a binary value")
+	}
+	if detail := resp.Diagnostics.Errors()[0].Detail(); !strings.Contains(detail, `"app/keystore"`) {
+		t.Errorf("expected the binary secret to be named, got %q", detail)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// warmUp opens the store and decrypts the secret at path, discarding the value.
+//
+// Run at Configure time, this moves gpg-agent startup and the passphrase or
+// PIN prompt in front of the plan graph: once it returns, the agent holds the
+// unlocked key and resource reads decrypt without waiting on a person. A
+// configured prefetch pass runs as part of the same read.
+func (c *GopassClient) warmUp(ctx context.Context, path string) error {
+	start := time.Now()
+
+	store, release, err := c.storeFor(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if _, err := c.storeGet(ctx, store, path); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "gpg-agent warmed up", map[string]interface{}{
+		"duration": time.Since(start).String(),
+	})
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGopassClient_WarmUp(t *testing.T) {
+	client, store := newLifecycleTestClient()
+	opened := 0
+	client.newStore = func(ctx context.Context) (SecretStore, error) {
+		opened++
+		return store, nil
+	}
+
+	if err := client.warmUp(context.Background(), "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opened != 1 {
+		t.Errorf("expected the store to be opened by the warm-up, got %d opens", opened)
+	}
+
+	ops, _, _ := client.metrics.snapshot()
+	if ops[opRead].Count != 1 {
+		t.Errorf("expected one decryption, got %d", ops[opRead].Count)
+	}
+	if refs := client.references(); refs != 0 {
+		t.Errorf("expected the store reference to be released, got %d", refs)
+	}
+}
+
+func TestGopassClient_WarmUp_NotFound(t *testing.T) {
+	client, _ := newLifecycleTestClient()
+
+	err := client.warmUp(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gopasspw/gopass/pkg/ctxutil"
+	"github.com/gopasspw/gopass/pkg/gitconfig"
+)
+
+// writeQueue serializes store mutations.
+//
+// gopass stages and commits every write in the store's git repository. When
+// many gopass_secret resources apply in parallel, concurrent writes race for
+// git's index lock and fail. Writes therefore go through the store one at a
+// time; reads are not affected. The gopass library store additionally
+// batches its commits, see commitBatch.
+type writeQueue struct {
+	slot chan struct{} // capacity 1, nil disables serialization
+}
+
+func newWriteQueue() writeQueue {
+	return writeQueue{slot: make(chan struct{}, 1)}
+}
+
+// acquire blocks until the caller may write. The returned function releases
+// the slot.
+func (q *writeQueue) acquire(ctx context.Context) (func(), error) {
+	if q.slot == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case q.slot <- struct{}{}:
+		return func() { <-q.slot }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// gitCommitStaged commits the changes staged in the git repository at dir
+// with message; injectable for testing.
+var gitCommitStaged = func(ctx context.Context, dir, message string) error {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "commit", "--quiet", "-m", message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git commit: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// commitBatch defers the git commits of a gopass library store's writes to a
+// single commit when the store is closed, normally once per apply. The writes
+// are staged by gopass as usual, just not committed one by one.
+//
+// Only writes to the store directory are batched: a secret the gopass
+// configuration mounts from another store lives in another repository, and
+// is committed by gopass right away.
+type commitBatch struct {
+	dir      string
+	mounted  func(name string) bool // reports names the gopass configuration mounts elsewhere
+	autopush bool                   // push after committing, as gopass' core.autopush
+
+	mu    sync.Mutex
+	names []string
+}
+
+// newCommitBatch returns the commit batch for the gopass store in dir, with
+// the mounts and core.autopush of the gopass configuration cfg (nil if there
+// is none). It returns nil, and writes are committed by gopass, if dir is
+// unknown or not a git repository, which gopass does not commit to either.
+func newCommitBatch(dir string, cfg *gitconfig.Config) *commitBatch {
+	if dir == "" || !isGitRepo(dir) {
+		return nil
+	}
+	b := &commitBatch{dir: dir, mounted: func(string) bool { return false }, autopush: true}
+	if cfg != nil {
+		b.mounted = func(name string) bool {
+			for prefix := path.Dir(name); prefix != "." && prefix != "/"; prefix = path.Dir(prefix) {
+				if _, ok := cfg.Get(mountConfigKey(prefix)); ok {
+					return true
+				}
+			}
+			return false
+		}
+		if v, ok := cfg.Get("core.autopush"); ok && v == "false" {
+			b.autopush = false
+		}
+	}
+	return b
+}
+
+// hold returns ctx telling gopass not to commit the write of name, and
+// whether it did so. The caller adds name once the write succeeded.
+func (b *commitBatch) hold(ctx context.Context, name string) (context.Context, bool) {
+	if b == nil || b.mounted(name) {
+		return ctx, false
+	}
+	return ctxutil.WithGitCommit(ctx, false), true
+}
+
+// add records the staged write of name for the next commit.
+func (b *commitBatch) add(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !slices.Contains(b.names, name) {
+		b.names = append(b.names, name)
+	}
+}
+
+// commit commits the writes held so far in one commit and pushes it if
+// gopass would have. Writes left uncommitted by a failure stay staged, and
+// go into gopass' next commit.
+func (b *commitBatch) commit(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	names := b.names
+	b.names = nil
+	b.mu.Unlock()
+
+	if len(names) == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("Update %d secret(s): %s", len(names), strings.Join(names, ", "))
+	if err := gitCommitStaged(ctx, b.dir, message); err != nil {
+		return fmt.Errorf("committing the writes of this run: %w", err)
+	}
+	if b.autopush {
+		if err := gitPullPush(ctx, b.dir); err != nil {
+			return fmt.Errorf("pushing the writes of this run: %w", err)
+		}
+	}
+	return nil
+}
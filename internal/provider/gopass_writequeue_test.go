@@ -0,0 +1,227 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gopasspw/gopass/pkg/ctxutil"
+	"github.com/gopasspw/gopass/pkg/gitconfig"
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// mockConcurrentWriteStore tracks how many writes are in flight at once.
+type mockConcurrentWriteStore struct {
+	*mockStore
+	mu       sync.Mutex
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (m *mockConcurrentWriteStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	n := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	if n > m.peak.Load() {
+		m.peak.Store(n)
+	}
+	time.Sleep(time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mockStore.Set(ctx, name, sec)
+}
+
+// mockStuckWriteStore blocks every Set until unblock is closed, ignoring the
+// context like a gopass write stuck in gpg.
+type mockStuckWriteStore struct {
+	*mockStore
+	unblock chan struct{}
+}
+
+func (m *mockStuckWriteStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	<-m.unblock
+	return nil
+}
+
+func TestGopassClient_WritesAreSerialized(t *testing.T) {
+	store := &mockConcurrentWriteStore{mockStore: newMockStore()}
+	client := NewGopassClient("")
+	client.store = store
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.SetSecret(context.Background(), "app/key"+string(rune('a'+i)), "v"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak := store.peak.Load(); peak != 1 {
+		t.Errorf("expected writes one at a time, saw %d concurrent writes", peak)
+	}
+}
+
+func TestGopassClient_WriteQueue_RespectsContext(t *testing.T) {
+	client := NewGopassClient("")
+	client.store = newMockStore()
+
+	release, err := client.writes.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = client.SetSecret(ctx, "app/key", "v")
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected a timeout while waiting for the write slot, got %v", err)
+	}
+}
+
+func TestGopassClient_AbandonedWriteHoldsSlot(t *testing.T) {
+	store := &mockStuckWriteStore{mockStore: newMockStore(), unblock: make(chan struct{})}
+	client := NewGopassClient("")
+	client.store = store
+	client.retry.MaxAttempts = 1
+	client.timeouts.Write = 10 * time.Millisecond
+
+	if err := client.SetSecret(context.Background(), "app/key", "v"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	// The abandoned write is still running, so the slot stays taken
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.writes.acquire(ctx); err == nil {
+		t.Fatal("expected the slot to be held by the abandoned write")
+	}
+
+	close(store.unblock)
+	release, err := client.writes.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected the slot to be freed once the write returned, got %v", err)
+	}
+	release()
+}
+
+// mockCommitStore records whether gopass would have committed each write.
+type mockCommitStore struct {
+	gopass.Store
+	committed map[string]bool
+}
+
+func (m *mockCommitStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	m.committed[name] = ctxutil.IsGitCommit(ctx)
+	return nil
+}
+
+func (m *mockCommitStore) Remove(ctx context.Context, name string) error {
+	m.committed[name] = ctxutil.IsGitCommit(ctx)
+	return nil
+}
+
+func (m *mockCommitStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// newBatchTestDir returns a store directory that is a git repository.
+func newBatchTestDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".git"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// stubGit replaces gitCommitStaged and gitPullPush, recording their calls.
+func stubGit(t *testing.T) *[]string {
+	t.Helper()
+	var calls []string
+	previousCommit, previousPush := gitCommitStaged, gitPullPush
+	gitCommitStaged = func(ctx context.Context, dir, message string) error {
+		calls = append(calls, "commit: "+message)
+		return nil
+	}
+	gitPullPush = func(ctx context.Context, dir string) error {
+		calls = append(calls, "push")
+		return nil
+	}
+	t.Cleanup(func() { gitCommitStaged, gitPullPush = previousCommit, previousPush })
+	return &calls
+}
+
+func TestLibraryStore_BatchesCommits(t *testing.T) {
+	ctx := context.Background()
+	calls := stubGit(t)
+	dir := newBatchTestDir(t)
+	lib := &mockCommitStore{committed: map[string]bool{}}
+	cfg := gitconfig.NewFromMap(map[string]string{mountConfigKey("team"): "/srv/team"})
+	store := &libraryStore{Store: lib, dir: dir, batch: newCommitBatch(dir, cfg)}
+
+	for _, name := range []string{"app/db", "app/api", "app/db", "team/token"} {
+		if err := store.Set(ctx, name, newPasswordSecret("v")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Remove(ctx, "app/old"); err != nil {
+		t.Fatal(err)
+	}
+	if lib.committed["app/db"] || lib.committed["app/old"] {
+		t.Error("expected writes to the store directory not to be committed one by one")
+	}
+	// The mount is another repository, which gopass commits to itself
+	if !lib.committed["team/token"] {
+		t.Error("expected writes to a configured mount to be committed by gopass")
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("expected no commit before Close, got %v", *calls)
+	}
+
+	if err := store.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := store.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	want := []string{
+		"commit: Update 3 secret(s): app/db, app/api, app/old",
+		"push",
+	}
+	if strings.Join(*calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected git calls:\n%s", strings.Join(*calls, "\n"))
+	}
+}
+
+func TestNewCommitBatch(t *testing.T) {
+	if newCommitBatch("", nil) != nil {
+		t.Error("expected no batching without a store directory")
+	}
+	if newCommitBatch(t.TempDir(), nil) != nil {
+		t.Error("expected no batching outside a git repository")
+	}
+
+	dir := newBatchTestDir(t)
+	if b := newCommitBatch(dir, nil); b == nil || !b.autopush {
+		t.Errorf("expected batching with gopass' default autopush, got %+v", b)
+	}
+	cfg := gitconfig.NewFromMap(map[string]string{"core.autopush": "false"})
+	if b := newCommitBatch(dir, cfg); b == nil || b.autopush {
+		t.Errorf("expected core.autopush = false to be kept, got %+v", b)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gopasspw/gopass/pkg/gopass"
+)
+
+// wslStoreDirScript points PASSWORD_STORE_DIR at its first argument, with a
+// leading "~/" expanded inside the distribution, and runs gopass with the
+// remaining ones.
+const wslStoreDirScript = `case $1 in "~/"*) d=$HOME/${1#"~/"} ;; *) d=$1 ;; esac; shift; ` +
+	`PASSWORD_STORE_DIR=$d exec gopass "$@"`
+
+// runCLI runs argv with stdin and returns its output, with stderr in the
+// error. env adds NAME=value variables to the process' environment;
+// injectable for testing.
+var runCLI = func(ctx context.Context, env []string, stdin []byte, argv ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, &commandError{name: argv[0], err: err, stderr: strings.TrimSpace(stderr.String())}
+	}
+	return out, nil
+}
+
+// wslHost selects the WSL distribution secrets are resolved in.
+type wslHost struct {
+	distribution string   // empty for WSL's default distribution
+	env          []string // NAME=value variables gopass runs with
+}
+
+// command returns the argv prefix that runs gopass in the distribution, with
+// the store in dir if dir is not empty.
+func (h *wslHost) command(dir string) []string {
+	argv := []string{"wsl.exe"}
+	if h.distribution != "" {
+		argv = append(argv, "--distribution", h.distribution)
+	}
+	argv = append(argv, "--exec")
+	if len(h.env) > 0 {
+		argv = append(append(argv, "env"), h.env...)
+	}
+	if dir == "" {
+		return append(argv, "gopass")
+	}
+	return append(argv, "sh", "-c", wslStoreDirScript, "sh", dir)
+}
+
+// useWSL makes the client resolve secrets through the gopass CLI inside the
+// WSL distribution, for Windows users whose store and keys live there. The
+// store and mount paths are paths inside the distribution.
+func (c *GopassClient) useWSL(distribution string) {
+	c.wsl = &wslHost{distribution: distribution}
+	c.newStore = func(ctx context.Context) (SecretStore, error) {
+		return &cliStore{command: c.wsl.command(c.storePath)}, nil
+	}
+}
+
+// cliStore is a SecretStore that runs the gopass command line client, e.g.
+// inside a WSL distribution.
+type cliStore struct {
+	command []string // argv prefix running gopass
+	env     []string // NAME=value variables gopass runs with
+}
+
+// Ensure cliStore satisfies SecretStore.
+var _ SecretStore = (*cliStore)(nil)
+
+// Exit statuses gopass ends with (internal/action/exit in gopass).
+const (
+	// gopassExitNotFound means the secret it was asked for does not exist
+	gopassExitNotFound = 10
+	// gopassExitDecrypt means the secret exists but could not be decrypted
+	gopassExitDecrypt = 11
+)
+
+// run runs gopass with args and stdin, mapping gopass' not-found exit status
+// to ErrNotFound and its decryption exit status to ErrDecryptionFailed.
+func (s *cliStore) run(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	out, err := runCLI(ctx, s.env, stdin, append(append([]string{}, s.command...), args...)...)
+	var exit interface{ ExitCode() int }
+	if errors.As(err, &exit) {
+		switch exit.ExitCode() {
+		case gopassExitNotFound:
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		case gopassExitDecrypt:
+			return nil, classify(ErrDecryptionFailed, err)
+		}
+	}
+	return out, err
+}
+
+// Get returns the given revision of the secret name.
+func (s *cliStore) Get(ctx context.Context, name, revision string) (gopass.Secret, error) {
+	args := []string{"show", "--noparsing", "--unsafe"}
+	if revision != "latest" && revision != "" {
+		args = append(args, "--revision", revision)
+	}
+	out, err := s.run(ctx, nil, name, append(args, "--", name)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseSecret(out), nil
+}
+
+// List returns the paths of all secrets.
+func (s *cliStore) List(ctx context.Context) ([]string, error) {
+	out, err := s.run(ctx, nil, "", "list", "--flat")
+	if err != nil {
+		return nil, err
+	}
+	// One path per line; paths may contain spaces
+	var names []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// Set creates or overwrites the secret name with sec.
+func (s *cliStore) Set(ctx context.Context, name string, sec gopass.Byter) error {
+	_, err := s.run(ctx, sec.Bytes(), name, "insert", "--force", "--", name)
+	return err
+}
+
+// Remove deletes the secret name.
+func (s *cliStore) Remove(ctx context.Context, name string) error {
+	_, err := s.run(ctx, nil, name, "rm", "--force", "--", name)
+	return err
+}
+
+// Sync pulls from and pushes to the store's git remotes with gopass sync.
+func (s *cliStore) Sync(ctx context.Context) error {
+	_, err := s.run(ctx, nil, "sync")
+	return err
+}
+
+// Revisions returns the commit hashes gopass lists in the history of the
+// secret name, newest first. Each line of "gopass history" reads
+// "<hash> - <author> <<email>> - <RFC 3339 date> - <subject>"; lines that do
+// not start with a hexadecimal hash are no revisions.
+func (s *cliStore) Revisions(ctx context.Context, name string) ([]string, error) {
+	out, err := s.run(ctx, nil, name, "history", "--", name)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		hash, _, ok := strings.Cut(scanner.Text(), " - ")
+		if ok && isCommitHash(hash) {
+			revisions = append(revisions, hash)
+		}
+	}
+	if len(revisions) == 0 {
+		return nil, errors.New("gopass history listed no revisions for " + name)
+	}
+	return revisions, nil
+}
+
+// isCommitHash reports whether s is an abbreviated or full git commit hash.
+func isCommitHash(s string) bool {
+	if len(s) < 4 || len(s) > 64 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
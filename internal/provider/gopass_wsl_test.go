@@ -0,0 +1,199 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// stubCLI replaces runCLI with fn for the test.
+func stubCLI(t *testing.T, fn func(argv []string, stdin []byte) ([]byte, error)) {
+	t.Helper()
+	previous := runCLI
+	runCLI = func(ctx context.Context, env []string, stdin []byte, argv ...string) ([]byte, error) {
+		return fn(argv, stdin)
+	}
+	t.Cleanup(func() { runCLI = previous })
+}
+
+// cliExit is a failed gopass run ending with an exit status.
+type cliExit int
+
+func (e cliExit) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+func (e cliExit) ExitCode() int { return int(e) }
+
+func TestWSLHost_Command(t *testing.T) {
+	tests := []struct {
+		host wslHost
+		dir  string
+		want string
+	}{
+		{want: "wsl.exe --exec gopass"},
+		{host: wslHost{distribution: "Ubuntu"}, want: "wsl.exe --distribution Ubuntu --exec gopass"},
+		{host: wslHost{distribution: "Ubuntu"}, dir: "~/.password-store",
+			want: "wsl.exe --distribution Ubuntu --exec sh -c " + wslStoreDirScript + " sh ~/.password-store"},
+		{host: wslHost{env: []string{"GOPASS_GPG_OPTS=--batch"}}, dir: "/srv/store",
+			want: "wsl.exe --exec env GOPASS_GPG_OPTS=--batch sh -c " + wslStoreDirScript + " sh /srv/store"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.host.command(tt.dir), " "); got != tt.want {
+			t.Errorf("command(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestCLIStore(t *testing.T) {
+	var calls []string
+	stubCLI(t, func(argv []string, stdin []byte) ([]byte, error) {
+		calls = append(calls, strings.Join(argv[1:], " ")+"|"+string(stdin))
+		switch argv[1] {
+		case "show":
+			// The exit statuses of gopass v1.15: 10 is NotFound, 11 is Decrypt
+			switch argv[len(argv)-1] {
+			case "missing":
+				return nil, fmt.Errorf("gopass: %w: Error: failed to retrieve secret", cliExit(10))
+			case "locked":
+				return nil, fmt.Errorf("gopass: %w: Error: failed to decrypt", cliExit(11))
+			}
+			return []byte("s3cret\nusername: admin\n"), nil
+		case "list":
+			return []byte("app/db\napp/api token\n"), nil
+		case "history":
+			return []byte("a1b2c3 - Dev <dev@example.com> - 2025-01-02T10:00:00Z - Edit\n" +
+				"0f9e8d - Dev <dev@example.com> - 2025-01-01T10:00:00Z - Add\n" +
+				"Warning - not a revision - \n"), nil
+		}
+		return nil, nil
+	})
+	store := &cliStore{command: []string{"gopass"}}
+	ctx := context.Background()
+
+	secret, err := store.Get(ctx, "app/db", "latest")
+	if err != nil || secret.Password() != "s3cret" {
+		t.Fatalf("unexpected secret %v: %v", secret, err)
+	}
+	if _, err := store.Get(ctx, "app/db", "a1b2c3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get(ctx, "missing", "latest"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Get(ctx, "locked", "latest"); !errors.Is(err, ErrDecryptionFailed) || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrDecryptionFailed, got %v", err)
+	}
+	names, err := store.List(ctx)
+	if err != nil || strings.Join(names, ",") != "app/db,app/api token" {
+		t.Errorf("unexpected listing %v: %v", names, err)
+	}
+	revisions, err := store.Revisions(ctx, "app/db")
+	if err != nil || strings.Join(revisions, ",") != "a1b2c3,0f9e8d" {
+		t.Errorf("unexpected revisions %v: %v", revisions, err)
+	}
+	if err := store.Set(ctx, "app/db", newPasswordSecret("n3w")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Remove(ctx, "app/db"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"show --noparsing --unsafe -- app/db|",
+		"show --noparsing --unsafe --revision a1b2c3 -- app/db|",
+		"show --noparsing --unsafe -- missing|",
+		"show --noparsing --unsafe -- locked|",
+		"list --flat|",
+		"history -- app/db|",
+		"insert --force -- app/db|n3w\n",
+		"rm --force -- app/db|",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected calls:\n%s", strings.Join(calls, "\n"))
+	}
+}
+
+func TestGopassClient_WSL(t *testing.T) {
+	var commands []string
+	stubCLI(t, func(argv []string, stdin []byte) ([]byte, error) {
+		commands = append(commands, strings.Join(argv, " "))
+		return []byte("s3cret\n"), nil
+	})
+
+	// The store path is a path inside the distribution, not on the host
+	client := NewGopassClient("/home/dev/.password-store")
+	client.useWSL("Debian")
+	client.addStoreMount("team", "~/team-store")
+	defer client.Close(context.Background())
+
+	ctx := context.Background()
+	for _, secretPath := range []string{"app", "team/token"} {
+		if value, err := client.GetSecret(ctx, secretPath); err != nil || value != "s3cret" {
+			t.Errorf("%s: unexpected value %q: %v", secretPath, value, err)
+		}
+	}
+	if len(commands) != 2 ||
+		!strings.HasSuffix(commands[0], "sh /home/dev/.password-store show --noparsing --unsafe -- app") ||
+		!strings.HasSuffix(commands[1], "sh ~/team-store show --noparsing --unsafe -- token") {
+		t.Errorf("unexpected commands:\n%s", strings.Join(commands, "\n"))
+	}
+}
+
+func TestProviderConfigure_WSL(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	tests := []struct {
+		name    string
+		config  map[string]tftypes.Value
+		wantErr string
+	}{
+		{
+			name: "enabled",
+			config: map[string]tftypes.Value{
+				"wsl":              tftypes.NewValue(tftypes.Bool, true),
+				"wsl_distribution": tftypes.NewValue(tftypes.String, "Ubuntu"),
+			},
+		},
+		{
+			name: "distribution without wsl",
+			config: map[string]tftypes.Value{
+				"wsl_distribution": tftypes.NewValue(tftypes.String, "Ubuntu"),
+			},
+			wantErr: "Invalid wsl_distribution",
+		},
+		{
+			name: "pass store",
+			config: map[string]tftypes.Value{
+				"wsl":          tftypes.NewValue(tftypes.Bool, true),
+				"store_format": tftypes.NewValue(tftypes.String, "pass"),
+			},
+			wantErr: "Invalid wsl",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &provider.ConfigureResponse{}
+			p.Configure(ctx, provider.ConfigureRequest{Config: newProviderConfig(t, p, tt.config)}, resp)
+			if tt.wantErr != "" {
+				if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tt.wantErr {
+					t.Fatalf("expected %q, got %v", tt.wantErr, resp.Diagnostics)
+				}
+				return
+			}
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+			if client := resp.EphemeralResourceData.(*GopassClient); client.wsl == nil || client.wsl.distribution != "Ubuntu" {
+				t.Errorf("unexpected WSL setup %+v", client.wsl)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretYAML decodes the YAML document below the password line of body, as
+// gopass writes YAML secrets: an optional "---" separator line, then the
+// document. It returns nil if there is nothing below the password.
+func secretYAML(body string) (any, error) {
+	_, rest, _ := strings.Cut(body, "\n")
+	if strings.TrimSpace(rest) == "" {
+		return nil, nil
+	}
+
+	var doc any
+	if err := yaml.Unmarshal([]byte(rest), &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
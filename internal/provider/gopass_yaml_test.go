@@ -0,0 +1,42 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"testing"
+)
+
+func TestSecretYAML(t *testing.T) {
+	tests := map[string]struct {
+		body  string
+		empty bool
+	}{
+		"separator":    {body: "s3cret\n---\napi:\n  token: abc\n"},
+		"no separator": {body: "s3cret\napi:\n  token: abc\n"},
+		"password":     {body: "s3cret", empty: true},
+		"blank lines":  {body: "s3cret\n\n\n", empty: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc, err := secretYAML(tt.body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.empty {
+				if doc != nil {
+					t.Errorf("expected no document, got %v", doc)
+				}
+				return
+			}
+			api, _ := doc.(map[string]any)["api"].(map[string]any)
+			if api["token"] != "abc" {
+				t.Errorf("unexpected document %v", doc)
+			}
+		})
+	}
+
+	if _, err := secretYAML("s3cret\nkey: value\nfree-form notes\n"); err == nil {
+		t.Error("expected an error for a body that is not YAML")
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &JSONEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &JSONEphemeralResource{}
+)
+
+// JSONEphemeralResource reads a secret holding a JSON document and returns
+// the decoded document.
+type JSONEphemeralResource struct {
+	client *GopassClient
+}
+
+// JSONModel describes the data model.
+type JSONModel struct {
+	Path   types.String  `tfsdk:"path"`
+	Policy types.String  `tfsdk:"policy"`
+	Data   types.Dynamic `tfsdk:"data"`
+}
+
+// NewJSONEphemeralResource creates a new instance.
+func NewJSONEphemeralResource() ephemeral.EphemeralResource {
+	return &JSONEphemeralResource{}
+}
+
+func (r *JSONEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_json"
+}
+
+func (r *JSONEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads a secret holding a JSON document, such as a service account key, and returns it decoded.",
+		MarkdownDescription: `
+Reads a secret holding a JSON document, such as a cloud service account key,
+and returns it decoded, so configurations index it directly instead of
+calling ` + "`jsondecode()`" + ` on a sensitive string.
+
+The document is either the whole entry, as stored by ` + "`gopass insert -m`" + `,
+or the lines below the password.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_json" "sa" {
+  path = "gcp/deploy-service-account"
+}
+
+provider "example" {
+  client_email = ephemeral.gopass_json.sa.data.client_email
+  private_key  = ephemeral.gopass_json.sa.data.private_key
+}
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret in the gopass store (e.g., 'gcp/deploy-service-account').",
+				MarkdownDescription: "Path to the secret in the gopass store (e.g., `gcp/deploy-service-account`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"data": schema.DynamicAttribute{
+				Description: "The decoded document: objects become objects, arrays tuples, and scalars strings, " +
+					"numbers or bools. JSON nulls become null strings.",
+				Computed:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+func (r *JSONEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *JSONEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data JSONModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "ephemeral.gopass_json", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	body, _, _, err := r.client.GetSecretWithBody(ctx, secretPath)
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	doc, err := secretJSON(body)
+	if err == nil && doc == nil {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "No JSON document in secret",
+			fmt.Sprintf("The secret at %q holds no JSON document, neither as a whole nor below the password.", secretPath))
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Invalid JSON document",
+			fmt.Sprintf("Could not decode the secret at %q as JSON: %s.", secretPath, err.Error()))
+		return
+	}
+
+	// Hand Terraform copies we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	value, err := documentValue(ctx, doc, func(s string) string {
+		r.client.redactor.addValues(s)
+		return buffers.protect(s)
+	})
+	if err != nil {
+		buffers.wipe()
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "Invalid JSON document",
+			fmt.Sprintf("Could not decode the secret at %q as JSON: %s.", secretPath, err.Error()))
+		return
+	}
+	data.Data = types.DynamicValue(value)
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Read JSON secret from gopass", map[string]interface{}{
+		"path": r.client.logPath(secretPath),
+	})
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *JSONEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
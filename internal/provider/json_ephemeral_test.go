@@ -0,0 +1,70 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newJSONTestClient returns a client holding a service account key at
+// gcp/sa and free-form notes at app/notes.
+func newJSONTestClient() *GopassClient {
+	return NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"gcp/sa":    "{\n  \"client_email\": \"deploy@example.iam\",\n  \"private_key\": \"-----BEGIN KEY-----\",\n  \"scopes\": [\"a\", \"b\"]\n}\n",
+		"app/notes": "s3cret\nnotes about hunter22\n",
+		"app/token": "t0ken",
+	}))
+}
+
+func TestJSONEphemeralResource_Open(t *testing.T) {
+	resp := openConfiguredEphemeral(t, &JSONEphemeralResource{client: newJSONTestClient()}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "gcp/sa"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data JSONModel
+	resp.Result.Get(context.Background(), &data)
+	attrs := data.Data.UnderlyingValue().(types.Object).Attributes()
+	if !attrs["client_email"].Equal(types.StringValue("deploy@example.iam")) {
+		t.Errorf("unexpected client_email %v", attrs["client_email"])
+	}
+	if scopes := attrs["scopes"].(types.Tuple).Elements(); len(scopes) != 2 {
+		t.Errorf("unexpected scopes %v", scopes)
+	}
+}
+
+func TestJSONEphemeralResource_Open_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"app/notes": "Invalid JSON document",
+		"app/token": "No JSON document in secret",
+	}
+	for secretPath, summary := range tests {
+		resp := openConfiguredEphemeral(t, &JSONEphemeralResource{client: newJSONTestClient()}, map[string]tftypes.Value{
+			"path": tftypes.NewValue(tftypes.String, secretPath),
+		})
+		if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != summary {
+			t.Errorf("%s: expected %q, got %v", secretPath, summary, resp.Diagnostics)
+			continue
+		}
+		if detail := resp.Diagnostics.Errors()[0].Detail(); strings.Contains(detail, "hunter22") {
+			t.Errorf("%s: expected no secret content in the error, got %q", secretPath, detail)
+		}
+	}
+}
+
+func TestJSONEphemeralResource_Open_NotFound(t *testing.T) {
+	resp := openConfiguredEphemeral(t, &JSONEphemeralResource{client: newJSONTestClient()}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "gcp/missing"),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Fatal("expected an error for a missing secret")
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &KVEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &KVEphemeralResource{}
+)
+
+// defaultPasswordKey is the key the password is found under in gopass_kv data.
+const defaultPasswordKey = "password"
+
+// KVEphemeralResource reads a secret in the shape of a Vault kv-v2 secret.
+type KVEphemeralResource struct {
+	client *GopassClient
+}
+
+// KVModel describes the data model.
+type KVModel struct {
+	Path        types.String      `tfsdk:"path"`
+	PasswordKey types.String      `tfsdk:"password_key"`
+	Policy      types.String      `tfsdk:"policy"`
+	Data        map[string]string `tfsdk:"data"`
+	DataJSON    types.String      `tfsdk:"data_json"`
+	Metadata    *KVMetadataModel  `tfsdk:"metadata"`
+}
+
+// KVMetadataModel is the version metadata of a KVModel.
+type KVMetadataModel struct {
+	Version      types.Int64  `tfsdk:"version"`
+	CreatedTime  types.String `tfsdk:"created_time"`
+	DeletionTime types.String `tfsdk:"deletion_time"`
+	Destroyed    types.Bool   `tfsdk:"destroyed"`
+}
+
+// NewKVEphemeralResource creates a new instance.
+func NewKVEphemeralResource() ephemeral.EphemeralResource {
+	return &KVEphemeralResource{}
+}
+
+func (r *KVEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_kv"
+}
+
+func (r *KVEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads a secret in the shape of a Vault kv-v2 secret: its keys as data, and version metadata from the store's git history.",
+		MarkdownDescription: `
+Reads a secret in the shape of a Vault kv-v2 secret, to ease moving modules
+written against the Vault provider to gopass: ` + "`data`" + ` holds the secret's
+` + "`key: value`" + ` lines and its password, ` + "`metadata`" + ` its version.
+
+## Example Usage
+
+` + "```hcl" + `
+# Was: ephemeral "vault_kv_secret_v2" "db" { mount = "kv", name = "app/db" }
+ephemeral "gopass_kv" "db" {
+  path = "kv/app/db"
+}
+
+provider "postgresql" {
+  username = ephemeral.gopass_kv.db.data["username"]
+  password = ephemeral.gopass_kv.db.data["password"]
+}
+` + "```" + `
+
+## Versions
+
+` + "`metadata.version`" + ` counts the git commits that changed the entry, so the
+first version is 1, and ` + "`metadata.created_time`" + ` is the time of the latest
+one. The provider needs to know where the store is for this: set ` + "`store_path`" + `
+or mount it. Otherwise the version is counted from the store's revisions and
+the time is null. gopass deletes entries for good, so ` + "`deletion_time`" + ` is
+always empty and ` + "`destroyed`" + ` false.
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret in the gopass store; for a Vault secret, its mount and name (e.g., 'kv/app/db').",
+				MarkdownDescription: "Path to the secret in the gopass store; for a Vault secret, its mount and name (e.g., `kv/app/db`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"password_key": schema.StringAttribute{
+				Description:         "Key of the password (first line of the secret) in data. Default: password.",
+				MarkdownDescription: "Key of the password (first line of the secret) in `data`. Default: `password`.",
+				Optional:            true,
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"data": schema.MapAttribute{
+				Description:         "The secret's keys and its password under password_key.",
+				MarkdownDescription: "The secret's keys and its password under `password_key`.",
+				ElementType:         types.StringType,
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"data_json": schema.StringAttribute{
+				Description:         "data encoded as JSON.",
+				MarkdownDescription: "`data` encoded as JSON.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"metadata": schema.SingleNestedAttribute{
+				Description:         "Version metadata, as Vault reports it for kv-v2 secrets.",
+				MarkdownDescription: "Version metadata, as Vault reports it for kv-v2 secrets.",
+				Computed:            true,
+				Attributes: map[string]schema.Attribute{
+					"version": schema.Int64Attribute{
+						Description: "Version of the secret, counted from 1.",
+						Computed:    true,
+					},
+					"created_time": schema.StringAttribute{
+						Description: "When this version was committed (RFC 3339). Null without git history.",
+						Computed:    true,
+					},
+					"deletion_time": schema.StringAttribute{
+						Description: "Always empty: gopass has no soft deletion.",
+						Computed:    true,
+					},
+					"destroyed": schema.BoolAttribute{
+						Description: "Always false: gopass has no soft deletion.",
+						Computed:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *KVEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *KVEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data KVModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	passwordKey := defaultPasswordKey
+	if !data.PasswordKey.IsNull() {
+		passwordKey = data.PasswordKey.ValueString()
+	}
+	if passwordKey == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("password_key"), "Invalid password key", "password_key must not be empty.")
+		return
+	}
+
+	ctx = withAccessor(ctx, "ephemeral.gopass_kv", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	password, fields, err := r.client.GetSecretFull(ctx, secretPath)
+	if err == nil {
+		var version int64
+		var created time.Time
+		version, created, err = r.client.secretVersion(ctx, secretPath)
+		data.Metadata = &KVMetadataModel{
+			Version:      types.Int64Value(version),
+			CreatedTime:  types.StringNull(),
+			DeletionTime: types.StringValue(""),
+			Destroyed:    types.BoolValue(false),
+		}
+		if !created.IsZero() {
+			data.Metadata.CreatedTime = types.StringValue(created.UTC().Format(time.RFC3339Nano))
+		}
+	}
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	if _, ok := fields[passwordKey]; ok {
+		resp.Diagnostics.AddAttributeError(path.Root("password_key"), "Password key conflicts with a secret key",
+			fmt.Sprintf("The secret at %q has a %q key, so its password cannot be put into data under that key. "+
+				"Set password_key to a name the secret does not use.", secretPath, passwordKey))
+		return
+	}
+	values := make(map[string]string, len(fields)+1)
+	for key, value := range fields {
+		values[key] = value
+	}
+	values[passwordKey] = password
+
+	resp.Diagnostics.Append(nonUTF8Diagnostics(map[string]string{secretPath: password})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if password == "" {
+		resp.Diagnostics.Append(r.client.emptyValueDiagnostics([]string{secretPath})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to encode secret data", err.Error())
+		return
+	}
+
+	// Hand Terraform copies we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.Data = make(map[string]string, len(values))
+	for key, value := range values {
+		data.Data[key] = buffers.protect(value)
+	}
+	data.DataJSON = types.StringValue(buffers.protect(string(encoded)))
+	clear(encoded)
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Read kv secret from gopass", map[string]interface{}{
+		"path":    r.client.logPath(secretPath),
+		"keys":    len(values),
+		"version": data.Metadata.Version.ValueInt64(),
+	})
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *KVEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestKVEphemeralResource_Open(t *testing.T) {
+	dir := newGitStore(t)
+	created := time.Date(2025, 5, 6, 7, 8, 9, 0, time.UTC)
+	commitFile(t, dir, "kv/app/db.age", "v1", created.Add(-24*time.Hour))
+	commitFile(t, dir, "kv/app/db.age", "v2", created)
+
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"kv/app/db": "s3cret\nusername: admin\nport: 5432",
+	}))
+	client.storePath = dir
+
+	resp := openKVEphemeral(t, client, "kv/app/db", "")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data KVModel
+	resp.Result.Get(context.Background(), &data)
+	if len(data.Data) != 3 || data.Data["password"] != "s3cret" || data.Data["username"] != "admin" || data.Data["port"] != "5432" {
+		t.Errorf("unexpected data %v", data.Data)
+	}
+	if want := `{"password":"s3cret","port":"5432","username":"admin"}`; data.DataJSON.ValueString() != want {
+		t.Errorf("unexpected data_json %s", data.DataJSON.ValueString())
+	}
+	m := data.Metadata
+	if m.Version.ValueInt64() != 2 || m.CreatedTime.ValueString() != "2025-05-06T07:08:09Z" ||
+		m.DeletionTime.ValueString() != "" || m.Destroyed.ValueBool() {
+		t.Errorf("unexpected metadata %+v", m)
+	}
+}
+
+func TestKVEphemeralResource_Open_WithoutHistory(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/token": "t0ken"}))
+
+	resp := openKVEphemeral(t, client, "app/token", "value")
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data KVModel
+	resp.Result.Get(context.Background(), &data)
+	if len(data.Data) != 1 || data.Data["value"] != "t0ken" {
+		t.Errorf("expected the password under the configured key, got %v", data.Data)
+	}
+	if data.Metadata.Version.ValueInt64() != 1 || !data.Metadata.CreatedTime.IsNull() {
+		t.Errorf("expected version 1 without a time, got %+v", data.Metadata)
+	}
+}
+
+func TestKVEphemeralResource_Open_Errors(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"app/db": "s3cret\npassword: other",
+	}))
+
+	str := func(s string) tftypes.Value { return tftypes.NewValue(tftypes.String, s) }
+	tests := map[string]struct {
+		config  map[string]tftypes.Value
+		summary string
+	}{
+		"not found":  {map[string]tftypes.Value{"path": str("app/missing")}, "Secret not found"},
+		"conflict":   {map[string]tftypes.Value{"path": str("app/db")}, "Password key conflicts with a secret key"},
+		"empty key":  {map[string]tftypes.Value{"path": str("app/db"), "password_key": str("")}, "Invalid password key"},
+		"custom key": {map[string]tftypes.Value{"path": str("app/db"), "password_key": str("pw")}, ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := openConfiguredEphemeral(t, &KVEphemeralResource{client: client}, tt.config)
+			errs := resp.Diagnostics.Errors()
+			if tt.summary == "" && len(errs) != 0 {
+				t.Errorf("unexpected error: %v", errs)
+			}
+			if tt.summary != "" && (len(errs) != 1 || errs[0].Summary() != tt.summary) {
+				t.Errorf("expected %q, got %v", tt.summary, resp.Diagnostics)
+			}
+		})
+	}
+}
+
+// openKVEphemeral opens a gopass_kv ephemeral resource for path, with the
+// password under passwordKey unless it is empty.
+func openKVEphemeral(t *testing.T, client *GopassClient, path, passwordKey string) *ephemeral.OpenResponse {
+	t.Helper()
+	config := map[string]tftypes.Value{"path": tftypes.NewValue(tftypes.String, path)}
+	if passwordKey != "" {
+		config["password_key"] = tftypes.NewValue(tftypes.String, passwordKey)
+	}
+	return openConfiguredEphemeral(t, &KVEphemeralResource{client: client}, config)
+}
@@ -0,0 +1,259 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ resource.Resource                   = &MountResource{}
+	_ resource.ResourceWithConfigure      = &MountResource{}
+	_ resource.ResourceWithImportState    = &MountResource{}
+	_ resource.ResourceWithValidateConfig = &MountResource{}
+)
+
+// MountResource manages a mount in the gopass configuration.
+type MountResource struct {
+	client *GopassClient
+}
+
+// MountResourceModel describes the resource data model.
+type MountResourceModel struct {
+	ID     types.String `tfsdk:"id"`
+	Prefix types.String `tfsdk:"prefix"`
+	Path   types.String `tfsdk:"path"`
+}
+
+// NewMountResource creates a new instance.
+func NewMountResource() resource.Resource {
+	return &MountResource{}
+}
+
+func (r *MountResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mount"
+}
+
+func (r *MountResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Mounts a gopass store at a prefix in the gopass configuration, like gopass mounts add. " +
+			"Destroying it unmounts the store again, leaving the store itself alone.",
+		MarkdownDescription: `
+Mounts a gopass store at a prefix in the gopass configuration, like ` + "`gopass mounts add`" + `.
+Destroying it unmounts the store again, like ` + "`gopass mounts remove`" + `, leaving the store itself alone.
+
+## Example Usage
+
+` + "```hcl" + `
+resource "gopass_mount" "team" {
+  prefix = "team"
+  path   = "~/.local/share/gopass/stores/team"
+}
+` + "```" + `
+
+## Import
+
+Mounts are imported by their prefix:
+
+` + "```bash" + `
+tofu import gopass_mount.team "team"
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "The prefix of the mount (same as prefix attribute).",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"prefix": schema.StringAttribute{
+				Description: "Prefix the store is mounted at, e.g. team for secrets like team/db/password.",
+				MarkdownDescription: "Prefix the store is mounted at, e.g. `team` for secrets like " +
+					"`team/db/password`.",
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"path": schema.StringAttribute{
+				Description: "Directory of the store to mount. It must hold an initialized store " +
+					"(a .gpg-id or .age-recipients file). Changing it moves the mount in place.",
+				MarkdownDescription: "Directory of the store to mount. It must hold an initialized store " +
+					"(a `.gpg-id` or `.age-recipients` file). Changing it moves the mount in place.",
+				Required: true,
+			},
+		},
+	}
+}
+
+func (r *MountResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+// ValidateConfig rejects prefixes gopass cannot mount at.
+func (r *MountResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config MountResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() || config.Prefix.IsNull() || config.Prefix.IsUnknown() {
+		return
+	}
+	if err := validateMountPrefix(config.Prefix.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("prefix"), "Invalid mount prefix", err.Error())
+	}
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *MountResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data MountResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefix, dir := data.Prefix.ValueString(), data.Path.ValueString()
+	ctx = r.client.logContext(ctx)
+
+	// Never move a mount nobody planned to change
+	current, mounted, err := r.client.ConfiguredMount(prefix)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create mount", fmt.Sprintf("Could not read the gopass mounts: %s", err.Error()))
+		return
+	}
+	if mounted && current != dir {
+		resp.Diagnostics.AddAttributeError(path.Root("prefix"), "Mount already exists",
+			fmt.Sprintf("The gopass configuration already mounts %s at %q. Import it to manage it with this resource:\n\n"+
+				"  tofu import <address> %q", current, prefix, prefix))
+		return
+	}
+
+	if err := r.client.SetConfiguredMount(ctx, prefix, dir); err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to create mount"),
+			errorDetail(fmt.Sprintf("Could not mount %s at %q: %s", dir, prefix, err.Error()), err),
+		)
+		return
+	}
+	data.ID = data.Prefix
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *MountResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data MountResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	dir, mounted, err := r.client.ConfiguredMount(data.Prefix.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read mount", fmt.Sprintf("Could not read the gopass mounts: %s", err.Error()))
+		return
+	}
+	if !mounted {
+		// Unmounted outside of Terraform
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	// A mount moved outside of Terraform shows up as a change of path
+	data.Path = types.StringValue(dir)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *MountResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data MountResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefix, dir := data.Prefix.ValueString(), data.Path.ValueString()
+	if err := r.client.SetConfiguredMount(r.client.logContext(ctx), prefix, dir); err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to update mount"),
+			errorDetail(fmt.Sprintf("Could not mount %s at %q: %s", dir, prefix, err.Error()), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *MountResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data MountResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefix := data.Prefix.ValueString()
+	if err := r.client.RemoveConfiguredMount(r.client.logContext(ctx), prefix); err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to remove mount"),
+			errorDetail(fmt.Sprintf("Could not unmount %q: %s", prefix, err.Error()), err),
+		)
+	}
+}
+
+// ImportState adopts a mount of the gopass configuration.
+func (r *MountResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	prefix := normalizePath(req.ID)
+
+	dir, mounted, err := r.client.ConfiguredMount(prefix)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to import mount", fmt.Sprintf("Could not read the gopass mounts: %s", err.Error()))
+		return
+	}
+	if !mounted {
+		resp.Diagnostics.AddError("Mount not found", fmt.Sprintf("The gopass configuration mounts no store at %q", prefix))
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), prefix)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("prefix"), prefix)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("path"), dir)...)
+}
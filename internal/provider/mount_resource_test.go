@@ -0,0 +1,135 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestAccMountResource_Lifecycle(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+	client := NewGopassClient(store.Dir)
+	dir := newSubstore(t, store.Recipient)
+
+	// Create mounts the store in the gopass configuration
+	state := acc.applyResource("gopass_mount", nil, map[string]tftypes.Value{
+		"prefix": tftypes.NewValue(tftypes.String, "team"),
+		"path":   tftypes.NewValue(tftypes.String, dir),
+	})
+	if got, ok, err := client.ConfiguredMount("team"); err != nil || !ok || got != dir {
+		t.Fatalf("expected %q to be mounted, got %q, %v, %v", dir, got, ok, err)
+	}
+
+	// Update moves the mount in place
+	moved := newSubstore(t, store.Recipient)
+	state = acc.applyResource("gopass_mount", state, map[string]tftypes.Value{
+		"prefix": tftypes.NewValue(tftypes.String, "team"),
+		"path":   tftypes.NewValue(tftypes.String, moved),
+	})
+	if got, _, _ := client.ConfiguredMount("team"); got != moved {
+		t.Errorf("expected the mount to move to %q, got %q", moved, got)
+	}
+
+	// Refresh picks up a mount moved outside of Terraform
+	if err := client.SetConfiguredMount(context.Background(), "team", dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refreshed := acc.refreshResource("gopass_mount", state)
+	if refreshed == nil {
+		t.Fatal("expected the resource to still exist")
+	}
+	var attrs map[string]tftypes.Value
+	if err := refreshed.value.As(&attrs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stringAttr(t, attrs, "path"); got != dir {
+		t.Errorf("expected refresh to report path %q, got %q", dir, got)
+	}
+
+	// Destroy unmounts it
+	acc.applyResource("gopass_mount", state, nil)
+	if _, ok, _ := client.ConfiguredMount("team"); ok {
+		t.Error("expected the mount to be removed")
+	}
+	if acc.refreshResource("gopass_mount", state) != nil {
+		t.Error("expected a removed mount to be removed from state")
+	}
+}
+
+func TestAccMountResource_Existing(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+	client := NewGopassClient(store.Dir)
+	if err := client.SetConfiguredMount(context.Background(), "team", newSubstore(t, store.Recipient)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	schema := acc.schemas.ResourceSchemas["gopass_mount"]
+
+	config := acc.object(schema, map[string]tftypes.Value{
+		"prefix": tftypes.NewValue(tftypes.String, "team"),
+		"path":   tftypes.NewValue(tftypes.String, newSubstore(t, store.Recipient)),
+	})
+	prior := acc.encode(schema, tftypes.NewValue(schema.ValueType(), nil))
+	plan, err := acc.server.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         "gopass_mount",
+		PriorState:       prior,
+		ProposedNewState: acc.encode(schema, config),
+		Config:           acc.encode(schema, config),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc.checkDiags("PlanResourceChange", plan.Diagnostics)
+	applied, err := acc.server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:     "gopass_mount",
+		PriorState:   prior,
+		PlannedState: plan.PlannedState,
+		Config:       acc.encode(schema, config),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasErrorSummary(applied.Diagnostics, "Mount already exists") {
+		t.Errorf("expected mounting over another store to be refused, got %v", applied.Diagnostics)
+	}
+}
+
+func TestAccMountResource_Import(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+	client := NewGopassClient(store.Dir)
+	if err := client.SetConfiguredMount(context.Background(), "team", newSubstore(t, store.Recipient)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	imported, err := acc.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: "gopass_mount",
+		ID:       "team",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc.checkDiags("ImportResourceState", imported.Diagnostics)
+	if len(imported.ImportedResources) != 1 {
+		t.Fatalf("expected one imported resource, got %d", len(imported.ImportedResources))
+	}
+
+	missing, err := acc.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: "gopass_mount",
+		ID:       "other",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasErrorSummary(missing.Diagnostics, "Mount not found") {
+		t.Errorf("expected an unknown mount to be refused, got %v", missing.Diagnostics)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &NetrcEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &NetrcEphemeralResource{}
+)
+
+// NetrcEphemeralResource renders machine logins as .netrc content.
+type NetrcEphemeralResource struct {
+	client *GopassClient
+}
+
+// NetrcModel describes the data model.
+type NetrcModel struct {
+	Entries []NetrcEntryModel `tfsdk:"entries"`
+	Policy  types.String      `tfsdk:"policy"`
+	Content types.String      `tfsdk:"content"`
+}
+
+// NetrcEntryModel is one machine of a NetrcModel.
+type NetrcEntryModel struct {
+	Path    types.String `tfsdk:"path"`
+	Machine types.String `tfsdk:"machine"`
+	Login   types.String `tfsdk:"login"`
+}
+
+// NewNetrcEphemeralResource creates a new instance.
+func NewNetrcEphemeralResource() ephemeral.EphemeralResource {
+	return &NetrcEphemeralResource{}
+}
+
+func (r *NetrcEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_netrc"
+}
+
+func (r *NetrcEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Renders machine logins stored in gopass as the content of a .netrc file.",
+		MarkdownDescription: `
+Renders machine logins stored in gopass as the content of a ` + "`.netrc`" + ` file,
+one ` + "`machine ... login ... password ...`" + ` line per entry, as read by curl, git,
+wget and most HTTP clients.
+
+The password is the first line of each secret. Machine and login come from
+the entry's arguments, or else from the secret's ` + "`machine`" + ` (or ` + "`host`" + `,
+` + "`hostname`" + `) and ` + "`username`" + ` (or ` + "`user`" + `, ` + "`login`" + `) keys. A login
+found in neither is left out; a machine is required.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_netrc" "ci" {
+  entries = [
+    { path = "ci/registry", machine = "registry.example.com" },
+    { path = "ci/git" },
+  ]
+}
+` + "```" + `
+
+Values containing whitespace, or starting with a quote, fail the read:
+` + "`.netrc`" + ` parsers do not agree on how to escape them.
+`,
+		Attributes: map[string]schema.Attribute{
+			"entries": schema.ListNestedAttribute{
+				Description:         "Machine logins, one line each, in order.",
+				MarkdownDescription: "Machine logins, one line each, in order.",
+				Required:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path": schema.StringAttribute{
+							Description:         "Path of the secret holding the password (e.g., 'ci/registry').",
+							MarkdownDescription: "Path of the secret holding the password (e.g., `ci/registry`).",
+							Required:            true,
+							Validators:          []validator.String{secretPathValidator{}},
+						},
+						"machine": schema.StringAttribute{
+							Description: "Host name the login is for. Default: the secret's machine, host or hostname key.",
+							Optional:    true,
+						},
+						"login": schema.StringAttribute{
+							Description: "User name. Default: the secret's username, user or login key.",
+							Optional:    true,
+						},
+					},
+				},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"content": schema.StringAttribute{
+				Description:         "The .netrc content, one newline-terminated line per entry.",
+				MarkdownDescription: "The `.netrc` content, one newline-terminated line per entry.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+		},
+	}
+}
+
+func (r *NetrcEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *NetrcEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data NetrcModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if len(data.Entries) == 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("entries"), "No entries", "Configure at least one entry.")
+		return
+	}
+
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	entries := make([]netrcEntry, 0, len(data.Entries))
+	passwords := make(map[string]string, len(data.Entries))
+	var empty []string
+	for i, entry := range data.Entries {
+		secretPath := entry.Path.ValueString()
+		password, fields, err := r.client.GetSecretFields(withAccessor(ctx, "ephemeral.gopass_netrc", secretPath), secretPath, netrcKeys...)
+		if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+			return
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("entries").AtListIndex(i).AtName("path"),
+				errorSummary(err, "Failed to read secret"),
+				errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+			)
+			return
+		}
+
+		passwords[secretPath] = password
+		if password == "" {
+			empty = append(empty, secretPath)
+		}
+		entries = append(entries, netrcEntry{
+			machine:  configuredOrField(entry.Machine, fields, machineKeys),
+			login:    configuredOrField(entry.Login, fields, usernameKeys),
+			password: password,
+		})
+	}
+
+	resp.Diagnostics.Append(nonUTF8Diagnostics(passwords)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	sort.Strings(empty)
+	resp.Diagnostics.Append(r.client.emptyValueDiagnostics(empty)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	content, err := renderNetrc(entries)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("entries"), "Invalid .netrc entry", err.Error())
+		return
+	}
+
+	// Hand Terraform a copy we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.Content = types.StringValue(buffers.protect(content))
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Rendered .netrc content from gopass", map[string]interface{}{
+		"entries": len(entries),
+	})
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *NetrcEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
@@ -0,0 +1,288 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &OTPEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &OTPEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithRenew = &OTPEphemeralResource{}
+)
+
+// defaultOTPMinValidity is how long a code must stay valid unless
+// min_validity says otherwise.
+const defaultOTPMinValidity = 5 * time.Second
+
+// otpPeriodKey is the private state key recording the TOTP period in seconds.
+const otpPeriodKey = "otp_period"
+
+// OTPEphemeralResource computes the current TOTP code of a secret.
+type OTPEphemeralResource struct {
+	client *GopassClient
+}
+
+// OTPModel describes the data model.
+type OTPModel struct {
+	Path        types.String `tfsdk:"path"`
+	MinValidity types.String `tfsdk:"min_validity"`
+	Policy      types.String `tfsdk:"policy"`
+	Code        types.String `tfsdk:"code"`
+	ExpiresAt   types.String `tfsdk:"expires_at"`
+	Period      types.Int64  `tfsdk:"period"`
+	Issuer      types.String `tfsdk:"issuer"`
+	AccountName types.String `tfsdk:"account_name"`
+}
+
+// NewOTPEphemeralResource creates a new instance.
+func NewOTPEphemeralResource() ephemeral.EphemeralResource {
+	return &OTPEphemeralResource{}
+}
+
+func (r *OTPEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_otp"
+}
+
+func (r *OTPEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Computes the current TOTP code of a secret, like gopass otp.",
+		MarkdownDescription: `
+Computes the current TOTP code of a secret, like ` + "`gopass otp`" + `. The key is
+an ` + "`otpauth://`" + ` URL on any line of the secret, or a base32 secret in its
+` + "`totp`" + ` field.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_otp" "registry" {
+  path = "websites/registry.example.com"
+}
+
+provider "example" {
+  username = "deploy"
+  otp      = ephemeral.gopass_otp.registry.code
+}
+` + "```" + `
+
+## Expiry
+
+Codes expire after ` + "`period`" + ` seconds. Terraform cannot replace an ephemeral
+value while it is in use, so the resource hands out a code that is still valid
+for at least ` + "`min_validity`" + `, waiting for the next one if needed, and
+renews itself at every period boundary to keep the store open until Terraform
+closes it. Renewing does not refresh ` + "`code`" + `: the value is valid for a single
+period only, until ` + "`expires_at`" + `. Consumers that run longer than a period need
+to open the resource again.
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret holding the TOTP key (e.g., 'websites/registry.example.com').",
+				MarkdownDescription: "Path to the secret holding the TOTP key (e.g., `websites/registry.example.com`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"min_validity": schema.StringAttribute{
+				Description: "How long the code must stay valid (e.g. '10s'). If the current code expires sooner, " +
+					"the resource waits for the next one. Must be shorter than the period. Default: 5s.",
+				MarkdownDescription: "How long the code must stay valid (e.g. `10s`). If the current code expires sooner, " +
+					"the resource waits for the next one. Must be shorter than the period. Default: `5s`.",
+				Optional: true,
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"code": schema.StringAttribute{
+				Description: "The current TOTP code. It is valid for a single period only, until expires_at; " +
+					"renewing the resource does not refresh it.",
+				MarkdownDescription: "The current TOTP code. It is valid for a single period only, until `expires_at`; " +
+					"renewing the resource does not refresh it.",
+				Computed:  true,
+				Sensitive: true,
+			},
+			"expires_at": schema.StringAttribute{
+				Description: "When the code expires (RFC 3339).",
+				Computed:    true,
+			},
+			"period": schema.Int64Attribute{
+				Description: "Seconds each code is valid for.",
+				Computed:    true,
+			},
+			"issuer": schema.StringAttribute{
+				Description: "Issuer named in the otpauth URL, empty if there is none.",
+				Computed:    true,
+			},
+			"account_name": schema.StringAttribute{
+				Description: "Account named in the otpauth URL, empty if there is none.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *OTPEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *OTPEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data OTPModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	minValidity := defaultOTPMinValidity
+	if !data.MinValidity.IsNull() {
+		var err error
+		minValidity, err = time.ParseDuration(data.MinValidity.ValueString())
+		if err != nil || minValidity < 0 {
+			resp.Diagnostics.AddAttributeError(path.Root("min_validity"), "Invalid min_validity",
+				fmt.Sprintf("min_validity must be a non-negative duration such as \"10s\", got %q.", data.MinValidity.ValueString()))
+			return
+		}
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "ephemeral.gopass_otp", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	body, _, fields, err := r.client.GetSecretWithBody(ctx, secretPath)
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	key, err := findTOTPKey(body, fields)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("path"), "No TOTP key in secret",
+			fmt.Sprintf("Could not compute a TOTP code from the secret at %q: %s.", secretPath, err.Error()))
+		return
+	}
+	if minValidity >= key.period {
+		resp.Diagnostics.AddAttributeError(path.Root("min_validity"), "Invalid min_validity",
+			fmt.Sprintf("min_validity must be shorter than the %s period of the key at %q.", key.period, secretPath))
+		return
+	}
+
+	now := r.client.now()
+	code, expires := key.code(now)
+	if remaining := expires.Sub(now); remaining < minValidity {
+		tflog.Debug(ctx, "Waiting for the next TOTP code", map[string]interface{}{
+			"wait": remaining.String(),
+		})
+		if err := r.client.sleep(ctx, remaining); err != nil {
+			resp.Diagnostics.AddError("Failed to compute TOTP code", err.Error())
+			return
+		}
+		code, expires = key.code(expires)
+	}
+
+	// Hand Terraform a copy we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.Code = types.StringValue(buffers.protect(code))
+	data.ExpiresAt = types.StringValue(expires.UTC().Format(time.RFC3339))
+	data.Period = types.Int64Value(int64(key.period / time.Second))
+	data.Issuer = types.StringValue(key.issuer)
+	data.AccountName = types.StringValue(key.account)
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.RenewAt = expires
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+		period, _ := json.Marshal(data.Period.ValueInt64()) // an int always encodes
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, otpPeriodKey, period)...)
+	}
+
+	tflog.Debug(ctx, "Computed TOTP code from gopass", map[string]interface{}{
+		"path":       r.client.logPath(secretPath),
+		"expires_at": data.ExpiresAt.ValueString(),
+	})
+}
+
+// Renew keeps the resource open across code expiries, asking to be renewed
+// again at the next period boundary. Terraform offers no way to hand out the
+// new code to consumers of the open resource, so code stays the one Open
+// computed, valid for a single period; the schema says so.
+func (r *OTPEphemeralResource) Renew(ctx context.Context, req ephemeral.RenewRequest, resp *ephemeral.RenewResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	renewAt, diags := otpRenewAt(ctx, req.Private, r.client.now())
+	resp.Diagnostics.Append(diags...)
+	if renewAt.IsZero() {
+		return
+	}
+	resp.RenewAt = renewAt
+	tflog.Debug(ctx, "TOTP code expired, renewing at the end of the next one", map[string]interface{}{
+		"renew_at": renewAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// otpRenewAt returns the first period boundary after now for the period Open
+// recorded in private, or the zero time if there is none.
+func otpRenewAt(ctx context.Context, private privateGetter, now time.Time) (time.Time, diag.Diagnostics) {
+	value, diags := private.GetKey(ctx, otpPeriodKey)
+	var seconds int64
+	if diags.HasError() || value == nil || json.Unmarshal(value, &seconds) != nil || seconds <= 0 {
+		return time.Time{}, diags
+	}
+	return time.Unix((now.Unix()/seconds+1)*seconds, 0), diags
+}
+
+// Close wipes the code buffer and releases the store reference taken by Open.
+func (r *OTPEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newOTPTestClient returns a client holding the RFC 6238 SHA1 key at
+// app/totp, a clock at now and a sleep recording how long it waited.
+func newOTPTestClient(now time.Time, slept *time.Duration) *GopassClient {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"app/totp":  "s3cret\notpauth://totp/ACME:deploy?digits=8&secret=" + rfc6238Secret("SHA1") + "\n",
+		"app/plain": "s3cret\nusername: deploy\n",
+	}))
+	client.now = func() time.Time { return now }
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		*slept += d
+		return nil
+	}
+	return client
+}
+
+func TestOTPEphemeralResource_Open(t *testing.T) {
+	var slept time.Duration
+	client := newOTPTestClient(time.Unix(1111111080, 0), &slept)
+
+	resp := openConfiguredEphemeral(t, &OTPEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/totp"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data OTPModel
+	resp.Result.Get(context.Background(), &data)
+	if data.Code.ValueString() != "07081804" {
+		t.Errorf("unexpected code %q", data.Code.ValueString())
+	}
+	if data.ExpiresAt.ValueString() != "2005-03-18T01:58:30Z" || !resp.RenewAt.Equal(time.Unix(1111111110, 0)) {
+		t.Errorf("unexpected expiry %q, renew at %s", data.ExpiresAt.ValueString(), resp.RenewAt)
+	}
+	if data.Period.ValueInt64() != 30 || data.Issuer.ValueString() != "ACME" || data.AccountName.ValueString() != "deploy" {
+		t.Errorf("unexpected key details %d %q %q", data.Period.ValueInt64(), data.Issuer.ValueString(), data.AccountName.ValueString())
+	}
+	if slept != 0 {
+		t.Errorf("expected no wait, waited %s", slept)
+	}
+}
+
+func TestOTPEphemeralResource_Open_WaitsForFreshCode(t *testing.T) {
+	var slept time.Duration
+	// 1s before the code valid at 1111111109 expires
+	client := newOTPTestClient(time.Unix(1111111109, 0), &slept)
+
+	resp := openConfiguredEphemeral(t, &OTPEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path":         tftypes.NewValue(tftypes.String, "app/totp"),
+		"min_validity": tftypes.NewValue(tftypes.String, "10s"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data OTPModel
+	resp.Result.Get(context.Background(), &data)
+	if slept != time.Second {
+		t.Errorf("expected to wait 1s, waited %s", slept)
+	}
+	// RFC 6238 lists 14050471 for 1111111111, in the next period
+	if data.Code.ValueString() != "14050471" || !resp.RenewAt.Equal(time.Unix(1111111140, 0)) {
+		t.Errorf("expected the next code, got %q renewing at %s", data.Code.ValueString(), resp.RenewAt)
+	}
+}
+
+func TestOTPEphemeralResource_Open_Errors(t *testing.T) {
+	tests := map[string]struct {
+		config  map[string]tftypes.Value
+		summary string
+	}{
+		"no key": {
+			config:  map[string]tftypes.Value{"path": tftypes.NewValue(tftypes.String, "app/plain")},
+			summary: "No TOTP key in secret",
+		},
+		"missing": {
+			config:  map[string]tftypes.Value{"path": tftypes.NewValue(tftypes.String, "app/missing")},
+			summary: "Secret not found",
+		},
+		"bad min_validity": {
+			config: map[string]tftypes.Value{
+				"path":         tftypes.NewValue(tftypes.String, "app/totp"),
+				"min_validity": tftypes.NewValue(tftypes.String, "soon"),
+			},
+			summary: "Invalid min_validity",
+		},
+		"min_validity beyond period": {
+			config: map[string]tftypes.Value{
+				"path":         tftypes.NewValue(tftypes.String, "app/totp"),
+				"min_validity": tftypes.NewValue(tftypes.String, "30s"),
+			},
+			summary: "Invalid min_validity",
+		},
+	}
+	for name, tt := range tests {
+		var slept time.Duration
+		client := newOTPTestClient(time.Unix(59, 0), &slept)
+		resp := openConfiguredEphemeral(t, &OTPEphemeralResource{client: client}, tt.config)
+		if errs := resp.Diagnostics.Errors(); len(errs) != 1 || errs[0].Summary() != tt.summary {
+			t.Errorf("%s: expected %q, got %v", name, tt.summary, resp.Diagnostics)
+		}
+	}
+}
+
+func TestOTPRenewAt(t *testing.T) {
+	ctx := context.Background()
+	private := mockPrivateState{}
+
+	if at, _ := otpRenewAt(ctx, private, time.Unix(100, 0)); !at.IsZero() {
+		t.Errorf("expected no renewal without a period, got %s", at)
+	}
+
+	private[otpPeriodKey] = []byte("30")
+	for now, want := range map[int64]int64{100: 120, 119: 120, 120: 150} {
+		if at, _ := otpRenewAt(ctx, private, time.Unix(now, 0)); at.Unix() != want {
+			t.Errorf("at %d: expected renewal at %d, got %d", now, want, at.Unix())
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &PgpassEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &PgpassEphemeralResource{}
+)
+
+// PgpassEphemeralResource renders database credentials as .pgpass content.
+type PgpassEphemeralResource struct {
+	client *GopassClient
+}
+
+// PgpassModel describes the data model.
+type PgpassModel struct {
+	Entries []PgpassEntryModel `tfsdk:"entries"`
+	Policy  types.String       `tfsdk:"policy"`
+	Content types.String       `tfsdk:"content"`
+}
+
+// PgpassEntryModel is one database login of a PgpassModel.
+type PgpassEntryModel struct {
+	Path     types.String `tfsdk:"path"`
+	Host     types.String `tfsdk:"host"`
+	Port     types.String `tfsdk:"port"`
+	Database types.String `tfsdk:"database"`
+	Username types.String `tfsdk:"username"`
+}
+
+// NewPgpassEphemeralResource creates a new instance.
+func NewPgpassEphemeralResource() ephemeral.EphemeralResource {
+	return &PgpassEphemeralResource{}
+}
+
+func (r *PgpassEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_pgpass"
+}
+
+func (r *PgpassEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Renders database logins stored in gopass as the content of a PostgreSQL password file (.pgpass).",
+		MarkdownDescription: `
+Renders database logins stored in gopass as the content of a PostgreSQL
+password file (` + "`.pgpass`" + `), one line per entry, escaped the way libpq
+expects.
+
+The password is the first line of each secret. Host, port, database and
+username come from the entry's arguments, or else from the secret's ` + "`host`" + `,
+` + "`port`" + `, ` + "`database`" + ` (or ` + "`dbname`" + `) and ` + "`username`" + ` (or ` + "`user`" + `, ` + "`login`" + `)
+keys; fields found in neither match anything (` + "`*`" + `).
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_pgpass" "db" {
+  entries = [
+    { path = "infrastructure/database/admin" },
+    { path = "infrastructure/database/replica", host = "replica.internal" },
+  ]
+}
+
+resource "ssh_resource" "pgpass" {
+  # ...
+  file {
+    content     = ephemeral.gopass_pgpass.db.content
+    destination = "/home/app/.pgpass"
+    permissions = "0600"
+  }
+}
+` + "```" + `
+
+libpq ignores a password file that others can read; install it with mode 0600.
+`,
+		Attributes: map[string]schema.Attribute{
+			"entries": schema.ListNestedAttribute{
+				Description:         "Database logins, one line each, in order.",
+				MarkdownDescription: "Database logins, one line each, in order.",
+				Required:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"path": schema.StringAttribute{
+							Description:         "Path of the secret holding the password (e.g., 'infrastructure/db/admin').",
+							MarkdownDescription: "Path of the secret holding the password (e.g., `infrastructure/db/admin`).",
+							Required:            true,
+							Validators:          []validator.String{secretPathValidator{}},
+						},
+						"host": schema.StringAttribute{
+							Description: "Host name. Default: the secret's host key.",
+							Optional:    true,
+						},
+						"port": schema.StringAttribute{
+							Description: "Port. Default: the secret's port key.",
+							Optional:    true,
+						},
+						"database": schema.StringAttribute{
+							Description: "Database name. Default: the secret's database or dbname key.",
+							Optional:    true,
+						},
+						"username": schema.StringAttribute{
+							Description: "User name. Default: the secret's username, user or login key.",
+							Optional:    true,
+						},
+					},
+				},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"content": schema.StringAttribute{
+				Description:         "The .pgpass content, one newline-terminated line per entry.",
+				MarkdownDescription: "The `.pgpass` content, one newline-terminated line per entry.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+		},
+	}
+}
+
+func (r *PgpassEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *PgpassEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data PgpassModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if len(data.Entries) == 0 {
+		resp.Diagnostics.AddAttributeError(path.Root("entries"), "No entries", "Configure at least one entry.")
+		return
+	}
+
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	entries := make([]pgpassEntry, 0, len(data.Entries))
+	passwords := make(map[string]string, len(data.Entries))
+	var empty []string
+	for i, entry := range data.Entries {
+		secretPath := entry.Path.ValueString()
+		password, fields, err := r.client.GetSecretFields(withAccessor(ctx, "ephemeral.gopass_pgpass", secretPath), secretPath, connectionKeys...)
+		if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+			return
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("entries").AtListIndex(i).AtName("path"),
+				errorSummary(err, "Failed to read secret"),
+				errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+			)
+			return
+		}
+
+		passwords[secretPath] = password
+		if password == "" {
+			empty = append(empty, secretPath)
+		}
+		entries = append(entries, pgpassEntry{
+			host:     configuredOrField(entry.Host, fields, hostKeys),
+			port:     configuredOrField(entry.Port, fields, portKeys),
+			database: configuredOrField(entry.Database, fields, databaseKeys),
+			username: configuredOrField(entry.Username, fields, usernameKeys),
+			password: password,
+		})
+	}
+
+	resp.Diagnostics.Append(nonUTF8Diagnostics(passwords)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	sort.Strings(empty)
+	resp.Diagnostics.Append(r.client.emptyValueDiagnostics(empty)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	content, err := renderPgpass(entries)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("entries"), "Invalid .pgpass entry", err.Error())
+		return
+	}
+
+	// Hand Terraform a copy we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.Content = types.StringValue(buffers.protect(content))
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Rendered .pgpass content from gopass", map[string]interface{}{
+		"entries": len(entries),
+	})
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *PgpassEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
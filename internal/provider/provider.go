@@ -5,9 +5,20 @@ package provider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -18,16 +29,75 @@ import (
 var (
 	_ provider.Provider                       = &GopassProvider{}
 	_ provider.ProviderWithEphemeralResources = &GopassProvider{}
+	_ provider.ProviderWithFunctions          = &GopassProvider{}
 )
 
 // GopassProvider defines the provider implementation.
 type GopassProvider struct {
 	version string
+
+	// mu guards client, the client set up by Configure. Functions require it
+	// and fail while the provider is not configured
+	mu     sync.Mutex
+	client *GopassClient
 }
 
 // GopassProviderModel describes the provider data model.
 type GopassProviderModel struct {
-	StorePath types.String `tfsdk:"store_path"`
+	StorePath           types.String `tfsdk:"store_path"`
+	HomeDir             types.String `tfsdk:"home_dir"`
+	NonInteractive      types.Bool   `tfsdk:"non_interactive"`
+	StoreFormat         types.String `tfsdk:"store_format"`
+	WSL                 types.Bool   `tfsdk:"wsl"`
+	WSLDistribution     types.String `tfsdk:"wsl_distribution"`
+	Backend             types.String `tfsdk:"backend"`
+	MockFixture         types.String `tfsdk:"mock_fixture"`
+	Cassette            types.String `tfsdk:"cassette"`
+	MaxDecryptFailures  types.Int64  `tfsdk:"max_decrypt_failures"`
+	MaxDecryptedSecrets types.Int64  `tfsdk:"max_decrypted_secrets"`
+	PrefetchPaths       types.List   `tfsdk:"prefetch_paths"`
+	CacheSecrets        types.Bool   `tfsdk:"cache_secrets"`
+	CacheTTL            types.String `tfsdk:"cache_ttl"`
+	VerifyPaths         types.List   `tfsdk:"verify_paths"`
+	HardwareToken       types.Bool   `tfsdk:"hardware_token"`
+	MetricsSummary      types.Bool   `tfsdk:"metrics_summary"`
+	OTLPEndpoint        types.String `tfsdk:"otlp_endpoint"`
+	Mounts              types.Map    `tfsdk:"mounts"`
+	ReadTimeout         types.String `tfsdk:"read_timeout"`
+	GitSync             types.Bool   `tfsdk:"git_sync"`
+	GitSyncFailure      types.String `tfsdk:"git_sync_failure"`
+	WarmUpPath          types.String `tfsdk:"warm_up_path"`
+	EmptyValue          types.String `tfsdk:"empty_value"`
+	ExpiredSecrets      types.String `tfsdk:"expired_secrets"`
+	AccessSummary       types.Bool   `tfsdk:"access_summary"`
+	HashLogPaths        types.Bool   `tfsdk:"hash_log_paths"`
+	AllowedPaths        types.List   `tfsdk:"allowed_paths"`
+	DeniedPaths         types.List   `tfsdk:"denied_paths"`
+	Policies            types.Map    `tfsdk:"policies"`
+	ReadOnly            types.Bool   `tfsdk:"read_only"`
+	AuditLog            types.String `tfsdk:"audit_log"`
+	AuditLogSigningKey  types.String `tfsdk:"audit_log_signing_key"`
+	SecureMemory        types.Bool   `tfsdk:"secure_memory"`
+	ChecksumOnly        types.Bool   `tfsdk:"checksum_only"`
+	IsolatedGnupgHome   types.String `tfsdk:"isolated_gnupg_home"`
+	RecipientPolicies   types.Map    `tfsdk:"recipient_policies"`
+	PwnedPasswordsAPI   types.Bool   `tfsdk:"pwned_passwords_api"`
+	PwnedPasswordsFile  types.String `tfsdk:"pwned_passwords_file"`
+	AgeIdentitiesFile   types.String `tfsdk:"age_identities_file"`
+	AgeRecipientsFile   types.String `tfsdk:"age_recipients_file"`
+	Mode                types.String `tfsdk:"mode"`
+}
+
+// PathPolicyModel describes a named path policy resources can declare.
+type PathPolicyModel struct {
+	AllowedPaths types.List `tfsdk:"allowed_paths"`
+	DeniedPaths  types.List `tfsdk:"denied_paths"`
+}
+
+// RecipientPolicyModel describes the recipients expected below a prefix.
+type RecipientPolicyModel struct {
+	Recipients    types.List  `tfsdk:"recipients"`
+	MinRecipients types.Int64 `tfsdk:"min_recipients"`
 }
 
 // New creates a new provider instance.
@@ -94,6 +164,432 @@ provider "postgresql" {
 					"configuration from `~/.config/gopass/config` or the `PASSWORD_STORE_DIR` environment variable.",
 				Optional: true,
 			},
+			"home_dir": schema.StringAttribute{
+				Description: "Home directory gopass reads its configuration from, as the GOPASS_HOMEDIR environment " +
+					"variable sets it: the configuration in .config/gopass below it selects the root store and its " +
+					"mounts. Lets CI runners and multi-store setups pick a gopass setup without changing the environment. " +
+					"Requires store_format \"gopass\" and cannot be combined with wsl.",
+				MarkdownDescription: "Home directory gopass reads its configuration from, as the `GOPASS_HOMEDIR` environment " +
+					"variable sets it: the configuration in `.config/gopass` below it selects the root store and its " +
+					"mounts. Lets CI runners and multi-store setups pick a gopass setup without changing the environment. " +
+					"Requires `store_format = \"gopass\"` and cannot be combined with `wsl`.",
+				Optional: true,
+			},
+			"non_interactive": schema.BoolAttribute{
+				Description: "Never prompt for a passphrase or PIN: gpg runs with --pinentry-mode=error, so a read " +
+					"that needs a key gpg-agent has not unlocked fails right away instead of waiting for pinentry. " +
+					"For automated runs. Default: false.",
+				MarkdownDescription: "Never prompt for a passphrase or PIN: gpg runs with `--pinentry-mode=error`, so a read " +
+					"that needs a key `gpg-agent` has not unlocked fails right away instead of waiting for pinentry. " +
+					"For automated runs. Default: `false`.",
+				Optional: true,
+			},
+			"age_identities_file": schema.StringAttribute{
+				Description: "File of age identities, as written by age-keygen, to decrypt age stores with. gopass age " +
+					"stores are then read with the age library, without the identity discovery of gopass (its " +
+					"passphrase-protected keyring, SSH keys) and without the gopass configuration: the root store is " +
+					"the one in store_path or PASSWORD_STORE_DIR. For headless runs. With store_format \"passage\" it " +
+					"replaces PASSAGE_IDENTITIES_FILE. Plugin identities are not supported. Cannot be combined with " +
+					"store_format \"pass\", wsl or home_dir.",
+				MarkdownDescription: "File of age identities, as written by `age-keygen`, to decrypt age stores with. gopass age " +
+					"stores are then read with the age library, without the identity discovery of gopass (its " +
+					"passphrase-protected keyring, SSH keys) and without the gopass configuration: the root store is " +
+					"the one in `store_path` or `PASSWORD_STORE_DIR`. For headless runs. With `store_format = \"passage\"` it " +
+					"replaces `PASSAGE_IDENTITIES_FILE`. Plugin identities are not supported. Cannot be combined with " +
+					"`store_format = \"pass\"`, `wsl` or `home_dir`.",
+				Optional: true,
+			},
+			"age_recipients_file": schema.StringAttribute{
+				Description: "File of age recipients to encrypt written secrets to, instead of the nearest " +
+					".age-recipients file (or PASSAGE_RECIPIENTS and PASSAGE_RECIPIENTS_FILE for passage stores). " +
+					"Requires age_identities_file or store_format \"passage\".",
+				MarkdownDescription: "File of age recipients to encrypt written secrets to, instead of the nearest " +
+					"`.age-recipients` file (or `PASSAGE_RECIPIENTS` and `PASSAGE_RECIPIENTS_FILE` for passage stores). " +
+					"Requires `age_identities_file` or `store_format = \"passage\"`.",
+				Optional: true,
+			},
+			"store_format": schema.StringAttribute{
+				Description: "Layout and encryption of the store: \"gopass\" (default) opens it through the gopass " +
+					"library and its configuration. \"pass\" keeps a store managed by pass byte-compatible: .gpg files " +
+					"and .gpg-id recipients handled like pass does, without reading or writing the gopass configuration; " +
+					"store_path then defaults to PASSWORD_STORE_DIR or ~/.password-store. \"passage\" reads a store " +
+					"managed by passage: age-encrypted .age files, decrypted with the identities in " +
+					"PASSAGE_IDENTITIES_FILE or ~/.passage/identities; store_path then defaults to PASSAGE_DIR or " +
+					"~/.passage/store. Mounts are stores of the same format.",
+				MarkdownDescription: "Layout and encryption of the store: `\"gopass\"` (default) opens it through the gopass " +
+					"library and its configuration. `\"pass\"` keeps a store managed by [pass](https://www.passwordstore.org/) " +
+					"byte-compatible: `.gpg` files and `.gpg-id` recipients handled like pass does, without reading or writing " +
+					"the gopass configuration; `store_path` then defaults to `PASSWORD_STORE_DIR` or `~/.password-store`. " +
+					"`\"passage\"` reads a store managed by [passage](https://github.com/FiloSottile/passage): age-encrypted " +
+					"`.age` files, decrypted with the identities in `PASSAGE_IDENTITIES_FILE` or `~/.passage/identities`; " +
+					"`store_path` then defaults to `PASSAGE_DIR` or `~/.passage/store`. `mounts` are stores of the same format.",
+				Optional: true,
+			},
+			"mode": schema.StringAttribute{
+				Description: "How to reach the store: \"library\" (default) links the gopass library, \"cli\" runs the " +
+					"gopass binary from PATH for every operation, for gopass setups the library does not fully support, " +
+					"such as custom pinentry wrappers. Requires store_format \"gopass\" and cannot be combined with " +
+					"wsl or age_identities_file.",
+				MarkdownDescription: "How to reach the store: `\"library\"` (default) links the gopass library, `\"cli\"` runs the " +
+					"`gopass` binary from `PATH` for every operation, for gopass setups the library does not fully support, " +
+					"such as custom pinentry wrappers. Requires `store_format = \"gopass\"` and cannot be combined with " +
+					"`wsl` or `age_identities_file`.",
+				Optional: true,
+			},
+			"wsl": schema.BoolAttribute{
+				Description: "Resolve secrets through the gopass command line client inside the Windows Subsystem " +
+					"for Linux, for Windows users whose store and keys live there. store_path and mounts are then paths " +
+					"inside the distribution. Requires store_format \"gopass\". Default: false.",
+				MarkdownDescription: "Resolve secrets through the gopass command line client inside the Windows Subsystem " +
+					"for Linux, for Windows users whose store and keys live there. `store_path` and `mounts` are then paths " +
+					"inside the distribution. Requires `store_format = \"gopass\"`. Default: `false`.",
+				Optional: true,
+			},
+			"wsl_distribution": schema.StringAttribute{
+				Description:         "WSL distribution to resolve secrets in when wsl is enabled. Defaults to WSL's default distribution.",
+				MarkdownDescription: "WSL distribution to resolve secrets in when `wsl` is enabled. Defaults to WSL's default distribution.",
+				Optional:            true,
+			},
+			"backend": schema.StringAttribute{
+				Description: "Backend to read and write secrets with: \"gopass\" uses the gopass store, \"mock\" an " +
+					"in-memory store seeded from mock_fixture, for tests without GPG, git or a real store. \"record\" " +
+					"uses the gopass store and records its responses to the cassette file, \"replay\" answers from " +
+					"a recorded cassette without a store. Defaults to the GOPASS_PROVIDER_BACKEND environment " +
+					"variable, or \"gopass\".",
+				MarkdownDescription: "Backend to read and write secrets with: `\"gopass\"` uses the gopass store, `\"mock\"` an " +
+					"in-memory store seeded from `mock_fixture`, for tests without GPG, git or a real store. `\"record\"` " +
+					"uses the gopass store and records its responses to the `cassette` file, `\"replay\"` answers from " +
+					"a recorded cassette without a store. Defaults to the `GOPASS_PROVIDER_BACKEND` environment " +
+					"variable, or `\"gopass\"`.",
+				Optional: true,
+			},
+			"mock_fixture": schema.StringAttribute{
+				Description: "JSON file seeding the mock backend: an object mapping secret paths to whole entries, " +
+					"e.g. {\"app/db\": \"s3cret\\nusername: admin\"}. Defaults to the GOPASS_PROVIDER_MOCK_FIXTURE " +
+					"environment variable; without either the mock store starts empty.",
+				MarkdownDescription: "JSON file seeding the mock backend: an object mapping secret paths to whole entries, " +
+					"e.g. `{\"app/db\": \"s3cret\\nusername: admin\"}`. Defaults to the `GOPASS_PROVIDER_MOCK_FIXTURE` " +
+					"environment variable; without either the mock store starts empty.",
+				Optional: true,
+			},
+			"cassette": schema.StringAttribute{
+				Description: "File the record backend writes the store's responses to, and the replay backend " +
+					"answers from. It is encrypted with the passphrase in the GOPASS_PROVIDER_CASSETTE_PASSPHRASE " +
+					"environment variable. Defaults to the GOPASS_PROVIDER_CASSETTE environment variable.",
+				MarkdownDescription: "File the `record` backend writes the store's responses to, and the `replay` backend " +
+					"answers from. It is encrypted with the passphrase in the `GOPASS_PROVIDER_CASSETTE_PASSPHRASE` " +
+					"environment variable. Defaults to the `GOPASS_PROVIDER_CASSETTE` environment variable.",
+				Optional: true,
+			},
+			"max_decrypt_failures": schema.Int64Attribute{
+				Description: "Number of consecutive decryption failures after which the provider stops attempting " +
+					"further reads for the rest of the run. Remaining reads fail immediately with a summary of the " +
+					"last error instead of prompting again. Set to 0 to disable. Defaults to 3.",
+				MarkdownDescription: "Number of consecutive decryption failures after which the provider stops attempting " +
+					"further reads for the rest of the run. Remaining reads fail immediately with a summary of the " +
+					"last error instead of prompting again. Set to `0` to disable. Defaults to `3`.",
+				Optional: true,
+			},
+			"max_decrypted_secrets": schema.Int64Attribute{
+				Description: "Maximum number of distinct secrets decrypted in a single run. Reads beyond it fail with " +
+					"an error instead of decrypting, which stops a gopass_env or prefetch_paths entry that covers far " +
+					"more of the store than intended. Set to 0 to disable. Defaults to 1000.",
+				MarkdownDescription: "Maximum number of distinct secrets decrypted in a single run. Reads beyond it fail with " +
+					"an error instead of decrypting, which stops a `gopass_env` or `prefetch_paths` entry that covers far " +
+					"more of the store than intended. Set to `0` to disable. Defaults to `1000`.",
+				Optional: true,
+			},
+			"prefetch_paths": schema.ListAttribute{
+				Description: "Secret paths to decrypt in a single pass before the first secret is read. " +
+					"Entries ending in '/' include every secret below that prefix. With a hardware token this " +
+					"concentrates all PIN/touch prompts at the start of the run instead of spreading them across it.",
+				MarkdownDescription: "Secret paths to decrypt in a single pass before the first secret is read. " +
+					"Entries ending in `/` include every secret below that prefix. With a hardware token this " +
+					"concentrates all PIN/touch prompts at the start of the run instead of spreading them across it.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"cache_secrets": schema.BoolAttribute{
+				Description: "Reuse decrypted secrets for repeated reads of the same path during a run, so each " +
+					"secret is decrypted (and a hardware token touched) only once per cache_ttl. Writes through the " +
+					"provider drop the affected entries. Defaults to true.",
+				MarkdownDescription: "Reuse decrypted secrets for repeated reads of the same path during a run, so each " +
+					"secret is decrypted (and a hardware token touched) only once per `cache_ttl`. Writes through the " +
+					"provider drop the affected entries. Defaults to `true`.",
+				Optional: true,
+			},
+			"cache_ttl": schema.StringAttribute{
+				Description: "How long a decrypted secret is reused (e.g. '30s'). Changes made outside the provider " +
+					"show up after at most this long. Defaults to 5m.",
+				MarkdownDescription: "How long a decrypted secret is reused (e.g. `30s`). Changes made outside the provider " +
+					"show up after at most this long. Defaults to `5m`.",
+				Optional: true,
+			},
+			"verify_paths": schema.ListAttribute{
+				Description: "Secret paths to verify before the first secret is read, like gopass fsck: every entry " +
+					"must have a non-empty recipient file (.gpg-id or .age-recipients) and must decrypt. Entries ending " +
+					"in '/' include every secret below that prefix. If any check fails, all reads fail with a report " +
+					"of the problems.",
+				MarkdownDescription: "Secret paths to verify before the first secret is read, like `gopass fsck`: every entry " +
+					"must have a non-empty recipient file (`.gpg-id` or `.age-recipients`) and must decrypt. Entries ending " +
+					"in `/` include every secret below that prefix. If any check fails, all reads fail with a report " +
+					"of the problems.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"hardware_token": schema.BoolAttribute{
+				Description: "Whether the GPG key lives on a hardware token (YubiKey, Nitrokey, OpenPGP card). " +
+					"When enabled, decryptions are serialized to one at a time and get a longer timeout, so parallel " +
+					"resources don't race for the token. If not set, the provider enables this automatically when " +
+					"it finds smartcard key stubs in the GnuPG home directory.",
+				MarkdownDescription: "Whether the GPG key lives on a hardware token (YubiKey, Nitrokey, OpenPGP card). " +
+					"When enabled, decryptions are serialized to one at a time and get a longer timeout, so parallel " +
+					"resources don't race for the token. If not set, the provider enables this automatically when " +
+					"it finds smartcard key stubs in the GnuPG home directory.",
+				Optional: true,
+			},
+			"metrics_summary": schema.BoolAttribute{
+				Description: "Log a summary of gopass operation counts, cache hits, retries and latencies when the " +
+					"provider shuts down. Per-operation timings are always available at debug level via " +
+					"TF_LOG_PROVIDER_GOPASS_METRICS=DEBUG.",
+				MarkdownDescription: "Log a summary of gopass operation counts, cache hits, retries and latencies when the " +
+					"provider shuts down. Per-operation timings are always available at debug level via " +
+					"`TF_LOG_PROVIDER_GOPASS_METRICS=DEBUG`.",
+				Optional: true,
+			},
+			"access_summary": schema.BoolAttribute{
+				Description: "Log every secret path the run read or wrote when the provider shuts down, grouped " +
+					"by resource type and configured path, so reviewers can see which secrets a configuration " +
+					"touches. Paths are logged at info level; values never are.",
+				MarkdownDescription: "Log every secret path the run read or wrote when the provider shuts down, grouped " +
+					"by resource type and configured path, so reviewers can see which secrets a configuration " +
+					"touches. Paths are logged at info level; values never are.",
+				Optional: true,
+			},
+			"read_only": schema.BoolAttribute{
+				Description: "Refuse every change to the password stores: writes, removals, commits and git syncs. " +
+					"The check is made by the client for every mutation, so no resource can write through a read-only " +
+					"provider. Defaults to false.",
+				MarkdownDescription: "Refuse every change to the password stores: writes, removals, commits and git syncs. " +
+					"The check is made by the client for every mutation, so no resource can write through a read-only " +
+					"provider. Defaults to `false`.",
+				Optional: true,
+			},
+			"isolated_gnupg_home": schema.StringAttribute{
+				Description: "Directory holding a GnuPG keyring (e.g. pubring.kbx, trustdb.gpg and private-keys-v1.d). " +
+					"Its files are copied into a temporary GNUPGHOME used for the run, which is deleted when the provider " +
+					"closes its stores, so CI runners never keep agent-cached passphrases between jobs.",
+				MarkdownDescription: "Directory holding a GnuPG keyring (e.g. `pubring.kbx`, `trustdb.gpg` and `private-keys-v1.d`). " +
+					"Its files are copied into a temporary `GNUPGHOME` used for the run, which is deleted when the provider " +
+					"closes its stores, so CI runners never keep agent-cached passphrases between jobs.",
+				Optional: true,
+			},
+			"checksum_only": schema.BoolAttribute{
+				Description: "Refuse every read of secret content, so that only checksums, existence and metadata " +
+					"are available, e.g. through the gopass_secret_checksum data source. Meant for plan-only " +
+					"pipelines that must verify secrets without being able to see them. Defaults to false.",
+				MarkdownDescription: "Refuse every read of secret content, so that only checksums, existence and metadata " +
+					"are available, e.g. through the `gopass_secret_checksum` data source. Meant for plan-only " +
+					"pipelines that must verify secrets without being able to see them. Defaults to `false`.",
+				Optional: true,
+			},
+			"allowed_paths": schema.ListAttribute{
+				Description: "Secret paths resources may access. Entries ending in '/' include every secret below " +
+					"that prefix, other entries match exactly. If not set, every path not in denied_paths is allowed.",
+				MarkdownDescription: "Secret paths resources may access. Entries ending in `/` include every secret below " +
+					"that prefix, other entries match exactly. If not set, every path not in `denied_paths` is allowed.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"denied_paths": schema.ListAttribute{
+				Description: "Secret paths no resource may access, in the same format as allowed_paths. " +
+					"Denied paths win over allowed ones.",
+				MarkdownDescription: "Secret paths no resource may access, in the same format as `allowed_paths`. " +
+					"Denied paths win over allowed ones.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"policies": schema.MapNestedAttribute{
+				Description: "Named path policies, which resources opt into with their policy attribute. A resource " +
+					"running under a policy may only access paths both the policy and the provider-level lists allow, " +
+					"so a shared module cannot read outside its contract. Violations fail the access and are logged " +
+					"per resource.",
+				MarkdownDescription: "Named path policies, which resources opt into with their `policy` attribute. A resource " +
+					"running under a policy may only access paths both the policy and the provider-level lists allow, " +
+					"so a shared module cannot read outside its contract. Violations fail the access and are logged " +
+					"per resource.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"allowed_paths": schema.ListAttribute{
+							Description:         "Secret paths resources under this policy may access, in the format of the provider's allowed_paths.",
+							MarkdownDescription: "Secret paths resources under this policy may access, in the format of the provider's `allowed_paths`.",
+							ElementType:         types.StringType,
+							Optional:            true,
+						},
+						"denied_paths": schema.ListAttribute{
+							Description:         "Secret paths resources under this policy may not access.",
+							MarkdownDescription: "Secret paths resources under this policy may not access.",
+							ElementType:         types.StringType,
+							Optional:            true,
+						},
+					},
+				},
+			},
+			"recipient_policies": schema.MapNestedAttribute{
+				Description: "Recipients secrets must be encrypted to, keyed by path prefix ('/' for the whole store). " +
+					"Before every write the recipient file that applies to the target (.gpg-id or .age-recipients) " +
+					"is checked against the longest matching prefix, and the write is refused if it does not match.",
+				MarkdownDescription: "Recipients secrets must be encrypted to, keyed by path prefix (`/` for the whole store). " +
+					"Before every write the recipient file that applies to the target (`.gpg-id` or `.age-recipients`) " +
+					"is checked against the longest matching prefix, and the write is refused if it does not match.",
+				Optional: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"recipients": schema.ListAttribute{
+							Description: "The exact set of recipients (gpg key IDs or fingerprints, age recipients). " +
+								"Key IDs match case-insensitively, with or without 0x.",
+							MarkdownDescription: "The exact set of recipients (gpg key IDs or fingerprints, age recipients). " +
+								"Key IDs match case-insensitively, with or without `0x`.",
+							ElementType: types.StringType,
+							Optional:    true,
+						},
+						"min_recipients": schema.Int64Attribute{
+							Description: "The minimum number of recipients.",
+							Optional:    true,
+						},
+					},
+				},
+			},
+			"pwned_passwords_api": schema.BoolAttribute{
+				Description: "Refuse to write passwords found in the Have I Been Pwned corpus, checked online. Only the first " +
+					"five hex digits of the password's SHA-1 leave the machine (k-anonymity). If the service cannot be " +
+					"reached, writes fail. Defaults to false.",
+				MarkdownDescription: "Refuse to write passwords found in the Have I Been Pwned corpus, checked online. Only the first " +
+					"five hex digits of the password's SHA-1 leave the machine (k-anonymity). If the service cannot be " +
+					"reached, writes fail. Defaults to `false`.",
+				Optional: true,
+			},
+			"pwned_passwords_file": schema.StringAttribute{
+				Description: "Refuse to write passwords listed in this local copy of the Pwned Passwords corpus: " +
+					"SHA-1 hashes ordered by hash, one HASH:COUNT per line, as published by Have I Been Pwned.",
+				MarkdownDescription: "Refuse to write passwords listed in this local copy of the Pwned Passwords corpus: " +
+					"SHA-1 hashes ordered by hash, one `HASH:COUNT` per line, as published by Have I Been Pwned.",
+				Optional: true,
+			},
+			"audit_log": schema.StringAttribute{
+				Description: "File to append a JSON line to for every secret read, write and removal, with the " +
+					"resource, path and outcome but never the value. Each record contains the SHA-256 of the " +
+					"previous one, so records removed or edited afterwards break the chain. Check a log with " +
+					"terraform-provider-gopass -verify-audit-log <file>.",
+				MarkdownDescription: "File to append a JSON line to for every secret read, write and removal, with the " +
+					"resource, path and outcome but never the value. Each record contains the SHA-256 of the " +
+					"previous one, so records removed or edited afterwards break the chain. Check a log with " +
+					"`terraform-provider-gopass -verify-audit-log <file>`.",
+				Optional: true,
+			},
+			"audit_log_signing_key": schema.StringAttribute{
+				Description: "PEM file with the runner's Ed25519 private key (PKCS #8). When set, the provider appends " +
+					"a checkpoint to audit_log at shutdown that signs the records of the run, so they are attributable " +
+					"to the key and cannot be rewritten without it. Check with terraform-provider-gopass " +
+					"-verify-audit-log <file> -audit-log-key <public key file>.",
+				MarkdownDescription: "PEM file with the runner's Ed25519 private key (PKCS #8). When set, the provider appends " +
+					"a checkpoint to `audit_log` at shutdown that signs the records of the run, so they are attributable " +
+					"to the key and cannot be rewritten without it. Check with `terraform-provider-gopass " +
+					"-verify-audit-log <file> -audit-log-key <public key file>`.",
+				Optional: true,
+			},
+			"secure_memory": schema.BoolAttribute{
+				Description: "Keep secrets decrypted by prefetch_paths in memory locked into RAM, so they are never " +
+					"swapped to disk, and wipe it when the cache is dropped. Where the platform or the locked memory " +
+					"limit does not allow this, the provider warns and uses regular memory. The secret cache " +
+					"(cache_secrets) uses locked memory too and skips secrets it cannot lock. The values ephemeral " +
+					"resources hand to OpenTofu are locked as well, and wiped and unlocked when they are closed. " +
+					"Defaults to false.",
+				MarkdownDescription: "Keep secrets decrypted by `prefetch_paths` in memory locked into RAM, so they are never " +
+					"swapped to disk, and wipe it when the cache is dropped. Where the platform or the locked memory " +
+					"limit does not allow this, the provider warns and uses regular memory. The secret cache " +
+					"(`cache_secrets`) uses locked memory too and skips secrets it cannot lock. The values ephemeral " +
+					"resources hand to OpenTofu are locked as well, and wiped and unlocked when they are closed. " +
+					"Defaults to `false`.",
+				Optional: true,
+			},
+			"mounts": schema.MapAttribute{
+				Description: "Additional password stores mounted below a path prefix, as a map of prefix to store " +
+					"directory (e.g. { \"team\" = \"~/.password-store-team\" }). Secrets below a prefix are read " +
+					"through that store's own handle, which is opened on first use independently of the root store.",
+				MarkdownDescription: "Additional password stores mounted below a path prefix, as a map of prefix to store " +
+					"directory (e.g. `{ team = \"~/.password-store-team\" }`). Secrets below a prefix are read " +
+					"through that store's own handle, which is opened on first use independently of the root store.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"otlp_endpoint": schema.StringAttribute{
+				Description: "Base URL of an OpenTelemetry collector (OTLP/HTTP, e.g. http://localhost:4318). " +
+					"When set, the provider exports a span for every store initialization, read, listing and " +
+					"write. Secret paths are recorded only as a truncated SHA-256 hash. Disabled if not set.",
+				MarkdownDescription: "Base URL of an OpenTelemetry collector (OTLP/HTTP, e.g. `http://localhost:4318`). " +
+					"When set, the provider exports a span for every store initialization, read, listing and " +
+					"write. Secret paths are recorded only as a truncated SHA-256 hash. Disabled if not set.",
+				Optional: true,
+			},
+			"git_sync": schema.BoolAttribute{
+				Description: "Pull from and push to the stores' git remotes when a store is first opened, so reads " +
+					"see the latest contents. Defaults to false.",
+				MarkdownDescription: "Pull from and push to the stores' git remotes when a store is first opened, so reads " +
+					"see the latest contents. Defaults to `false`.",
+				Optional: true,
+			},
+			"git_sync_failure": schema.StringAttribute{
+				Description: "What to do when git_sync cannot reach a remote: \"warn\" continues with the local " +
+					"store contents and emits a warning, \"error\" fails the operation. Defaults to \"warn\".",
+				MarkdownDescription: "What to do when `git_sync` cannot reach a remote: `\"warn\"` continues with the local " +
+					"store contents and emits a warning, `\"error\"` fails the operation. Defaults to `\"warn\"`.",
+				Optional: true,
+			},
+			"warm_up_path": schema.StringAttribute{
+				Description: "Secret to decrypt while the provider is configured, before any resource is read. " +
+					"This starts gpg-agent and asks for the passphrase or hardware token PIN up front, instead of " +
+					"in the middle of the plan. The value is discarded. A failed warm-up only produces a warning.",
+				MarkdownDescription: "Secret to decrypt while the provider is configured, before any resource is read. " +
+					"This starts `gpg-agent` and asks for the passphrase or hardware token PIN up front, instead of " +
+					"in the middle of the plan. The value is discarded. A failed warm-up only produces a warning.",
+				Optional: true,
+			},
+			"hash_log_paths": schema.BoolAttribute{
+				Description: "Log secret paths only as hashed identifiers (sha256:<first 16 hex digits>), for " +
+					"environments where the layout of the store is itself sensitive. Diagnostics shown to the " +
+					"user keep the plain paths. Secret values are never logged either way.",
+				MarkdownDescription: "Log secret paths only as hashed identifiers (`sha256:<first 16 hex digits>`), for " +
+					"environments where the layout of the store is itself sensitive. Diagnostics shown to the " +
+					"user keep the plain paths. Secret values are never logged either way.",
+				Optional: true,
+			},
+			"empty_value": schema.StringAttribute{
+				Description: "What to do when a secret is read with an empty password (first line), which usually " +
+					"means a malformed entry: \"warn\" emits a warning, \"error\" fails the read. Defaults to \"warn\".",
+				MarkdownDescription: "What to do when a secret is read with an empty password (first line), which usually " +
+					"means a malformed entry: `\"warn\"` emits a warning, `\"error\"` fails the read. Defaults to `\"warn\"`.",
+				Optional: true,
+			},
+			"expired_secrets": schema.StringAttribute{
+				Description: "What to do when a secret is read past the expiry stored in its 'expires' key (a date) or " +
+					"'ttl' key (a lifetime from the last write): \"warn\" emits a warning, \"error\" fails the read. " +
+					"Defaults to \"warn\".",
+				MarkdownDescription: "What to do when a secret is read past the expiry stored in its `expires` key (a date) or " +
+					"`ttl` key (a lifetime from the last write): `\"warn\"` emits a warning, `\"error\"` fails the read. " +
+					"Defaults to `\"warn\"`.",
+				Optional: true,
+			},
+			"read_timeout": schema.StringAttribute{
+				Description: "Maximum time a single secret read may take, as a Go duration (e.g. 30s, 2m). A read " +
+					"that exceeds it fails instead of stalling the run; gopass_env returns the secrets that could " +
+					"be read and warns about the rest. Set to 0 to disable. Defaults to 2m, or 5m in hardware token mode.",
+				MarkdownDescription: "Maximum time a single secret read may take, as a Go duration (e.g. `30s`, `2m`). A read " +
+					"that exceeds it fails instead of stalling the run; `gopass_env` returns the secrets that could " +
+					"be read and warns about the rest. Set to `0` to disable. Defaults to `2m`, or `5m` in hardware token mode.",
+				Optional: true,
+			},
 		},
 	}
 }
@@ -115,28 +611,773 @@ func (p *GopassProvider) Configure(ctx context.Context, req provider.ConfigureRe
 	// Create gopass client - uses native gopass library
 	client := NewGopassClient(storePath)
 
+	virtual, diags := configureBackend(client, config)
+	resp.Diagnostics.Append(diags...)
+	if !virtual {
+		resp.Diagnostics.Append(configureStoreFormat(client, config)...)
+		resp.Diagnostics.Append(configureWSL(client, config)...)
+		resp.Diagnostics.Append(configureHomeDir(client, config)...)
+		resp.Diagnostics.Append(configureAge(client, config)...)
+		resp.Diagnostics.Append(configureMode(client, config)...)
+		configureNonInteractive(client, config)
+	}
+	resp.Diagnostics.Append(configureFaults(client)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.MaxDecryptFailures.IsNull() && !config.MaxDecryptFailures.IsUnknown() {
+		maxFailures := config.MaxDecryptFailures.ValueInt64()
+		if maxFailures < 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("max_decrypt_failures"),
+				"Invalid max_decrypt_failures",
+				fmt.Sprintf("max_decrypt_failures must be 0 (disabled) or a positive number, got %d.", maxFailures),
+			)
+			return
+		}
+		client.breaker.threshold = int(maxFailures)
+	}
+
+	if !config.MaxDecryptedSecrets.IsNull() && !config.MaxDecryptedSecrets.IsUnknown() {
+		maxSecrets := config.MaxDecryptedSecrets.ValueInt64()
+		if maxSecrets < 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("max_decrypted_secrets"),
+				"Invalid max_decrypted_secrets",
+				fmt.Sprintf("max_decrypted_secrets must be 0 (disabled) or a positive number, got %d.", maxSecrets),
+			)
+			return
+		}
+		client.budget.limit = int(maxSecrets)
+	}
+
+	if !config.PrefetchPaths.IsNull() && !config.PrefetchPaths.IsUnknown() {
+		var prefetchPaths []string
+		resp.Diagnostics.Append(config.PrefetchPaths.ElementsAs(ctx, &prefetchPaths, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		client.prefetch = newPrefetcher(prefetchPaths)
+		if client.prefetch != nil {
+			client.prefetch.secure = config.SecureMemory.ValueBool()
+		}
+	}
+
+	client.secureMemory = config.SecureMemory.ValueBool()
+
+	resp.Diagnostics.Append(configureSecretCache(client, config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.IsolatedGnupgHome.IsNull() && !config.IsolatedGnupgHome.IsUnknown() {
+		keyring, err := client.expandHome(config.IsolatedGnupgHome.ValueString())
+		if err == nil {
+			var info os.FileInfo
+			if info, err = os.Stat(keyring); err == nil && !info.IsDir() {
+				err = fmt.Errorf("%s is not a directory", keyring)
+			}
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("isolated_gnupg_home"),
+				"Invalid isolated_gnupg_home",
+				fmt.Sprintf("Cannot use the keyring directory: %s.", err.Error()),
+			)
+			return
+		}
+		client.gnupg = &isolatedGnupgHome{keyring: keyring}
+	}
+
+	if !config.VerifyPaths.IsNull() && !config.VerifyPaths.IsUnknown() {
+		var verifyPaths []string
+		resp.Diagnostics.Append(config.VerifyPaths.ElementsAs(ctx, &verifyPaths, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		client.integrity = newIntegrityCheck(verifyPaths)
+	}
+
+	// Hardware token mode: explicit setting wins, otherwise auto-detect
+	var hardwareToken *bool
+	if !config.HardwareToken.IsNull() && !config.HardwareToken.IsUnknown() {
+		hardwareToken = config.HardwareToken.ValueBoolPointer()
+	}
+	client.configureHardwareToken(ctx, hardwareToken)
+
+	// An explicit read timeout wins over the hardware token default
+	if !config.ReadTimeout.IsNull() && !config.ReadTimeout.IsUnknown() {
+		timeout, err := time.ParseDuration(config.ReadTimeout.ValueString())
+		if err != nil || timeout < 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("read_timeout"),
+				"Invalid read_timeout",
+				fmt.Sprintf("read_timeout must be a non-negative duration such as \"30s\" or \"2m\", got %q.", config.ReadTimeout.ValueString()),
+			)
+			return
+		}
+		client.timeouts.Read = timeout
+	}
+
+	client.metricsSummary = config.MetricsSummary.ValueBool()
+	if config.AccessSummary.ValueBool() {
+		client.access = &accessLog{}
+	}
+	client.redactor.hashPaths = config.HashLogPaths.ValueBool()
+	client.readOnly = config.ReadOnly.ValueBool()
+	client.checksumOnly = config.ChecksumOnly.ValueBool()
+
+	if !config.AuditLog.IsNull() && !config.AuditLog.IsUnknown() {
+		auditPath, err := client.expandHome(config.AuditLog.ValueString())
+		if err == nil {
+			client.audit, err = openAuditLog(auditPath)
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("audit_log"),
+				"Invalid audit_log",
+				fmt.Sprintf("Cannot append to the audit log: %s.", err.Error()),
+			)
+			return
+		}
+	}
+
+	if !config.AuditLogSigningKey.IsNull() && !config.AuditLogSigningKey.IsUnknown() {
+		if client.audit == nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("audit_log_signing_key"),
+				"Invalid audit_log_signing_key",
+				"audit_log_signing_key signs the audit log and requires audit_log to be set.",
+			)
+			return
+		}
+		keyPath, err := client.expandHome(config.AuditLogSigningKey.ValueString())
+		if err == nil {
+			client.audit.signer, err = loadAuditSigner(keyPath)
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("audit_log_signing_key"),
+				"Invalid audit_log_signing_key",
+				fmt.Sprintf("Cannot load the audit log signing key: %s.", err.Error()),
+			)
+			return
+		}
+	}
+
+	if config.PwnedPasswordsAPI.ValueBool() {
+		client.pwned = &pwnedChecker{apiURL: pwnedPasswordsAPI, httpClient: &http.Client{Timeout: pwnedCheckTimeout}}
+	}
+	if !config.PwnedPasswordsFile.IsNull() && !config.PwnedPasswordsFile.IsUnknown() {
+		file, err := client.expandHome(config.PwnedPasswordsFile.ValueString())
+		if err == nil {
+			_, err = os.Stat(file)
+		}
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("pwned_passwords_file"),
+				"Invalid pwned_passwords_file",
+				fmt.Sprintf("Cannot read the hash list: %s.", err.Error()),
+			)
+			return
+		}
+		if client.pwned == nil {
+			client.pwned = &pwnedChecker{}
+		}
+		client.pwned.file = file
+	}
+
+	resp.Diagnostics.Append(configurePolicies(ctx, client, config)...)
+	resp.Diagnostics.Append(configureRecipientPolicies(ctx, client, config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.Mounts.IsNull() && !config.Mounts.IsUnknown() {
+		var mounts map[string]string
+		resp.Diagnostics.Append(config.Mounts.ElementsAs(ctx, &mounts, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		for prefix, dir := range mounts {
+			if normalizePath(prefix) == "" || dir == "" {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("mounts"),
+					"Invalid mount",
+					fmt.Sprintf("Mount prefixes and store directories must not be empty, got %q = %q.", prefix, dir),
+				)
+				return
+			}
+			if !virtual {
+				// The mock store and the cassette hold mounted paths as well
+				client.addStoreMount(prefix, dir)
+			}
+		}
+	}
+
+	client.sync.enabled = config.GitSync.ValueBool()
+	if !config.GitSyncFailure.IsNull() && !config.GitSyncFailure.IsUnknown() {
+		switch policy := config.GitSyncFailure.ValueString(); policy {
+		case syncFailureWarn:
+		case syncFailureError:
+			client.sync.failOnError = true
+		default:
+			resp.Diagnostics.AddAttributeError(
+				path.Root("git_sync_failure"),
+				"Invalid git_sync_failure",
+				fmt.Sprintf("git_sync_failure must be %q or %q, got %q.", syncFailureWarn, syncFailureError, policy),
+			)
+			return
+		}
+	}
+
+	if !config.EmptyValue.IsNull() && !config.EmptyValue.IsUnknown() {
+		switch policy := config.EmptyValue.ValueString(); policy {
+		case emptyValueWarn:
+		case emptyValueError:
+			client.failOnEmptyValue = true
+		default:
+			resp.Diagnostics.AddAttributeError(
+				path.Root("empty_value"),
+				"Invalid empty_value",
+				fmt.Sprintf("empty_value must be %q or %q, got %q.", emptyValueWarn, emptyValueError, policy),
+			)
+			return
+		}
+	}
+
+	if !config.ExpiredSecrets.IsNull() && !config.ExpiredSecrets.IsUnknown() {
+		switch policy := config.ExpiredSecrets.ValueString(); policy {
+		case expiredSecretWarn:
+		case expiredSecretError:
+			client.failOnExpired = true
+		default:
+			resp.Diagnostics.AddAttributeError(
+				path.Root("expired_secrets"),
+				"Invalid expired_secrets",
+				fmt.Sprintf("expired_secrets must be %q or %q, got %q.", expiredSecretWarn, expiredSecretError, policy),
+			)
+			return
+		}
+	}
+
+	if !config.OTLPEndpoint.IsNull() && !config.OTLPEndpoint.IsUnknown() {
+		endpoint := config.OTLPEndpoint.ValueString()
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("otlp_endpoint"),
+				"Invalid otlp_endpoint",
+				fmt.Sprintf("otlp_endpoint must be an http:// or https:// URL, got %q.", endpoint),
+			)
+			return
+		}
+		client.tracer = newTracer(endpoint)
+	}
+
+	if !config.WarmUpPath.IsNull() && !config.WarmUpPath.IsUnknown() {
+		warmUpPath := config.WarmUpPath.ValueString()
+		if err := client.warmUp(ctx, warmUpPath); err != nil {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root("warm_up_path"),
+				"gpg-agent warm-up failed",
+				fmt.Sprintf("Could not decrypt %q while configuring the provider: %s. "+
+					"Resources will prompt when they first read a secret.", warmUpPath, err.Error()),
+			)
+		}
+		resp.Diagnostics = client.finishDiagnostics(resp.Diagnostics)
+	}
+
 	// Make client available to data sources, resources, and ephemeral resources
 	resp.DataSourceData = client
 	resp.ResourceData = client
 	resp.EphemeralResourceData = client
+
+	// Functions have no provider data; they find the client here
+	p.mu.Lock()
+	p.client = client
+	p.mu.Unlock()
+}
+
+// functionClient returns the client functions read secrets with, nil if the
+// provider has not been configured. Functions never fall back to gopass' own
+// configuration: that would bypass the configured store, policies and
+// read_only.
+func (p *GopassProvider) functionClient() *GopassClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.client
+}
+
+// configureBackend sets up the backend selected by the configuration or the
+// environment, and reports whether it replaces the store on disk, which the
+// mock and replay backends do.
+func configureBackend(client *GopassClient, config GopassProviderModel) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	backend, fixture, cassette := os.Getenv(backendEnv), os.Getenv(mockFixtureEnv), os.Getenv(cassetteEnv)
+	if !config.Backend.IsNull() && !config.Backend.IsUnknown() {
+		backend = config.Backend.ValueString()
+	}
+	if !config.MockFixture.IsNull() && !config.MockFixture.IsUnknown() {
+		fixture = config.MockFixture.ValueString()
+	}
+	if !config.Cassette.IsNull() && !config.Cassette.IsUnknown() {
+		cassette = config.Cassette.ValueString()
+	}
+
+	if backend != backendMock && !config.MockFixture.IsNull() && !config.MockFixture.IsUnknown() {
+		diags.AddAttributeError(path.Root("mock_fixture"), "Invalid mock_fixture",
+			fmt.Sprintf("mock_fixture seeds the mock backend and requires backend = %q.", backendMock))
+		return false, diags
+	}
+	if backend != backendRecord && backend != backendReplay && !config.Cassette.IsNull() && !config.Cassette.IsUnknown() {
+		diags.AddAttributeError(path.Root("cassette"), "Invalid cassette",
+			fmt.Sprintf("cassette requires backend = %q or %q.", backendRecord, backendReplay))
+		return false, diags
+	}
+
+	switch backend {
+	case "", backendGopass:
+		return false, diags
+	case backendMock:
+		return true, configureMockBackend(client, fixture)
+	case backendRecord, backendReplay:
+		return backend == backendReplay, configureCassette(client, backend, cassette)
+	default:
+		diags.AddAttributeError(path.Root("backend"), "Invalid backend",
+			fmt.Sprintf("backend must be %q, %q, %q or %q, got %q.",
+				backendGopass, backendMock, backendRecord, backendReplay, backend))
+		return false, diags
+	}
+}
+
+// configureStoreFormat sets up opening the store in the configured format.
+func configureStoreFormat(client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.StoreFormat.IsNull() || config.StoreFormat.IsUnknown() {
+		return diags
+	}
+	switch format := config.StoreFormat.ValueString(); format {
+	case storeFormatGopass:
+	case storeFormatPass:
+		dir, err := passDefaultDir(client.userHomeDir)
+		if err != nil {
+			diags.AddAttributeError(path.Root("store_format"), "Invalid store_format", err.Error())
+			return diags
+		}
+		if client.storePath == "" {
+			client.storePath = dir
+		}
+		client.usePass()
+	case storeFormatPassage:
+		dir, identitiesFile, err := passageDefaults(client.userHomeDir)
+		if err != nil {
+			diags.AddAttributeError(path.Root("store_format"), "Invalid store_format", err.Error())
+			return diags
+		}
+		if client.storePath == "" {
+			client.storePath = dir
+		}
+		client.usePassage(identitiesFile)
+	default:
+		diags.AddAttributeError(path.Root("store_format"), "Invalid store_format",
+			fmt.Sprintf("store_format must be %q, %q or %q, got %q.", storeFormatGopass, storeFormatPass, storeFormatPassage, format))
+	}
+	return diags
+}
+
+// configureWSL sets up resolving secrets inside WSL if wsl is enabled.
+func configureWSL(client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !config.WSL.ValueBool() {
+		if !config.WSLDistribution.IsNull() && !config.WSLDistribution.IsUnknown() {
+			diags.AddAttributeError(path.Root("wsl_distribution"), "Invalid wsl_distribution",
+				"wsl_distribution requires wsl = true.")
+		}
+		return diags
+	}
+	if client.storeFormat != "" {
+		diags.AddAttributeError(path.Root("wsl"), "Invalid wsl",
+			fmt.Sprintf("wsl requires store_format = %q, got %q.", storeFormatGopass, client.storeFormat))
+		return diags
+	}
+	client.useWSL(config.WSLDistribution.ValueString())
+	return diags
+}
+
+// configureHomeDir points gopass at the configured home directory.
+func configureHomeDir(client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.HomeDir.IsNull() || config.HomeDir.IsUnknown() {
+		return diags
+	}
+	switch {
+	case config.HomeDir.ValueString() == "":
+		diags.AddAttributeError(path.Root("home_dir"), "Invalid home_dir", "home_dir must not be empty.")
+	case client.storeFormat != "":
+		diags.AddAttributeError(path.Root("home_dir"), "Invalid home_dir",
+			fmt.Sprintf("home_dir requires store_format = %q, got %q: %s stores do not read the gopass configuration.",
+				storeFormatGopass, client.storeFormat, client.storeFormat))
+	case client.wsl != nil:
+		diags.AddAttributeError(path.Root("home_dir"), "Invalid home_dir",
+			"home_dir cannot be combined with wsl; set GOPASS_HOMEDIR inside the distribution instead.")
+	default:
+		client.homeDir = config.HomeDir.ValueString()
+	}
+	return diags
+}
+
+// configureMode switches to the gopass command line client if mode is "cli".
+func configureMode(client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.Mode.IsNull() || config.Mode.IsUnknown() {
+		return diags
+	}
+	switch mode := config.Mode.ValueString(); {
+	case mode == modeLibrary:
+	case mode != modeCLI:
+		diags.AddAttributeError(path.Root("mode"), "Invalid mode",
+			fmt.Sprintf("mode must be %q or %q, got %q.", modeLibrary, modeCLI, mode))
+	case client.storeFormat != "":
+		diags.AddAttributeError(path.Root("mode"), "Invalid mode",
+			fmt.Sprintf("mode = %q requires store_format = %q, got %q.", modeCLI, storeFormatGopass, client.storeFormat))
+	case client.wsl != nil:
+		diags.AddAttributeError(path.Root("mode"), "Invalid mode",
+			fmt.Sprintf("mode = %q cannot be combined with wsl, which runs the gopass command line client already.", modeCLI))
+	case client.ageStores:
+		diags.AddAttributeError(path.Root("mode"), "Invalid mode",
+			fmt.Sprintf("mode = %q cannot be combined with age_identities_file, which reads age stores without gopass.", modeCLI))
+	default:
+		client.useCLI()
+	}
+	return diags
+}
+
+// configureAge sets up reading age stores with the configured identities and
+// encrypting to the configured recipients.
+func configureAge(client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !config.AgeIdentitiesFile.IsNull() && !config.AgeIdentitiesFile.IsUnknown() {
+		file := config.AgeIdentitiesFile.ValueString()
+		switch {
+		case file == "":
+			diags.AddAttributeError(path.Root("age_identities_file"), "Invalid age_identities_file",
+				"age_identities_file must not be empty.")
+		case client.storeFormat == storeFormatPass:
+			diags.AddAttributeError(path.Root("age_identities_file"), "Invalid age_identities_file",
+				fmt.Sprintf("age_identities_file requires store_format = %q or %q: pass stores are encrypted with GPG.",
+					storeFormatGopass, storeFormatPassage))
+		case client.wsl != nil:
+			diags.AddAttributeError(path.Root("age_identities_file"), "Invalid age_identities_file",
+				"age_identities_file cannot be combined with wsl; configure the identities of gopass inside the distribution instead.")
+		case client.homeDir != "":
+			diags.AddAttributeError(path.Root("age_identities_file"), "Invalid age_identities_file",
+				"age_identities_file cannot be combined with home_dir: age stores are then read without the gopass "+
+					"configuration. Set store_path instead.")
+		case client.storeFormat == "" && client.storeDir() == "":
+			diags.AddAttributeError(path.Root("age_identities_file"), "Invalid age_identities_file",
+				"age_identities_file requires store_path or PASSWORD_STORE_DIR: age stores are then read without the "+
+					"gopass configuration, which is where gopass would find the store.")
+		default:
+			expanded, err := client.expandHome(file)
+			if err == nil {
+				_, err = os.Stat(expanded)
+			}
+			if err != nil {
+				diags.AddAttributeError(path.Root("age_identities_file"), "Invalid age_identities_file",
+					fmt.Sprintf("Cannot use the identities file: %s.", err.Error()))
+				return diags
+			}
+			client.useAgeIdentities(file)
+		}
+	}
+
+	if !config.AgeRecipientsFile.IsNull() && !config.AgeRecipientsFile.IsUnknown() {
+		switch {
+		case config.AgeRecipientsFile.ValueString() == "":
+			diags.AddAttributeError(path.Root("age_recipients_file"), "Invalid age_recipients_file",
+				"age_recipients_file must not be empty.")
+		case client.storeFormat != storeFormatPassage && !client.ageStores:
+			diags.AddAttributeError(path.Root("age_recipients_file"), "Invalid age_recipients_file",
+				fmt.Sprintf("age_recipients_file requires age_identities_file or store_format = %q.", storeFormatPassage))
+		default:
+			client.ageRecipients = config.AgeRecipientsFile.ValueString()
+		}
+	}
+	return diags
+}
+
+// configureSecretCache enables the secret cache unless cache_secrets is
+// false, with the cache_ttl lifetime.
+func configureSecretCache(client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !config.CacheSecrets.IsNull() && !config.CacheSecrets.ValueBool() {
+		return diags
+	}
+
+	ttl := defaultSecretCacheTTL
+	if !config.CacheTTL.IsNull() && !config.CacheTTL.IsUnknown() {
+		var err error
+		ttl, err = time.ParseDuration(config.CacheTTL.ValueString())
+		if err != nil || ttl <= 0 {
+			diags.AddAttributeError(
+				path.Root("cache_ttl"),
+				"Invalid cache_ttl",
+				fmt.Sprintf("cache_ttl must be a positive duration such as \"30s\" or \"5m\", got %q. "+
+					"Set cache_secrets = false to disable the cache.", config.CacheTTL.ValueString()),
+			)
+			return diags
+		}
+	}
+	client.cache.ttl = ttl
+	client.cache.secure = config.SecureMemory.ValueBool()
+	return diags
+}
+
+// configureNonInteractive keeps gpg from prompting if non_interactive is set.
+func configureNonInteractive(client *GopassClient, config GopassProviderModel) {
+	if !config.NonInteractive.ValueBool() {
+		return
+	}
+	client.nonInteractive = true
+	// gopass inside WSL does not see this process' environment
+	if client.wsl != nil {
+		client.wsl.env = []string{gopassGPGOptsEnv + "=" + strings.Join(nonInteractiveGPGOpts, " ")}
+	}
+}
+
+// configureMockBackend switches client to an in-memory store seeded from the
+// fixture file, if any.
+func configureMockBackend(client *GopassClient, fixture string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	store := NewMemoryStore(nil)
+	if fixture != "" {
+		fixturePath, err := client.expandHome(fixture)
+		if err == nil {
+			store, err = LoadMemoryStore(fixturePath)
+		}
+		if err != nil {
+			diags.AddAttributeError(path.Root("mock_fixture"), "Invalid mock_fixture",
+				fmt.Sprintf("Cannot load the mock backend fixture: %s.", err.Error()))
+			return diags
+		}
+	}
+
+	// Nothing of the mock backend lives on disk
+	client.storePath = ""
+	client.newStore = func(ctx context.Context) (SecretStore, error) { return store, nil }
+	return diags
+}
+
+// configureCassette sets up recording the store's responses to the cassette,
+// or replaying them from it instead of opening a store.
+func configureCassette(client *GopassClient, backend, cassette string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	passphrase := os.Getenv(cassettePassphraseEnv)
+	switch {
+	case cassette == "":
+		diags.AddAttributeError(path.Root("cassette"), "Missing cassette",
+			fmt.Sprintf("backend = %q requires the cassette file, set in cassette or the %s environment variable.",
+				backend, cassetteEnv))
+		return diags
+	case passphrase == "":
+		diags.AddAttributeError(path.Root("cassette"), "Missing cassette passphrase",
+			fmt.Sprintf("Cassettes hold secret values and are encrypted; set the passphrase in the %s environment variable.",
+				cassettePassphraseEnv))
+		return diags
+	}
+	cassettePath, err := client.expandHome(cassette)
+	if err != nil {
+		diags.AddAttributeError(path.Root("cassette"), "Invalid cassette", err.Error())
+		return diags
+	}
+
+	if backend == backendRecord {
+		if info, err := os.Stat(filepath.Dir(cassettePath)); err != nil || !info.IsDir() {
+			diags.AddAttributeError(path.Root("cassette"), "Invalid cassette",
+				fmt.Sprintf("The directory of the cassette %s does not exist.", cassettePath))
+			return diags
+		}
+		client.cassette = &cassetteRecorder{path: cassettePath, passphrase: passphrase}
+		return diags
+	}
+
+	store, err := LoadReplayStore(cassettePath, passphrase)
+	if err != nil {
+		diags.AddAttributeError(path.Root("cassette"), "Invalid cassette",
+			fmt.Sprintf("Cannot load the cassette: %s.", err.Error()))
+		return diags
+	}
+	// The cassette holds everything the run will see of the store
+	client.storePath = ""
+	client.newStore = func(ctx context.Context) (SecretStore, error) { return store, nil }
+	return diags
+}
+
+// configurePolicies sets up the provider-level path lists and the named
+// policies on client.
+func configurePolicies(ctx context.Context, client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	providerPolicy, diags := pathPolicyFrom(ctx, path.Root("allowed_paths"), config.AllowedPaths, path.Root("denied_paths"), config.DeniedPaths)
+	if diags.HasError() {
+		return diags
+	}
+	client.policies.provider = providerPolicy
+
+	if !config.Policies.IsNull() && !config.Policies.IsUnknown() {
+		var policies map[string]PathPolicyModel
+		diags.Append(config.Policies.ElementsAs(ctx, &policies, false)...)
+		if diags.HasError() {
+			return diags
+		}
+		client.policies.named = make(map[string]pathPolicy, len(policies))
+		for name, model := range policies {
+			if name == "" {
+				diags.AddAttributeError(path.Root("policies"), "Invalid policy", "Policy names must not be empty.")
+				return diags
+			}
+			attr := path.Root("policies").AtMapKey(name)
+			policy, policyDiags := pathPolicyFrom(ctx, attr.AtName("allowed_paths"), model.AllowedPaths, attr.AtName("denied_paths"), model.DeniedPaths)
+			diags.Append(policyDiags...)
+			if diags.HasError() {
+				return diags
+			}
+			client.policies.named[name] = policy
+		}
+	}
+
+	if len(providerPolicy.allowed) > 0 || len(providerPolicy.denied) > 0 || len(client.policies.named) > 0 {
+		client.policies.violations = &accessLog{}
+	}
+	return diags
+}
+
+// configureRecipientPolicies sets up the recipient checks made before writes.
+func configureRecipientPolicies(ctx context.Context, client *GopassClient, config GopassProviderModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.RecipientPolicies.IsNull() || config.RecipientPolicies.IsUnknown() {
+		return diags
+	}
+
+	var policies map[string]RecipientPolicyModel
+	diags.Append(config.RecipientPolicies.ElementsAs(ctx, &policies, false)...)
+	if diags.HasError() {
+		return diags
+	}
+	for prefix, model := range policies {
+		attr := path.Root("recipient_policies").AtMapKey(prefix)
+		rule := recipientRule{minCount: int(model.MinRecipients.ValueInt64())}
+		if trimmed := strings.Trim(prefix, "/"); trimmed != "" {
+			rule.prefix = trimmed + "/"
+		}
+
+		if !model.Recipients.IsNull() && !model.Recipients.IsUnknown() {
+			var recipients []string
+			diags.Append(model.Recipients.ElementsAs(ctx, &recipients, false)...)
+			if diags.HasError() {
+				return diags
+			}
+			rule.recipients = make([]string, 0, len(recipients))
+			for _, recipient := range recipients {
+				rule.recipients = append(rule.recipients, normalizeRecipient(recipient))
+			}
+			if len(rule.recipients) == 0 {
+				diags.AddAttributeError(attr.AtName("recipients"), "Invalid recipient policy",
+					"The recipient list must not be empty, leave it out to accept any recipients.")
+				return diags
+			}
+		}
+		if rule.recipients == nil && rule.minCount < 1 {
+			diags.AddAttributeError(attr, "Invalid recipient policy",
+				"Set recipients or a min_recipients of at least 1.")
+			return diags
+		}
+		client.recipients.add(rule)
+	}
+	return diags
+}
+
+// pathPolicyFrom reads a pair of allowed and denied path lists.
+func pathPolicyFrom(ctx context.Context, allowedAttr path.Path, allowed types.List, deniedAttr path.Path, denied types.List) (pathPolicy, diag.Diagnostics) {
+	var (
+		policy pathPolicy
+		diags  diag.Diagnostics
+	)
+	for _, list := range []struct {
+		attr   path.Path
+		value  types.List
+		target *[]string
+	}{
+		{allowedAttr, allowed, &policy.allowed},
+		{deniedAttr, denied, &policy.denied},
+	} {
+		if list.value.IsNull() || list.value.IsUnknown() {
+			continue
+		}
+		diags.Append(list.value.ElementsAs(ctx, list.target, false)...)
+		if diags.HasError() {
+			return policy, diags
+		}
+		for _, entry := range *list.target {
+			if strings.Trim(entry, "/") == "" {
+				diags.AddAttributeError(list.attr, "Invalid path policy entry",
+					fmt.Sprintf("Path policy entries must name a secret or a prefix ending in \"/\", got %q.", entry))
+				return policy, diags
+			}
+		}
+	}
+	return policy, diags
 }
 
 // Resources returns the resources this provider offers.
 func (p *GopassProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewSecretResource,
+		NewGeneratedPasswordResource,
+		NewRecipientResource,
+		NewMountResource,
 	}
 }
 
-// DataSources returns an empty slice - this provider only provides ephemeral resources.
+// DataSources returns the data sources this provider offers. None of them
+// expose secret values, which would end up in state.
 func (p *GopassProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return []func() datasource.DataSource{}
+	return []func() datasource.DataSource{
+		NewSecretChecksumDataSource,
+		NewSecretMetadataDataSource,
+		NewRevisionsDataSource,
+	}
+}
+
+// Functions returns the functions this provider offers.
+func (p *GopassProvider) Functions(ctx context.Context) []func() function.Function {
+	return []func() function.Function{
+		NewSecretFunction(p),
+	}
 }
 
 // EphemeralResources returns the ephemeral resources this provider offers.
 func (p *GopassProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
 	return []func() ephemeral.EphemeralResource{
 		NewSecretEphemeralResource,
+		NewSecretsEphemeralResource,
 		NewEnvEphemeralResource,
+		NewPgpassEphemeralResource,
+		NewNetrcEphemeralResource,
+		NewConnectionStringEphemeralResource,
+		NewKVEphemeralResource,
+		NewSOPSFileEphemeralResource,
+		NewSecretFullEphemeralResource,
+		NewOTPEphemeralResource,
+		NewBinaryEphemeralResource,
+		NewJSONEphemeralResource,
+		NewSearchEphemeralResource,
 	}
 }
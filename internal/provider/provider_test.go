@@ -6,12 +6,45 @@ package provider
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
+// newProviderConfig builds a provider configuration from the provider schema.
+// Attributes not present in values are set to null.
+func newProviderConfig(t *testing.T, p *GopassProvider, values map[string]tftypes.Value) tfsdk.Config {
+	t.Helper()
+	ctx := context.Background()
+
+	schemaResp := &provider.SchemaResponse{}
+	p.Schema(ctx, provider.SchemaRequest{}, schemaResp)
+	if schemaResp.Diagnostics.HasError() {
+		t.Fatalf("Schema() returned errors: %v", schemaResp.Diagnostics)
+	}
+
+	objectType, ok := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	if !ok {
+		t.Fatal("provider schema type is not an object")
+	}
+
+	attributes := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attrType := range objectType.AttributeTypes {
+		if value, ok := values[name]; ok {
+			attributes[name] = value
+		} else {
+			attributes[name] = tftypes.NewValue(attrType, nil)
+		}
+	}
+
+	return tfsdk.Config{
+		Schema: schemaResp.Schema,
+		Raw:    tftypes.NewValue(objectType, attributes),
+	}
+}
+
 func TestProvider(t *testing.T) {
 	// Basic provider instantiation test
 	provider := New("test")()
@@ -32,30 +65,9 @@ func TestProviderConfigure_SetsEphemeralResourceData(t *testing.T) {
 	ctx := context.Background()
 	p := &GopassProvider{version: "test"}
 
-	// Get schema first
-	schemaReq := provider.SchemaRequest{}
-	schemaResp := &provider.SchemaResponse{}
-	p.Schema(ctx, schemaReq, schemaResp)
-
-	if schemaResp.Diagnostics.HasError() {
-		t.Fatalf("Schema() returned errors: %v", schemaResp.Diagnostics)
-	}
-
-	// Create empty config using the schema
-	configValue := tftypes.NewValue(tftypes.Object{
-		AttributeTypes: map[string]tftypes.Type{
-			"store_path": tftypes.String,
-		},
-	}, map[string]tftypes.Value{
-		"store_path": tftypes.NewValue(tftypes.String, nil), // null value
-	})
-
-	// Create configure request with empty config
+	// Create configure request with empty config (all attributes null)
 	req := provider.ConfigureRequest{
-		Config: tfsdk.Config{
-			Schema: schemaResp.Schema,
-			Raw:    configValue,
-		},
+		Config: newProviderConfig(t, p, nil),
 	}
 	resp := &provider.ConfigureResponse{}
 
@@ -95,25 +107,11 @@ func TestProviderConfigure_WithStorePath(t *testing.T) {
 	ctx := context.Background()
 	p := &GopassProvider{version: "test"}
 
-	// Get schema first
-	schemaReq := provider.SchemaRequest{}
-	schemaResp := &provider.SchemaResponse{}
-	p.Schema(ctx, schemaReq, schemaResp)
-
 	// Create config with store_path set
-	configValue := tftypes.NewValue(tftypes.Object{
-		AttributeTypes: map[string]tftypes.Type{
-			"store_path": tftypes.String,
-		},
-	}, map[string]tftypes.Value{
-		"store_path": tftypes.NewValue(tftypes.String, "/tmp/test-store"),
-	})
-
 	req := provider.ConfigureRequest{
-		Config: tfsdk.Config{
-			Schema: schemaResp.Schema,
-			Raw:    configValue,
-		},
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"store_path": tftypes.NewValue(tftypes.String, "/tmp/test-store"),
+		}),
 	}
 	resp := &provider.ConfigureResponse{}
 
@@ -136,6 +134,172 @@ func TestProviderConfigure_WithStorePath(t *testing.T) {
 	}
 }
 
+func TestProviderConfigure_MaxDecryptFailures(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"max_decrypt_failures": tftypes.NewValue(tftypes.Number, 7),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Configure() returned errors: %v", resp.Diagnostics)
+	}
+
+	client, ok := resp.EphemeralResourceData.(*GopassClient)
+	if !ok || client == nil {
+		t.Fatal("EphemeralResourceData is not properly set")
+	}
+	if client.breaker.threshold != 7 {
+		t.Errorf("expected breaker threshold 7, got %d", client.breaker.threshold)
+	}
+}
+
+func TestProviderConfigure_MaxDecryptFailures_Negative(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"max_decrypt_failures": tftypes.NewValue(tftypes.Number, -1),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected error for negative max_decrypt_failures")
+	}
+}
+
+func TestProviderConfigure_PrefetchPaths(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"prefetch_paths": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+				tftypes.NewValue(tftypes.String, "infra/db/password"),
+				tftypes.NewValue(tftypes.String, "env/ci/"),
+			}),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Configure() returned errors: %v", resp.Diagnostics)
+	}
+
+	client, ok := resp.EphemeralResourceData.(*GopassClient)
+	if !ok || client == nil {
+		t.Fatal("EphemeralResourceData is not properly set")
+	}
+	if client.prefetch == nil || len(client.prefetch.paths) != 2 {
+		t.Errorf("expected prefetcher with 2 paths, got %+v", client.prefetch)
+	}
+}
+
+func TestProviderConfigure_Mounts(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"mounts": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+				"team":       tftypes.NewValue(tftypes.String, "~/.password-store-team"),
+				"team/infra": tftypes.NewValue(tftypes.String, "/srv/stores/infra"),
+			}),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Configure() returned errors: %v", resp.Diagnostics)
+	}
+
+	client, ok := resp.EphemeralResourceData.(*GopassClient)
+	if !ok || client == nil {
+		t.Fatal("EphemeralResourceData is not properly set")
+	}
+	if len(client.mounts) != 2 || client.mounts[0].prefix != "team/infra/" {
+		t.Errorf("expected 2 mounts, longest first, got %+v", client.mounts)
+	}
+}
+
+func TestProviderConfigure_Mounts_EmptyPrefix(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"mounts": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+				"/": tftypes.NewValue(tftypes.String, "/srv/store"),
+			}),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected error for empty mount prefix")
+	}
+}
+
+func TestProviderConfigure_OTLPEndpoint(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"otlp_endpoint": tftypes.NewValue(tftypes.String, "http://localhost:4318/"),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Configure() returned errors: %v", resp.Diagnostics)
+	}
+
+	client, ok := resp.EphemeralResourceData.(*GopassClient)
+	if !ok || client == nil {
+		t.Fatal("EphemeralResourceData is not properly set")
+	}
+	if client.tracer == nil || client.tracer.endpoint != "http://localhost:4318/v1/traces" {
+		t.Errorf("expected tracer exporting to /v1/traces, got %+v", client.tracer)
+	}
+}
+
+func TestProviderConfigure_OTLPEndpoint_Invalid(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"otlp_endpoint": tftypes.NewValue(tftypes.String, "localhost:4318"),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected error for otlp_endpoint without scheme")
+	}
+}
+
 func TestProvider_Metadata(t *testing.T) {
 	ctx := context.Background()
 	p := &GopassProvider{version: "0.1.0"}
@@ -170,8 +334,9 @@ func TestProvider_DataSources(t *testing.T) {
 
 	dataSources := p.DataSources(ctx)
 
-	// May be empty if no data sources defined
-	_ = dataSources
+	if len(dataSources) != 3 {
+		t.Errorf("expected 3 data sources, got %d", len(dataSources))
+	}
 }
 
 func TestProvider_EphemeralResources(t *testing.T) {
@@ -207,18 +372,175 @@ func TestGopassClient_NewGopassClient(t *testing.T) {
 	}
 }
 
-// Acceptance tests - require TF_ACC=1 and actual gopass setup
-// func TestAccSecretEphemeral_basic(t *testing.T) {
-// 	resource.Test(t, resource.TestCase{
-// 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
-// 		Steps: []resource.TestStep{
-// 			{
-// 				Config: `
-// 					ephemeral "gopass_secret" "test" {
-// 						path = "test/secret"
-// 					}
-// 				`,
-// 			},
-// 		},
-// 	})
-// }
+func TestProviderConfigure_ReadTimeout(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"read_timeout":   tftypes.NewValue(tftypes.String, "30s"),
+			"hardware_token": tftypes.NewValue(tftypes.Bool, true),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Configure() returned errors: %v", resp.Diagnostics)
+	}
+
+	client, ok := resp.EphemeralResourceData.(*GopassClient)
+	if !ok || client == nil {
+		t.Fatal("EphemeralResourceData is not properly set")
+	}
+	// The explicit setting wins over the hardware token default
+	if client.timeouts.Read != 30*time.Second {
+		t.Errorf("expected read timeout 30s, got %s", client.timeouts.Read)
+	}
+}
+
+func TestProviderConfigure_ReadTimeout_Invalid(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	for _, value := range []string{"soon", "-1s"} {
+		req := provider.ConfigureRequest{
+			Config: newProviderConfig(t, p, map[string]tftypes.Value{
+				"read_timeout": tftypes.NewValue(tftypes.String, value),
+			}),
+		}
+		resp := &provider.ConfigureResponse{}
+
+		p.Configure(ctx, req, resp)
+
+		if !resp.Diagnostics.HasError() {
+			t.Errorf("expected error for read_timeout %q", value)
+		}
+	}
+}
+
+func TestProviderConfigure_GitSync(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"git_sync":         tftypes.NewValue(tftypes.Bool, true),
+			"git_sync_failure": tftypes.NewValue(tftypes.String, "error"),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("Configure() returned errors: %v", resp.Diagnostics)
+	}
+
+	client, ok := resp.EphemeralResourceData.(*GopassClient)
+	if !ok || client == nil {
+		t.Fatal("EphemeralResourceData is not properly set")
+	}
+	if !client.sync.enabled || !client.sync.failOnError {
+		t.Errorf("expected git sync failing on errors, got %+v", client.sync)
+	}
+}
+
+func TestProviderConfigure_GitSyncFailure_Invalid(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"git_sync_failure": tftypes.NewValue(tftypes.String, "ignore"),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected error for invalid git_sync_failure")
+	}
+}
+
+func TestProviderConfigure_WarmUpFailureWarns(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	req := provider.ConfigureRequest{
+		Config: newProviderConfig(t, p, map[string]tftypes.Value{
+			"store_path":   tftypes.NewValue(tftypes.String, t.TempDir()),
+			"warm_up_path": tftypes.NewValue(tftypes.String, "app/db"),
+		}),
+	}
+	resp := &provider.ConfigureResponse{}
+
+	p.Configure(ctx, req, resp)
+
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("expected a failed warm-up not to fail Configure, got %v", resp.Diagnostics)
+	}
+	if resp.Diagnostics.WarningsCount() != 1 {
+		t.Errorf("expected a warm-up warning, got %v", resp.Diagnostics)
+	}
+	if resp.EphemeralResourceData == nil {
+		t.Error("expected the client to be configured despite the failed warm-up")
+	}
+}
+
+func TestProviderConfigure_HomeDir(t *testing.T) {
+	ctx := context.Background()
+	p := &GopassProvider{version: "test"}
+
+	tests := []struct {
+		name    string
+		config  map[string]tftypes.Value
+		wantErr string
+	}{
+		{
+			name:   "gopass store",
+			config: map[string]tftypes.Value{"home_dir": tftypes.NewValue(tftypes.String, "/srv/ci/gopass")},
+		},
+		{
+			name:    "empty",
+			config:  map[string]tftypes.Value{"home_dir": tftypes.NewValue(tftypes.String, "")},
+			wantErr: "Invalid home_dir",
+		},
+		{
+			name: "pass store",
+			config: map[string]tftypes.Value{
+				"home_dir":     tftypes.NewValue(tftypes.String, "/srv/ci/gopass"),
+				"store_format": tftypes.NewValue(tftypes.String, "pass"),
+			},
+			wantErr: "Invalid home_dir",
+		},
+		{
+			name: "wsl",
+			config: map[string]tftypes.Value{
+				"home_dir": tftypes.NewValue(tftypes.String, "/srv/ci/gopass"),
+				"wsl":      tftypes.NewValue(tftypes.Bool, true),
+			},
+			wantErr: "Invalid home_dir",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &provider.ConfigureResponse{}
+			p.Configure(ctx, provider.ConfigureRequest{Config: newProviderConfig(t, p, tt.config)}, resp)
+			if tt.wantErr != "" {
+				if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tt.wantErr {
+					t.Fatalf("expected %q, got %v", tt.wantErr, resp.Diagnostics)
+				}
+				return
+			}
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+			if client := resp.EphemeralResourceData.(*GopassClient); client.homeDir != "/srv/ci/gopass" {
+				t.Errorf("unexpected home_dir %q", client.homeDir)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ resource.Resource                = &RecipientResource{}
+	_ resource.ResourceWithConfigure   = &RecipientResource{}
+	_ resource.ResourceWithImportState = &RecipientResource{}
+	_ resource.ResourceWithModifyPlan  = &RecipientResource{}
+)
+
+// RecipientResource manages a recipient of a gopass store.
+type RecipientResource struct {
+	client *GopassClient
+}
+
+// RecipientResourceModel describes the resource data model.
+type RecipientResourceModel struct {
+	ID        types.String `tfsdk:"id"`
+	Recipient types.String `tfsdk:"recipient"`
+	Store     types.String `tfsdk:"store"`
+	Reencrypt types.Bool   `tfsdk:"reencrypt"`
+}
+
+// NewRecipientResource creates a new instance.
+func NewRecipientResource() resource.Resource {
+	return &RecipientResource{}
+}
+
+func (r *RecipientResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_recipient"
+}
+
+func (r *RecipientResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Adds a GPG or age recipient to a gopass store, or a store mounted through the provider's mounts, " +
+			"and re-encrypts the store's secrets, like gopass recipients add. Destroying it removes the recipient again.",
+		MarkdownDescription: `
+Adds a GPG or age recipient to a gopass store, or a store mounted through the provider's ` + "`mounts`" + `,
+and re-encrypts the store's secrets, like ` + "`gopass recipients add`" + `. Destroying it removes the recipient
+and re-encrypts again, like ` + "`gopass recipients remove`" + `.
+
+## Example Usage
+
+` + "```hcl" + `
+resource "gopass_recipient" "alice" {
+  recipient = "0xDEADBEEFCAFE1234"
+}
+
+resource "gopass_recipient" "ci" {
+  store     = "team"
+  recipient = "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+}
+` + "```" + `
+
+## Import
+
+Recipients are imported as ` + "`<recipient>`" + ` for the root store, ` + "`<store>:<recipient>`" + ` for a mount:
+
+` + "```bash" + `
+tofu import gopass_recipient.ci "team:age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "The recipient, prefixed with the store and \":\" for mounted stores.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"recipient": schema.StringAttribute{
+				Description: "The recipient to add: a GPG key ID or fingerprint for gpg stores, an age recipient for age stores. " +
+					"GPG keys must be in the keyring.",
+				Required: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"store": schema.StringAttribute{
+				Description:         "Prefix of a store mounted through the provider's mounts. Defaults to the root store.",
+				MarkdownDescription: "Prefix of a store mounted through the provider's `mounts`. Defaults to the root store.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"reencrypt": schema.BoolAttribute{
+				Description: "Whether to re-encrypt every secret of the store when the recipient is added or removed. " +
+					"Without it, only secrets written later are encrypted to the new set. Defaults to true.",
+				MarkdownDescription: "Whether to re-encrypt every secret of the store when the recipient is added or removed. " +
+					"Without it, only secrets written later are encrypted to the new set. Defaults to `true`.",
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(true),
+			},
+		},
+	}
+}
+
+func (r *RecipientResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+// recipientID returns the import ID of a recipient of store.
+func recipientID(store, recipient string) string {
+	if store = normalizePath(store); store != "" {
+		return store + ":" + recipient
+	}
+	return recipient
+}
+
+// storeDescription names store in messages.
+func storeDescription(store string) string {
+	if store = normalizePath(store); store != "" {
+		return fmt.Sprintf("the store mounted at %q", store)
+	}
+	return "the root store"
+}
+
+// ModifyPlan forecasts the hardware token interactions of re-encrypting the
+// store.
+//
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *RecipientResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.Equal(req.State.Raw) {
+		return
+	}
+	resp.Diagnostics.Append(r.client.interactionForecast()...)
+}
+
+// edit returns the change of the store's recipients adding or removing the
+// recipient of the model.
+func (m *RecipientResourceModel) edit(add bool) recipientEdit {
+	return recipientEdit{
+		store:     m.Store.ValueString(),
+		recipient: m.Recipient.ValueString(),
+		add:       add,
+		reencrypt: m.Reencrypt.ValueBool(),
+	}
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *RecipientResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data RecipientResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	store, recipient := data.Store.ValueString(), data.Recipient.ValueString()
+	ctx = withAccessor(ctx, "gopass_recipient", recipientID(store, recipient))
+	ctx = r.client.logContext(ctx)
+
+	count, err := r.client.EditRecipients(ctx, data.edit(true))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to add recipient"),
+			errorDetail(fmt.Sprintf("Could not add recipient %q to %s: %s", recipient, storeDescription(store), err.Error()), err),
+		)
+		return
+	}
+	tflog.Debug(ctx, "Added store recipient", map[string]interface{}{
+		"reencrypted": count,
+	})
+	data.ID = types.StringValue(recipientID(store, recipient))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *RecipientResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data RecipientResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	store, recipient := data.Store.ValueString(), data.Recipient.ValueString()
+	recipients, err := r.client.StoreRecipients(store)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to read recipients",
+			fmt.Sprintf("Could not read the recipients of %s: %s", storeDescription(store), err.Error()),
+		)
+		return
+	}
+	if !hasRecipient(recipients, recipient) {
+		// Removed outside of Terraform
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Update only changes reencrypt, which takes effect with the next change of
+// the recipients.
+//
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *RecipientResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data RecipientResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *RecipientResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data RecipientResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	store, recipient := data.Store.ValueString(), data.Recipient.ValueString()
+	ctx = withAccessor(ctx, "gopass_recipient", recipientID(store, recipient))
+	ctx = r.client.logContext(ctx)
+
+	count, err := r.client.EditRecipients(ctx, data.edit(false))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to remove recipient"),
+			errorDetail(fmt.Sprintf("Could not remove recipient %q from %s: %s", recipient, storeDescription(store), err.Error()), err),
+		)
+		return
+	}
+	tflog.Debug(ctx, "Removed store recipient", map[string]interface{}{
+		"reencrypted": count,
+	})
+}
+
+// ImportState adopts a recipient listed in a store's recipient file.
+func (r *RecipientResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	store, recipient := "", req.ID
+	if before, after, ok := strings.Cut(req.ID, ":"); ok {
+		store, recipient = before, after
+	}
+
+	recipients, err := r.client.StoreRecipients(store)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Failed to import recipient",
+			fmt.Sprintf("Could not read the recipients of %s: %s", storeDescription(store), err.Error()),
+		)
+		return
+	}
+	if !hasRecipient(recipients, recipient) {
+		resp.Diagnostics.AddError(
+			"Recipient not found",
+			fmt.Sprintf("%q is not a recipient of %s. Import recipients of mounted stores as <store>:<recipient>.",
+				recipient, storeDescription(store)),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), recipientID(store, recipient))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("recipient"), recipient)...)
+	if store != "" {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("store"), store)...)
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("reencrypt"), true)...)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"git.ingo-struck.com/opentofu/terraform-provider-gopass/gopasstest"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// decryptsWith reports whether identity can decrypt the entry at path.
+func decryptsWith(t *testing.T, store *gopasstest.Store, path string, identity age.Identity) bool {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(store.Dir, path+".age"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(content), identity)
+	if err != nil {
+		return false
+	}
+	_, err = io.ReadAll(r)
+	return err == nil
+}
+
+func TestAccRecipientResource_Lifecycle(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{
+		"app/db":  "s3cret\nusername: admin",
+		"app/api": "token",
+	})
+	acc := newAccProvider(t, store, nil)
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recipient := identity.Recipient().String()
+	recipientsFile := filepath.Join(store.Dir, ".age-recipients")
+
+	if decryptsWith(t, store, "app/db", identity) {
+		t.Fatal("expected the new recipient not to decrypt entries before it is added")
+	}
+	before, err := store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Create adds the recipient and re-encrypts the store
+	state := acc.applyResource("gopass_recipient", nil, map[string]tftypes.Value{
+		"recipient": tftypes.NewValue(tftypes.String, recipient),
+	})
+	if !strings.Contains(readFile(t, recipientsFile), recipient) {
+		t.Error("expected the recipient to be added to the recipient file")
+	}
+	for _, path := range []string{"app/db", "app/api"} {
+		if !decryptsWith(t, store, path, identity) {
+			t.Errorf("expected %s to be re-encrypted to the new recipient", path)
+		}
+	}
+	secret, err := store.Get("app/db")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(secret.Bytes(), before.Bytes()) {
+		t.Errorf("expected re-encryption to keep the entry, got %q", secret.Bytes())
+	}
+	var attrs map[string]tftypes.Value
+	if err := state.value.As(&attrs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id := stringAttr(t, attrs, "id"); id != recipient {
+		t.Errorf("unexpected id %q", id)
+	}
+
+	// Refresh keeps the resource while the recipient is listed
+	if acc.refreshResource("gopass_recipient", state) == nil {
+		t.Fatal("expected the resource to still exist")
+	}
+
+	// Destroy removes it and re-encrypts without it
+	acc.applyResource("gopass_recipient", state, nil)
+	if strings.Contains(readFile(t, recipientsFile), recipient) {
+		t.Error("expected the recipient to be removed from the recipient file")
+	}
+	if decryptsWith(t, store, "app/db", identity) {
+		t.Error("expected the removed recipient to no longer decrypt entries")
+	}
+	if acc.refreshResource("gopass_recipient", state) != nil {
+		t.Error("expected a removed recipient to be removed from state")
+	}
+}
+
+func TestAccRecipientResource_NoReencrypt(t *testing.T) {
+	store := gopasstest.New(t, map[string]string{"app/db": "s3cret"})
+	acc := newAccProvider(t, store, nil)
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc.applyResource("gopass_recipient", nil, map[string]tftypes.Value{
+		"recipient": tftypes.NewValue(tftypes.String, identity.Recipient().String()),
+		"reencrypt": tftypes.NewValue(tftypes.Bool, false),
+	})
+	if decryptsWith(t, store, "app/db", identity) {
+		t.Error("expected reencrypt = false to leave existing entries alone")
+	}
+
+	// Secrets written later are encrypted to the new set
+	store.Set("app/new", "value")
+	if !decryptsWith(t, store, "app/new", identity) {
+		t.Error("expected new entries to be encrypted to the new recipient")
+	}
+}
+
+func TestAccRecipientResource_Import(t *testing.T) {
+	store := gopasstest.New(t, nil)
+	acc := newAccProvider(t, store, nil)
+
+	imported, err := acc.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: "gopass_recipient",
+		ID:       store.Recipient,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	acc.checkDiags("ImportResourceState", imported.Diagnostics)
+	if len(imported.ImportedResources) != 1 {
+		t.Fatalf("expected one imported resource, got %d", len(imported.ImportedResources))
+	}
+
+	missing, err := acc.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: "gopass_recipient",
+		ID:       "age1notarecipientofthestore",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hasErrorSummary(missing.Diagnostics, "Recipient not found") {
+		t.Errorf("expected an unknown recipient to be refused, got %v", missing.Diagnostics)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ datasource.DataSource              = &RevisionsDataSource{}
+	_ datasource.DataSourceWithConfigure = &RevisionsDataSource{}
+)
+
+// RevisionsDataSource lists the revision history of a secret.
+type RevisionsDataSource struct {
+	client *GopassClient
+}
+
+// RevisionsModel describes the data source data model.
+type RevisionsModel struct {
+	Path      types.String `tfsdk:"path"`
+	Policy    types.String `tfsdk:"policy"`
+	Exists    types.Bool   `tfsdk:"exists"`
+	Revisions types.List   `tfsdk:"revisions"`
+}
+
+// RevisionModel describes one entry of revisions.
+type RevisionModel struct {
+	ID     types.String `tfsdk:"id"`
+	Time   types.String `tfsdk:"time"`
+	Author types.String `tfsdk:"author"`
+}
+
+// revisionAttrTypes are the attribute types of RevisionModel.
+var revisionAttrTypes = map[string]attr.Type{
+	"id":     types.StringType,
+	"time":   types.StringType,
+	"author": types.StringType,
+}
+
+// NewRevisionsDataSource creates a new instance.
+func NewRevisionsDataSource() datasource.DataSource {
+	return &RevisionsDataSource{}
+}
+
+func (d *RevisionsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_revisions"
+}
+
+func (d *RevisionsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the revision history of a secret: revision ids, commit times and authors, " +
+			"never the content of any revision.",
+		MarkdownDescription: `
+Lists the revision history of a secret: revision ids, commit times and authors,
+never the content of any revision.
+
+Times and authors come from the git history of the secret's file and are null
+for stores the provider cannot find on disk or that are not git repositories.
+Secrets in stores without any history have a single revision, ` + "`latest`" + `.
+
+## Example Usage
+
+` + "```hcl" + `
+data "gopass_revisions" "api" {
+  path = "services/api/token"
+}
+
+# Read the version before the current one
+ephemeral "gopass_secret" "api_previous" {
+  path     = "services/api/token"
+  revision = data.gopass_revisions.api.revisions[1].id
+}
+
+check "api_token_rotated" {
+  assert {
+    condition     = timecmp(data.gopass_revisions.api.revisions[0].time, timeadd(plantimestamp(), "-2160h")) > 0
+    error_message = "The API token was not rotated in the last 90 days."
+  }
+}
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret in the gopass store (e.g., 'services/api/token').",
+				MarkdownDescription: "Path to the secret in the gopass store (e.g., `services/api/token`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this data source runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this data source runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"exists": schema.BoolAttribute{
+				Description: "Whether the secret exists.",
+				Computed:    true,
+			},
+			"revisions": schema.ListNestedAttribute{
+				Description: "Revisions of the secret, newest first. Empty if the secret does not exist.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							Description: "Revision id, as the revision attribute of the gopass_secret ephemeral " +
+								"resource takes it (e.g. a git commit hash).",
+							MarkdownDescription: "Revision id, as the `revision` attribute of the `gopass_secret` " +
+								"ephemeral resource takes it (e.g. a git commit hash).",
+							Computed: true,
+						},
+						"time": schema.StringAttribute{
+							Description: "Commit time of the revision as an RFC 3339 timestamp in UTC, null if unknown.",
+							Computed:    true,
+						},
+						"author": schema.StringAttribute{
+							Description:         "Commit author as 'Name <email>', null if unknown.",
+							MarkdownDescription: "Commit author as `Name <email>`, null if unknown.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *RevisionsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = client
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (d *RevisionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = d.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data RevisionsModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "data.gopass_revisions", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = d.client.logContext(ctx)
+
+	revisions, exists, err := d.client.SecretRevisions(ctx, secretPath)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not list the revisions of secret %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	entries := make([]RevisionModel, len(revisions))
+	for i, revision := range revisions {
+		entries[i] = RevisionModel{
+			ID:     types.StringValue(revision.ID),
+			Time:   types.StringNull(),
+			Author: types.StringNull(),
+		}
+		if !revision.Time.IsZero() {
+			entries[i].Time = types.StringValue(revision.Time.UTC().Format(time.RFC3339))
+		}
+		if revision.Author != "" {
+			entries[i].Author = types.StringValue(revision.Author)
+		}
+	}
+
+	list, diags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: revisionAttrTypes}, entries)
+	resp.Diagnostics.Append(diags...)
+	data.Exists = types.BoolValue(exists)
+	data.Revisions = list
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
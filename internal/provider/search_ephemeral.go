@@ -0,0 +1,180 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var _ ephemeral.EphemeralResource = &SearchEphemeralResource{}
+
+// SearchEphemeralResource finds the secret paths matching a glob or regular
+// expression.
+type SearchEphemeralResource struct {
+	client *GopassClient
+}
+
+// SearchModel describes the data model.
+type SearchModel struct {
+	Glob   types.String `tfsdk:"glob"`
+	Regex  types.String `tfsdk:"regex"`
+	Policy types.String `tfsdk:"policy"`
+	Paths  []string     `tfsdk:"paths"`
+}
+
+// NewSearchEphemeralResource creates a new instance.
+func NewSearchEphemeralResource() ephemeral.EphemeralResource {
+	return &SearchEphemeralResource{}
+}
+
+func (r *SearchEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_search"
+}
+
+func (r *SearchEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Finds the paths of all secrets matching a glob or a regular expression. No secret is decrypted.",
+		MarkdownDescription: `
+Finds the paths of all secrets matching a glob or a regular expression, across
+directories and mounts. No secret is decrypted.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_search" "prod_passwords" {
+  glob = "services/*/prod/password"
+}
+
+ephemeral "gopass_search" "certificates" {
+  regex = "certs/.+\\.(crt|pem)"
+}
+` + "```" + `
+
+## Patterns
+
+Both patterns must match the whole path. In globs, ` + "`*`" + ` matches within
+a single directory level, ` + "`?`" + ` a single character, ` + "`[...]`" + ` a character
+class (` + "`[!...]`" + ` negated) and ` + "`**`" + ` any number of levels, so
+` + "`services/**/password`" + ` matches ` + "`services/password`" + ` as well as
+` + "`services/a/b/password`" + `. Regular expressions use Go's RE2 syntax.
+`,
+		Attributes: map[string]schema.Attribute{
+			"glob": schema.StringAttribute{
+				Description: "Glob the secret paths must match, e.g. 'services/*/prod/password'. " +
+					"Exactly one of glob and regex is required.",
+				MarkdownDescription: "Glob the secret paths must match, e.g. `services/*/prod/password`. " +
+					"Exactly one of `glob` and `regex` is required.",
+				Optional: true,
+			},
+			"regex": schema.StringAttribute{
+				Description: "Regular expression (RE2 syntax) the whole secret path must match. " +
+					"Exactly one of glob and regex is required.",
+				MarkdownDescription: "Regular expression (RE2 syntax) the whole secret path must match. " +
+					"Exactly one of `glob` and `regex` is required.",
+				Optional: true,
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Paths the policy does not allow are left out.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Paths the policy does not allow are left out.",
+				Optional: true,
+			},
+			"paths": schema.ListAttribute{
+				Description: "Sorted paths of the matching secrets.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *SearchEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *SearchEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data SearchModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var (
+		search  *secretSearch
+		pattern string
+		err     error
+	)
+	switch {
+	case data.Glob.IsNull() == data.Regex.IsNull():
+		resp.Diagnostics.AddAttributeError(path.Root("glob"), "Invalid search",
+			"Exactly one of glob and regex must be set.")
+		return
+	case !data.Glob.IsNull():
+		pattern = data.Glob.ValueString()
+		if search, err = compileGlob(pattern); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("glob"), "Invalid glob", err.Error()+".")
+			return
+		}
+	default:
+		pattern = data.Regex.ValueString()
+		if search, err = compileRegex(pattern); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("regex"), "Invalid regex",
+				fmt.Sprintf("regex %q is not a valid regular expression: %s.", pattern, err.Error()))
+			return
+		}
+	}
+
+	ctx = withAccessor(ctx, "ephemeral.gopass_search", pattern)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	paths, err := r.client.SearchSecrets(ctx, search)
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to search secrets"),
+			errorDetail(fmt.Sprintf("Could not search for secrets matching %q: %s", pattern, err.Error()), err),
+		)
+		return
+	}
+
+	data.Paths = paths
+	if data.Paths == nil {
+		data.Paths = []string{}
+	}
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+
+	tflog.Debug(ctx, "Searched secrets in gopass", map[string]interface{}{
+		"count": len(paths),
+	})
+}
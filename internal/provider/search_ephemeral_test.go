@@ -0,0 +1,72 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newSearchTestClient returns a client holding a few services' secrets.
+func newSearchTestClient() *GopassClient {
+	return NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"services/api/prod/password":     "a",
+		"services/api/staging/password":  "b",
+		"services/web/prod/password":     "c",
+		"services/web/prod/tls/cert.pem": "d",
+	}))
+}
+
+func TestSearchEphemeralResource_Open(t *testing.T) {
+	tests := map[string]struct {
+		attr, pattern string
+		want          []string
+	}{
+		"glob":  {"glob", "services/*/prod/password", []string{"services/api/prod/password", "services/web/prod/password"}},
+		"regex": {"regex", `services/[a-z]+/prod/.*\.pem`, []string{"services/web/prod/tls/cert.pem"}},
+		"none":  {"glob", "other/**", []string{}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := openConfiguredEphemeral(t, &SearchEphemeralResource{client: newSearchTestClient()}, map[string]tftypes.Value{
+				tt.attr: tftypes.NewValue(tftypes.String, tt.pattern),
+			})
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+
+			var data SearchModel
+			resp.Result.Get(context.Background(), &data)
+			if !slices.Equal(data.Paths, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, data.Paths)
+			}
+		})
+	}
+}
+
+func TestSearchEphemeralResource_Open_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		config  map[string]tftypes.Value
+		summary string
+	}{
+		"neither": {map[string]tftypes.Value{}, "Invalid search"},
+		"both": {map[string]tftypes.Value{
+			"glob":  tftypes.NewValue(tftypes.String, "a/*"),
+			"regex": tftypes.NewValue(tftypes.String, "a/.*"),
+		}, "Invalid search"},
+		"glob":  {map[string]tftypes.Value{"glob": tftypes.NewValue(tftypes.String, "a/[b")}, "Invalid glob"},
+		"regex": {map[string]tftypes.Value{"regex": tftypes.NewValue(tftypes.String, "a/(")}, "Invalid regex"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := openConfiguredEphemeral(t, &SearchEphemeralResource{client: newSearchTestClient()}, tt.config)
+			if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tt.summary {
+				t.Errorf("expected %q, got %v", tt.summary, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ datasource.DataSource              = &SecretChecksumDataSource{}
+	_ datasource.DataSourceWithConfigure = &SecretChecksumDataSource{}
+)
+
+// SecretChecksumDataSource reports whether a secret exists and outputs derived
+// from its content (checksum, length, key names, expiry), without exposing the
+// content itself.
+type SecretChecksumDataSource struct {
+	client *GopassClient
+}
+
+// SecretChecksumModel describes the data source data model.
+type SecretChecksumModel struct {
+	Path          types.String `tfsdk:"path"`
+	Policy        types.String `tfsdk:"policy"`
+	Exists        types.Bool   `tfsdk:"exists"`
+	Checksum      types.String `tfsdk:"checksum"`
+	Length        types.Int64  `tfsdk:"length"`
+	Keys          types.List   `tfsdk:"keys"`
+	Expires       types.String `tfsdk:"expires"`
+	RevisionCount types.Int64  `tfsdk:"revision_count"`
+}
+
+// NewSecretChecksumDataSource creates a new instance.
+func NewSecretChecksumDataSource() datasource.DataSource {
+	return &SecretChecksumDataSource{}
+}
+
+func (d *SecretChecksumDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secret_checksum"
+}
+
+func (d *SecretChecksumDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Checks that a secret exists and returns outputs derived from its content " +
+			"(checksum, length, key names, expiry), never the content itself.",
+		MarkdownDescription: `
+Checks that a secret exists and returns outputs derived from its content
+(checksum, length, key names, expiry), never the content itself.
+
+All outputs are computed inside the provider, so the plaintext is never handed
+to Terraform. The checksum changes whenever the secret's password or any of its
+key-value lines change, so plan-only pipelines can verify that secrets exist,
+are strong enough, have the expected keys and were rotated. It is the only way
+to read secrets from a provider with ` + "`checksum_only = true`" + `.
+
+## Example Usage
+
+` + "```hcl" + `
+data "gopass_secret_checksum" "db" {
+  path = "infrastructure/database/admin_password"
+}
+
+check "db_password_present" {
+  assert {
+    condition     = data.gopass_secret_checksum.db.exists
+    error_message = "The database password is missing from gopass."
+  }
+  assert {
+    condition     = data.gopass_secret_checksum.db.length >= 20
+    error_message = "The database password is too short."
+  }
+  assert {
+    condition     = contains(data.gopass_secret_checksum.db.keys, "username")
+    error_message = "The database secret has no username."
+  }
+}
+` + "```" + `
+
+The checksum is an unsalted SHA-256 and is stored in state: weak secrets could
+be recovered from it by guessing.
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret in the gopass store (e.g., 'infrastructure/db/password').",
+				MarkdownDescription: "Path to the secret in the gopass store (e.g., `infrastructure/db/password`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this data source runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this data source runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"exists": schema.BoolAttribute{
+				Description: "Whether the secret exists.",
+				Computed:    true,
+			},
+			"checksum": schema.StringAttribute{
+				Description:         "SHA-256 of the whole secret, as 'sha256:<hex>'. Null if the secret does not exist.",
+				MarkdownDescription: "SHA-256 of the whole secret, as `sha256:<hex>`. Null if the secret does not exist.",
+				Computed:            true,
+			},
+			"length": schema.Int64Attribute{
+				Description: "Length of the password (the first line) in characters. Null if the secret does not exist.",
+				Computed:    true,
+			},
+			"keys": schema.ListAttribute{
+				Description: "Sorted names of the secret's key-value lines, without their values. " +
+					"Null if the secret does not exist.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"expires": schema.StringAttribute{
+				Description: "When the secret expires, from its 'expires' key (a date) or 'ttl' key (a lifetime from " +
+					"the last write), as an RFC 3339 timestamp in UTC. Null if the secret has no usable expiry " +
+					"or does not exist.",
+				MarkdownDescription: "When the secret expires, from its `expires` key (a date) or `ttl` key (a lifetime from " +
+					"the last write), as an RFC 3339 timestamp in UTC. Null if the secret has no usable expiry " +
+					"or does not exist.",
+				Computed: true,
+			},
+			"revision_count": schema.Int64Attribute{
+				Description: "Number of revisions in gopass for this secret, 0 if it does not exist.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (d *SecretChecksumDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = client
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (d *SecretChecksumDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = d.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data SecretChecksumModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "data.gopass_secret_checksum", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = d.client.logContext(ctx)
+
+	digest, exists, err := d.client.SecretDigest(ctx, secretPath)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not compute the checksum of secret %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	data.Exists = types.BoolValue(exists)
+	data.Checksum = types.StringNull()
+	data.Length = types.Int64Null()
+	data.Keys = types.ListNull(types.StringType)
+	data.Expires = types.StringNull()
+	data.RevisionCount = types.Int64Value(0)
+	if exists {
+		data.Checksum = types.StringValue(digest.Checksum)
+		data.Length = types.Int64Value(int64(digest.Length))
+		keys, diags := types.ListValueFrom(ctx, types.StringType, digest.Keys)
+		resp.Diagnostics.Append(diags...)
+		data.Keys = keys
+		if !digest.Expires.IsZero() {
+			data.Expires = types.StringValue(digest.Expires.Format(time.RFC3339))
+		}
+
+		revCount, err := d.client.GetRevisionCount(ctx, secretPath)
+		if err != nil {
+			tflog.Warn(ctx, "Could not get revision count", map[string]interface{}{
+				"path":  d.client.logPath(secretPath),
+				"error": d.client.logError(err),
+			})
+			revCount = 1 // Fallback: we know it exists
+		}
+		data.RevisionCount = types.Int64Value(revCount)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
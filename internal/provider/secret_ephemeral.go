@@ -5,16 +5,22 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
-// Ensure implementation satisfies interface.
-var _ ephemeral.EphemeralResource = &SecretEphemeralResource{}
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &SecretEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &SecretEphemeralResource{}
+)
 
 // SecretEphemeralResource reads a single secret from gopass.
 type SecretEphemeralResource struct {
@@ -23,8 +29,16 @@ type SecretEphemeralResource struct {
 
 // SecretModel describes the data model.
 type SecretModel struct {
-	Path  types.String `tfsdk:"path"`
-	Value types.String `tfsdk:"value"`
+	Path            types.String `tfsdk:"path"`
+	Revision        types.String `tfsdk:"revision"`
+	Value           types.String `tfsdk:"value"`
+	Policy          types.String `tfsdk:"policy"`
+	AllowMissing    types.Bool   `tfsdk:"allow_missing"`
+	Default         types.String `tfsdk:"default"`
+	BasicAuthHeader types.String `tfsdk:"basic_auth_header"`
+	ValueURL        types.String `tfsdk:"value_urlencoded"`
+	Username        types.String `tfsdk:"username"`
+	UsernameURL     types.String `tfsdk:"username_urlencoded"`
 }
 
 // NewSecretEphemeralResource creates a new instance.
@@ -58,6 +72,64 @@ provider "example" {
 }
 ` + "```" + `
 
+## Pinning a Revision
+
+` + "`revision`" + ` reads a historical version of the secret instead of the latest
+one, e.g. to roll back to the previous password. Revision ids are the ones
+` + "`gopass history`" + ` lists, git commit hashes for git-backed stores:
+
+` + "```hcl" + `
+ephemeral "gopass_secret" "api_key_previous" {
+  path     = "services/api/token"
+  revision = "3f2c1e9"
+}
+` + "```" + `
+
+## Optional Secrets
+
+With ` + "`allow_missing`" + `, a secret that does not exist yields ` + "`default`" + `
+(null if unset) instead of failing the plan. Secrets that exist but cannot be
+decrypted still fail:
+
+` + "```hcl" + `
+ephemeral "gopass_secret" "sentry_dsn" {
+  path          = "services/sentry/dsn"
+  allow_missing = true
+  default       = ""
+}
+` + "```" + `
+
+## HTTP Basic Authentication
+
+If the secret has a ` + "`username`" + ` (or ` + "`user`" + `, ` + "`login`" + `) key,
+` + "`basic_auth_header`" + ` holds the matching ` + "`Authorization`" + ` header value:
+
+` + "```hcl" + `
+ephemeral "gopass_secret" "registry" {
+  path = "ci/registry"
+}
+
+provider "restapi" {
+  headers = {
+    Authorization = ephemeral.gopass_secret.registry.basic_auth_header
+  }
+}
+` + "```" + `
+
+## Connection Strings
+
+` + "`value_urlencoded`" + ` and ` + "`username_urlencoded`" + ` are percent-encoded for use
+in URLs and DSNs, where a password containing ` + "`@`" + `, ` + "`:`" + ` or ` + "`/`" + ` would
+otherwise break the string:
+
+` + "```hcl" + `
+locals {
+  database_url = format("postgres://%s:%s@db.internal:5432/app",
+    ephemeral.gopass_secret.db.username_urlencoded,
+    ephemeral.gopass_secret.db.value_urlencoded)
+}
+` + "```" + `
+
 ## GPG/Hardware Token
 
 If your gopass store is encrypted with a hardware token (YubiKey, Nitrokey, etc.),
@@ -69,6 +141,36 @@ Terraform operation that accesses the secret.
 				Description:         "Path to the secret in the gopass store (e.g., 'infrastructure/db/password').",
 				MarkdownDescription: "Path to the secret in the gopass store (e.g., `infrastructure/db/password`).",
 				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"revision": schema.StringAttribute{
+				Description: "Revision of the secret to read instead of the latest one: a revision id as " +
+					"'gopass history' lists it, e.g. a git commit hash. Expiry is not checked for pinned revisions.",
+				MarkdownDescription: "Revision of the secret to read instead of the latest one: a revision id as " +
+					"`gopass history` lists it, e.g. a git commit hash. Expiry is not checked for pinned revisions.",
+				Optional: true,
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"allow_missing": schema.BoolAttribute{
+				Description: "Return default instead of failing if the secret does not exist. " +
+					"Secrets that exist but cannot be read still fail. Default: false.",
+				MarkdownDescription: "Return `default` instead of failing if the secret does not exist. " +
+					"Secrets that exist but cannot be read still fail. Default: `false`.",
+				Optional: true,
+			},
+			"default": schema.StringAttribute{
+				Description: "Value of a secret that does not exist, with allow_missing. " +
+					"value is null for missing secrets if unset.",
+				MarkdownDescription: "Value of a secret that does not exist, with `allow_missing`. " +
+					"`value` is null for missing secrets if unset.",
+				Optional:  true,
+				Sensitive: true,
 			},
 			"value": schema.StringAttribute{
 				Description:         "The secret value (password/first line of the secret).",
@@ -76,6 +178,34 @@ Terraform operation that accesses the secret.
 				Computed:            true,
 				Sensitive:           true,
 			},
+			"basic_auth_header": schema.StringAttribute{
+				Description: "HTTP Authorization header value for basic authentication with the secret's " +
+					"username, user or login key and the password (\"Basic \" and base64 of username:password). " +
+					"Null if the secret has none of these keys.",
+				MarkdownDescription: "HTTP `Authorization` header value for basic authentication with the secret's " +
+					"`username`, `user` or `login` key and the password (`Basic ` and base64 of `username:password`). " +
+					"Null if the secret has none of these keys.",
+				Computed:  true,
+				Sensitive: true,
+			},
+			"value_urlencoded": schema.StringAttribute{
+				Description: "The secret value percent-encoded for use in URLs and connection strings: " +
+					"everything but letters, digits and -._~ is escaped.",
+				MarkdownDescription: "The secret value percent-encoded for use in URLs and connection strings: " +
+					"everything but letters, digits and `-._~` is escaped.",
+				Computed:  true,
+				Sensitive: true,
+			},
+			"username": schema.StringAttribute{
+				Description:         "The secret's username, user or login key. Null if it has none of them.",
+				MarkdownDescription: "The secret's `username`, `user` or `login` key. Null if it has none of them.",
+				Computed:            true,
+			},
+			"username_urlencoded": schema.StringAttribute{
+				Description:         "The username percent-encoded like value_urlencoded. Null if the secret has no username.",
+				MarkdownDescription: "The username percent-encoded like `value_urlencoded`. Null if the secret has no username.",
+				Computed:            true,
+			},
 		},
 	}
 }
@@ -98,6 +228,10 @@ func (r *SecretEphemeralResource) Configure(ctx context.Context, req ephemeral.C
 }
 
 func (r *SecretEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
 	var data SecretModel
 
 	// Read configuration
@@ -106,28 +240,129 @@ func (r *SecretEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRe
 		return
 	}
 
-	path := data.Path.ValueString()
+	revision := "latest"
+	if !data.Revision.IsNull() {
+		revision = data.Revision.ValueString()
+		if revision == "" {
+			resp.Diagnostics.AddAttributeError(path.Root("revision"), "Invalid revision",
+				"revision must not be empty. Leave it unset to read the latest revision.")
+			return
+		}
+	}
+
+	allowMissing := data.AllowMissing.ValueBool()
+	if !data.Default.IsNull() && !allowMissing {
+		resp.Diagnostics.AddAttributeError(path.Root("default"), "Invalid default",
+			"default is only used for missing secrets. Set allow_missing = true as well.")
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "ephemeral.gopass_secret", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
 
 	tflog.Debug(ctx, "Reading secret from gopass", map[string]interface{}{
-		"path": path,
+		"path":     r.client.logPath(secretPath),
+		"revision": revision,
 	})
 
 	// Use native gopass library
-	value, err := r.client.GetSecret(ctx, path)
+	value, fields, err := r.client.GetSecretAtRevision(ctx, secretPath, revision, usernameKeys...)
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil && allowMissing && errors.Is(err, ErrNotFound) {
+		tflog.Info(ctx, "Secret not found, using the default", map[string]interface{}{
+			"path": r.client.logPath(secretPath),
+		})
+		r.openDefault(ctx, resp, &data)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Failed to read secret",
-			fmt.Sprintf("Could not read secret at path %q: %s", path, err.Error()),
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
 		)
 		return
 	}
 
-	data.Value = types.StringValue(value)
+	resp.Diagnostics.Append(nonUTF8Diagnostics(map[string]string{secretPath: value})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if value == "" {
+		resp.Diagnostics.Append(r.client.emptyValueDiagnostics([]string{secretPath})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// Hand Terraform a copy we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.Value = types.StringValue(buffers.protect(value))
+	data.ValueURL = types.StringValue(buffers.protect(percentEncode(value)))
+	data.BasicAuthHeader = types.StringNull()
+	data.Username = types.StringNull()
+	data.UsernameURL = types.StringNull()
+	if username, ok := credentialField(fields, usernameKeys); ok {
+		data.Username = types.StringValue(username)
+		data.UsernameURL = types.StringValue(percentEncode(username))
+		header, err := basicAuthHeader(username, value)
+		if err != nil {
+			resp.Diagnostics.AddWarning("Basic auth header unavailable",
+				fmt.Sprintf("basic_auth_header of the secret at %q is null: %s.", secretPath, err.Error()))
+		} else {
+			data.BasicAuthHeader = types.StringValue(buffers.protect(header))
+		}
+	}
 
 	// Set result - this is NEVER written to state
 	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
 
 	tflog.Debug(ctx, "Successfully read secret from gopass", map[string]interface{}{
-		"path": path,
+		"path": r.client.logPath(secretPath),
 	})
 }
+
+// openDefault sets the result of a missing secret read with allow_missing:
+// the default, or null without one.
+func (r *SecretEphemeralResource) openDefault(ctx context.Context, resp *ephemeral.OpenResponse, data *SecretModel) {
+	buffers := r.client.newSecretBuffers()
+	data.Value = types.StringNull()
+	data.ValueURL = types.StringNull()
+	if !data.Default.IsNull() {
+		value := data.Default.ValueString()
+		data.Value = types.StringValue(buffers.protect(value))
+		data.ValueURL = types.StringValue(buffers.protect(percentEncode(value)))
+	}
+	data.BasicAuthHeader = types.StringNull()
+	data.Username = types.StringNull()
+	data.UsernameURL = types.StringNull()
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *SecretEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
@@ -0,0 +1,251 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &SecretFullEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &SecretFullEphemeralResource{}
+)
+
+// SecretFullEphemeralResource reads a whole secret: its body, password and
+// key/value fields.
+type SecretFullEphemeralResource struct {
+	client *GopassClient
+}
+
+// SecretFullModel describes the data model.
+type SecretFullModel struct {
+	Path      types.String      `tfsdk:"path"`
+	Policy    types.String      `tfsdk:"policy"`
+	ParseYAML types.Bool        `tfsdk:"parse_yaml"`
+	Password  types.String      `tfsdk:"password"`
+	Body      types.String      `tfsdk:"body"`
+	Fields    map[string]string `tfsdk:"fields"`
+	YAML      types.Dynamic     `tfsdk:"yaml"`
+}
+
+// NewSecretFullEphemeralResource creates a new instance.
+func NewSecretFullEphemeralResource() ephemeral.EphemeralResource {
+	return &SecretFullEphemeralResource{}
+}
+
+func (r *SecretFullEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secret_full"
+}
+
+func (r *SecretFullEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads a whole secret from gopass as an ephemeral value: its body, its password and all its key/value fields.",
+		MarkdownDescription: `
+Reads a whole secret from gopass as an ephemeral value: the body as stored,
+the password on its first line, and all ` + "`key: value`" + ` lines as a map.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_secret_full" "db" {
+  path = "infrastructure/database/admin"
+}
+
+provider "postgresql" {
+  username = ephemeral.gopass_secret_full.db.fields["username"]
+  password = ephemeral.gopass_secret_full.db.password
+}
+` + "```" + `
+
+## YAML Secrets
+
+With ` + "`parse_yaml = true`" + ` the lines below the password are decoded as a YAML
+document, as gopass writes YAML secrets, and returned in ` + "`yaml`" + ` for direct
+indexing:
+
+` + "```hcl" + `
+ephemeral "gopass_secret_full" "app" {
+  path       = "services/app/config"
+  parse_yaml = true
+}
+
+provider "example" {
+  endpoint = ephemeral.gopass_secret_full.app.yaml.api.endpoints[0]
+  token    = ephemeral.gopass_secret_full.app.yaml.api.token
+}
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret in the gopass store (e.g., 'infrastructure/database/admin').",
+				MarkdownDescription: "Path to the secret in the gopass store (e.g., `infrastructure/database/admin`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"parse_yaml": schema.BoolAttribute{
+				Description: "Decode the lines below the password as a YAML document into yaml. " +
+					"A body that is not valid YAML fails the read. Default: false.",
+				MarkdownDescription: "Decode the lines below the password as a YAML document into `yaml`. " +
+					"A body that is not valid YAML fails the read. Default: `false`.",
+				Optional: true,
+			},
+			"password": schema.StringAttribute{
+				Description: "The password: the first line of the secret.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"body": schema.StringAttribute{
+				Description: "The whole secret as stored: the password line, the key/value lines and any other lines.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"fields": schema.MapAttribute{
+				Description: "The key/value lines of the secret. A key appearing more than once maps to its first value.",
+				MarkdownDescription: "The `key: value` lines of the secret. A key appearing more than once maps to " +
+					"its first value.",
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"yaml": schema.DynamicAttribute{
+				Description: "The YAML document below the password with parse_yaml: mappings become objects, " +
+					"sequences tuples, and scalars strings, numbers or bools. Null without parse_yaml " +
+					"or if there is nothing below the password.",
+				MarkdownDescription: "The YAML document below the password with `parse_yaml`: mappings become objects, " +
+					"sequences tuples, and scalars strings, numbers or bools. Null without `parse_yaml` " +
+					"or if there is nothing below the password.",
+				Computed:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+func (r *SecretFullEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *SecretFullEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data SecretFullModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "ephemeral.gopass_secret_full", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	body, password, fields, err := r.client.GetSecretWithBody(ctx, secretPath)
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(nonUTF8Diagnostics(map[string]string{secretPath: body})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if password == "" {
+		resp.Diagnostics.Append(r.client.emptyValueDiagnostics([]string{secretPath})...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// Hand Terraform copies we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.Password = types.StringValue(buffers.protect(password))
+	data.Body = types.StringValue(buffers.protect(body))
+	data.Fields = make(map[string]string, len(fields))
+	for key, value := range fields {
+		data.Fields[key] = buffers.protect(value)
+	}
+
+	data.YAML = types.DynamicNull()
+	if data.ParseYAML.ValueBool() {
+		doc, err := secretYAML(body)
+		if err == nil && doc != nil {
+			var value attr.Value
+			value, err = documentValue(ctx, doc, func(s string) string {
+				r.client.redactor.addValues(s)
+				return buffers.protect(s)
+			})
+			data.YAML = types.DynamicValue(value)
+		}
+		if err != nil {
+			buffers.wipe()
+			resp.Diagnostics.AddAttributeError(path.Root("parse_yaml"), "Invalid YAML body",
+				fmt.Sprintf("Could not decode the body of the secret at %q as YAML: %s.", secretPath, err.Error()))
+			return
+		}
+	}
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Read full secret from gopass", map[string]interface{}{
+		"path":   r.client.logPath(secretPath),
+		"fields": len(fields),
+	})
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *SecretFullEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
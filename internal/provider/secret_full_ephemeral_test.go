@@ -0,0 +1,124 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestSecretFullEphemeralResource_Open(t *testing.T) {
+	body := "s3cret\nusername: admin\nport: 5432\n\nfree-form notes\n"
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": body}))
+
+	resp := openConfiguredEphemeral(t, &SecretFullEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/db"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data SecretFullModel
+	resp.Result.Get(context.Background(), &data)
+	if data.Password.ValueString() != "s3cret" {
+		t.Errorf("unexpected password %q", data.Password.ValueString())
+	}
+	if data.Body.ValueString() != body {
+		t.Errorf("unexpected body %q", data.Body.ValueString())
+	}
+	if len(data.Fields) != 2 || data.Fields["username"] != "admin" || data.Fields["port"] != "5432" {
+		t.Errorf("unexpected fields %v", data.Fields)
+	}
+}
+
+func TestSecretFullEphemeralResource_Open_PasswordOnly(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/token": "t0ken"}))
+
+	resp := openConfiguredEphemeral(t, &SecretFullEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/token"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data SecretFullModel
+	resp.Result.Get(context.Background(), &data)
+	if data.Password.ValueString() != "t0ken" || data.Fields == nil || len(data.Fields) != 0 {
+		t.Errorf("expected the password and no fields, got %q %v", data.Password.ValueString(), data.Fields)
+	}
+}
+
+func TestSecretFullEphemeralResource_Open_NotFound(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+
+	resp := openConfiguredEphemeral(t, &SecretFullEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/missing"),
+	})
+	if errs := resp.Diagnostics.Errors(); len(errs) != 1 || errs[0].Summary() != "Secret not found" {
+		t.Errorf("expected a not found error, got %v", resp.Diagnostics)
+	}
+}
+
+func TestSecretFullEphemeralResource_Open_ParseYAML(t *testing.T) {
+	body := "s3cret\n---\napi:\n  token: abc123\n  endpoints:\n    - https://a.example.com\n"
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/config": body}))
+
+	resp := openConfiguredEphemeral(t, &SecretFullEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path":       tftypes.NewValue(tftypes.String, "app/config"),
+		"parse_yaml": tftypes.NewValue(tftypes.Bool, true),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data SecretFullModel
+	resp.Result.Get(context.Background(), &data)
+	api := data.YAML.UnderlyingValue().(types.Object).Attributes()["api"].(types.Object).Attributes()
+	if !api["token"].Equal(types.StringValue("abc123")) {
+		t.Errorf("unexpected token %v", api["token"])
+	}
+	endpoints := api["endpoints"].(types.Tuple).Elements()
+	if len(endpoints) != 1 || !endpoints[0].Equal(types.StringValue("https://a.example.com")) {
+		t.Errorf("unexpected endpoints %v", endpoints)
+	}
+}
+
+func TestSecretFullEphemeralResource_Open_ParseYAMLDisabled(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/config": "s3cret\napi: {token: abc}\n"}))
+
+	resp := openConfiguredEphemeral(t, &SecretFullEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path": tftypes.NewValue(tftypes.String, "app/config"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data SecretFullModel
+	resp.Result.Get(context.Background(), &data)
+	if !data.YAML.IsNull() {
+		t.Errorf("expected yaml to be null without parse_yaml, got %v", data.YAML)
+	}
+}
+
+func TestSecretFullEphemeralResource_Open_InvalidYAML(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"app/notes": "s3cret\nuser: admin\nfree-form notes with hunter22\n",
+	}))
+
+	resp := openConfiguredEphemeral(t, &SecretFullEphemeralResource{client: client}, map[string]tftypes.Value{
+		"path":       tftypes.NewValue(tftypes.String, "app/notes"),
+		"parse_yaml": tftypes.NewValue(tftypes.Bool, true),
+	})
+	if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "Invalid YAML body" {
+		t.Fatalf("expected an invalid YAML error, got %v", resp.Diagnostics)
+	}
+	if detail := resp.Diagnostics.Errors()[0].Detail(); strings.Contains(detail, "hunter22") {
+		t.Errorf("expected no secret content in the error, got %q", detail)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var _ function.Function = &SecretFunction{}
+
+// SecretFunction returns the password of a secret inline in expressions.
+type SecretFunction struct {
+	provider *GopassProvider
+}
+
+// NewSecretFunction returns a constructor for the secret function of p.
+func NewSecretFunction(p *GopassProvider) func() function.Function {
+	return func() function.Function {
+		return &SecretFunction{provider: p}
+	}
+}
+
+func (f *SecretFunction) Metadata(ctx context.Context, req function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "secret"
+}
+
+func (f *SecretFunction) Definition(ctx context.Context, req function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Reads the password of a secret from gopass.",
+		Description: "Returns the password (first line) of the secret at path, for one-off lookups in locals and " +
+			"module arguments. Unlike the ephemeral resources, function results are regular values: OpenTofu " +
+			"stores them in plan and state wherever they end up in a resource argument. Use the gopass_secret " +
+			"ephemeral resource for anything that must not be persisted.",
+		MarkdownDescription: "Returns the password (first line) of the secret at `path`, for one-off lookups in locals and " +
+			"module arguments.\n\n" +
+			"**Unlike the ephemeral resources, function results are regular values:** OpenTofu stores them in plan " +
+			"and state wherever they end up in a resource argument. Use the `gopass_secret` ephemeral resource for " +
+			"anything that must not be persisted.\n\n" +
+			"The function requires a configured `provider \"gopass\"` block and reads through its store, " +
+			"policies and mounts; it never falls back to gopass' own configuration.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "path",
+				Description:         "Path to the secret in the gopass store (e.g., 'infrastructure/database/password').",
+				MarkdownDescription: "Path to the secret in the gopass store (e.g., `infrastructure/database/password`).",
+			},
+		},
+		Return: function.StringReturn{},
+	}
+}
+
+func (f *SecretFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var secretPath string
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &secretPath))
+	if resp.Error != nil {
+		return
+	}
+
+	client := f.provider.functionClient()
+	if client == nil {
+		resp.Error = function.NewFuncError("Provider not configured: provider::gopass::secret was called before " +
+			"the gopass provider was configured. Add a provider \"gopass\" block to the configuration.")
+		return
+	}
+	ctx = withAccessor(ctx, "provider::gopass::secret", secretPath)
+	ctx = client.logContext(ctx)
+
+	value, err := client.GetSecret(ctx, secretPath)
+	if err != nil {
+		// Keep secret values out of the error, as diagnostics do
+		resp.Error = function.NewArgumentFuncError(0, client.redactor.redactValues(fmt.Sprintf("%s: %s",
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err))))
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, value))
+
+	tflog.Debug(ctx, "Read secret from gopass in a function call", map[string]interface{}{
+		"path": client.logPath(secretPath),
+	})
+}
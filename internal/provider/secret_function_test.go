@@ -0,0 +1,72 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// runSecretFunction calls the secret function of p with path.
+func runSecretFunction(p *GopassProvider, path string) *function.RunResponse {
+	resp := &function.RunResponse{Result: function.NewResultData(types.StringUnknown())}
+	f := NewSecretFunction(p)()
+	f.Run(context.Background(), function.RunRequest{
+		Arguments: function.NewArgumentsData([]attr.Value{types.StringValue(path)}),
+	}, resp)
+	return resp
+}
+
+func TestSecretFunction_Run(t *testing.T) {
+	p := &GopassProvider{client: NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"app/db": "s3cret\nusername: admin",
+	}))}
+
+	resp := runSecretFunction(p, "app/db")
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if got := resp.Result.Value().(types.String).ValueString(); got != "s3cret" {
+		t.Errorf("unexpected value %q", got)
+	}
+}
+
+func TestSecretFunction_Run_NotFound(t *testing.T) {
+	p := &GopassProvider{client: NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))}
+
+	resp := runSecretFunction(p, "app/missing")
+	if resp.Error == nil || resp.Error.FunctionArgument == nil || *resp.Error.FunctionArgument != 0 {
+		t.Fatalf("expected an error on the path argument, got %v", resp.Error)
+	}
+	if !strings.HasPrefix(resp.Error.Text, "Secret not found: ") {
+		t.Errorf("unexpected error %q", resp.Error.Text)
+	}
+}
+
+func TestSecretFunction_Run_Policy(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret", "billing/key": "k3y"}))
+	client.policies = policySet{provider: pathPolicy{allowed: []string{"app/"}}, violations: &accessLog{}}
+	p := &GopassProvider{client: client}
+
+	if resp := runSecretFunction(p, "billing/key"); resp.Error == nil || !strings.Contains(resp.Error.Text, "not allowed") {
+		t.Errorf("expected the provider policy to apply, got %v", resp.Error)
+	}
+}
+
+func TestSecretFunction_Run_Unconfigured(t *testing.T) {
+	p := &GopassProvider{}
+
+	resp := runSecretFunction(p, "app/db")
+	if resp.Error == nil || !strings.HasPrefix(resp.Error.Text, "Provider not configured: ") {
+		t.Fatalf("expected an error for an unconfigured provider, got %v", resp.Error)
+	}
+	if p.functionClient() != nil {
+		t.Error("expected no client to be made up from the environment")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ datasource.DataSource              = &SecretMetadataDataSource{}
+	_ datasource.DataSourceWithConfigure = &SecretMetadataDataSource{}
+)
+
+// SecretMetadataDataSource reports whether a secret exists and its
+// non-sensitive metadata: key names, revision count and last modification.
+type SecretMetadataDataSource struct {
+	client *GopassClient
+}
+
+// SecretMetadataModel describes the data source data model.
+type SecretMetadataModel struct {
+	Path          types.String `tfsdk:"path"`
+	Policy        types.String `tfsdk:"policy"`
+	Exists        types.Bool   `tfsdk:"exists"`
+	Keys          types.List   `tfsdk:"keys"`
+	RevisionCount types.Int64  `tfsdk:"revision_count"`
+	LastModified  types.String `tfsdk:"last_modified"`
+}
+
+// NewSecretMetadataDataSource creates a new instance.
+func NewSecretMetadataDataSource() datasource.DataSource {
+	return &SecretMetadataDataSource{}
+}
+
+func (d *SecretMetadataDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secret_metadata"
+}
+
+func (d *SecretMetadataDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Returns non-sensitive metadata about a secret: whether it exists, its key names, " +
+			"number of revisions and last modification time.",
+		MarkdownDescription: `
+Returns non-sensitive metadata about a secret: whether it exists, its key
+names, number of revisions and last modification time.
+
+Nothing derived from the content is returned, not even a checksum, so plans
+can reference the structure of a secret without putting anything about its
+value in state. Use ` + "`gopass_secret_checksum`" + ` to detect changes to the value.
+
+## Example Usage
+
+` + "```hcl" + `
+data "gopass_secret_metadata" "db" {
+  path = "infrastructure/database/admin"
+}
+
+check "db_secret_structure" {
+  assert {
+    condition     = data.gopass_secret_metadata.db.exists && contains(data.gopass_secret_metadata.db.keys, "username")
+    error_message = "The database secret is missing or has no username."
+  }
+}
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Description:         "Path to the secret in the gopass store (e.g., 'infrastructure/db/password').",
+				MarkdownDescription: "Path to the secret in the gopass store (e.g., `infrastructure/db/password`).",
+				Required:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this data source runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this data source runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"exists": schema.BoolAttribute{
+				Description: "Whether the secret exists.",
+				Computed:    true,
+			},
+			"keys": schema.ListAttribute{
+				Description: "Sorted names of the secret's key-value lines, without their values. " +
+					"Null if the secret does not exist.",
+				ElementType: types.StringType,
+				Computed:    true,
+			},
+			"revision_count": schema.Int64Attribute{
+				Description: "Number of revisions in gopass for this secret, 1 if the store keeps no history, " +
+					"0 if it does not exist.",
+				Computed: true,
+			},
+			"last_modified": schema.StringAttribute{
+				Description: "When the secret was last written, from the modification time of its encrypted file, " +
+					"as an RFC 3339 timestamp in UTC. Null if it is unknown, e.g. for stores not on disk, " +
+					"or the secret does not exist.",
+				Computed: true,
+			},
+		},
+	}
+}
+
+func (d *SecretMetadataDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.client = client
+}
+
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (d *SecretMetadataDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = d.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data SecretMetadataModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "data.gopass_secret_metadata", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = d.client.logContext(ctx)
+
+	meta, exists, err := d.client.SecretMetadata(ctx, secretPath)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read the metadata of secret %q: %s", secretPath, err.Error()), err),
+		)
+		return
+	}
+
+	data.Exists = types.BoolValue(exists)
+	data.Keys = types.ListNull(types.StringType)
+	data.RevisionCount = types.Int64Value(0)
+	data.LastModified = types.StringNull()
+	if exists {
+		keys, diags := types.ListValueFrom(ctx, types.StringType, meta.Keys)
+		resp.Diagnostics.Append(diags...)
+		data.Keys = keys
+		data.RevisionCount = types.Int64Value(meta.RevisionCount)
+		if !meta.LastModified.IsZero() {
+			data.LastModified = types.StringValue(meta.LastModified.Format(time.RFC3339))
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
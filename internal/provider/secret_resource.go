@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -14,15 +15,18 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure implementation satisfies interfaces.
 var (
-	_ resource.Resource                = &SecretResource{}
-	_ resource.ResourceWithConfigure   = &SecretResource{}
-	_ resource.ResourceWithImportState = &SecretResource{}
+	_ resource.Resource                   = &SecretResource{}
+	_ resource.ResourceWithConfigure      = &SecretResource{}
+	_ resource.ResourceWithImportState    = &SecretResource{}
+	_ resource.ResourceWithModifyPlan     = &SecretResource{}
+	_ resource.ResourceWithValidateConfig = &SecretResource{}
 )
 
 // SecretResource writes secrets to gopass with write-only value support.
@@ -36,8 +40,11 @@ type SecretResourceModel struct {
 	Path           types.String `tfsdk:"path"`
 	ValueWO        types.String `tfsdk:"value_wo"`
 	ValueWOVersion types.Int64  `tfsdk:"value_wo_version"`
+	Password       types.String `tfsdk:"password"`
+	Fields         types.Map    `tfsdk:"fields"`
 	DeleteOnRemove types.Bool   `tfsdk:"delete_on_remove"`
 	RevisionCount  types.Int64  `tfsdk:"revision_count"`
+	Policy         types.String `tfsdk:"policy"`
 }
 
 // NewSecretResource creates a new instance.
@@ -52,7 +59,8 @@ func (r *SecretResource) Metadata(ctx context.Context, req resource.MetadataRequ
 func (r *SecretResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Description: "Writes a secret to the gopass store using write-only attributes. " +
-			"The secret value is never stored in Terraform state.",
+			"The secret value is never stored in Terraform state. Alternatively, manages the password and " +
+			"key/value fields of the secret, which are kept in state.",
 		MarkdownDescription: `
 Writes a secret to the gopass store using **write-only attributes**.
 
@@ -81,6 +89,20 @@ resource "gopass_secret" "db_password" {
 }
 ` + "```" + `
 
+To manage the whole entry, set ` + "`password`" + ` and ` + "`fields`" + ` instead. The password is kept in
+state, marked sensitive, and every change is written to gopass:
+
+` + "```hcl" + `
+resource "gopass_secret" "db" {
+  path     = "infrastructure/database/app"
+  password = random_password.db.result
+  fields = {
+    username = "app"
+    host     = "db.internal"
+  }
+}
+` + "```" + `
+
 ## Write-Only Behavior
 
 - ` + "`value_wo`" + ` accepts ephemeral values (from ephemeral resources)
@@ -113,6 +135,7 @@ After import, set ` + "`value_wo`" + ` and ` + "`value_wo_version`" + ` in your
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplace(),
 				},
+				Validators: []validator.String{secretPathValidator{}},
 			},
 			"value_wo": schema.StringAttribute{
 				Description: "The secret value to write. This is a write-only attribute - " +
@@ -133,6 +156,22 @@ After import, set ` + "`value_wo`" + ` and ` + "`value_wo_version`" + ` in your
 					int64planmodifier.UseStateForUnknown(),
 				},
 			},
+			"password": schema.StringAttribute{
+				Description: "The password to write to the first line of the secret. Unlike value_wo, it is kept in " +
+					"state, and every change is written to gopass. Conflicts with value_wo.",
+				MarkdownDescription: "The password to write to the first line of the secret. Unlike `value_wo`, it is kept in " +
+					"state, and every change is written to gopass. Conflicts with `value_wo`.",
+				Optional:  true,
+				Sensitive: true,
+			},
+			"fields": schema.MapAttribute{
+				Description: "Key/value fields to write below the password, as \"key: value\" lines in key order. " +
+					"Every change is written to gopass. Without password or value_wo, the secret keeps its current password.",
+				MarkdownDescription: "Key/value fields to write below the password, as `key: value` lines in key order. " +
+					"Every change is written to gopass. Without `password` or `value_wo`, the secret keeps its current password.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
 			"delete_on_remove": schema.BoolAttribute{
 				Description:         "Whether to delete the secret from gopass when the resource is destroyed. Defaults to true.",
 				MarkdownDescription: "Whether to delete the secret from gopass when the resource is destroyed. Defaults to `true`.",
@@ -140,6 +179,13 @@ After import, set ` + "`value_wo`" + ` and ` + "`value_wo_version`" + ` in your
 				Computed:            true,
 				Default:             booldefault.StaticBool(true),
 			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
 			"revision_count": schema.Int64Attribute{
 				Description: "Number of revisions in gopass for this secret. Used for drift detection. " +
 					"A warning is shown if this changes outside of Terraform. " +
@@ -173,8 +219,70 @@ func (r *SecretResource) Configure(ctx context.Context, req resource.ConfigureRe
 	r.client = client
 }
 
+// ValidateConfig rejects setting both password and value_wo.
+func (r *SecretResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config SecretResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !config.Password.IsNull() && !config.ValueWO.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("password"), "Conflicting secret values",
+			"Set either password, which is kept in state, or the write-only value_wo, not both.")
+	}
+}
+
+// secretContent returns the password to write, from password or else
+// value_wo, whether one is configured, and the configured fields, nil if
+// there are none.
+func secretContent(ctx context.Context, plan, config SecretResourceModel) (string, bool, map[string]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	var fields map[string]string
+	if !plan.Fields.IsNull() && !plan.Fields.IsUnknown() {
+		diags.Append(plan.Fields.ElementsAs(ctx, &fields, false)...)
+	}
+	switch {
+	case !plan.Password.IsNull() && !plan.Password.IsUnknown():
+		return plan.Password.ValueString(), true, fields, diags
+	case !config.ValueWO.IsNull() && !config.ValueWO.IsUnknown():
+		return config.ValueWO.ValueString(), true, fields, diags
+	}
+	return "", false, fields, diags
+}
+
+// write writes password and fields to the secret. Without a password, the
+// secret keeps its current one.
+func (r *SecretResource) write(ctx context.Context, secretPath, password string, hasPassword bool, fields map[string]string) error {
+	if fields == nil {
+		return r.client.SetSecret(ctx, secretPath, password)
+	}
+	if !hasPassword {
+		current, err := r.client.GetSecret(ctx, secretPath)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		password = current
+	}
+	return r.client.SetSecretFull(ctx, secretPath, password, fields)
+}
+
+// ModifyPlan forecasts the hardware token interactions of the apply when the
+// secret is about to be created or changed.
+//
+//nolint:gocritic // hugeParam: Terraform framework interface requirement
+func (r *SecretResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || req.Plan.Raw.Equal(req.State.Raw) {
+		return
+	}
+	resp.Diagnostics.Append(r.client.interactionForecast()...)
+}
+
 //nolint:gocritic // hugeParam: Terraform framework interface requirement
 func (r *SecretResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
 	var data SecretResourceModel
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
@@ -183,9 +291,12 @@ func (r *SecretResource) Create(ctx context.Context, req resource.CreateRequest,
 	}
 
 	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_secret", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
 
 	tflog.Debug(ctx, "Creating gopass secret", map[string]interface{}{
-		"path": secretPath,
+		"path": r.client.logPath(secretPath),
 	})
 
 	// Get write-only value from config (not plan, as write-only values are only in config)
@@ -195,20 +306,25 @@ func (r *SecretResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
-	// Write the secret if value_wo is provided
-	if !config.ValueWO.IsNull() && !config.ValueWO.IsUnknown() {
-		value := config.ValueWO.ValueString()
-		if err := r.client.SetSecret(ctx, secretPath, value); err != nil {
+	password, hasPassword, fields, diags := secretContent(ctx, data, config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Write the secret if a password or fields are provided
+	if hasPassword || fields != nil {
+		if err := r.write(ctx, secretPath, password, hasPassword, fields); err != nil {
 			resp.Diagnostics.AddError(
-				"Failed to create secret",
-				fmt.Sprintf("Could not write secret to gopass at %q: %s", secretPath, err.Error()),
+				errorSummary(err, "Failed to create secret"),
+				errorDetail(fmt.Sprintf("Could not write secret to gopass at %q: %s", secretPath, err.Error()), err),
 			)
 			return
 		}
 	} else {
 		resp.Diagnostics.AddWarning(
 			"No value provided",
-			"The secret was created but no value_wo was provided. The secret in gopass may be empty or unchanged.",
+			"The secret was created but no value_wo, password or fields were provided. The secret in gopass may be empty or unchanged.",
 		)
 	}
 
@@ -216,8 +332,8 @@ func (r *SecretResource) Create(ctx context.Context, req resource.CreateRequest,
 	revCount, err := r.client.GetRevisionCount(ctx, secretPath)
 	if err != nil {
 		tflog.Warn(ctx, "Could not get revision count", map[string]interface{}{
-			"path":  secretPath,
-			"error": err.Error(),
+			"path":  r.client.logPath(secretPath),
+			"error": r.client.logError(err),
 		})
 		revCount = 1 // Fallback: we know it exists
 	}
@@ -227,7 +343,7 @@ func (r *SecretResource) Create(ctx context.Context, req resource.CreateRequest,
 	data.ID = data.Path
 
 	tflog.Debug(ctx, "Created gopass secret", map[string]interface{}{
-		"path": secretPath,
+		"path": r.client.logPath(secretPath),
 	})
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -235,6 +351,10 @@ func (r *SecretResource) Create(ctx context.Context, req resource.CreateRequest,
 
 //nolint:gocritic // hugeParam: Terraform framework interface requirement
 func (r *SecretResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
 	var data SecretResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -243,17 +363,20 @@ func (r *SecretResource) Read(ctx context.Context, req resource.ReadRequest, res
 	}
 
 	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_secret", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
 
 	tflog.Debug(ctx, "Reading gopass secret", map[string]interface{}{
-		"path": secretPath,
+		"path": r.client.logPath(secretPath),
 	})
 
-	// Only check if secret exists - we never read the value back
+	// Check the secret exists; value_wo is never read back
 	exists, err := r.client.SecretExists(ctx, secretPath)
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Failed to read secret",
-			fmt.Sprintf("Could not check if secret exists at %q: %s", secretPath, err.Error()),
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not check if secret exists at %q: %s", secretPath, err.Error()), err),
 		)
 		return
 	}
@@ -268,8 +391,8 @@ func (r *SecretResource) Read(ctx context.Context, req resource.ReadRequest, res
 	currentRevCount, err := r.client.GetRevisionCount(ctx, secretPath)
 	if err != nil {
 		tflog.Warn(ctx, "Could not get revision count for drift detection", map[string]interface{}{
-			"path":  secretPath,
-			"error": err.Error(),
+			"path":  r.client.logPath(secretPath),
+			"error": r.client.logError(err),
 		})
 	} else {
 		storedRevCount := data.RevisionCount.ValueInt64()
@@ -293,12 +416,43 @@ func (r *SecretResource) Read(ctx context.Context, req resource.ReadRequest, res
 		data.RevisionCount = types.Int64Value(currentRevCount)
 	}
 
-	// Keep existing state (with updated revision count)
+	// password and fields are in state: refresh them from the secret, so a
+	// change made outside of Terraform shows up as drift and is written back
+	if !data.Password.IsNull() || !data.Fields.IsNull() {
+		password, fields, err := r.client.GetSecretFull(ctx, secretPath)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				errorSummary(err, "Failed to read secret"),
+				errorDetail(fmt.Sprintf("Could not read secret at %q: %s", secretPath, err.Error()), err),
+			)
+			return
+		}
+		if !data.Password.IsNull() {
+			data.Password = types.StringValue(password)
+		}
+		if !data.Fields.IsNull() {
+			if fields == nil {
+				fields = map[string]string{}
+			}
+			value, diags := types.MapValueFrom(ctx, types.StringType, fields)
+			resp.Diagnostics.Append(diags...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			data.Fields = value
+		}
+	}
+
+	// Keep existing state (with updated revision count and content)
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 //nolint:gocritic // hugeParam: Terraform framework interface requirement
 func (r *SecretResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
 	var data SecretResourceModel
 	var state SecretResourceModel
 
@@ -309,9 +463,12 @@ func (r *SecretResource) Update(ctx context.Context, req resource.UpdateRequest,
 	}
 
 	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_secret", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
 
 	tflog.Debug(ctx, "Updating gopass secret", map[string]interface{}{
-		"path": secretPath,
+		"path": r.client.logPath(secretPath),
 	})
 
 	// Get write-only value from config
@@ -329,36 +486,44 @@ func (r *SecretResource) Update(ctx context.Context, req resource.UpdateRequest,
 		versionChanged = true
 	}
 
-	// Write the secret if version changed and value_wo is provided
-	if versionChanged {
-		if !config.ValueWO.IsNull() && !config.ValueWO.IsUnknown() {
-			value := config.ValueWO.ValueString()
-			if err := r.client.SetSecret(ctx, secretPath, value); err != nil {
-				resp.Diagnostics.AddError(
-					"Failed to update secret",
-					fmt.Sprintf("Could not write secret to gopass at %q: %s", secretPath, err.Error()),
-				)
-				return
-			}
-			tflog.Info(ctx, "Updated gopass secret (value_wo_version changed)", map[string]interface{}{
-				"path":        secretPath,
-				"old_version": state.ValueWOVersion.ValueInt64(),
-				"new_version": data.ValueWOVersion.ValueInt64(),
-			})
-		} else {
-			resp.Diagnostics.AddWarning(
-				"Version changed but no value provided",
-				"value_wo_version was incremented but no value_wo was provided. The secret in gopass was not updated.",
+	password, hasPassword, fields, diags := secretContent(ctx, data, config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// password and fields are in state, so any change of them is written too
+	contentChanged := !data.Password.Equal(state.Password) || !data.Fields.Equal(state.Fields)
+
+	// Write the secret if version changed and value_wo is provided, or if the
+	// password or fields changed
+	if versionChanged && !hasPassword {
+		resp.Diagnostics.AddWarning(
+			"Version changed but no value provided",
+			"value_wo_version was incremented but no value_wo was provided. The password in gopass was not updated.",
+		)
+	}
+	if (versionChanged && hasPassword) || contentChanged {
+		if err := r.write(ctx, secretPath, password, hasPassword, fields); err != nil {
+			resp.Diagnostics.AddError(
+				errorSummary(err, "Failed to update secret"),
+				errorDetail(fmt.Sprintf("Could not write secret to gopass at %q: %s", secretPath, err.Error()), err),
 			)
+			return
 		}
+		tflog.Info(ctx, "Updated gopass secret", map[string]interface{}{
+			"path":            r.client.logPath(secretPath),
+			"old_version":     state.ValueWOVersion.ValueInt64(),
+			"new_version":     data.ValueWOVersion.ValueInt64(),
+			"content_changed": contentChanged,
+		})
 	}
 
 	// Update revision count after write
 	revCount, err := r.client.GetRevisionCount(ctx, secretPath)
 	if err != nil {
 		tflog.Warn(ctx, "Could not get revision count after update", map[string]interface{}{
-			"path":  secretPath,
-			"error": err.Error(),
+			"path":  r.client.logPath(secretPath),
+			"error": r.client.logError(err),
 		})
 		// Keep previous count if we can't get new one
 		revCount = state.RevisionCount.ValueInt64()
@@ -370,6 +535,10 @@ func (r *SecretResource) Update(ctx context.Context, req resource.UpdateRequest,
 
 //nolint:gocritic // hugeParam: Terraform framework interface requirement
 func (r *SecretResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
 	var data SecretResourceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -378,10 +547,13 @@ func (r *SecretResource) Delete(ctx context.Context, req resource.DeleteRequest,
 	}
 
 	secretPath := data.Path.ValueString()
+	ctx = withAccessor(ctx, "gopass_secret", secretPath)
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
 	deleteOnRemove := data.DeleteOnRemove.ValueBool()
 
 	tflog.Debug(ctx, "Deleting gopass secret resource", map[string]interface{}{
-		"path":             secretPath,
+		"path":             r.client.logPath(secretPath),
 		"delete_on_remove": deleteOnRemove,
 	})
 
@@ -391,7 +563,7 @@ func (r *SecretResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		if err != nil {
 			resp.Diagnostics.AddWarning(
 				"Failed to check secret existence",
-				fmt.Sprintf("Could not verify if secret exists at %q: %s", secretPath, err.Error()),
+				errorDetail(fmt.Sprintf("Could not verify if secret exists at %q: %s", secretPath, err.Error()), err),
 			)
 			return
 		}
@@ -399,35 +571,41 @@ func (r *SecretResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		if exists {
 			if err := r.client.RemoveSecret(ctx, secretPath); err != nil {
 				resp.Diagnostics.AddError(
-					"Failed to remove secret",
-					fmt.Sprintf("Could not remove secret from gopass at %q: %s", secretPath, err.Error()),
+					errorSummary(err, "Failed to remove secret"),
+					errorDetail(fmt.Sprintf("Could not remove secret from gopass at %q: %s", secretPath, err.Error()), err),
 				)
 				return
 			}
 			tflog.Info(ctx, "Removed gopass secret", map[string]interface{}{
-				"path": secretPath,
+				"path": r.client.logPath(secretPath),
 			})
 		}
 	} else {
 		tflog.Info(ctx, "Keeping gopass secret (delete_on_remove=false)", map[string]interface{}{
-			"path": secretPath,
+			"path": r.client.logPath(secretPath),
 		})
 	}
 }
 
 func (r *SecretResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
 	secretPath := req.ID
+	ctx = withAccessor(ctx, "gopass_secret", secretPath)
+	ctx = r.client.logContext(ctx)
 
 	tflog.Debug(ctx, "Importing gopass secret", map[string]interface{}{
-		"path": secretPath,
+		"path": r.client.logPath(secretPath),
 	})
 
 	// Verify the secret exists
 	exists, err := r.client.SecretExists(ctx, secretPath)
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Failed to import secret",
-			fmt.Sprintf("Could not check if secret exists at %q: %s", secretPath, err.Error()),
+			errorSummary(err, "Failed to import secret"),
+			errorDetail(fmt.Sprintf("Could not check if secret exists at %q: %s", secretPath, err.Error()), err),
 		)
 		return
 	}
@@ -444,8 +622,8 @@ func (r *SecretResource) ImportState(ctx context.Context, req resource.ImportSta
 	revCount, err := r.client.GetRevisionCount(ctx, secretPath)
 	if err != nil {
 		tflog.Warn(ctx, "Could not get revision count during import", map[string]interface{}{
-			"path":  secretPath,
-			"error": err.Error(),
+			"path":  r.client.logPath(secretPath),
+			"error": r.client.logError(err),
 		})
 		revCount = 1 // Fallback
 	}
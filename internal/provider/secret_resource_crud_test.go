@@ -84,6 +84,9 @@ func TestSecretResource_Create(t *testing.T) {
 			"value_wo_version": tftypes.Number,
 			"delete_on_remove": tftypes.Bool,
 			"revision_count":   tftypes.Number,
+			"policy":           tftypes.String,
+			"password":         tftypes.String,
+			"fields":           tftypes.Map{ElementType: tftypes.String},
 		},
 	}, map[string]tftypes.Value{
 		"id":               tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
@@ -92,6 +95,9 @@ func TestSecretResource_Create(t *testing.T) {
 		"value_wo_version": tftypes.NewValue(tftypes.Number, tftypes.UnknownValue),
 		"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
 		"revision_count":   tftypes.NewValue(tftypes.Number, tftypes.UnknownValue),
+		"policy":           tftypes.NewValue(tftypes.String, nil),
+		"password":         tftypes.NewValue(tftypes.String, nil),
+		"fields":           tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
 	})
 
 	configValue := tftypes.NewValue(tftypes.Object{
@@ -102,6 +108,9 @@ func TestSecretResource_Create(t *testing.T) {
 			"value_wo_version": tftypes.Number,
 			"delete_on_remove": tftypes.Bool,
 			"revision_count":   tftypes.Number,
+			"policy":           tftypes.String,
+			"password":         tftypes.String,
+			"fields":           tftypes.Map{ElementType: tftypes.String},
 		},
 	}, map[string]tftypes.Value{
 		"id":               tftypes.NewValue(tftypes.String, nil),
@@ -110,6 +119,9 @@ func TestSecretResource_Create(t *testing.T) {
 		"value_wo_version": tftypes.NewValue(tftypes.Number, 1),
 		"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
 		"revision_count":   tftypes.NewValue(tftypes.Number, nil),
+		"policy":           tftypes.NewValue(tftypes.String, nil),
+		"password":         tftypes.NewValue(tftypes.String, nil),
+		"fields":           tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
 	})
 
 	req := resource.CreateRequest{
@@ -174,6 +186,9 @@ func TestSecretResource_Create_NoValueWO(t *testing.T) {
 			"value_wo_version": tftypes.Number,
 			"delete_on_remove": tftypes.Bool,
 			"revision_count":   tftypes.Number,
+			"policy":           tftypes.String,
+			"password":         tftypes.String,
+			"fields":           tftypes.Map{ElementType: tftypes.String},
 		},
 	}, map[string]tftypes.Value{
 		"id":               tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
@@ -182,6 +197,9 @@ func TestSecretResource_Create_NoValueWO(t *testing.T) {
 		"value_wo_version": tftypes.NewValue(tftypes.Number, tftypes.UnknownValue),
 		"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
 		"revision_count":   tftypes.NewValue(tftypes.Number, tftypes.UnknownValue),
+		"policy":           tftypes.NewValue(tftypes.String, nil),
+		"password":         tftypes.NewValue(tftypes.String, nil),
+		"fields":           tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
 	})
 
 	configValue := tftypes.NewValue(tftypes.Object{
@@ -192,6 +210,9 @@ func TestSecretResource_Create_NoValueWO(t *testing.T) {
 			"value_wo_version": tftypes.Number,
 			"delete_on_remove": tftypes.Bool,
 			"revision_count":   tftypes.Number,
+			"policy":           tftypes.String,
+			"password":         tftypes.String,
+			"fields":           tftypes.Map{ElementType: tftypes.String},
 		},
 	}, map[string]tftypes.Value{
 		"id":               tftypes.NewValue(tftypes.String, nil),
@@ -200,6 +221,9 @@ func TestSecretResource_Create_NoValueWO(t *testing.T) {
 		"value_wo_version": tftypes.NewValue(tftypes.Number, nil),
 		"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
 		"revision_count":   tftypes.NewValue(tftypes.Number, nil),
+		"policy":           tftypes.NewValue(tftypes.String, nil),
+		"password":         tftypes.NewValue(tftypes.String, nil),
+		"fields":           tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
 	})
 
 	req := resource.CreateRequest{
@@ -262,6 +286,9 @@ func TestSecretResource_Read(t *testing.T) {
 			"value_wo_version": tftypes.Number,
 			"delete_on_remove": tftypes.Bool,
 			"revision_count":   tftypes.Number,
+			"policy":           tftypes.String,
+			"password":         tftypes.String,
+			"fields":           tftypes.Map{ElementType: tftypes.String},
 		},
 	}, map[string]tftypes.Value{
 		"id":               tftypes.NewValue(tftypes.String, "test/secret"),
@@ -270,6 +297,9 @@ func TestSecretResource_Read(t *testing.T) {
 		"value_wo_version": tftypes.NewValue(tftypes.Number, nil),
 		"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
 		"revision_count":   tftypes.NewValue(tftypes.Number, 1),
+		"policy":           tftypes.NewValue(tftypes.String, nil),
+		"password":         tftypes.NewValue(tftypes.String, nil),
+		"fields":           tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
 	})
 
 	req := resource.ReadRequest{
@@ -323,6 +353,9 @@ func TestSecretResource_Read_NotFound(t *testing.T) {
 			"value_wo_version": tftypes.Number,
 			"delete_on_remove": tftypes.Bool,
 			"revision_count":   tftypes.Number,
+			"policy":           tftypes.String,
+			"password":         tftypes.String,
+			"fields":           tftypes.Map{ElementType: tftypes.String},
 		},
 	}, map[string]tftypes.Value{
 		"id":               tftypes.NewValue(tftypes.String, "nonexistent"),
@@ -331,6 +364,9 @@ func TestSecretResource_Read_NotFound(t *testing.T) {
 		"value_wo_version": tftypes.NewValue(tftypes.Number, nil),
 		"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
 		"revision_count":   tftypes.NewValue(tftypes.Number, 1),
+		"policy":           tftypes.NewValue(tftypes.String, nil),
+		"password":         tftypes.NewValue(tftypes.String, nil),
+		"fields":           tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, nil),
 	})
 
 	req := resource.ReadRequest{
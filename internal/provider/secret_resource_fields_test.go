@@ -0,0 +1,232 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// secretResourceValue returns a gopass_secret object for path with the given
+// attributes set and all others null.
+func secretResourceValue(t *testing.T, r *SecretResource, attrs map[string]tftypes.Value) tftypes.Value {
+	t.Helper()
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(context.Background(), resource.SchemaRequest{}, schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(context.Background()).(tftypes.Object)
+
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attrType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attrType, nil)
+	}
+	for name, value := range attrs {
+		values[name] = value
+	}
+	return tftypes.NewValue(objectType, values)
+}
+
+// stringMap returns a tftypes map of strings.
+func stringMap(entries map[string]string) tftypes.Value {
+	values := make(map[string]tftypes.Value, len(entries))
+	for key, value := range entries {
+		values[key] = tftypes.NewValue(tftypes.String, value)
+	}
+	return tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, values)
+}
+
+func TestSecretResource_Create_PasswordAndFields(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(nil)
+	r := &SecretResource{client: NewGopassClientWithStore(store)}
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+
+	value := secretResourceValue(t, r, map[string]tftypes.Value{
+		"path":             tftypes.NewValue(tftypes.String, "app/db"),
+		"password":         tftypes.NewValue(tftypes.String, "s3cret"),
+		"fields":           stringMap(map[string]string{"username": "app", "host": "db.internal"}),
+		"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
+	})
+	resp := &resource.CreateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+	r.Create(ctx, resource.CreateRequest{
+		Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: value},
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: value},
+	}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	secret, err := store.Get(ctx, "app/db", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(secret.Bytes()); got != "s3cret\nhost: db.internal\nusername: app\n" {
+		t.Errorf("unexpected entry %q", got)
+	}
+}
+
+func TestSecretResource_Read_ContentDrift(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(map[string]string{"app/db": "changed\nhost: db.internal\nport: 5432"})
+	r := &SecretResource{client: NewGopassClientWithStore(store)}
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+
+	state := secretResourceValue(t, r, map[string]tftypes.Value{
+		"path":     tftypes.NewValue(tftypes.String, "app/db"),
+		"password": tftypes.NewValue(tftypes.String, "s3cret"),
+		"fields":   stringMap(map[string]string{"host": "db.internal"}),
+	})
+	resp := &resource.ReadResponse{State: tfsdk.State{Schema: schemaResp.Schema, Raw: state}}
+	r.Read(ctx, resource.ReadRequest{State: tfsdk.State{Schema: schemaResp.Schema, Raw: state}}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data SecretResourceModel
+	resp.State.Get(ctx, &data)
+	if data.Password.ValueString() != "changed" {
+		t.Errorf("expected the changed password in state, got %q", data.Password.ValueString())
+	}
+	fields := map[string]string{}
+	data.Fields.ElementsAs(ctx, &fields, false)
+	if len(fields) != 2 || fields["host"] != "db.internal" || fields["port"] != "5432" {
+		t.Errorf("expected the changed fields in state, got %v", fields)
+	}
+}
+
+func TestSecretResource_Update_Fields(t *testing.T) {
+	ctx := context.Background()
+	schemaResp := &resource.SchemaResponse{}
+	(&SecretResource{}).Schema(ctx, resource.SchemaRequest{}, schemaResp)
+
+	tests := []struct {
+		name        string
+		state, plan map[string]tftypes.Value
+		config      map[string]tftypes.Value // defaults to plan
+		want        string
+	}{
+		{
+			name: "fields only keep the password",
+			state: map[string]tftypes.Value{
+				"fields": stringMap(map[string]string{"username": "old"}),
+			},
+			plan: map[string]tftypes.Value{
+				"fields": stringMap(map[string]string{"username": "new"}),
+			},
+			want: "current\nusername: new\n",
+		},
+		{
+			name: "password change",
+			state: map[string]tftypes.Value{
+				"password": tftypes.NewValue(tftypes.String, "current"),
+			},
+			plan: map[string]tftypes.Value{
+				"password": tftypes.NewValue(tftypes.String, "rotated"),
+			},
+			want: "rotated\n",
+		},
+		{
+			name: "fields with value_wo",
+			state: map[string]tftypes.Value{
+				"value_wo_version": tftypes.NewValue(tftypes.Number, 1),
+			},
+			plan: map[string]tftypes.Value{
+				"value_wo_version": tftypes.NewValue(tftypes.Number, 1),
+				"fields":           stringMap(map[string]string{"host": "db"}),
+			},
+			config: map[string]tftypes.Value{
+				"value_wo":         tftypes.NewValue(tftypes.String, "from-config"),
+				"value_wo_version": tftypes.NewValue(tftypes.Number, 1),
+				"fields":           stringMap(map[string]string{"host": "db"}),
+			},
+			want: "from-config\nhost: db\n",
+		},
+		{
+			name: "unchanged",
+			state: map[string]tftypes.Value{
+				"password": tftypes.NewValue(tftypes.String, "current"),
+				"fields":   stringMap(map[string]string{"username": "admin"}),
+			},
+			plan: map[string]tftypes.Value{
+				"password": tftypes.NewValue(tftypes.String, "current"),
+				"fields":   stringMap(map[string]string{"username": "admin"}),
+			},
+			want: "current\nusername: admin\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(map[string]string{"app/db": "current\nusername: admin\n"})
+			r := &SecretResource{client: NewGopassClientWithStore(store)}
+			object := func(attrs map[string]tftypes.Value) tftypes.Value {
+				all := map[string]tftypes.Value{
+					"id":               tftypes.NewValue(tftypes.String, "app/db"),
+					"path":             tftypes.NewValue(tftypes.String, "app/db"),
+					"delete_on_remove": tftypes.NewValue(tftypes.Bool, true),
+					"revision_count":   tftypes.NewValue(tftypes.Number, 1),
+				}
+				for name, value := range attrs {
+					all[name] = value
+				}
+				return secretResourceValue(t, r, all)
+			}
+			config := tt.config
+			if config == nil {
+				config = tt.plan
+			}
+
+			resp := &resource.UpdateResponse{State: tfsdk.State{Schema: schemaResp.Schema}}
+			r.Update(ctx, resource.UpdateRequest{
+				State:  tfsdk.State{Schema: schemaResp.Schema, Raw: object(tt.state)},
+				Plan:   tfsdk.Plan{Schema: schemaResp.Schema, Raw: object(tt.plan)},
+				Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: object(config)},
+			}, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", resp.Diagnostics)
+			}
+
+			secret, err := store.Get(ctx, "app/db", "latest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(secret.Bytes()); got != tt.want {
+				t.Errorf("unexpected entry %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretResource_ValidateConfig(t *testing.T) {
+	ctx := context.Background()
+	r := &SecretResource{}
+	schemaResp := &resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, schemaResp)
+
+	validate := func(attrs map[string]tftypes.Value) *resource.ValidateConfigResponse {
+		attrs["path"] = tftypes.NewValue(tftypes.String, "app/db")
+		resp := &resource.ValidateConfigResponse{}
+		r.ValidateConfig(ctx, resource.ValidateConfigRequest{
+			Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: secretResourceValue(t, r, attrs)},
+		}, resp)
+		return resp
+	}
+
+	if resp := validate(map[string]tftypes.Value{
+		"password": tftypes.NewValue(tftypes.String, "s3cret"),
+		"value_wo": tftypes.NewValue(tftypes.String, "s3cret"),
+	}); !resp.Diagnostics.HasError() {
+		t.Error("expected password and value_wo to conflict")
+	}
+	if resp := validate(map[string]tftypes.Value{
+		"password": tftypes.NewValue(tftypes.String, "s3cret"),
+		"fields":   stringMap(map[string]string{"username": "app"}),
+	}); resp.Diagnostics.HasError() {
+		t.Errorf("unexpected error: %v", resp.Diagnostics)
+	}
+}
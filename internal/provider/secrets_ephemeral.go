@@ -0,0 +1,200 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &SecretsEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &SecretsEphemeralResource{}
+)
+
+// SecretsEphemeralResource reads a list of secrets in one block.
+type SecretsEphemeralResource struct {
+	client *GopassClient
+}
+
+// SecretsModel describes the data model.
+type SecretsModel struct {
+	Paths  []string          `tfsdk:"paths"`
+	Policy types.String      `tfsdk:"policy"`
+	Values map[string]string `tfsdk:"values"`
+}
+
+// NewSecretsEphemeralResource creates a new instance.
+func NewSecretsEphemeralResource() ephemeral.EphemeralResource {
+	return &SecretsEphemeralResource{}
+}
+
+func (r *SecretsEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secrets"
+}
+
+func (r *SecretsEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reads the passwords of a list of secrets in one block, decrypting them concurrently.",
+		MarkdownDescription: `
+Reads the passwords of a list of secrets in one block and returns them keyed
+by path, so configurations using many secrets need a single ephemeral block.
+Up to 8 secrets are decrypted at the same time.
+
+Every path must be readable: a missing, denied or unreadable secret fails the
+whole block, naming every path that failed.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_secrets" "app" {
+  paths = [
+    "services/api/token",
+    "infrastructure/database/admin",
+  ]
+}
+
+provider "example" {
+  api_token   = ephemeral.gopass_secrets.app.values["services/api/token"]
+  db_password = ephemeral.gopass_secrets.app.values["infrastructure/database/admin"]
+}
+` + "```" + `
+`,
+		Attributes: map[string]schema.Attribute{
+			"paths": schema.ListAttribute{
+				Description: "Paths of the secrets to read (e.g., ['services/api/token']).",
+				MarkdownDescription: "Paths of the secrets to read (e.g., `[\"services/api/token\"]`). " +
+					"A path listed more than once is read once.",
+				ElementType: types.StringType,
+				Required:    true,
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"values": schema.MapAttribute{
+				Description:         "The passwords of the secrets, keyed by their paths as given in paths.",
+				MarkdownDescription: "The passwords of the secrets, keyed by their paths as given in `paths`.",
+				ElementType:         types.StringType,
+				Computed:            true,
+				Sensitive:           true,
+			},
+		},
+	}
+}
+
+func (r *SecretsEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *SecretsEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data SecretsModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for i, secretPath := range data.Paths {
+		if problem := pathProblem(secretPath, false); problem != "" {
+			resp.Diagnostics.AddAttributeError(path.Root("paths").AtListIndex(i), "Invalid secret path",
+				fmt.Sprintf("Secret paths %s, got %q.", problem, secretPath))
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = withAccessor(ctx, "ephemeral.gopass_secrets", strings.Join(data.Paths, ", "))
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	values, err := r.client.GetSecrets(ctx, data.Paths)
+	if err != nil && r.client.deferOpen(ctx, req, resp, err) {
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secrets"),
+			errorDetail(fmt.Sprintf("Could not read all of the %d secret(s): %s", len(data.Paths), err.Error()), err),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(nonUTF8Diagnostics(values)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	var empty []string
+	for secretPath, value := range values {
+		if value == "" {
+			empty = append(empty, secretPath)
+		}
+	}
+	sort.Strings(empty)
+	resp.Diagnostics.Append(r.client.emptyValueDiagnostics(empty)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Hand Terraform copies we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	data.Values = make(map[string]string, len(values))
+	for secretPath, value := range values {
+		data.Values[secretPath] = buffers.protect(value)
+	}
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Read secrets from gopass", map[string]interface{}{
+		"count": len(values),
+	})
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *SecretsEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
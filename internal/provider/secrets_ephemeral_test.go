@@ -0,0 +1,78 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// secretsConfig returns a gopass_secrets configuration reading paths.
+func secretsConfig(paths ...string) map[string]tftypes.Value {
+	values := make([]tftypes.Value, len(paths))
+	for i, p := range paths {
+		values[i] = tftypes.NewValue(tftypes.String, p)
+	}
+	return map[string]tftypes.Value{
+		"paths": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, values),
+	}
+}
+
+func TestSecretsEphemeralResource_Open(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"services/api/token": "t0ken",
+		"app/db":             "s3cret\nusername: admin",
+	}))
+
+	resp := openConfiguredEphemeral(t, &SecretsEphemeralResource{client: client},
+		secretsConfig("services/api/token", "app/db"))
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data SecretsModel
+	resp.Result.Get(context.Background(), &data)
+	if len(data.Values) != 2 || data.Values["services/api/token"] != "t0ken" || data.Values["app/db"] != "s3cret" {
+		t.Errorf("unexpected values %v", data.Values)
+	}
+}
+
+func TestSecretsEphemeralResource_Open_Empty(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(nil))
+
+	resp := openConfiguredEphemeral(t, &SecretsEphemeralResource{client: client}, secretsConfig())
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+
+	var data SecretsModel
+	resp.Result.Get(context.Background(), &data)
+	if data.Values == nil || len(data.Values) != 0 {
+		t.Errorf("expected an empty map, got %v", data.Values)
+	}
+}
+
+func TestSecretsEphemeralResource_Open_Errors(t *testing.T) {
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{"app/db": "s3cret"}))
+
+	tests := map[string]struct {
+		paths   []string
+		summary string
+	}{
+		"missing":       {[]string{"app/db", "app/missing"}, "Secret not found"},
+		"empty path":    {[]string{"app/db", "/"}, "Invalid secret path"},
+		"parent":        {[]string{"app/../db"}, "Invalid secret path"},
+		"leading slash": {[]string{"/app/db"}, "Invalid secret path"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := openConfiguredEphemeral(t, &SecretsEphemeralResource{client: client}, secretsConfig(tt.paths...))
+			if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != tt.summary {
+				t.Errorf("expected %q, got %v", tt.summary, resp.Diagnostics)
+			}
+		})
+	}
+}
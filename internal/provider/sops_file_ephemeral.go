@@ -0,0 +1,312 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure implementation satisfies interfaces.
+var (
+	_ ephemeral.EphemeralResource          = &SOPSFileEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithClose = &SOPSFileEphemeralResource{}
+)
+
+// SOPSFileEphemeralResource decrypts a local SOPS file with keys kept in gopass.
+type SOPSFileEphemeralResource struct {
+	client *GopassClient
+}
+
+// SOPSFileModel describes the data model.
+type SOPSFileModel struct {
+	Source        types.String      `tfsdk:"source"`
+	Format        types.String      `tfsdk:"format"`
+	AgeIdentity   types.String      `tfsdk:"age_identity"`
+	PGPKey        types.String      `tfsdk:"pgp_key"`
+	PGPPassphrase types.String      `tfsdk:"pgp_passphrase"`
+	Policy        types.String      `tfsdk:"policy"`
+	Data          map[string]string `tfsdk:"data"`
+	Raw           types.String      `tfsdk:"raw"`
+}
+
+// NewSOPSFileEphemeralResource creates a new instance.
+func NewSOPSFileEphemeralResource() ephemeral.EphemeralResource {
+	return &SOPSFileEphemeralResource{}
+}
+
+func (r *SOPSFileEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_sops_file"
+}
+
+func (r *SOPSFileEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Decrypts a local SOPS-encrypted JSON or YAML file with an age identity or PGP key stored in gopass.",
+		MarkdownDescription: `
+Decrypts a local [SOPS](https://github.com/getsops/sops)-encrypted JSON or YAML
+file with an age identity or PGP private key stored in gopass, so repositories
+can keep their SOPS files while the keys stay in the shared password store.
+
+The file is decrypted in the provider; neither the ` + "`sops`" + ` binary nor a key
+file on disk is needed. Files encrypted for cloud KMS keys decrypt if they also
+list an age or PGP recipient. Key groups (Shamir secret sharing) are not supported.
+
+## Example Usage
+
+` + "```hcl" + `
+ephemeral "gopass_sops_file" "app" {
+  source       = "${path.module}/secrets.enc.yaml"
+  age_identity = "infrastructure/sops/age-key"
+}
+
+provider "postgresql" {
+  password = ephemeral.gopass_sops_file.app.data["database.password"]
+}
+` + "```" + `
+
+## Keys
+
+` + "`age_identity`" + ` is a secret holding the output of ` + "`age-keygen`" + `; comment
+lines are ignored. ` + "`pgp_key`" + ` is a secret holding an armored private key,
+as exported by ` + "`gpg --export-secret-keys --armor`" + `; if the key is protected,
+` + "`pgp_passphrase`" + ` is the secret whose password unlocks it.
+`,
+		Attributes: map[string]schema.Attribute{
+			"source": schema.StringAttribute{
+				Description: "Path of the SOPS-encrypted file; ~ expands to the home directory.",
+				Required:    true,
+			},
+			"format": schema.StringAttribute{
+				Description: "Format of the file: json or yaml. Default: from the file extension (.json, .yaml, .yml).",
+				MarkdownDescription: "Format of the file: `json` or `yaml`. " +
+					"Default: from the file extension (`.json`, `.yaml`, `.yml`).",
+				Optional: true,
+			},
+			"age_identity": schema.StringAttribute{
+				Description:         "Path of the secret holding the age identity (AGE-SECRET-KEY-...) to decrypt with.",
+				MarkdownDescription: "Path of the secret holding the age identity (`AGE-SECRET-KEY-...`) to decrypt with.",
+				Optional:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"pgp_key": schema.StringAttribute{
+				Description: "Path of the secret holding the armored PGP private key to decrypt with.",
+				Optional:    true,
+				Validators:  []validator.String{secretPathValidator{}},
+			},
+			"pgp_passphrase": schema.StringAttribute{
+				Description:         "Path of the secret whose password unlocks pgp_key.",
+				MarkdownDescription: "Path of the secret whose password unlocks `pgp_key`.",
+				Optional:            true,
+				Validators:          []validator.String{secretPathValidator{}},
+			},
+			"policy": schema.StringAttribute{
+				Description: "Name of a path policy from the provider's policies this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				MarkdownDescription: "Name of a path policy from the provider's `policies` this resource runs under. " +
+					"Accessing a secret the policy does not allow fails.",
+				Optional: true,
+			},
+			"data": schema.MapAttribute{
+				Description: "The decrypted values, keyed by their path in the file joined with dots; " +
+					"list items are keyed by their index (e.g., 'database.hosts.0').",
+				MarkdownDescription: "The decrypted values, keyed by their path in the file joined with dots; " +
+					"list items are keyed by their index (e.g., `database.hosts.0`).",
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+			},
+			"raw": schema.StringAttribute{
+				Description: "The decrypted file without its SOPS metadata, in its format.",
+				Computed:    true,
+				Sensitive:   true,
+			},
+		},
+	}
+}
+
+func (r *SOPSFileEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*GopassClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Provider Data",
+			fmt.Sprintf("Expected *GopassClient, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.client = client
+}
+
+func (r *SOPSFileEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	// Report warnings the client raised along the way, e.g. a failed git sync,
+	// and keep secret values out of every diagnostic
+	defer func() { resp.Diagnostics = r.client.finishDiagnostics(resp.Diagnostics) }()
+
+	var data SOPSFileModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Check what needs no secret first, so a typo does not cost a decryption
+	if data.AgeIdentity.IsNull() && data.PGPKey.IsNull() {
+		resp.Diagnostics.AddError("No SOPS key", "Set age_identity or pgp_key to the secret holding the key to decrypt with.")
+		return
+	}
+	if !data.PGPPassphrase.IsNull() && data.PGPKey.IsNull() {
+		resp.Diagnostics.AddAttributeError(path.Root("pgp_passphrase"), "No PGP key", "pgp_passphrase requires pgp_key.")
+		return
+	}
+
+	source, err := r.client.expandHome(data.Source.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Invalid source", err.Error())
+		return
+	}
+	format, err := sopsFormat(source, data.Format.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("format"), "Invalid format", err.Error())
+		return
+	}
+	content, err := os.ReadFile(source)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Failed to read SOPS file", err.Error())
+		return
+	}
+	file, err := parseSopsFile(content, format)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Invalid SOPS file",
+			fmt.Sprintf("Could not parse %q: %s.", source, err.Error()))
+		return
+	}
+
+	ctx = withPolicy(ctx, data.Policy.ValueString())
+	ctx = r.client.logContext(ctx)
+
+	var keys sopsKeys
+	if !data.AgeIdentity.IsNull() {
+		material, ok := r.readKey(ctx, req, resp, data.AgeIdentity.ValueString())
+		if !ok {
+			return
+		}
+		keys.age, err = parseSopsAgeKeys(material)
+		clear(material)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("age_identity"), "Invalid age identity",
+				fmt.Sprintf("The secret at %q holds %s.", data.AgeIdentity.ValueString(), err.Error()))
+			return
+		}
+	}
+	if !data.PGPKey.IsNull() {
+		material, ok := r.readKey(ctx, req, resp, data.PGPKey.ValueString())
+		if !ok {
+			return
+		}
+		var passphrase []byte
+		if !data.PGPPassphrase.IsNull() {
+			passphrasePath := data.PGPPassphrase.ValueString()
+			value, err := r.client.GetSecret(withAccessor(ctx, "ephemeral.gopass_sops_file", passphrasePath), passphrasePath)
+			if err != nil {
+				clear(material)
+				if r.client.deferOpen(ctx, req, resp, err) {
+					return
+				}
+				resp.Diagnostics.AddError(
+					errorSummary(err, "Failed to read secret"),
+					errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", passphrasePath, err.Error()), err),
+				)
+				return
+			}
+			passphrase = []byte(value)
+		}
+		keys.pgp, err = parseSopsPGPKeys(material, passphrase)
+		clear(material)
+		clear(passphrase)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("pgp_key"), "Invalid PGP key",
+				fmt.Sprintf("The secret at %q: %s.", data.PGPKey.ValueString(), err.Error()))
+			return
+		}
+	}
+
+	if err := file.decrypt(keys); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("source"), "Failed to decrypt SOPS file",
+			fmt.Sprintf("Could not decrypt %q: %s.", source, err.Error()))
+		return
+	}
+	raw, err := file.render()
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to encode SOPS file", err.Error())
+		return
+	}
+
+	// Hand Terraform copies we can wipe when the resource is closed
+	buffers := r.client.newSecretBuffers()
+	values := file.flatten()
+	data.Data = make(map[string]string, len(values))
+	for key, value := range values {
+		data.Data[key] = buffers.protect(value)
+	}
+	data.Raw = types.StringValue(buffers.protect(string(raw)))
+	clear(raw)
+
+	// Set result - NEVER written to state
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the store open and the buffers intact until Terraform is done with
+	// this value. Private state is only set up when called through the plugin server.
+	if resp.Private != nil {
+		resp.Diagnostics.Append(r.client.openLease(ctx, resp.Private, buffers)...)
+	}
+
+	tflog.Debug(ctx, "Decrypted SOPS file with key from gopass", map[string]interface{}{
+		"source": source,
+		"format": format,
+		"values": len(values),
+	})
+}
+
+// readKey returns the full content of the secret holding key material at
+// secretPath; false if it failed and was reported in resp.
+func (r *SOPSFileEphemeralResource) readKey(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse, secretPath string) ([]byte, bool) {
+	var buf bytes.Buffer
+	_, err := r.client.WriteSecretTo(withAccessor(ctx, "ephemeral.gopass_sops_file", secretPath), secretPath, &buf)
+	if err != nil {
+		clear(buf.Bytes())
+		if r.client.deferOpen(ctx, req, resp, err) {
+			return nil, false
+		}
+		resp.Diagnostics.AddError(
+			errorSummary(err, "Failed to read secret"),
+			errorDetail(fmt.Sprintf("Could not read secret at path %q: %s", secretPath, err.Error()), err),
+		)
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// Close wipes the secret buffers and releases the store reference taken by Open.
+func (r *SOPSFileEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	if r.client == nil || req.Private == nil {
+		return
+	}
+	resp.Diagnostics.Append(r.client.closeLease(ctx, req.Private)...)
+}
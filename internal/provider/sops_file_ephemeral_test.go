@@ -0,0 +1,163 @@
+// Copyright (c) Ingo Struck
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// writeSopsFile writes content to a file named name in a temporary directory
+// and returns its path.
+func writeSopsFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func openSOPSFileEphemeral(t *testing.T, client *GopassClient, config map[string]string) SOPSFileModel {
+	t.Helper()
+	r := &SOPSFileEphemeralResource{client: client}
+	values := map[string]tftypes.Value{}
+	for name, value := range config {
+		values[name] = tftypes.NewValue(tftypes.String, value)
+	}
+
+	resp := openConfiguredEphemeral(t, r, values)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", resp.Diagnostics)
+	}
+	var data SOPSFileModel
+	resp.Result.Get(context.Background(), &data)
+	return data
+}
+
+func TestSOPSFileEphemeralResource_Open_Age(t *testing.T) {
+	id := newAgeIdentity(t)
+	source := writeSopsFile(t, "secrets.enc.yaml",
+		sopsEncrypt(t, sopsTestYAML, "yaml", sopsTestOptions{age: []*age.X25519Identity{id}}))
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"infra/sops/age-key": "# created: 2025-01-02T03:04:05Z\n" + id.String(),
+	}))
+
+	data := openSOPSFileEphemeral(t, client, map[string]string{
+		"source":       source,
+		"age_identity": "infra/sops/age-key",
+	})
+	if data.Data["database.password"] != "s3cr:t" || data.Data["database.replicas.1"] != "b.db.internal" || len(data.Data) != 9 {
+		t.Errorf("unexpected data %v", data.Data)
+	}
+	if raw := data.Raw.ValueString(); !strings.Contains(raw, "password: s3cr:t") || strings.Contains(raw, "sops") {
+		t.Errorf("unexpected raw:\n%s", raw)
+	}
+}
+
+func TestSOPSFileEphemeralResource_Open_PGP(t *testing.T) {
+	entity, armored := newPGPKey(t, "unl0ck")
+	source := writeSopsFile(t, "secrets",
+		sopsEncrypt(t, `{"token":"t0ken"}`, "json", sopsTestOptions{pgp: []*openpgp.Entity{entity}}))
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"infra/sops/pgp-key":        string(armored),
+		"infra/sops/pgp-passphrase": "unl0ck\nnote: for the sops key",
+	}))
+
+	data := openSOPSFileEphemeral(t, client, map[string]string{
+		"source":         source,
+		"format":         "json",
+		"pgp_key":        "infra/sops/pgp-key",
+		"pgp_passphrase": "infra/sops/pgp-passphrase",
+	})
+	if data.Data["token"] != "t0ken" || data.Raw.ValueString() != "{\n  \"token\": \"t0ken\"\n}\n" {
+		t.Errorf("unexpected result %v %q", data.Data, data.Raw.ValueString())
+	}
+}
+
+func TestSOPSFileEphemeralResource_Open_Errors(t *testing.T) {
+	id := newAgeIdentity(t)
+	source := writeSopsFile(t, "secrets.yaml",
+		sopsEncrypt(t, "token: t0ken\n", "yaml", sopsTestOptions{age: []*age.X25519Identity{id}}))
+	plain := writeSopsFile(t, "plain.yaml", []byte("token: t0ken\n"))
+	client := NewGopassClientWithStore(NewMemoryStore(map[string]string{
+		"sops/age-key":   id.String(),
+		"sops/other-key": newAgeIdentity(t).String(),
+		"sops/password":  "s3cret",
+	}))
+
+	tests := []struct {
+		name    string
+		config  map[string]string
+		wantErr string
+	}{
+		{
+			name:    "no key",
+			config:  map[string]string{"source": source},
+			wantErr: "No SOPS key",
+		},
+		{
+			name:    "passphrase without PGP key",
+			config:  map[string]string{"source": source, "age_identity": "sops/age-key", "pgp_passphrase": "sops/password"},
+			wantErr: "No PGP key",
+		},
+		{
+			name:    "unknown format",
+			config:  map[string]string{"source": source + ".txt", "age_identity": "sops/age-key"},
+			wantErr: "Invalid format",
+		},
+		{
+			name:    "missing file",
+			config:  map[string]string{"source": source + ".yaml", "age_identity": "sops/age-key"},
+			wantErr: "Failed to read SOPS file",
+		},
+		{
+			name:    "not a SOPS file",
+			config:  map[string]string{"source": plain, "age_identity": "sops/age-key"},
+			wantErr: "Invalid SOPS file",
+		},
+		{
+			name:    "missing key secret",
+			config:  map[string]string{"source": source, "age_identity": "sops/missing"},
+			wantErr: "sops/missing",
+		},
+		{
+			name:    "not an age identity",
+			config:  map[string]string{"source": source, "age_identity": "sops/password"},
+			wantErr: "Invalid age identity",
+		},
+		{
+			name:    "not a PGP key",
+			config:  map[string]string{"source": source, "pgp_key": "sops/password"},
+			wantErr: "Invalid PGP key",
+		},
+		{
+			name:    "wrong key",
+			config:  map[string]string{"source": source, "age_identity": "sops/other-key"},
+			wantErr: "Failed to decrypt SOPS file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[string]tftypes.Value{}
+			for name, value := range tt.config {
+				values[name] = tftypes.NewValue(tftypes.String, value)
+			}
+			resp := openConfiguredEphemeral(t, &SOPSFileEphemeralResource{client: client}, values)
+			if !resp.Diagnostics.HasError() {
+				t.Fatal("expected an error")
+			}
+			if got := resp.Diagnostics.Errors()[0]; !strings.Contains(got.Summary()+got.Detail(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %s: %s", tt.wantErr, got.Summary(), got.Detail())
+			}
+		})
+	}
+}
@@ -6,7 +6,10 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"git.ingo-struck.com/opentofu/terraform-provider-gopass/internal/provider"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
@@ -15,19 +18,106 @@ import (
 // version is set via ldflags at build time
 var version = "dev"
 
+// mountFlags collects repeated -mount prefix=dir flags.
+type mountFlags map[string]string
+
+func (m mountFlags) String() string {
+	return fmt.Sprint(map[string]string(m))
+}
+
+func (m mountFlags) Set(value string) error {
+	prefix, dir, ok := strings.Cut(value, "=")
+	if !ok || prefix == "" || dir == "" {
+		return fmt.Errorf("expected prefix=dir, got %q", value)
+	}
+	m[prefix] = dir
+	return nil
+}
+
 func main() {
-	var debug bool
+	var debug, diagnose bool
+	var verifyAuditLog, auditLogKey string
+	var diagnoseOpts provider.DiagnoseOptions
+	mounts := mountFlags{}
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")
+	flag.BoolVar(&diagnose, "diagnose", false, "check store, crypto backend, gpg-agent and mounts, print a report and exit")
+	flag.StringVar(&diagnoseOpts.StorePath, "store-path", "", "with -diagnose: store directory, like the store_path provider argument")
+	flag.Var(mounts, "mount", "with -diagnose: mounted store as prefix=dir, like the mounts provider argument; repeatable")
+	flag.StringVar(&diagnoseOpts.WarmUpPath, "warm-up-path", "", "with -diagnose: secret to decrypt as an end-to-end check")
+	flag.StringVar(&verifyAuditLog, "verify-audit-log", "", "check the hash chain of an audit log written by the provider and exit")
+	flag.StringVar(&auditLogKey, "audit-log-key", "", "with -verify-audit-log: PEM public key the log's checkpoints must be signed with")
 	flag.Parse()
 
+	if verifyAuditLog != "" {
+		if err := verifyAuditLogFile(verifyAuditLog, auditLogKey); err != nil {
+			fmt.Fprintf(os.Stderr, "audit log %s: %s\n", verifyAuditLog, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if diagnose {
+		diagnoseOpts.Mounts = mounts
+		ctx := context.Background()
+		passed := provider.Diagnose(ctx, os.Stdout, diagnoseOpts)
+		provider.Shutdown(ctx)
+		if !passed {
+			os.Exit(1)
+		}
+		return
+	}
+
 	opts := providerserver.ServeOpts{
 		Address: "registry.opentofu.org/istr/gopass",
 		Debug:   debug,
 	}
 
-	err := providerserver.Serve(context.Background(), provider.New(version), opts)
+	ctx := context.Background()
+	err := providerserver.Serve(ctx, provider.New(version), opts)
+
+	// Release gopass stores (and with them gpg-agent connections) before exiting
+	provider.Shutdown(ctx)
+
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 }
+
+// verifyAuditLogFile checks the audit log at path and prints the number of
+// records and the hash of the last one. With keyPath set, the signatures of
+// its checkpoints are checked as well.
+func verifyAuditLogFile(path, keyPath string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if keyPath == "" {
+		records, head, err := provider.VerifyAuditLog(file)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("audit log %s is intact: %d record(s), head %s\n", path, records, head)
+		return nil
+	}
+
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	key, err := provider.ParseAuditPublicKey(keyData)
+	if err != nil {
+		return fmt.Errorf("invalid key %s: %w", keyPath, err)
+	}
+	records, unsigned, head, err := provider.VerifySignedAuditLog(file, key)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("audit log %s is intact and signed: %d record(s), head %s\n", path, records, head)
+	if unsigned > 0 {
+		fmt.Printf("warning: the last %d record(s) are not covered by a signed checkpoint\n", unsigned)
+	}
+	return nil
+}